@@ -0,0 +1,31 @@
+// Package accounts provides the signers used by the RPC daemon to sign transactions and messages on behalf of
+// locally managed accounts.
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+)
+
+// Signer signs transactions and messages with the keys of the accounts it manages.
+type Signer interface {
+	// Accounts returns the addresses of the accounts the signer can sign for.
+	Accounts(ctx context.Context) ([]common.Address, error)
+	// SignTx signs the transaction for the given chain with the key of the account.
+	SignTx(ctx context.Context, account common.Address, tx types.Transaction, chainID *big.Int) (types.Transaction, error)
+	// SignText signs the TextHash of the data with the key of the account. The signature is in the [R || S || V]
+	// format, with V being 27 or 28.
+	SignText(ctx context.Context, account common.Address, data []byte) ([]byte, error)
+}
+
+// TextHash is the EIP-191 hash of a personal message:
+// keccak256("\x19Ethereum Signed Message:\n"${message length}${message}).
+func TextHash(data []byte) []byte {
+	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
+	return crypto.Keccak256([]byte(msg))
+}
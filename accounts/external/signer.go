@@ -0,0 +1,118 @@
+// Package external implements a signer backed by an external signer speaking the clef JSON-RPC API.
+package external
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon/accounts"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// ExternalSigner forwards signing requests to clef, which asks for approval according to its own rules.
+type ExternalSigner struct {
+	client   *rpc.Client
+	endpoint string
+}
+
+var _ accounts.Signer = (*ExternalSigner)(nil)
+
+// NewExternalSigner connects to the signer, endpoint is an ipc path or an http/ws url.
+func NewExternalSigner(ctx context.Context, endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to external signer %s: %w", endpoint, err)
+	}
+	return &ExternalSigner{client: client, endpoint: endpoint}, nil
+}
+
+func (s *ExternalSigner) Close() {
+	s.client.Close()
+}
+
+func (s *ExternalSigner) Accounts(ctx context.Context) ([]common.Address, error) {
+	var res []common.MixedcaseAddress
+	if err := s.client.CallContext(ctx, &res, "account_list"); err != nil {
+		return nil, err
+	}
+	addresses := make([]common.Address, len(res))
+	for i, addr := range res {
+		addresses[i] = addr.Address()
+	}
+	return addresses, nil
+}
+
+// signTransactionArgs are the transaction arguments of account_signTransaction.
+type signTransactionArgs struct {
+	From                 common.MixedcaseAddress  `json:"from"`
+	To                   *common.MixedcaseAddress `json:"to"`
+	Gas                  hexutil.Uint64           `json:"gas"`
+	GasPrice             *hexutil.Big             `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big             `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big             `json:"maxPriorityFeePerGas,omitempty"`
+	Value                hexutil.Big              `json:"value"`
+	Nonce                hexutil.Uint64           `json:"nonce"`
+	Data                 *hexutil.Bytes           `json:"data,omitempty"`
+	AccessList           *types.AccessList        `json:"accessList,omitempty"`
+	ChainID              *hexutil.Big             `json:"chainId,omitempty"`
+}
+
+type signTransactionResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+func (s *ExternalSigner) SignTx(ctx context.Context, account common.Address, tx types.Transaction, chainID *big.Int) (types.Transaction, error) {
+	args := signTransactionArgs{
+		From:    common.NewMixedcaseAddress(account),
+		Gas:     hexutil.Uint64(tx.GetGas()),
+		Value:   hexutil.Big(*tx.GetValue().ToBig()),
+		Nonce:   hexutil.Uint64(tx.GetNonce()),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if to := tx.GetTo(); to != nil {
+		mixedTo := common.NewMixedcaseAddress(*to)
+		args.To = &mixedTo
+	}
+	if data := tx.GetData(); len(data) > 0 {
+		args.Data = (*hexutil.Bytes)(&data)
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GetPrice().ToBig())
+	case types.AccessListTxType:
+		accessList := tx.GetAccessList()
+		args.GasPrice = (*hexutil.Big)(tx.GetPrice().ToBig())
+		args.AccessList = &accessList
+	case types.DynamicFeeTxType:
+		accessList := tx.GetAccessList()
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GetFeeCap().ToBig())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GetTip().ToBig())
+		args.AccessList = &accessList
+	default:
+		return nil, fmt.Errorf("%w: %d", types.ErrTxTypeNotSupported, tx.Type())
+	}
+	var res signTransactionResult
+	if err := s.client.CallContext(ctx, &res, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+	return types.DecodeTransaction(res.Raw)
+}
+
+func (s *ExternalSigner) SignText(ctx context.Context, account common.Address, data []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := s.client.CallContext(ctx, &sig, "account_signData", "text/plain", common.NewMixedcaseAddress(account), hexutil.Encode(data)); err != nil {
+		return nil, err
+	}
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("invalid signature length %d from external signer", len(sig))
+	}
+	// clef returns V as 27/28 already, older versions returned 0/1.
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+	return sig, nil
+}
@@ -0,0 +1,93 @@
+package keystore
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/accounts"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors of the Web3 Secret Storage definition.
+const (
+	pbkdf2KeyJSON = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"6087dab2f9fdbbfaddc31a909735c1e6"},"ciphertext":"5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46","kdf":"pbkdf2","kdfparams":{"c":262144,"dklen":32,"prf":"hmac-sha256","salt":"ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},"mac":"517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+	scryptKeyJSON = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"83dbcc02d8ccb40e466191a123791e0e"},"ciphertext":"d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c","kdf":"scrypt","kdfparams":{"dklen":32,"n":262144,"p":8,"r":1,"salt":"ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"},"mac":"2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+	vectorKey     = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+)
+
+func TestDecryptKeyVectors(t *testing.T) {
+	for name, keyJSON := range map[string]string{"pbkdf2": pbkdf2KeyJSON, "scrypt": scryptKeyJSON} {
+		t.Run(name, func(t *testing.T) {
+			key, err := DecryptKey([]byte(keyJSON), "testpassword")
+			require.NoError(t, err)
+			require.Equal(t, vectorKey, hex.EncodeToString(crypto.FromECDSA(key.PrivateKey)))
+
+			_, err = DecryptKey([]byte(keyJSON), "wrong")
+			require.ErrorIs(t, err, ErrDecrypt)
+		})
+	}
+}
+
+func TestEncryptKey(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keyJSON, err := EncryptKey(newKeyFromECDSA(key), "foo", LightScryptN, LightScryptP)
+	require.NoError(t, err)
+	decrypted, err := DecryptKey(keyJSON, "foo")
+	require.NoError(t, err)
+	require.Equal(t, crypto.FromECDSA(key), crypto.FromECDSA(decrypted.PrivateKey))
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), decrypted.Address)
+}
+
+func TestKeyStore(t *testing.T) {
+	dir := t.TempDir()
+	keys := make([]common.Address, 2)
+	for i, password := range []string{"foo", "bar"} {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		keyJSON, err := EncryptKey(newKeyFromECDSA(key), password, LightScryptN, LightScryptP)
+		require.NoError(t, err)
+		keys[i] = crypto.PubkeyToAddress(key.PublicKey)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, keys[i].Hex()), keyJSON, 0600))
+	}
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("bar\nfoo\n"), 0600))
+
+	ks, err := Open(dir, passwordFile)
+	require.NoError(t, err)
+	addresses, err := ks.Accounts(context.Background())
+	require.NoError(t, err)
+	require.ElementsMatch(t, keys, addresses)
+
+	// A key no password opens fails the whole keystore.
+	require.NoError(t, os.WriteFile(passwordFile, []byte("foo\n"), 0600))
+	_, err = Open(dir, passwordFile)
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	chainID := big.NewInt(5)
+	tx := types.NewTransaction(0, common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), nil)
+	signed, err := ks.SignTx(context.Background(), keys[0], tx, chainID)
+	require.NoError(t, err)
+	sender, err := signed.Sender(*types.LatestSignerForChainID(chainID))
+	require.NoError(t, err)
+	require.Equal(t, keys[0], sender)
+
+	sig, err := ks.SignText(context.Background(), keys[1], []byte("hello"))
+	require.NoError(t, err)
+	require.Contains(t, []byte{27, 28}, sig[crypto.RecoveryIDOffset])
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte("hello")), sig)
+	require.NoError(t, err)
+	require.Equal(t, keys[1], crypto.PubkeyToAddress(*pub))
+
+	_, err = ks.SignText(context.Background(), common.Address{1}, []byte("hello"))
+	require.Error(t, err)
+}
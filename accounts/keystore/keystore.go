@@ -0,0 +1,157 @@
+// Package keystore reads and writes keys stored in the Web3 Secret Storage format, the format of the geth and clef
+// keystores.
+package keystore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/accounts"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+)
+
+// KeyStore is a signer holding the decrypted keys of a keystore directory.
+type KeyStore struct {
+	keys      map[common.Address]*ecdsa.PrivateKey
+	addresses []common.Address
+}
+
+var _ accounts.Signer = (*KeyStore)(nil)
+
+// Open decrypts all the key files of the directory. Every line of the password file is tried on every key, so that
+// keys protected by different passwords can share a keystore. Opening fails if any key cannot be decrypted.
+func Open(dir, passwordFile string) (*KeyStore, error) {
+	passwords, err := readPasswords(passwordFile)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading keystore: %w", err)
+	}
+	ks := &KeyStore{keys: map[common.Address]*ecdsa.PrivateKey{}}
+	for _, entry := range entries {
+		// Skip editor backups and hidden files, like geth does.
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+		keyJSON, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		key, err := decryptWithAny(keyJSON, passwords)
+		if err != nil {
+			return nil, fmt.Errorf("key file %s: %w", name, err)
+		}
+		ks.add(key.PrivateKey)
+	}
+	return ks, nil
+}
+
+// NewKeyStore makes a signer out of already decrypted keys.
+func NewKeyStore(keys ...*ecdsa.PrivateKey) *KeyStore {
+	ks := &KeyStore{keys: map[common.Address]*ecdsa.PrivateKey{}}
+	for _, key := range keys {
+		ks.add(key)
+	}
+	return ks
+}
+
+func (ks *KeyStore) add(key *ecdsa.PrivateKey) {
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	if _, ok := ks.keys[address]; ok {
+		return
+	}
+	ks.keys[address] = key
+	ks.addresses = append(ks.addresses, address)
+	sort.Slice(ks.addresses, func(i, j int) bool { return bytes.Compare(ks.addresses[i][:], ks.addresses[j][:]) < 0 })
+}
+
+func (ks *KeyStore) Accounts(context.Context) ([]common.Address, error) {
+	return append([]common.Address{}, ks.addresses...), nil
+}
+
+func (ks *KeyStore) SignTx(_ context.Context, account common.Address, tx types.Transaction, chainID *big.Int) (types.Transaction, error) {
+	key, err := ks.key(account)
+	if err != nil {
+		return nil, err
+	}
+	return types.SignTx(tx, *types.LatestSignerForChainID(chainID), key)
+}
+
+func (ks *KeyStore) SignText(_ context.Context, account common.Address, data []byte) ([]byte, error) {
+	key, err := ks.key(account)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(accounts.TextHash(data), key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+func (ks *KeyStore) key(account common.Address) (*ecdsa.PrivateKey, error) {
+	key, ok := ks.keys[account]
+	if !ok {
+		return nil, fmt.Errorf("unknown account %x", account)
+	}
+	return key, nil
+}
+
+func readPasswords(passwordFile string) ([]string, error) {
+	if passwordFile == "" {
+		return []string{""}, nil
+	}
+	f, err := os.Open(passwordFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading password file: %w", err)
+	}
+	defer f.Close()
+	var passwords []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		passwords = append(passwords, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading password file: %w", err)
+	}
+	return passwords, nil
+}
+
+func decryptWithAny(keyJSON []byte, passwords []string) (*Key, error) {
+	var header struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(keyJSON, &header); err != nil {
+		return nil, err
+	}
+	for _, password := range passwords {
+		key, err := DecryptKey(keyJSON, password)
+		if err == ErrDecrypt {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Make sure we're really operating on the requested key, like geth does.
+		if header.Address != "" && common.HexToAddress(header.Address) != key.Address {
+			return nil, fmt.Errorf("key content mismatch: have account %x, want %s", key.Address, header.Address)
+		}
+		return key, nil
+	}
+	return nil, ErrDecrypt
+}
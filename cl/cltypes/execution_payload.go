@@ -6,6 +6,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/core/types"
+	ssz "github.com/prysmaticlabs/fastssz"
 )
 
 func (e *ExecutionPayload) Header() *types.Header {
@@ -51,3 +52,61 @@ func (e *ExecutionPayload) BlockBody() *types.RawBody {
 		Transactions: e.Transactions,
 	}
 }
+
+// PayloadHeader returns the execution payload header, which commits to the payload transactions by their root.
+func (e *ExecutionPayload) PayloadHeader() (*ExecutionHeader, error) {
+	transactionsRoot, err := ssz.HashWithDefaultHasher(transactionsList(e.Transactions))
+	if err != nil {
+		return nil, err
+	}
+	return &ExecutionHeader{
+		ParentHash:      e.ParentHash,
+		FeeRecipient:    e.FeeRecipient,
+		StateRoot:       e.StateRoot,
+		ReceiptsRoot:    e.ReceiptsRoot,
+		LogsBloom:       e.LogsBloom,
+		PrevRandao:      e.PrevRandao,
+		BlockNumber:     e.BlockNumber,
+		GasLimit:        e.GasLimit,
+		GasUsed:         e.GasUsed,
+		Timestamp:       e.Timestamp,
+		ExtraData:       e.ExtraData,
+		BaseFeePerGas:   e.BaseFeePerGas,
+		BlockHash:       e.BlockHash,
+		TransactionRoot: transactionsRoot,
+	}, nil
+}
+
+// transactionsList is the ssz list of opaque transactions of an execution payload.
+type transactionsList [][]byte
+
+func (t transactionsList) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(t)
+}
+
+func (t transactionsList) HashTreeRootWith(hh *ssz.Hasher) error {
+	indx := hh.Index()
+	num := uint64(len(t))
+	if num > 1048576 {
+		return ssz.ErrIncorrectListSize
+	}
+	for _, elem := range t {
+		elemIndx := hh.Index()
+		byteLen := uint64(len(elem))
+		if byteLen > 1073741824 {
+			return ssz.ErrIncorrectListSize
+		}
+		hh.AppendBytes32(elem)
+		if ssz.EnableVectorizedHTR {
+			hh.MerkleizeWithMixinVectorizedHTR(elemIndx, byteLen, (1073741824+31)/32)
+		} else {
+			hh.MerkleizeWithMixin(elemIndx, byteLen, (1073741824+31)/32)
+		}
+	}
+	if ssz.EnableVectorizedHTR {
+		hh.MerkleizeWithMixinVectorizedHTR(indx, num, 1048576)
+	} else {
+		hh.MerkleizeWithMixin(indx, num, 1048576)
+	}
+	return nil
+}
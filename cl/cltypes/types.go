@@ -81,6 +81,13 @@ type DepositData struct {
 	Root                  [32]byte `ssz:"-"`
 }
 
+// DepositMessage is the deposit data without the signature, it is the message signed by the depositor.
+type DepositMessage struct {
+	PubKey                [48]byte `ssz-size:"48"`
+	WithdrawalCredentials []byte   `ssz-size:"32"`
+	Amount                uint64
+}
+
 type Deposit struct {
 	// Merkle proof is used for deposits
 	Proof [][]byte `ssz-size:"33,32"`
@@ -320,6 +327,57 @@ func (l *LightClientUpdate) HasSyncFinality() bool {
 		utils.SlotToPeriod(l.AttestedHeader.Slot) == utils.SlotToPeriod(l.FinalizedHeader.Slot)
 }
 
+// IsBetterUpdate compares two light client updates of the same period.
+func IsBetterUpdate(oldUpdate *LightClientUpdate, newUpdate *LightClientUpdate) bool {
+	var (
+		maxActiveParticipants = len(newUpdate.SyncAggregate.SyncCommiteeBits) * 8 // Bits
+		newActiveParticipants = newUpdate.SyncAggregate.Sum()
+		oldActiveParticipants = oldUpdate.SyncAggregate.Sum()
+		newHasSuperMajority   = newActiveParticipants*3 >= maxActiveParticipants*2
+		oldHasSuperMajority   = oldActiveParticipants*3 >= maxActiveParticipants*2
+	)
+
+	// Compare supermajority (> 2/3) sync committee participation
+	if newHasSuperMajority != oldHasSuperMajority {
+		return newHasSuperMajority && !oldHasSuperMajority
+	}
+
+	if !newHasSuperMajority && newActiveParticipants != oldActiveParticipants {
+		return newActiveParticipants > oldActiveParticipants
+	}
+
+	// Compare presence of relevant sync committee
+	isNewUpdateRelevant := newUpdate.HasNextSyncCommittee() &&
+		utils.SlotToPeriod(newUpdate.AttestedHeader.Slot) == utils.SlotToPeriod(newUpdate.SignatureSlot)
+	isOldUpdateRelevant := oldUpdate.HasNextSyncCommittee() &&
+		utils.SlotToPeriod(oldUpdate.AttestedHeader.Slot) == utils.SlotToPeriod(oldUpdate.SignatureSlot)
+
+	if isNewUpdateRelevant != isOldUpdateRelevant {
+		return isNewUpdateRelevant
+	}
+
+	isNewFinality := newUpdate.IsFinalityUpdate()
+	isOldFinality := oldUpdate.IsFinalityUpdate()
+
+	if isNewFinality != isOldFinality {
+		return isNewFinality
+	}
+
+	// Compare sync committee finality
+	if isNewFinality && newUpdate.HasSyncFinality() != oldUpdate.HasSyncFinality() {
+		return newUpdate.HasSyncFinality()
+	}
+
+	// Tie Breakers
+	if newActiveParticipants != oldActiveParticipants {
+		return newActiveParticipants > oldActiveParticipants
+	}
+	if newUpdate.AttestedHeader.Slot != oldUpdate.AttestedHeader.Slot {
+		return newUpdate.AttestedHeader.Slot < oldUpdate.AttestedHeader.Slot
+	}
+	return newUpdate.SignatureSlot < oldUpdate.SignatureSlot
+}
+
 // LightClientFinalityUpdate is used to update the sync aggreggate every 6 minutes.
 type LightClientFinalityUpdate struct {
 	AttestedHeader  *BeaconBlockHeader
@@ -384,6 +442,34 @@ type SignedAggregateAndProof struct {
 	Signature [96]byte `ssz-size:"96"`
 }
 
+// BeaconStateAltair is the altair beacon state.
+type BeaconStateAltair struct {
+	GenesisTime                 uint64
+	GenesisValidatorsRoot       [32]byte `ssz-size:"32"`
+	Slot                        uint64
+	Fork                        *Fork
+	LatestBlockHeader           *BeaconBlockHeader
+	BlockRoots                  [][32]byte `ssz-size:"8192,32"`
+	StateRoots                  [][32]byte `ssz-size:"8192,32"`
+	HistoricalRoots             [][32]byte `ssz-max:"16777216" ssz-size:"?,32"`
+	Eth1Data                    *Eth1Data
+	Eth1DataVotes               []*Eth1Data `ssz-max:"2048"`
+	Eth1DepositIndex            uint64
+	Validators                  []*Validator `ssz-max:"1099511627776"`
+	Balances                    []uint64     `ssz-max:"1099511627776"`
+	RandaoMixes                 [][32]byte   `ssz-size:"65536,32"`
+	Slashings                   []uint64     `ssz-size:"8192"`
+	PreviousEpochParticipation  []byte       `ssz-max:"1099511627776"`
+	CurrentEpochParticipation   []byte       `ssz-max:"1099511627776"`
+	JustificationBits           []byte       `ssz-size:"1"`
+	PreviousJustifiedCheckpoint *Checkpoint
+	CurrentJustifiedCheckpoint  *Checkpoint
+	FinalizedCheckpoint         *Checkpoint
+	InactivityScores            []uint64 `ssz-max:"1099511627776"`
+	CurrentSyncCommittee        *SyncCommittee
+	NextSyncCommittee           *SyncCommittee
+}
+
 // BellatrixBeaconState is the bellatrix beacon state.
 type BeaconStateBellatrix struct {
 	GenesisTime                  uint64
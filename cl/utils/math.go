@@ -1,5 +1,7 @@
 package utils
 
+import "math"
+
 func IsPowerOf2(n uint64) bool {
 	return n != 0 && (n&(n-1)) == 0
 }
@@ -10,3 +12,17 @@ func PowerOf2(n uint64) uint64 {
 	}
 	return 1 << n
 }
+
+// IntegerSquareRoot returns the largest integer x such that x*x <= n.
+func IntegerSquareRoot(n uint64) uint64 {
+	if n == math.MaxUint64 {
+		return math.MaxUint32
+	}
+	x := n
+	y := (x + 1) / 2
+	for y < x {
+		x = y
+		y = (x + n/x) / 2
+	}
+	return x
+}
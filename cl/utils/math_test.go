@@ -0,0 +1,18 @@
+package utils_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/cl/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegerSquareRoot(t *testing.T) {
+	assert.Equal(t, uint64(0), utils.IntegerSquareRoot(0))
+	assert.Equal(t, uint64(1), utils.IntegerSquareRoot(3))
+	assert.Equal(t, uint64(2), utils.IntegerSquareRoot(4))
+	assert.Equal(t, uint64(31622), utils.IntegerSquareRoot(1_000_000_000))
+	assert.Equal(t, uint64(math.MaxUint32), utils.IntegerSquareRoot(math.MaxUint64))
+	assert.Equal(t, uint64(math.MaxUint32-1), utils.IntegerSquareRoot(math.MaxUint32*math.MaxUint32-1))
+}
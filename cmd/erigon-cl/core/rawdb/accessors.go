@@ -2,10 +2,13 @@ package rawdb
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/big"
 
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cl/clparams"
 	"github.com/ledgerwatch/erigon/cl/cltypes"
 	"github.com/ledgerwatch/erigon/cl/utils"
 	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/state"
@@ -51,15 +54,13 @@ func ReadBeaconState(tx kv.Getter, slot uint64) (*state.BeaconState, error) {
 	return state.FromBellatrixState(bellatrixState), nil
 }
 
+// WriteLightClientUpdate writes the update of the sync committee period of its attested header.
 func WriteLightClientUpdate(tx kv.RwTx, update *cltypes.LightClientUpdate) error {
-	key := make([]byte, 4)
-	binary.BigEndian.PutUint32(key, uint32(update.SignatureSlot/8192))
-
 	encoded, err := update.MarshalSSZ()
 	if err != nil {
 		return err
 	}
-	return tx.Put(kv.LightClientUpdates, key, encoded)
+	return tx.Put(kv.LightClientUpdates, EncodeNumber(utils.SlotToPeriod(update.AttestedHeader.Slot)), encoded)
 }
 
 func WriteLightClientFinalityUpdate(tx kv.RwTx, update *cltypes.LightClientFinalityUpdate) error {
@@ -78,14 +79,14 @@ func WriteLightClientOptimisticUpdate(tx kv.RwTx, update *cltypes.LightClientOpt
 	return tx.Put(kv.LightClient, kv.LightClientOptimisticUpdate, encoded)
 }
 
-func ReadLightClientUpdate(tx kv.RwTx, period uint32) (*cltypes.LightClientUpdate, error) {
-	key := make([]byte, 4)
-	binary.BigEndian.PutUint32(key, period)
-
-	encoded, err := tx.GetOne(kv.LightClientUpdates, key)
+func ReadLightClientUpdate(tx kv.Getter, period uint64) (*cltypes.LightClientUpdate, error) {
+	encoded, err := tx.GetOne(kv.LightClientUpdates, EncodeNumber(period))
 	if err != nil {
 		return nil, err
 	}
+	if len(encoded) == 0 {
+		return nil, nil
+	}
 	update := &cltypes.LightClientUpdate{}
 	if err = update.UnmarshalSSZ(encoded); err != nil {
 		return nil, err
@@ -93,6 +94,34 @@ func ReadLightClientUpdate(tx kv.RwTx, period uint32) (*cltypes.LightClientUpdat
 	return update, nil
 }
 
+// WriteLightClientBootstrap writes the bootstrap of a block keyed by block root, and indexes it by the slot of its
+// header so it is pruned with the beacon data.
+func WriteLightClientBootstrap(tx kv.RwTx, blockRoot common.Hash, bootstrap *cltypes.LightClientBootstrap) error {
+	encoded, err := bootstrap.MarshalSSZ()
+	if err != nil {
+		return err
+	}
+	if err := tx.Put(LightClientBootstrapSlots, lightClientBootstrapSlotKey(bootstrap.Header.Slot, blockRoot), []byte{}); err != nil {
+		return err
+	}
+	return tx.Put(LightClientBootstraps, blockRoot[:], encoded)
+}
+
+func ReadLightClientBootstrap(tx kv.Getter, blockRoot common.Hash) (*cltypes.LightClientBootstrap, error) {
+	encoded, err := tx.GetOne(LightClientBootstraps, blockRoot[:])
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+	bootstrap := &cltypes.LightClientBootstrap{}
+	if err = bootstrap.UnmarshalSSZ(encoded); err != nil {
+		return nil, err
+	}
+	return bootstrap, nil
+}
+
 func ReadLightClientFinalityUpdate(tx kv.Tx) (*cltypes.LightClientFinalityUpdate, error) {
 	encoded, err := tx.GetOne(kv.LightClient, kv.LightClientFinalityUpdate)
 	if err != nil {
@@ -148,7 +177,7 @@ func WriteAttestations(tx kv.RwTx, slot uint64, attestations []*cltypes.Attestat
 	return tx.Put(kv.Attestetations, EncodeNumber(slot), cltypes.EncodeAttestationsForStorage(attestations))
 }
 
-func ReadAttestations(tx kv.RwTx, slot uint64) ([]*cltypes.Attestation, error) {
+func ReadAttestations(tx kv.Getter, slot uint64) ([]*cltypes.Attestation, error) {
 	attestationsEncoded, err := tx.GetOne(kv.Attestetations, EncodeNumber(slot))
 	if err != nil {
 		return nil, err
@@ -160,7 +189,7 @@ func WriteBeaconBlock(tx kv.RwTx, signedBlock *cltypes.SignedBeaconBlock) error
 	var (
 		block     = signedBlock.Block
 		blockBody = block.Body
-		//payload   = blockBody.ExecutionPayload
+		payload   = blockBody.ExecutionPayload
 	)
 
 	// database key is is [slot + body root]
@@ -169,9 +198,11 @@ func WriteBeaconBlock(tx kv.RwTx, signedBlock *cltypes.SignedBeaconBlock) error
 	if err != nil {
 		return err
 	}
-	/*if err := WriteExecutionPayload(tx, payload); err != nil {
-		return err
-	}*/
+	if signedBlock.Version() >= clparams.BellatrixVersion && payload.BlockHash != (common.Hash{}) {
+		if err := WriteExecutionPayload(tx, payload); err != nil {
+			return err
+		}
+	}
 
 	if err := WriteAttestations(tx, block.Slot, blockBody.Attestations); err != nil {
 		return err
@@ -180,8 +211,11 @@ func WriteBeaconBlock(tx kv.RwTx, signedBlock *cltypes.SignedBeaconBlock) error
 	return tx.Put(kv.BeaconBlocks, key, value)
 }
 
-func ReadBeaconBlock(tx kv.RwTx, slot uint64) (*cltypes.SignedBeaconBlock, error) {
-	signedBlock, _, _, _, err := ReadBeaconBlockForStorage(tx, slot)
+// ErrExecutionPayloadMissing is returned for the blocks after the merge whose execution payload is not stored.
+var ErrExecutionPayloadMissing = errors.New("execution payload is not available")
+
+func ReadBeaconBlock(tx kv.Tx, slot uint64) (*cltypes.SignedBeaconBlock, error) {
+	signedBlock, eth1Number, eth1Hash, _, err := ReadBeaconBlockForStorage(tx, slot)
 	if err != nil {
 		return nil, err
 	}
@@ -194,56 +228,26 @@ func ReadBeaconBlock(tx kv.RwTx, slot uint64) (*cltypes.SignedBeaconBlock, error
 		return nil, err
 	}
 	signedBlock.Block.Body.Attestations = attestations
-	return signedBlock, err
-	/*
-		// Process payload
-		header := rawdb2.ReadHeader(tx, hash, blockNumber)
-		if header == nil {
-			beaconBlock.Body = beaconBody
-			signedBeaconBlock.Block = beaconBlock
-			return signedBeaconBlock, nil // Header is empty so avoid writing EL data.
-		}
-		// Pack basic
-		payload := &cltypes.ExecutionPayload{
-			ParentHash:   header.ParentHash,
-			FeeRecipient: header.Coinbase,
-			StateRoot:    header.Root,
-			ReceiptsRoot: header.ReceiptHash,
-			LogsBloom:    header.Bloom[:],
-			PrevRandao:   header.MixDigest,
-			BlockNumber:  header.Number.Uint64(),
-			GasLimit:     header.GasLimit,
-			GasUsed:      header.GasUsed,
-			Timestamp:    header.Time,
-			ExtraData:    header.Extra,
-			BlockHash:    hash,
-		}
-		// TODO: pack back the baseFee
-		if header.BaseFee != nil {
-			baseFeeBytes := header.BaseFee.Bytes()
-			payload.BaseFeePerGas = make([]byte, 32)
-			for i, j := 0, len(baseFeeBytes)-1; i < j; i, j = i+1, j-1 {
-				baseFeeBytes[i], baseFeeBytes[j] = baseFeeBytes[j], baseFeeBytes[i]
-			}
-			copy(payload.BaseFeePerGas, baseFeeBytes)
-		}
-
-		body, err := rawdb2.ReadStorageBody(tx, hash, blockNumber)
-		if err != nil {
-			return nil, err
-		}
-		if err := tx.ForAmount(kv.EthTx, common2.EncodeTs(body.BaseTxId+1), body.TxAmount-2, func(k, v []byte) error {
-			payload.Transactions = append(payload.Transactions, v)
-			return nil
-		}); err != nil {
-			return nil, err
+	if signedBlock.Version() < clparams.BellatrixVersion {
+		return signedBlock, nil
+	}
+	// Blocks before the merge have an empty payload, which is not stored.
+	if eth1Hash == (common.Hash{}) {
+		signedBlock.Block.Body.ExecutionPayload = &cltypes.ExecutionPayload{
+			LogsBloom:     make([]byte, 256),
+			BaseFeePerGas: make([]byte, 32),
 		}
-		//
-		// Pack types
-		beaconBody.ExecutionPayload = payload
-		beaconBlock.Body = beaconBody
-		signedBeaconBlock.Block = beaconBlock
-		return signedBeaconBlock, nil*/
+		return signedBlock, nil
+	}
+	payload, err := ReadExecutionPayload(tx, eth1Hash, eth1Number)
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return nil, fmt.Errorf("%w: slot %d, block %d %x", ErrExecutionPayloadMissing, slot, eth1Number, eth1Hash)
+	}
+	signedBlock.Block.Body.ExecutionPayload = payload
+	return signedBlock, nil
 }
 
 func ReadBeaconBlockForStorage(tx kv.Getter, slot uint64) (block *cltypes.SignedBeaconBlock, eth1Number uint64, eth1Hash common.Hash, eth2Hash common.Hash, err error) {
@@ -304,3 +308,73 @@ func WriteExecutionPayload(tx kv.RwTx, payload *cltypes.ExecutionPayload) error
 
 	return err
 }
+
+// ReadExecutionPayload reads an Execution Payload stored in EL format, it returns nil if the payload is not available
+// anymore (e.g. eth1 data has been cleared).
+func ReadExecutionPayload(tx kv.Tx, hash common.Hash, number uint64) (*cltypes.ExecutionPayload, error) {
+	header := rawdb2.ReadHeader(tx, hash, number)
+	if header == nil {
+		return nil, nil
+	}
+	payload := &cltypes.ExecutionPayload{
+		ParentHash:    header.ParentHash,
+		FeeRecipient:  header.Coinbase,
+		StateRoot:     header.Root,
+		ReceiptsRoot:  header.ReceiptHash,
+		LogsBloom:     common.CopyBytes(header.Bloom[:]),
+		PrevRandao:    header.MixDigest,
+		BlockNumber:   header.Number.Uint64(),
+		GasLimit:      header.GasLimit,
+		GasUsed:       header.GasUsed,
+		Timestamp:     header.Time,
+		ExtraData:     header.Extra,
+		BaseFeePerGas: make([]byte, 32),
+		BlockHash:     hash,
+		Transactions:  [][]byte{},
+	}
+	// The base fee is stored little endian in the payload.
+	if header.BaseFee != nil {
+		baseFeeBytes := header.BaseFee.Bytes()
+		for i, j := 0, len(baseFeeBytes)-1; i < j; i, j = i+1, j-1 {
+			baseFeeBytes[i], baseFeeBytes[j] = baseFeeBytes[j], baseFeeBytes[i]
+		}
+		copy(payload.BaseFeePerGas, baseFeeBytes)
+	}
+
+	body, err := rawdb2.ReadStorageBody(tx, hash, number)
+	if err != nil {
+		return nil, err
+	}
+	// The first and last transaction ids of a body are reserved for system transactions.
+	if body.TxAmount <= 2 {
+		return payload, nil
+	}
+	if err := tx.ForAmount(kv.EthTx, libcommon.EncodeTs(body.BaseTxId+1), body.TxAmount-2, func(k, v []byte) error {
+		payload.Transactions = append(payload.Transactions, common.CopyBytes(v))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// ReadBlockSlotByBlockRoot returns the slot of the block with the given root, or nil if no such block is indexed.
+func ReadBlockSlotByBlockRoot(tx kv.Getter, blockRoot common.Hash) (*uint64, error) {
+	slotBytes, err := tx.GetOne(kv.RootSlotIndex, blockRoot[:])
+	if err != nil {
+		return nil, err
+	}
+	if len(slotBytes) != 4 {
+		return nil, nil
+	}
+	slot := uint64(binary.BigEndian.Uint32(slotBytes))
+	// State roots and eth1 hashes share the index, make sure this is a block root.
+	_, _, _, eth2Hash, err := ReadBeaconBlockForStorage(tx, slot)
+	if err != nil {
+		return nil, err
+	}
+	if eth2Hash != blockRoot {
+		return nil, nil
+	}
+	return &slot, nil
+}
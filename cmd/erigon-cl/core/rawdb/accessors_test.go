@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/cl/cltypes"
 	"github.com/ledgerwatch/erigon/cl/utils"
 	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/rawdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,20 +30,135 @@ func TestBeaconBlock(t *testing.T) {
 	require.NoError(t, rawdb.WriteBeaconBlock(tx, signedBeaconBlock))
 	newBlock, err := rawdb.ReadBeaconBlock(tx, signedBeaconBlock.Block.Slot)
 	require.NoError(t, err)
-	newBlock.Block.Body.ExecutionPayload = &cltypes.ExecutionPayload{
-		BaseFeePerGas: make([]byte, 32),
-		LogsBloom:     make([]byte, 256),
-	}
+	// The execution payload is stored in EL format and packed back on read.
+	require.Equal(t, signedBeaconBlock.Block.Body.ExecutionPayload, newBlock.Block.Body.ExecutionPayload)
 	newRoot, err := newBlock.HashTreeRoot()
 	require.NoError(t, err)
-	signedBeaconBlock.Block.Body.ExecutionPayload = &cltypes.ExecutionPayload{
-		BaseFeePerGas: make([]byte, 32),
-		LogsBloom:     make([]byte, 256),
-	}
 	root, err := signedBeaconBlock.HashTreeRoot()
 	require.NoError(t, err)
 
 	require.Equal(t, root, newRoot)
+
+	// Once the EL header is gone the block can't be transitioned, it must not be returned without its payload.
+	payload := signedBeaconBlock.Block.Body.ExecutionPayload
+	require.NoError(t, tx.Delete(kv.Headers, dbutils.HeaderKey(payload.BlockNumber, payload.BlockHash)))
+	_, err = rawdb.ReadBeaconBlock(tx, signedBeaconBlock.Block.Slot)
+	require.ErrorIs(t, err, rawdb.ErrExecutionPayloadMissing)
+}
+
+func TestLightClientData(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	branch := func(depth int) [][]byte {
+		branch := make([][]byte, depth)
+		for i := range branch {
+			branch[i] = make([]byte, 32)
+		}
+		return branch
+	}
+	bootstrap := &cltypes.LightClientBootstrap{
+		Header:                     &cltypes.BeaconBlockHeader{Slot: 32},
+		CurrentSyncCommittee:       &cltypes.SyncCommittee{PubKeys: make([][48]byte, 512)},
+		CurrentSyncCommitteeBranch: branch(5),
+	}
+	require.NoError(t, rawdb.WriteLightClientBootstrap(tx, common.Hash{1}, bootstrap))
+	newBootstrap, err := rawdb.ReadLightClientBootstrap(tx, common.Hash{1})
+	require.NoError(t, err)
+	require.Equal(t, bootstrap, newBootstrap)
+	newBootstrap, err = rawdb.ReadLightClientBootstrap(tx, common.Hash{2})
+	require.NoError(t, err)
+	require.Nil(t, newBootstrap)
+
+	// Updates are keyed by the period of the attested header.
+	update := &cltypes.LightClientUpdate{
+		AttestedHeader:          &cltypes.BeaconBlockHeader{Slot: 8191},
+		NextSyncCommitee:        &cltypes.SyncCommittee{PubKeys: make([][48]byte, 512)},
+		NextSyncCommitteeBranch: branch(5),
+		FinalizedHeader:         &cltypes.BeaconBlockHeader{},
+		FinalityBranch:          branch(6),
+		SyncAggregate:           &cltypes.SyncAggregate{SyncCommiteeBits: make([]byte, 64)},
+		SignatureSlot:           8192,
+	}
+	require.NoError(t, rawdb.WriteLightClientUpdate(tx, update))
+	newUpdate, err := rawdb.ReadLightClientUpdate(tx, 0)
+	require.NoError(t, err)
+	require.Equal(t, update, newUpdate)
+	newUpdate, err = rawdb.ReadLightClientUpdate(tx, 1)
+	require.NoError(t, err)
+	require.Nil(t, newUpdate)
+}
+
+func TestLightClientDataMigrationAndPruning(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	branch := func(depth int) [][]byte {
+		branch := make([][]byte, depth)
+		for i := range branch {
+			branch[i] = make([]byte, 32)
+		}
+		return branch
+	}
+	bootstrap := func(slot uint64) *cltypes.LightClientBootstrap {
+		return &cltypes.LightClientBootstrap{
+			Header:                     &cltypes.BeaconBlockHeader{Slot: slot},
+			CurrentSyncCommittee:       &cltypes.SyncCommittee{PubKeys: make([][48]byte, 512)},
+			CurrentSyncCommitteeBranch: branch(5),
+		}
+	}
+	update := &cltypes.LightClientUpdate{
+		AttestedHeader:          &cltypes.BeaconBlockHeader{Slot: 8191},
+		NextSyncCommitee:        &cltypes.SyncCommittee{PubKeys: make([][48]byte, 512)},
+		NextSyncCommitteeBranch: branch(5),
+		FinalizedHeader:         &cltypes.BeaconBlockHeader{},
+		FinalityBranch:          branch(6),
+		SyncAggregate:           &cltypes.SyncAggregate{SyncCommiteeBits: make([]byte, 64)},
+		SignatureSlot:           8192,
+	}
+
+	// The previous layout: bootstraps in the LightClient table, updates keyed by the period of the signature slot.
+	encoded, err := bootstrap(32).MarshalSSZ()
+	require.NoError(t, err)
+	require.NoError(t, tx.Put(kv.LightClient, common.Hash{1}.Bytes(), encoded))
+	encoded, err = update.MarshalSSZ()
+	require.NoError(t, err)
+	require.NoError(t, tx.Put(kv.LightClientUpdates, rawdb.EncodeNumber(1), encoded))
+	require.NoError(t, tx.Put(kv.LightClient, kv.LightClientFinalityUpdate, []byte{1}))
+
+	require.NoError(t, rawdb.MigrateLightClientData(tx))
+	newBootstrap, err := rawdb.ReadLightClientBootstrap(tx, common.Hash{1})
+	require.NoError(t, err)
+	require.Equal(t, bootstrap(32), newBootstrap)
+	old, err := tx.GetOne(kv.LightClient, common.Hash{1}.Bytes())
+	require.NoError(t, err)
+	require.Nil(t, old)
+	old, err = tx.GetOne(kv.LightClient, kv.LightClientFinalityUpdate)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, old)
+	newUpdate, err := rawdb.ReadLightClientUpdate(tx, 0)
+	require.NoError(t, err)
+	require.Equal(t, update, newUpdate)
+	newUpdate, err = rawdb.ReadLightClientUpdate(tx, 1)
+	require.NoError(t, err)
+	require.Nil(t, newUpdate)
+
+	// The migration runs once.
+	require.NoError(t, tx.Put(kv.LightClientUpdates, rawdb.EncodeNumber(1), encoded))
+	require.NoError(t, rawdb.MigrateLightClientData(tx))
+	newUpdate, err = rawdb.ReadLightClientUpdate(tx, 1)
+	require.NoError(t, err)
+	require.Equal(t, update, newUpdate)
+
+	// Pruning drops the bootstraps of the blocks before the slot.
+	require.NoError(t, rawdb.WriteLightClientBootstrap(tx, common.Hash{2}, bootstrap(64)))
+	require.NoError(t, rawdb.WriteLightClientBootstrap(tx, common.Hash{3}, bootstrap(96)))
+	require.NoError(t, rawdb.PruneLightClientBootstraps(tx, 64))
+	for root, slot := range map[common.Hash]uint64{{1}: 0, {2}: 64, {3}: 96} {
+		newBootstrap, err = rawdb.ReadLightClientBootstrap(tx, root)
+		require.NoError(t, err)
+		if slot == 0 {
+			require.Nil(t, newBootstrap)
+		} else {
+			require.Equal(t, bootstrap(slot), newBootstrap)
+		}
+	}
 }
 
 // Benchmarks
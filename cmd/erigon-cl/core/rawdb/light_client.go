@@ -0,0 +1,125 @@
+package rawdb
+
+import (
+	"encoding/binary"
+	"sort"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/utils"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// Tables of the light client bootstraps. erigon-lib doesn't know about them, so they are added to the chaindata
+// tables here.
+const (
+	LightClientBootstraps     = "LightClientBootstraps"     // block root -> bootstrap
+	LightClientBootstrapSlots = "LightClientBootstrapSlots" // slot_u64 + block root -> empty, to prune the bootstraps
+)
+
+func init() {
+	for _, name := range []string{LightClientBootstraps, LightClientBootstrapSlots} {
+		if _, ok := kv.ChaindataTablesCfg[name]; !ok {
+			kv.ChaindataTables = append(kv.ChaindataTables, name)
+			kv.ChaindataTablesCfg[name] = kv.TableCfgItem{}
+		}
+	}
+	sort.Strings(kv.ChaindataTables)
+}
+
+func lightClientBootstrapSlotKey(slot uint64, blockRoot common.Hash) []byte {
+	return append(libcommon.EncodeTs(slot), blockRoot[:]...)
+}
+
+// PruneLightClientBootstraps deletes the bootstraps of the blocks before the slot.
+func PruneLightClientBootstraps(tx kv.RwTx, slot uint64) error {
+	c, err := tx.RwCursor(LightClientBootstrapSlots)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint64(k[:8]) >= slot {
+			break
+		}
+		if err := tx.Delete(LightClientBootstraps, k[8:]); err != nil {
+			return err
+		}
+		if err := c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var lightClientVersionKey = []byte("lightClientVersion")
+
+// MigrateLightClientData moves the data written by the previous versions to the current layout, once:
+// the bootstraps from the LightClient table to their own tables, and the updates from the keys of the period of
+// their signature slot to the period of their attested header, keeping the best update of each period.
+func MigrateLightClientData(tx kv.RwTx) error {
+	version, err := tx.GetOne(kv.DatabaseInfo, lightClientVersionKey)
+	if err != nil {
+		return err
+	}
+	if len(version) > 0 {
+		return nil
+	}
+
+	bootstraps := map[common.Hash]*cltypes.LightClientBootstrap{}
+	if err := tx.ForEach(kv.LightClient, nil, func(k, v []byte) error {
+		// The other keys of the table are names, shorter than roots.
+		if len(k) != length.Hash {
+			return nil
+		}
+		bootstrap := &cltypes.LightClientBootstrap{}
+		if err := bootstrap.UnmarshalSSZ(v); err != nil {
+			return err
+		}
+		bootstraps[common.BytesToHash(k)] = bootstrap
+		return nil
+	}); err != nil {
+		return err
+	}
+	for blockRoot, bootstrap := range bootstraps {
+		if err := tx.Delete(kv.LightClient, blockRoot[:]); err != nil {
+			return err
+		}
+		if err := WriteLightClientBootstrap(tx, blockRoot, bootstrap); err != nil {
+			return err
+		}
+	}
+
+	var updates []*cltypes.LightClientUpdate
+	if err := tx.ForEach(kv.LightClientUpdates, nil, func(k, v []byte) error {
+		update := &cltypes.LightClientUpdate{}
+		if err := update.UnmarshalSSZ(v); err != nil {
+			return err
+		}
+		updates = append(updates, update)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := tx.ClearBucket(kv.LightClientUpdates); err != nil {
+		return err
+	}
+	for _, update := range updates {
+		bestUpdate, err := ReadLightClientUpdate(tx, utils.SlotToPeriod(update.AttestedHeader.Slot))
+		if err != nil {
+			return err
+		}
+		if bestUpdate != nil && !cltypes.IsBetterUpdate(bestUpdate, update) {
+			continue
+		}
+		if err := WriteLightClientUpdate(tx, update); err != nil {
+			return err
+		}
+	}
+	return tx.Put(kv.DatabaseInfo, lightClientVersionKey, []byte{1})
+}
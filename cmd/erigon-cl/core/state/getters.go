@@ -96,6 +96,10 @@ func (b *BeaconState) FinalizedCheckpoint() *cltypes.Checkpoint {
 	return b.finalizedCheckpoint
 }
 
+func (b *BeaconState) InactivityScores() []uint64 {
+	return b.inactivityScores
+}
+
 func (b *BeaconState) CurrentSyncCommittee() *cltypes.SyncCommittee {
 	return b.currentSyncCommittee
 }
@@ -108,9 +112,40 @@ func (b *BeaconState) LatestExecutionPayloadHeader() *cltypes.ExecutionHeader {
 	return b.latestExecutionPayloadHeader
 }
 
+func (b *BeaconState) Version() clparams.StateVersion {
+	return b.version
+}
+
 // GetStateSSZObject allows us to use ssz methods.
 func (b *BeaconState) GetStateSSZObject() cltypes.ObjectSSZ {
 	switch b.version {
+	case clparams.AltairVersion:
+		return &cltypes.BeaconStateAltair{
+			GenesisTime:                 b.genesisTime,
+			GenesisValidatorsRoot:       b.genesisValidatorsRoot,
+			Slot:                        b.slot,
+			Fork:                        b.fork,
+			LatestBlockHeader:           b.latestBlockHeader,
+			BlockRoots:                  b.blockRoots,
+			StateRoots:                  b.stateRoots,
+			HistoricalRoots:             b.historicalRoots,
+			Eth1Data:                    b.eth1Data,
+			Eth1DataVotes:               b.eth1DataVotes,
+			Eth1DepositIndex:            b.eth1DepositIndex,
+			Validators:                  b.validators,
+			Balances:                    b.balances,
+			RandaoMixes:                 b.randaoMixes,
+			Slashings:                   b.slashings,
+			PreviousEpochParticipation:  b.previousEpochParticipation,
+			CurrentEpochParticipation:   b.currentEpochParticipation,
+			JustificationBits:           b.justificationBits,
+			FinalizedCheckpoint:         b.finalizedCheckpoint,
+			CurrentJustifiedCheckpoint:  b.currentJustifiedCheckpoint,
+			PreviousJustifiedCheckpoint: b.previousJustifiedCheckpoint,
+			InactivityScores:            b.inactivityScores,
+			CurrentSyncCommittee:        b.currentSyncCommittee,
+			NextSyncCommittee:           b.nextSyncCommittee,
+		}
 	case clparams.BellatrixVersion:
 		return &cltypes.BeaconStateBellatrix{
 			GenesisTime:                  b.genesisTime,
@@ -31,5 +31,6 @@ const (
 	LatestExecutionPayloadHeaderLeafIndex StateLeafIndex = 24
 
 	// Leaves sizes
+	AltairLeavesSize    = 24
 	BellatrixLeavesSize = 25
 )
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"github.com/ledgerwatch/erigon/cl/clparams"
 	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/state/state_encoding"
 	"github.com/ledgerwatch/erigon/common"
 )
@@ -18,6 +19,27 @@ func (b *BeaconState) HashTreeRoot() ([32]byte, error) {
 	return state_encoding.MerkleRootFromLeaves(currentLayer)
 }
 
+// LeafBranch returns the merkle branch proving the state field at the given index against the state root.
+func (b *BeaconState) LeafBranch(idx StateLeafIndex) ([][]byte, error) {
+	if err := b.computeDirtyLeaves(); err != nil {
+		return nil, err
+	}
+	leaves := make([][32]byte, 32)
+	copy(leaves, b.leaves)
+	return state_encoding.MerkleBranchFromLeaves(leaves, uint64(idx))
+}
+
+// FinalizedRootBranch returns the merkle branch proving the finalized checkpoint root against the state root.
+func (b *BeaconState) FinalizedRootBranch() ([][]byte, error) {
+	checkpointBranch, err := b.LeafBranch(FinalizedCheckpointLeafIndex)
+	if err != nil {
+		return nil, err
+	}
+	// The root is the second field of the checkpoint, its sibling is the epoch.
+	epochRoot := state_encoding.Uint64Root(b.finalizedCheckpoint.Epoch)
+	return append([][]byte{epochRoot[:]}, checkpointBranch...), nil
+}
+
 func (b *BeaconState) computeDirtyLeaves() error {
 	// Update all dirty leafs
 	// ----
@@ -219,6 +241,10 @@ func (b *BeaconState) computeDirtyLeaves() error {
 		b.updateLeaf(NextSyncCommitteeLeafIndex, committeeRoot)
 	}
 
+	if b.version < clparams.BellatrixVersion {
+		return nil
+	}
+
 	// Field(24): LatestExecutionPayloadHeader
 	if b.isLeafDirty(LatestExecutionPayloadHeaderLeafIndex) {
 		headerRoot, err := b.latestExecutionPayloadHeader.HashTreeRoot()
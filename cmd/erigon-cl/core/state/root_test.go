@@ -4,7 +4,9 @@ import (
 	"testing"
 
 	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/utils"
 	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/state"
+	"github.com/stretchr/testify/require"
 )
 
 func getTestBeaconState() *cltypes.BeaconStateBellatrix {
@@ -54,3 +56,50 @@ func BenchmarkStateRootCached(b *testing.B) {
 		state.HashTreeRoot()
 	}
 }
+
+func TestAltairStateRoot(t *testing.T) {
+	bellatrix := getTestBeaconState()
+	altair := &cltypes.BeaconStateAltair{
+		BlockRoots:                  bellatrix.BlockRoots,
+		StateRoots:                  bellatrix.StateRoots,
+		RandaoMixes:                 bellatrix.RandaoMixes,
+		Slashings:                   bellatrix.Slashings,
+		JustificationBits:           bellatrix.JustificationBits,
+		CurrentSyncCommittee:        bellatrix.CurrentSyncCommittee,
+		NextSyncCommittee:           bellatrix.NextSyncCommittee,
+		LatestBlockHeader:           bellatrix.LatestBlockHeader,
+		Fork:                        bellatrix.Fork,
+		Eth1Data:                    bellatrix.Eth1Data,
+		PreviousJustifiedCheckpoint: bellatrix.PreviousJustifiedCheckpoint,
+		CurrentJustifiedCheckpoint:  bellatrix.CurrentJustifiedCheckpoint,
+		FinalizedCheckpoint:         bellatrix.FinalizedCheckpoint,
+	}
+	expected, err := altair.HashTreeRoot()
+	require.NoError(t, err)
+	root, err := state.FromAltairState(altair).HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
+
+func TestStateLeafBranches(t *testing.T) {
+	base := getTestBeaconState()
+	base.CurrentSyncCommittee.AggregatePublicKey = [48]byte{1}
+	base.FinalizedCheckpoint = &cltypes.Checkpoint{Epoch: 3, Root: [32]byte{2}}
+	beaconState := state.FromBellatrixState(base)
+	root, err := beaconState.HashTreeRoot()
+	require.NoError(t, err)
+
+	committeeRoot, err := base.CurrentSyncCommittee.HashTreeRoot()
+	require.NoError(t, err)
+	branch, err := beaconState.LeafBranch(state.CurrentSyncCommitteeLeafIndex)
+	require.NoError(t, err)
+	require.Len(t, branch, 5)
+	require.True(t, utils.IsValidMerkleBranch(committeeRoot, branch, 5, uint64(state.CurrentSyncCommitteeLeafIndex), root))
+
+	// Finalized root generalized index is 105.
+	branch, err = beaconState.FinalizedRootBranch()
+	require.NoError(t, err)
+	require.Len(t, branch, 6)
+	require.True(t, utils.IsValidMerkleBranch(base.FinalizedCheckpoint.Root, branch, 6, 41, root))
+	require.False(t, utils.IsValidMerkleBranch([32]byte{3}, branch, 6, 41, root))
+}
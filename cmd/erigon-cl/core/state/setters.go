@@ -82,21 +82,41 @@ func (b *BeaconState) SetValidators(validators []*cltypes.Validator) {
 	b.validators = validators
 }
 
+func (b *BeaconState) SetValidatorAt(index int, validator *cltypes.Validator) {
+	b.touchedLeaves[ValidatorsLeafIndex] = true
+	b.validators[index] = validator
+}
+
 func (b *BeaconState) SetBalances(balances []uint64) {
 	b.touchedLeaves[BalancesLeafIndex] = true
 	b.balances = balances
 }
 
+func (b *BeaconState) SetBalanceAt(index int, balance uint64) {
+	b.touchedLeaves[BalancesLeafIndex] = true
+	b.balances[index] = balance
+}
+
 func (b *BeaconState) SetRandaoMixes(randaoMixes [][32]byte) {
 	b.touchedLeaves[RandaoMixesLeafIndex] = true
 	b.randaoMixes = randaoMixes
 }
 
+func (b *BeaconState) SetRandaoMixAt(index int, mix [32]byte) {
+	b.touchedLeaves[RandaoMixesLeafIndex] = true
+	b.randaoMixes[index] = mix
+}
+
 func (b *BeaconState) SetSlashings(slashings []uint64) {
 	b.touchedLeaves[SlashingsLeafIndex] = true
 	b.slashings = slashings
 }
 
+func (b *BeaconState) SetSlashingAt(index int, slashing uint64) {
+	b.touchedLeaves[SlashingsLeafIndex] = true
+	b.slashings[index] = slashing
+}
+
 func (b *BeaconState) SetPreviousEpochParticipation(previousEpochParticipation []byte) {
 	b.touchedLeaves[PreviousEpochParticipationLeafIndex] = true
 	b.previousEpochParticipation = previousEpochParticipation
@@ -127,6 +147,11 @@ func (b *BeaconState) SetFinalizedCheckpoint(finalizedCheckpoint *cltypes.Checkp
 	b.finalizedCheckpoint = finalizedCheckpoint
 }
 
+func (b *BeaconState) SetInactivityScores(inactivityScores []uint64) {
+	b.touchedLeaves[InactivityScoresLeafIndex] = true
+	b.inactivityScores = inactivityScores
+}
+
 func (b *BeaconState) SetCurrentSyncCommittee(currentSyncCommittee *cltypes.SyncCommittee) {
 	b.touchedLeaves[CurrentSyncCommitteeLeafIndex] = true
 	b.currentSyncCommittee = currentSyncCommittee
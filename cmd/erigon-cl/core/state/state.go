@@ -41,6 +41,40 @@ type BeaconState struct {
 	touchedLeaves map[StateLeafIndex]bool // Maps each leaf to whether they were touched or not.
 }
 
+// FromAltairState initialize the beacon state as an altair state.
+func FromAltairState(state *cltypes.BeaconStateAltair) *BeaconState {
+	return &BeaconState{
+		genesisTime:                 state.GenesisTime,
+		genesisValidatorsRoot:       state.GenesisValidatorsRoot,
+		slot:                        state.Slot,
+		fork:                        state.Fork,
+		latestBlockHeader:           state.LatestBlockHeader,
+		blockRoots:                  state.BlockRoots,
+		stateRoots:                  state.StateRoots,
+		historicalRoots:             state.HistoricalRoots,
+		eth1Data:                    state.Eth1Data,
+		eth1DataVotes:               state.Eth1DataVotes,
+		eth1DepositIndex:            state.Eth1DepositIndex,
+		validators:                  state.Validators,
+		balances:                    state.Balances,
+		randaoMixes:                 state.RandaoMixes,
+		slashings:                   state.Slashings,
+		previousEpochParticipation:  state.PreviousEpochParticipation,
+		currentEpochParticipation:   state.CurrentEpochParticipation,
+		justificationBits:           state.JustificationBits,
+		previousJustifiedCheckpoint: state.PreviousJustifiedCheckpoint,
+		currentJustifiedCheckpoint:  state.CurrentJustifiedCheckpoint,
+		finalizedCheckpoint:         state.FinalizedCheckpoint,
+		inactivityScores:            state.InactivityScores,
+		currentSyncCommittee:        state.CurrentSyncCommittee,
+		nextSyncCommittee:           state.NextSyncCommittee,
+		// Internals
+		version:       clparams.AltairVersion,
+		leaves:        make([][32]byte, AltairLeavesSize),
+		touchedLeaves: map[StateLeafIndex]bool{},
+	}
+}
+
 // FromBellatrixState initialize the beacon state as a bellatrix state.
 func FromBellatrixState(state *cltypes.BeaconStateBellatrix) *BeaconState {
 	return &BeaconState{
@@ -116,6 +116,30 @@ func MerkleRootFromLeaves(leaves [][32]byte) ([32]byte, error) {
 	return merkleizeTrieLeaves(hashLayer)
 }
 
+// MerkleBranchFromLeaves returns the sibling nodes on the path from the leaf at the given index to the root, from the
+// bottom up.
+func MerkleBranchFromLeaves(leaves [][32]byte, index uint64) ([][]byte, error) {
+	if !utils.IsPowerOf2(uint64(len(leaves))) {
+		return nil, fmt.Errorf("hash layer is a non power of 2: %d", len(leaves))
+	}
+	if index >= uint64(len(leaves)) {
+		return nil, fmt.Errorf("leaf index %d out of range", index)
+	}
+	branch := make([][]byte, 0, getDepth(uint64(len(leaves))))
+	layer := leaves
+	for len(layer) > 1 {
+		sibling := layer[index^1]
+		branch = append(branch, sibling[:])
+		nextLayer := make([][32]byte, len(layer)/2)
+		if err := gohashtree.Hash(nextLayer, layer); err != nil {
+			return nil, err
+		}
+		layer = nextLayer
+		index /= 2
+	}
+	return branch, nil
+}
+
 // getDepth returns the depth of a merkle tree with a given number of nodes.
 // The depth is defined as the number of levels in the tree, with the root
 // node at level 0 and each child node at a level one greater than its parent.
@@ -136,11 +136,11 @@ func GetDomain(state *state.BeaconState, domainType [4]byte, epoch uint64) ([]by
 }
 
 func ComputeSigningRootEpoch(epoch uint64, domain []byte) ([32]byte, error) {
-	b := make([]byte, 32)
-	binary.LittleEndian.PutUint64(b, epoch)
-	hash := utils.Keccak256(b)
+	// The hash tree root of an epoch is its little endian encoding padded to 32 bytes.
+	var root [32]byte
+	binary.LittleEndian.PutUint64(root[:], epoch)
 	sd := &cltypes.SigningData{
-		Root:   hash,
+		Root:   root,
 		Domain: domain,
 	}
 	return sd.HashTreeRoot()
@@ -238,7 +238,7 @@ func ProcessRandao(state *state.BeaconState, body *cltypes.BeaconBody) error {
 	for i := range mix {
 		mix[i] = randaoMixes[i] ^ randaoHash[i]
 	}
-	state.RandaoMixes()[epoch%EPOCHS_PER_HISTORICAL_VECTOR] = mix
+	state.SetRandaoMixAt(int(epoch%EPOCHS_PER_HISTORICAL_VECTOR), mix)
 	return nil
 }
 
@@ -11,8 +11,8 @@ import (
 )
 
 var (
-	testPublicKeyRandao        = [48]byte{185, 225, 110, 228, 192, 192, 246, 253, 101, 180, 140, 141, 199, 89, 3, 139, 210, 238, 189, 151, 158, 72, 157, 8, 214, 152, 37, 190, 211, 42, 55, 195, 204, 105, 233, 224, 95, 87, 116, 69, 238, 39, 49, 151, 145, 131, 41, 97}
-	testSignatureRandao        = [96]byte{176, 25, 98, 55, 102, 11, 185, 56, 3, 22, 58, 233, 230, 117, 206, 195, 18, 104, 110, 224, 140, 189, 89, 124, 247, 110, 34, 24, 137, 244, 55, 83, 15, 8, 202, 57, 11, 60, 112, 92, 226, 219, 183, 237, 167, 236, 67, 192, 11, 6, 2, 84, 137, 7, 71, 232, 61, 13, 111, 99, 125, 174, 150, 122, 177, 219, 187, 234, 12, 60, 229, 15, 199, 29, 125, 30, 238, 123, 23, 138, 38, 232, 248, 31, 234, 1, 149, 77, 70, 111, 237, 19, 7, 35, 209, 236}
+	testPublicKeyRandao        = [48]byte{181, 61, 157, 138, 35, 162, 5, 89, 146, 230, 236, 237, 183, 79, 27, 9, 18, 199, 95, 106, 251, 110, 76, 114, 57, 225, 224, 249, 126, 249, 217, 179, 189, 180, 207, 254, 82, 93, 104, 137, 132, 253, 166, 50, 171, 112, 76, 2}
+	testSignatureRandao        = [96]byte{148, 121, 89, 63, 32, 139, 208, 92, 23, 193, 137, 27, 210, 194, 220, 46, 206, 145, 240, 104, 157, 250, 203, 10, 74, 248, 45, 96, 71, 27, 105, 13, 138, 231, 193, 239, 84, 196, 79, 160, 198, 212, 243, 49, 119, 178, 91, 193, 1, 40, 164, 100, 76, 50, 9, 52, 136, 44, 183, 7, 49, 125, 129, 107, 46, 191, 67, 231, 98, 105, 115, 10, 122, 35, 123, 247, 35, 41, 177, 96, 12, 39, 112, 231, 88, 217, 6, 222, 201, 24, 108, 49, 128, 248, 52, 131}
 	testInvalidSignatureRandao = [96]byte{184, 251, 223, 57, 162, 123, 12, 186, 44, 184, 215, 35, 161, 141, 224, 113, 181, 186, 115, 49, 235, 201, 109, 120, 232, 80, 255, 174, 134, 106, 251, 67, 181, 99, 103, 137, 52, 0, 32, 249, 129, 139, 187, 236, 191, 67, 189, 233, 1, 46, 128, 22, 101, 172, 228, 195, 232, 87, 204, 52, 44, 10, 62, 91, 250, 72, 104, 5, 160, 248, 0, 54, 135, 170, 198, 172, 15, 194, 222, 39, 74, 45, 5, 196, 225, 97, 99, 85, 253, 190, 142, 245, 16, 148, 29, 42}
 )
 
@@ -0,0 +1,88 @@
+package transition
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cl/utils"
+)
+
+// maxShuffledSetsCacheSize bounds the shuffled sets cache, a block only needs the current and previous epoch ones.
+const maxShuffledSetsCacheSize = 4
+
+// shuffleList returns the list permuted by the swap-or-not shuffle, the element at position i of the result is the
+// one at position ComputeShuffledIndex(i) of the input. Rounds are applied in reverse order over the whole list so
+// that every pivot and source hash is computed once per round instead of once per index.
+func shuffleList(input []uint64, seed [32]byte) []uint64 {
+	shuffled := make([]uint64, len(input))
+	copy(shuffled, input)
+	listSize := uint64(len(shuffled))
+	if listSize <= 1 {
+		return shuffled
+	}
+	positionBytes := make([]byte, 4)
+	sources := make([][32]byte, (listSize+255)/256)
+	for round := int(SHUFFLE_ROUND_COUNT) - 1; round >= 0; round-- {
+		roundByte := []byte{uint8(round)}
+		pivotHash := utils.Keccak256(seed[:], roundByte)
+		pivot := binary.LittleEndian.Uint64(pivotHash[:8]) % listSize
+		for i := range sources {
+			binary.LittleEndian.PutUint32(positionBytes, uint32(i))
+			sources[i] = utils.Keccak256(seed[:], roundByte, positionBytes)
+		}
+		for i := uint64(0); i < listSize; i++ {
+			flip := (pivot + listSize - i) % listSize
+			// Each pair is visited twice, swap it only once, from its lowest position.
+			if flip <= i {
+				continue
+			}
+			source := sources[flip/256]
+			if (source[(flip%256)/8]>>(flip%8))&1 == 1 {
+				shuffled[i], shuffled[flip] = shuffled[flip], shuffled[i]
+			}
+		}
+	}
+	return shuffled
+}
+
+// shuffledActiveIndices returns the active validator indices of the epoch shuffled with the attester seed.
+func (s *StateTransistor) shuffledActiveIndices(epoch uint64) []uint64 {
+	var seed [32]byte
+	copy(seed[:], GetSeed(s.state, epoch, s.beaconConfig.DomainBeaconAttester))
+	if shuffled, ok := s.shuffledSetsCache[seed]; ok {
+		return shuffled
+	}
+	shuffled := shuffleList(GetActiveValidatorIndices(s.state, epoch), seed)
+	if len(s.shuffledSetsCache) >= maxShuffledSetsCacheSize {
+		s.shuffledSetsCache = map[[32]byte][]uint64{}
+	}
+	s.shuffledSetsCache[seed] = shuffled
+	return shuffled
+}
+
+// committeeCountPerSlot returns the number of beacon committees of each slot of the epoch.
+func (s *StateTransistor) committeeCountPerSlot(epoch uint64) uint64 {
+	count := uint64(len(s.shuffledActiveIndices(epoch))) / s.beaconConfig.SlotsPerEpoch / s.beaconConfig.TargetCommitteeSize
+	if count > s.beaconConfig.MaxCommitteesPerSlot {
+		return s.beaconConfig.MaxCommitteesPerSlot
+	}
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+// beaconCommittee returns the validator indices of the committee with the given index at the given slot.
+func (s *StateTransistor) beaconCommittee(slot, committeeIndex uint64) ([]uint64, error) {
+	epoch := slot / s.beaconConfig.SlotsPerEpoch
+	committeesPerSlot := s.committeeCountPerSlot(epoch)
+	if committeeIndex >= committeesPerSlot {
+		return nil, fmt.Errorf("committee index %d out of range, slot %d has %d committees", committeeIndex, slot, committeesPerSlot)
+	}
+	shuffled := s.shuffledActiveIndices(epoch)
+	count := committeesPerSlot * s.beaconConfig.SlotsPerEpoch
+	index := (slot%s.beaconConfig.SlotsPerEpoch)*committeesPerSlot + committeeIndex
+	start := uint64(len(shuffled)) * index / count
+	end := uint64(len(shuffled)) * (index + 1) / count
+	return shuffled[start:end], nil
+}
@@ -1,6 +1,9 @@
 package transition
 
 import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cl/clparams"
 	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/state"
 )
 
@@ -13,16 +16,16 @@ const (
 )
 
 func IncreaseBalance(state *state.BeaconState, index, delta uint64) {
-	state.Balances()[index] += delta
+	state.SetBalanceAt(int(index), state.Balances()[index]+delta)
 }
 
 func DecreaseBalance(state *state.BeaconState, index, delta uint64) {
 	curAmount := state.Balances()[index]
 	if curAmount < delta {
-		state.Balances()[index] = 0
+		state.SetBalanceAt(int(index), 0)
 		return
 	}
-	state.Balances()[index] -= delta
+	state.SetBalanceAt(int(index), curAmount-delta)
 }
 
 func ComputeActivationExitEpoch(epoch uint64) uint64 {
@@ -45,14 +48,10 @@ func InitiateValidatorExit(state *state.BeaconState, index uint64) {
 	}
 
 	currentEpoch := GetEpochAtSlot(state.Slot())
-	exitQueueEpoch := currentEpoch
-	activationExitEpoch := ComputeActivationExitEpoch(currentEpoch)
+	exitQueueEpoch := ComputeActivationExitEpoch(currentEpoch)
 	for _, v := range state.Validators() {
-		if v.ExitEpoch != FAR_FUTURE_EPOCH {
-			potentialExit := v.ExitEpoch + activationExitEpoch
-			if potentialExit > exitQueueEpoch {
-				exitQueueEpoch = potentialExit
-			}
+		if v.ExitEpoch != FAR_FUTURE_EPOCH && v.ExitEpoch > exitQueueEpoch {
+			exitQueueEpoch = v.ExitEpoch
 		}
 	}
 
@@ -68,4 +67,38 @@ func InitiateValidatorExit(state *state.BeaconState, index uint64) {
 
 	validator.ExitEpoch = exitQueueEpoch
 	validator.WithdrawableEpoch = exitQueueEpoch + MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+	state.SetValidatorAt(int(index), validator)
+}
+
+// slashValidator slashes the validator and rewards the block proposer and the whistleblower, the whistleblower is the
+// proposer itself when whistleblowerIndex is nil.
+func (s *StateTransistor) slashValidator(slashedIndex uint64, whistleblowerIndex *uint64) error {
+	epoch := s.currentEpoch()
+	InitiateValidatorExit(s.state, slashedIndex)
+	validator := s.state.ValidatorAt(int(slashedIndex))
+	validator.Slashed = true
+	if withdrawableEpoch := epoch + s.beaconConfig.EpochsPerSlashingsVector; withdrawableEpoch > validator.WithdrawableEpoch {
+		validator.WithdrawableEpoch = withdrawableEpoch
+	}
+	s.state.SetValidatorAt(int(slashedIndex), validator)
+	slashingsIndex := epoch % s.beaconConfig.EpochsPerSlashingsVector
+	s.state.SetSlashingAt(int(slashingsIndex), s.state.Slashings()[slashingsIndex]+validator.EffectiveBalance)
+	minSlashingPenaltyQuotient := s.beaconConfig.MinSlashingPenaltyQuotientAltair
+	if s.state.Version() >= clparams.BellatrixVersion {
+		minSlashingPenaltyQuotient = s.beaconConfig.MinSlashingPenaltyQuotientBellatrix
+	}
+	DecreaseBalance(s.state, slashedIndex, validator.EffectiveBalance/minSlashingPenaltyQuotient)
+
+	proposerIndex, err := GetBeaconProposerIndex(s.state)
+	if err != nil {
+		return fmt.Errorf("unable to get proposer index: %v", err)
+	}
+	if whistleblowerIndex == nil {
+		whistleblowerIndex = &proposerIndex
+	}
+	whistleblowerReward := validator.EffectiveBalance / s.beaconConfig.WhistleBlowerRewardQuotient
+	proposerReward := whistleblowerReward * s.beaconConfig.ProposerWeight / s.beaconConfig.WeightDenominator
+	IncreaseBalance(s.state, proposerIndex, proposerReward)
+	IncreaseBalance(s.state, *whistleblowerIndex, whistleblowerReward-proposerReward)
+	return nil
 }
@@ -88,7 +88,8 @@ func TestDecreaseBalance(t *testing.T) {
 }
 
 func TestInitiatieValidatorExit(t *testing.T) {
-	exitDelay := uint64(testExitEpoch + MAX_SEED_LOOKAHEAD + 1)
+	// Other validators exit before the activation exit epoch, so the exit queue starts there.
+	activationExitEpoch := uint64(testExitEpoch + MAX_SEED_LOOKAHEAD + 1)
 	testCases := []struct {
 		description                string
 		numValidators              uint64
@@ -99,8 +100,8 @@ func TestInitiatieValidatorExit(t *testing.T) {
 		{
 			description:                "success",
 			numValidators:              3,
-			expectedExitEpoch:          testExitEpoch + exitDelay,
-			expectedWithdrawlableEpoch: testExitEpoch + exitDelay + MIN_VALIDATOR_WITHDRAWABILITY_DELAY,
+			expectedExitEpoch:          activationExitEpoch,
+			expectedWithdrawlableEpoch: activationExitEpoch + MIN_VALIDATOR_WITHDRAWABILITY_DELAY,
 			validator: &cltypes.Validator{
 				ExitEpoch:       FAR_FUTURE_EPOCH,
 				ActivationEpoch: 0,
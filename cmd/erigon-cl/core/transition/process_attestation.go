@@ -0,0 +1,165 @@
+package transition
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Giulio2002/bls"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/fork"
+	"github.com/ledgerwatch/erigon/cl/utils"
+	"github.com/prysmaticlabs/go-bitfield"
+)
+
+// processAttestations processes the block attestations, the proposer and base rewards are shared by all of them as
+// they do not change within a block.
+func (s *StateTransistor) processAttestations(attestations []*cltypes.Attestation) error {
+	if len(attestations) == 0 {
+		return nil
+	}
+	proposerIndex, err := GetBeaconProposerIndex(s.state)
+	if err != nil {
+		return fmt.Errorf("unable to get proposer index: %v", err)
+	}
+	baseRewardPerIncrement := s.baseRewardPerIncrement(s.totalActiveBalance())
+	for i, attestation := range attestations {
+		if err := s.processAttestation(attestation, proposerIndex, baseRewardPerIncrement); err != nil {
+			return fmt.Errorf("unable to process attestation %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *StateTransistor) processAttestation(attestation *cltypes.Attestation, proposerIndex, baseRewardPerIncrement uint64) error {
+	data := attestation.Data
+	currentEpoch := s.currentEpoch()
+	if data.Target.Epoch != currentEpoch && data.Target.Epoch != s.previousEpoch() {
+		return fmt.Errorf("attestation target epoch %d is neither the current nor the previous epoch", data.Target.Epoch)
+	}
+	if data.Target.Epoch != data.Slot/s.beaconConfig.SlotsPerEpoch {
+		return fmt.Errorf("attestation target epoch %d does not match slot %d", data.Target.Epoch, data.Slot)
+	}
+	stateSlot := s.state.Slot()
+	if data.Slot+s.beaconConfig.MinAttestationInclusionDelay > stateSlot || stateSlot > data.Slot+s.beaconConfig.SlotsPerEpoch {
+		return fmt.Errorf("attestation of slot %d cannot be included at slot %d", data.Slot, stateSlot)
+	}
+	committee, err := s.beaconCommittee(data.Slot, data.Index)
+	if err != nil {
+		return err
+	}
+	aggregationBits := bitfield.Bitlist(attestation.AggregationBits)
+	if aggregationBits.Len() != uint64(len(committee)) {
+		return fmt.Errorf("aggregation bits length %d does not match committee size %d", aggregationBits.Len(), len(committee))
+	}
+	flagIndices, err := s.attestationParticipationFlagIndices(data, stateSlot-data.Slot)
+	if err != nil {
+		return err
+	}
+	attestingIndices := make([]uint64, 0, len(committee))
+	for i, index := range committee {
+		if aggregationBits.BitAt(uint64(i)) {
+			attestingIndices = append(attestingIndices, index)
+		}
+	}
+	sort.Slice(attestingIndices, func(i, j int) bool { return attestingIndices[i] < attestingIndices[j] })
+	if err := s.verifyIndexedAttestation(&cltypes.IndexedAttestation{
+		AttestingIndices: attestingIndices,
+		Data:             data,
+		Signature:        attestation.Signature,
+	}); err != nil {
+		return err
+	}
+
+	// Update participation flags and reward the proposer for each newly set one.
+	participation := s.state.PreviousEpochParticipation()
+	if data.Target.Epoch == currentEpoch {
+		participation = s.state.CurrentEpochParticipation()
+	}
+	proposerRewardNumerator := uint64(0)
+	for _, index := range attestingIndices {
+		baseReward := s.state.ValidatorAt(int(index)).EffectiveBalance / s.beaconConfig.EffectiveBalanceIncrement * baseRewardPerIncrement
+		for _, flagIndex := range flagIndices {
+			if hasFlag(participation[index], flagIndex) {
+				continue
+			}
+			participation[index] |= 1 << flagIndex
+			proposerRewardNumerator += baseReward * s.participationFlagWeight(flagIndex)
+		}
+	}
+	if data.Target.Epoch == currentEpoch {
+		s.state.SetCurrentEpochParticipation(participation)
+	} else {
+		s.state.SetPreviousEpochParticipation(participation)
+	}
+	proposerRewardDenominator := (s.beaconConfig.WeightDenominator - s.beaconConfig.ProposerWeight) * s.beaconConfig.WeightDenominator / s.beaconConfig.ProposerWeight
+	IncreaseBalance(s.state, proposerIndex, proposerRewardNumerator/proposerRewardDenominator)
+	return nil
+}
+
+// attestationParticipationFlagIndices returns the participation flags earned by an attestation included with the
+// given delay, it fails if the attestation source is not the justified checkpoint.
+func (s *StateTransistor) attestationParticipationFlagIndices(data *cltypes.AttestationData, inclusionDelay uint64) ([]uint8, error) {
+	justifiedCheckpoint := s.state.PreviousJustifiedCheckpoint()
+	if data.Target.Epoch == s.currentEpoch() {
+		justifiedCheckpoint = s.state.CurrentJustifiedCheckpoint()
+	}
+	if data.Source.Epoch != justifiedCheckpoint.Epoch || data.Source.Root != justifiedCheckpoint.Root {
+		return nil, fmt.Errorf("attestation source does not match justified checkpoint at epoch %d", justifiedCheckpoint.Epoch)
+	}
+	targetRoot, err := s.blockRootAtSlot(data.Target.Epoch * s.beaconConfig.SlotsPerEpoch)
+	if err != nil {
+		return nil, err
+	}
+	headRoot, err := s.blockRootAtSlot(data.Slot)
+	if err != nil {
+		return nil, err
+	}
+	isMatchingTarget := data.Target.Root == targetRoot
+	isMatchingHead := isMatchingTarget && data.BeaconBlockHash == headRoot
+
+	flagIndices := []uint8{}
+	if inclusionDelay <= utils.IntegerSquareRoot(s.beaconConfig.SlotsPerEpoch) {
+		flagIndices = append(flagIndices, s.beaconConfig.TimelySourceFlagIndex)
+	}
+	if isMatchingTarget && inclusionDelay <= s.beaconConfig.SlotsPerEpoch {
+		flagIndices = append(flagIndices, s.beaconConfig.TimelyTargetFlagIndex)
+	}
+	if isMatchingHead && inclusionDelay == s.beaconConfig.MinAttestationInclusionDelay {
+		flagIndices = append(flagIndices, s.beaconConfig.TimelyHeadFlagIndex)
+	}
+	return flagIndices, nil
+}
+
+// verifyIndexedAttestation checks the attesting indices are sorted and unique and verifies the aggregate signature.
+func (s *StateTransistor) verifyIndexedAttestation(attestation *cltypes.IndexedAttestation) error {
+	indices := attestation.AttestingIndices
+	if len(indices) == 0 {
+		return fmt.Errorf("indexed attestation has no attesting indices")
+	}
+	pubKeys := make([][]byte, 0, len(indices))
+	for i, index := range indices {
+		if i > 0 && indices[i-1] >= index {
+			return fmt.Errorf("attesting indices are not sorted and unique")
+		}
+		if index >= uint64(len(s.state.Validators())) {
+			return fmt.Errorf("attesting index %d out of range", index)
+		}
+		pubKeys = append(pubKeys, s.state.ValidatorAt(int(index)).PublicKey[:])
+	}
+	domain, err := s.getDomain(s.beaconConfig.DomainBeaconAttester, attestation.Data.Target.Epoch)
+	if err != nil {
+		return fmt.Errorf("unable to get domain: %v", err)
+	}
+	signingRoot, err := fork.ComputeSigningRoot(attestation.Data, domain)
+	if err != nil {
+		return fmt.Errorf("unable to compute signing root: %v", err)
+	}
+	valid, err := bls.VerifyAggregate(attestation.Signature[:], signingRoot[:], pubKeys)
+	if err != nil {
+		return fmt.Errorf("unable to verify aggregate signature: %v", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid aggregate signature")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package transition
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+)
+
+func (s *StateTransistor) processAttesterSlashing(slashing *cltypes.AttesterSlashing) error {
+	attestation1, attestation2 := slashing.Attestation_1, slashing.Attestation_2
+	slashable, err := isSlashableAttestationData(attestation1.Data, attestation2.Data)
+	if err != nil {
+		return err
+	}
+	if !slashable {
+		return fmt.Errorf("attestation data is not slashable")
+	}
+	if err := s.verifyIndexedAttestation(attestation1); err != nil {
+		return fmt.Errorf("invalid first attestation: %v", err)
+	}
+	if err := s.verifyIndexedAttestation(attestation2); err != nil {
+		return fmt.Errorf("invalid second attestation: %v", err)
+	}
+
+	currentEpoch := s.currentEpoch()
+	attesters2 := make(map[uint64]struct{}, len(attestation2.AttestingIndices))
+	for _, index := range attestation2.AttestingIndices {
+		attesters2[index] = struct{}{}
+	}
+	slashedAny := false
+	// Attesting indices of a valid indexed attestation are sorted, so the intersection is slashed in ascending order.
+	for _, index := range attestation1.AttestingIndices {
+		if _, ok := attesters2[index]; !ok {
+			continue
+		}
+		if !isSlashableValidator(s.state.ValidatorAt(int(index)), currentEpoch) {
+			continue
+		}
+		if err := s.slashValidator(index, nil); err != nil {
+			return err
+		}
+		slashedAny = true
+	}
+	if !slashedAny {
+		return fmt.Errorf("no validator was slashed")
+	}
+	return nil
+}
+
+// isSlashableAttestationData tells whether the two attestations are a double vote or one surrounds the other.
+func isSlashableAttestationData(data1, data2 *cltypes.AttestationData) (bool, error) {
+	root1, err := data1.HashTreeRoot()
+	if err != nil {
+		return false, fmt.Errorf("unable to hash attestation data: %v", err)
+	}
+	root2, err := data2.HashTreeRoot()
+	if err != nil {
+		return false, fmt.Errorf("unable to hash attestation data: %v", err)
+	}
+	isDoubleVote := root1 != root2 && data1.Target.Epoch == data2.Target.Epoch
+	isSurroundVote := data1.Source.Epoch < data2.Source.Epoch && data2.Target.Epoch < data1.Target.Epoch
+	return isDoubleVote || isSurroundVote, nil
+}
@@ -0,0 +1,103 @@
+package transition
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/fork"
+)
+
+// processBlock applies the block on top of the state, which must have already been advanced to the block slot.
+func (s *StateTransistor) processBlock(block *cltypes.BeaconBlock) error {
+	if block.Version() == clparams.Phase0Version {
+		return ErrPhase0NotSupported
+	}
+	if block.Version() != s.state.Version() {
+		return fmt.Errorf("block version %d does not match state version %d", block.Version(), s.state.Version())
+	}
+	if err := ProcessBlockHeader(s.state, block); err != nil {
+		return fmt.Errorf("unable to process block header: %v", err)
+	}
+	if s.state.Version() >= clparams.BellatrixVersion {
+		executionEnabled, err := s.isExecutionEnabled(block.Body.ExecutionPayload)
+		if err != nil {
+			return err
+		}
+		if executionEnabled {
+			if err := s.processExecutionPayload(block.Body.ExecutionPayload); err != nil {
+				return fmt.Errorf("unable to process execution payload: %v", err)
+			}
+		}
+	}
+	if err := ProcessRandao(s.state, block.Body); err != nil {
+		return fmt.Errorf("unable to process randao reveal: %v", err)
+	}
+	if err := ProcessEth1Data(s.state, block.Body); err != nil {
+		return fmt.Errorf("unable to process eth1 data: %v", err)
+	}
+	if err := s.processOperations(block.Body); err != nil {
+		return err
+	}
+	if err := s.processSyncAggregate(block.Body.SyncAggregate); err != nil {
+		return fmt.Errorf("unable to process sync aggregate: %v", err)
+	}
+	return nil
+}
+
+func (s *StateTransistor) processOperations(body *cltypes.BeaconBody) error {
+	// All pending deposits, up to the block limit, must be included.
+	expectedDeposits := uint64(0)
+	if depositCount, depositIndex := s.state.Eth1Data().DepositCount, s.state.Eth1DepositIndex(); depositCount > depositIndex {
+		expectedDeposits = depositCount - depositIndex
+	}
+	if expectedDeposits > s.beaconConfig.MaxDeposits {
+		expectedDeposits = s.beaconConfig.MaxDeposits
+	}
+	if uint64(len(body.Deposits)) != expectedDeposits {
+		return fmt.Errorf("block has %d deposits, expected %d", len(body.Deposits), expectedDeposits)
+	}
+
+	for i, slashing := range body.ProposerSlashings {
+		if err := s.processProposerSlashing(slashing); err != nil {
+			return fmt.Errorf("unable to process proposer slashing %d: %v", i, err)
+		}
+	}
+	for i, slashing := range body.AttesterSlashings {
+		if err := s.processAttesterSlashing(slashing); err != nil {
+			return fmt.Errorf("unable to process attester slashing %d: %v", i, err)
+		}
+	}
+	if err := s.processAttestations(body.Attestations); err != nil {
+		return err
+	}
+	for i, deposit := range body.Deposits {
+		if err := s.processDeposit(deposit); err != nil {
+			return fmt.Errorf("unable to process deposit %d: %v", i, err)
+		}
+	}
+	for i, exit := range body.VoluntaryExits {
+		if err := s.processVoluntaryExit(exit); err != nil {
+			return fmt.Errorf("unable to process voluntary exit %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// getDomain returns the signature domain of the given type at the given epoch, based on the state fork.
+func (s *StateTransistor) getDomain(domainType [4]byte, epoch uint64) ([]byte, error) {
+	forkVersion := s.state.Fork().CurrentVersion
+	if epoch < s.state.Fork().Epoch {
+		forkVersion = s.state.Fork().PreviousVersion
+	}
+	return fork.ComputeDomain(domainType[:], forkVersion, s.state.GenesisValidatorsRoot())
+}
+
+// blockRootAtSlot returns the block root at a past slot which is still in the state block roots.
+func (s *StateTransistor) blockRootAtSlot(slot uint64) ([32]byte, error) {
+	stateSlot := s.state.Slot()
+	if slot >= stateSlot || stateSlot > slot+s.beaconConfig.SlotsPerHistoricalRoot {
+		return [32]byte{}, fmt.Errorf("block root of slot %d is not available at slot %d", slot, stateSlot)
+	}
+	return s.state.BlockRoots()[slot%s.beaconConfig.SlotsPerHistoricalRoot], nil
+}
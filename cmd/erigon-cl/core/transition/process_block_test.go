@@ -0,0 +1,467 @@
+package transition
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/fork"
+	"github.com/ledgerwatch/erigon/cl/utils"
+	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/state"
+	"github.com/stretchr/testify/require"
+	blst "github.com/supranational/blst/bindings/go"
+	"gopkg.in/yaml.v2"
+)
+
+var testSignatureDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+func getTestSecretKey(index int) *blst.SecretKey {
+	ikm := sha256.Sum256(utils.Uint64ToLE(uint64(index)))
+	return blst.KeyGen(ikm[:])
+}
+
+func testSign(index int, root [32]byte) [96]byte {
+	var signature [96]byte
+	copy(signature[:], new(blst.P2Affine).Sign(getTestSecretKey(index), root[:], testSignatureDST).Compress())
+	return signature
+}
+
+func testAggregateSign(indices []uint64, root [32]byte) [96]byte {
+	signatures := make([]*blst.P2Affine, len(indices))
+	for i, index := range indices {
+		signatures[i] = new(blst.P2Affine).Sign(getTestSecretKey(int(index)), root[:], testSignatureDST)
+	}
+	aggregate := new(blst.P2Aggregate)
+	aggregate.Aggregate(signatures, false)
+	var signature [96]byte
+	copy(signature[:], aggregate.ToAffine().Compress())
+	return signature
+}
+
+// getTestBlockState returns a bellatrix state with active validators holding known keys, the sync committee is made
+// of the validators in order.
+func getTestBlockState(t *testing.T, numValidators int, slot uint64) *state.BeaconState {
+	st := getTestEpochState(numValidators, slot)
+	for i, validator := range st.Validators() {
+		copy(validator.PublicKey[:], new(blst.P1Affine).From(getTestSecretKey(i)).Compress())
+	}
+	syncCommittee := &cltypes.SyncCommittee{PubKeys: make([][48]byte, clparams.MainnetBeaconConfig.SyncCommitteeSize)}
+	for i := range syncCommittee.PubKeys {
+		syncCommittee.PubKeys[i] = st.ValidatorAt(i % numValidators).PublicKey
+	}
+	st.SetCurrentSyncCommittee(syncCommittee)
+	st.SetNextSyncCommittee(syncCommittee)
+	st.SetLatestExecutionPayloadHeader(&cltypes.ExecutionHeader{
+		LogsBloom:     make([]byte, 256),
+		BaseFeePerGas: make([]byte, 32),
+	})
+	st.SetValidators(st.Validators())
+	return st
+}
+
+func testSigningRoot(t *testing.T, s *StateTransistor, obj cltypes.ObjectSSZ, domainType [4]byte, epoch uint64) [32]byte {
+	domain, err := s.getDomain(domainType, epoch)
+	require.NoError(t, err)
+	root, err := fork.ComputeSigningRoot(obj, domain)
+	require.NoError(t, err)
+	return root
+}
+
+func TestShuffleList(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 3, 10, 100, 300, 1000} {
+		seed := [32]byte{byte(size), 42}
+		input := make([]uint64, size)
+		for i := range input {
+			input[i] = uint64(rand.Int63())
+		}
+		shuffled := shuffleList(input, seed)
+		require.Len(t, shuffled, size)
+		for i := range input {
+			index, err := ComputeShuffledIndex(uint64(i), uint64(size), seed)
+			require.NoError(t, err)
+			require.Equal(t, input[index], shuffled[i], "size %d, position %d", size, i)
+		}
+	}
+}
+
+func TestPayloadHeaderRoot(t *testing.T) {
+	payload := &cltypes.ExecutionPayload{
+		ParentHash:    [32]byte{1},
+		LogsBloom:     make([]byte, 256),
+		BlockNumber:   42,
+		ExtraData:     []byte{1, 2, 3},
+		BaseFeePerGas: make([]byte, 32),
+		Transactions:  [][]byte{{1, 2}, make([]byte, 100)},
+	}
+	header, err := payload.PayloadHeader()
+	require.NoError(t, err)
+	// A header commits to the transactions by their root, so both hash to the same root.
+	payloadRoot, err := payload.HashTreeRoot()
+	require.NoError(t, err)
+	headerRoot, err := header.HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, payloadRoot, headerRoot)
+}
+
+func TestProcessAttestation(t *testing.T) {
+	st := getTestBlockState(t, 64, 33)
+	st.BlockRoots()[32] = [32]byte{7}
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	committee, err := s.beaconCommittee(32, 0)
+	require.NoError(t, err)
+	// 64 validators make a single committee of 2 validators per slot.
+	require.Len(t, committee, 2)
+
+	data := &cltypes.AttestationData{
+		Slot:            32,
+		Index:           0,
+		BeaconBlockHash: [32]byte{7},
+		Source:          &cltypes.Checkpoint{},
+		Target:          &cltypes.Checkpoint{Epoch: 1, Root: [32]byte{7}},
+	}
+	attestation := &cltypes.Attestation{
+		AggregationBits: []byte{0b111},
+		Data:            data,
+		Signature:       testAggregateSign(committee, testSigningRoot(t, s, data, clparams.MainnetBeaconConfig.DomainBeaconAttester, 1)),
+	}
+	proposerIndex, err := GetBeaconProposerIndex(st)
+	require.NoError(t, err)
+	proposerBalance := st.Balances()[proposerIndex]
+
+	require.NoError(t, s.processAttestations([]*cltypes.Attestation{attestation}))
+	for _, index := range committee {
+		// Timely source, target and head.
+		require.Equal(t, byte(0b111), st.CurrentEpochParticipation()[index])
+	}
+	require.Equal(t, proposerBalance+344990, st.Balances()[proposerIndex])
+
+	// Flags are already set, including the attestation again does not reward the proposer.
+	require.NoError(t, s.processAttestations([]*cltypes.Attestation{attestation}))
+	require.Equal(t, proposerBalance+344990, st.Balances()[proposerIndex])
+
+	badSignature := *attestation
+	badSignature.AggregationBits = []byte{0b101}
+	require.Error(t, s.processAttestations([]*cltypes.Attestation{&badSignature}))
+	badSource := *attestation
+	badSource.Data = &cltypes.AttestationData{Slot: 32, Source: &cltypes.Checkpoint{Epoch: 1}, Target: data.Target}
+	require.Error(t, s.processAttestations([]*cltypes.Attestation{&badSource}))
+}
+
+func TestProcessDeposit(t *testing.T) {
+	st := getTestBlockState(t, 64, 33)
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	depositMessage := &cltypes.DepositMessage{
+		WithdrawalCredentials: make([]byte, 32),
+		Amount:                clparams.MainnetBeaconConfig.MaxEffectiveBalance,
+	}
+	copy(depositMessage.PubKey[:], new(blst.P1Affine).From(getTestSecretKey(64)).Compress())
+	domainType := clparams.MainnetBeaconConfig.DomainDeposit
+	domain, err := fork.ComputeDomain(domainType[:], [4]byte{}, [32]byte{})
+	require.NoError(t, err)
+	signingRoot, err := fork.ComputeSigningRoot(depositMessage, domain)
+	require.NoError(t, err)
+	depositData := &cltypes.DepositData{
+		PubKey:                depositMessage.PubKey,
+		WithdrawalCredentials: depositMessage.WithdrawalCredentials,
+		Amount:                depositMessage.Amount,
+		Signature:             testSign(64, signingRoot),
+	}
+
+	// Deposit tree with a single leaf, the last branch is the deposit count mix-in.
+	leaf, err := depositData.HashTreeRoot()
+	require.NoError(t, err)
+	proof := make([][]byte, clparams.MainnetBeaconConfig.DepositContractTreeDepth+1)
+	depositRoot := leaf
+	zeroHash := [32]byte{}
+	for i := 0; i < len(proof)-1; i++ {
+		proof[i] = append([]byte{}, zeroHash[:]...)
+		depositRoot = utils.Keccak256(depositRoot[:], zeroHash[:])
+		zeroHash = utils.Keccak256(zeroHash[:], zeroHash[:])
+	}
+	proof[len(proof)-1] = make([]byte, 32)
+	binary.LittleEndian.PutUint64(proof[len(proof)-1], 1)
+	depositRoot = utils.Keccak256(depositRoot[:], proof[len(proof)-1])
+	st.SetEth1Data(&cltypes.Eth1Data{Root: depositRoot, DepositCount: 1})
+
+	badProof := &cltypes.Deposit{Proof: proof[1:], Data: depositData}
+	require.Error(t, s.processDeposit(badProof))
+	require.NoError(t, s.processDeposit(&cltypes.Deposit{Proof: proof, Data: depositData}))
+	require.Equal(t, uint64(1), st.Eth1DepositIndex())
+	require.Len(t, st.Validators(), 65)
+	require.Equal(t, &cltypes.Validator{
+		PublicKey:                  depositData.PubKey,
+		WithdrawalCredentials:      depositData.WithdrawalCredentials,
+		EffectiveBalance:           clparams.MainnetBeaconConfig.MaxEffectiveBalance,
+		ActivationEligibilityEpoch: clparams.MainnetBeaconConfig.FarFutureEpoch,
+		ActivationEpoch:            clparams.MainnetBeaconConfig.FarFutureEpoch,
+		ExitEpoch:                  clparams.MainnetBeaconConfig.FarFutureEpoch,
+		WithdrawableEpoch:          clparams.MainnetBeaconConfig.FarFutureEpoch,
+	}, st.ValidatorAt(64))
+	require.Equal(t, clparams.MainnetBeaconConfig.MaxEffectiveBalance, st.Balances()[64])
+	require.Len(t, st.PreviousEpochParticipation(), 65)
+	require.Len(t, st.CurrentEpochParticipation(), 65)
+	require.Len(t, st.InactivityScores(), 65)
+
+	// A deposit of a known public key tops up the balance, without checking the signature.
+	depositData.Signature = [96]byte{}
+	require.NoError(t, s.applyDeposit(depositData))
+	require.Len(t, st.Validators(), 65)
+	require.Equal(t, 2*clparams.MainnetBeaconConfig.MaxEffectiveBalance, st.Balances()[64])
+
+	// A new validator with an invalid signature is skipped.
+	depositData.PubKey = st.ValidatorAt(0).PublicKey
+	depositData.PubKey[0] ^= 1
+	require.NoError(t, s.applyDeposit(depositData))
+	require.Len(t, st.Validators(), 65)
+}
+
+func TestProcessVoluntaryExit(t *testing.T) {
+	epoch := clparams.MainnetBeaconConfig.ShardCommitteePeriod
+	st := getTestBlockState(t, 64, epoch*SLOTS_PER_EPOCH)
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	exit := &cltypes.VoluntaryExit{Epoch: epoch, ValidatorIndex: 3}
+	signedExit := &cltypes.SignedVoluntaryExit{
+		VolunaryExit: exit,
+		Signature:    testSign(3, testSigningRoot(t, s, exit, clparams.MainnetBeaconConfig.DomainVoluntaryExit, epoch)),
+	}
+	badSignature := &cltypes.SignedVoluntaryExit{VolunaryExit: exit, Signature: testSign(4, [32]byte{})}
+	require.Error(t, s.processVoluntaryExit(badSignature))
+	require.NoError(t, s.processVoluntaryExit(signedExit))
+	exitEpoch := ComputeActivationExitEpoch(epoch)
+	require.Equal(t, exitEpoch, st.ValidatorAt(3).ExitEpoch)
+	require.Equal(t, exitEpoch+clparams.MainnetBeaconConfig.MinValidatorWithdrawabilityDelay, st.ValidatorAt(3).WithdrawableEpoch)
+	// Exits cannot be initiated twice.
+	require.Error(t, s.processVoluntaryExit(signedExit))
+
+	// Validators must be active for the shard committee period before exiting.
+	st = getTestBlockState(t, 64, (epoch-1)*SLOTS_PER_EPOCH)
+	s = New(st, &clparams.MainnetBeaconConfig, nil)
+	exit = &cltypes.VoluntaryExit{Epoch: epoch - 1, ValidatorIndex: 3}
+	signedExit = &cltypes.SignedVoluntaryExit{
+		VolunaryExit: exit,
+		Signature:    testSign(3, testSigningRoot(t, s, exit, clparams.MainnetBeaconConfig.DomainVoluntaryExit, epoch-1)),
+	}
+	require.Error(t, s.processVoluntaryExit(signedExit))
+}
+
+func TestProcessProposerSlashing(t *testing.T) {
+	epoch := uint64(3)
+	st := getTestBlockState(t, 64, epoch*SLOTS_PER_EPOCH+1)
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	proposerIndex, err := GetBeaconProposerIndex(st)
+	require.NoError(t, err)
+	slashedIndex := (proposerIndex + 1) % 64
+
+	header1 := &cltypes.BeaconBlockHeader{Slot: epoch * SLOTS_PER_EPOCH, ProposerIndex: slashedIndex, BodyRoot: [32]byte{1}}
+	header2 := &cltypes.BeaconBlockHeader{Slot: epoch * SLOTS_PER_EPOCH, ProposerIndex: slashedIndex, BodyRoot: [32]byte{2}}
+	slashing := &cltypes.ProposerSlashing{
+		Header1: &cltypes.SignedBeaconBlockHeader{
+			Header:    header1,
+			Signature: testSign(int(slashedIndex), testSigningRoot(t, s, header1, clparams.MainnetBeaconConfig.DomainBeaconProposer, epoch)),
+		},
+		Header2: &cltypes.SignedBeaconBlockHeader{
+			Header:    header2,
+			Signature: testSign(int(slashedIndex), testSigningRoot(t, s, header2, clparams.MainnetBeaconConfig.DomainBeaconProposer, epoch)),
+		},
+	}
+	sameHeaders := &cltypes.ProposerSlashing{Header1: slashing.Header1, Header2: slashing.Header1}
+	require.Error(t, s.processProposerSlashing(sameHeaders))
+	require.NoError(t, s.processProposerSlashing(slashing))
+
+	maxBalance := clparams.MainnetBeaconConfig.MaxEffectiveBalance
+	validator := st.ValidatorAt(int(slashedIndex))
+	require.True(t, validator.Slashed)
+	require.Equal(t, ComputeActivationExitEpoch(epoch), validator.ExitEpoch)
+	require.Equal(t, epoch+clparams.MainnetBeaconConfig.EpochsPerSlashingsVector, validator.WithdrawableEpoch)
+	require.Equal(t, maxBalance, st.Slashings()[epoch])
+	// The minimum penalty is 1/32 of the effective balance, the proposer is also the whistleblower and gets 1/512.
+	require.Equal(t, maxBalance-maxBalance/32, st.Balances()[slashedIndex])
+	require.Equal(t, maxBalance+maxBalance/512, st.Balances()[proposerIndex])
+	// Slashed validators cannot be slashed again.
+	require.Error(t, s.processProposerSlashing(slashing))
+}
+
+func TestProcessSyncAggregate(t *testing.T) {
+	st := getTestBlockState(t, 64, 1)
+	st.BlockRoots()[0] = [32]byte{3}
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	proposerIndex, err := GetBeaconProposerIndex(st)
+	require.NoError(t, err)
+	maxBalance := clparams.MainnetBeaconConfig.MaxEffectiveBalance
+	// Each validator holds 8 of the 512 seats of the sync committee.
+	participantReward, proposerReward := uint64(174), uint64(24)
+
+	// Nobody signed, every member is penalized.
+	require.NoError(t, s.processSyncAggregate(&cltypes.SyncAggregate{
+		SyncCommiteeBits:      make([]byte, 64),
+		SyncCommiteeSignature: infiniteSignature,
+	}))
+	for index := range st.Validators() {
+		require.Equal(t, maxBalance-8*participantReward, st.Balances()[index])
+	}
+	require.Error(t, s.processSyncAggregate(&cltypes.SyncAggregate{SyncCommiteeBits: make([]byte, 64)}))
+
+	// The whole committee signed the previous block root.
+	st = getTestBlockState(t, 64, 1)
+	st.BlockRoots()[0] = [32]byte{3}
+	s = New(st, &clparams.MainnetBeaconConfig, nil)
+	domain, err := s.getDomain(clparams.MainnetBeaconConfig.DomainSyncCommittee, 0)
+	require.NoError(t, err)
+	signingRoot, err := (&cltypes.SigningData{Root: [32]byte{3}, Domain: domain}).HashTreeRoot()
+	require.NoError(t, err)
+	members := make([]uint64, clparams.MainnetBeaconConfig.SyncCommitteeSize)
+	for i := range members {
+		members[i] = uint64(i % 64)
+	}
+	bits := make([]byte, 64)
+	for i := range bits {
+		bits[i] = 0xff
+	}
+	require.NoError(t, s.processSyncAggregate(&cltypes.SyncAggregate{
+		SyncCommiteeBits:      bits,
+		SyncCommiteeSignature: testAggregateSign(members, signingRoot),
+	}))
+	for index := range st.Validators() {
+		expected := maxBalance + 8*participantReward
+		if uint64(index) == proposerIndex {
+			expected += 512 * proposerReward
+		}
+		require.Equal(t, expected, st.Balances()[index])
+	}
+}
+
+func readSpecTestObject(t *testing.T, path string, obj cltypes.ObjectSSZ) {
+	compressed, err := os.ReadFile(path)
+	require.NoError(t, err)
+	encoded, err := snappy.Decode(nil, compressed)
+	require.NoError(t, err)
+	require.NoError(t, obj.UnmarshalSSZ(encoded))
+}
+
+// operationsTestMeta holds the optional settings of an operations test case.
+type operationsTestMeta struct {
+	// BlsSetting 2 means signatures are not meant to be verified, we always verify them.
+	BlsSetting int `yaml:"bls_setting"`
+	// ExecutionValid is the result of the execution engine verification of the payload.
+	ExecutionValid *bool `yaml:"execution_valid"`
+}
+
+func TestProcessExecutionPayloadMissing(t *testing.T) {
+	testState := getTestEpochState(4, clparams.MainnetBeaconConfig.SlotsPerEpoch)
+	// A non empty payload header completes the merge, so execution is enabled whatever the block holds.
+	testState.SetLatestExecutionPayloadHeader(&cltypes.ExecutionHeader{
+		BlockHash:     [32]byte{1},
+		LogsBloom:     make([]byte, 256),
+		BaseFeePerGas: make([]byte, 32),
+	})
+	s := New(testState, &clparams.MainnetBeaconConfig, nil)
+	enabled, err := s.isExecutionEnabled(nil)
+	require.NoError(t, err)
+	require.True(t, enabled)
+	require.Error(t, s.processExecutionPayload(nil))
+}
+
+func TestOperationsSpecTests(t *testing.T) {
+	handlers := map[string]func(t *testing.T, s *StateTransistor, caseDir string, version clparams.StateVersion) error{
+		"attestation": func(t *testing.T, s *StateTransistor, caseDir string, _ clparams.StateVersion) error {
+			attestation := &cltypes.Attestation{}
+			readSpecTestObject(t, filepath.Join(caseDir, "attestation.ssz_snappy"), attestation)
+			return s.processAttestations([]*cltypes.Attestation{attestation})
+		},
+		"attester_slashing": func(t *testing.T, s *StateTransistor, caseDir string, _ clparams.StateVersion) error {
+			slashing := &cltypes.AttesterSlashing{}
+			readSpecTestObject(t, filepath.Join(caseDir, "attester_slashing.ssz_snappy"), slashing)
+			return s.processAttesterSlashing(slashing)
+		},
+		"block_header": func(t *testing.T, s *StateTransistor, caseDir string, version clparams.StateVersion) error {
+			var block cltypes.ObjectSSZ = &cltypes.BeaconBlockAltair{}
+			if version == clparams.BellatrixVersion {
+				block = &cltypes.BeaconBlockBellatrix{}
+			}
+			readSpecTestObject(t, filepath.Join(caseDir, "block.ssz_snappy"), block)
+			return ProcessBlockHeader(s.state, cltypes.NewBeaconBlock(block))
+		},
+		"deposit": func(t *testing.T, s *StateTransistor, caseDir string, _ clparams.StateVersion) error {
+			deposit := &cltypes.Deposit{}
+			readSpecTestObject(t, filepath.Join(caseDir, "deposit.ssz_snappy"), deposit)
+			return s.processDeposit(deposit)
+		},
+		"proposer_slashing": func(t *testing.T, s *StateTransistor, caseDir string, _ clparams.StateVersion) error {
+			slashing := &cltypes.ProposerSlashing{}
+			readSpecTestObject(t, filepath.Join(caseDir, "proposer_slashing.ssz_snappy"), slashing)
+			return s.processProposerSlashing(slashing)
+		},
+		"voluntary_exit": func(t *testing.T, s *StateTransistor, caseDir string, _ clparams.StateVersion) error {
+			exit := &cltypes.SignedVoluntaryExit{}
+			readSpecTestObject(t, filepath.Join(caseDir, "voluntary_exit.ssz_snappy"), exit)
+			return s.processVoluntaryExit(exit)
+		},
+		"sync_aggregate": func(t *testing.T, s *StateTransistor, caseDir string, _ clparams.StateVersion) error {
+			aggregate := &cltypes.SyncAggregate{}
+			readSpecTestObject(t, filepath.Join(caseDir, "sync_aggregate.ssz_snappy"), aggregate)
+			return s.processSyncAggregate(aggregate)
+		},
+		"execution_payload": func(t *testing.T, s *StateTransistor, caseDir string, _ clparams.StateVersion) error {
+			payload := &cltypes.ExecutionPayload{}
+			readSpecTestObject(t, filepath.Join(caseDir, "execution_payload.ssz_snappy"), payload)
+			return s.processExecutionPayload(payload)
+		},
+	}
+	forks := map[string]clparams.StateVersion{
+		"altair":    clparams.AltairVersion,
+		"bellatrix": clparams.BellatrixVersion,
+	}
+	for fork, version := range forks {
+		version := version
+		forkDir := filepath.Join(specTestsDir, fork, "operations")
+		handlerDirs := readSpecTestDir(t, forkDir)
+		for _, handlerDir := range handlerDirs {
+			handler := handlerDir.Name()
+			process, ok := handlers[handler]
+			require.True(t, ok, "unknown operations handler %s", handler)
+			casesDir := filepath.Join(forkDir, handler, "pyspec_tests")
+			cases, err := os.ReadDir(casesDir)
+			require.NoError(t, err)
+			for _, c := range cases {
+				caseDir := filepath.Join(casesDir, c.Name())
+				t.Run(fork+"/"+handler+"/"+c.Name(), func(t *testing.T) {
+					meta := operationsTestMeta{}
+					for _, metaFile := range []string{"meta.yaml", "execution.yaml"} {
+						encoded, err := os.ReadFile(filepath.Join(caseDir, metaFile))
+						if os.IsNotExist(err) {
+							continue
+						}
+						require.NoError(t, err)
+						require.NoError(t, yaml.Unmarshal(encoded, &meta))
+					}
+					if meta.BlsSetting == 2 {
+						t.Skip("signatures are always verified")
+					}
+					if meta.ExecutionValid != nil && !*meta.ExecutionValid {
+						t.Skip("payloads are verified by the execution layer")
+					}
+					s := New(readSpecTestState(t, filepath.Join(caseDir, "pre.ssz_snappy"), version), &clparams.MainnetBeaconConfig, nil)
+					err := process(t, s, caseDir, version)
+					postPath := filepath.Join(caseDir, "post.ssz_snappy")
+					if _, statErr := os.Stat(postPath); os.IsNotExist(statErr) {
+						// No post state means the operation must be rejected.
+						require.Error(t, err)
+						return
+					}
+					require.NoError(t, err)
+					expectedRoot, err := readSpecTestState(t, postPath, version).HashTreeRoot()
+					require.NoError(t, err)
+					root, err := s.state.HashTreeRoot()
+					require.NoError(t, err)
+					require.Equal(t, expectedRoot, root)
+				})
+			}
+		}
+	}
+}
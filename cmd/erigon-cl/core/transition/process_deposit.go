@@ -0,0 +1,78 @@
+package transition
+
+import (
+	"fmt"
+
+	"github.com/Giulio2002/bls"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/fork"
+	"github.com/ledgerwatch/erigon/cl/utils"
+)
+
+func (s *StateTransistor) processDeposit(deposit *cltypes.Deposit) error {
+	depth := s.beaconConfig.DepositContractTreeDepth + 1 // Includes the deposit count mix-in.
+	if uint64(len(deposit.Proof)) != depth {
+		return fmt.Errorf("deposit proof has %d branches, expected %d", len(deposit.Proof), depth)
+	}
+	leaf, err := deposit.Data.HashTreeRoot()
+	if err != nil {
+		return fmt.Errorf("unable to hash deposit data: %v", err)
+	}
+	depositIndex := s.state.Eth1DepositIndex()
+	if !utils.IsValidMerkleBranch(leaf, deposit.Proof, depth, depositIndex, s.state.Eth1Data().Root) {
+		return fmt.Errorf("invalid merkle branch for deposit %d", depositIndex)
+	}
+	s.state.SetEth1DepositIndex(depositIndex + 1)
+	return s.applyDeposit(deposit.Data)
+}
+
+// applyDeposit tops up the balance of an existing validator, or registers a new one if the deposit is signed by it.
+func (s *StateTransistor) applyDeposit(data *cltypes.DepositData) error {
+	for index, validator := range s.state.Validators() {
+		if validator.PublicKey == data.PubKey {
+			IncreaseBalance(s.state, uint64(index), data.Amount)
+			return nil
+		}
+	}
+	// Deposits are fork agnostic, they are signed over the genesis fork version.
+	domainType := s.beaconConfig.DomainDeposit
+	domain, err := fork.ComputeDomain(domainType[:], utils.BytesToBytes4(s.beaconConfig.GenesisForkVersion), [32]byte{})
+	if err != nil {
+		return fmt.Errorf("unable to compute deposit domain: %v", err)
+	}
+	signingRoot, err := fork.ComputeSigningRoot(&cltypes.DepositMessage{
+		PubKey:                data.PubKey,
+		WithdrawalCredentials: data.WithdrawalCredentials,
+		Amount:                data.Amount,
+	}, domain)
+	if err != nil {
+		return fmt.Errorf("unable to compute signing root: %v", err)
+	}
+	// The deposit contract does not check signatures, deposits with invalid ones are skipped.
+	valid, err := bls.Verify(data.Signature[:], signingRoot[:], data.PubKey[:])
+	if err != nil || !valid {
+		return nil
+	}
+	s.addValidatorToRegistry(data)
+	return nil
+}
+
+func (s *StateTransistor) addValidatorToRegistry(data *cltypes.DepositData) {
+	effectiveBalance := data.Amount - data.Amount%s.beaconConfig.EffectiveBalanceIncrement
+	if effectiveBalance > s.beaconConfig.MaxEffectiveBalance {
+		effectiveBalance = s.beaconConfig.MaxEffectiveBalance
+	}
+	s.state.SetValidators(append(s.state.Validators(), &cltypes.Validator{
+		PublicKey:                  data.PubKey,
+		WithdrawalCredentials:      data.WithdrawalCredentials,
+		EffectiveBalance:           effectiveBalance,
+		ActivationEligibilityEpoch: s.beaconConfig.FarFutureEpoch,
+		ActivationEpoch:            s.beaconConfig.FarFutureEpoch,
+		ExitEpoch:                  s.beaconConfig.FarFutureEpoch,
+		WithdrawableEpoch:          s.beaconConfig.FarFutureEpoch,
+	}))
+	s.state.SetBalances(append(s.state.Balances(), data.Amount))
+	s.state.SetPreviousEpochParticipation(append(s.state.PreviousEpochParticipation(), 0))
+	s.state.SetCurrentEpochParticipation(append(s.state.CurrentEpochParticipation(), 0))
+	s.state.SetInactivityScores(append(s.state.InactivityScores(), 0))
+}
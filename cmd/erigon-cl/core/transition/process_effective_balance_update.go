@@ -0,0 +1,18 @@
+package transition
+
+func (s *StateTransistor) processEffectiveBalanceUpdates() {
+	// Update effective balances with hysteresis.
+	hysteresisIncrement := s.beaconConfig.EffectiveBalanceIncrement / s.beaconConfig.HysteresisQuotient
+	downwardThreshold := hysteresisIncrement * s.beaconConfig.HysteresisDownwardMultiplier
+	upwardThreshold := hysteresisIncrement * s.beaconConfig.HysteresisUpwardMultiplier
+	for index, validator := range s.state.Validators() {
+		balance := s.state.Balances()[index]
+		if balance+downwardThreshold < validator.EffectiveBalance || validator.EffectiveBalance+upwardThreshold < balance {
+			validator.EffectiveBalance = balance - balance%s.beaconConfig.EffectiveBalanceIncrement
+			if validator.EffectiveBalance > s.beaconConfig.MaxEffectiveBalance {
+				validator.EffectiveBalance = s.beaconConfig.MaxEffectiveBalance
+			}
+			s.state.SetValidatorAt(index, validator)
+		}
+	}
+}
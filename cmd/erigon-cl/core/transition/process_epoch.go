@@ -0,0 +1,173 @@
+package transition
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/state/state_encoding"
+)
+
+// ErrPhase0NotSupported is returned for phase0 states and blocks. BeaconState has no phase0 form, it doesn't keep
+// the pending attestations of phase0, so the transition starts from altair.
+var ErrPhase0NotSupported = errors.New("phase0 state transition is not supported")
+
+// processEpoch applies the epoch transition, it is called on the last slot of each epoch before the state moves to
+// the start slot of the next one.
+func (s *StateTransistor) processEpoch() error {
+	if s.state.Version() == clparams.Phase0Version {
+		return ErrPhase0NotSupported
+	}
+	s.processJustificationAndFinalization()
+	s.processInactivityUpdates()
+	s.processRewardsAndPenalties()
+	s.processRegistryUpdates()
+	s.processSlashings()
+	s.processEth1DataReset()
+	s.processEffectiveBalanceUpdates()
+	s.processSlashingsReset()
+	s.processRandaoMixesReset()
+	if err := s.processHistoricalRootsUpdate(); err != nil {
+		return fmt.Errorf("unable to process historical roots update: %v", err)
+	}
+	s.processParticipationFlagUpdates()
+	if err := s.processSyncCommitteeUpdates(); err != nil {
+		return fmt.Errorf("unable to process sync committee updates: %v", err)
+	}
+	return nil
+}
+
+func (s *StateTransistor) processEth1DataReset() {
+	nextEpoch := s.currentEpoch() + 1
+	if nextEpoch%s.beaconConfig.EpochsPerEth1VotingPeriod == 0 {
+		s.state.SetEth1DataVotes(nil)
+	}
+}
+
+func (s *StateTransistor) processSlashingsReset() {
+	nextEpoch := s.currentEpoch() + 1
+	s.state.SetSlashingAt(int(nextEpoch%s.beaconConfig.EpochsPerSlashingsVector), 0)
+}
+
+func (s *StateTransistor) processRandaoMixesReset() {
+	currentEpoch := s.currentEpoch()
+	nextEpoch := currentEpoch + 1
+	currentMix := s.state.RandaoMixes()[currentEpoch%s.beaconConfig.EpochsPerHistoricalVector]
+	s.state.SetRandaoMixAt(int(nextEpoch%s.beaconConfig.EpochsPerHistoricalVector), currentMix)
+}
+
+func (s *StateTransistor) processHistoricalRootsUpdate() error {
+	nextEpoch := s.currentEpoch() + 1
+	if nextEpoch%(s.beaconConfig.SlotsPerHistoricalRoot/s.beaconConfig.SlotsPerEpoch) != 0 {
+		return nil
+	}
+	// Hash tree root of HistoricalBatch{BlockRoots, StateRoots}.
+	blockRootsRoot, err := state_encoding.ArraysRoot(s.state.BlockRoots(), state_encoding.BlockRootsLength)
+	if err != nil {
+		return err
+	}
+	stateRootsRoot, err := state_encoding.ArraysRoot(s.state.StateRoots(), state_encoding.StateRootsLength)
+	if err != nil {
+		return err
+	}
+	historicalRoot, err := state_encoding.MerkleRootFromLeaves([][32]byte{blockRootsRoot, stateRootsRoot})
+	if err != nil {
+		return err
+	}
+	s.state.SetHistoricalRoots(append(s.state.HistoricalRoots(), historicalRoot))
+	return nil
+}
+
+func (s *StateTransistor) processParticipationFlagUpdates() {
+	s.state.SetPreviousEpochParticipation(s.state.CurrentEpochParticipation())
+	s.state.SetCurrentEpochParticipation(make([]byte, len(s.state.Validators())))
+}
+
+func (s *StateTransistor) currentEpoch() uint64 {
+	return s.state.Slot() / s.beaconConfig.SlotsPerEpoch
+}
+
+func (s *StateTransistor) previousEpoch() uint64 {
+	currentEpoch := s.currentEpoch()
+	if currentEpoch == s.beaconConfig.GenesisEpoch {
+		return currentEpoch
+	}
+	return currentEpoch - 1
+}
+
+// blockRootAtEpoch returns the block root at the start slot of the given epoch.
+func (s *StateTransistor) blockRootAtEpoch(epoch uint64) [32]byte {
+	return s.state.BlockRoots()[(epoch*s.beaconConfig.SlotsPerEpoch)%s.beaconConfig.SlotsPerHistoricalRoot]
+}
+
+// totalBalance sums effective balances of the validators set in the mask, it is never less than EffectiveBalanceIncrement.
+func (s *StateTransistor) totalBalance(mask []bool) uint64 {
+	total := uint64(0)
+	for i, ok := range mask {
+		if ok {
+			total += s.state.ValidatorAt(i).EffectiveBalance
+		}
+	}
+	if total < s.beaconConfig.EffectiveBalanceIncrement {
+		return s.beaconConfig.EffectiveBalanceIncrement
+	}
+	return total
+}
+
+func (s *StateTransistor) totalActiveBalance() uint64 {
+	epoch := s.currentEpoch()
+	mask := make([]bool, len(s.state.Validators()))
+	for i, v := range s.state.Validators() {
+		mask[i] = v.ActivationEpoch <= epoch && epoch < v.ExitEpoch
+	}
+	return s.totalBalance(mask)
+}
+
+// unslashedParticipatingValidators marks validators which were active and not slashed in the given epoch (current or
+// previous one) and have the given participation flag set.
+func (s *StateTransistor) unslashedParticipatingValidators(flagIndex uint8, epoch uint64) []bool {
+	participation := s.state.PreviousEpochParticipation()
+	if epoch == s.currentEpoch() {
+		participation = s.state.CurrentEpochParticipation()
+	}
+	mask := make([]bool, len(s.state.Validators()))
+	for i, v := range s.state.Validators() {
+		mask[i] = v.ActivationEpoch <= epoch && epoch < v.ExitEpoch && !v.Slashed && hasFlag(participation[i], flagIndex)
+	}
+	return mask
+}
+
+// eligibleValidatorIndices returns validators which are rewarded or penalized for the previous epoch.
+func (s *StateTransistor) eligibleValidatorIndices() []uint64 {
+	previousEpoch := s.previousEpoch()
+	indices := []uint64{}
+	for i, v := range s.state.Validators() {
+		isActive := v.ActivationEpoch <= previousEpoch && previousEpoch < v.ExitEpoch
+		if isActive || (v.Slashed && previousEpoch+1 < v.WithdrawableEpoch) {
+			indices = append(indices, uint64(i))
+		}
+	}
+	return indices
+}
+
+func (s *StateTransistor) isInInactivityLeak() bool {
+	return s.previousEpoch()-s.state.FinalizedCheckpoint().Epoch > s.beaconConfig.MinEpochsToInactivityPenalty
+}
+
+func (s *StateTransistor) inactivityPenaltyQuotient() uint64 {
+	if s.state.Version() >= clparams.BellatrixVersion {
+		return s.beaconConfig.InactivityPenaltyQuotientBellatrix
+	}
+	return s.beaconConfig.InactivityPenaltyQuotientAltair
+}
+
+func (s *StateTransistor) proportionalSlashingMultiplier() uint64 {
+	if s.state.Version() >= clparams.BellatrixVersion {
+		return s.beaconConfig.ProportionalSlashingMultiplierBellatrix
+	}
+	return s.beaconConfig.ProportionalSlashingMultiplierAltair
+}
+
+func hasFlag(participation byte, flagIndex uint8) bool {
+	return (participation>>flagIndex)&1 == 1
+}
@@ -0,0 +1,267 @@
+package transition
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/state"
+	"github.com/stretchr/testify/require"
+)
+
+// specTestsDir holds the mainnet vectors of the consensus-spec-tests release pinned in the Makefile
+// (https://github.com/ethereum/consensus-spec-tests), use "make consensus-spec-tests" to fetch them.
+var specTestsDir = filepath.Join("testdata", "consensus-spec-tests", "tests", "mainnet")
+
+func readSpecTestState(t *testing.T, path string, version clparams.StateVersion) *state.BeaconState {
+	compressed, err := os.ReadFile(path)
+	require.NoError(t, err)
+	encoded, err := snappy.Decode(nil, compressed)
+	require.NoError(t, err)
+	switch version {
+	case clparams.AltairVersion:
+		altairState := &cltypes.BeaconStateAltair{}
+		require.NoError(t, altairState.UnmarshalSSZ(encoded))
+		return state.FromAltairState(altairState)
+	case clparams.BellatrixVersion:
+		bellatrixState := &cltypes.BeaconStateBellatrix{}
+		require.NoError(t, bellatrixState.UnmarshalSSZ(encoded))
+		return state.FromBellatrixState(bellatrixState)
+	default:
+		t.Fatalf("unsupported state version %d", version)
+		return nil
+	}
+}
+
+// readSpecTestDir lists the spec test directory, the test is skipped if the vectors were not fetched.
+func readSpecTestDir(t *testing.T, dir string) []os.DirEntry {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		t.Skip("missing consensus spec tests, run \"make consensus-spec-tests\"")
+	}
+	require.NoError(t, err)
+	return entries
+}
+
+func TestEpochProcessingSpecTests(t *testing.T) {
+	handlers := map[string]func(s *StateTransistor) error{
+		"justification_and_finalization": func(s *StateTransistor) error { s.processJustificationAndFinalization(); return nil },
+		"inactivity_updates":             func(s *StateTransistor) error { s.processInactivityUpdates(); return nil },
+		"rewards_and_penalties":          func(s *StateTransistor) error { s.processRewardsAndPenalties(); return nil },
+		"registry_updates":               func(s *StateTransistor) error { s.processRegistryUpdates(); return nil },
+		"slashings":                      func(s *StateTransistor) error { s.processSlashings(); return nil },
+		"eth1_data_reset":                func(s *StateTransistor) error { s.processEth1DataReset(); return nil },
+		"effective_balance_updates":      func(s *StateTransistor) error { s.processEffectiveBalanceUpdates(); return nil },
+		"slashings_reset":                func(s *StateTransistor) error { s.processSlashingsReset(); return nil },
+		"randao_mixes_reset":             func(s *StateTransistor) error { s.processRandaoMixesReset(); return nil },
+		"historical_roots_update":        func(s *StateTransistor) error { return s.processHistoricalRootsUpdate() },
+		"participation_flag_updates":     func(s *StateTransistor) error { s.processParticipationFlagUpdates(); return nil },
+		"sync_committee_updates":         func(s *StateTransistor) error { return s.processSyncCommitteeUpdates() },
+	}
+	forks := map[string]clparams.StateVersion{
+		"altair":    clparams.AltairVersion,
+		"bellatrix": clparams.BellatrixVersion,
+	}
+	for fork, version := range forks {
+		version := version
+		forkDir := filepath.Join(specTestsDir, fork, "epoch_processing")
+		handlerDirs := readSpecTestDir(t, forkDir)
+		for _, handlerDir := range handlerDirs {
+			handler := handlerDir.Name()
+			process, ok := handlers[handler]
+			require.True(t, ok, "unknown epoch processing handler %s", handler)
+			casesDir := filepath.Join(forkDir, handler, "pyspec_tests")
+			cases, err := os.ReadDir(casesDir)
+			require.NoError(t, err)
+			for _, c := range cases {
+				caseDir := filepath.Join(casesDir, c.Name())
+				t.Run(fork+"/"+handler+"/"+c.Name(), func(t *testing.T) {
+					s := New(readSpecTestState(t, filepath.Join(caseDir, "pre.ssz_snappy"), version), &clparams.MainnetBeaconConfig, nil)
+					err := process(s)
+					postPath := filepath.Join(caseDir, "post.ssz_snappy")
+					if _, statErr := os.Stat(postPath); os.IsNotExist(statErr) {
+						// No post state means the transition must fail.
+						require.Error(t, err)
+						return
+					}
+					require.NoError(t, err)
+					expectedRoot, err := readSpecTestState(t, postPath, version).HashTreeRoot()
+					require.NoError(t, err)
+					root, err := s.state.HashTreeRoot()
+					require.NoError(t, err)
+					require.Equal(t, expectedRoot, root)
+				})
+			}
+		}
+	}
+}
+
+func getTestEpochState(numValidators int, slot uint64) *state.BeaconState {
+	validators := make([]*cltypes.Validator, numValidators)
+	balances := make([]uint64, numValidators)
+	for i := range validators {
+		validators[i] = &cltypes.Validator{
+			WithdrawalCredentials:      make([]byte, 32),
+			EffectiveBalance:           clparams.MainnetBeaconConfig.MaxEffectiveBalance,
+			ActivationEligibilityEpoch: 0,
+			ActivationEpoch:            0,
+			ExitEpoch:                  FAR_FUTURE_EPOCH,
+			WithdrawableEpoch:          FAR_FUTURE_EPOCH,
+		}
+		balances[i] = clparams.MainnetBeaconConfig.MaxEffectiveBalance
+	}
+	return state.FromBellatrixState(&cltypes.BeaconStateBellatrix{
+		Slot:                         slot,
+		Fork:                         &cltypes.Fork{},
+		LatestBlockHeader:            &cltypes.BeaconBlockHeader{},
+		BlockRoots:                   make([][32]byte, clparams.MainnetBeaconConfig.SlotsPerHistoricalRoot),
+		StateRoots:                   make([][32]byte, clparams.MainnetBeaconConfig.SlotsPerHistoricalRoot),
+		Eth1Data:                     &cltypes.Eth1Data{},
+		Eth1DataVotes:                []*cltypes.Eth1Data{{}},
+		Validators:                   validators,
+		Balances:                     balances,
+		RandaoMixes:                  make([][32]byte, clparams.MainnetBeaconConfig.EpochsPerHistoricalVector),
+		Slashings:                    make([]uint64, clparams.MainnetBeaconConfig.EpochsPerSlashingsVector),
+		PreviousEpochParticipation:   make([]byte, numValidators),
+		CurrentEpochParticipation:    make([]byte, numValidators),
+		JustificationBits:            []byte{0},
+		PreviousJustifiedCheckpoint:  &cltypes.Checkpoint{},
+		CurrentJustifiedCheckpoint:   &cltypes.Checkpoint{},
+		FinalizedCheckpoint:          &cltypes.Checkpoint{},
+		InactivityScores:             make([]uint64, numValidators),
+		CurrentSyncCommittee:         &cltypes.SyncCommittee{},
+		NextSyncCommittee:            &cltypes.SyncCommittee{},
+		LatestExecutionPayloadHeader: &cltypes.ExecutionHeader{},
+	})
+}
+
+func TestProcessJustificationAndFinalization(t *testing.T) {
+	// All validators attested to the targets of the previous and current epoch.
+	epoch := uint64(4)
+	st := getTestEpochState(64, epoch*SLOTS_PER_EPOCH+SLOTS_PER_EPOCH-1)
+	for i := range st.Validators() {
+		st.PreviousEpochParticipation()[i] = 1 << clparams.MainnetBeaconConfig.TimelyTargetFlagIndex
+		st.CurrentEpochParticipation()[i] = 1 << clparams.MainnetBeaconConfig.TimelyTargetFlagIndex
+	}
+	st.SetJustificationBits([]byte{0b0011})
+	st.SetPreviousJustifiedCheckpoint(&cltypes.Checkpoint{Epoch: epoch - 2})
+	st.SetCurrentJustifiedCheckpoint(&cltypes.Checkpoint{Epoch: epoch - 1})
+	st.BlockRoots()[epoch*SLOTS_PER_EPOCH] = [32]byte{1}
+
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	s.processJustificationAndFinalization()
+	require.Equal(t, []byte{0b0111}, st.JustificationBits())
+	require.Equal(t, &cltypes.Checkpoint{Epoch: epoch, Root: [32]byte{1}}, st.CurrentJustifiedCheckpoint())
+	require.Equal(t, &cltypes.Checkpoint{Epoch: epoch - 1}, st.PreviousJustifiedCheckpoint())
+	// Current epoch is justified with the previous justified epoch as source, so it gets finalized.
+	require.Equal(t, &cltypes.Checkpoint{Epoch: epoch - 1}, st.FinalizedCheckpoint())
+}
+
+func TestProcessEffectiveBalanceUpdates(t *testing.T) {
+	increment := clparams.MainnetBeaconConfig.EffectiveBalanceIncrement
+	st := getTestEpochState(3, 0)
+	// Within hysteresis, effective balance stays the same.
+	st.Balances()[0] = clparams.MainnetBeaconConfig.MaxEffectiveBalance - increment/4
+	// Below the downward threshold, effective balance is lowered.
+	st.Balances()[1] = clparams.MainnetBeaconConfig.MaxEffectiveBalance - increment
+	// Effective balance never exceeds the maximum.
+	st.Balances()[2] = clparams.MainnetBeaconConfig.MaxEffectiveBalance + 10*increment
+
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	s.processEffectiveBalanceUpdates()
+	require.Equal(t, clparams.MainnetBeaconConfig.MaxEffectiveBalance, st.ValidatorAt(0).EffectiveBalance)
+	require.Equal(t, clparams.MainnetBeaconConfig.MaxEffectiveBalance-increment, st.ValidatorAt(1).EffectiveBalance)
+	require.Equal(t, clparams.MainnetBeaconConfig.MaxEffectiveBalance, st.ValidatorAt(2).EffectiveBalance)
+}
+
+func TestProcessRewardsAndPenalties(t *testing.T) {
+	st := getTestEpochState(64, 2*SLOTS_PER_EPOCH+SLOTS_PER_EPOCH-1)
+	allFlags := byte(1<<clparams.MainnetBeaconConfig.TimelySourceFlagIndex | 1<<clparams.MainnetBeaconConfig.TimelyTargetFlagIndex | 1<<clparams.MainnetBeaconConfig.TimelyHeadFlagIndex)
+	// Only the first half of validators participated in the previous epoch.
+	for i := 0; i < 32; i++ {
+		st.PreviousEpochParticipation()[i] = allFlags
+	}
+
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	s.processRewardsAndPenalties()
+	// Base reward is 32 * 10^9 * 64 / isqrt(64 * 32 * 10^9) = 1431072, participants get half of the source, target
+	// and head rewards while the others pay the full source and target penalties.
+	for i := range st.Validators() {
+		if i < 32 {
+			require.Equal(t, uint64(32000603732), st.Balances()[i])
+		} else {
+			require.Equal(t, uint64(31999105580), st.Balances()[i])
+		}
+	}
+}
+
+func TestProcessInactivityUpdates(t *testing.T) {
+	// Finality is 10 epochs behind, so the chain is in an inactivity leak.
+	st := getTestEpochState(2, 10*SLOTS_PER_EPOCH+SLOTS_PER_EPOCH-1)
+	st.PreviousEpochParticipation()[0] = 1 << clparams.MainnetBeaconConfig.TimelyTargetFlagIndex
+	st.InactivityScores()[0] = 5
+
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	s.processInactivityUpdates()
+	require.Equal(t, []uint64{4, clparams.MainnetBeaconConfig.InactivityScoreBias}, st.InactivityScores())
+}
+
+func TestProcessRegistryUpdates(t *testing.T) {
+	epoch := uint64(3)
+	st := getTestEpochState(3, epoch*SLOTS_PER_EPOCH+SLOTS_PER_EPOCH-1)
+	// Validator 1 becomes eligible for activation.
+	validator := st.ValidatorAt(1)
+	validator.ActivationEligibilityEpoch = FAR_FUTURE_EPOCH
+	validator.ActivationEpoch = FAR_FUTURE_EPOCH
+	st.SetValidatorAt(1, validator)
+	// Validator 2 was eligible before finalization, so it gets activated.
+	validator = st.ValidatorAt(2)
+	validator.ActivationEpoch = FAR_FUTURE_EPOCH
+	st.SetValidatorAt(2, validator)
+	st.SetFinalizedCheckpoint(&cltypes.Checkpoint{Epoch: 1})
+
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	s.processRegistryUpdates()
+	require.Equal(t, epoch+1, st.ValidatorAt(1).ActivationEligibilityEpoch)
+	require.Equal(t, uint64(FAR_FUTURE_EPOCH), st.ValidatorAt(1).ActivationEpoch)
+	require.Equal(t, epoch+1+clparams.MainnetBeaconConfig.MaxSeedLookahead, st.ValidatorAt(2).ActivationEpoch)
+}
+
+func TestProcessSlashings(t *testing.T) {
+	epoch := uint64(5)
+	st := getTestEpochState(64, epoch*SLOTS_PER_EPOCH+SLOTS_PER_EPOCH-1)
+	validator := st.ValidatorAt(0)
+	validator.Slashed = true
+	validator.WithdrawableEpoch = epoch + clparams.MainnetBeaconConfig.EpochsPerSlashingsVector/2
+	st.SetValidatorAt(0, validator)
+	st.Slashings()[0] = clparams.MainnetBeaconConfig.MaxEffectiveBalance
+
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	s.processSlashings()
+	// Penalty is 32 increments * 3 * 32 ETH / 2048 ETH, rounded down to 1 increment.
+	require.Equal(t, clparams.MainnetBeaconConfig.MaxEffectiveBalance-clparams.MainnetBeaconConfig.EffectiveBalanceIncrement, st.Balances()[0])
+	require.Equal(t, clparams.MainnetBeaconConfig.MaxEffectiveBalance, st.Balances()[1])
+}
+
+func TestProcessEpochResets(t *testing.T) {
+	// Last epoch of the eth1 voting period.
+	epoch := clparams.MainnetBeaconConfig.EpochsPerEth1VotingPeriod - 1
+	st := getTestEpochState(4, epoch*SLOTS_PER_EPOCH+SLOTS_PER_EPOCH-1)
+	st.RandaoMixes()[epoch] = [32]byte{1}
+	st.Slashings()[epoch+1] = 100
+	st.CurrentEpochParticipation()[0] = 1
+
+	s := New(st, &clparams.MainnetBeaconConfig, nil)
+	s.processEth1DataReset()
+	s.processSlashingsReset()
+	s.processRandaoMixesReset()
+	s.processParticipationFlagUpdates()
+	require.Empty(t, st.Eth1DataVotes())
+	require.Equal(t, uint64(0), st.Slashings()[epoch+1])
+	require.Equal(t, [32]byte{1}, st.RandaoMixes()[epoch+1])
+	require.Equal(t, []byte{1, 0, 0, 0}, st.PreviousEpochParticipation())
+	require.Equal(t, []byte{0, 0, 0, 0}, st.CurrentEpochParticipation())
+}
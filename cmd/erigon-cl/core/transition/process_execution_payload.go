@@ -0,0 +1,73 @@
+package transition
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+)
+
+// processExecutionPayload checks the payload is consistent with the beacon state and records its header, the payload
+// itself is validated by the execution client.
+func (s *StateTransistor) processExecutionPayload(payload *cltypes.ExecutionPayload) error {
+	if payload == nil {
+		return fmt.Errorf("block has no execution payload")
+	}
+	mergeTransitionComplete, err := s.isMergeTransitionComplete()
+	if err != nil {
+		return err
+	}
+	if mergeTransitionComplete && payload.ParentHash != s.state.LatestExecutionPayloadHeader().BlockHash {
+		return fmt.Errorf("payload parent hash %x does not match latest block hash %x", payload.ParentHash, s.state.LatestExecutionPayloadHeader().BlockHash)
+	}
+	if randaoMix := s.state.RandaoMixes()[s.currentEpoch()%s.beaconConfig.EpochsPerHistoricalVector]; payload.PrevRandao != randaoMix {
+		return fmt.Errorf("payload randao %x does not match state randao mix %x", payload.PrevRandao, randaoMix)
+	}
+	if timestamp := s.state.GenesisTime() + s.state.Slot()*s.beaconConfig.SecondsPerSlot; payload.Timestamp != timestamp {
+		return fmt.Errorf("payload timestamp %d does not match slot timestamp %d", payload.Timestamp, timestamp)
+	}
+	header, err := payload.PayloadHeader()
+	if err != nil {
+		return fmt.Errorf("unable to compute payload header: %v", err)
+	}
+	s.state.SetLatestExecutionPayloadHeader(header)
+	return nil
+}
+
+// isMergeTransitionComplete tells whether the state already holds an execution payload header.
+func (s *StateTransistor) isMergeTransitionComplete() (bool, error) {
+	root, err := s.state.LatestExecutionPayloadHeader().HashTreeRoot()
+	if err != nil {
+		return false, fmt.Errorf("unable to hash latest execution payload header: %v", err)
+	}
+	emptyRoot, err := (&cltypes.ExecutionHeader{
+		LogsBloom:     make([]byte, 256),
+		BaseFeePerGas: make([]byte, 32),
+	}).HashTreeRoot()
+	if err != nil {
+		return false, err
+	}
+	return root != emptyRoot, nil
+}
+
+// isExecutionEnabled tells whether the block payload must be processed, that is from the merge transition block on.
+func (s *StateTransistor) isExecutionEnabled(payload *cltypes.ExecutionPayload) (bool, error) {
+	mergeTransitionComplete, err := s.isMergeTransitionComplete()
+	if err != nil || mergeTransitionComplete {
+		return mergeTransitionComplete, err
+	}
+	if payload == nil {
+		return false, fmt.Errorf("block has no execution payload")
+	}
+	root, err := payload.HashTreeRoot()
+	if err != nil {
+		return false, fmt.Errorf("unable to hash execution payload: %v", err)
+	}
+	emptyRoot, err := (&cltypes.ExecutionPayload{
+		LogsBloom:     make([]byte, 256),
+		BaseFeePerGas: make([]byte, 32),
+	}).HashTreeRoot()
+	if err != nil {
+		return false, err
+	}
+	return root != emptyRoot, nil
+}
@@ -0,0 +1,62 @@
+package transition
+
+import (
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+)
+
+func (s *StateTransistor) processJustificationAndFinalization() {
+	currentEpoch := s.currentEpoch()
+	// Initial FFG checkpoint values have a 0x00 stub for root, skip the first two epochs.
+	if currentEpoch <= s.beaconConfig.GenesisEpoch+1 {
+		return
+	}
+	previousEpoch := s.previousEpoch()
+	totalActiveBalance := s.totalActiveBalance()
+	previousTargetBalance := s.totalBalance(s.unslashedParticipatingValidators(s.beaconConfig.TimelyTargetFlagIndex, previousEpoch))
+	currentTargetBalance := s.totalBalance(s.unslashedParticipatingValidators(s.beaconConfig.TimelyTargetFlagIndex, currentEpoch))
+	s.weighJustificationAndFinalization(totalActiveBalance, previousTargetBalance, currentTargetBalance)
+}
+
+func (s *StateTransistor) weighJustificationAndFinalization(totalActiveBalance, previousTargetBalance, currentTargetBalance uint64) {
+	previousEpoch := s.previousEpoch()
+	currentEpoch := s.currentEpoch()
+	oldPreviousJustifiedCheckpoint := s.state.PreviousJustifiedCheckpoint()
+	oldCurrentJustifiedCheckpoint := s.state.CurrentJustifiedCheckpoint()
+
+	// Process justifications, bit i of the justification bits tells whether epoch currentEpoch-i is justified.
+	s.state.SetPreviousJustifiedCheckpoint(oldCurrentJustifiedCheckpoint)
+	bits := (s.state.JustificationBits()[0] << 1) & 0x0f
+	if previousTargetBalance*3 >= totalActiveBalance*2 {
+		s.state.SetCurrentJustifiedCheckpoint(&cltypes.Checkpoint{
+			Epoch: previousEpoch,
+			Root:  s.blockRootAtEpoch(previousEpoch),
+		})
+		bits |= 1 << 1
+	}
+	if currentTargetBalance*3 >= totalActiveBalance*2 {
+		s.state.SetCurrentJustifiedCheckpoint(&cltypes.Checkpoint{
+			Epoch: currentEpoch,
+			Root:  s.blockRootAtEpoch(currentEpoch),
+		})
+		bits |= 1 << 0
+	}
+	s.state.SetJustificationBits([]byte{bits})
+
+	// Process finalizations.
+	// The 2nd/3rd/4th most recent epochs are justified, the 2nd using the 4th as source.
+	if bits&0b1110 == 0b1110 && oldPreviousJustifiedCheckpoint.Epoch+3 == currentEpoch {
+		s.state.SetFinalizedCheckpoint(oldPreviousJustifiedCheckpoint)
+	}
+	// The 2nd/3rd most recent epochs are justified, the 2nd using the 3rd as source.
+	if bits&0b0110 == 0b0110 && oldPreviousJustifiedCheckpoint.Epoch+2 == currentEpoch {
+		s.state.SetFinalizedCheckpoint(oldPreviousJustifiedCheckpoint)
+	}
+	// The 1st/2nd/3rd most recent epochs are justified, the 1st using the 3rd as source.
+	if bits&0b0111 == 0b0111 && oldCurrentJustifiedCheckpoint.Epoch+2 == currentEpoch {
+		s.state.SetFinalizedCheckpoint(oldCurrentJustifiedCheckpoint)
+	}
+	// The 1st/2nd most recent epochs are justified, the 1st using the 2nd as source.
+	if bits&0b0011 == 0b0011 && oldCurrentJustifiedCheckpoint.Epoch+1 == currentEpoch {
+		s.state.SetFinalizedCheckpoint(oldCurrentJustifiedCheckpoint)
+	}
+}
@@ -0,0 +1,59 @@
+package transition
+
+import (
+	"fmt"
+
+	"github.com/Giulio2002/bls"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/fork"
+)
+
+func (s *StateTransistor) processProposerSlashing(slashing *cltypes.ProposerSlashing) error {
+	header1, header2 := slashing.Header1.Header, slashing.Header2.Header
+	if header1.Slot != header2.Slot {
+		return fmt.Errorf("non-matching slots on proposer slashing: %d != %d", header1.Slot, header2.Slot)
+	}
+	if header1.ProposerIndex != header2.ProposerIndex {
+		return fmt.Errorf("non-matching proposer indices on proposer slashing: %d != %d", header1.ProposerIndex, header2.ProposerIndex)
+	}
+	root1, err := header1.HashTreeRoot()
+	if err != nil {
+		return fmt.Errorf("unable to hash header: %v", err)
+	}
+	root2, err := header2.HashTreeRoot()
+	if err != nil {
+		return fmt.Errorf("unable to hash header: %v", err)
+	}
+	if root1 == root2 {
+		return fmt.Errorf("proposer slashing headers are the same: %x", root1)
+	}
+	if header1.ProposerIndex >= uint64(len(s.state.Validators())) {
+		return fmt.Errorf("proposer index %d out of range", header1.ProposerIndex)
+	}
+	proposer := s.state.ValidatorAt(int(header1.ProposerIndex))
+	if !isSlashableValidator(proposer, s.currentEpoch()) {
+		return fmt.Errorf("proposer %d is not slashable", header1.ProposerIndex)
+	}
+	for _, signedHeader := range []*cltypes.SignedBeaconBlockHeader{slashing.Header1, slashing.Header2} {
+		domain, err := s.getDomain(s.beaconConfig.DomainBeaconProposer, signedHeader.Header.Slot/s.beaconConfig.SlotsPerEpoch)
+		if err != nil {
+			return fmt.Errorf("unable to get domain: %v", err)
+		}
+		signingRoot, err := fork.ComputeSigningRoot(signedHeader.Header, domain)
+		if err != nil {
+			return fmt.Errorf("unable to compute signing root: %v", err)
+		}
+		valid, err := bls.Verify(signedHeader.Signature[:], signingRoot[:], proposer.PublicKey[:])
+		if err != nil {
+			return fmt.Errorf("unable to verify signature: %v", err)
+		}
+		if !valid {
+			return fmt.Errorf("invalid signature: signature %x, root %x, pubkey %x", signedHeader.Signature[:], signingRoot[:], proposer.PublicKey[:])
+		}
+	}
+	return s.slashValidator(header1.ProposerIndex, nil)
+}
+
+func isSlashableValidator(validator *cltypes.Validator, epoch uint64) bool {
+	return !validator.Slashed && validator.ActivationEpoch <= epoch && epoch < validator.WithdrawableEpoch
+}
@@ -0,0 +1,43 @@
+package transition
+
+import (
+	"sort"
+)
+
+func (s *StateTransistor) processRegistryUpdates() {
+	currentEpoch := s.currentEpoch()
+	// Process activation eligibility and ejections.
+	for index, validator := range s.state.Validators() {
+		if validator.ActivationEligibilityEpoch == s.beaconConfig.FarFutureEpoch && validator.EffectiveBalance == s.beaconConfig.MaxEffectiveBalance {
+			validator.ActivationEligibilityEpoch = currentEpoch + 1
+			s.state.SetValidatorAt(index, validator)
+		}
+		isActive := validator.ActivationEpoch <= currentEpoch && currentEpoch < validator.ExitEpoch
+		if isActive && validator.EffectiveBalance <= s.beaconConfig.EjectionBalance {
+			InitiateValidatorExit(s.state, uint64(index))
+		}
+	}
+
+	// Queue validators eligible for activation and not yet dequeued for activation.
+	activationQueue := []uint64{}
+	for index, validator := range s.state.Validators() {
+		if validator.ActivationEligibilityEpoch <= s.state.FinalizedCheckpoint().Epoch && validator.ActivationEpoch == s.beaconConfig.FarFutureEpoch {
+			activationQueue = append(activationQueue, uint64(index))
+		}
+	}
+	// Order by the sequence of activation eligibility epoch, then by validator index.
+	sort.SliceStable(activationQueue, func(i, j int) bool {
+		return s.state.ValidatorAt(int(activationQueue[i])).ActivationEligibilityEpoch < s.state.ValidatorAt(int(activationQueue[j])).ActivationEligibilityEpoch
+	})
+
+	// Dequeue validators for activation up to churn limit.
+	churnLimit := GetValidtorChurnLimit(s.state)
+	if uint64(len(activationQueue)) > churnLimit {
+		activationQueue = activationQueue[:churnLimit]
+	}
+	for _, index := range activationQueue {
+		validator := s.state.ValidatorAt(int(index))
+		validator.ActivationEpoch = ComputeActivationExitEpoch(currentEpoch)
+		s.state.SetValidatorAt(int(index), validator)
+	}
+}
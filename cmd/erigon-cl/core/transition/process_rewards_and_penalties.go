@@ -0,0 +1,108 @@
+package transition
+
+import (
+	"github.com/ledgerwatch/erigon/cl/utils"
+)
+
+func (s *StateTransistor) processInactivityUpdates() {
+	if s.currentEpoch() == s.beaconConfig.GenesisEpoch {
+		return
+	}
+	isInInactivityLeak := s.isInInactivityLeak()
+	participating := s.unslashedParticipatingValidators(s.beaconConfig.TimelyTargetFlagIndex, s.previousEpoch())
+	scores := s.state.InactivityScores()
+	for _, index := range s.eligibleValidatorIndices() {
+		// Increase the inactivity score of inactive validators.
+		if participating[index] {
+			if scores[index] > 0 {
+				scores[index]--
+			}
+		} else {
+			scores[index] += s.beaconConfig.InactivityScoreBias
+		}
+		// Decrease the inactivity score of all eligible validators during a leak-free epoch.
+		if !isInInactivityLeak {
+			if scores[index] < s.beaconConfig.InactivityScoreRecoveryRate {
+				scores[index] = 0
+			} else {
+				scores[index] -= s.beaconConfig.InactivityScoreRecoveryRate
+			}
+		}
+	}
+	s.state.SetInactivityScores(scores)
+}
+
+func (s *StateTransistor) processRewardsAndPenalties() {
+	if s.currentEpoch() == s.beaconConfig.GenesisEpoch {
+		return
+	}
+	flagIndices := []uint8{s.beaconConfig.TimelySourceFlagIndex, s.beaconConfig.TimelyTargetFlagIndex, s.beaconConfig.TimelyHeadFlagIndex}
+	for _, flagIndex := range flagIndices {
+		rewards, penalties := s.flagIndexDeltas(flagIndex)
+		s.applyDeltas(rewards, penalties)
+	}
+	rewards, penalties := s.inactivityPenaltyDeltas()
+	s.applyDeltas(rewards, penalties)
+}
+
+func (s *StateTransistor) applyDeltas(rewards, penalties []uint64) {
+	for index := range s.state.Validators() {
+		IncreaseBalance(s.state, uint64(index), rewards[index])
+		DecreaseBalance(s.state, uint64(index), penalties[index])
+	}
+}
+
+// flagIndexDeltas computes the rewards and penalties of the previous epoch for the given participation flag.
+func (s *StateTransistor) flagIndexDeltas(flagIndex uint8) (rewards []uint64, penalties []uint64) {
+	rewards = make([]uint64, len(s.state.Validators()))
+	penalties = make([]uint64, len(s.state.Validators()))
+	participating := s.unslashedParticipatingValidators(flagIndex, s.previousEpoch())
+	weight := s.participationFlagWeight(flagIndex)
+	participatingIncrements := s.totalBalance(participating) / s.beaconConfig.EffectiveBalanceIncrement
+	totalActiveBalance := s.totalActiveBalance()
+	activeIncrements := totalActiveBalance / s.beaconConfig.EffectiveBalanceIncrement
+	baseRewardPerIncrement := s.baseRewardPerIncrement(totalActiveBalance)
+	isInInactivityLeak := s.isInInactivityLeak()
+
+	for _, index := range s.eligibleValidatorIndices() {
+		baseReward := s.state.ValidatorAt(int(index)).EffectiveBalance / s.beaconConfig.EffectiveBalanceIncrement * baseRewardPerIncrement
+		if participating[index] {
+			if !isInInactivityLeak {
+				rewards[index] += baseReward * weight * participatingIncrements / (activeIncrements * s.beaconConfig.WeightDenominator)
+			}
+		} else if flagIndex != s.beaconConfig.TimelyHeadFlagIndex {
+			penalties[index] += baseReward * weight / s.beaconConfig.WeightDenominator
+		}
+	}
+	return rewards, penalties
+}
+
+// inactivityPenaltyDeltas computes the penalties of validators which did not attest to the previous epoch target.
+func (s *StateTransistor) inactivityPenaltyDeltas() (rewards []uint64, penalties []uint64) {
+	rewards = make([]uint64, len(s.state.Validators()))
+	penalties = make([]uint64, len(s.state.Validators()))
+	participating := s.unslashedParticipatingValidators(s.beaconConfig.TimelyTargetFlagIndex, s.previousEpoch())
+	penaltyDenominator := s.beaconConfig.InactivityScoreBias * s.inactivityPenaltyQuotient()
+	for _, index := range s.eligibleValidatorIndices() {
+		if !participating[index] {
+			penaltyNumerator := s.state.ValidatorAt(int(index)).EffectiveBalance * s.state.InactivityScores()[index]
+			penalties[index] += penaltyNumerator / penaltyDenominator
+		}
+	}
+	return rewards, penalties
+}
+
+func (s *StateTransistor) participationFlagWeight(flagIndex uint8) uint64 {
+	switch flagIndex {
+	case s.beaconConfig.TimelySourceFlagIndex:
+		return s.beaconConfig.TimelySourceWeight
+	case s.beaconConfig.TimelyTargetFlagIndex:
+		return s.beaconConfig.TimelyTargetWeight
+	default:
+		return s.beaconConfig.TimelyHeadWeight
+	}
+}
+
+func (s *StateTransistor) baseRewardPerIncrement(totalActiveBalance uint64) uint64 {
+	return s.beaconConfig.EffectiveBalanceIncrement * s.beaconConfig.BaseRewardFactor / utils.IntegerSquareRoot(totalActiveBalance)
+}
@@ -0,0 +1,23 @@
+package transition
+
+func (s *StateTransistor) processSlashings() {
+	epoch := s.currentEpoch()
+	totalBalance := s.totalActiveBalance()
+	totalSlashings := uint64(0)
+	for _, slashing := range s.state.Slashings() {
+		totalSlashings += slashing
+	}
+	adjustedTotalSlashingBalance := totalSlashings * s.proportionalSlashingMultiplier()
+	if adjustedTotalSlashingBalance > totalBalance {
+		adjustedTotalSlashingBalance = totalBalance
+	}
+	increment := s.beaconConfig.EffectiveBalanceIncrement
+	for index, validator := range s.state.Validators() {
+		if validator.Slashed && epoch+s.beaconConfig.EpochsPerSlashingsVector/2 == validator.WithdrawableEpoch {
+			// Factored out from penalty numerator to avoid uint64 overflow.
+			penaltyNumerator := validator.EffectiveBalance / increment * adjustedTotalSlashingBalance
+			penalty := penaltyNumerator / totalBalance * increment
+			DecreaseBalance(s.state, uint64(index), penalty)
+		}
+	}
+}
@@ -5,11 +5,16 @@ import (
 
 	"github.com/Giulio2002/bls"
 	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/fork"
 )
 
-func (s *StateTransistor) transitionState(block *cltypes.SignedBeaconBlock, validate bool) error {
+// TransitionState advances the state to the block slot and applies the block on top of it, when validate is set the
+// block signature and the resulting state root are checked as well.
+func (s *StateTransistor) TransitionState(block *cltypes.SignedBeaconBlock, validate bool) error {
 	currentBlock := block.Block
-	s.processSlots(currentBlock.Slot)
+	if err := s.processSlots(currentBlock.Slot); err != nil {
+		return err
+	}
 	if validate {
 		valid, err := s.verifyBlockSignature(block)
 		if err != nil {
@@ -19,7 +24,9 @@ func (s *StateTransistor) transitionState(block *cltypes.SignedBeaconBlock, vali
 			return fmt.Errorf("block not valid")
 		}
 	}
-	// TODO add logic to process block and update state.
+	if err := s.processBlock(currentBlock); err != nil {
+		return fmt.Errorf("unable to process block: %v", err)
+	}
 	if validate {
 		expectedStateRoot, err := s.state.HashTreeRoot()
 		if err != nil {
@@ -66,7 +73,12 @@ func (s *StateTransistor) processSlots(slot uint64) error {
 		if err != nil {
 			return fmt.Errorf("unable to process slot transition: %v", err)
 		}
-		// TODO: add logic to process epoch updates.
+		// Process epoch on the last slot of the epoch, before moving to the start slot of the next one.
+		if (stateSlot+1)%s.beaconConfig.SlotsPerEpoch == 0 {
+			if err := s.processEpoch(); err != nil {
+				return fmt.Errorf("unable to process epoch transition: %v", err)
+			}
+		}
 		stateSlot += 1
 		s.state.SetSlot(stateSlot)
 	}
@@ -74,8 +86,15 @@ func (s *StateTransistor) processSlots(slot uint64) error {
 }
 
 func (s *StateTransistor) verifyBlockSignature(block *cltypes.SignedBeaconBlock) (bool, error) {
+	if block.Block.ProposerIndex >= uint64(len(s.state.Validators())) {
+		return false, fmt.Errorf("proposer index %d out of range", block.Block.ProposerIndex)
+	}
 	proposer := s.state.ValidatorAt(int(block.Block.ProposerIndex))
-	sigRoot, err := block.Block.Body.HashTreeRoot()
+	domain, err := s.getDomain(s.beaconConfig.DomainBeaconProposer, s.currentEpoch())
+	if err != nil {
+		return false, err
+	}
+	sigRoot, err := fork.ComputeSigningRoot(block.Block, domain)
 	if err != nil {
 		return false, err
 	}
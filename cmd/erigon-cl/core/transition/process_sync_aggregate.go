@@ -0,0 +1,88 @@
+package transition
+
+import (
+	"fmt"
+
+	"github.com/Giulio2002/bls"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/prysmaticlabs/go-bitfield"
+)
+
+// infiniteSignature is the compressed G2 point at infinity, the valid signature of an empty sync aggregate.
+var infiniteSignature = [96]byte{0xc0}
+
+func (s *StateTransistor) processSyncAggregate(aggregate *cltypes.SyncAggregate) error {
+	if aggregate == nil {
+		return fmt.Errorf("block has no sync aggregate")
+	}
+	committeePubKeys := s.state.CurrentSyncCommittee().PubKeys
+	if uint64(len(aggregate.SyncCommiteeBits))*8 != s.beaconConfig.SyncCommitteeSize || uint64(len(committeePubKeys)) != s.beaconConfig.SyncCommitteeSize {
+		return fmt.Errorf("sync committee bits and public keys do not match the sync committee size")
+	}
+	bits := bitfield.Bitvector512(aggregate.SyncCommiteeBits)
+	participantPubKeys := [][]byte{}
+	for i := range committeePubKeys {
+		if bits.BitAt(uint64(i)) {
+			participantPubKeys = append(participantPubKeys, committeePubKeys[i][:])
+		}
+	}
+
+	// Verify the sync committee signature of the previous slot block root.
+	previousSlot := uint64(0)
+	if s.state.Slot() > 0 {
+		previousSlot = s.state.Slot() - 1
+	}
+	domain, err := s.getDomain(s.beaconConfig.DomainSyncCommittee, previousSlot/s.beaconConfig.SlotsPerEpoch)
+	if err != nil {
+		return fmt.Errorf("unable to get domain: %v", err)
+	}
+	blockRoot, err := s.blockRootAtSlot(previousSlot)
+	if err != nil {
+		return err
+	}
+	signingRoot, err := (&cltypes.SigningData{Root: blockRoot, Domain: domain}).HashTreeRoot()
+	if err != nil {
+		return fmt.Errorf("unable to compute signing root: %v", err)
+	}
+	if len(participantPubKeys) > 0 || aggregate.SyncCommiteeSignature != infiniteSignature {
+		valid, err := bls.VerifyAggregate(aggregate.SyncCommiteeSignature[:], signingRoot[:], participantPubKeys)
+		if err != nil {
+			return fmt.Errorf("unable to verify sync committee signature: %v", err)
+		}
+		if !valid {
+			return fmt.Errorf("invalid sync committee signature")
+		}
+	}
+
+	// Compute participant and proposer rewards.
+	totalActiveBalance := s.totalActiveBalance()
+	totalBaseRewards := s.baseRewardPerIncrement(totalActiveBalance) * (totalActiveBalance / s.beaconConfig.EffectiveBalanceIncrement)
+	maxParticipantRewards := totalBaseRewards * s.beaconConfig.SyncRewardWeight / s.beaconConfig.WeightDenominator / s.beaconConfig.SlotsPerEpoch
+	participantReward := maxParticipantRewards / s.beaconConfig.SyncCommitteeSize
+	proposerReward := participantReward * s.beaconConfig.ProposerWeight / (s.beaconConfig.WeightDenominator - s.beaconConfig.ProposerWeight)
+	proposerIndex, err := GetBeaconProposerIndex(s.state)
+	if err != nil {
+		return fmt.Errorf("unable to get proposer index: %v", err)
+	}
+
+	// Apply participant and proposer rewards.
+	validatorIndices := make(map[[48]byte]uint64, len(s.state.Validators()))
+	for index, validator := range s.state.Validators() {
+		if _, ok := validatorIndices[validator.PublicKey]; !ok {
+			validatorIndices[validator.PublicKey] = uint64(index)
+		}
+	}
+	for i, pubKey := range committeePubKeys {
+		participantIndex, ok := validatorIndices[pubKey]
+		if !ok {
+			return fmt.Errorf("sync committee member %x is not a validator", pubKey)
+		}
+		if bits.BitAt(uint64(i)) {
+			IncreaseBalance(s.state, participantIndex, participantReward)
+			IncreaseBalance(s.state, proposerIndex, proposerReward)
+		} else {
+			DecreaseBalance(s.state, participantIndex, participantReward)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package transition
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/utils"
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+func (s *StateTransistor) processSyncCommitteeUpdates() error {
+	nextEpoch := s.currentEpoch() + 1
+	if nextEpoch%s.beaconConfig.EpochsPerSyncCommitteePeriod != 0 {
+		return nil
+	}
+	nextSyncCommittee, err := s.computeNextSyncCommittee()
+	if err != nil {
+		return err
+	}
+	s.state.SetCurrentSyncCommittee(s.state.NextSyncCommittee())
+	s.state.SetNextSyncCommittee(nextSyncCommittee)
+	return nil
+}
+
+// computeNextSyncCommittee samples the sync committee of the next period, validators are picked with probability
+// proportional to their effective balance.
+func (s *StateTransistor) computeNextSyncCommittee() (*cltypes.SyncCommittee, error) {
+	epoch := s.currentEpoch() + 1
+	maxRandomByte := uint64(1<<8 - 1)
+	activeValidatorIndices := GetActiveValidatorIndices(s.state, epoch)
+	activeValidatorCount := uint64(len(activeValidatorIndices))
+	if activeValidatorCount == 0 {
+		return nil, fmt.Errorf("no active validators at epoch %d", epoch)
+	}
+	seed := [32]byte{}
+	copy(seed[:], GetSeed(s.state, epoch, s.beaconConfig.DomainSyncCommittee))
+
+	pubKeys := make([][48]byte, 0, s.beaconConfig.SyncCommitteeSize)
+	buf := make([]byte, 8)
+	for i := uint64(0); uint64(len(pubKeys)) < s.beaconConfig.SyncCommitteeSize; i++ {
+		shuffledIndex, err := ComputeShuffledIndex(i%activeValidatorCount, activeValidatorCount, seed)
+		if err != nil {
+			return nil, err
+		}
+		candidate := s.state.ValidatorAt(int(activeValidatorIndices[shuffledIndex]))
+		binary.LittleEndian.PutUint64(buf, i/32)
+		randomByte := uint64(utils.Keccak256(seed[:], buf)[i%32])
+		if candidate.EffectiveBalance*maxRandomByte >= s.beaconConfig.MaxEffectiveBalance*randomByte {
+			pubKeys = append(pubKeys, candidate.PublicKey)
+		}
+	}
+
+	rawPubKeys := make([][]byte, len(pubKeys))
+	for i := range pubKeys {
+		rawPubKeys[i] = pubKeys[i][:]
+	}
+	aggregatePublicKey := new(blst.P1Aggregate)
+	if !aggregatePublicKey.AggregateCompressed(rawPubKeys, false) {
+		return nil, fmt.Errorf("unable to aggregate sync committee public keys")
+	}
+	syncCommittee := &cltypes.SyncCommittee{PubKeys: pubKeys}
+	copy(syncCommittee.AggregatePublicKey[:], aggregatePublicKey.ToAffine().Compress())
+	return syncCommittee, nil
+}
@@ -0,0 +1,47 @@
+package transition
+
+import (
+	"fmt"
+
+	"github.com/Giulio2002/bls"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/fork"
+)
+
+func (s *StateTransistor) processVoluntaryExit(signedExit *cltypes.SignedVoluntaryExit) error {
+	exit := signedExit.VolunaryExit
+	if exit.ValidatorIndex >= uint64(len(s.state.Validators())) {
+		return fmt.Errorf("validator index %d out of range", exit.ValidatorIndex)
+	}
+	validator := s.state.ValidatorAt(int(exit.ValidatorIndex))
+	currentEpoch := s.currentEpoch()
+	if validator.ActivationEpoch > currentEpoch || currentEpoch >= validator.ExitEpoch {
+		return fmt.Errorf("validator %d is not active", exit.ValidatorIndex)
+	}
+	if validator.ExitEpoch != s.beaconConfig.FarFutureEpoch {
+		return fmt.Errorf("validator %d has already initiated its exit", exit.ValidatorIndex)
+	}
+	if currentEpoch < exit.Epoch {
+		return fmt.Errorf("exit epoch %d is after the current epoch %d", exit.Epoch, currentEpoch)
+	}
+	if currentEpoch < validator.ActivationEpoch+s.beaconConfig.ShardCommitteePeriod {
+		return fmt.Errorf("validator %d has not been active long enough to exit", exit.ValidatorIndex)
+	}
+	domain, err := s.getDomain(s.beaconConfig.DomainVoluntaryExit, exit.Epoch)
+	if err != nil {
+		return fmt.Errorf("unable to get domain: %v", err)
+	}
+	signingRoot, err := fork.ComputeSigningRoot(exit, domain)
+	if err != nil {
+		return fmt.Errorf("unable to compute signing root: %v", err)
+	}
+	valid, err := bls.Verify(signedExit.Signature[:], signingRoot[:], validator.PublicKey[:])
+	if err != nil {
+		return fmt.Errorf("unable to verify signature: %v", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid signature: signature %x, root %x, pubkey %x", signedExit.Signature[:], signingRoot[:], validator.PublicKey[:])
+	}
+	InitiateValidatorExit(s.state, exit.ValidatorIndex)
+	return nil
+}
@@ -10,6 +10,8 @@ type StateTransistor struct {
 	state         *state.BeaconState
 	beaconConfig  *clparams.BeaconChainConfig
 	genesisConfig *clparams.GenesisConfig
+	// shuffledSetsCache maps committee seeds to the shuffled active validator indices of their epoch.
+	shuffledSetsCache map[[32]byte][]uint64
 }
 
 func New(state *state.BeaconState, beaconConfig *clparams.BeaconChainConfig, genesisConfig *clparams.GenesisConfig) *StateTransistor {
@@ -17,5 +19,7 @@ func New(state *state.BeaconState, beaconConfig *clparams.BeaconChainConfig, gen
 		state:         state,
 		beaconConfig:  beaconConfig,
 		genesisConfig: genesisConfig,
+
+		shuffledSetsCache: map[[32]byte][]uint64{},
 	}
 }
@@ -57,6 +57,10 @@ func runConsensusLayerNode(cliCtx *cli.Context) error {
 		log.Error("Could load beacon data configuration", "err", err)
 		return err
 	}
+	if err := db.Update(ctx, rawdb.MigrateLightClientData); err != nil {
+		log.Error("Could not migrate light client data", "err", err)
+		return err
+	}
 	// Fetch the checkpoint state.
 	cpState, err := getCheckpointState(ctx, db, cfg.CheckpointUri)
 	if err != nil {
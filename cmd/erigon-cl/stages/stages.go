@@ -87,7 +87,7 @@ func NewConsensusStagedSync(ctx context.Context,
 			ctx,
 			StageHistoryReconstruction(db, backwardDownloader, genesisCfg, beaconCfg, beaconDBCfg, state, tmpdir, executionClient),
 			StageBeaconsBlock(db, forwardDownloader, genesisCfg, beaconCfg, state, executionClient),
-			StageBeaconState(db, genesisCfg, beaconCfg, state, triggerExecution, clearEth1Data, executionClient),
+			StageBeaconState(db, genesisCfg, beaconCfg, state, triggerExecution, clearEth1Data, executionClient, beaconDBCfg),
 			StageBeaconIndexes(db, tmpdir),
 		),
 		ConsensusUnwindOrder,
@@ -7,8 +7,10 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/cl/clparams"
 	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/utils"
 	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/rawdb"
 	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/state"
+	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/transition"
 	"github.com/ledgerwatch/erigon/cmd/erigon-cl/execution_client"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
@@ -26,10 +28,11 @@ type StageBeaconStateCfg struct {
 	clearEth1Data    bool // Whether we want to discard eth1 data.
 	triggerExecution triggerExecutionFunc
 	executionClient  *execution_client.ExecutionClient
+	beaconDBCfg      *rawdb.BeaconDataConfig
 }
 
 func StageBeaconState(db kv.RwDB, genesisCfg *clparams.GenesisConfig,
-	beaconCfg *clparams.BeaconChainConfig, state *state.BeaconState, triggerExecution triggerExecutionFunc, clearEth1Data bool, executionClient *execution_client.ExecutionClient) StageBeaconStateCfg {
+	beaconCfg *clparams.BeaconChainConfig, state *state.BeaconState, triggerExecution triggerExecutionFunc, clearEth1Data bool, executionClient *execution_client.ExecutionClient, beaconDBCfg *rawdb.BeaconDataConfig) StageBeaconStateCfg {
 	return StageBeaconStateCfg{
 		db:               db,
 		genesisCfg:       genesisCfg,
@@ -38,6 +41,7 @@ func StageBeaconState(db kv.RwDB, genesisCfg *clparams.GenesisConfig,
 		clearEth1Data:    clearEth1Data,
 		triggerExecution: triggerExecution,
 		executionClient:  executionClient,
+		beaconDBCfg:      beaconDBCfg,
 	}
 }
 
@@ -59,6 +63,7 @@ func SpawnStageBeaconState(cfg StageBeaconStateCfg, s *stagedsync.StageState, tx
 	latestBlockHeader := cfg.state.LatestBlockHeader()
 
 	fromSlot := latestBlockHeader.Slot
+	stateTransistor := transition.New(cfg.state, cfg.beaconCfg, cfg.genesisCfg)
 	for slot := fromSlot + 1; slot <= endSlot; slot++ {
 		block, err := rawdb.ReadBeaconBlock(tx, slot)
 		if err != nil {
@@ -68,8 +73,19 @@ func SpawnStageBeaconState(cfg StageBeaconStateCfg, s *stagedsync.StageState, tx
 		if block == nil {
 			continue
 		}
-		// TODO: Pass this to state transition with the state
-		_ = block
+		// Before the transition the state is the post state of the block the sync aggregate attests.
+		if err := writeLightClientData(tx, cfg.beaconCfg, cfg.state, block); err != nil {
+			return fmt.Errorf("[%s] unable to write light client data at slot %d: %v", s.LogPrefix(), slot, err)
+		}
+		if err := stateTransistor.TransitionState(block, true); err != nil {
+			return fmt.Errorf("[%s] unable to transition state at slot %d: %v", s.LogPrefix(), slot, err)
+		}
+	}
+	// The bootstraps are kept as far back as the beacon data.
+	if cfg.beaconDBCfg != nil && cfg.state.Slot() > cfg.beaconDBCfg.BackFillingAmount {
+		if err := rawdb.PruneLightClientBootstraps(tx, cfg.state.Slot()-cfg.beaconDBCfg.BackFillingAmount); err != nil {
+			return fmt.Errorf("[%s] unable to prune light client bootstraps: %v", s.LogPrefix(), err)
+		}
 	}
 	// If successful update fork choice
 	if cfg.executionClient != nil {
@@ -99,10 +115,7 @@ func SpawnStageBeaconState(cfg StageBeaconStateCfg, s *stagedsync.StageState, tx
 			return err
 		}
 	}
-	latestBlockHeader.Slot = endSlot
-	cfg.state.SetLatestBlockHeader(latestBlockHeader)
-
-	log.Info(fmt.Sprintf("[%s] Finished transitioning state", s.LogPrefix()), "from", fromSlot, "to", endSlot)
+	log.Info(fmt.Sprintf("[%s] Finished transitioning state", s.LogPrefix()), "from", fromSlot, "to", cfg.state.Slot())
 	if !useExternalTx {
 		if err = tx.Commit(); err != nil {
 			return err
@@ -110,3 +123,100 @@ func SpawnStageBeaconState(cfg StageBeaconStateCfg, s *stagedsync.StageState, tx
 	}
 	return nil
 }
+
+// writeLightClientData writes the bootstrap of the attested block when it is an epoch checkpoint and keeps the best
+// update of its sync committee period, beaconState must be the post state of the attested block.
+func writeLightClientData(tx kv.RwTx, beaconCfg *clparams.BeaconChainConfig, beaconState *state.BeaconState, block *cltypes.SignedBeaconBlock) error {
+	if beaconState.Version() < clparams.AltairVersion || block.Version() < clparams.AltairVersion {
+		return nil
+	}
+	stateRoot, err := beaconState.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	// The state root of the latest block header is only filled in at the next slot.
+	attestedHeader := *beaconState.LatestBlockHeader()
+	if attestedHeader.Root == ([32]byte{}) {
+		attestedHeader.Root = stateRoot
+	}
+	attestedRoot, err := attestedHeader.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	// Not our parent, the transition will reject the block.
+	if attestedRoot != block.Block.ParentRoot {
+		return nil
+	}
+
+	// Checkpoint roots are the latest block at or before the epoch start slot.
+	checkpointSlot := block.Block.Slot / beaconCfg.SlotsPerEpoch * beaconCfg.SlotsPerEpoch
+	if attestedHeader.Slot%beaconCfg.SlotsPerEpoch == 0 || (attestedHeader.Slot < checkpointSlot && checkpointSlot < block.Block.Slot) {
+		branch, err := beaconState.LeafBranch(state.CurrentSyncCommitteeLeafIndex)
+		if err != nil {
+			return err
+		}
+		if err := rawdb.WriteLightClientBootstrap(tx, attestedRoot, &cltypes.LightClientBootstrap{
+			Header:                     &attestedHeader,
+			CurrentSyncCommittee:       beaconState.CurrentSyncCommittee(),
+			CurrentSyncCommitteeBranch: branch,
+		}); err != nil {
+			return err
+		}
+	}
+
+	syncAggregate := block.Block.Body.SyncAggregate
+	if uint64(syncAggregate.Sum()) < beaconCfg.MinSyncCommitteeParticipants {
+		return nil
+	}
+	nextSyncCommitteeBranch, err := beaconState.LeafBranch(state.NextSyncCommitteeLeafIndex)
+	if err != nil {
+		return err
+	}
+	finalityBranch, err := beaconState.FinalizedRootBranch()
+	if err != nil {
+		return err
+	}
+	// Finalized blocks are epoch checkpoints, their header is in their bootstrap. Before that the header is left empty.
+	finalizedHeader := &cltypes.BeaconBlockHeader{}
+	if finalizedRoot := beaconState.FinalizedCheckpoint().Root; finalizedRoot != ([32]byte{}) {
+		bootstrap, err := rawdb.ReadLightClientBootstrap(tx, finalizedRoot)
+		if err != nil {
+			return err
+		}
+		if bootstrap != nil {
+			finalizedHeader = bootstrap.Header
+		}
+	}
+	update := &cltypes.LightClientUpdate{
+		AttestedHeader:          &attestedHeader,
+		NextSyncCommitee:        beaconState.NextSyncCommittee(),
+		NextSyncCommitteeBranch: nextSyncCommitteeBranch,
+		FinalizedHeader:         finalizedHeader,
+		FinalityBranch:          finalityBranch,
+		SyncAggregate:           syncAggregate,
+		SignatureSlot:           block.Block.Slot,
+	}
+	bestUpdate, err := rawdb.ReadLightClientUpdate(tx, utils.SlotToPeriod(attestedHeader.Slot))
+	if err != nil {
+		return err
+	}
+	if bestUpdate == nil || cltypes.IsBetterUpdate(bestUpdate, update) {
+		if err := rawdb.WriteLightClientUpdate(tx, update); err != nil {
+			return err
+		}
+	}
+	if err := rawdb.WriteLightClientFinalityUpdate(tx, &cltypes.LightClientFinalityUpdate{
+		AttestedHeader:  update.AttestedHeader,
+		FinalizedHeader: update.FinalizedHeader,
+		FinalityBranch:  update.FinalityBranch,
+		SyncAggregate:   update.SyncAggregate,
+		SignatureSlot:   update.SignatureSlot,
+	}); err != nil {
+		return err
+	}
+	return rawdb.WriteLightClientOptimisticUpdate(tx, &cltypes.LightClientOptimisticUpdate{
+		AttestedHeader: update.AttestedHeader,
+		SyncAggregate:  update.SyncAggregate,
+		SignatureSlot:  update.SignatureSlot,
+	})
+}
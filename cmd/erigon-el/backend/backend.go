@@ -68,8 +68,10 @@ import (
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/ethconfig/estimate"
 	"github.com/ledgerwatch/erigon/eth/ethconsensusconfig"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	snapproto "github.com/ledgerwatch/erigon/eth/protocols/snap"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
@@ -130,6 +132,7 @@ type Ethereum struct {
 	sentryCancel   context.CancelFunc
 	sentriesClient *sentry.MultiClient
 	sentryServers  []*sentry.GrpcServer
+	sentryAdmins   privateapi.SentryAdmins
 
 	stagedSync *stagedsync.Sync
 
@@ -290,6 +293,7 @@ func NewBackend(stack *node.Node, config *ethconfig.Config, logger log.Logger) (
 				return nil, err
 			}
 			sentries = append(sentries, sentryClient)
+			backend.sentryAdmins = append(backend.sentryAdmins, sentryClient)
 		}
 	} else {
 		var readNodeInfo = func() *eth.NodeInfo {
@@ -313,6 +317,11 @@ func NewBackend(stack *node.Node, config *ethconfig.Config, logger log.Logger) (
 			return nil, err
 		}
 
+		var snapHandler *snapproto.Handler
+		if refCfg.ServeSnap {
+			snapHandler = snapproto.NewHandler(backend.sentryCtx, backend.chainDB, backend.blockReader)
+		}
+
 		var pi int // points to next port to be picked from refCfg.AllowedPorts
 		for _, protocol := range refCfg.ProtocolVersion {
 			cfg := refCfg
@@ -340,8 +349,13 @@ func NewBackend(stack *node.Node, config *ethconfig.Config, logger log.Logger) (
 			cfg.ListenAddr = fmt.Sprintf("%s:%d", listenHost, listenPort)
 
 			server := sentry.NewGrpcServer(backend.sentryCtx, discovery, readNodeInfo, &cfg, protocol)
+			if snapHandler != nil {
+				server.ExtraProtocols = append(server.ExtraProtocols, snapHandler.Protocol())
+			}
 			backend.sentryServers = append(backend.sentryServers, server)
-			sentries = append(sentries, direct.NewSentryClientDirect(protocol, server))
+			sentryClient := sentry.NewSentryClientLocal(protocol, server)
+			sentries = append(sentries, sentryClient)
+			backend.sentryAdmins = append(backend.sentryAdmins, sentryClient)
 		}
 
 		go func() {
@@ -407,6 +421,10 @@ func NewBackend(stack *node.Node, config *ethconfig.Config, logger log.Logger) (
 		consensusConfig = &config.Ethash
 	}
 	backend.engine = ethconsensusconfig.CreateConsensusEngine(chainConfig, logger, consensusConfig, config.Miner.Notify, config.Miner.Noverify, config.HeimdallURL, config.WithoutHeimdall, stack.DataDir(), allSnapshots, false /* readonly */, backend.chainDB)
+	if casted, ok := backend.engine.(*bor.Bor); ok && config.HeimdallRecordDir != "" {
+		log.Info("Recording heimdall responses", "dir", config.HeimdallRecordDir)
+		casted.SetHeimdallClient(bor.NewRecordingHeimdallClient(casted.HeimdallClient, bor.NewHeimdallStore(config.HeimdallRecordDir)))
+	}
 	backend.forkValidator = engineapi.NewForkValidator(currentBlockNumber, inMemoryExecution, tmpdir)
 
 	if err != nil {
@@ -441,11 +459,15 @@ func NewBackend(stack *node.Node, config *ethconfig.Config, logger log.Logger) (
 		backend.newTxs2 = make(chan types2.Hashes, 1024)
 		//defer close(newTxs)
 		backend.txPool2DB, backend.txPool2, backend.txPool2Fetch, backend.txPool2Send, backend.txPool2GrpcServer, err = txpooluitl.AllComponents(
-			ctx, config.TxPool, kvcache.NewDummy(), backend.newTxs2, backend.chainDB, backend.sentriesClient.Sentries(), stateDiffClient,
+			ctx, config.TxPool, kvcache.NewDummy(), backend.newTxs2, backend.chainDB, sentry.TxPoolSentries(backend.sentriesClient.Sentries()), stateDiffClient,
 		)
 		if err != nil {
 			return nil, err
 		}
+		txPoolClient := direct.NewTxPoolClient(backend.txPool2GrpcServer)
+		for _, server := range backend.sentryServers {
+			server.TxPool = txPoolClient
+		}
 	}
 
 	backend.notifyMiningAboutNewTxs = make(chan struct{}, 1)
@@ -644,10 +666,18 @@ func NewBackend(stack *node.Node, config *ethconfig.Config, logger log.Logger) (
 	if casted, ok := backend.engine.(*bor.Bor); ok {
 		borDb = casted.DB
 	}
-	apiList := commands.APIList(chainKv, borDb, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, backend.blockReader, backend.agg, httpRpcCfg, backend.engine)
+	signer, err := cli.Signer(ctx, httpRpcCfg)
+	if err != nil {
+		return nil, err
+	}
+	apiList := commands.APIList(chainKv, borDb, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, backend.blockReader, backend.agg, httpRpcCfg, backend.engine, signer)
 	authApiList := commands.AuthAPIList(chainKv, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, backend.blockReader, backend.agg, httpRpcCfg, backend.engine)
+	resultCache, err := cli.ResultCache(ctx, httpRpcCfg, chainKv, backend.blockReader, ff)
+	if err != nil {
+		return nil, err
+	}
 	go func() {
-		if err := cli.StartRpcServer(ctx, httpRpcCfg, apiList, authApiList); err != nil {
+		if err := cli.StartRpcServer(ctx, httpRpcCfg, apiList, authApiList, resultCache); err != nil {
 			log.Error(err.Error())
 			return
 		}
@@ -840,6 +870,32 @@ func (s *Ethereum) NodesInfo(limit int) (*remote.NodesInfoReply, error) {
 	return nodesInfo, nil
 }
 
+// SyncProgress reports the progress of the sync stages, it is empty until the staged sync is set up.
+func (s *Ethereum) SyncProgress() []stages.Progress {
+	if s.stagedSync == nil {
+		return nil
+	}
+	return s.stagedSync.Progress()
+}
+
+// CliqueAPI controls the votes of the clique sealer, nil if the chain isn't clique.
+func (s *Ethereum) CliqueAPI() *clique.API {
+	var clq *clique.Clique
+	if c, ok := s.engine.(*clique.Clique); ok {
+		clq = c
+	} else if cl, ok := s.engine.(*serenity.Serenity); ok {
+		if c, ok := cl.InnerEngine().(*clique.Clique); ok {
+			clq = c
+		}
+	}
+	if clq == nil {
+		return nil
+	}
+	return clique.NewAPI(clq, s.chainDB, func(tx kv.Getter) consensus.ChainHeaderReader {
+		return stagedsync.ChainReader{Cfg: *s.chainConfig, Db: tx}
+	})
+}
+
 // sets up blockReader and client downloader
 func (s *Ethereum) setUpBlockReader(ctx context.Context, dirs datadir.Dirs, snConfig ethconfig.Snapshot, downloaderCfg *downloadercfg.Cfg) (services.FullBlockReader, *snapshotsync.RoSnapshots, *libstate.AggregatorV3, error) {
 	if !snConfig.Enabled {
@@ -847,6 +903,16 @@ func (s *Ethereum) setUpBlockReader(ctx context.Context, dirs datadir.Dirs, snCo
 		return blockReader, nil, nil, nil
 	}
 
+	var verifyReport *snapshotsync.VerifyReport
+	if snConfig.VerifySegments {
+		chainID, _ := uint256.FromBig(s.chainConfig.ChainID)
+		var err error
+		verifyReport, err = snapshotsync.VerifyAndHealSnapshots(ctx, "snapshots", s.chainDB, dirs, s.chainConfig.ChainName, *chainID, estimate.IndexSnapshot.Workers())
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("verify snapshots: %w", err)
+		}
+	}
+
 	allSnapshots := snapshotsync.NewRoSnapshots(snConfig, dirs.Snap)
 	var err error
 	if !snConfig.NoDownloader {
@@ -875,6 +941,11 @@ func (s *Ethereum) setUpBlockReader(ctx context.Context, dirs datadir.Dirs, snCo
 		if err != nil {
 			return nil, nil, nil, err
 		}
+		if verifyReport != nil {
+			if err := snapshotsync.RedownloadSnapshots(ctx, "snapshots", verifyReport, s.downloaderClient); err != nil {
+				return nil, nil, nil, err
+			}
+		}
 	}
 
 	dir.MustExist(dirs.SnapHistory)
@@ -901,6 +972,36 @@ func (s *Ethereum) Peers(ctx context.Context) (*remote.PeersReply, error) {
 	return &reply, nil
 }
 
+// AddPeer makes one of the sentries connect to the node and reconnect when disconnected, across restarts too
+func (s *Ethereum) AddPeer(ctx context.Context, url string) error {
+	return s.sentryAdmins.AddPeer(ctx, url)
+}
+
+// RemovePeer disconnects the sentries from the node added with AddPeer
+func (s *Ethereum) RemovePeer(ctx context.Context, url string) error {
+	return s.sentryAdmins.RemovePeer(ctx, url)
+}
+
+// AddTrustedPeer lets the node connect to the sentries even when they have no peer slots left
+func (s *Ethereum) AddTrustedPeer(ctx context.Context, url string) error {
+	return s.sentryAdmins.AddTrustedPeer(ctx, url)
+}
+
+// RemoveTrustedPeer removes the node added with AddTrustedPeer, without disconnecting it
+func (s *Ethereum) RemoveTrustedPeer(ctx context.Context, url string) error {
+	return s.sentryAdmins.RemoveTrustedPeer(ctx, url)
+}
+
+// BanPeer disconnects the peer with the public key and bans it for good
+func (s *Ethereum) BanPeer(ctx context.Context, peerID []byte) error {
+	return s.sentryAdmins.BanPeer(ctx, peerID)
+}
+
+// PeerScores returns the scores of the connected peers by their IDs
+func (s *Ethereum) PeerScores(ctx context.Context) (map[string]*p2p.PeerScore, error) {
+	return s.sentryAdmins.PeerScores(ctx)
+}
+
 // Protocols returns all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
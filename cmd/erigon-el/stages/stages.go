@@ -56,7 +56,7 @@ func NewStagedSync(ctx context.Context,
 	// Hence we run it in the test mode.
 	runInTestMode := cfg.ImportMode
 
-	return stagedsync.New(
+	stagesList, unwindOrder, pruneOrder, err := stagedsync.WithCustomStages(db,
 		ExecutionStages(ctx, cfg.Prune,
 			stagedsync.StageSnapshotsCfg(db, *controlServer.ChainConfig, dirs, snapshots, blockRetire, snapDownloader, blockReader, notifications.Events, engine, cfg.HistoryV3, agg),
 			stagedsync.StageHeadersCfg(
@@ -107,5 +107,9 @@ func NewStagedSync(ctx context.Context,
 			stagedsync.StageFinishCfg(db, dirs.Tmp, forkValidator), runInTestMode),
 		stagedsync.DefaultUnwindOrder,
 		stagedsync.DefaultPruneOrder,
-	), nil
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stagedsync.New(stagesList, unwindOrder, pruneOrder), nil
 }
@@ -4,16 +4,21 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ledgerwatch/log/v3"
 	"github.com/urfave/cli/v2"
 
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	erigonapp "github.com/ledgerwatch/erigon/turbo/app"
 	erigoncli "github.com/ledgerwatch/erigon/turbo/cli"
+	"github.com/ledgerwatch/erigon/turbo/logging"
+	"github.com/ledgerwatch/erigon/turbo/node"
 )
 
-// defining a custom command-line flag, a string
-var flag = cli.StringFlag{
-	Name:  "custom-stage-greeting",
-	Value: "default-value",
+// defining a custom command-line flag, an integer
+var flag = cli.Uint64Flag{
+	Name:  "custom-stage.keep-blocks",
+	Usage: "How many of the latest blocks the custom stage keeps the log counts of, 0 keeps all of them",
+	Value: 0,
 }
 
 // defining a custom bucket name
@@ -21,6 +26,13 @@ const (
 	customBucketName = "ch.torquem.demo.tgcustom.CUSTOM_BUCKET" //nolint
 )
 
+// registering the custom stage with its bucket, before the node opens the database
+func init() {
+	if err := stagedsync.RegisterCustomStage(logCountCustomStage()); err != nil {
+		panic(err)
+	}
+}
+
 // the regular main function
 func main() {
 	// initializing Erigon application here and providing our custom flag
@@ -34,19 +46,23 @@ func main() {
 }
 
 // Erigon main function
-func runErigon(ctx *cli.Context) error {
-	// running a node and initializing a custom bucket with all default settings
-	//eri := node.New(ctx, node.Params{
-	//	CustomBuckets: map[string]dbutils.BucketConfigItem{
-	//		customBucketName: {},
-	//	},
-	//})
-
-	//err := eri.Serve()
-
-	//if err != nil {
-	//	log.Error("error while serving a Erigon node", "err", err)
-	//  return err
-	//}
-	return nil
+func runErigon(cliCtx *cli.Context) error {
+	keepBlocks = cliCtx.Uint64(flag.Name)
+
+	logger := logging.GetLoggerCtx("erigoncustom", cliCtx)
+
+	// running a node with all default settings, its sync includes the custom stage
+	nodeCfg := node.NewNodConfigUrfave(cliCtx)
+	ethCfg := node.NewEthConfigUrfave(cliCtx, nodeCfg)
+
+	ethNode, err := node.New(nodeCfg, ethCfg, logger)
+	if err != nil {
+		log.Error("Erigon startup", "err", err)
+		return err
+	}
+	err = ethNode.Serve()
+	if err != nil {
+		log.Error("error while serving an Erigon node", "err", err)
+	}
+	return err
 }
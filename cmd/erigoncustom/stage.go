@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+)
+
+// the custom stage indexes how many logs every block emitted: block_num_u64 -> logs_count_u64
+const logCountStage stages.SyncStage = "ch.torquem.demo.tgcustom.LogCount"
+
+// keepBlocks is how many of the latest blocks the custom stage keeps in its table, 0 keeps all of them
+var keepBlocks uint64
+
+func logCountCustomStage() stagedsync.CustomStage {
+	return stagedsync.CustomStage{
+		Stage: &stagedsync.Stage{
+			ID:          logCountStage,
+			Description: "Count logs of every block",
+			// it only reads what Execution wrote, so it can run together with the index stages
+			DependsOn: []stages.SyncStage{stages.Execution},
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *stagedsync.StageState, u stagedsync.Unwinder, tx kv.RwTx, quiet bool) error {
+				return spawnLogCount(s, tx, quiet)
+			},
+			Unwind: func(firstCycle bool, u *stagedsync.UnwindState, s *stagedsync.StageState, tx kv.RwTx) error {
+				return unwindLogCount(u, tx)
+			},
+			Prune: func(firstCycle bool, p *stagedsync.PruneState, tx kv.RwTx) error {
+				return pruneLogCount(p, tx)
+			},
+		},
+		After:  stages.TxLookup,
+		Tables: kv.TableCfg{customBucketName: {}},
+	}
+}
+
+func spawnLogCount(s *stagedsync.StageState, tx kv.RwTx, quiet bool) error {
+	to, err := s.ExecutionAt(tx)
+	if err != nil {
+		return err
+	}
+	if to <= s.BlockNumber {
+		return nil
+	}
+	logPrefix := s.LogPrefix()
+	if !quiet && to > s.BlockNumber+16 {
+		log.Info(fmt.Sprintf("[%s] Counting logs", logPrefix), "from", s.BlockNumber+1, "to", to)
+	}
+	logEvery := time.NewTicker(30 * time.Second)
+	defer logEvery.Stop()
+
+	logs, err := tx.Cursor(kv.Log)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	count := make([]byte, 8)
+	for blockNum := s.BlockNumber + 1; blockNum <= to; blockNum++ {
+		var n uint64
+		prefix := dbutils.EncodeBlockNumber(blockNum)
+		for k, v, err := logs.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v, err = logs.Next() {
+			if err != nil {
+				return err
+			}
+			var txLogs types.Logs
+			if err := cbor.Unmarshal(&txLogs, bytes.NewReader(v)); err != nil {
+				return fmt.Errorf("receipt unmarshal failed: %w, block=%d", err, blockNum)
+			}
+			n += uint64(len(txLogs))
+		}
+		binary.BigEndian.PutUint64(count, n)
+		if err := tx.Put(customBucketName, prefix, count); err != nil {
+			return err
+		}
+
+		select {
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "block", blockNum)
+			s.ReportProgress(blockNum, to)
+		default:
+		}
+	}
+	return s.Update(tx, to)
+}
+
+func unwindLogCount(u *stagedsync.UnwindState, tx kv.RwTx) error {
+	if err := deleteLogCounts(tx, u.UnwindPoint+1, nil); err != nil {
+		return err
+	}
+	return u.Done(tx)
+}
+
+func pruneLogCount(p *stagedsync.PruneState, tx kv.RwTx) error {
+	if keepBlocks == 0 || p.ForwardProgress <= keepBlocks {
+		return nil
+	}
+	pruneTo := p.ForwardProgress - keepBlocks
+	if err := deleteLogCounts(tx, 0, dbutils.EncodeBlockNumber(pruneTo)); err != nil {
+		return err
+	}
+	return p.DoneAt(tx, pruneTo)
+}
+
+// deleteLogCounts removes the counts of blocks from the given one up to the to key, nil to means up to the end
+func deleteLogCounts(tx kv.RwTx, from uint64, to []byte) error {
+	c, err := tx.RwCursor(customBucketName)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(dbutils.EncodeBlockNumber(from)); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if to != nil && bytes.Compare(k, to) >= 0 {
+			break
+		}
+		if err := c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/params"
+	stages2 "github.com/ledgerwatch/erigon/turbo/stages"
+)
+
+func TestLogCountStage(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: &params.ChainConfig{
+				ChainID:               big.NewInt(1),
+				HomesteadBlock:        new(big.Int),
+				TangerineWhistleBlock: new(big.Int),
+				SpuriousDragonBlock:   new(big.Int),
+				ByzantiumBlock:        new(big.Int),
+				ConstantinopleBlock:   new(big.Int),
+			},
+			Alloc: core.GenesisAlloc{
+				address: {Balance: big.NewInt(1_000_000_000_000_000_000)},
+			},
+		}
+		signer = types.LatestSignerForChainID(nil)
+		// creates an empty contract and emits a single log on the way: PUSH1 0 PUSH1 0 LOG0 STOP
+		initCode = common.FromHex("0x60006000a000")
+	)
+	t.Cleanup(func() { keepBlocks = 0 })
+
+	// the stage is registered by init, so the mock creates its table and adds it to the sync
+	m := stages2.MockWithGenesis(t, gspec, key, false)
+	var ids []stages.SyncStage
+	for _, p := range m.Sync.Progress() {
+		ids = append(ids, p.Stage)
+	}
+	require.Contains(t, ids, stages.TxLookup)
+	for i, id := range ids {
+		if id == stages.TxLookup {
+			require.Equal(t, logCountStage, ids[i+1])
+		}
+	}
+
+	// block N has N transactions, one log each
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 3, func(i int, block *core.BlockGen) {
+		for j := 0; j <= i; j++ {
+			tx, err := types.SignTx(types.NewContractCreation(block.TxNonce(address), uint256.NewInt(0), 100_000, uint256.NewInt(1), initCode), *signer, key)
+			require.NoError(t, err)
+			block.AddTx(tx)
+		}
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(chain))
+	require.Equal(t, map[uint64]uint64{1: 1, 2: 2, 3: 3}, logCounts(t, m.DB))
+
+	// the longer fork without transactions unwinds the counts of the first chain
+	fork, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 5, func(i int, block *core.BlockGen) {
+		block.SetCoinbase(common.Address{1})
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(fork))
+	require.Equal(t, map[uint64]uint64{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}, logCounts(t, m.DB))
+
+	// only the counts of the latest blocks are kept after pruning
+	keepBlocks = 2
+	extension, err := core.GenerateChain(m.ChainConfig, fork.TopBlock, m.Engine, m.DB, 2, func(i int, block *core.BlockGen) {
+		block.SetCoinbase(common.Address{1})
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(extension))
+	require.Equal(t, map[uint64]uint64{5: 0, 6: 0, 7: 0}, logCounts(t, m.DB))
+
+	err = m.DB.View(context.Background(), func(tx kv.Tx) error {
+		progress, err := stages.GetStageProgress(tx, logCountStage)
+		require.NoError(t, err)
+		require.Equal(t, uint64(7), progress)
+		pruneProgress, err := stages.GetStagePruneProgress(tx, logCountStage)
+		require.NoError(t, err)
+		require.Equal(t, uint64(5), pruneProgress)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func logCounts(t *testing.T, db kv.RoDB) map[uint64]uint64 {
+	t.Helper()
+	counts := map[uint64]uint64{}
+	err := db.View(context.Background(), func(tx kv.Tx) error {
+		return tx.ForEach(customBucketName, nil, func(k, v []byte) error {
+			counts[binary.BigEndian.Uint64(k)] = binary.BigEndian.Uint64(v)
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	return counts
+}
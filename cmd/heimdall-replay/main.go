@@ -0,0 +1,45 @@
+// heimdall-replay serves the heimdall responses recorded by erigon with --bor.heimdall.record over the
+// REST API of heimdall, as a local stand-in for it: bor nodes pointed at it with --bor.heimdall sync offline.
+package main
+
+import (
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/consensus/bor"
+	"github.com/ledgerwatch/erigon/turbo/logging"
+)
+
+func main() {
+	var (
+		listenAddr = flag.String("addr", "localhost:1317", "listen address")
+		dir        = flag.String("dir", "", "directory of the recorded responses")
+	)
+	flag.Parse()
+
+	logger := logging.GetLogger("heimdall-replay")
+
+	if *dir == "" {
+		utils.Fatalf("-dir is required")
+	}
+	if _, err := os.Stat(*dir); err != nil {
+		utils.Fatalf("-dir: %v", err)
+	}
+	listener, err := net.Listen("tcp", *listenAddr)
+	if err != nil {
+		utils.Fatalf("-addr: %v", err)
+	}
+	logger.Info("Serving recorded heimdall responses", "addr", listener.Addr(), "dir", *dir)
+	srv := &http.Server{
+		Handler:           bor.NewHeimdallServer(bor.NewHeimdallStore(*dir)),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	if err := srv.Serve(listener); err != nil {
+		logger.Error("Heimdall stand-in stopped", "err", err)
+		os.Exit(1)
+	}
+}
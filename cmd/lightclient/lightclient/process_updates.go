@@ -7,56 +7,6 @@ import (
 	"github.com/ledgerwatch/erigon/cl/utils"
 )
 
-func (l *LightClient) isBetterUpdate(oldUpdate *cltypes.LightClientUpdate, newUpdate *cltypes.LightClientUpdate) bool {
-	var (
-		maxActiveParticipants = len(newUpdate.SyncAggregate.SyncCommiteeBits) * 8 // Bits
-		newActiveParticipants = newUpdate.SyncAggregate.Sum()
-		oldActiveParticipants = oldUpdate.SyncAggregate.Sum()
-		newHasSuperMajority   = newActiveParticipants*3 >= maxActiveParticipants*2
-		oldHasSuperMajority   = oldActiveParticipants*3 >= maxActiveParticipants*2
-	)
-
-	// Compare supermajority (> 2/3) sync committee participation
-	if newHasSuperMajority != oldHasSuperMajority {
-		return newHasSuperMajority && !oldHasSuperMajority
-	}
-
-	if !newHasSuperMajority && newActiveParticipants != oldActiveParticipants {
-		return newActiveParticipants > oldActiveParticipants
-	}
-
-	// Compare presence of relevant sync committee
-	isNewUpdateRelevant := newUpdate.HasNextSyncCommittee() &&
-		utils.SlotToPeriod(newUpdate.AttestedHeader.Slot) == utils.SlotToPeriod(newUpdate.SignatureSlot)
-	isOldUpdateRelevant := oldUpdate.HasNextSyncCommittee() &&
-		utils.SlotToPeriod(oldUpdate.AttestedHeader.Slot) == utils.SlotToPeriod(oldUpdate.SignatureSlot)
-
-	if isNewUpdateRelevant != isOldUpdateRelevant {
-		return isNewUpdateRelevant
-	}
-
-	isNewFinality := newUpdate.IsFinalityUpdate()
-	isOldFinality := oldUpdate.IsFinalityUpdate()
-
-	if isNewFinality != isOldFinality {
-		return isNewFinality
-	}
-
-	// Compare sync committee finality
-	if isNewFinality && newUpdate.HasSyncFinality() != oldUpdate.HasSyncFinality() {
-		return newUpdate.HasSyncFinality()
-	}
-
-	// Tie Breakers
-	if newActiveParticipants != oldActiveParticipants {
-		return newActiveParticipants > oldActiveParticipants
-	}
-	if newUpdate.AttestedHeader.Slot != oldUpdate.AttestedHeader.Slot {
-		return newUpdate.AttestedHeader.Slot < oldUpdate.AttestedHeader.Slot
-	}
-	return newUpdate.SignatureSlot < oldUpdate.SignatureSlot
-}
-
 func (l *LightClient) applyLightClientUpdate(update *cltypes.LightClientUpdate) error {
 	storePeriod := utils.SlotToPeriod(l.store.finalizedHeader.Slot)
 	finalizedPeriod := utils.SlotToPeriod(update.FinalizedHeader.Slot)
@@ -89,7 +39,7 @@ func (l *LightClient) processLightClientUpdate(update *cltypes.LightClientUpdate
 		return fmt.Errorf("BLS validation failed")
 	}
 
-	if l.store.bestValidUpdate == nil || l.isBetterUpdate(update, l.store.bestValidUpdate) {
+	if l.store.bestValidUpdate == nil || cltypes.IsBetterUpdate(update, l.store.bestValidUpdate) {
 		l.store.bestValidUpdate = update
 	}
 	updateParticipants := uint64(update.SyncAggregate.Sum())
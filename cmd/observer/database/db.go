@@ -25,6 +25,25 @@ type HandshakeError struct {
 	Time       time.Time
 }
 
+type NodeENR struct {
+	Seq     uint64
+	Text    string            // "enr:..." text form of the record
+	Entries map[string][]byte // RLP values of the record by key
+
+	EthForkHash    *string // hex of the "eth" entry fork ID hash
+	EthForkNext    *uint64
+	Eth2ForkDigest *string // hex of the "eth2" entry fork digest
+}
+
+type DiscoveredNode struct {
+	ClientID       *string
+	EthForkHash    *string
+	EthForkNext    *uint64
+	Eth2ForkDigest *string
+	IsDiscV4       bool
+	IsDiscV5       bool
+}
+
 type DB interface {
 	io.Closer
 
@@ -53,6 +72,10 @@ type DB interface {
 
 	UpdateForkCompatibility(ctx context.Context, id NodeID, isCompatFork bool) error
 
+	// UpsertENR replaces the record of the node and its entries unless a newer one is known.
+	UpsertENR(ctx context.Context, id NodeID, enr NodeENR) error
+	UpdateDiscVersion(ctx context.Context, id NodeID, version uint, isSupported bool) error
+
 	UpdateNeighborBucketKeys(ctx context.Context, id NodeID, keys []string) error
 	FindNeighborBucketKeys(ctx context.Context, id NodeID) ([]string, error)
 
@@ -74,4 +97,5 @@ type DB interface {
 	CountClientsWithNetworkID(ctx context.Context, clientIDPrefix string, maxPingTries uint) (uint, error)
 	CountClientsWithHandshakeTransientError(ctx context.Context, clientIDPrefix string, maxPingTries uint) (uint, error)
 	EnumerateClientIDs(ctx context.Context, maxPingTries uint, networkID uint, enumFunc func(clientID *string)) error
+	EnumerateDiscoveredNodes(ctx context.Context, maxPingTries uint, enumFunc func(node DiscoveredNode)) error
 }
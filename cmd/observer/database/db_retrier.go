@@ -198,6 +198,20 @@ func (db DBRetrier) UpdateForkCompatibility(ctx context.Context, id NodeID, isCo
 	return err
 }
 
+func (db DBRetrier) UpsertENR(ctx context.Context, id NodeID, enr NodeENR) error {
+	_, err := db.retry(ctx, "UpsertENR", func(ctx context.Context) (interface{}, error) {
+		return nil, db.db.UpsertENR(ctx, id, enr)
+	})
+	return err
+}
+
+func (db DBRetrier) UpdateDiscVersion(ctx context.Context, id NodeID, version uint, isSupported bool) error {
+	_, err := db.retry(ctx, "UpdateDiscVersion", func(ctx context.Context) (interface{}, error) {
+		return nil, db.db.UpdateDiscVersion(ctx, id, version, isSupported)
+	})
+	return err
+}
+
 func (db DBRetrier) UpdateNeighborBucketKeys(ctx context.Context, id NodeID, keys []string) error {
 	_, err := db.retry(ctx, "UpdateNeighborBucketKeys", func(ctx context.Context) (interface{}, error) {
 		return nil, db.db.UpdateNeighborBucketKeys(ctx, id, keys)
@@ -60,6 +60,31 @@ CREATE TABLE IF NOT EXISTS sentry_candidates_intake (
     last_event_time INTEGER NOT NULL
 );
 
+CREATE TABLE IF NOT EXISTS node_enrs (
+    id TEXT PRIMARY KEY,
+    seq INTEGER NOT NULL,
+    enr TEXT NOT NULL,
+    eth_fork_hash TEXT,
+    eth_fork_next INTEGER,
+    eth2_fork_digest TEXT,
+    updated INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS node_enr_entries (
+    id TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value BLOB NOT NULL,
+    PRIMARY KEY (id, key)
+);
+
+CREATE TABLE IF NOT EXISTS node_disc_versions (
+    id TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    is_supported INTEGER NOT NULL,
+    updated INTEGER NOT NULL,
+    PRIMARY KEY (id, version)
+);
+
 CREATE INDEX IF NOT EXISTS idx_nodes_crawl_retry_time ON nodes (crawl_retry_time);
 CREATE INDEX IF NOT EXISTS idx_nodes_ip ON nodes (ip);
 CREATE INDEX IF NOT EXISTS idx_nodes_ip_v6 ON nodes (ip_v6);
@@ -68,6 +93,7 @@ CREATE INDEX IF NOT EXISTS idx_nodes_compat_fork ON nodes (compat_fork);
 CREATE INDEX IF NOT EXISTS idx_nodes_network_id ON nodes (network_id);
 CREATE INDEX IF NOT EXISTS idx_nodes_handshake_retry_time ON nodes (handshake_retry_time);
 CREATE INDEX IF NOT EXISTS idx_handshake_errors_id ON handshake_errors (id);
+CREATE INDEX IF NOT EXISTS idx_node_enr_entries_key ON node_enr_entries (key);
 `
 
 	sqlUpsertNodeAddr = `
@@ -196,6 +222,53 @@ UPDATE nodes SET handshake_retry_time = ? WHERE id IN (123)
 UPDATE nodes SET compat_fork = ?, compat_fork_updated = ? WHERE id = ?
 `
 
+	sqlFindENRSeq = `
+SELECT seq FROM node_enrs WHERE id = ?
+`
+
+	sqlUpsertENR = `
+INSERT INTO node_enrs(
+    id,
+    seq,
+    enr,
+    eth_fork_hash,
+    eth_fork_next,
+    eth2_fork_digest,
+    updated
+) VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+    seq = excluded.seq,
+    enr = excluded.enr,
+    eth_fork_hash = excluded.eth_fork_hash,
+    eth_fork_next = excluded.eth_fork_next,
+    eth2_fork_digest = excluded.eth2_fork_digest,
+    updated = excluded.updated
+`
+
+	sqlDeleteENREntries = `
+DELETE FROM node_enr_entries WHERE id = ?
+`
+
+	sqlInsertENREntry = `
+INSERT INTO node_enr_entries(
+    id,
+    key,
+    value
+) VALUES (?, ?, ?)
+`
+
+	sqlUpdateDiscVersion = `
+INSERT INTO node_disc_versions(
+    id,
+    version,
+    is_supported,
+    updated
+) VALUES (?, ?, ?, ?)
+ON CONFLICT(id, version) DO UPDATE SET
+    is_supported = excluded.is_supported,
+    updated = excluded.updated
+`
+
 	sqlUpdateNeighborBucketKeys = `
 UPDATE nodes SET neighbor_keys = ? WHERE id = ?
 `
@@ -283,6 +356,21 @@ SELECT client_id FROM nodes
 WHERE (ping_try < ?)
     AND ((network_id = ?) OR (network_id IS NULL))
     AND ((compat_fork == TRUE) OR (compat_fork IS NULL))
+`
+
+	sqlEnumerateDiscoveredNodes = `
+SELECT
+    nodes.client_id,
+    node_enrs.eth_fork_hash,
+    node_enrs.eth_fork_next,
+    node_enrs.eth2_fork_digest,
+    EXISTS(SELECT 1 FROM node_disc_versions
+        WHERE (node_disc_versions.id = nodes.id) AND (version = 4) AND (is_supported = 1)),
+    EXISTS(SELECT 1 FROM node_disc_versions
+        WHERE (node_disc_versions.id = nodes.id) AND (version = 5) AND (is_supported = 1))
+FROM nodes
+LEFT JOIN node_enrs ON node_enrs.id = nodes.id
+WHERE (nodes.ping_try < ?)
 `
 )
 
@@ -684,6 +772,75 @@ func (db *DBSQLite) UpdateForkCompatibility(ctx context.Context, id NodeID, isCo
 	return nil
 }
 
+func (db *DBSQLite) UpsertENR(ctx context.Context, id NodeID, enr NodeENR) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("UpsertENR failed to start transaction: %w", err)
+	}
+
+	var prevSeq int64
+	err = tx.QueryRowContext(ctx, sqlFindENRSeq, id).Scan(&prevSeq)
+	if (err != nil) && !errors.Is(err, sql.ErrNoRows) {
+		_ = tx.Rollback()
+		return fmt.Errorf("UpsertENR failed to find the record: %w", err)
+	}
+	seq := int64(enr.Seq)
+	if (err == nil) && (prevSeq > seq) {
+		_ = tx.Rollback()
+		return nil
+	}
+
+	var ethForkNext *int64
+	if enr.EthForkNext != nil {
+		value := int64(*enr.EthForkNext)
+		ethForkNext = &value
+	}
+
+	updated := time.Now().Unix()
+
+	_, err = tx.ExecContext(ctx, sqlUpsertENR,
+		id,
+		seq,
+		enr.Text,
+		enr.EthForkHash,
+		ethForkNext,
+		enr.Eth2ForkDigest,
+		updated)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("UpsertENR failed to upsert the record: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, sqlDeleteENREntries, id)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("UpsertENR failed to delete the entries: %w", err)
+	}
+	for key, value := range enr.Entries {
+		_, err = tx.ExecContext(ctx, sqlInsertENREntry, id, key, value)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("UpsertENR failed to insert an entry: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("UpsertENR failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (db *DBSQLite) UpdateDiscVersion(ctx context.Context, id NodeID, version uint, isSupported bool) error {
+	updated := time.Now().Unix()
+
+	_, err := db.db.ExecContext(ctx, sqlUpdateDiscVersion, id, version, isSupported, updated)
+	if err != nil {
+		return fmt.Errorf("UpdateDiscVersion failed: %w", err)
+	}
+	return nil
+}
+
 func (db *DBSQLite) UpdateNeighborBucketKeys(ctx context.Context, id NodeID, keys []string) error {
 	keysStr := strings.Join(keys, ",")
 
@@ -919,6 +1076,58 @@ func (db *DBSQLite) EnumerateClientIDs(
 	return nil
 }
 
+func (db *DBSQLite) EnumerateDiscoveredNodes(
+	ctx context.Context,
+	maxPingTries uint,
+	enumFunc func(node DiscoveredNode),
+) error {
+	cursor, err := db.db.QueryContext(ctx, sqlEnumerateDiscoveredNodes, maxPingTries)
+	if err != nil {
+		return fmt.Errorf("EnumerateDiscoveredNodes failed to query: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close()
+	}()
+
+	for cursor.Next() {
+		var clientID sql.NullString
+		var ethForkHash sql.NullString
+		var ethForkNext sql.NullInt64
+		var eth2ForkDigest sql.NullString
+		var node DiscoveredNode
+		err := cursor.Scan(
+			&clientID,
+			&ethForkHash,
+			&ethForkNext,
+			&eth2ForkDigest,
+			&node.IsDiscV4,
+			&node.IsDiscV5)
+		if err != nil {
+			return fmt.Errorf("EnumerateDiscoveredNodes failed to read data: %w", err)
+		}
+
+		if clientID.Valid {
+			node.ClientID = &clientID.String
+		}
+		if ethForkHash.Valid {
+			node.EthForkHash = &ethForkHash.String
+		}
+		if ethForkNext.Valid {
+			value := uint64(ethForkNext.Int64)
+			node.EthForkNext = &value
+		}
+		if eth2ForkDigest.Valid {
+			node.Eth2ForkDigest = &eth2ForkDigest.String
+		}
+		enumFunc(node)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("EnumerateDiscoveredNodes failed to iterate: %w", err)
+	}
+	return nil
+}
+
 func stringsToAny(strValues []NodeID) []interface{} {
 	values := make([]interface{}, 0, len(strValues))
 	for _, value := range strValues {
@@ -39,3 +39,56 @@ func TestDBSQLiteInsertAndFind(t *testing.T) {
 	assert.Equal(t, addr.PortDisc, candidate.PortDisc)
 	assert.Equal(t, addr.PortRLPx, candidate.PortRLPx)
 }
+
+func TestDBSQLiteUpsertENR(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewDBSQLite(filepath.Join(t.TempDir(), "observer.sqlite"))
+	require.Nil(t, err)
+	defer func() { _ = db.Close() }()
+
+	var id NodeID = "ba85011c70bcc5c04d8607d3a0ed29aa6179c092cbdda10d5d32684fb33ed01bd94f588ca8f91ac48318087dcb02eaf36773a7a453f0eedd6742af668097b29c"
+	var addr NodeAddr
+	addr.IP = net.ParseIP("10.0.1.16")
+	addr.PortDisc = 30304
+	require.Nil(t, db.UpsertNodeAddr(ctx, id, addr))
+
+	forkHash := "fc64ec04"
+	forkNext := uint64(1150000)
+	enr := NodeENR{
+		Seq:         2,
+		Text:        "enr:new",
+		Entries:     map[string][]byte{"id": {0x82, 0x76, 0x34}, "udp": {0x82, 0x76, 0x60}},
+		EthForkHash: &forkHash,
+		EthForkNext: &forkNext,
+	}
+	require.Nil(t, db.UpsertENR(ctx, id, enr))
+
+	// an older record doesn't replace the newer one
+	require.Nil(t, db.UpsertENR(ctx, id, NodeENR{Seq: 1, Text: "enr:old"}))
+
+	var text string
+	require.Nil(t, db.db.QueryRowContext(ctx, "SELECT enr FROM node_enrs WHERE id = ?", id).Scan(&text))
+	assert.Equal(t, "enr:new", text)
+
+	var value []byte
+	require.Nil(t, db.db.QueryRowContext(ctx, "SELECT value FROM node_enr_entries WHERE id = ? AND key = 'udp'", id).Scan(&value))
+	assert.Equal(t, enr.Entries["udp"], value)
+
+	require.Nil(t, db.UpdateDiscVersion(ctx, id, 4, false))
+	require.Nil(t, db.UpdateDiscVersion(ctx, id, 5, true))
+
+	var nodes []DiscoveredNode
+	err = db.EnumerateDiscoveredNodes(ctx, 3, func(node DiscoveredNode) { nodes = append(nodes, node) })
+	require.Nil(t, err)
+	require.Equal(t, 1, len(nodes))
+
+	node := nodes[0]
+	assert.Nil(t, node.ClientID)
+	require.NotNil(t, node.EthForkHash)
+	assert.Equal(t, forkHash, *node.EthForkHash)
+	require.NotNil(t, node.EthForkNext)
+	assert.Equal(t, forkNext, *node.EthForkNext)
+	assert.Nil(t, node.Eth2ForkDigest)
+	assert.False(t, node.IsDiscV4)
+	assert.True(t, node.IsDiscV5)
+}
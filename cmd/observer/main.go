@@ -27,10 +27,14 @@ func mainWithFlags(ctx context.Context, flags observer.CommandFlags) error {
 	}
 	defer func() { _ = db.Close() }()
 
-	discV4, err := server.Listen(ctx)
+	discV4, discV5, err := server.Listen(ctx)
 	if err != nil {
 		return err
 	}
+	var discV5Transport observer.DiscV5Transport
+	if discV5 != nil {
+		discV5Transport = discV5
+	}
 
 	networkID := uint(params.NetworkIDByChainName(flags.Chain))
 	go observer.StatusLoggerLoop(ctx, db, networkID, flags.StatusLogPeriod, log.Root())
@@ -54,7 +58,7 @@ func mainWithFlags(ctx context.Context, flags observer.CommandFlags) error {
 		ErigonLogPath: flags.ErigonLogPath,
 	}
 
-	crawler, err := observer.NewCrawler(discV4, db, crawlerConfig, log.Root())
+	crawler, err := observer.NewCrawler(discV4, discV5Transport, db, crawlerConfig, log.Root())
 	if err != nil {
 		return err
 	}
@@ -80,6 +84,15 @@ func reportWithFlags(ctx context.Context, flags reports.CommandFlags) error {
 		return nil
 	}
 
+	if flags.Discovery {
+		report, err := reports.CreateDiscoveryReport(ctx, db, flags.ClientsLimit, flags.MaxPingTries)
+		if err != nil {
+			return err
+		}
+		fmt.Println(report)
+		return nil
+	}
+
 	if flags.SentryCandidates {
 		report, err := reports.CreateSentryCandidatesReport(ctx, db, flags.ErigonLogPath)
 		if err != nil {
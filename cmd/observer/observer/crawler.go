@@ -20,7 +20,8 @@ import (
 )
 
 type Crawler struct {
-	transport DiscV4Transport
+	transport   DiscV4Transport
+	transportV5 DiscV5Transport
 
 	db        database.DBRetrier
 	saveQueue *utils.TaskQueue
@@ -56,6 +57,7 @@ type CrawlerConfig struct {
 
 func NewCrawler(
 	transport DiscV4Transport,
+	transportV5 DiscV5Transport,
 	db database.DB,
 	config CrawlerConfig,
 	logger log.Logger,
@@ -102,6 +104,7 @@ func NewCrawler(
 
 	instance := Crawler{
 		transport,
+		transportV5,
 		database.NewDBRetrier(db, logger),
 		saveQueue,
 		config,
@@ -324,6 +327,7 @@ func (crawler *Crawler) Run(ctx context.Context) error {
 		interrogator, err := NewInterrogator(
 			node,
 			crawler.transport,
+			crawler.transportV5,
 			crawler.forkFilter,
 			diplomat,
 			handshakeNextRetryTime,
@@ -432,6 +436,34 @@ func (crawler *Crawler) saveInterrogationResult(
 		if dbErr != nil {
 			return dbErr
 		}
+
+		if node_utils.IsSignedENR(peer) {
+			dbErr = crawler.saveENR(ctx, peerID, peer)
+			if dbErr != nil {
+				return dbErr
+			}
+		}
+	}
+
+	if result != nil {
+		dbErr := crawler.db.UpdateDiscVersion(ctx, id, 4, result.IsDiscV4)
+		if dbErr != nil {
+			return dbErr
+		}
+	}
+
+	if (result != nil) && (result.IsDiscV5 != nil) {
+		dbErr := crawler.db.UpdateDiscVersion(ctx, id, 5, *result.IsDiscV5)
+		if dbErr != nil {
+			return dbErr
+		}
+	}
+
+	if (result != nil) && (result.ENR != nil) && node_utils.IsSignedENR(result.ENR) {
+		dbErr := crawler.saveENR(ctx, id, result.ENR)
+		if dbErr != nil {
+			return dbErr
+		}
 	}
 
 	if (result != nil) && (len(result.KeygenKeys) >= 15) {
@@ -511,6 +543,16 @@ func (crawler *Crawler) saveInterrogationResult(
 	return crawler.db.UpdateCrawlRetryTime(ctx, id, nextRetryTime)
 }
 
+func (crawler *Crawler) saveENR(ctx context.Context, id database.NodeID, node *enode.Node) error {
+	enr, err := node_utils.MakeNodeENR(node)
+	if err != nil {
+		// a malformed record of a single node shouldn't fail the whole save
+		crawler.log.Debug("Failed to read ENR", "id", id, "err", err)
+		return nil
+	}
+	return crawler.db.UpsertENR(ctx, id, *enr)
+}
+
 func (crawler *Crawler) nextRetryTime(isPingError bool, prevPingTries uint) time.Time {
 	return time.Now().Add(crawler.nextRetryDelay(isPingError, prevPingTries))
 }
@@ -242,6 +242,7 @@ func makeOurHelloMessage(myPrivateKey *ecdsa.PrivateKey) HelloMessage {
 		{Name: eth.ProtocolName, Version: 65},
 		{Name: eth.ProtocolName, Version: eth.ETH66},
 		{Name: eth.ProtocolName, Version: eth.ETH67},
+		{Name: eth.ProtocolName, Version: eth.ETH68},
 	}
 
 	return HelloMessage{
@@ -22,10 +22,21 @@ type DiscV4Transport interface {
 	FindNode(toNode *enode.Node, targetKey *ecdsa.PublicKey) ([]*enode.Node, error)
 }
 
+type DiscV5Transport interface {
+	RequestENR(*enode.Node) (*enode.Node, error)
+	Ping(*enode.Node) error
+	FindNode(toNode *enode.Node, distances []uint) ([]*enode.Node, error)
+}
+
+// discV5FindNodeDistances is how many top log distances are requested with discv5 FindNode.
+// It matches the number of the keys generated for discv4 FindNode, the lower buckets are almost always empty.
+const discV5FindNodeDistances = 17
+
 type Interrogator struct {
-	node       *enode.Node
-	transport  DiscV4Transport
-	forkFilter forkid.Filter
+	node        *enode.Node
+	transport   DiscV4Transport
+	transportV5 DiscV5Transport // nil unless discv5 crawling is enabled
+	forkFilter  forkid.Filter
 
 	diplomat           *Diplomat
 	handshakeRetryTime *time.Time
@@ -40,6 +51,9 @@ type Interrogator struct {
 
 type InterrogationResult struct {
 	Node               *enode.Node
+	IsDiscV4           bool
+	IsDiscV5           *bool // nil unless discv5 crawling is enabled
+	ENR                *enode.Node
 	IsCompatFork       *bool
 	HandshakeResult    *DiplomatResult
 	HandshakeRetryTime *time.Time
@@ -50,6 +64,7 @@ type InterrogationResult struct {
 func NewInterrogator(
 	node *enode.Node,
 	transport DiscV4Transport,
+	transportV5 DiscV5Transport,
 	forkFilter forkid.Filter,
 	diplomat *Diplomat,
 	handshakeRetryTime *time.Time,
@@ -62,6 +77,7 @@ func NewInterrogator(
 	instance := Interrogator{
 		node,
 		transport,
+		transportV5,
 		forkFilter,
 		diplomat,
 		handshakeRetryTime,
@@ -77,16 +93,30 @@ func NewInterrogator(
 func (interrogator *Interrogator) Run(ctx context.Context) (*InterrogationResult, *InterrogationError) {
 	interrogator.log.Debug("Interrogating a node")
 
-	err := interrogator.transport.Ping(interrogator.node)
-	if err != nil {
-		return nil, NewInterrogationError(InterrogationErrorPing, err)
+	pingErr := interrogator.transport.Ping(interrogator.node)
+	isDiscV4 := pingErr == nil
+
+	var isDiscV5 *bool
+	if interrogator.transportV5 != nil {
+		err := interrogator.transportV5.Ping(interrogator.node)
+		isDiscV5 = new(bool)
+		*isDiscV5 = err == nil
+		if err != nil {
+			interrogator.log.Trace("Discv5 Ping failed", "err", err)
+		}
 	}
 
-	// The outgoing Ping above triggers an incoming Ping.
-	// We need to wait until Server sends a Pong reply to that.
-	// The remote side is waiting for this Pong no longer than v4_udp.respTimeout.
-	// If we don't wait, the ENRRequest/FindNode might fail due to errUnknownNode.
-	utils.Sleep(ctx, 500*time.Millisecond)
+	if !isDiscV4 && ((isDiscV5 == nil) || !*isDiscV5) {
+		return nil, NewInterrogationError(InterrogationErrorPing, pingErr)
+	}
+
+	if isDiscV4 {
+		// The outgoing Ping above triggers an incoming Ping.
+		// We need to wait until Server sends a Pong reply to that.
+		// The remote side is waiting for this Pong no longer than v4_udp.respTimeout.
+		// If we don't wait, the ENRRequest/FindNode might fail due to errUnknownNode.
+		utils.Sleep(ctx, 500*time.Millisecond)
+	}
 
 	// request client ID
 	var handshakeResult *DiplomatResult
@@ -105,9 +135,16 @@ func (interrogator *Interrogator) Run(ctx context.Context) (*InterrogationResult
 	// request ENR
 	var forkID *forkid.ID
 	var enr *enode.Node
-	if (handshakeResult == nil) || (handshakeResult.ClientID == nil) || isENRRequestSupportedByClientID(*handshakeResult.ClientID) {
+	var err error
+	if isDiscV4 && ((handshakeResult == nil) || (handshakeResult.ClientID == nil) || isENRRequestSupportedByClientID(*handshakeResult.ClientID)) {
 		enr, err = interrogator.transport.RequestENR(interrogator.node)
 	}
+	if (enr == nil) && (isDiscV5 != nil) && *isDiscV5 {
+		if err != nil {
+			interrogator.log.Debug("ENR request failed", "err", err)
+		}
+		enr, err = interrogator.transportV5.RequestENR(interrogator.node)
+	}
 	if err != nil {
 		interrogator.log.Debug("ENR request failed", "err", err)
 	} else if enr != nil {
@@ -133,9 +170,12 @@ func (interrogator *Interrogator) Run(ctx context.Context) (*InterrogationResult
 	}
 
 	// keygen
-	keys, err := interrogator.keygen(ctx)
-	if err != nil {
-		return nil, NewInterrogationError(InterrogationErrorKeygen, err)
+	var keys []*ecdsa.PublicKey
+	if isDiscV4 {
+		keys, err = interrogator.keygen(ctx)
+		if err != nil {
+			return nil, NewInterrogationError(InterrogationErrorKeygen, err)
+		}
 	}
 
 	// FindNode
@@ -149,20 +189,35 @@ func (interrogator *Interrogator) Run(ctx context.Context) (*InterrogationResult
 			return nil, NewInterrogationError(InterrogationErrorFindNode, err)
 		}
 
-		for _, node := range neighbors {
-			if node.Incomplete() {
-				continue
-			}
-			peersByID[node.ID()] = node
-		}
+		addPeers(peersByID, neighbors)
 
 		utils.Sleep(ctx, 1*time.Second)
 	}
 
+	// discv5 FindNode asks for the log distances directly, no keygen is needed
+	if (isDiscV5 != nil) && *isDiscV5 {
+		for i := uint(0); i < discV5FindNodeDistances; i++ {
+			neighbors, err := interrogator.findNodeV5(ctx, 256-i)
+			if err != nil {
+				if isFindNodeTimeoutError(err) {
+					return nil, NewInterrogationError(InterrogationErrorFindNodeTimeout, err)
+				}
+				return nil, NewInterrogationError(InterrogationErrorFindNode, err)
+			}
+
+			addPeers(peersByID, neighbors)
+
+			utils.Sleep(ctx, 1*time.Second)
+		}
+	}
+
 	peers := valuesOfIDToNodeMap(peersByID)
 
 	result := InterrogationResult{
 		interrogator.node,
+		isDiscV4,
+		isDiscV5,
+		enr,
 		isCompatFork,
 		handshakeResult,
 		handshakeRetryTime,
@@ -211,6 +266,32 @@ func (interrogator *Interrogator) findNode(ctx context.Context, targetKey *ecdsa
 	return result, err
 }
 
+func (interrogator *Interrogator) findNodeV5(ctx context.Context, distance uint) ([]*enode.Node, error) {
+	delayForAttempt := func(attempt int) time.Duration { return 2 * time.Second }
+	resultAny, err := utils.Retry(ctx, 2, delayForAttempt, isFindNodeTimeoutError, interrogator.log, "FindNodeV5", func(ctx context.Context) (interface{}, error) {
+		return interrogator.transportV5.FindNode(interrogator.node, []uint{distance})
+	})
+
+	if resultAny == nil {
+		return nil, err
+	}
+	result := resultAny.([]*enode.Node)
+	return result, err
+}
+
+func addPeers(peersByID map[enode.ID]*enode.Node, neighbors []*enode.Node) {
+	for _, node := range neighbors {
+		if node.Incomplete() {
+			continue
+		}
+		// prefer the signed records of discv5 over the bare discv4 addresses
+		if prev, ok := peersByID[node.ID()]; ok && (node.Seq() < prev.Seq()) {
+			continue
+		}
+		peersByID[node.ID()] = node
+	}
+}
+
 func isFindNodeTimeoutError(err error) bool {
 	return (err != nil) && (err.Error() == "RPC timeout")
 }
@@ -0,0 +1,63 @@
+package node_utils
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cmd/observer/database"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/p2p/enr"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// eth2ENREntry is the SSZ encoded ENRForkID of the consensus layer nodes,
+// it starts with the 4 bytes fork digest.
+type eth2ENREntry []byte
+
+func (eth2ENREntry) ENRKey() string { return "eth2" }
+
+// IsSignedENR tells whether the node has a real record, unlike the nodes of discv4 Neighbors replies.
+func IsSignedENR(node *enode.Node) bool {
+	return len(node.Record().Signature()) > 0
+}
+
+func MakeNodeENR(node *enode.Node) (*database.NodeENR, error) {
+	record := node.Record()
+
+	entries := make(map[string][]byte)
+	elements := record.AppendElements(nil)
+	for i := 1; i+1 < len(elements); i += 2 {
+		key := elements[i].(string)
+		value := elements[i+1].(rlp.RawValue)
+		entries[key] = value
+	}
+
+	enrValue := database.NodeENR{
+		Seq:     node.Seq(),
+		Text:    node.String(),
+		Entries: entries,
+	}
+
+	forkID, err := eth.LoadENRForkID(record)
+	if err != nil {
+		return nil, err
+	}
+	if forkID != nil {
+		forkHash := hex.EncodeToString(forkID.Hash[:])
+		enrValue.EthForkHash = &forkHash
+		enrValue.EthForkNext = &forkID.Next
+	}
+
+	var eth2Entry eth2ENREntry
+	if err := record.Load(&eth2Entry); err != nil {
+		if !enr.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to load eth2 fork digest from ENR: %w", err)
+		}
+	} else if len(eth2Entry) >= 4 {
+		forkDigest := hex.EncodeToString(eth2Entry[:4])
+		enrValue.Eth2ForkDigest = &forkDigest
+	}
+
+	return &enrValue, nil
+}
@@ -28,6 +28,7 @@ type Server struct {
 	listenAddr   string
 	natInterface nat.Interface
 	discConfig   discover.Config
+	discV5       bool
 
 	log log.Logger
 }
@@ -80,6 +81,7 @@ func NewServer(flags CommandFlags) (*Server, error) {
 		listenAddr,
 		natInterface,
 		discConfig,
+		flags.Discv5,
 		logger,
 	}
 	return &instance, nil
@@ -153,22 +155,24 @@ func (server *Server) detectNATExternalIP() (net.IP, error) {
 	return ip, nil
 }
 
-func (server *Server) Listen(ctx context.Context) (*discover.UDPv4, error) {
+// Listen starts discv4 and, if enabled, discv5 on the same UDP port.
+// The discv5 listener is nil if it is not enabled.
+func (server *Server) Listen(ctx context.Context) (*discover.UDPv4, *discover.UDPv5, error) {
 	if server.natInterface != nil {
 		ip, err := server.detectNATExternalIP()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		server.localNode.SetStaticIP(ip)
 	}
 
 	addr, err := net.ResolveUDPAddr("udp", server.listenAddr)
 	if err != nil {
-		return nil, fmt.Errorf("ResolveUDPAddr error: %w", err)
+		return nil, nil, fmt.Errorf("ResolveUDPAddr error: %w", err)
 	}
 	conn, err := net.ListenUDP("udp", addr)
 	if err != nil {
-		return nil, fmt.Errorf("ListenUDP error: %w", err)
+		return nil, nil, fmt.Errorf("ListenUDP error: %w", err)
 	}
 
 	realAddr := conn.LocalAddr().(*net.UDPAddr)
@@ -180,5 +184,45 @@ func (server *Server) Listen(ctx context.Context) (*discover.UDPv4, error) {
 
 	server.log.Debug("Discovery UDP listener is up", "addr", realAddr)
 
-	return discover.ListenV4(ctx, conn, server.localNode, server.discConfig)
+	if !server.discV5 {
+		discV4, err := discover.ListenV4(ctx, conn, server.localNode, server.discConfig)
+		return discV4, nil, err
+	}
+
+	// discv4 passes the packets it can't handle to discv5
+	unhandled := make(chan discover.ReadPacket, 100)
+	discV4Config := server.discConfig
+	discV4Config.Unhandled = unhandled
+	discV4, err := discover.ListenV4(ctx, conn, server.localNode, discV4Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	discV5, err := discover.ListenV5(ctx, &sharedUDPConn{conn, unhandled}, server.localNode, server.discConfig)
+	if err != nil {
+		discV4.Close()
+		return nil, nil, err
+	}
+	return discV4, discV5, nil
+}
+
+// sharedUDPConn is the connection of discv5, it writes to the connection of discv4
+// and reads the packets that discv4 couldn't handle.
+type sharedUDPConn struct {
+	*net.UDPConn
+	unhandled chan discover.ReadPacket
+}
+
+func (conn *sharedUDPConn) ReadFromUDP(b []byte) (n int, addr *net.UDPAddr, err error) {
+	packet, ok := <-conn.unhandled
+	if !ok {
+		return 0, nil, errors.New("connection was closed")
+	}
+	n = copy(b, packet.Data)
+	return n, packet.Addr, nil
+}
+
+// Close is a no-op, the connection is closed by discv4.
+func (conn *sharedUDPConn) Close() error {
+	return nil
 }
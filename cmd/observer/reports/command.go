@@ -15,6 +15,7 @@ type CommandFlags struct {
 	ClientsLimit uint
 	MaxPingTries uint
 	Estimate     bool
+	Discovery    bool
 
 	SentryCandidates bool
 	ErigonLogPath    string
@@ -39,6 +40,7 @@ func NewCommand() *Command {
 	instance.withClientsLimit()
 	instance.withMaxPingTries()
 	instance.withEstimate()
+	instance.withDiscovery()
 	instance.withSentryCandidates()
 	instance.withErigonLogPath()
 
@@ -82,6 +84,14 @@ func (command *Command) withEstimate() {
 	command.command.Flags().BoolVar(&command.flags.Estimate, flag.Name, false, flag.Usage)
 }
 
+func (command *Command) withDiscovery() {
+	flag := cli.BoolFlag{
+		Name:  "discovery",
+		Usage: "Break down the nodes by fork ID and client across discv4 and discv5",
+	}
+	command.command.Flags().BoolVar(&command.flags.Discovery, flag.Name, false, flag.Usage)
+}
+
 func (command *Command) withSentryCandidates() {
 	flag := cli.BoolFlag{
 		Name:  "sentry-candidates",
@@ -0,0 +1,145 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/cmd/observer/database"
+	"github.com/ledgerwatch/erigon/cmd/observer/observer"
+)
+
+type DiscoveryReportEntry struct {
+	Name   string
+	DiscV4 uint
+	DiscV5 uint
+	Total  uint
+}
+
+// DiscoveryReport breaks down the live nodes of all the networks by the fork ID of their ENR and by client,
+// counting how many of them answered on discv4 and on discv5.
+type DiscoveryReport struct {
+	ForkIDs []DiscoveryReportEntry
+	Clients []DiscoveryReportEntry
+}
+
+func CreateDiscoveryReport(ctx context.Context, db database.DB, limit uint, maxPingTries uint) (*DiscoveryReport, error) {
+	forkIDGroups := make(map[string]*DiscoveryReportEntry)
+	clientGroups := make(map[string]*DiscoveryReportEntry)
+	var unknownForkID DiscoveryReportEntry
+	var unknownClient DiscoveryReportEntry
+
+	enumFunc := func(node database.DiscoveredNode) {
+		if (node.ClientID != nil) && observer.IsClientIDBlacklisted(*node.ClientID) {
+			return
+		}
+
+		forkIDEntry := &unknownForkID
+		if name := forkIDName(node); name != "" {
+			forkIDEntry = discoveryGroup(forkIDGroups, name)
+		}
+		countDiscoveredNode(forkIDEntry, node)
+
+		clientEntry := &unknownClient
+		if node.ClientID != nil {
+			clientEntry = discoveryGroup(clientGroups, observer.NameFromClientID(*node.ClientID))
+		}
+		countDiscoveredNode(clientEntry, node)
+	}
+	if err := db.EnumerateDiscoveredNodes(ctx, maxPingTries, enumFunc); err != nil {
+		return nil, err
+	}
+
+	report := DiscoveryReport{
+		topDiscoveryEntries(forkIDGroups, limit, unknownForkID),
+		topDiscoveryEntries(clientGroups, limit, unknownClient),
+	}
+	return &report, nil
+}
+
+func forkIDName(node database.DiscoveredNode) string {
+	if node.EthForkHash != nil {
+		if (node.EthForkNext != nil) && (*node.EthForkNext != 0) {
+			return fmt.Sprintf("eth %s next %d", *node.EthForkHash, *node.EthForkNext)
+		}
+		return "eth " + *node.EthForkHash
+	}
+	if node.Eth2ForkDigest != nil {
+		return "eth2 " + *node.Eth2ForkDigest
+	}
+	return ""
+}
+
+func discoveryGroup(groups map[string]*DiscoveryReportEntry, name string) *DiscoveryReportEntry {
+	entry, ok := groups[name]
+	if !ok {
+		entry = &DiscoveryReportEntry{Name: name}
+		groups[name] = entry
+	}
+	return entry
+}
+
+func countDiscoveredNode(entry *DiscoveryReportEntry, node database.DiscoveredNode) {
+	if node.IsDiscV4 {
+		entry.DiscV4++
+	}
+	if node.IsDiscV5 {
+		entry.DiscV5++
+	}
+	entry.Total++
+}
+
+func topDiscoveryEntries(groups map[string]*DiscoveryReportEntry, limit uint, unknown DiscoveryReportEntry) []DiscoveryReportEntry {
+	entries := make([]DiscoveryReportEntry, 0, len(groups))
+	for _, entry := range groups {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Total != entries[j].Total {
+			return entries[i].Total > entries[j].Total
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	others := DiscoveryReportEntry{Name: "..."}
+	total := DiscoveryReportEntry{Name: "total"}
+	var top []DiscoveryReportEntry
+	for i, entry := range entries {
+		if uint(i) < limit {
+			top = append(top, entry)
+		} else {
+			addDiscoveryEntry(&others, entry)
+		}
+		addDiscoveryEntry(&total, entry)
+	}
+
+	unknown.Name = "unknown"
+	return append(top, others, total, unknown)
+}
+
+func addDiscoveryEntry(sum *DiscoveryReportEntry, entry DiscoveryReportEntry) {
+	sum.DiscV4 += entry.DiscV4
+	sum.DiscV5 += entry.DiscV5
+	sum.Total += entry.Total
+}
+
+func (report *DiscoveryReport) String() string {
+	var builder strings.Builder
+	builder.Grow(4 + 2*len(report.ForkIDs) + 2*len(report.Clients))
+	writeDiscoveryEntries(&builder, "fork IDs:", report.ForkIDs)
+	builder.WriteRune('\n')
+	writeDiscoveryEntries(&builder, "clients:", report.Clients)
+	return builder.String()
+}
+
+func writeDiscoveryEntries(builder *strings.Builder, title string, entries []DiscoveryReportEntry) {
+	builder.WriteString(title)
+	builder.WriteRune('\n')
+	builder.WriteString(fmt.Sprintf("%6s %6s %6s", "discv4", "discv5", "total"))
+	builder.WriteRune('\n')
+	for _, entry := range entries {
+		builder.WriteString(fmt.Sprintf("%6d %6d %6d %s", entry.DiscV4, entry.DiscV5, entry.Total, entry.Name))
+		builder.WriteRune('\n')
+	}
+}
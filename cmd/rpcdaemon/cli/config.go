@@ -21,6 +21,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/state/temporal"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 	"github.com/ledgerwatch/erigon/turbo/debug"
@@ -70,13 +71,14 @@ var rootCmd = &cobra.Command{
 }
 
 var (
-	stateCacheStr string
+	stateCacheStr      string
+	resultCacheSizeStr string
 )
 
 func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	utils.CobraFlags(rootCmd, debug.Flags, utils.MetricFlags, logging.Flags)
 
-	cfg := &httpcfg.HttpCfg{Enabled: true, StateCache: kvcache.DefaultCoherentConfig}
+	cfg := &httpcfg.HttpCfg{Enabled: true, StateCache: kvcache.DefaultCoherentConfig, AuthRpcTimeouts: rpccfg.DefaultHTTPTimeouts}
 	rootCmd.PersistentFlags().StringVar(&cfg.PrivateApiAddr, "private.api.addr", "127.0.0.1:9090", "private api network address, for example: 127.0.0.1:9090")
 	rootCmd.PersistentFlags().StringVar(&cfg.DataDir, "datadir", "", "path to Erigon working directory")
 	rootCmd.PersistentFlags().StringVar(&cfg.HttpListenAddress, "http.addr", nodecfg.DefaultHTTPHost, "HTTP-RPC server listening interface")
@@ -93,6 +95,7 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketEnabled, "ws", false, "Enable Websockets")
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketCompression, "ws.compression", false, "Enable Websocket compression (RFC 7692)")
 	rootCmd.PersistentFlags().StringVar(&cfg.RpcAllowListFilePath, "rpc.accessList", "", "Specify granular (method-by-method) API allowlist")
+	rootCmd.PersistentFlags().StringVar(&cfg.RpcRateLimitsFilePath, "rpc.rateLimits", "", "Specify per-client request rate and concurrency limits of the HTTP, WebSocket and TCP endpoints")
 	rootCmd.PersistentFlags().UintVar(&cfg.RpcBatchConcurrency, utils.RpcBatchConcurrencyFlag.Name, 2, utils.RpcBatchConcurrencyFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.RpcStreamingDisable, utils.RpcStreamingDisableFlag.Name, false, utils.RpcStreamingDisableFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.DBReadConcurrency, utils.DBReadConcurrencyFlag.Name, utils.DBReadConcurrencyFlag.Value, utils.DBReadConcurrencyFlag.Usage)
@@ -100,6 +103,9 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().StringVar(&cfg.TxPoolApiAddr, "txpool.api.addr", "", "txpool api network address, for example: 127.0.0.1:9090 (default: use value of --private.api.addr)")
 	rootCmd.PersistentFlags().BoolVar(&cfg.Sync.UseSnapshots, "snapshot", true, utils.SnapshotFlag.Usage)
 	rootCmd.PersistentFlags().StringVar(&stateCacheStr, "state.cache", "0MB", "Amount of data to store in StateCache (enabled if no --datadir set). Set 0 to disable StateCache. Defaults to 0MB RAM")
+	rootCmd.PersistentFlags().StringVar(&cfg.RpcResultCache, utils.RpcResultCacheFlag.Name, "", utils.RpcResultCacheFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&resultCacheSizeStr, utils.RpcResultCacheSizeFlag.Name, utils.RpcResultCacheSizeFlag.Value, utils.RpcResultCacheSizeFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.RpcResultCacheFinality, utils.RpcResultCacheFinalityFlag.Name, utils.RpcResultCacheFinalityFlag.Value, utils.RpcResultCacheFinalityFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.GRPCServerEnabled, "grpc", false, "Enable GRPC server")
 	rootCmd.PersistentFlags().StringVar(&cfg.GRPCListenAddress, "grpc.addr", nodecfg.DefaultGRPCHost, "GRPC server listening interface")
 	rootCmd.PersistentFlags().IntVar(&cfg.GRPCPort, "grpc.port", nodecfg.DefaultGRPCPort, "GRPC server listening port")
@@ -109,11 +115,22 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().StringVar(&cfg.TCPListenAddress, "tcp.addr", nodecfg.DefaultTCPHost, "TCP server listening interface")
 	rootCmd.PersistentFlags().IntVar(&cfg.TCPPort, "tcp.port", nodecfg.DefaultTCPPort, "TCP server listening port")
 
+	rootCmd.PersistentFlags().StringVar(&cfg.AuthRpcHTTPListenAddress, utils.AuthRpcAddr.Name, utils.AuthRpcAddr.Value, "HTTP-RPC server listening interface for the Engine API, the clique API and the admin peer methods")
+	rootCmd.PersistentFlags().IntVar(&cfg.AuthRpcPort, utils.AuthRpcPort.Name, 0, "HTTP-RPC server listening port for the Engine API, the clique API and the admin peer methods, 0 disables the authenticated endpoint")
+	rootCmd.PersistentFlags().StringVar(&cfg.JWTSecretPath, utils.JWTSecretPath.Name, "", utils.JWTSecretPath.Usage)
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.AuthRpcVirtualHost, utils.AuthRpcVirtualHostsFlag.Name, nodecfg.DefaultConfig.HTTPVirtualHosts, utils.AuthRpcVirtualHostsFlag.Usage)
+
 	rootCmd.PersistentFlags().BoolVar(&cfg.TraceRequests, utils.HTTPTraceFlag.Name, false, "Trace HTTP requests with INFO level")
 	rootCmd.PersistentFlags().DurationVar(&cfg.HTTPTimeouts.ReadTimeout, "http.timeouts.read", rpccfg.DefaultHTTPTimeouts.ReadTimeout, "Maximum duration for reading the entire request, including the body.")
 	rootCmd.PersistentFlags().DurationVar(&cfg.HTTPTimeouts.WriteTimeout, "http.timeouts.write", rpccfg.DefaultHTTPTimeouts.WriteTimeout, "Maximum duration before timing out writes of the response. It is reset whenever a new request's header is read")
 	rootCmd.PersistentFlags().DurationVar(&cfg.HTTPTimeouts.IdleTimeout, "http.timeouts.idle", rpccfg.DefaultHTTPTimeouts.IdleTimeout, "Maximum amount of time to wait for the next request when keep-alives are enabled. If http.timeouts.idle is zero, the value of http.timeouts.read is used")
 	rootCmd.PersistentFlags().DurationVar(&cfg.EvmCallTimeout, "rpc.evmtimeout", rpccfg.DefaultEvmCallTimeout, "Maximum amount of time to wait for the answer from EVM call.")
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxGetProofRewindBlockCount, utils.RpcMaxGetProofRewindBlockCountFlag.Name, rpccfg.DefaultMaxGetProofRewindBlockCount, utils.RpcMaxGetProofRewindBlockCountFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.Signer, utils.RpcSignerFlag.Name, "", utils.RpcSignerFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.SignerKeystoreDir, utils.RpcSignerKeystoreFlag.Name, "", utils.RpcSignerKeystoreFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.SignerPasswordFile, utils.RpcSignerPasswordFlag.Name, "", utils.RpcSignerPasswordFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.SignerURL, utils.RpcSignerURLFlag.Name, "", utils.RpcSignerURLFlag.Usage)
+	rootCmd.PersistentFlags().BoolVar(&cfg.InsecureUnlockAllowed, utils.InsecureUnlockAllowedFlag.Name, false, utils.InsecureUnlockAllowedFlag.Usage)
 
 	if err := rootCmd.MarkPersistentFlagFilename("rpc.accessList", "json"); err != nil {
 		panic(err)
@@ -137,6 +154,10 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 			return fmt.Errorf("state.cache value of %v is not valid", stateCacheStr)
 		}
 
+		if err = cfg.RpcResultCacheSize.UnmarshalText([]byte(resultCacheSizeStr)); err != nil {
+			return fmt.Errorf("%s value of %v is not valid", utils.RpcResultCacheSizeFlag.Name, resultCacheSizeStr)
+		}
+
 		cfg.WithDatadir = cfg.DataDir != ""
 		if cfg.WithDatadir {
 			if cfg.DataDir == "" {
@@ -256,8 +277,20 @@ func EmbeddedServices(ctx context.Context,
 	subscribeToStateChangesLoop(ctx, stateDiffClient, stateCache)
 
 	directClient := direct.NewEthBackendClientDirect(ethBackendServer)
+	var syncProgressClient privateapi.SyncProgressClient
+	if syncProgressServer, ok := ethBackendServer.(privateapi.SyncProgressServer); ok {
+		syncProgressClient = privateapi.NewSyncProgressClientDirect(syncProgressServer)
+	}
+	var cliqueClient privateapi.CliqueClient
+	if cliqueServer, ok := ethBackendServer.(privateapi.CliqueServer); ok {
+		cliqueClient = privateapi.NewCliqueClientDirect(cliqueServer)
+	}
+	var adminClient privateapi.AdminClient
+	if adminServer, ok := ethBackendServer.(privateapi.AdminServer); ok {
+		adminClient = privateapi.NewAdminClientDirect(adminServer)
+	}
 
-	eth = rpcservices.NewRemoteBackend(directClient, erigonDB, blockReader)
+	eth = rpcservices.NewRemoteBackend(directClient, syncProgressClient, cliqueClient, adminClient, erigonDB, blockReader)
 	txPool = direct.NewTxPoolClient(txPoolServer)
 	mining = direct.NewMiningClient(miningServer)
 	ff = rpchelper.New(ctx, eth, txPool, mining, func() {})
@@ -452,7 +485,7 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		blockReader = snapshotsync.NewRemoteBlockReader(remoteBackendClient)
 	}
 
-	remoteEth := rpcservices.NewRemoteBackend(remoteBackendClient, db, blockReader)
+	remoteEth := rpcservices.NewRemoteBackend(remoteBackendClient, privateapi.NewSyncProgressClient(conn), privateapi.NewCliqueClient(conn), privateapi.NewAdminClient(conn), db, blockReader)
 	blockReader = remoteEth
 	eth = remoteEth
 	go func() {
@@ -474,7 +507,7 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 	return db, borDb, eth, txPool, mining, stateCache, blockReader, ff, agg, err
 }
 
-func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API, authAPI []rpc.API) error {
+func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API, authAPI []rpc.API, resultCache rpc.ResultCache) error {
 	if len(authAPI) > 0 {
 		engineInfo, err := startAuthenticatedRpcServer(cfg, authAPI)
 		if err != nil {
@@ -484,13 +517,13 @@ func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API,
 	}
 
 	if cfg.Enabled {
-		return startRegularRpcServer(ctx, cfg, rpcAPI)
+		return startRegularRpcServer(ctx, cfg, rpcAPI, resultCache)
 	}
 
 	return nil
 }
 
-func startRegularRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API) error {
+func startRegularRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API, resultCache rpc.ResultCache) error {
 	// register apis and create handler stack
 	httpEndpoint := fmt.Sprintf("%s:%d", cfg.HttpListenAddress, cfg.HttpPort)
 
@@ -503,6 +536,17 @@ func startRegularRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rp
 	}
 	srv.SetAllowList(allowListForRPC)
 
+	rateLimitsForRPC, err := parseRateLimitsForRPC(cfg.RpcRateLimitsFilePath)
+	if err != nil {
+		return err
+	}
+	if err = srv.SetRateLimits(rateLimitsForRPC); err != nil {
+		return fmt.Errorf("invalid %s: %w", cfg.RpcRateLimitsFilePath, err)
+	}
+	if resultCache != nil {
+		srv.SetResultCache(resultCache)
+	}
+
 	var defaultAPIList []rpc.API
 
 	for _, api := range rpcAPI {
@@ -3,6 +3,7 @@ package httpcfg
 import (
 	"time"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
@@ -32,6 +33,10 @@ type HttpCfg struct {
 	WebsocketEnabled         bool
 	WebsocketCompression     bool
 	RpcAllowListFilePath     string
+	RpcRateLimitsFilePath    string
+	RpcResultCache           string // backend of the cache of the results about finalized blocks, empty when disabled
+	RpcResultCacheSize       datasize.ByteSize
+	RpcResultCacheFinality   string
 	RpcBatchConcurrency      uint
 	RpcStreamingDisable      bool
 	DBReadConcurrency        int
@@ -41,6 +46,16 @@ type HttpCfg struct {
 	Snap                     ethconfig.Snapshot
 	Sync                     ethconfig.Sync
 
+	MaxGetProofRewindBlockCount int // how many blocks back eth_getProof can rewind the state trie
+
+	// Signer of eth_sendTransaction, eth_signTransaction and eth_sign: "" (disabled), "keystore" or "external"
+	Signer             string
+	SignerKeystoreDir  string
+	SignerPasswordFile string
+	SignerURL          string // clef endpoint
+	// InsecureUnlockAllowed lets the keystore signer serve an http endpoint that listens beyond loopback
+	InsecureUnlockAllowed bool
+
 	// GRPC server
 	GRPCServerEnabled      bool
 	GRPCListenAddress      string
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/resultcache"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/services"
+)
+
+// ResultCache opens the cache of the rpc results about finalized blocks, nil when it is disabled.
+// The cache follows the reorgs announced by ff and is closed when ctx is done.
+func ResultCache(ctx context.Context, cfg httpcfg.HttpCfg, db kv.RoDB, txnReader services.TxnReader, ff *rpchelper.Filters) (rpc.ResultCache, error) {
+	var dir string
+	if cfg.Dirs.DataDir != "" {
+		dir = filepath.Join(cfg.Dirs.DataDir, "rpccache")
+	}
+	c, err := resultcache.New(resultcache.Config{
+		Backend:  cfg.RpcResultCache,
+		Size:     cfg.RpcResultCacheSize,
+		Finality: cfg.RpcResultCacheFinality,
+		Dir:      dir,
+	}, db, txnReader)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	go func() {
+		defer c.Close()
+		c.Run(ctx, ff)
+	}()
+	log.Info("RPC result cache enabled", "backend", cfg.RpcResultCache, "size", cfg.RpcResultCacheSize, "finality", cfg.RpcResultCacheFinality)
+	return c, nil
+}
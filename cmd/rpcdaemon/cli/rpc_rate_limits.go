@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+func parseRateLimitsForRPC(path string) (*rpc.RateLimits, error) {
+	path = strings.TrimSpace(path)
+	if path == "" { // no file is provided
+		return nil, nil
+	}
+
+	fileContents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rateLimits rpc.RateLimits
+	if err = json.Unmarshal(fileContents, &rateLimits); err != nil {
+		return nil, err
+	}
+	return &rateLimits, nil
+}
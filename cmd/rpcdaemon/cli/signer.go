@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/ledgerwatch/erigon/accounts"
+	"github.com/ledgerwatch/erigon/accounts/external"
+	"github.com/ledgerwatch/erigon/accounts/keystore"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// Signer returns the signer of eth_sendTransaction, eth_signTransaction and eth_sign, nil when signing is disabled.
+func Signer(ctx context.Context, cfg httpcfg.HttpCfg) (accounts.Signer, error) {
+	switch cfg.Signer {
+	case "":
+		return nil, nil
+	case "keystore":
+		if cfg.SignerKeystoreDir == "" {
+			return nil, fmt.Errorf("--rpc.signer.keystore is required by the keystore signer")
+		}
+		// The unlocked keys sign for anyone reaching the eth namespace, so like geth refuse it on a reachable listener.
+		if !cfg.InsecureUnlockAllowed && !isLoopback(cfg.HttpListenAddress) {
+			return nil, fmt.Errorf("keystore signer is forbidden on http.addr %s, it isn't loopback; use --allow-insecure-unlock to force it", cfg.HttpListenAddress)
+		}
+		ks, err := keystore.Open(cfg.SignerKeystoreDir, cfg.SignerPasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		addresses, _ := ks.Accounts(ctx)
+		log.Info("Keystore signer enabled", "accounts", len(addresses))
+		return ks, nil
+	case "external":
+		if cfg.SignerURL == "" {
+			return nil, fmt.Errorf("--rpc.signer.url is required by the external signer")
+		}
+		signer, err := external.NewExternalSigner(ctx, cfg.SignerURL)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("External signer enabled", "url", cfg.SignerURL)
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unknown signer %q, expected keystore or external", cfg.Signer)
+	}
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -19,6 +19,24 @@ type AdminAPI interface {
 	Peers(ctx context.Context) ([]*p2p.PeerInfo, error)
 }
 
+// AdminPeersAPI the interface for the admin_* RPC commands changing the peers, served on the authenticated endpoint only.
+type AdminPeersAPI interface {
+	// AddPeer requests connecting to a remote node, and also maintaining the new
+	// connection at all times, even reconnecting if it is lost. The node is kept across restarts.
+	// https://geth.ethereum.org/docs/rpc/ns-admin#admin_addpeer
+	AddPeer(ctx context.Context, url string) (bool, error)
+
+	// RemovePeer disconnects from a remote node added with AddPeer.
+	RemovePeer(ctx context.Context, url string) (bool, error)
+
+	// AddTrustedPeer allows a remote node to always connect, even if slots are full.
+	AddTrustedPeer(ctx context.Context, url string) (bool, error)
+
+	// RemoveTrustedPeer removes a remote node from the trusted peer set, but it
+	// does not disconnect it automatically.
+	RemoveTrustedPeer(ctx context.Context, url string) (bool, error)
+}
+
 // AdminAPIImpl data structure to store things needed for admin_* commands.
 type AdminAPIImpl struct {
 	ethBackend rpchelper.ApiBackend
@@ -47,3 +65,43 @@ func (api *AdminAPIImpl) NodeInfo(ctx context.Context) (*p2p.NodeInfo, error) {
 func (api *AdminAPIImpl) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
 	return api.ethBackend.Peers(ctx)
 }
+
+// AdminPeersAPIImpl data structure to store things needed for the admin_* commands changing the peers.
+type AdminPeersAPIImpl struct {
+	ethBackend rpchelper.ApiBackend
+}
+
+// NewAdminPeersAPI returns AdminPeersAPIImpl instance.
+func NewAdminPeersAPI(eth rpchelper.ApiBackend) *AdminPeersAPIImpl {
+	return &AdminPeersAPIImpl{
+		ethBackend: eth,
+	}
+}
+
+func (api *AdminPeersAPIImpl) AddPeer(ctx context.Context, url string) (bool, error) {
+	if err := api.ethBackend.AddPeer(ctx, url); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (api *AdminPeersAPIImpl) RemovePeer(ctx context.Context, url string) (bool, error) {
+	if err := api.ethBackend.RemovePeer(ctx, url); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (api *AdminPeersAPIImpl) AddTrustedPeer(ctx context.Context, url string) (bool, error) {
+	if err := api.ethBackend.AddTrustedPeer(ctx, url); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (api *AdminPeersAPIImpl) RemoveTrustedPeer(ctx context.Context, url string) (bool, error) {
+	if err := api.ethBackend.RemoveTrustedPeer(ctx, url); err != nil {
+		return false, err
+	}
+	return true, nil
+}
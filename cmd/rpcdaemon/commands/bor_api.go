@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"context"
+
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/bor"
@@ -18,6 +20,10 @@ type BorAPI interface {
 	GetCurrentProposer() (common.Address, error)
 	GetCurrentValidators() ([]*bor.Validator, error)
 	GetRootHash(start uint64, end uint64) (string, error)
+
+	// Bor state sync related (see ./bor_state_sync.go)
+	GetStateSyncEvents(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*bor.EventRecordWithTime, error)
+	GetSpan(ctx context.Context, id uint64) (*bor.HeimdallSpan, error)
 }
 
 // BorImpl is implementation of the BorAPI interface
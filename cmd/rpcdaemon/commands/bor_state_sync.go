@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/consensus/bor"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// GetStateSyncEvents returns the state sync events committed by the block, in the order of their ids.
+// Events are committed by the first block of a sprint only, the other blocks have none.
+func (api *BorImpl) GetStateSyncEvents(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*bor.EventRecordWithTime, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNum, _, _, err := rpchelper.GetCanonicalBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	return bor.ReadStateSyncEvents(tx, blockNum)
+}
+
+// GetSpan returns the span with the given id once a block has committed it
+func (api *BorImpl) GetSpan(ctx context.Context, id uint64) (*bor.HeimdallSpan, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	span, _, err := bor.ReadSpan(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if span == nil {
+		return nil, fmt.Errorf("span %d not found", id)
+	}
+	return span, nil
+}
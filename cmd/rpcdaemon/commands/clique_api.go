@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/clique"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// CliqueAPI the interface for the clique_* RPC commands, they operate the clique sealer of the node.
+// It is only served over the authenticated endpoint.
+type CliqueAPI interface {
+	// Propose injects a new authorization proposal that the signer will vote on until it is discarded.
+	Propose(ctx context.Context, address common.Address, auth bool) error
+	// Discard drops a proposal, stopping the signer from casting further votes (either for or against).
+	Discard(ctx context.Context, address common.Address) error
+	// Proposals returns the current proposals the node tries to uphold and vote on.
+	Proposals(ctx context.Context) (map[common.Address]bool, error)
+	// Status returns the in-turn percentage and the sealer activity of the recent blocks.
+	Status(ctx context.Context) (*clique.Status, error)
+}
+
+// CliqueAPIImpl data structure to store things needed for clique_* commands.
+type CliqueAPIImpl struct {
+	ethBackend rpchelper.ApiBackend
+}
+
+// NewCliqueAPI returns CliqueAPIImpl instance.
+func NewCliqueAPI(eth rpchelper.ApiBackend) *CliqueAPIImpl {
+	return &CliqueAPIImpl{ethBackend: eth}
+}
+
+func (api *CliqueAPIImpl) Propose(ctx context.Context, address common.Address, auth bool) error {
+	return api.ethBackend.CliquePropose(ctx, address, auth)
+}
+
+func (api *CliqueAPIImpl) Discard(ctx context.Context, address common.Address) error {
+	return api.ethBackend.CliqueDiscard(ctx, address)
+}
+
+func (api *CliqueAPIImpl) Proposals(ctx context.Context) (map[common.Address]bool, error) {
+	return api.ethBackend.CliqueProposals(ctx)
+}
+
+func (api *CliqueAPIImpl) Status(ctx context.Context) (*clique.Status, error) {
+	return api.ethBackend.CliqueStatus(ctx)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	libstate "github.com/ledgerwatch/erigon-lib/state"
+	"github.com/ledgerwatch/erigon/accounts"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
 	"github.com/ledgerwatch/erigon/consensus"
 	"github.com/ledgerwatch/erigon/rpc"
@@ -16,9 +17,10 @@ import (
 func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
 	filters *rpchelper.Filters, stateCache kvcache.Cache,
 	blockReader services.FullBlockReader, agg *libstate.AggregatorV3, cfg httpcfg.HttpCfg, engine consensus.EngineReader,
+	signer accounts.Signer,
 ) (list []rpc.API) {
-	base := NewBaseApi(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout, engine)
-	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap)
+	base := NewBaseApi(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout, engine, cfg.Dirs, cfg.MaxGetProofRewindBlockCount)
+	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, signer)
 	erigonImpl := NewErigonAPI(base, db, eth)
 	txpoolImpl := NewTxPoolAPI(base, db, txPool)
 	netImpl := NewNetAPIImpl(eth)
@@ -128,10 +130,12 @@ func AuthAPIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClien
 	agg *libstate.AggregatorV3,
 	cfg httpcfg.HttpCfg, engine consensus.EngineReader,
 ) (list []rpc.API) {
-	base := NewBaseApi(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout, engine)
+	base := NewBaseApi(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout, engine, cfg.Dirs, cfg.MaxGetProofRewindBlockCount)
 
-	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap)
+	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, nil)
 	engineImpl := NewEngineAPI(base, db, eth, cfg.InternalCL)
+	cliqueImpl := NewCliqueAPI(eth)
+	adminPeersImpl := NewAdminPeersAPI(eth)
 
 	list = append(list, rpc.API{
 		Namespace: "eth",
@@ -143,6 +147,16 @@ func AuthAPIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClien
 		Public:    true,
 		Service:   EngineAPI(engineImpl),
 		Version:   "1.0",
+	}, rpc.API{
+		Namespace: "clique",
+		Public:    false,
+		Service:   CliqueAPI(cliqueImpl),
+		Version:   "1.0",
+	}, rpc.API{
+		Namespace: "admin",
+		Public:    false,
+		Service:   AdminPeersAPI(adminPeersImpl),
+		Version:   "1.0",
 	})
 
 	return list
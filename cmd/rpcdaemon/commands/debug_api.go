@@ -73,7 +73,20 @@ func (api *PrivateDebugAPIImpl) StorageRangeAt(ctx context.Context, blockHash co
 	}
 
 	if api.historyV3(tx) {
-		panic("not implemented!")
+		minTxNum, err := rawdb.TxNums.Min(tx, block.NumberU64())
+		if err != nil {
+			return StorageRangeResult{}, err
+		}
+		ac := api._agg.MakeContext()
+		ac.SetTx(tx)
+		stateReader := state.NewHistoryReaderV3()
+		stateReader.SetTx(tx)
+		stateReader.SetAc(ac)
+		stateReader.SetStorageHistory(api._agg.Storage().MakeContext(), api._agg.EndTxNumMinimax())
+		// first txNum of the block belongs to the system tx which initializes the block,
+		// so txNum of transaction txIndex is minTxNum+1+txIndex - state before it is the state at that txNum
+		stateReader.SetTxNum(minTxNum + 1 + txIndex)
+		return StorageRangeAt(stateReader, contractAddress, keyStart, maxResult)
 	}
 	_, _, _, _, stateReader, err := transactions.ComputeTxEnv(ctx, engine, block, chainConfig, api._blockReader, tx, txIndex, api._agg, api.historyV3(tx))
 	if err != nil {
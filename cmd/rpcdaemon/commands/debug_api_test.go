@@ -4,17 +4,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"math/big"
 	"testing"
 
+	"github.com/holiman/uint256"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+	"github.com/stretchr/testify/require"
 )
 
 var debugTraceTransactionTests = []struct {
@@ -44,8 +54,8 @@ func TestTraceBlockByNumber(t *testing.T) {
 	agg := m.HistoryV3Components()
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000)
+	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount)
+	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, nil)
 	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
@@ -93,8 +103,8 @@ func TestTraceBlockByHash(t *testing.T) {
 	agg := m.HistoryV3Components()
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000)
+	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount)
+	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, nil)
 	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
@@ -130,7 +140,7 @@ func TestTraceTransaction(t *testing.T) {
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	api := NewPrivateDebugAPI(
-		NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine),
+		NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount),
 		m.DB, 0)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
@@ -164,7 +174,7 @@ func TestTraceTransactionNoRefund(t *testing.T) {
 	agg := m.HistoryV3Components()
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	api := NewPrivateDebugAPI(
-		NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine),
+		NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount),
 		m.DB, 0)
 	for _, tt := range debugTraceTransactionNoRefundTests {
 		var buf bytes.Buffer
@@ -192,3 +202,115 @@ func TestTraceTransactionNoRefund(t *testing.T) {
 		}
 	}
 }
+
+type storageWrite struct{ slot, value int64 }
+
+// storageRangeWrites are the storage writes of the transactions of blocks 2.., block 1 deploys the contract
+var storageRangeWrites = [][]storageWrite{
+	{{1, 1}, {2, 2}, {3, 3}},
+	{{2, 0}, {4, 4}, {1, 11}},
+	{{5, 5}, {2, 22}},
+}
+
+// storageRangeChain builds the chain of storageRangeWrites with the given history version
+func storageRangeChain(t *testing.T, historyV3 bool) (*PrivateDebugAPIImpl, *core.ChainPack, common.Address) {
+	var (
+		signer      = types.LatestSignerForChainID(nil)
+		bankKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		bankAddress = crypto.PubkeyToAddress(bankKey.PublicKey)
+		gspec       = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{bankAddress: {Balance: big.NewInt(1e9)}},
+		}
+		// Runtime code stores calldata[32:64] to the slot calldata[0:32]
+		contract = hexutil.MustDecode("0x6008600c60003960086000f36020356000355500")
+	)
+	m := stages.MockWithGenesisHistoryV3(t, gspec, bankKey, historyV3)
+	contractAddr := crypto.CreateAddress(bankAddress, 0)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, len(storageRangeWrites)+1, func(i int, block *core.BlockGen) {
+		if i == 0 {
+			txn, err := types.SignTx(types.NewContractCreation(block.TxNonce(bankAddress), new(uint256.Int), 1e6, new(uint256.Int), contract), *signer, bankKey)
+			require.NoError(t, err)
+			block.AddTx(txn)
+			return
+		}
+		for _, w := range storageRangeWrites[i-1] {
+			data := append(common.BigToHash(big.NewInt(w.slot)).Bytes(), common.BigToHash(big.NewInt(w.value)).Bytes()...)
+			txn, err := types.SignTx(types.NewTransaction(block.TxNonce(bankAddress), contractAddr, new(uint256.Int), 100000, new(uint256.Int), data), *signer, bankKey)
+			require.NoError(t, err)
+			block.AddTx(txn)
+		}
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(chain))
+
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewPrivateDebugAPI(
+		NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount),
+		m.DB, 0)
+	return api, chain, contractAddr
+}
+
+func TestStorageRangeAt(t *testing.T) {
+	api, chain, contractAddr := storageRangeChain(t, ethconfig.EnableHistoryV3InTest)
+	writes := storageRangeWrites
+
+	// Expected storage is replayed from the writes, so the same expectations hold for both
+	// history v2 and history v3 (erigon3 build tag) databases
+	expected := map[common.Hash]common.Hash{}
+	for i, block := range chain.Blocks[1:] {
+		for txIndex, w := range writes[i] {
+			const maxResult = 2
+			got := map[common.Hash]common.Hash{}
+			var keyStart hexutil.Bytes
+			for {
+				res, err := api.StorageRangeAt(context.Background(), block.Hash(), uint64(txIndex), contractAddr, keyStart, maxResult)
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(res.Storage), maxResult)
+				for seckey, entry := range res.Storage {
+					require.Equal(t, crypto.Keccak256Hash(entry.Key[:]), seckey)
+					got[*entry.Key] = entry.Value
+				}
+				if res.NextKey == nil {
+					break
+				}
+				keyStart = res.NextKey.Bytes()
+			}
+			require.Equal(t, expected, got, "block %d, txIndex %d", block.NumberU64(), txIndex)
+
+			slot := common.BigToHash(big.NewInt(w.slot))
+			if w.value == 0 {
+				delete(expected, slot)
+			} else {
+				expected[slot] = common.BigToHash(big.NewInt(w.value))
+			}
+		}
+	}
+}
+
+func TestStorageRangeAtHistoryV2V3(t *testing.T) {
+	apiV2, chain, contractAddr := storageRangeChain(t, false)
+	apiV3, chainV3, _ := storageRangeChain(t, true)
+	require.Equal(t, chain.TopBlock.Hash(), chainV3.TopBlock.Hash())
+
+	for _, block := range chain.Blocks {
+		for txIndex := 0; txIndex < len(block.Transactions()); txIndex++ {
+			for _, maxResult := range []int{1, 2, 10} {
+				var keyStart hexutil.Bytes
+				for page := 0; ; page++ {
+					resV2, err := apiV2.StorageRangeAt(context.Background(), block.Hash(), uint64(txIndex), contractAddr, keyStart, maxResult)
+					require.NoError(t, err)
+					resV3, err := apiV3.StorageRangeAt(context.Background(), block.Hash(), uint64(txIndex), contractAddr, keyStart, maxResult)
+					require.NoError(t, err)
+					require.Equal(t, resV2, resV3, "block %d, txIndex %d, maxResult %d, page %d", block.NumberU64(), txIndex, maxResult, page)
+					if resV2.NextKey == nil {
+						break
+					}
+					keyStart = resV2.NextKey.Bytes()
+				}
+			}
+		}
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"github.com/ledgerwatch/erigon/eth/filters"
 	ethFilters "github.com/ledgerwatch/erigon/eth/filters"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
@@ -32,6 +34,9 @@ type ErigonAPI interface {
 	//GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error)
 	GetLogs(ctx context.Context, crit ethFilters.FilterCriteria) (types.ErigonLogs, error)
 	GetLatestLogs(ctx context.Context, crit filters.FilterCriteria, logOptions ethFilters.LogFilterOptions) (types.ErigonLogs, error)
+	// Paged logs (see ./erigon_logs_paged.go)
+	GetLogsPaged(ctx context.Context, crit ethFilters.FilterCriteria, pageSize *hexutil.Uint64, cursor *hexutil.Bytes) (*LogsPage, error)
+	StreamLogsPaged(ctx context.Context, crit ethFilters.FilterCriteria, pageSize *hexutil.Uint64, cursor *hexutil.Bytes, stream *jsoniter.Stream) error
 	// Gets cannonical block receipt through hash. If the block is not cannonical returns error
 	GetBlockReceiptsByBlockHash(ctx context.Context, cannonicalBlockHash common.Hash) ([]map[string]interface{}, error)
 
@@ -43,6 +48,12 @@ type ErigonAPI interface {
 
 	// NodeInfo returns a collection of metadata known about the host.
 	NodeInfo(ctx context.Context) ([]p2p.NodeInfo, error)
+
+	// SyncStatus returns the progress of the sync stages (see ./erigon_sync_status.go)
+	SyncStatus(ctx context.Context) ([]stages.Progress, error)
+
+	// Proofs related (see ./erigon_proof.go)
+	GetProofAt(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ProofAt, error)
 }
 
 // ErigonImpl is implementation of the ErigonAPI interface
@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+const (
+	// DefaultLogsPageSize is the page size of erigon_getLogsPaged when none is given.
+	DefaultLogsPageSize = 1000
+	// MaxLogsPageSize is the largest page size erigon_getLogsPaged accepts.
+	MaxLogsPageSize = 10000
+
+	logsCursorLength = 8 + 4 + 8 + 8
+)
+
+// LogsPage is a page of logs, Cursor is nil once all the logs of the range were returned.
+type LogsPage struct {
+	Logs   types.ErigonLogs `json:"logs"`
+	Cursor hexutil.Bytes    `json:"cursor"`
+}
+
+// logsCursor is the position of the next log to return. It pins the end of the range so that pages of a query up to
+// "latest" do not depend on the chain head, and carries a digest of the filter to reject cursors of other queries.
+type logsCursor struct {
+	blockNumber uint64
+	logIndex    uint32
+	toBlock     uint64
+	filterHash  [8]byte
+}
+
+func (c *logsCursor) encode() hexutil.Bytes {
+	b := make([]byte, logsCursorLength)
+	binary.BigEndian.PutUint64(b, c.blockNumber)
+	binary.BigEndian.PutUint32(b[8:], c.logIndex)
+	binary.BigEndian.PutUint64(b[12:], c.toBlock)
+	copy(b[20:], c.filterHash[:])
+	return b
+}
+
+func decodeLogsCursor(b hexutil.Bytes) (*logsCursor, error) {
+	if len(b) != logsCursorLength {
+		return nil, fmt.Errorf("invalid cursor length %d", len(b))
+	}
+	c := &logsCursor{
+		blockNumber: binary.BigEndian.Uint64(b),
+		logIndex:    binary.BigEndian.Uint32(b[8:]),
+		toBlock:     binary.BigEndian.Uint64(b[12:]),
+	}
+	copy(c.filterHash[:], b[20:])
+	return c, nil
+}
+
+// logsFilterHash digests the addresses and topics of the filter, the block range is tracked by the cursor itself.
+func logsFilterHash(crit filters.FilterCriteria) [8]byte {
+	var buf bytes.Buffer
+	for _, addr := range crit.Addresses {
+		buf.Write(addr[:])
+	}
+	for _, sub := range crit.Topics {
+		// Separates the topic positions, so that {{A},{B}} and {{A,B}} differ.
+		buf.WriteByte(0)
+		for _, topic := range sub {
+			buf.Write(topic[:])
+		}
+	}
+	var h [8]byte
+	copy(h[:], crypto.Keccak256(buf.Bytes()))
+	return h
+}
+
+// GetLogsPaged implements erigon_getLogsPaged. Returns at most pageSize logs matching a given filter object, and the
+// cursor to pass back to get the next page.
+func (api *ErigonImpl) GetLogsPaged(ctx context.Context, crit filters.FilterCriteria, pageSize *hexutil.Uint64, cursor *hexutil.Bytes) (*LogsPage, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	size, start, err := getLogsPageStart(tx, crit, pageSize, cursor)
+	if err != nil {
+		return nil, err
+	}
+	page := &LogsPage{Logs: types.ErigonLogs{}}
+	next, err := api.walkLogsPage(ctx, tx, crit, start, size, func(log *types.ErigonLog) error {
+		page.Logs = append(page.Logs, log)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if next != nil {
+		page.Cursor = next.encode()
+	}
+	return page, nil
+}
+
+// StreamLogsPaged implements erigon_streamLogsPaged, the streaming version of erigon_getLogsPaged: logs are written
+// out as they are read. If the page is cut short by an error, the cursor still points at the first log not written.
+func (api *ErigonImpl) StreamLogsPaged(ctx context.Context, crit filters.FilterCriteria, pageSize *hexutil.Uint64, cursor *hexutil.Bytes, stream *jsoniter.Stream) error {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	size, start, err := getLogsPageStart(tx, crit, pageSize, cursor)
+	if err != nil {
+		return err
+	}
+	stream.WriteObjectStart()
+	stream.WriteObjectField("logs")
+	stream.WriteArrayStart()
+	first := true
+	next, walkErr := api.walkLogsPage(ctx, tx, crit, start, size, func(log *types.ErigonLog) error {
+		b, err := json.Marshal(log)
+		if err != nil {
+			return err
+		}
+		if !first {
+			stream.WriteMore()
+		}
+		first = false
+		stream.Write(b)
+		return stream.Flush()
+	})
+	stream.WriteArrayEnd()
+	stream.WriteMore()
+	stream.WriteObjectField("cursor")
+	if next != nil {
+		stream.WriteString(next.encode().String())
+	} else {
+		stream.WriteNil()
+	}
+	if walkErr != nil {
+		stream.WriteMore()
+		rpc.HandleError(walkErr, stream)
+	}
+	stream.WriteObjectEnd()
+	return stream.Flush()
+}
+
+// getLogsPageStart validates the page size and returns the position of the first log of the page, from the cursor or
+// from the filter block range.
+func getLogsPageStart(tx kv.Tx, crit filters.FilterCriteria, pageSize *hexutil.Uint64, cursor *hexutil.Bytes) (uint64, *logsCursor, error) {
+	size := uint64(DefaultLogsPageSize)
+	if pageSize != nil {
+		size = uint64(*pageSize)
+	}
+	if size == 0 || size > MaxLogsPageSize {
+		return 0, nil, fmt.Errorf("page size must be between 1 and %d", MaxLogsPageSize)
+	}
+	filterHash := logsFilterHash(crit)
+	if cursor != nil {
+		start, err := decodeLogsCursor(*cursor)
+		if err != nil {
+			return 0, nil, err
+		}
+		if start.filterHash != filterHash {
+			return 0, nil, fmt.Errorf("cursor does not belong to this filter")
+		}
+		if start.blockNumber > start.toBlock {
+			return 0, nil, fmt.Errorf("invalid cursor block %d", start.blockNumber)
+		}
+		return size, start, nil
+	}
+	begin, end, err := getLogsBlockRange(tx, crit)
+	if err != nil {
+		return 0, nil, err
+	}
+	return size, &logsCursor{blockNumber: begin, toBlock: end, filterHash: filterHash}, nil
+}
+
+// walkLogsPage passes the logs matching the filter from start on to emit, at most size of them. Only the blocks
+// selected by the log index bitmaps, or covered by receipts snapshots out of the log index range, are read. It returns the position of the first log not emitted, nil if there are
+// none left.
+func (api *ErigonImpl) walkLogsPage(ctx context.Context, tx kv.Tx, crit filters.FilterCriteria, start *logsCursor, size uint64, emit func(*types.ErigonLog) error) (*logsCursor, error) {
+	blockNumbers, indexedFrom, indexedTo, err := api.getErigonLogsBlockNumbers(tx, crit, start.blockNumber, start.toBlock)
+	if err != nil {
+		return start, err
+	}
+	defer bitmapdb.ReturnToPool(blockNumbers)
+
+	addrMap := make(map[common.Address]struct{}, len(crit.Addresses))
+	for _, v := range crit.Addresses {
+		addrMap[v] = struct{}{}
+	}
+	emitted := uint64(0)
+	iter := blockNumbers.Iterator()
+	for iter.HasNext() {
+		blockNumber := uint64(iter.Next())
+		position := &logsCursor{blockNumber: blockNumber, toBlock: start.toBlock, filterHash: start.filterHash}
+		if blockNumber == start.blockNumber {
+			position.logIndex = start.logIndex
+		}
+		if err := ctx.Err(); err != nil {
+			return position, err
+		}
+		indexed := indexedFrom <= blockNumber && blockNumber <= indexedTo
+		blockLogs, err := api.getBlockErigonLogs(ctx, tx, blockNumber, indexed, addrMap, crit)
+		if err != nil {
+			return position, err
+		}
+		for _, log := range blockLogs {
+			if uint32(log.Index) < position.logIndex {
+				continue
+			}
+			if emitted == size {
+				position.logIndex = uint32(log.Index)
+				return position, nil
+			}
+			if err := emit(log); err != nil {
+				position.logIndex = uint32(log.Index)
+				return position, err
+			}
+			emitted++
+		}
+	}
+	return nil, nil
+}
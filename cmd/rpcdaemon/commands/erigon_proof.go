@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/rpc"
+	ethapi2 "github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+)
+
+// GetProofAt implements erigon_getProofAt. Works like eth_getProof, but also returns the block the proofs were built
+// for, so the result can be checked against the state root without an extra request
+func (api *ErigonImpl) GetProofAt(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ProofAt, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, header, err := api.getProof(ctx, tx, address, storageKeys, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return &ProofAt{
+		AccountResult: result,
+		BlockNumber:   hexutil.Uint64(header.Number.Uint64()),
+		BlockHash:     header.Hash(),
+		StateRoot:     header.Root,
+	}, nil
+}
+
+// ProofAt - result of erigon_getProofAt
+type ProofAt struct {
+	*ethapi2.AccountResult
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	StateRoot   common.Hash    `json:"stateRoot"`
+}
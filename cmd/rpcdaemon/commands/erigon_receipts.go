@@ -8,6 +8,7 @@ import (
 
 	"github.com/RoaringBitmap/roaring"
 	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
 	"github.com/ledgerwatch/erigon/common"
@@ -53,7 +54,6 @@ func (api *ErigonImpl) GetLogsByHash(ctx context.Context, hash common.Hash) ([][
 
 // GetLogs implements erigon_getLogs. Returns an array of logs matching a given filter object.
 func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (types.ErigonLogs, error) {
-	var begin, end uint64
 	erigonLogs := types.ErigonLogs{}
 
 	tx, beginErr := api.db.BeginRo(ctx)
@@ -62,10 +62,47 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 	}
 	defer tx.Rollback()
 
+	begin, end, err := getLogsBlockRange(tx, crit)
+	if err != nil {
+		return nil, err
+	}
+	blockNumbers, indexedFrom, indexedTo, err := api.getErigonLogsBlockNumbers(tx, crit, begin, end)
+	if err != nil {
+		return nil, err
+	}
+	defer bitmapdb.ReturnToPool(blockNumbers)
+	if blockNumbers.GetCardinality() == 0 {
+		return erigonLogs, nil
+	}
+
+	addrMap := make(map[common.Address]struct{}, len(crit.Addresses))
+	for _, v := range crit.Addresses {
+		addrMap[v] = struct{}{}
+	}
+	iter := blockNumbers.Iterator()
+	for iter.HasNext() {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		blockNumber := uint64(iter.Next())
+		indexed := indexedFrom <= blockNumber && blockNumber <= indexedTo
+		blockLogs, err := api.getBlockErigonLogs(ctx, tx, blockNumber, indexed, addrMap, crit)
+		if err != nil {
+			return nil, err
+		}
+		erigonLogs = append(erigonLogs, blockLogs...)
+	}
+
+	return erigonLogs, nil
+}
+
+// getLogsBlockRange resolves the block range of the filter.
+func getLogsBlockRange(tx kv.Tx, crit filters.FilterCriteria) (begin, end uint64, err error) {
 	if crit.BlockHash != nil {
 		number := rawdb.ReadHeaderNumber(tx, *crit.BlockHash)
 		if number == nil {
-			return nil, fmt.Errorf("block not found: %x", *crit.BlockHash)
+			return 0, 0, fmt.Errorf("block not found: %x", *crit.BlockHash)
 		}
 		begin = *number
 		end = *number
@@ -73,7 +110,7 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 		// Convert the RPC block numbers into internal representations
 		latest, err := rpchelper.GetLatestBlockNumber(tx)
 		if err != nil {
-			return nil, err
+			return 0, 0, err
 		}
 
 		begin = latest
@@ -81,7 +118,7 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 			if crit.FromBlock.Sign() >= 0 {
 				begin = crit.FromBlock.Uint64()
 			} else if !crit.FromBlock.IsInt64() || crit.FromBlock.Int64() != int64(rpc.LatestBlockNumber) {
-				return nil, fmt.Errorf("negative value for FromBlock: %v", crit.FromBlock)
+				return 0, 0, fmt.Errorf("negative value for FromBlock: %v", crit.FromBlock)
 			}
 		}
 		end = latest
@@ -89,22 +126,28 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 			if crit.ToBlock.Sign() >= 0 {
 				end = crit.ToBlock.Uint64()
 			} else if !crit.ToBlock.IsInt64() || crit.ToBlock.Int64() != int64(rpc.LatestBlockNumber) {
-				return nil, fmt.Errorf("negative value for ToBlock: %v", crit.ToBlock)
+				return 0, 0, fmt.Errorf("negative value for ToBlock: %v", crit.ToBlock)
 			}
 		}
 	}
 	if end < begin {
-		return nil, fmt.Errorf("end (%d) < begin (%d)", end, begin)
+		return 0, 0, fmt.Errorf("end (%d) < begin (%d)", end, begin)
 	}
 	if end > roaring.MaxUint32 {
-		return nil, fmt.Errorf("end (%d) > MaxUint32", end)
+		return 0, 0, fmt.Errorf("end (%d) > MaxUint32", end)
 	}
+	return begin, end, nil
+}
+
+// getLogsBlockNumbers returns the blocks in [begin, end] which may have logs matching the filter, according to the
+// LogAddressIndex and LogTopicIndex bitmaps. The bitmap must be returned to the pool.
+func getLogsBlockNumbers(tx kv.Tx, crit filters.FilterCriteria, begin, end uint64) (*roaring.Bitmap, error) {
 	blockNumbers := bitmapdb.NewBitmap()
-	defer bitmapdb.ReturnToPool(blockNumbers)
 	blockNumbers.AddRange(begin, end+1) // [min,max)
 
 	topicsBitmap, err := getTopicsBitmap(tx, crit.Topics, uint32(begin), uint32(end))
 	if err != nil {
+		bitmapdb.ReturnToPool(blockNumbers)
 		return nil, err
 	}
 	if topicsBitmap != nil {
@@ -115,6 +158,7 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 	for idx, addr := range crit.Addresses {
 		m, err := bitmapdb.Get(tx, kv.LogAddressIndex, addr[:], uint32(begin), uint32(end))
 		if err != nil {
+			bitmapdb.ReturnToPool(blockNumbers)
 			return nil, err
 		}
 		rx[idx] = m
@@ -124,94 +168,131 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 	if len(rx) > 0 {
 		blockNumbers.And(addrBitmap)
 	}
+	return blockNumbers, nil
+}
 
-	if blockNumbers.GetCardinality() == 0 {
-		return erigonLogs, nil
+// getErigonLogsBlockNumbers returns the blocks in [begin, end] which may have logs matching the filter: the ones
+// selected by the log index bitmaps where the log index covers them, and the ones with receipts in snapshots out of it.
+// The bitmap must be returned to the pool.
+func (api *ErigonImpl) getErigonLogsBlockNumbers(tx kv.Tx, crit filters.FilterCriteria, begin, end uint64) (blockNumbers *roaring.Bitmap, indexedFrom, indexedTo uint64, err error) {
+	indexedFrom, indexedTo, err = logIndexRange(tx)
+	if err != nil {
+		return nil, 0, 0, err
 	}
-
-	addrMap := make(map[common.Address]struct{}, len(crit.Addresses))
-	for _, v := range crit.Addresses {
-		addrMap[v] = struct{}{}
+	if from, to := cmp.Max(begin, indexedFrom), cmp.Min(end, indexedTo); from <= to {
+		if blockNumbers, err = getLogsBlockNumbers(tx, crit, from, to); err != nil {
+			return nil, 0, 0, err
+		}
+	} else {
+		blockNumbers = bitmapdb.NewBitmap()
 	}
-	iter := blockNumbers.Iterator()
-	for iter.HasNext() {
-		if err = ctx.Err(); err != nil {
-			return nil, err
+	if begin < indexedFrom {
+		for _, r := range api.frozenReceiptsRanges(begin, indexedFrom-1) {
+			blockNumbers.AddRange(r[0], r[1]+1) // [min,max)
 		}
+	}
+	if end > indexedTo {
+		for _, r := range api.frozenReceiptsRanges(cmp.Max(begin, indexedTo+1), end) {
+			blockNumbers.AddRange(r[0], r[1]+1) // [min,max)
+		}
+	}
+	return blockNumbers, indexedFrom, indexedTo, nil
+}
 
-		blockNumber := uint64(iter.Next())
-		var logIndex uint
-		var txIndex uint
-		var blockLogs []*types.Log
-		it, err := tx.Prefix(kv.Log, common2.EncodeTs(blockNumber))
+// getBlockErigonLogs returns the logs of the block matching the filter, from the log tables if the block is in the log
+// index range and from receipts snapshots otherwise.
+func (api *ErigonImpl) getBlockErigonLogs(ctx context.Context, tx kv.Tx, blockNumber uint64, indexed bool, addrMap map[common.Address]struct{}, crit filters.FilterCriteria) (types.ErigonLogs, error) {
+	if !indexed {
+		blockLogs, header, err := api.getFrozenBlockLogs(ctx, tx, blockNumber, addrMap, crit)
 		if err != nil {
 			return nil, err
 		}
-		for it.HasNext() {
-			k, v, err := it.Next()
-			if err != nil {
-				return erigonLogs, err
-			}
-			var logs types.Logs
-			if err := cbor.Unmarshal(&logs, bytes.NewReader(v)); err != nil {
-				return erigonLogs, fmt.Errorf("receipt unmarshal failed:  %w", err)
-			}
-			for _, log := range logs {
-				log.Index = logIndex
-				logIndex++
-			}
-			filtered := logs.Filter(addrMap, crit.Topics)
-			if len(filtered) == 0 {
-				continue
-			}
-			txIndex = uint(binary.BigEndian.Uint32(k[8:]))
-			for _, log := range filtered {
-				log.TxIndex = txIndex
-			}
-			blockLogs = append(blockLogs, filtered...)
-		}
-		if len(blockLogs) == 0 {
-			continue
-		}
+		return toErigonLogs(blockLogs, header.Time), nil
+	}
 
-		header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNumber)
+	var logIndex uint
+	var txIndex uint
+	var blockLogs types.Logs
+	it, err := tx.Prefix(kv.Log, common2.EncodeTs(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+	for it.HasNext() {
+		k, v, err := it.Next()
 		if err != nil {
 			return nil, err
 		}
-		if header == nil {
-			return nil, fmt.Errorf("block header not found: %d", blockNumber)
+		var logs types.Logs
+		if err := cbor.Unmarshal(&logs, bytes.NewReader(v)); err != nil {
+			return nil, fmt.Errorf("receipt unmarshal failed:  %w", err)
 		}
-		timestamp := header.Time
-
-		blockHash := header.Hash()
-		body, err := api._blockReader.BodyWithTransactions(ctx, tx, blockHash, blockNumber)
-		if err != nil {
-			return nil, err
+		for _, log := range logs {
+			log.Index = logIndex
+			logIndex++
 		}
-		if body == nil {
-			return nil, fmt.Errorf("block not found %d", blockNumber)
+		filtered := logs.Filter(addrMap, crit.Topics)
+		if len(filtered) == 0 {
+			continue
 		}
-		for _, log := range blockLogs {
-			erigonLog := &types.ErigonLog{}
-			erigonLog.BlockNumber = blockNumber
-			erigonLog.BlockHash = blockHash
-			if log.TxIndex == uint(len(body.Transactions)) {
-				erigonLog.TxHash = types.ComputeBorTxHash(blockNumber, blockHash)
-			} else {
-				erigonLog.TxHash = body.Transactions[log.TxIndex].Hash()
-			}
-			erigonLog.Timestamp = timestamp
-			erigonLog.Address = log.Address
-			erigonLog.Topics = log.Topics
-			erigonLog.Data = log.Data
-			erigonLog.Index = log.Index
-			erigonLog.Removed = log.Removed
-			erigonLog.TxIndex = log.TxIndex
-			erigonLogs = append(erigonLogs, erigonLog)
+		txIndex = uint(binary.BigEndian.Uint32(k[8:]))
+		for _, log := range filtered {
+			log.TxIndex = txIndex
 		}
+		blockLogs = append(blockLogs, filtered...)
+	}
+	if len(blockLogs) == 0 {
+		return nil, nil
 	}
 
-	return erigonLogs, nil
+	header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block header not found: %d", blockNumber)
+	}
+
+	blockHash := header.Hash()
+	body, err := api._blockReader.BodyWithTransactions(ctx, tx, blockHash, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("block not found %d", blockNumber)
+	}
+	for _, log := range blockLogs {
+		log.BlockNumber = blockNumber
+		log.BlockHash = blockHash
+		if log.TxIndex == uint(len(body.Transactions)) {
+			log.TxHash = types.ComputeBorTxHash(blockNumber, blockHash)
+		} else {
+			log.TxHash = body.Transactions[log.TxIndex].Hash()
+		}
+	}
+	return toErigonLogs(blockLogs, header.Time), nil
+}
+
+// toErigonLogs adds the block timestamp to logs with derived fields set.
+func toErigonLogs(logs types.Logs, timestamp uint64) types.ErigonLogs {
+	if len(logs) == 0 {
+		return nil
+	}
+	erigonLogs := make(types.ErigonLogs, 0, len(logs))
+	for _, log := range logs {
+		erigonLog := &types.ErigonLog{}
+		erigonLog.BlockNumber = log.BlockNumber
+		erigonLog.BlockHash = log.BlockHash
+		erigonLog.TxHash = log.TxHash
+		erigonLog.Timestamp = timestamp
+		erigonLog.Address = log.Address
+		erigonLog.Topics = log.Topics
+		erigonLog.Data = log.Data
+		erigonLog.Index = log.Index
+		erigonLog.Removed = log.Removed
+		erigonLog.TxIndex = log.TxIndex
+		erigonLogs = append(erigonLogs, erigonLog)
+	}
+	return erigonLogs
 }
 
 // GetLatestLogs implements erigon_getLatestLogs.
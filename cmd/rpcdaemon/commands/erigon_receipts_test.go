@@ -1,15 +1,19 @@
 package commands
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"math/big"
 	"testing"
 
 	"github.com/holiman/uint256"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
@@ -31,7 +35,7 @@ func TestErigonGetLatestLogs(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	db := m.DB
 	agg := m.HistoryV3Components()
-	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), db, nil)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), db, nil)
 	expectedLogs, _ := api.GetLogs(context.Background(), filters.FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())})
 
 	expectedErigonLogs := make([]*types.ErigonLog, 0)
@@ -66,7 +70,7 @@ func TestErigonGetLatestLogsIgnoreTopics(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	db := m.DB
 	agg := m.HistoryV3Components()
-	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), db, nil)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), db, nil)
 	expectedLogs, _ := api.GetLogs(context.Background(), filters.FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())})
 
 	expectedErigonLogs := make([]*types.ErigonLog, 0)
@@ -107,6 +111,58 @@ func TestErigonGetLatestLogsIgnoreTopics(t *testing.T) {
 	assert.EqualValues(expectedErigonLogs, actual)
 }
 
+func TestErigonGetLogsPaged(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	agg := m.HistoryV3Components()
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil)
+	crit := filters.FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())}
+	expectedLogs, err := api.GetLogs(context.Background(), crit)
+	require.NoError(t, err)
+	require.Greater(t, len(expectedLogs), 3)
+
+	for _, size := range []hexutil.Uint64{1, 3, hexutil.Uint64(len(expectedLogs)), MaxLogsPageSize} {
+		var logs types.ErigonLogs
+		var cursor *hexutil.Bytes
+		pages := 0
+		for {
+			page, err := api.GetLogsPaged(context.Background(), crit, &size, cursor)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page.Logs), int(size))
+			logs = append(logs, page.Logs...)
+			pages++
+			if page.Cursor == nil {
+				break
+			}
+			cursor = &page.Cursor
+		}
+		require.Equal(t, expectedLogs, logs, "page size %d", size)
+		require.Equal(t, (len(expectedLogs)+int(size)-1)/int(size), pages, "page size %d", size)
+	}
+
+	size := hexutil.Uint64(2)
+	page, err := api.GetLogsPaged(context.Background(), crit, &size, nil)
+	require.NoError(t, err)
+	// Cursors cannot be used with another filter.
+	otherCrit := filters.FilterCriteria{Addresses: []common.Address{{1}}}
+	_, err = api.GetLogsPaged(context.Background(), otherCrit, &size, &page.Cursor)
+	require.Error(t, err)
+	zero := hexutil.Uint64(0)
+	_, err = api.GetLogsPaged(context.Background(), crit, &zero, nil)
+	require.Error(t, err)
+
+	// The streaming version returns the same page.
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	require.NoError(t, api.StreamLogsPaged(context.Background(), crit, &size, &page.Cursor, stream))
+	expectedPage, err := api.GetLogsPaged(context.Background(), crit, &size, &page.Cursor)
+	require.NoError(t, err)
+	expectedJson, err := json.Marshal(expectedPage)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedJson), buf.String())
+}
+
 var (
 	// testKey is a private key to use for funding a tester account.
 	testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
@@ -156,7 +212,7 @@ func TestGetBlockReceiptsByBlockHash(t *testing.T) {
 	agg := m.HistoryV3Components()
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil)
 
 	if m.HistoryV3 {
 		t.Skip("ErigonV3 doesn't store receipts in DB. Can't use \"rawdb\" package in this case. need somehow change assertion")
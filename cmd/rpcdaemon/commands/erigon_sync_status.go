@@ -0,0 +1,13 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+// SyncStatus implements erigon_syncStatus. Returns, for every enabled sync stage in the order they run, the current
+// and target block, throughput, ETA, last error and latest unwinds.
+func (api *ErigonImpl) SyncStatus(ctx context.Context) ([]stages.Progress, error) {
+	return api.ethBackend.SyncProgress(ctx)
+}
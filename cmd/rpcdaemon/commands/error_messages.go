@@ -8,3 +8,6 @@ const NotAvailableChainData = "the function %s is not available, please use --pr
 
 // NotAvailableDeprecated x
 const NotAvailableDeprecated = "the method has been deprecated: %s"
+
+// SignerDisabled is returned by the methods signing with local accounts when no signer is configured
+const SignerDisabled = "the method requires a signer, enable one with --rpc.signer: %s"
@@ -296,10 +296,17 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs
 	return hexutil.Uint64(hi), nil
 }
 
-// GetProof not implemented
-func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error) {
-	var stub interface{}
-	return &stub, fmt.Errorf(NotImplemented, "eth_getProof")
+// GetProof implements eth_getProof (EIP-1186). Returns the account and storage values of the specified account
+// including the Merkle proofs. See BaseAPI.getProof for the blocks proofs are available for
+func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi2.AccountResult, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, _, err := api.getProof(ctx, tx, address, storageKeys, blockNrOrHash)
+	return result, err
 }
 
 func (api *APIImpl) tryBlockFromLru(hash common.Hash) *types.Block {
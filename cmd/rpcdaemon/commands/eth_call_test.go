@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,11 +28,14 @@ import (
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages"
+	"github.com/ledgerwatch/erigon/turbo/trie"
 )
 
 func TestEstimateGas(t *testing.T) {
@@ -40,7 +46,7 @@ func TestEstimateGas(t *testing.T) {
 	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
 	mining := txpool.NewMiningClient(conn)
 	ff := rpchelper.New(ctx, nil, nil, mining, func() {})
-	api := NewEthAPI(NewBaseApi(ff, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000)
+	api := NewEthAPI(NewBaseApi(ff, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil, nil, nil, 5000000, nil)
 	var from = common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
 	var to = common.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
 	if _, err := api.EstimateGas(context.Background(), &ethapi.CallArgs{
@@ -56,7 +62,7 @@ func TestEthCallNonCanonical(t *testing.T) {
 	agg := m.HistoryV3Components()
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil, nil, nil, 5000000, nil)
 	var from = common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
 	var to = common.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
 	if _, err := api.Call(context.Background(), ethapi.CallArgs{
@@ -81,7 +87,7 @@ func TestEthCallToPrunedBlock(t *testing.T) {
 	agg := m.HistoryV3Components()
 
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil, nil, nil, 5000000, nil)
 
 	callData := hexutil.MustDecode("0x2e64cec1")
 	callDataBytes := hexutil.Bytes(callData)
@@ -107,7 +113,7 @@ func TestGetBlockByTimestampLatestTime(t *testing.T) {
 	defer tx.Rollback()
 
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil)
 
 	latestBlock := rawdb.ReadCurrentBlock(tx)
 	response, err := ethapi.RPCMarshalBlockDeprecated(latestBlock, true, false)
@@ -145,7 +151,7 @@ func TestGetBlockByTimestampOldestTime(t *testing.T) {
 	defer tx.Rollback()
 
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil)
 
 	oldestBlock, err := rawdb.ReadBlockByNumber(tx, 0)
 	if err != nil {
@@ -187,7 +193,7 @@ func TestGetBlockByTimeHigherThanLatestBlock(t *testing.T) {
 	defer tx.Rollback()
 
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil)
 
 	latestBlock := rawdb.ReadCurrentBlock(tx)
 
@@ -226,7 +232,7 @@ func TestGetBlockByTimeMiddle(t *testing.T) {
 	defer tx.Rollback()
 
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil)
 
 	currentHeader := rawdb.ReadCurrentHeader(tx)
 	oldestHeader, err := api._blockReader.HeaderByNumber(ctx, tx, 0)
@@ -278,7 +284,7 @@ func TestGetBlockByTimestamp(t *testing.T) {
 	defer tx.Rollback()
 
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil)
 
 	highestBlockNumber := rawdb.ReadCurrentHeader(tx).Number
 	pickedBlock, err := rawdb.ReadBlockByNumber(tx, highestBlockNumber.Uint64()/3)
@@ -312,13 +318,16 @@ func TestGetBlockByTimestamp(t *testing.T) {
 	}
 }
 
+// simpleStorageContract has methods retrieve() (0x2e64cec1) and store(uint256) (0x6057361d), the value is kept in slot 0
+var simpleStorageContract = hexutil.MustDecode("0x608060405234801561001057600080fd5b50610150806100206000396000f3fe608060405234801561001057600080fd5b50600436106100365760003560e01c80632e64cec11461003b5780636057361d14610059575b600080fd5b610043610075565b60405161005091906100d9565b60405180910390f35b610073600480360381019061006e919061009d565b61007e565b005b60008054905090565b8060008190555050565b60008135905061009781610103565b92915050565b6000602082840312156100b3576100b26100fe565b5b60006100c184828501610088565b91505092915050565b6100d3816100f4565b82525050565b60006020820190506100ee60008301846100ca565b92915050565b6000819050919050565b600080fd5b61010c816100f4565b811461011757600080fd5b5056fea26469706673582212209a159a4f3847890f10bfb87871a61eba91c5dbf5ee3cf6398207e292eee22a1664736f6c63430008070033")
+
 func chainWithDeployedContract(t *testing.T) (*stages.MockSentry, common.Address, common.Address) {
 	var (
 		signer      = types.LatestSignerForChainID(nil)
 		bankKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		bankAddress = crypto.PubkeyToAddress(bankKey.PublicKey)
 		bankFunds   = big.NewInt(1e9)
-		contract    = hexutil.MustDecode("0x608060405234801561001057600080fd5b50610150806100206000396000f3fe608060405234801561001057600080fd5b50600436106100365760003560e01c80632e64cec11461003b5780636057361d14610059575b600080fd5b610043610075565b60405161005091906100d9565b60405180910390f35b610073600480360381019061006e919061009d565b61007e565b005b60008054905090565b8060008190555050565b60008135905061009781610103565b92915050565b6000602082840312156100b3576100b26100fe565b5b60006100c184828501610088565b91505092915050565b6100d3816100f4565b82525050565b60006020820190506100ee60008301846100ca565b92915050565b6000819050919050565b600080fd5b61010c816100f4565b811461011757600080fd5b5056fea26469706673582212209a159a4f3847890f10bfb87871a61eba91c5dbf5ee3cf6398207e292eee22a1664736f6c63430008070033")
+		contract    = simpleStorageContract
 		gspec       = &core.Genesis{
 			Config: params.TestChainConfig,
 			Alloc:  core.GenesisAlloc{bankAddress: {Balance: bankFunds}},
@@ -398,3 +407,341 @@ func prune(t *testing.T, db kv.RwDB, pruneTo uint64) {
 	err = tx.Commit()
 	assert.NoError(t, err)
 }
+
+// chainWithContractStorage deploys simpleStorageContract in block 1 and calls store(n) in each following block n
+func chainWithContractStorage(t *testing.T, blocks int) (*stages.MockSentry, common.Address, common.Address) {
+	var (
+		signer      = types.LatestSignerForChainID(nil)
+		bankKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		bankAddress = crypto.PubkeyToAddress(bankKey.PublicKey)
+		bankFunds   = big.NewInt(1e9)
+		gspec       = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{bankAddress: {Balance: bankFunds}},
+		}
+	)
+	m := stages.MockWithGenesis(t, gspec, bankKey, false)
+
+	var contractAddr common.Address
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, blocks, func(i int, block *core.BlockGen) {
+		nonce := block.TxNonce(bankAddress)
+		if i == 0 {
+			txn, err := types.SignTx(types.NewContractCreation(nonce, new(uint256.Int), 1e6, new(uint256.Int), simpleStorageContract), *signer, bankKey)
+			assert.NoError(t, err)
+			block.AddTx(txn)
+			contractAddr = crypto.CreateAddress(bankAddress, nonce)
+			return
+		}
+		data := append(hexutil.MustDecode("0x6057361d"), common.BigToHash(big.NewInt(int64(i+1))).Bytes()...)
+		txn, err := types.SignTx(types.NewTransaction(nonce, contractAddr, new(uint256.Int), 90000, new(uint256.Int), data), *signer, bankKey)
+		assert.NoError(t, err)
+		block.AddTx(txn)
+	}, false /* intermediateHashes */)
+	if err != nil {
+		t.Fatalf("generate blocks: %v", err)
+	}
+	if err = m.InsertChain(chain); err != nil {
+		t.Fatalf("insert chain: %v", err)
+	}
+	return m, bankAddress, contractAddr
+}
+
+func TestGetProof(t *testing.T) {
+	m, bankAddr, contractAddr := chainWithContractStorage(t, 3)
+	if m.HistoryV3 {
+		t.Skip("eth_getProof is not supported on history v3 databases")
+	}
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil, nil, nil, 5000000, nil)
+
+	tx, err := m.DB.BeginRo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	header := rawdb.ReadCurrentHeader(tx)
+
+	for _, tt := range []struct {
+		name        string
+		addr        common.Address
+		storageKeys []string
+	}{
+		{name: "contract with storage", addr: contractAddr, storageKeys: []string{"0x0", "0x1"}},
+		{name: "account without storage", addr: bankAddr, storageKeys: []string{"0x0"}},
+		{name: "non-existing account", addr: common.HexToAddress("0xdeadbeef"), storageKeys: []string{"0x0"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, err := api.GetProof(context.Background(), tt.addr, tt.storageKeys, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+			if err != nil {
+				t.Fatalf("eth_getProof: %v", err)
+			}
+			verifyAccountResult(t, header.Root, proof)
+
+			st := state.New(state.NewPlainStateReader(tx))
+			assert.Equal(t, st.GetBalance(tt.addr).ToBig().String(), proof.Balance.ToInt().String())
+			assert.Equal(t, st.GetNonce(tt.addr), uint64(proof.Nonce))
+			for i, key := range tt.storageKeys {
+				k := common.HexToHash(key)
+				var v uint256.Int
+				st.GetState(tt.addr, &k, &v)
+				assert.Equal(t, v.ToBig().String(), proof.StorageProof[i].Value.ToInt().String())
+			}
+		})
+	}
+
+	for _, key := range []string{"0xzz", "0x" + strings.Repeat("11", 33), "11"} {
+		_, err := api.GetProof(context.Background(), contractAddr, []string{key}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != -32602 {
+			t.Errorf("expected invalid params error for storage key %q, got %v", key, err)
+		}
+	}
+}
+
+func TestGetProofHistorical(t *testing.T) {
+	const blocks = 5
+	m, bankAddr, contractAddr := chainWithContractStorage(t, blocks)
+	if m.HistoryV3 {
+		t.Skip("eth_getProof is not supported on history v3 databases")
+	}
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil, nil, nil, 5000000, nil)
+
+	tx, err := m.DB.BeginRo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	for blockNum := uint64(0); blockNum <= blocks; blockNum++ {
+		header := rawdb.ReadHeaderByNumber(tx, blockNum)
+		st := state.New(state.NewPlainState(tx, blockNum+1, nil))
+		for _, addr := range []common.Address{contractAddr, bankAddr} {
+			storageKeys := []string{"0x0", "0x1"}
+			proof, err := api.GetProof(context.Background(), addr, storageKeys, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum)))
+			if err != nil {
+				t.Fatalf("eth_getProof at block %d: %v", blockNum, err)
+			}
+			verifyAccountResult(t, header.Root, proof)
+
+			assert.Equal(t, st.GetBalance(addr).ToBig().String(), proof.Balance.ToInt().String(), "block %d", blockNum)
+			assert.Equal(t, st.GetNonce(addr), uint64(proof.Nonce), "block %d", blockNum)
+			for i, key := range storageKeys {
+				k := common.HexToHash(key)
+				var v uint256.Int
+				st.GetState(addr, &k, &v)
+				assert.Equal(t, v.ToBig().String(), proof.StorageProof[i].Value.ToInt().String(), "block %d", blockNum)
+			}
+		}
+	}
+
+	// rewinding further than allowed
+	api = NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, 2), m.DB, nil, nil, nil, 5000000, nil)
+	if _, err = api.GetProof(context.Background(), contractAddr, nil, rpc.BlockNumberOrHashWithNumber(blocks-2)); err != nil {
+		t.Errorf("eth_getProof within the rewind limit: %v", err)
+	}
+	if _, err = api.GetProof(context.Background(), contractAddr, nil, rpc.BlockNumberOrHashWithNumber(blocks-3)); err == nil {
+		t.Errorf("expected error for the block beyond the rewind limit")
+	}
+}
+
+func TestGetProofHistoryPruned(t *testing.T) {
+	const blocks = 5
+	m, _, contractAddr := chainWithContractStorage(t, blocks)
+	if m.HistoryV3 {
+		t.Skip("eth_getProof is not supported on history v3 databases")
+	}
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil, nil, nil, 5000000, nil)
+
+	// keep the history of the last 2 blocks only
+	if err := m.DB.Update(context.Background(), func(tx kv.RwTx) error {
+		return prune2.Override(tx, prune2.Mode{
+			Initialised: true,
+			History:     prune2.Distance(2),
+			Receipts:    prune2.Distance(math.MaxUint64),
+			TxIndex:     prune2.Distance(math.MaxUint64),
+			CallTraces:  prune2.Distance(math.MaxUint64),
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := api.GetProof(context.Background(), contractAddr, nil, rpc.BlockNumberOrHashWithNumber(blocks-3)); err != nil {
+		t.Errorf("eth_getProof within the kept history: %v", err)
+	}
+	_, err := api.GetProof(context.Background(), contractAddr, nil, rpc.BlockNumberOrHashWithNumber(blocks-4))
+	if err == nil || !strings.Contains(err.Error(), "history pruned") {
+		t.Errorf("expected history pruned error, got %v", err)
+	}
+}
+
+func TestGetProofAt(t *testing.T) {
+	m, _, contractAddr := chainWithContractStorage(t, 3)
+	if m.HistoryV3 {
+		t.Skip("eth_getProof is not supported on history v3 databases")
+	}
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewErigonAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil)
+
+	tx, err := m.DB.BeginRo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	header := rawdb.ReadHeaderByNumber(tx, 2)
+
+	proof, err := api.GetProofAt(context.Background(), contractAddr, []string{"0x0"}, rpc.BlockNumberOrHashWithHash(header.Hash(), true))
+	if err != nil {
+		t.Fatalf("erigon_getProofAt: %v", err)
+	}
+	assert.Equal(t, uint64(2), uint64(proof.BlockNumber))
+	assert.Equal(t, header.Hash(), proof.BlockHash)
+	assert.Equal(t, header.Root, proof.StateRoot)
+	verifyAccountResult(t, proof.StateRoot, proof.AccountResult)
+	assert.Equal(t, "2", proof.StorageProof[0].Value.ToInt().String())
+}
+
+// verifyAccountResult checks all proofs of eth_getProof result against the state root
+func verifyAccountResult(t *testing.T, root common.Hash, res *ethapi.AccountResult) {
+	t.Helper()
+	enc, err := verifyProof(root, res.Address[:], res.AccountProof)
+	if err != nil {
+		t.Fatalf("account proof: %v", err)
+	}
+	if enc == nil {
+		assert.Zero(t, res.Balance.ToInt().Sign())
+		assert.Zero(t, uint64(res.Nonce))
+		assert.Equal(t, trie.EmptyRoot, res.StorageHash)
+	} else {
+		var acc struct {
+			Nonce    uint64
+			Balance  *big.Int
+			Root     common.Hash
+			CodeHash common.Hash
+		}
+		if err = rlp.DecodeBytes(enc, &acc); err != nil {
+			t.Fatalf("decode account: %v", err)
+		}
+		assert.Equal(t, acc.Nonce, uint64(res.Nonce))
+		assert.Equal(t, acc.Balance.String(), res.Balance.ToInt().String())
+		assert.Equal(t, acc.Root, res.StorageHash)
+		assert.Equal(t, acc.CodeHash, res.CodeHash)
+	}
+
+	for _, sp := range res.StorageProof {
+		key := common.HexToHash(sp.Key)
+		enc, err := verifyProof(res.StorageHash, key[:], sp.Proof)
+		if err != nil {
+			t.Fatalf("storage proof of %s: %v", sp.Key, err)
+		}
+		var v []byte
+		if enc != nil {
+			if err = rlp.DecodeBytes(enc, &v); err != nil {
+				t.Fatalf("decode storage value: %v", err)
+			}
+		}
+		assert.Equal(t, new(big.Int).SetBytes(v).String(), sp.Value.ToInt().String())
+	}
+}
+
+// verifyProof is a minimal Merkle-Patricia proof verifier, independent of turbo/trie. Like other
+// implementations of EIP-1186 verifiers, it resolves proof nodes by their hashes, starting from the root.
+// It returns the value stored at keccak(key), or nil if the proof proves absence of the key
+func verifyProof(root common.Hash, key []byte, proof []string) ([]byte, error) {
+	if root == trie.EmptyRoot {
+		if len(proof) != 0 {
+			return nil, fmt.Errorf("non-empty proof for the empty trie")
+		}
+		return nil, nil
+	}
+	nodes := make(map[common.Hash][]byte, len(proof))
+	for _, p := range proof {
+		n, err := hexutil.Decode(p)
+		if err != nil {
+			return nil, err
+		}
+		nodes[crypto.Keccak256Hash(n)] = n
+	}
+	var path []byte
+	for _, b := range crypto.Keccak256(key) {
+		path = append(path, b>>4, b&0x0f)
+	}
+
+	n, ok := nodes[root]
+	if !ok {
+		return nil, fmt.Errorf("root node %x is not in the proof", root)
+	}
+	for {
+		var elems []rlp.RawValue
+		if err := rlp.DecodeBytes(n, &elems); err != nil {
+			return nil, err
+		}
+		var ref rlp.RawValue
+		switch len(elems) {
+		case 17:
+			if len(path) == 0 {
+				return nil, fmt.Errorf("key is shorter than the path in the trie")
+			}
+			ref, path = elems[path[0]], path[1:]
+		case 2:
+			var compact []byte
+			if err := rlp.DecodeBytes(elems[0], &compact); err != nil {
+				return nil, err
+			}
+			if len(compact) == 0 {
+				return nil, fmt.Errorf("empty key in the short node")
+			}
+			isLeaf := compact[0]&0x20 != 0
+			var nibbles []byte
+			if compact[0]&0x10 != 0 {
+				nibbles = append(nibbles, compact[0]&0x0f)
+			}
+			for _, b := range compact[1:] {
+				nibbles = append(nibbles, b>>4, b&0x0f)
+			}
+			if !bytes.HasPrefix(path, nibbles) {
+				return nil, nil
+			}
+			path = path[len(nibbles):]
+			if isLeaf {
+				if len(path) != 0 {
+					return nil, nil
+				}
+				var v []byte
+				if err := rlp.DecodeBytes(elems[1], &v); err != nil {
+					return nil, err
+				}
+				return v, nil
+			}
+			ref = elems[1]
+		default:
+			return nil, fmt.Errorf("invalid node with %d elements", len(elems))
+		}
+
+		kind, content, _, err := rlp.Split(ref)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case kind == rlp.List: // embedded node
+			n = ref
+		case len(content) == 0:
+			return nil, nil
+		case len(content) == 32:
+			if n, ok = nodes[common.BytesToHash(content)]; !ok {
+				return nil, fmt.Errorf("node %x is not in the proof", content)
+			}
+		default:
+			return nil, fmt.Errorf("invalid node reference %x", ref)
+		}
+	}
+}
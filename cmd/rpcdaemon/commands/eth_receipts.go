@@ -11,6 +11,7 @@ import (
 	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/holiman/uint256"
 	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
 	"github.com/ledgerwatch/erigon/core/state/temporal"
@@ -25,7 +26,9 @@ import (
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
 	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
@@ -33,7 +36,11 @@ import (
 )
 
 func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, senders []common.Address) (types.Receipts, error) {
-	if cached := rawdb.ReadReceipts(tx, block, senders); cached != nil {
+	cached, err := api._blockReader.Receipts(ctx, tx, block, senders)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
 		return cached, nil
 	}
 	engine := api.engine()
@@ -135,6 +142,54 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (t
 		return api.getLogsV3(ctx, tx.(kv.TemporalTx), begin, end, crit)
 	}
 
+	// Log index covers [indexedFrom, indexedTo], logs of blocks out of it may still be in receipts snapshots
+	indexedFrom, indexedTo, err := logIndexRange(tx)
+	if err != nil {
+		return nil, err
+	}
+	if begin < indexedFrom {
+		frozen, err := api.getLogsFromFrozenReceipts(ctx, tx, begin, cmp.Min(end, indexedFrom-1), crit)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, frozen...)
+	}
+	if from, to := cmp.Max(begin, indexedFrom), cmp.Min(end, indexedTo); from <= to {
+		indexed, err := api.getLogsFromLogIndex(ctx, tx, from, to, crit)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, indexed...)
+	}
+	if end > indexedTo {
+		frozen, err := api.getLogsFromFrozenReceipts(ctx, tx, cmp.Max(begin, indexedTo+1), end, crit)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, frozen...)
+	}
+	return logs, nil
+}
+
+// logIndexRange - blocks covered by the log index: it's built up to the LogIndex stage progress and pruned together
+// with receipts
+func logIndexRange(tx kv.Tx) (from, to uint64, err error) {
+	to, err = stages.GetStageProgress(tx, stages.LogIndex)
+	if err != nil {
+		return 0, 0, err
+	}
+	pruneMode, err := prune2.Get(tx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !pruneMode.Receipts.Enabled() {
+		return 0, to, nil
+	}
+	return pruneMode.Receipts.PruneTo(to), to, nil
+}
+
+func (api *APIImpl) getLogsFromLogIndex(ctx context.Context, tx kv.Tx, begin, end uint64, crit filters.FilterCriteria) (types.Logs, error) {
+	logs := types.Logs{}
 	blockNumbers := bitmapdb.NewBitmap()
 	defer bitmapdb.ReturnToPool(blockNumbers)
 	blockNumbers.AddRange(begin, end+1) // [min,max)
@@ -240,6 +295,98 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (t
 	return logs, nil
 }
 
+// getLogsFromFrozenReceipts - scans receipts snapshots, only the parts of [begin, end] they cover are visited
+func (api *APIImpl) getLogsFromFrozenReceipts(ctx context.Context, tx kv.Tx, begin, end uint64, crit filters.FilterCriteria) (types.Logs, error) {
+	logs := types.Logs{}
+	addrMap := make(map[common.Address]struct{}, len(crit.Addresses))
+	for _, v := range crit.Addresses {
+		addrMap[v] = struct{}{}
+	}
+	for _, r := range api.frozenReceiptsRanges(begin, end) {
+		for blockNumber := r[0]; blockNumber <= r[1]; blockNumber++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			blockLogs, _, err := api.getFrozenBlockLogs(ctx, tx, blockNumber, addrMap, crit)
+			if err != nil {
+				return nil, err
+			}
+			logs = append(logs, blockLogs...)
+		}
+	}
+	return logs, nil
+}
+
+// frozenReceiptsRanges - parts of [begin, end] covered by receipts snapshots, as [from, to] ranges
+func (api *BaseAPI) frozenReceiptsRanges(begin, end uint64) (ranges [][2]uint64) {
+	for _, r := range api._blockReader.FrozenReceiptsRanges() {
+		if from, to := cmp.Max(begin, r[0]), cmp.Min(end, r[1]-1); from <= to {
+			ranges = append(ranges, [2]uint64{from, to})
+		}
+	}
+	return ranges
+}
+
+// getFrozenBlockLogs - logs of the block matching the filter, read from receipts snapshots. Logs have derived fields
+// set. Returns no logs without reading the receipts if the header bloom rules the block out.
+func (api *BaseAPI) getFrozenBlockLogs(ctx context.Context, tx kv.Tx, blockNumber uint64, addrMap map[common.Address]struct{}, crit filters.FilterCriteria) (types.Logs, *types.Header, error) {
+	header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header == nil {
+		return nil, nil, fmt.Errorf("block header not found: %d", blockNumber)
+	}
+	if !bloomMatches(header.Bloom, crit) {
+		return nil, header, nil
+	}
+	block, senders, err := api._blockReader.BlockWithSenders(ctx, tx, header.Hash(), blockNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	if block == nil {
+		return nil, nil, fmt.Errorf("block not found %d", blockNumber)
+	}
+	receipts, err := api._blockReader.Receipts(ctx, tx, block, senders)
+	if err != nil {
+		return nil, nil, err
+	}
+	var logs types.Logs
+	for _, receipt := range receipts {
+		logs = append(logs, types.Logs(receipt.Logs).Filter(addrMap, crit.Topics)...)
+	}
+	return logs, header, nil
+}
+
+// bloomMatches - block may contain logs matching the criteria
+func bloomMatches(bloom types.Bloom, crit filters.FilterCriteria) bool {
+	if len(crit.Addresses) > 0 {
+		var included bool
+		for _, addr := range crit.Addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, sub := range crit.Topics {
+		included := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
 // The Topic list restricts matches to particular event topics. Each event has a list
 // of topics. Topics matches a prefix of that list. An empty element slice matches any
 // topic. Non-empty elements represent an alternative that matches any of the
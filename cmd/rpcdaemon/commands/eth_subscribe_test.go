@@ -42,7 +42,7 @@ func TestEthSubscribe(t *testing.T) {
 	ctx := context.Background()
 	backendServer := privateapi.NewEthBackendServer(ctx, nil, m.DB, m.Notifications.Events, br, nil, nil, nil, false)
 	backendClient := direct.NewEthBackendClientDirect(backendServer)
-	backend := rpcservices.NewRemoteBackend(backendClient, m.DB, br)
+	backend := rpcservices.NewRemoteBackend(backendClient, privateapi.NewSyncProgressClientDirect(backendServer), privateapi.NewCliqueClientDirect(backendServer), privateapi.NewAdminClientDirect(backendServer), m.DB, br)
 	ff := rpchelper.New(ctx, backend, nil, nil, func() {})
 
 	newHeads, id := ff.SubscribeNewHeads(16)
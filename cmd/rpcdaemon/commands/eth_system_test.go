@@ -43,8 +43,8 @@ func TestGasPrice(t *testing.T) {
 			m := createGasPriceTestKV(t, testCase.chainSize)
 			defer m.DB.Close()
 			stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-			base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-			eth := NewEthAPI(base, m.DB, nil, nil, nil, 5000000)
+			base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount)
+			eth := NewEthAPI(base, m.DB, nil, nil, nil, 5000000, nil)
 
 			ctx := context.Background()
 			result, err := eth.GasPrice(ctx)
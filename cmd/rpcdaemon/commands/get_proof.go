@@ -0,0 +1,363 @@
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/temporal/historyv2"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/state/historyv2read"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/rpc"
+	ethapi2 "github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// getProof builds the account and storage proofs (EIP-1186) at the given block. Proofs are built from the hashed
+// state and intermediate hashes, which exist only for the block up to which the IntermediateHashes stage has progressed.
+// For older blocks the account and storage change sets are applied backwards onto a temporary in-memory overlay of
+// the hashed state, the real DB is never modified. How far back it can go is limited by maxGetProofRewindBlockCount
+func (api *BaseAPI) getProof(ctx context.Context, tx kv.Tx, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi2.AccountResult, *types.Header, error) {
+	if api.historyV3(tx) {
+		return nil, nil, fmt.Errorf("eth_getProof is not supported on history v3 databases")
+	}
+
+	keys := make([]common.Hash, len(storageKeys))
+	for i, key := range storageKeys {
+		k, err := decodeStorageKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = k
+	}
+
+	blockNumber, _, _, err := rpchelper.GetCanonicalBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header == nil {
+		return nil, nil, fmt.Errorf("block %d not found", blockNumber)
+	}
+
+	trieProgress, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashStateProgress, err := stages.GetStageProgress(tx, stages.HashState)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hashStateProgress != trieProgress {
+		return nil, nil, fmt.Errorf("hashed state (block %d) and intermediate hashes (block %d) are out of sync, try again later", hashStateProgress, trieProgress)
+	}
+	if blockNumber > trieProgress {
+		return nil, nil, fmt.Errorf("block %d is not processed yet, intermediate hashes are at block %d", blockNumber, trieProgress)
+	}
+	if trieProgress-blockNumber > uint64(api.maxGetProofRewindBlockCount) {
+		return nil, nil, fmt.Errorf("block %d is too old, proofs are available only for the last %d blocks (latest block %d)", blockNumber, api.maxGetProofRewindBlockCount, trieProgress)
+	}
+
+	stateTx, rl := tx, trie.NewRetainList(0)
+	if blockNumber < trieProgress {
+		if err = checkHistoryAvailable(tx, blockNumber); err != nil {
+			return nil, nil, err
+		}
+		batch := memdb.NewMemoryBatch(tx, api.dirs.Tmp)
+		defer batch.Rollback()
+		if rl, err = rewindHashedState(tx, batch, blockNumber, trieProgress, ctx.Done()); err != nil {
+			return nil, nil, err
+		}
+		stateTx = batch
+	}
+
+	result, err := buildProof(stateTx, rl, address, keys, header.Root, ctx.Done())
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, key := range storageKeys {
+		result.StorageProof[i].Key = key
+	}
+	return result, header, nil
+}
+
+// checkHistoryAvailable makes sure the change sets needed to rewind the state to blockNumber were not pruned
+func checkHistoryAvailable(tx kv.Tx, blockNumber uint64) error {
+	pruneMode, err := prune2.Get(tx)
+	if err != nil {
+		return err
+	}
+	if !pruneMode.History.Enabled() {
+		return nil
+	}
+	executionProgress, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if pruneTo := pruneMode.History.PruneTo(executionProgress); blockNumber+1 < pruneTo {
+		return fmt.Errorf("history pruned: state changes before block %d are not kept, proofs for block %d are not available", pruneTo, blockNumber)
+	}
+	return nil
+}
+
+// decodeStorageKey decodes a hex encoded storage key of at most 32 bytes, odd length keys such as "0x0" are accepted
+func decodeStorageKey(key string) (common.Hash, error) {
+	encoded := key
+	if len(encoded) >= 2 && (encoded[:2] == "0x" || encoded[:2] == "0X") && len(encoded)%2 == 1 {
+		encoded = "0x0" + encoded[2:]
+	}
+	b, err := hexutil.Decode(encoded)
+	if err != nil {
+		return common.Hash{}, rpc.NewInvalidParamsError(fmt.Sprintf("invalid storage key %q: %v", key, err))
+	}
+	if len(b) > length.Hash {
+		return common.Hash{}, rpc.NewInvalidParamsError(fmt.Sprintf("invalid storage key %q: longer than %d bytes", key, length.Hash))
+	}
+	return common.BytesToHash(b), nil
+}
+
+// maxGetProofRewindKeys limits how many changed accounts and storage slots are rewound in memory for a single proof
+const maxGetProofRewindKeys = 1_000_000
+
+// errRewindDone stops the change set walks once they pass the last block to rewind
+var errRewindDone = errors.New("rewind done")
+
+func errTooManyRewindKeys(blockNumber uint64) error {
+	return fmt.Errorf("too many state changes to rewind for block %d, more than %d accounts and storage slots were changed since", blockNumber, maxGetProofRewindKeys)
+}
+
+// rewindHashedState applies the change sets of blocks (blockNumber, trieProgress] backwards onto the overlay, so that
+// its hashed state matches the state at the end of blockNumber. The intermediate hashes are not rewound, instead
+// the returned list marks changed keys the same way the IntermediateHashes stage does on unwind, so the trie loader
+// knows which of the intermediate hashes are stale
+func rewindHashedState(tx kv.Tx, overlay kv.RwTx, blockNumber, trieProgress uint64, quit <-chan struct{}) (*trie.RetainList, error) {
+	rl := trie.NewRetainList(0)
+	seen := map[string]struct{}{}
+	var deletedAccounts [][]byte
+
+	// Change sets are walked in ascending order, so the first occurrence of a key holds its value at blockNumber
+	if err := historyv2.ForEach(tx, kv.AccountChangeSet, libcommon.EncodeTs(blockNumber+1), func(blockN uint64, k, v []byte) error {
+		if err := libcommon.Stopped(quit); err != nil {
+			return err
+		}
+		if blockN > trieProgress {
+			return errRewindDone
+		}
+		if _, ok := seen[string(k)]; ok {
+			return nil
+		}
+		if len(seen) >= maxGetProofRewindKeys {
+			return errTooManyRewindKeys(blockNumber)
+		}
+		seen[string(k)] = struct{}{}
+
+		addrHash, err := common.HashData(k)
+		if err != nil {
+			return err
+		}
+		current, err := tx.GetOne(kv.HashedAccounts, addrHash[:])
+		if err != nil {
+			return err
+		}
+		rl.AddKeyWithMarker(addrHash[:], len(current) == 0)
+
+		if len(current) > 0 {
+			var currentAccount accounts.Account
+			if err = currentAccount.DecodeForStorage(current); err != nil {
+				return err
+			}
+			if currentAccount.Incarnation > 0 {
+				if len(v) == 0 { // created after blockNumber
+					deletedAccounts = append(deletedAccounts, common.CopyBytes(addrHash[:]))
+				} else {
+					incarnation, err := accounts.DecodeIncarnationFromStorage(v)
+					if err != nil {
+						return err
+					}
+					if incarnation != currentAccount.Incarnation { // re-created after blockNumber
+						deletedAccounts = append(deletedAccounts, common.CopyBytes(addrHash[:]))
+					}
+				}
+			}
+		}
+
+		if len(v) == 0 {
+			return overlay.Delete(kv.HashedAccounts, addrHash[:])
+		}
+		if v, err = historyv2read.RestoreCodeHash(tx, k, v); err != nil {
+			return err
+		}
+		return overlay.Put(kv.HashedAccounts, addrHash[:], v)
+	}); err != nil && !errors.Is(err, errRewindDone) {
+		return nil, err
+	}
+
+	if err := historyv2.ForEach(tx, kv.StorageChangeSet, libcommon.EncodeTs(blockNumber+1), func(blockN uint64, k, v []byte) error {
+		if err := libcommon.Stopped(quit); err != nil {
+			return err
+		}
+		if blockN > trieProgress {
+			return errRewindDone
+		}
+		if _, ok := seen[string(k)]; ok {
+			return nil
+		}
+		if len(seen) >= maxGetProofRewindKeys {
+			return errTooManyRewindKeys(blockNumber)
+		}
+		seen[string(k)] = struct{}{}
+
+		addrHash, err := common.HashData(k[:length.Addr])
+		if err != nil {
+			return err
+		}
+		incarnation := binary.BigEndian.Uint64(k[length.Addr:])
+		locHash, err := common.HashData(k[length.Addr+length.Incarnation:])
+		if err != nil {
+			return err
+		}
+		newK := dbutils.GenerateCompositeStorageKey(addrHash, incarnation, locHash)
+		current, err := tx.GetOne(kv.HashedStorage, newK)
+		if err != nil {
+			return err
+		}
+		rl.AddKeyWithMarker(newK, len(current) == 0)
+
+		if len(v) == 0 {
+			return overlay.Delete(kv.HashedStorage, newK)
+		}
+		return overlay.Put(kv.HashedStorage, newK, v)
+	}); err != nil && !errors.Is(err, errRewindDone) {
+		return nil, err
+	}
+
+	// storage tries of accounts created after blockNumber are not valid at blockNumber
+	for _, addrHash := range deletedAccounts {
+		var trieKeys [][]byte
+		if err := overlay.ForPrefix(kv.TrieOfStorage, addrHash, func(k, _ []byte) error {
+			trieKeys = append(trieKeys, common.CopyBytes(k))
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		for _, k := range trieKeys {
+			if err := overlay.Delete(kv.TrieOfStorage, k); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rl, nil
+}
+
+// buildProof loads from the hashed state and intermediate hashes the part of the state trie which is needed
+// to prove the account and its storage keys, checks that it matches the expected state root and builds the proofs.
+// rl lists the keys whose intermediate hashes must not be used, the keys to prove are added to it
+func buildProof(tx kv.Tx, rl *trie.RetainList, address common.Address, storageKeys []common.Hash, root common.Hash, quit <-chan struct{}) (*ethapi2.AccountResult, error) {
+	addrHash, err := common.HashData(address[:])
+	if err != nil {
+		return nil, err
+	}
+	var incarnation uint64
+	enc, err := tx.GetOne(kv.HashedAccounts, addrHash[:])
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) > 0 {
+		var acc accounts.Account
+		if err = acc.DecodeForStorage(enc); err != nil {
+			return nil, err
+		}
+		incarnation = acc.Incarnation
+	}
+
+	proofRl := trie.NewRetainList(0)
+	rl.AddKey(addrHash[:])
+	proofRl.AddKey(addrHash[:])
+	storageTrieKeys := make([][]byte, len(storageKeys))
+	for i, key := range storageKeys {
+		keyHash, err := common.HashData(key[:])
+		if err != nil {
+			return nil, err
+		}
+		rl.AddKey(dbutils.GenerateCompositeStorageKey(addrHash, incarnation, keyHash))
+		proofRl.AddKey(dbutils.GenerateCompositeStorageKey(addrHash, incarnation, keyHash))
+		storageTrieKeys[i] = append(common.CopyBytes(addrHash[:]), keyHash[:]...)
+	}
+
+	loader := trie.NewFlatDBTrieLoader("eth_getProof")
+	if err = loader.Reset(rl, nil, nil, false); err != nil {
+		return nil, err
+	}
+	t, err := loader.CalcSubTrie(tx, proofRl, quit)
+	if err != nil {
+		return nil, err
+	}
+	if hash := t.Hash(); hash != root {
+		return nil, fmt.Errorf("state root mismatch: computed %x, expected %x", hash, root)
+	}
+
+	accountProof, err := t.Prove(addrHash[:], 0, false)
+	if err != nil {
+		return nil, err
+	}
+	result := &ethapi2.AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		CodeHash:     trie.EmptyCodeHash,
+		StorageHash:  trie.EmptyRoot,
+		StorageProof: make([]ethapi2.StorageResult, len(storageKeys)),
+	}
+	acc, ok := t.GetAccount(addrHash[:])
+	if !ok {
+		return nil, fmt.Errorf("account %x is not loaded into the trie", address)
+	}
+	if acc != nil {
+		result.Balance = (*hexutil.Big)(acc.Balance.ToBig())
+		result.Nonce = hexutil.Uint64(acc.Nonce)
+		result.CodeHash = acc.CodeHash
+		result.StorageHash = acc.Root
+	}
+
+	for i, key := range storageKeys {
+		proof, err := t.Prove(storageTrieKeys[i], 64, true)
+		if err != nil {
+			return nil, err
+		}
+		v, ok := t.Get(storageTrieKeys[i])
+		if !ok {
+			return nil, fmt.Errorf("storage key %x of account %x is not loaded into the trie", key, address)
+		}
+		result.StorageProof[i] = ethapi2.StorageResult{
+			Key:   key.Hex(),
+			Value: (*hexutil.Big)(new(big.Int).SetBytes(v)),
+			Proof: toHexSlice(proof),
+		}
+	}
+	return result, nil
+}
+
+func toHexSlice(b [][]byte) []string {
+	result := make([]string, len(b))
+	for i := range b {
+		result[i] = hexutil.Encode(b[i])
+	}
+	return result
+}
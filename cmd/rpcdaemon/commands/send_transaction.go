@@ -1,11 +1,13 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"math/big"
 
+	"github.com/holiman/uint256"
 	txPoolProto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
@@ -14,6 +16,8 @@ import (
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	ethapi2 "github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
 	"github.com/ledgerwatch/log/v3"
 )
 
@@ -81,9 +85,223 @@ func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutil.By
 	return txn.Hash(), nil
 }
 
+// SendTxArgs represents the arguments to submit a new transaction into the transaction pool.
+type SendTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to"`
+	Gas                  *hexutil.Uint64 `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                *hexutil.Uint64 `json:"nonce"`
+	// We accept "data" and "input" for backwards-compatibility reasons. "input" is the
+	// newer name and should be preferred by clients.
+	Data       *hexutil.Bytes    `json:"data"`
+	Input      *hexutil.Bytes    `json:"input"`
+	AccessList *types.AccessList `json:"accessList"`
+	ChainID    *hexutil.Big      `json:"chainId"`
+}
+
+// SignTransactionResult is the result of eth_signTransaction: the raw signed transaction and its fields.
+type SignTransactionResult struct {
+	Raw hexutil.Bytes   `json:"raw"`
+	Tx  *RPCTransaction `json:"tx"`
+}
+
 // SendTransaction implements eth_sendTransaction. Creates new message call transaction or a contract creation if the data field contains code.
-func (api *APIImpl) SendTransaction(_ context.Context, txObject interface{}) (common.Hash, error) {
-	return common.Hash{0}, fmt.Errorf(NotImplemented, "eth_sendTransaction")
+// The transaction is signed by the signer enabled with --rpc.signer.
+func (api *APIImpl) SendTransaction(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	signed, err := api.signTransaction(ctx, "eth_sendTransaction", args)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var buf bytes.Buffer
+	if err := signed.MarshalBinary(&buf); err != nil {
+		return common.Hash{}, err
+	}
+	return api.SendRawTransaction(ctx, buf.Bytes())
+}
+
+// SignTransaction implements eth_signTransaction. Signs the transaction without submitting it, with the signer enabled
+// with --rpc.signer.
+func (api *APIImpl) SignTransaction(ctx context.Context, args SendTxArgs) (*SignTransactionResult, error) {
+	signed, err := api.signTransaction(ctx, "eth_signTransaction", args)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := signed.MarshalBinary(&buf); err != nil {
+		return nil, err
+	}
+	return &SignTransactionResult{Raw: buf.Bytes(), Tx: newRPCTransaction(signed, common.Hash{}, 0, 0, nil)}, nil
+}
+
+// Sign implements eth_sign. Calculates an Ethereum specific signature with: sign(keccak256('\\x19Ethereum Signed Message:\\n' + len(message) + message))),
+// with the signer enabled with --rpc.signer.
+func (api *APIImpl) Sign(ctx context.Context, address common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	if api.signer == nil {
+		return nil, fmt.Errorf(SignerDisabled, "eth_sign")
+	}
+	return api.signer.SignText(ctx, address, data)
+}
+
+// Accounts implements eth_accounts. Returns the accounts of the signer enabled with --rpc.signer.
+func (api *APIImpl) Accounts(ctx context.Context) ([]common.Address, error) {
+	if api.signer == nil {
+		return []common.Address{}, fmt.Errorf(SignerDisabled, "eth_accounts")
+	}
+	return api.signer.Accounts(ctx)
+}
+
+// signTransaction fills in the missing fields of the transaction and signs it.
+func (api *APIImpl) signTransaction(ctx context.Context, method string, args SendTxArgs) (types.Transaction, error) {
+	if api.signer == nil {
+		return nil, fmt.Errorf(SignerDisabled, method)
+	}
+	if err := api.setTxDefaults(ctx, &args); err != nil {
+		return nil, err
+	}
+	txn, err := args.toTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return api.signer.SignTx(ctx, args.From, txn, args.ChainID.ToInt())
+}
+
+// setTxDefaults fills in the chain id, the nonce, the fees and the gas limit when they are not given.
+func (api *APIImpl) setTxDefaults(ctx context.Context, args *SendTxArgs) error {
+	if args.Data != nil && args.Input != nil && !bytes.Equal(*args.Data, *args.Input) {
+		return errors.New(`both "data" and "input" are set and not equal. Please use "input" to pass transaction call data`)
+	}
+	if args.Input == nil {
+		args.Input = args.Data
+	}
+	if args.GasPrice != nil && (args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil) {
+		return errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified")
+	}
+	if args.Value == nil {
+		args.Value = new(hexutil.Big)
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	cc, err := api.chainConfig(tx)
+	if err != nil {
+		return err
+	}
+	if args.ChainID == nil {
+		args.ChainID = (*hexutil.Big)(cc.ChainID)
+	} else if args.ChainID.ToInt().Cmp(cc.ChainID) != 0 {
+		return fmt.Errorf("invalid chain id, expected: %d got: %d", cc.ChainID, args.ChainID.ToInt())
+	}
+	head := rawdb.ReadCurrentHeader(tx)
+	tx.Rollback()
+
+	if args.Nonce == nil {
+		nonce, err := api.GetTransactionCount(ctx, args.From, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+		if err != nil {
+			return err
+		}
+		args.Nonce = nonce
+	}
+	if args.GasPrice == nil {
+		if head != nil && head.BaseFee != nil {
+			// Dynamic fee transaction, with room for the base fee to double.
+			if args.MaxPriorityFeePerGas == nil {
+				tip, err := api.MaxPriorityFeePerGas(ctx)
+				if err != nil {
+					return err
+				}
+				args.MaxPriorityFeePerGas = tip
+			}
+			if args.MaxFeePerGas == nil {
+				feeCap := new(big.Int).Add(args.MaxPriorityFeePerGas.ToInt(), new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+				args.MaxFeePerGas = (*hexutil.Big)(feeCap)
+			}
+			if args.MaxFeePerGas.ToInt().Cmp(args.MaxPriorityFeePerGas.ToInt()) < 0 {
+				return fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", args.MaxFeePerGas, args.MaxPriorityFeePerGas)
+			}
+		} else {
+			if args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil {
+				return errors.New("maxFeePerGas or maxPriorityFeePerGas specified but london is not active yet")
+			}
+			price, err := api.GasPrice(ctx)
+			if err != nil {
+				return err
+			}
+			args.GasPrice = price
+		}
+	}
+	if args.Gas == nil {
+		callArgs := ethapi2.CallArgs{
+			From:                 &args.From,
+			To:                   args.To,
+			GasPrice:             args.GasPrice,
+			MaxFeePerGas:         args.MaxFeePerGas,
+			MaxPriorityFeePerGas: args.MaxPriorityFeePerGas,
+			Value:                args.Value,
+			Data:                 args.Input,
+			AccessList:           args.AccessList,
+		}
+		gas, err := api.EstimateGas(ctx, &callArgs, nil)
+		if err != nil {
+			return err
+		}
+		args.Gas = &gas
+	}
+	return nil
+}
+
+// toTransaction builds the unsigned transaction, its type follows from the fee fields and the access list.
+func (args *SendTxArgs) toTransaction() (types.Transaction, error) {
+	var data []byte
+	if args.Input != nil {
+		data = *args.Input
+	}
+	value, overflow := uint256.FromBig(args.Value.ToInt())
+	if overflow {
+		return nil, fmt.Errorf("value %v overflows uint256", args.Value)
+	}
+	chainID, overflow := uint256.FromBig(args.ChainID.ToInt())
+	if overflow {
+		return nil, fmt.Errorf("chain id %v overflows uint256", args.ChainID)
+	}
+	commonTx := types.CommonTx{
+		Nonce: uint64(*args.Nonce),
+		Gas:   uint64(*args.Gas),
+		To:    args.To,
+		Value: value,
+		Data:  data,
+	}
+	if args.MaxFeePerGas != nil {
+		tip, overflow := uint256.FromBig(args.MaxPriorityFeePerGas.ToInt())
+		if overflow {
+			return nil, fmt.Errorf("maxPriorityFeePerGas %v overflows uint256", args.MaxPriorityFeePerGas)
+		}
+		feeCap, overflow := uint256.FromBig(args.MaxFeePerGas.ToInt())
+		if overflow {
+			return nil, fmt.Errorf("maxFeePerGas %v overflows uint256", args.MaxFeePerGas)
+		}
+		commonTx.ChainID = chainID
+		txn := &types.DynamicFeeTransaction{CommonTx: commonTx, Tip: tip, FeeCap: feeCap}
+		if args.AccessList != nil {
+			txn.AccessList = *args.AccessList
+		}
+		return txn, nil
+	}
+	gasPrice, overflow := uint256.FromBig(args.GasPrice.ToInt())
+	if overflow {
+		return nil, fmt.Errorf("gasPrice %v overflows uint256", args.GasPrice)
+	}
+	legacy := types.LegacyTx{CommonTx: commonTx, GasPrice: gasPrice}
+	if args.AccessList != nil {
+		return &types.AccessListTx{LegacyTx: legacy, ChainID: chainID, AccessList: *args.AccessList}, nil
+	}
+	return &legacy, nil
 }
 
 // checkTxFee is an internal function used to check whether the fee of
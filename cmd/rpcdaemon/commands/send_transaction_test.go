@@ -2,6 +2,7 @@ package commands_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"math/big"
 	"testing"
@@ -10,12 +11,16 @@ import (
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	accounts2 "github.com/ledgerwatch/erigon/accounts"
+	"github.com/ledgerwatch/erigon/accounts/keystore"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/commands"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/common/u256"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
@@ -73,7 +78,7 @@ func TestSendRawTransaction(t *testing.T) {
 	ff := rpchelper.New(ctx, nil, txPool, txpool.NewMiningClient(conn), func() {})
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
-	api := commands.NewEthAPI(commands.NewBaseApi(ff, stateCache, br, nil, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, txPool, nil, 5000000)
+	api := commands.NewEthAPI(commands.NewBaseApi(ff, stateCache, br, nil, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount), m.DB, nil, txPool, nil, 5000000, nil)
 
 	buf := bytes.NewBuffer(nil)
 	err = txn.MarshalBinary(buf)
@@ -100,6 +105,68 @@ func TestSendRawTransaction(t *testing.T) {
 	//require.Equal(eth.ToProto[m.MultiClient.Protocol()][eth.NewPooledTransactionHashesMsg], sent.Id)
 }
 
+func TestSignTransaction(t *testing.T) {
+	m, require := stages.Mock(t), require.New(t)
+	ctx := context.Background()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots)
+	base := commands.NewBaseApi(nil, stateCache, br, nil, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, rpccfg.DefaultMaxGetProofRewindBlockCount)
+	from := crypto.PubkeyToAddress(m.Key.PublicKey)
+	nonce, gas := hexutil.Uint64(3), hexutil.Uint64(params.TxGas)
+	args := commands.SendTxArgs{
+		From:     from,
+		To:       &common.Address{1},
+		Gas:      &gas,
+		GasPrice: (*hexutil.Big)(big.NewInt(params.GWei)),
+		Value:    (*hexutil.Big)(big.NewInt(1234)),
+		Nonce:    &nonce,
+	}
+
+	// Signing is off without a signer.
+	api := commands.NewEthAPI(base, m.DB, nil, nil, nil, 5000000, nil)
+	_, err := api.SignTransaction(ctx, args)
+	require.Error(err)
+	_, err = api.Accounts(ctx)
+	require.Error(err)
+
+	api = commands.NewEthAPI(base, m.DB, nil, nil, nil, 5000000, keystore.NewKeyStore(m.Key))
+	accounts, err := api.Accounts(ctx)
+	require.NoError(err)
+	require.Equal([]common.Address{from}, accounts)
+
+	res, err := api.SignTransaction(ctx, args)
+	require.NoError(err)
+	txn, err := types.DecodeTransaction(res.Raw)
+	require.NoError(err)
+	sender, err := txn.Sender(*types.LatestSignerForChainID(m.ChainConfig.ChainID))
+	require.NoError(err)
+	require.Equal(from, sender)
+	require.Equal(from, res.Tx.From)
+	require.Equal(uint64(3), txn.GetNonce())
+	require.Equal(uint64(1234), txn.GetValue().Uint64())
+	require.True(txn.Protected())
+
+	// Fee fields select a dynamic fee transaction.
+	args.GasPrice = nil
+	args.MaxFeePerGas = (*hexutil.Big)(big.NewInt(2 * params.GWei))
+	args.MaxPriorityFeePerGas = (*hexutil.Big)(big.NewInt(params.GWei))
+	res, err = api.SignTransaction(ctx, args)
+	require.NoError(err)
+	require.Equal(hexutil.Uint64(types.DynamicFeeTxType), res.Tx.Type)
+
+	// Another account of the keystore cannot be used.
+	args.From = common.Address{2}
+	_, err = api.SignTransaction(ctx, args)
+	require.Error(err)
+
+	sig, err := api.Sign(ctx, from, []byte("hello"))
+	require.NoError(err)
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(accounts2.TextHash([]byte("hello")), sig)
+	require.NoError(err)
+	require.Equal(from, crypto.PubkeyToAddress(*pub))
+}
+
 func transaction(nonce uint64, gaslimit uint64, key *ecdsa.PrivateKey) types.Transaction {
 	return pricedTransaction(nonce, gaslimit, u256.Num1, key)
 }
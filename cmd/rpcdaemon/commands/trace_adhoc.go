@@ -1262,8 +1262,52 @@ func (api *TraceAPIImpl) doCallMany(ctx context.Context, dbtx kv.Tx, msgs []type
 	return results, nil
 }
 
-// RawTransaction implements trace_rawTransaction.
-func (api *TraceAPIImpl) RawTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error) {
-	var stub []interface{}
-	return stub, fmt.Errorf(NotImplemented, "trace_rawTransaction")
+// RawTransaction implements trace_rawTransaction. Traces a signed, but not mined transaction on top of the latest state
+// (or the state after the given block), for example to check what it does before it is broadcasted.
+func (api *TraceAPIImpl) RawTransaction(ctx context.Context, encodedTx hexutil.Bytes, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash) (*TraceCallResult, error) {
+	txn, err := types.DecodeTransaction(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := api.kv.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if blockNrOrHash == nil {
+		var num = rpc.LatestBlockNumber
+		blockNrOrHash = &rpc.BlockNumberOrHash{BlockNumber: &num}
+	}
+	blockNumber, hash, _, err := rpchelper.GetBlockNumber(*blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	header, err := api._blockReader.Header(ctx, tx, hash, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+	}
+
+	// The sender is recovered the same way as it would be if the transaction was included into the next block
+	msg, err := txn.AsMessage(*types.MakeSigner(chainConfig, blockNumber+1), header.BaseFee, chainConfig.Rules(blockNumber+1, header.Time))
+	if err != nil {
+		return nil, fmt.Errorf("convert tx into msg: %w", err)
+	}
+	txHash := txn.Hash()
+	callParams := []TraceCallParam{{txHash: &txHash, traceTypes: traceTypes}}
+
+	traces, err := api.doCallMany(ctx, tx, []types.Message{msg}, callParams, blockNrOrHash, nil, false /* gasBailout */, -1 /* all tx indices */)
+	if err != nil {
+		return nil, err
+	}
+	return traces[0], nil
 }
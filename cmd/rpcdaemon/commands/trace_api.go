@@ -19,7 +19,7 @@ type TraceAPI interface {
 	ReplayTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) (*TraceCallResult, error)
 	Call(ctx context.Context, call TraceCallParam, types []string, blockNr *rpc.BlockNumberOrHash) (*TraceCallResult, error)
 	CallMany(ctx context.Context, calls json.RawMessage, blockNr *rpc.BlockNumberOrHash) ([]*TraceCallResult, error)
-	RawTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error)
+	RawTransaction(ctx context.Context, encodedTx hexutil.Bytes, traceTypes []string, blockNr *rpc.BlockNumberOrHash) (*TraceCallResult, error)
 
 	// Filtering (see ./trace_filtering.go)
 	Transaction(ctx context.Context, txHash common.Hash) (ParityTraces, error)
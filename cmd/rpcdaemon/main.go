@@ -7,6 +7,7 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/commands"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/logging"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/spf13/cobra"
@@ -30,8 +31,22 @@ func main() {
 
 		// TODO: Replace with correct consensus Engine
 		engine := ethash.NewFaker()
-		apiList := commands.APIList(db, borDb, backend, txPool, mining, ff, stateCache, blockReader, agg, *cfg, engine)
-		if err := cli.StartRpcServer(ctx, *cfg, apiList, nil); err != nil {
+		signer, err := cli.Signer(ctx, *cfg)
+		if err != nil {
+			log.Error("Could not create signer", "err", err)
+			return nil
+		}
+		apiList := commands.APIList(db, borDb, backend, txPool, mining, ff, stateCache, blockReader, agg, *cfg, engine, signer)
+		resultCache, err := cli.ResultCache(ctx, *cfg, db, blockReader, ff)
+		if err != nil {
+			log.Error("Could not open the result cache", "err", err)
+			return nil
+		}
+		var authApiList []rpc.API
+		if cfg.AuthRpcPort != 0 {
+			authApiList = commands.AuthAPIList(db, backend, txPool, mining, ff, stateCache, blockReader, agg, *cfg, engine)
+		}
+		if err := cli.StartRpcServer(ctx, *cfg, apiList, authApiList, resultCache); err != nil {
 			log.Error(err.Error())
 			return nil
 		}
@@ -0,0 +1,379 @@
+// Package resultcache caches the results of rpc calls about finalized blocks, which never change:
+// blocks, receipts and traces of finalized blocks are served from the cache instead of being read
+// (or re-executed) again.
+package resultcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/services"
+)
+
+const (
+	FinalityFinalized = "finalized"
+	FinalitySafe      = "safe"
+
+	BackendMemory = "memory"
+	BackendMdbx   = "mdbx"
+)
+
+var (
+	cacheHits        = metrics.GetOrCreateCounter(`rpc_result_cache{result="hit"}`)
+	cacheMisses      = metrics.GetOrCreateCounter(`rpc_result_cache{result="miss"}`)
+	cachePuts        = metrics.GetOrCreateCounter(`rpc_result_cache_puts`)
+	cacheInvalidated = metrics.GetOrCreateCounter(`rpc_result_cache_invalidated`)
+	cacheSize        = metrics.GetOrCreateCounter(`rpc_result_cache_size_bytes`)
+)
+
+// Backend stores the cached results. Keys are 32 bytes long, every entry belongs to the block it is about.
+type Backend interface {
+	// Get returns nil when the key isn't cached
+	Get(key []byte) ([]byte, error)
+	// Put stores the entry, evicting the others as needed to stay under the size limit
+	Put(key []byte, blockNum uint64, value []byte) error
+	Delete(key []byte) error
+	// DeleteFrom removes the entries of blockNum and the later blocks
+	DeleteFrom(blockNum uint64) (deleted int, err error)
+	// Size is the number of bytes taken by the keys and the values
+	Size() uint64
+	Close()
+}
+
+// Config of the cache, the zero value (no backend) disables it.
+type Config struct {
+	Backend  string            // BackendMemory or BackendMdbx
+	Size     datasize.ByteSize // limit of the cached results
+	Finality string            // FinalityFinalized (default) or FinalitySafe, only the results of the blocks up to it are cached
+	Dir      string            // directory of the mdbx backend
+}
+
+// blockOfCall returns the block the result of the call is about, ok is false when it can't be told
+// or the result may change even for a finalized block (e.g. a "latest" block parameter).
+type blockOfCall func(ctx context.Context, tx kv.Tx, txnReader services.TxnReader, params []json.RawMessage, result json.RawMessage) (blockNum uint64, blockHash common.Hash, ok bool, err error)
+
+// cachedMethods are the methods whose results are cached, along with the way to find the block of the result.
+var cachedMethods = map[string]blockOfCall{
+	"eth_getBlockByNumber":                    blockOfParam(0),
+	"eth_getBlockByHash":                      blockOfParam(0),
+	"eth_getBlockReceipts":                    blockOfParam(0),
+	"eth_getTransactionByBlockNumberAndIndex": blockOfParam(0),
+	"eth_getTransactionByBlockHashAndIndex":   blockOfParam(0),
+	"eth_getTransactionByHash":                blockOfResult,
+	"eth_getTransactionReceipt":               blockOfResult,
+	"erigon_getBlockReceiptsByBlockHash":      blockOfParam(0),
+	"trace_block":                             blockOfParam(0),
+	"trace_replayBlockTransactions":           blockOfParam(0),
+	"trace_transaction":                       blockOfTxnParam(0),
+	"debug_traceTransaction":                  blockOfTxnParam(0),
+	"debug_traceBlockByNumber":                blockOfParam(0),
+	"debug_traceBlockByHash":                  blockOfParam(0),
+}
+
+// Cache is the rpc.ResultCache of the results about finalized blocks. Every entry remembers the hash of
+// its block, entries of blocks which aren't canonical anymore are dropped on reorgs and when they are read.
+type Cache struct {
+	db        kv.RoDB
+	txnReader services.TxnReader
+	backend   Backend
+	finality  string
+	maxEntry  uint64
+	methods   map[string]blockOfCall
+
+	lock sync.Mutex
+	head uint64 // number of the latest header seen by OnNewHeader
+}
+
+var _ rpc.ResultCache = (*Cache)(nil)
+
+// New opens the cache, the result is nil when the cache is disabled.
+func New(cfg Config, db kv.RoDB, txnReader services.TxnReader) (*Cache, error) {
+	var backend Backend
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case BackendMemory:
+		backend = NewMemoryBackend(uint64(cfg.Size))
+	case BackendMdbx:
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("the %s result cache needs a directory", BackendMdbx)
+		}
+		var err error
+		if backend, err = OpenMdbxBackend(cfg.Dir, uint64(cfg.Size)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown result cache backend %q, expected %s or %s", cfg.Backend, BackendMemory, BackendMdbx)
+	}
+	c, err := NewWithBackend(backend, cfg.Finality, uint64(cfg.Size), db, txnReader)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func NewWithBackend(backend Backend, finality string, size uint64, db kv.RoDB, txnReader services.TxnReader) (*Cache, error) {
+	switch finality {
+	case "":
+		finality = FinalityFinalized
+	case FinalityFinalized, FinalitySafe:
+	default:
+		return nil, fmt.Errorf("unknown result cache finality %q, expected %s or %s", finality, FinalityFinalized, FinalitySafe)
+	}
+	cacheSize.Set(backend.Size())
+	return &Cache{
+		db:        db,
+		txnReader: txnReader,
+		backend:   backend,
+		finality:  finality,
+		maxEntry:  size / 16, // a few huge traces must not evict everything else
+		methods:   cachedMethods,
+	}, nil
+}
+
+func (c *Cache) Close() {
+	c.backend.Close()
+}
+
+func (c *Cache) Cacheable(method string) bool {
+	_, ok := c.methods[method]
+	return ok
+}
+
+// key of the call, params are compacted so the formatting of the request doesn't matter
+func cacheKey(method string, params json.RawMessage) []byte {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, params); err != nil {
+		compact.Reset()
+		compact.Write(params)
+	}
+	return crypto.Keccak256([]byte(method), []byte{0}, compact.Bytes())
+}
+
+func (c *Cache) Get(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, bool) {
+	key := cacheKey(method, params)
+	v, err := c.backend.Get(key)
+	if err != nil {
+		log.Warn("[rpc] result cache read failed", "method", method, "err", err)
+		return nil, false
+	}
+	if len(v) < 8+common.HashLength {
+		cacheMisses.Inc()
+		return nil, false
+	}
+	blockNum, blockHash := binary.BigEndian.Uint64(v), common.BytesToHash(v[8:8+common.HashLength])
+	var canonical common.Hash
+	if err = c.db.View(ctx, func(tx kv.Tx) error {
+		canonical, err = rawdb.ReadCanonicalHash(tx, blockNum)
+		return err
+	}); err != nil {
+		return nil, false
+	}
+	if canonical != blockHash {
+		// the block was reorged away before OnNewHeader saw it
+		if err := c.backend.Delete(key); err != nil {
+			log.Warn("[rpc] result cache delete failed", "err", err)
+		}
+		cacheInvalidated.Inc()
+		cacheMisses.Inc()
+		cacheSize.Set(c.backend.Size())
+		return nil, false
+	}
+	cacheHits.Inc()
+	return v[8+common.HashLength:], true
+}
+
+// Admit tells if the result of the call will be kept before it is made, which needs the block of the call to be
+// told by its params and to be final. The results whose block is only found in them (blockOfResult) aren't admitted.
+func (c *Cache) Admit(ctx context.Context, method string, params json.RawMessage) (uint64, bool) {
+	_, _, final := c.finalBlockOf(ctx, method, params, nil)
+	return c.maxEntry, final
+}
+
+func (c *Cache) Put(ctx context.Context, method string, params json.RawMessage, result json.RawMessage) {
+	if uint64(len(result)) > c.maxEntry {
+		return
+	}
+	blockNum, blockHash, final := c.finalBlockOf(ctx, method, params, result)
+	if !final {
+		return
+	}
+	v := make([]byte, 8+common.HashLength+len(result))
+	binary.BigEndian.PutUint64(v, blockNum)
+	copy(v[8:], blockHash[:])
+	copy(v[8+common.HashLength:], result)
+	if err := c.backend.Put(cacheKey(method, params), blockNum, v); err != nil {
+		log.Warn("[rpc] result cache write failed", "method", method, "err", err)
+		return
+	}
+	cachePuts.Inc()
+	cacheSize.Set(c.backend.Size())
+}
+
+// finalBlockOf returns the block of the call, final is false when it isn't final or can't be told
+func (c *Cache) finalBlockOf(ctx context.Context, method string, params json.RawMessage, result json.RawMessage) (blockNum uint64, blockHash common.Hash, final bool) {
+	blockOf, ok := c.methods[method]
+	if !ok {
+		return 0, common.Hash{}, false
+	}
+	var args []json.RawMessage
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &args); err != nil {
+			return 0, common.Hash{}, false
+		}
+	}
+	err := c.db.View(ctx, func(tx kv.Tx) (err error) {
+		var known bool
+		if blockNum, blockHash, known, err = blockOf(ctx, tx, c.txnReader, args, result); err != nil || !known {
+			return err
+		}
+		final, err = c.isFinal(tx, blockNum, blockHash)
+		return err
+	})
+	if err != nil {
+		log.Debug("[rpc] result cache: can't find the block of the result", "method", method, "err", err)
+		return 0, common.Hash{}, false
+	}
+	return blockNum, blockHash, final
+}
+
+// isFinal tells if the block is canonical and not later than the finality point
+func (c *Cache) isFinal(tx kv.Tx, blockNum uint64, blockHash common.Hash) (bool, error) {
+	var final uint64
+	var err error
+	if c.finality == FinalitySafe {
+		final, err = rpchelper.GetSafeBlockNumber(tx)
+	} else {
+		final, err = rpchelper.GetFinalizedBlockNumber(tx)
+	}
+	if errors.Is(err, rpchelper.UnknownBlockError) {
+		return false, nil // nothing is final before the merge
+	}
+	if err != nil || blockNum > final {
+		return false, err
+	}
+	canonical, err := rawdb.ReadCanonicalHash(tx, blockNum)
+	if err != nil {
+		return false, err
+	}
+	return canonical == blockHash, nil
+}
+
+// OnNewHeader drops the entries of the blocks replaced by a reorg (or an unwind): a header which isn't
+// higher than the previous one means the chain was rewound to its parent.
+func (c *Cache) OnNewHeader(number uint64) {
+	c.lock.Lock()
+	prev := c.head
+	c.head = number
+	c.lock.Unlock()
+	if number > prev {
+		return
+	}
+	deleted, err := c.backend.DeleteFrom(number)
+	if err != nil {
+		log.Warn("[rpc] result cache invalidation failed", "from", number, "err", err)
+		return
+	}
+	if deleted > 0 {
+		log.Info("[rpc] result cache invalidated by reorg", "from", number, "entries", deleted)
+		cacheInvalidated.Add(deleted)
+		cacheSize.Set(c.backend.Size())
+	}
+}
+
+// Run invalidates the cache on reorgs until ctx is done
+func (c *Cache) Run(ctx context.Context, ff *rpchelper.Filters) {
+	headers, id := ff.SubscribeNewHeads(32)
+	defer ff.UnsubscribeHeads(id)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case h, ok := <-headers:
+			if !ok {
+				return
+			}
+			c.OnNewHeader(h.Number.Uint64())
+		}
+	}
+}
+
+// blockOfParam finds the block by a block number, block hash or BlockNumberOrHash param, tags are not cacheable.
+func blockOfParam(i int) blockOfCall {
+	return func(ctx context.Context, tx kv.Tx, txnReader services.TxnReader, params []json.RawMessage, result json.RawMessage) (uint64, common.Hash, bool, error) {
+		if len(params) <= i {
+			return 0, common.Hash{}, false, nil
+		}
+		var block rpc.BlockNumberOrHash
+		if err := json.Unmarshal(params[i], &block); err != nil {
+			return 0, common.Hash{}, false, nil
+		}
+		if hash, ok := block.Hash(); ok {
+			number := rawdb.ReadHeaderNumber(tx, hash)
+			if number == nil {
+				return 0, common.Hash{}, false, nil
+			}
+			return *number, hash, true, nil
+		}
+		number, ok := block.Number()
+		if !ok || number < 0 {
+			return 0, common.Hash{}, false, nil
+		}
+		hash, err := rawdb.ReadCanonicalHash(tx, uint64(number))
+		if err != nil || hash == (common.Hash{}) {
+			return 0, common.Hash{}, false, err
+		}
+		return uint64(number), hash, true, nil
+	}
+}
+
+// blockOfTxnParam finds the block of a transaction hash param
+func blockOfTxnParam(i int) blockOfCall {
+	return func(ctx context.Context, tx kv.Tx, txnReader services.TxnReader, params []json.RawMessage, result json.RawMessage) (uint64, common.Hash, bool, error) {
+		if len(params) <= i {
+			return 0, common.Hash{}, false, nil
+		}
+		var txnHash common.Hash
+		if err := json.Unmarshal(params[i], &txnHash); err != nil {
+			return 0, common.Hash{}, false, nil
+		}
+		blockNum, ok, err := txnReader.TxnLookup(ctx, tx, txnHash)
+		if err != nil || !ok {
+			return 0, common.Hash{}, false, err
+		}
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil || hash == (common.Hash{}) {
+			return 0, common.Hash{}, false, err
+		}
+		return blockNum, hash, true, nil
+	}
+}
+
+// blockOfResult finds the block by the blockNumber and blockHash fields of transactions and receipts
+func blockOfResult(ctx context.Context, tx kv.Tx, txnReader services.TxnReader, params []json.RawMessage, result json.RawMessage) (uint64, common.Hash, bool, error) {
+	var fields struct {
+		BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+		BlockHash   *common.Hash    `json:"blockHash"`
+	}
+	if err := json.Unmarshal(result, &fields); err != nil || fields.BlockNumber == nil || fields.BlockHash == nil {
+		return 0, common.Hash{}, false, nil
+	}
+	return uint64(*fields.BlockNumber), *fields.BlockHash, true, nil
+}
@@ -0,0 +1,191 @@
+package resultcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/turbo/services"
+)
+
+type txnLookup struct {
+	services.TxnReader
+	blocks map[common.Hash]uint64
+}
+
+func (l txnLookup) TxnLookup(ctx context.Context, tx kv.Getter, txnHash common.Hash) (uint64, bool, error) {
+	n, ok := l.blocks[txnHash]
+	return n, ok, nil
+}
+
+func blockHash(n uint64, fork byte) common.Hash {
+	return common.Hash{fork, byte(n)}
+}
+
+// writeChain makes blocks 0..10 canonical, block 5 is finalized and 7 is safe
+func writeChain(t *testing.T, db kv.RwDB) {
+	t.Helper()
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for n := uint64(0); n <= 10; n++ {
+			if err := rawdb.WriteCanonicalHash(tx, blockHash(n, 0), n); err != nil {
+				return err
+			}
+			if err := rawdb.WriteHeaderNumber(tx, blockHash(n, 0), n); err != nil {
+				return err
+			}
+		}
+		rawdb.WriteForkchoiceFinalized(tx, blockHash(5, 0))
+		rawdb.WriteForkchoiceSafe(tx, blockHash(7, 0))
+		return nil
+	}))
+}
+
+func TestCache(t *testing.T) {
+	backends := map[string]func(t *testing.T, limit uint64) Backend{
+		BackendMemory: func(t *testing.T, limit uint64) Backend { return NewMemoryBackend(limit) },
+		BackendMdbx: func(t *testing.T, limit uint64) Backend {
+			b, err := OpenMdbxBackend(filepath.Join(t.TempDir(), "rpccache"), limit)
+			require.NoError(t, err)
+			return b
+		},
+	}
+	for name, newBackend := range backends {
+		newBackend := newBackend
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := memdb.NewTestDB(t)
+			writeChain(t, db)
+			txns := txnLookup{blocks: map[common.Hash]uint64{{1}: 4, {2}: 8}}
+			backend := newBackend(t, 1<<20)
+			defer backend.Close()
+			c, err := NewWithBackend(backend, "", 1<<20, db, txns)
+			require.NoError(t, err)
+
+			require.True(t, c.Cacheable("trace_block"))
+			require.False(t, c.Cacheable("eth_blockNumber"))
+
+			put := func(method, params, result string) {
+				c.Put(ctx, method, json.RawMessage(params), json.RawMessage(result))
+			}
+			get := func(method, params string) string {
+				result, ok := c.Get(ctx, method, json.RawMessage(params))
+				if !ok {
+					return ""
+				}
+				return string(result)
+			}
+
+			// finalized blocks are cached, the formatting of the params doesn't matter
+			put("eth_getBlockByNumber", `["0x3",false]`, `{"number":"0x3"}`)
+			require.Equal(t, `{"number":"0x3"}`, get("eth_getBlockByNumber", `[ "0x3", false ]`))
+			require.Equal(t, "", get("eth_getBlockByNumber", `["0x3",true]`))
+			put("eth_getBlockByHash", fmt.Sprintf(`["%s",false]`, blockHash(5, 0).Hex()), `{"number":"0x5"}`)
+			require.Equal(t, `{"number":"0x5"}`, get("eth_getBlockByHash", fmt.Sprintf(`["%s",false]`, blockHash(5, 0).Hex())))
+			// later blocks and tags are not
+			put("eth_getBlockByNumber", `["0x6",false]`, `{"number":"0x6"}`)
+			require.Equal(t, "", get("eth_getBlockByNumber", `["0x6",false]`))
+			put("eth_getBlockByNumber", `["finalized",false]`, `{"number":"0x5"}`)
+			require.Equal(t, "", get("eth_getBlockByNumber", `["finalized",false]`))
+			// neither are blocks which aren't canonical
+			put("eth_getBlockByHash", fmt.Sprintf(`["%s",false]`, blockHash(2, 1).Hex()), `{"number":"0x2"}`)
+			require.Equal(t, "", get("eth_getBlockByHash", fmt.Sprintf(`["%s",false]`, blockHash(2, 1).Hex())))
+
+			// the block is found by the transaction
+			put("debug_traceTransaction", fmt.Sprintf(`["%s"]`, common.Hash{1}.Hex()), `{"gas":1}`)
+			require.Equal(t, `{"gas":1}`, get("debug_traceTransaction", fmt.Sprintf(`["%s"]`, common.Hash{1}.Hex())))
+			put("debug_traceTransaction", fmt.Sprintf(`["%s"]`, common.Hash{2}.Hex()), `{"gas":2}`)
+			require.Equal(t, "", get("debug_traceTransaction", fmt.Sprintf(`["%s"]`, common.Hash{2}.Hex())))
+			// or by the result
+			receipt := fmt.Sprintf(`{"blockNumber":"0x2","blockHash":"%s"}`, blockHash(2, 0).Hex())
+			put("eth_getTransactionReceipt", `["0x01"]`, receipt)
+			require.Equal(t, receipt, get("eth_getTransactionReceipt", `["0x01"]`))
+			put("eth_getTransactionReceipt", `["0x02"]`, `null`)
+			require.Equal(t, "", get("eth_getTransactionReceipt", `["0x02"]`))
+
+			// a reorg down to block 4 drops the entries of the blocks from 4
+			c.OnNewHeader(10)
+			c.OnNewHeader(4)
+			require.Equal(t, "", get("debug_traceTransaction", fmt.Sprintf(`["%s"]`, common.Hash{1}.Hex())))
+			require.Equal(t, "", get("eth_getBlockByHash", fmt.Sprintf(`["%s",false]`, blockHash(5, 0).Hex())))
+			require.Equal(t, `{"number":"0x3"}`, get("eth_getBlockByNumber", `["0x3",false]`))
+
+			// the entries of the blocks which aren't canonical anymore are dropped when they are read
+			require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+				return rawdb.WriteCanonicalHash(tx, blockHash(3, 1), 3)
+			}))
+			require.Equal(t, "", get("eth_getBlockByNumber", `["0x3",false]`))
+			require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+				return rawdb.WriteCanonicalHash(tx, blockHash(3, 0), 3)
+			}))
+			require.Equal(t, "", get("eth_getBlockByNumber", `["0x3",false]`))
+			require.Equal(t, receipt, get("eth_getTransactionReceipt", `["0x01"]`))
+		})
+	}
+}
+
+func TestCacheSafeFinality(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+	writeChain(t, db)
+	c, err := NewWithBackend(NewMemoryBackend(1<<20), FinalitySafe, 1<<20, db, nil)
+	require.NoError(t, err)
+	c.Put(ctx, "trace_block", json.RawMessage(`["0x7"]`), json.RawMessage(`[]`))
+	_, ok := c.Get(ctx, "trace_block", json.RawMessage(`["0x7"]`))
+	require.True(t, ok)
+	c.Put(ctx, "trace_block", json.RawMessage(`["0x8"]`), json.RawMessage(`[]`))
+	_, ok = c.Get(ctx, "trace_block", json.RawMessage(`["0x8"]`))
+	require.False(t, ok)
+
+	// streamed results are only collected for the final blocks
+	maxSize, ok := c.Admit(ctx, "trace_block", json.RawMessage(`["0x7"]`))
+	require.True(t, ok)
+	require.Equal(t, uint64(1<<20/16), maxSize)
+	_, ok = c.Admit(ctx, "trace_block", json.RawMessage(`["0x8"]`))
+	require.False(t, ok)
+	_, ok = c.Admit(ctx, "trace_block", json.RawMessage(`["latest"]`))
+	require.False(t, ok)
+	_, ok = c.Admit(ctx, "eth_getTransactionReceipt", json.RawMessage(`["0x01"]`))
+	require.False(t, ok)
+
+	_, err = NewWithBackend(NewMemoryBackend(1<<20), "latest", 1<<20, db, nil)
+	require.Error(t, err)
+}
+
+func TestBackendLimit(t *testing.T) {
+	mdbxBackend, err := OpenMdbxBackend(filepath.Join(t.TempDir(), "rpccache"), 3*40)
+	require.NoError(t, err)
+	defer mdbxBackend.Close()
+	for _, b := range []Backend{NewMemoryBackend(3 * 40), mdbxBackend} {
+		key := func(i byte) []byte { return common.Hash{i}.Bytes() }
+		value := make([]byte, 8)
+		for i := byte(1); i <= 3; i++ {
+			require.NoError(t, b.Put(key(i), uint64(i), value))
+		}
+		require.Equal(t, uint64(3*40), b.Size())
+		require.NoError(t, b.Put(key(4), 4, value))
+		require.Equal(t, uint64(3*40), b.Size())
+		v, err := b.Get(key(1))
+		require.NoError(t, err)
+		require.Nil(t, v, "%T", b)
+		for i := byte(2); i <= 4; i++ {
+			v, err := b.Get(key(i))
+			require.NoError(t, err)
+			require.Equal(t, value, v)
+		}
+
+		deleted, err := b.DeleteFrom(3)
+		require.NoError(t, err)
+		require.Equal(t, 2, deleted)
+		require.Equal(t, uint64(40), b.Size())
+		require.NoError(t, b.Delete(key(2)))
+		require.Equal(t, uint64(0), b.Size())
+	}
+}
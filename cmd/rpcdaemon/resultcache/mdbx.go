@@ -0,0 +1,232 @@
+package resultcache
+
+import (
+	"context"
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+	mdbx1 "github.com/torquem-ch/mdbx-go/mdbx"
+)
+
+const (
+	resultsTable       = "RpcResults"       // key -> seq + block number + value
+	resultsOrderTable  = "RpcResultsOrder"  // seq -> key, entries are evicted in the order they were added
+	resultsBlocksTable = "RpcResultsBlocks" // block number + key -> nil, to invalidate the entries of the blocks
+	resultsMetaTable   = "RpcResultsMeta"   // sizeKey -> size, seqKey -> next seq
+)
+
+// resultsDBLabel keeps the cache out of the chaindata metrics, erigon-lib has no label for it
+const resultsDBLabel kv.Label = 255
+
+var (
+	sizeKey = []byte("size")
+	seqKey  = []byte("seq")
+)
+
+func resultsTablesCfg(_ kv.TableCfg) kv.TableCfg {
+	return kv.TableCfg{
+		resultsTable:       {},
+		resultsOrderTable:  {},
+		resultsBlocksTable: {},
+		resultsMetaTable:   {},
+	}
+}
+
+// MdbxBackend keeps the results on disk, so they survive restarts. Entries are evicted in the order they were added.
+type MdbxBackend struct {
+	db    kv.RwDB
+	limit uint64
+	size  uint64 // mirror of sizeKey, atomic
+}
+
+func OpenMdbxBackend(path string, limit uint64) (*MdbxBackend, error) {
+	db, err := mdbx.NewMDBX(log.New()).
+		Path(path).
+		Label(resultsDBLabel).
+		WithTableCfg(resultsTablesCfg).
+		MapSize(datasize.ByteSize(2*limit) + 256*datasize.MB).
+		GrowthStep(16 * datasize.MB).
+		Flags(func(f uint) uint { return f ^ mdbx1.Durable | mdbx1.SafeNoSync }). // it's a cache, losing the last writes is fine
+		SyncPeriod(5 * time.Second).
+		Open()
+	if err != nil {
+		return nil, err
+	}
+	b := &MdbxBackend{db: db, limit: limit}
+	if err = db.View(context.Background(), func(tx kv.Tx) error {
+		v, err := tx.GetOne(resultsMetaTable, sizeKey)
+		if len(v) == 8 {
+			atomic.StoreUint64(&b.size, binary.BigEndian.Uint64(v))
+		}
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *MdbxBackend) Get(key []byte) (value []byte, err error) {
+	err = b.db.View(context.Background(), func(tx kv.Tx) error {
+		v, err := tx.GetOne(resultsTable, key)
+		if err != nil || len(v) < 16 {
+			return err
+		}
+		value = common.Copy(v[16:])
+		return nil
+	})
+	return value, err
+}
+
+func (b *MdbxBackend) Put(key []byte, blockNum uint64, value []byte) error {
+	return b.db.Update(context.Background(), func(tx kv.RwTx) error {
+		size, err := b.readU64(tx, sizeKey)
+		if err != nil {
+			return err
+		}
+		seq, err := b.readU64(tx, seqKey)
+		if err != nil {
+			return err
+		}
+		if size, err = b.delete(tx, key, size); err != nil {
+			return err
+		}
+
+		v := make([]byte, 16+len(value))
+		binary.BigEndian.PutUint64(v, seq)
+		binary.BigEndian.PutUint64(v[8:], blockNum)
+		copy(v[16:], value)
+		if err = tx.Put(resultsTable, key, v); err != nil {
+			return err
+		}
+		if err = tx.Put(resultsOrderTable, u64Key(seq), key); err != nil {
+			return err
+		}
+		if err = tx.Put(resultsBlocksTable, append(u64Key(blockNum), key...), nil); err != nil {
+			return err
+		}
+		size += uint64(len(key) + len(value))
+
+		// evict the oldest entries
+		for size > b.limit {
+			k, oldest, err := firstKV(tx, resultsOrderTable)
+			if err != nil {
+				return err
+			}
+			if k == nil {
+				break
+			}
+			if size, err = b.delete(tx, common.Copy(oldest), size); err != nil {
+				return err
+			}
+		}
+
+		if err = tx.Put(resultsMetaTable, seqKey, u64Key(seq+1)); err != nil {
+			return err
+		}
+		return b.writeSize(tx, size)
+	})
+}
+
+func (b *MdbxBackend) Delete(key []byte) error {
+	return b.db.Update(context.Background(), func(tx kv.RwTx) error {
+		size, err := b.readU64(tx, sizeKey)
+		if err != nil {
+			return err
+		}
+		if size, err = b.delete(tx, key, size); err != nil {
+			return err
+		}
+		return b.writeSize(tx, size)
+	})
+}
+
+func (b *MdbxBackend) DeleteFrom(blockNum uint64) (deleted int, err error) {
+	err = b.db.Update(context.Background(), func(tx kv.RwTx) error {
+		var keys [][]byte
+		if err := tx.ForEach(resultsBlocksTable, u64Key(blockNum), func(k, _ []byte) error {
+			keys = append(keys, common.Copy(k[8:]))
+			return nil
+		}); err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		size, err := b.readU64(tx, sizeKey)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if size, err = b.delete(tx, key, size); err != nil {
+				return err
+			}
+		}
+		deleted = len(keys)
+		return b.writeSize(tx, size)
+	})
+	return deleted, err
+}
+
+// delete removes the entry with its indices, size is the size before and the result is the size after the removal
+func (b *MdbxBackend) delete(tx kv.RwTx, key []byte, size uint64) (uint64, error) {
+	v, err := tx.GetOne(resultsTable, key)
+	if err != nil || len(v) < 16 {
+		return size, err
+	}
+	seq, blockNum, valueLen := binary.BigEndian.Uint64(v), binary.BigEndian.Uint64(v[8:]), len(v)-16
+	if err = tx.Delete(resultsOrderTable, u64Key(seq)); err != nil {
+		return size, err
+	}
+	if err = tx.Delete(resultsBlocksTable, append(u64Key(blockNum), key...)); err != nil {
+		return size, err
+	}
+	if err = tx.Delete(resultsTable, key); err != nil {
+		return size, err
+	}
+	if entrySize := uint64(len(key) + valueLen); entrySize < size {
+		return size - entrySize, nil
+	}
+	return 0, nil
+}
+
+func (b *MdbxBackend) readU64(tx kv.Tx, key []byte) (uint64, error) {
+	v, err := tx.GetOne(resultsMetaTable, key)
+	if err != nil || len(v) != 8 {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func (b *MdbxBackend) writeSize(tx kv.RwTx, size uint64) error {
+	if err := tx.Put(resultsMetaTable, sizeKey, u64Key(size)); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&b.size, size) // the transaction may still fail, the mirror is only used for metrics
+	return nil
+}
+
+func (b *MdbxBackend) Size() uint64 { return atomic.LoadUint64(&b.size) }
+
+func (b *MdbxBackend) Close() { b.db.Close() }
+
+func firstKV(tx kv.Tx, table string) ([]byte, []byte, error) {
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.Close()
+	return c.First()
+}
+
+func u64Key(n uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, n)
+	return k
+}
@@ -0,0 +1,99 @@
+package resultcache
+
+import (
+	"container/list"
+	"sync"
+)
+
+type memoryEntry struct {
+	key      string
+	blockNum uint64
+	value    []byte
+}
+
+// MemoryBackend keeps the results in memory, least recently used ones are evicted first.
+type MemoryBackend struct {
+	lock     sync.Mutex
+	limit    uint64
+	size     uint64
+	maxBlock uint64     // no entry is later, so most reorgs don't scan the entries
+	lru      *list.List // front is the most recently used
+	entries  map[string]*list.Element
+}
+
+func NewMemoryBackend(limit uint64) *MemoryBackend {
+	return &MemoryBackend{limit: limit, lru: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (m *MemoryBackend) Get(key []byte) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	el, ok := m.entries[string(key)]
+	if !ok {
+		return nil, nil
+	}
+	m.lru.MoveToFront(el)
+	return el.Value.(*memoryEntry).value, nil
+}
+
+func (m *MemoryBackend) Put(key []byte, blockNum uint64, value []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if el, ok := m.entries[string(key)]; ok {
+		m.remove(el)
+	}
+	e := &memoryEntry{key: string(key), blockNum: blockNum, value: value}
+	m.entries[e.key] = m.lru.PushFront(e)
+	m.size += uint64(len(e.key) + len(value))
+	if blockNum > m.maxBlock {
+		m.maxBlock = blockNum
+	}
+	for m.size > m.limit && m.lru.Len() > 0 {
+		m.remove(m.lru.Back())
+	}
+	return nil
+}
+
+func (m *MemoryBackend) Delete(key []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if el, ok := m.entries[string(key)]; ok {
+		m.remove(el)
+	}
+	return nil
+}
+
+func (m *MemoryBackend) DeleteFrom(blockNum uint64) (int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if blockNum > m.maxBlock || len(m.entries) == 0 {
+		return 0, nil
+	}
+	var deleted int
+	for el := m.lru.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*memoryEntry).blockNum >= blockNum {
+			m.remove(el)
+			deleted++
+		}
+		el = next
+	}
+	if blockNum > 0 {
+		m.maxBlock = blockNum - 1
+	}
+	return deleted, nil
+}
+
+func (m *MemoryBackend) remove(el *list.Element) {
+	e := m.lru.Remove(el).(*memoryEntry)
+	delete(m.entries, e.key)
+	m.size -= uint64(len(e.key) + len(e.value))
+}
+
+func (m *MemoryBackend) Size() uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.size
+}
+
+func (m *MemoryBackend) Close() {}
@@ -14,7 +14,9 @@ import (
 	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/clique"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/rlp"
@@ -25,17 +27,26 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+var errClique = errors.New("clique api is not available")
+var errAdmin = errors.New("admin api is not available")
+
 type RemoteBackend struct {
 	remoteEthBackend remote.ETHBACKENDClient
+	syncProgress     privateapi.SyncProgressClient
+	clique           privateapi.CliqueClient
+	admin            privateapi.AdminClient
 	log              log.Logger
 	version          gointerfaces.Version
 	db               kv.RoDB
 	blockReader      services.FullBlockReader
 }
 
-func NewRemoteBackend(client remote.ETHBACKENDClient, db kv.RoDB, blockReader services.FullBlockReader) *RemoteBackend {
+func NewRemoteBackend(client remote.ETHBACKENDClient, syncProgress privateapi.SyncProgressClient, cliqueClient privateapi.CliqueClient, adminClient privateapi.AdminClient, db kv.RoDB, blockReader services.FullBlockReader) *RemoteBackend {
 	return &RemoteBackend{
 		remoteEthBackend: client,
+		syncProgress:     syncProgress,
+		clique:           cliqueClient,
+		admin:            adminClient,
 		version:          gointerfaces.VersionFromProto(privateapi.EthBackendAPIVersion),
 		log:              log.New("remote_service", "eth_backend"),
 		db:               db,
@@ -193,6 +204,15 @@ func (back *RemoteBackend) HeaderByHash(ctx context.Context, tx kv.Getter, hash
 func (back *RemoteBackend) CanonicalHash(ctx context.Context, tx kv.Getter, blockHeight uint64) (common.Hash, error) {
 	return back.blockReader.CanonicalHash(ctx, tx, blockHeight)
 }
+func (back *RemoteBackend) Receipts(ctx context.Context, tx kv.Tx, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	return back.blockReader.Receipts(ctx, tx, block, senders)
+}
+func (back *RemoteBackend) FrozenReceipts(blockHeight uint64) bool {
+	return back.blockReader.FrozenReceipts(blockHeight)
+}
+func (back *RemoteBackend) FrozenReceiptsRanges() [][2]uint64 {
+	return back.blockReader.FrozenReceiptsRanges()
+}
 func (back *RemoteBackend) TxnByIdxInBlock(ctx context.Context, tx kv.Getter, blockNum uint64, i int) (types.Transaction, error) {
 	return back.blockReader.TxnByIdxInBlock(ctx, tx, blockNum, i)
 }
@@ -284,11 +304,112 @@ func (back *RemoteBackend) NodeInfo(ctx context.Context, limit uint32) ([]p2p.No
 	return ret, nil
 }
 
+func (back *RemoteBackend) SyncProgress(ctx context.Context) ([]stages.Progress, error) {
+	if back.syncProgress == nil {
+		return nil, errors.New("sync progress is not available")
+	}
+	reply, err := back.syncProgress.SyncProgress(ctx, &privateapi.SyncProgressRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("SYNCPROGRESSClient.SyncProgress() error: %w", err)
+	}
+	return reply.Stages, nil
+}
+
+func (back *RemoteBackend) CliquePropose(ctx context.Context, address common.Address, auth bool) error {
+	if back.clique == nil {
+		return errClique
+	}
+	if _, err := back.clique.CliquePropose(ctx, &privateapi.CliqueProposeRequest{Address: address, Auth: auth}); err != nil {
+		return fmt.Errorf("CLIQUEClient.Propose() error: %w", err)
+	}
+	return nil
+}
+
+func (back *RemoteBackend) CliqueDiscard(ctx context.Context, address common.Address) error {
+	if back.clique == nil {
+		return errClique
+	}
+	if _, err := back.clique.CliqueDiscard(ctx, &privateapi.CliqueDiscardRequest{Address: address}); err != nil {
+		return fmt.Errorf("CLIQUEClient.Discard() error: %w", err)
+	}
+	return nil
+}
+
+func (back *RemoteBackend) CliqueProposals(ctx context.Context) (map[common.Address]bool, error) {
+	if back.clique == nil {
+		return nil, errClique
+	}
+	reply, err := back.clique.CliqueProposals(ctx, &privateapi.CliqueProposalsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("CLIQUEClient.Proposals() error: %w", err)
+	}
+	return reply.Proposals, nil
+}
+
+func (back *RemoteBackend) CliqueStatus(ctx context.Context) (*clique.Status, error) {
+	if back.clique == nil {
+		return nil, errClique
+	}
+	status, err := back.clique.CliqueStatus(ctx, &privateapi.CliqueStatusRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("CLIQUEClient.Status() error: %w", err)
+	}
+	return status, nil
+}
+
+func (back *RemoteBackend) AddPeer(ctx context.Context, url string) error {
+	if back.admin == nil {
+		return errAdmin
+	}
+	if _, err := back.admin.AdminAddPeer(ctx, &privateapi.AdminPeerRequest{URL: url}); err != nil {
+		return fmt.Errorf("ADMINClient.AddPeer() error: %w", err)
+	}
+	return nil
+}
+
+func (back *RemoteBackend) RemovePeer(ctx context.Context, url string) error {
+	if back.admin == nil {
+		return errAdmin
+	}
+	if _, err := back.admin.AdminRemovePeer(ctx, &privateapi.AdminPeerRequest{URL: url}); err != nil {
+		return fmt.Errorf("ADMINClient.RemovePeer() error: %w", err)
+	}
+	return nil
+}
+
+func (back *RemoteBackend) AddTrustedPeer(ctx context.Context, url string) error {
+	if back.admin == nil {
+		return errAdmin
+	}
+	if _, err := back.admin.AdminAddTrustedPeer(ctx, &privateapi.AdminPeerRequest{URL: url}); err != nil {
+		return fmt.Errorf("ADMINClient.AddTrustedPeer() error: %w", err)
+	}
+	return nil
+}
+
+func (back *RemoteBackend) RemoveTrustedPeer(ctx context.Context, url string) error {
+	if back.admin == nil {
+		return errAdmin
+	}
+	if _, err := back.admin.AdminRemoveTrustedPeer(ctx, &privateapi.AdminPeerRequest{URL: url}); err != nil {
+		return fmt.Errorf("ADMINClient.RemoveTrustedPeer() error: %w", err)
+	}
+	return nil
+}
+
 func (back *RemoteBackend) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
 	rpcPeers, err := back.remoteEthBackend.Peers(ctx, &emptypb.Empty{})
 	if err != nil {
 		return nil, fmt.Errorf("ETHBACKENDClient.Peers() error: %w", err)
 	}
+	var scores map[string]*p2p.PeerScore
+	if back.admin != nil {
+		if reply, err := back.admin.AdminPeerScores(ctx, &privateapi.AdminPeerScoresRequest{}); err != nil {
+			back.log.Debug("ADMINClient.PeerScores() error, the peers have no scores", "err", err)
+		} else {
+			scores = reply.Scores
+		}
+	}
 
 	peers := make([]*p2p.PeerInfo, 0, len(rpcPeers.Peers))
 
@@ -313,6 +434,7 @@ func (back *RemoteBackend) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
 				Static:        rpcPeer.ConnIsStatic,
 			},
 			Protocols: nil,
+			Score:     scores[rpcPeer.Id],
 		}
 
 		peers = append(peers, &peer)
@@ -82,6 +82,28 @@ func DecodeAndReadNoForkDigest(r io.Reader, val cltypes.ObjectSSZ) error {
 	return nil
 }
 
+// DecodeAndReadVariableSize decodes a variable size object, whose encoded length must not exceed maxSize.
+func DecodeAndReadVariableSize(r io.Reader, val cltypes.ObjectSSZ, maxSize uint64) error {
+	encodedLn, _, err := ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("unable to read varint from message prefix: %v", err)
+	}
+	if encodedLn > maxSize {
+		return fmt.Errorf("encoded length %d exceeds maximum size %d", encodedLn, maxSize)
+	}
+
+	sr := snappy.NewReader(r)
+	raw := make([]byte, encodedLn)
+	if _, err := io.ReadFull(sr, raw); err != nil {
+		return fmt.Errorf("unable to readPacket: %w", err)
+	}
+
+	if err := val.UnmarshalSSZ(raw); err != nil {
+		return fmt.Errorf("unable to unmarshal message: %v", err)
+	}
+	return nil
+}
+
 func ReadUvarint(r io.Reader) (x, n uint64, err error) {
 	currByte := make([]byte, 1)
 	for shift := uint(0); shift < 64; shift += 7 {
@@ -14,14 +14,166 @@
 package handlers
 
 import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cl/fork"
+	"github.com/ledgerwatch/erigon/cl/utils"
+	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/rawdb"
+	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/communication/ssz_snappy"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/libp2p/go-libp2p/core/network"
 )
 
+// blocksByRangeHandler serves the V1 protocol, which has no context bytes and can only carry phase0 blocks.
 func (c *ConsensusHandlers) blocksByRangeHandler(stream network.Stream) {
-	log.Trace("Got block by range handler call")
+	c.serveBlocksByRange(stream, false)
+}
+
+func (c *ConsensusHandlers) blocksByRangeV2Handler(stream network.Stream) {
+	c.serveBlocksByRange(stream, true)
 }
 
+// beaconBlocksByRootHandler serves the V1 protocol, which has no context bytes and can only carry phase0 blocks.
 func (c *ConsensusHandlers) beaconBlocksByRootHandler(stream network.Stream) {
-	log.Trace("Got beacon block by root handler call")
+	c.serveBlocksByRoot(stream, false)
+}
+
+func (c *ConsensusHandlers) beaconBlocksByRootV2Handler(stream network.Stream) {
+	c.serveBlocksByRoot(stream, true)
+}
+
+func (c *ConsensusHandlers) serveBlocksByRange(stream network.Stream, withContext bool) {
+	defer stream.Close()
+	req := &cltypes.BeaconBlocksByRangeRequest{}
+	if err := ssz_snappy.DecodeAndReadNoForkDigest(stream, req); err != nil {
+		writeErrorResponse(stream, InvalidRequestPrefix, err.Error())
+		return
+	}
+	if req.Count == 0 || req.Step == 0 {
+		writeErrorResponse(stream, InvalidRequestPrefix, "count and step must be positive")
+		return
+	}
+	if c.db == nil {
+		stream.Write([]byte{ResourceUnavaiablePrefix})
+		return
+	}
+	count := req.Count
+	if count > c.networkConfig.MaxRequestBlocks {
+		count = c.networkConfig.MaxRequestBlocks
+	}
+	tx, err := c.db.BeginRo(c.ctx)
+	if err != nil {
+		writeErrorResponse(stream, ServerErrorPrefix, "unable to read blocks")
+		return
+	}
+	defer tx.Rollback()
+
+	slot := req.StartSlot
+	for i := uint64(0); i < count; i, slot = i+1, slot+req.Step {
+		// Stop on overflow, no block can be that far anyway.
+		if slot < req.StartSlot {
+			return
+		}
+		block, err := rawdb.ReadBeaconBlock(tx, slot)
+		if err != nil {
+			log.Trace("[Sentinel] Unable to read block", "slot", slot, "err", err)
+			writeErrorResponse(stream, ServerErrorPrefix, "unable to read blocks")
+			return
+		}
+		// Missed proposal are absent slot
+		if block == nil {
+			continue
+		}
+		if !c.canServeBlock(block, withContext) {
+			return
+		}
+		if err := c.writeBlockResponse(stream, block, withContext); err != nil {
+			log.Trace("[Sentinel] Unable to send block", "slot", slot, "err", err)
+			return
+		}
+	}
+}
+
+func (c *ConsensusHandlers) serveBlocksByRoot(stream network.Stream, withContext bool) {
+	defer stream.Close()
+	var req cltypes.BeaconBlocksByRootRequest
+	if err := ssz_snappy.DecodeAndReadVariableSize(stream, &req, c.networkConfig.MaxRequestBlocks*32); err != nil {
+		writeErrorResponse(stream, InvalidRequestPrefix, err.Error())
+		return
+	}
+	if c.db == nil {
+		stream.Write([]byte{ResourceUnavaiablePrefix})
+		return
+	}
+	tx, err := c.db.BeginRo(c.ctx)
+	if err != nil {
+		writeErrorResponse(stream, ServerErrorPrefix, "unable to read blocks")
+		return
+	}
+	defer tx.Rollback()
+
+	for _, root := range req {
+		slot, err := rawdb.ReadBlockSlotByBlockRoot(tx, root)
+		if err != nil {
+			log.Trace("[Sentinel] Unable to read block slot", "root", root, "err", err)
+			writeErrorResponse(stream, ServerErrorPrefix, "unable to read blocks")
+			return
+		}
+		// Unknown blocks are skipped.
+		if slot == nil {
+			continue
+		}
+		block, err := rawdb.ReadBeaconBlock(tx, *slot)
+		if err != nil {
+			log.Trace("[Sentinel] Unable to read block", "slot", *slot, "err", err)
+			writeErrorResponse(stream, ServerErrorPrefix, "unable to read blocks")
+			return
+		}
+		if block == nil || !c.canServeBlock(block, withContext) {
+			continue
+		}
+		if err := c.writeBlockResponse(stream, block, withContext); err != nil {
+			log.Trace("[Sentinel] Unable to send block", "slot", *slot, "err", err)
+			return
+		}
+	}
+}
+
+// canServeBlock checks that the block can be sent with the protocol version and that it is stored in full, execution
+// payloads are gone once eth1 data has been cleared.
+func (c *ConsensusHandlers) canServeBlock(block *cltypes.SignedBeaconBlock, withContext bool) bool {
+	if !withContext && block.Version() != clparams.Phase0Version {
+		return false
+	}
+	return block.Version() < clparams.BellatrixVersion || block.Block.Body.ExecutionPayload != nil
+}
+
+// writeBlockResponse writes a successful response chunk, the context bytes are the fork digest of the block fork.
+func (c *ConsensusHandlers) writeBlockResponse(stream network.Stream, block *cltypes.SignedBeaconBlock, withContext bool) error {
+	prefix := []byte{SuccessfulResponsePrefix}
+	if withContext {
+		forkDigest, err := c.forkDigestForVersion(block.Version())
+		if err != nil {
+			return err
+		}
+		prefix = append(prefix, forkDigest[:]...)
+	}
+	return ssz_snappy.EncodeAndWrite(stream, block, prefix...)
+}
+
+func (c *ConsensusHandlers) forkDigestForVersion(version clparams.StateVersion) ([4]byte, error) {
+	var forkVersion []byte
+	switch version {
+	case clparams.Phase0Version:
+		forkVersion = c.beaconConfig.GenesisForkVersion
+	case clparams.AltairVersion:
+		forkVersion = c.beaconConfig.AltairForkVersion
+	case clparams.BellatrixVersion:
+		forkVersion = c.beaconConfig.BellatrixForkVersion
+	default:
+		return [4]byte{}, fmt.Errorf("unsupported block version %d", version)
+	}
+	return fork.ComputeForkDigestForVersion(utils.BytesToBytes4(forkVersion), c.genesisConfig.GenesisValidatorRoot)
 }
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/rawdb"
+	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/communication"
+	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/communication/ssz_snappy"
+	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/peers"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+type testBlocksServer struct {
+	client, server host.Host
+	beaconConfig   *clparams.BeaconChainConfig
+	genesisConfig  *clparams.GenesisConfig
+	// roots of the stored blocks, by slot.
+	roots map[uint64][32]byte
+}
+
+func newTestHost(t *testing.T) host.Host {
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+// newTestBlocksServer stores a phase0 block at slot 1, an altair block at slot 2 and a bellatrix block, then serves
+// them from an in-process host the client host is connected to.
+func newTestBlocksServer(t *testing.T) *testBlocksServer {
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+	bellatrixBlock := &cltypes.SignedBeaconBlockBellatrix{}
+	require.NoError(t, bellatrixBlock.UnmarshalSSZ(rawdb.SSZTestBeaconBlock))
+	blocks := []*cltypes.SignedBeaconBlock{
+		cltypes.NewSignedBeaconBlock(&cltypes.SignedBeaconBlockPhase0{
+			Block: &cltypes.BeaconBlockPhase0{
+				Slot: 1,
+				Body: &cltypes.BeaconBodyPhase0{Eth1Data: &cltypes.Eth1Data{}, Graffiti: make([]byte, 32)},
+			},
+		}),
+		cltypes.NewSignedBeaconBlock(&cltypes.SignedBeaconBlockAltair{
+			Block: &cltypes.BeaconBlockAltair{
+				Slot: 2,
+				Body: &cltypes.BeaconBodyAltair{
+					Eth1Data:      &cltypes.Eth1Data{},
+					Graffiti:      make([]byte, 32),
+					SyncAggregate: &cltypes.SyncAggregate{SyncCommiteeBits: make([]byte, 64)},
+				},
+			},
+		}),
+		cltypes.NewSignedBeaconBlock(bellatrixBlock),
+	}
+	roots := map[uint64][32]byte{}
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for _, block := range blocks {
+			if err := rawdb.WriteBeaconBlock(tx, block); err != nil {
+				return err
+			}
+			root, err := block.Block.HashTreeRoot()
+			if err != nil {
+				return err
+			}
+			if err := tx.Put(kv.RootSlotIndex, root[:], rawdb.EncodeNumber(block.Block.Slot)); err != nil {
+				return err
+			}
+			roots[block.Block.Slot] = root
+		}
+		return nil
+	}))
+
+	genesisConfig, networkConfig, beaconConfig := clparams.GetConfigsByNetwork(clparams.MainnetNetwork)
+	server, client := newTestHost(t), newTestHost(t)
+	NewConsensusHandlers(ctx, db, server, peers.New(server), beaconConfig, genesisConfig, networkConfig, &cltypes.MetadataV2{}).Start()
+	require.NoError(t, client.Connect(ctx, peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}))
+	return &testBlocksServer{
+		client:        client,
+		server:        server,
+		beaconConfig:  beaconConfig,
+		genesisConfig: genesisConfig,
+		roots:         roots,
+	}
+}
+
+// request sends the request and returns the roots of the received blocks, checking they have the expected version.
+func (s *testBlocksServer) request(t *testing.T, topic string, req cltypes.ObjectSSZ, withContext bool) ([][32]byte, bool) {
+	var buffer bytes.Buffer
+	require.NoError(t, ssz_snappy.EncodeAndWrite(&buffer, req))
+	response, isError, err := communication.SendRequestRawToPeer(context.Background(), s.client, buffer.Bytes(), topic, s.server.ID())
+	require.NoError(t, err)
+	if isError {
+		return nil, true
+	}
+
+	c := &ConsensusHandlers{beaconConfig: s.beaconConfig, genesisConfig: s.genesisConfig}
+	r := bytes.NewReader(response)
+	roots := [][32]byte{}
+	for r.Len() > 0 {
+		// The response code of the first chunk is consumed by the request.
+		if len(roots) > 0 {
+			code, err := r.ReadByte()
+			require.NoError(t, err)
+			require.Equal(t, byte(SuccessfulResponsePrefix), code)
+		}
+		var block cltypes.ObjectSSZ = &cltypes.SignedBeaconBlockPhase0{}
+		if withContext {
+			forkDigest := [4]byte{}
+			_, err := io.ReadFull(r, forkDigest[:])
+			require.NoError(t, err)
+			blocksByDigest := map[clparams.StateVersion]cltypes.ObjectSSZ{
+				clparams.Phase0Version:    &cltypes.SignedBeaconBlockPhase0{},
+				clparams.AltairVersion:    &cltypes.SignedBeaconBlockAltair{},
+				clparams.BellatrixVersion: &cltypes.SignedBeaconBlockBellatrix{},
+			}
+			block = nil
+			for version, versionBlock := range blocksByDigest {
+				digest, err := c.forkDigestForVersion(version)
+				require.NoError(t, err)
+				if digest == forkDigest {
+					block = versionBlock
+				}
+			}
+			require.NotNil(t, block, "unknown fork digest %x", forkDigest)
+		}
+		encodedLn, _, err := ssz_snappy.ReadUvarint(r)
+		require.NoError(t, err)
+		raw := make([]byte, encodedLn)
+		_, err = io.ReadFull(snappy.NewReader(r), raw)
+		require.NoError(t, err)
+		require.NoError(t, block.UnmarshalSSZ(raw))
+		signedBlock := cltypes.NewSignedBeaconBlock(block)
+		root, err := signedBlock.Block.HashTreeRoot()
+		require.NoError(t, err)
+		roots = append(roots, root)
+	}
+	return roots, false
+}
+
+func TestBlocksByRangeHandler(t *testing.T) {
+	s := newTestBlocksServer(t)
+	req := &cltypes.BeaconBlocksByRangeRequest{StartSlot: 0, Count: 10, Step: 1}
+
+	roots, isError := s.request(t, communication.BeaconBlocksByRangeProtocolV2, req, true)
+	require.False(t, isError)
+	require.Equal(t, [][32]byte{s.roots[1], s.roots[2]}, roots)
+
+	// V1 responses cannot carry post phase0 blocks.
+	roots, isError = s.request(t, communication.BeaconBlocksByRangeProtocolV1, req, false)
+	require.False(t, isError)
+	require.Equal(t, [][32]byte{s.roots[1]}, roots)
+
+	// Every other slot.
+	req = &cltypes.BeaconBlocksByRangeRequest{StartSlot: 0, Count: 10, Step: 2}
+	roots, isError = s.request(t, communication.BeaconBlocksByRangeProtocolV2, req, true)
+	require.False(t, isError)
+	require.Equal(t, [][32]byte{s.roots[2]}, roots)
+
+	_, isError = s.request(t, communication.BeaconBlocksByRangeProtocolV2, &cltypes.BeaconBlocksByRangeRequest{Count: 0, Step: 1}, true)
+	require.True(t, isError)
+}
+
+func TestBeaconBlocksByRootHandler(t *testing.T) {
+	s := newTestBlocksServer(t)
+	var bellatrixRoot [32]byte
+	for slot, root := range s.roots {
+		if slot > 2 {
+			bellatrixRoot = root
+		}
+	}
+	req := cltypes.BeaconBlocksByRootRequest{s.roots[2], {1, 2, 3}, bellatrixRoot, s.roots[1]}
+
+	roots, isError := s.request(t, communication.BeaconBlocksByRootProtocolV2, &req, true)
+	require.False(t, isError)
+	require.Equal(t, [][32]byte{s.roots[2], bellatrixRoot, s.roots[1]}, roots)
+
+	roots, isError = s.request(t, communication.BeaconBlocksByRootProtocolV1, &req, false)
+	require.False(t, isError)
+	require.Equal(t, [][32]byte{s.roots[1]}, roots)
+}
@@ -15,7 +15,9 @@ package handlers
 
 import (
 	"context"
+	"encoding/binary"
 
+	"github.com/golang/snappy"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/cl/clparams"
 	"github.com/ledgerwatch/erigon/cl/cltypes"
@@ -33,6 +35,7 @@ type ConsensusHandlers struct {
 	metadata      *cltypes.MetadataV2
 	beaconConfig  *clparams.BeaconChainConfig
 	genesisConfig *clparams.GenesisConfig
+	networkConfig *clparams.NetworkConfig
 	ctx           context.Context
 
 	db kv.RoDB // Read stuff from database to answer
@@ -40,11 +43,17 @@ type ConsensusHandlers struct {
 
 const (
 	SuccessfulResponsePrefix = 0x00
+	InvalidRequestPrefix     = 0x01
+	ServerErrorPrefix        = 0x02
 	ResourceUnavaiablePrefix = 0x03
 )
 
+// maxErrorMessageLength is the maximum length of the error message of an error response.
+const maxErrorMessageLength = 256
+
 func NewConsensusHandlers(ctx context.Context, db kv.RoDB, host host.Host,
-	peers *peers.Peers, beaconConfig *clparams.BeaconChainConfig, genesisConfig *clparams.GenesisConfig, metadata *cltypes.MetadataV2) *ConsensusHandlers {
+	peers *peers.Peers, beaconConfig *clparams.BeaconChainConfig, genesisConfig *clparams.GenesisConfig,
+	networkConfig *clparams.NetworkConfig, metadata *cltypes.MetadataV2) *ConsensusHandlers {
 	c := &ConsensusHandlers{
 		peers:         peers,
 		host:          host,
@@ -52,6 +61,7 @@ func NewConsensusHandlers(ctx context.Context, db kv.RoDB, host host.Host,
 		db:            db,
 		genesisConfig: genesisConfig,
 		beaconConfig:  beaconConfig,
+		networkConfig: networkConfig,
 		ctx:           ctx,
 	}
 	c.handlers = map[protocol.ID]network.StreamHandler{
@@ -61,13 +71,35 @@ func NewConsensusHandlers(ctx context.Context, db kv.RoDB, host host.Host,
 		protocol.ID(communication.MetadataProtocolV1):            c.metadataV1Handler,
 		protocol.ID(communication.MetadataProtocolV2):            c.metadataV2Handler,
 		protocol.ID(communication.BeaconBlocksByRangeProtocolV1): c.blocksByRangeHandler,
+		protocol.ID(communication.BeaconBlocksByRangeProtocolV2): c.blocksByRangeV2Handler,
 		protocol.ID(communication.BeaconBlocksByRootProtocolV1):  c.beaconBlocksByRootHandler,
+		protocol.ID(communication.BeaconBlocksByRootProtocolV2):  c.beaconBlocksByRootV2Handler,
 		protocol.ID(communication.LightClientFinalityUpdateV1):   c.lightClientFinalityUpdateHandler,
 		protocol.ID(communication.LightClientOptimisticUpdateV1): c.lightClientOptimisticUpdateHandler,
+		protocol.ID(communication.LightClientBootstrapV1):        c.lightClientBootstrapHandler,
+		protocol.ID(communication.LightClientUpdatesByRangeV1):   c.lightClientUpdatesByRangeHandler,
 	}
 	return c
 }
 
+// writeErrorResponse writes an error response chunk with the given code, the message is ssz_snappy encoded as the
+// spec ErrorMessage (a byte list).
+func writeErrorResponse(stream network.Stream, code byte, message string) error {
+	if len(message) > maxErrorMessageLength {
+		message = message[:maxErrorMessageLength]
+	}
+	lengthBuf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(lengthBuf, uint64(len(message)))
+	if _, err := stream.Write(append([]byte{code}, lengthBuf[:n]...)); err != nil {
+		return err
+	}
+	sw := snappy.NewBufferedWriter(stream)
+	if _, err := sw.Write([]byte(message)); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
 func (c *ConsensusHandlers) Start() {
 	for id, handler := range c.handlers {
 		c.host.SetStreamHandler(id, handler)
@@ -14,79 +14,166 @@
 package handlers
 
 import (
-	"github.com/ledgerwatch/erigon/cl/fork"
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
 	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/rawdb"
 	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/communication/ssz_snappy"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/libp2p/go-libp2p/core/network"
 )
 
+// maxRequestLightClientUpdates is the maximum amount of updates served by a single updates by range request.
+const maxRequestLightClientUpdates = 128
+
 func (c *ConsensusHandlers) lightClientFinalityUpdateHandler(stream network.Stream) {
+	defer stream.Close()
 	if c.db == nil {
 		stream.Write([]byte{ResourceUnavaiablePrefix})
 		return
 	}
-
-	forkDigest, err := fork.ComputeForkDigest(c.beaconConfig, c.genesisConfig)
-	if err != nil {
-		stream.Close()
-		return
-	}
-	if _, err := stream.Write(forkDigest[:]); err != nil {
-		stream.Close()
-		return
-	}
 	// Read latest lightclient update
 	tx, err := c.db.BeginRo(c.ctx)
 	if err != nil {
-		stream.Close()
+		writeErrorResponse(stream, ServerErrorPrefix, "unable to read finality update")
 		return
 	}
 	defer tx.Rollback()
 	update, err := rawdb.ReadLightClientFinalityUpdate(tx)
 	if err != nil {
-		stream.Close()
+		writeErrorResponse(stream, ServerErrorPrefix, "unable to read finality update")
 		return
 	}
-
-	ssz_snappy.EncodeAndWrite(stream, update, SuccessfulResponsePrefix)
+	if update == nil {
+		writeErrorResponse(stream, ResourceUnavaiablePrefix, "no finality update available")
+		return
+	}
+	if err := c.writeLightClientResponse(stream, update, update.AttestedHeader.Slot); err != nil {
+		log.Trace("[Sentinel] Unable to send finality update", "err", err)
+	}
 }
 
 func (c *ConsensusHandlers) lightClientOptimisticUpdateHandler(stream network.Stream) {
+	defer stream.Close()
 	if c.db == nil {
 		stream.Write([]byte{ResourceUnavaiablePrefix})
 		return
 	}
-	forkDigest, err := fork.ComputeForkDigest(c.beaconConfig, c.genesisConfig)
+	// Read latest lightclient update
+	tx, err := c.db.BeginRo(c.ctx)
+	if err != nil {
+		writeErrorResponse(stream, ServerErrorPrefix, "unable to read optimistic update")
+		return
+	}
+	defer tx.Rollback()
+	update, err := rawdb.ReadLightClientOptimisticUpdate(tx)
 	if err != nil {
-		stream.Close()
+		writeErrorResponse(stream, ServerErrorPrefix, "unable to read optimistic update")
 		return
 	}
-	if _, err := stream.Write(forkDigest[:]); err != nil {
-		stream.Close()
+	if update == nil {
+		writeErrorResponse(stream, ResourceUnavaiablePrefix, "no optimistic update available")
+		return
+	}
+	if err := c.writeLightClientResponse(stream, update, update.AttestedHeader.Slot); err != nil {
+		log.Trace("[Sentinel] Unable to send optimistic update", "err", err)
+	}
+}
+
+func (c *ConsensusHandlers) lightClientBootstrapHandler(stream network.Stream) {
+	defer stream.Close()
+	req := &cltypes.SingleRoot{}
+	if err := ssz_snappy.DecodeAndReadNoForkDigest(stream, req); err != nil {
+		writeErrorResponse(stream, InvalidRequestPrefix, err.Error())
+		return
+	}
+	if c.db == nil {
+		stream.Write([]byte{ResourceUnavaiablePrefix})
 		return
 	}
-	// Read latest lightclient update
 	tx, err := c.db.BeginRo(c.ctx)
 	if err != nil {
-		stream.Close()
+		writeErrorResponse(stream, ServerErrorPrefix, "unable to read bootstrap")
 		return
 	}
 	defer tx.Rollback()
-	update, err := rawdb.ReadLightClientOptimisticUpdate(tx)
+	bootstrap, err := rawdb.ReadLightClientBootstrap(tx, req.Root)
 	if err != nil {
-		stream.Close()
+		log.Trace("[Sentinel] Unable to read bootstrap", "root", req.Root, "err", err)
+		writeErrorResponse(stream, ServerErrorPrefix, "unable to read bootstrap")
 		return
 	}
-
-	ssz_snappy.EncodeAndWrite(stream, update, SuccessfulResponsePrefix)
+	if bootstrap == nil {
+		writeErrorResponse(stream, ResourceUnavaiablePrefix, "bootstrap not available")
+		return
+	}
+	if err := c.writeLightClientResponse(stream, bootstrap, bootstrap.Header.Slot); err != nil {
+		log.Trace("[Sentinel] Unable to send bootstrap", "root", req.Root, "err", err)
+	}
 }
 
-func (c *ConsensusHandlers) lightClientUpdatesByRange(stream network.Stream) {
+// lightClientUpdatesByRangeHandler serves the best update of each requested period, it stops at the first period
+// without an update as responses must be consecutive.
+func (c *ConsensusHandlers) lightClientUpdatesByRangeHandler(stream network.Stream) {
+	defer stream.Close()
+	req := &cltypes.LightClientUpdatesByRangeRequest{}
+	if err := ssz_snappy.DecodeAndReadNoForkDigest(stream, req); err != nil {
+		writeErrorResponse(stream, InvalidRequestPrefix, err.Error())
+		return
+	}
+	if req.Count == 0 {
+		writeErrorResponse(stream, InvalidRequestPrefix, "count must be positive")
+		return
+	}
 	if c.db == nil {
 		stream.Write([]byte{ResourceUnavaiablePrefix})
 		return
 	}
-	log.Info("Got lightClientUpdatesByRange handler call")
-	// TODO: ADD Proper logic
+	count := req.Count
+	if count > maxRequestLightClientUpdates {
+		count = maxRequestLightClientUpdates
+	}
+	tx, err := c.db.BeginRo(c.ctx)
+	if err != nil {
+		writeErrorResponse(stream, ServerErrorPrefix, "unable to read updates")
+		return
+	}
+	defer tx.Rollback()
+
+	for period := req.Period; period < req.Period+count; period++ {
+		update, err := rawdb.ReadLightClientUpdate(tx, period)
+		if err != nil {
+			log.Trace("[Sentinel] Unable to read update", "period", period, "err", err)
+			writeErrorResponse(stream, ServerErrorPrefix, "unable to read updates")
+			return
+		}
+		if update == nil {
+			return
+		}
+		if err := c.writeLightClientResponse(stream, update, update.AttestedHeader.Slot); err != nil {
+			log.Trace("[Sentinel] Unable to send update", "period", period, "err", err)
+			return
+		}
+	}
+}
+
+// writeLightClientResponse writes a successful response chunk, the context bytes are the fork digest of the fork at
+// the attested slot.
+func (c *ConsensusHandlers) writeLightClientResponse(stream network.Stream, object cltypes.ObjectSSZ, attestedSlot uint64) error {
+	forkDigest, err := c.forkDigestForVersion(c.stateVersionForSlot(attestedSlot))
+	if err != nil {
+		return err
+	}
+	return ssz_snappy.EncodeAndWrite(stream, object, append([]byte{SuccessfulResponsePrefix}, forkDigest[:]...)...)
+}
+
+func (c *ConsensusHandlers) stateVersionForSlot(slot uint64) clparams.StateVersion {
+	epoch := slot / c.beaconConfig.SlotsPerEpoch
+	switch {
+	case epoch >= c.beaconConfig.BellatrixForkEpoch:
+		return clparams.BellatrixVersion
+	case epoch >= c.beaconConfig.AltairForkEpoch:
+		return clparams.AltairVersion
+	default:
+		return clparams.Phase0Version
+	}
 }
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/cl/clparams"
+	"github.com/ledgerwatch/erigon/cl/cltypes"
+	"github.com/ledgerwatch/erigon/cmd/erigon-cl/core/rawdb"
+	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/communication"
+	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/communication/ssz_snappy"
+	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/peers"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func testBranch(depth int) [][]byte {
+	branch := make([][]byte, depth)
+	for i := range branch {
+		branch[i] = make([]byte, 32)
+		branch[i][0] = byte(i)
+	}
+	return branch
+}
+
+func testSyncCommittee() *cltypes.SyncCommittee {
+	return &cltypes.SyncCommittee{PubKeys: make([][48]byte, 512)}
+}
+
+func testLightClientUpdate(attestedSlot uint64) *cltypes.LightClientUpdate {
+	return &cltypes.LightClientUpdate{
+		AttestedHeader:          &cltypes.BeaconBlockHeader{Slot: attestedSlot},
+		NextSyncCommitee:        testSyncCommittee(),
+		NextSyncCommitteeBranch: testBranch(5),
+		FinalizedHeader:         &cltypes.BeaconBlockHeader{Slot: attestedSlot - 64},
+		FinalityBranch:          testBranch(6),
+		SyncAggregate:           &cltypes.SyncAggregate{SyncCommiteeBits: make([]byte, 64)},
+		SignatureSlot:           attestedSlot + 1,
+	}
+}
+
+type testLightClientServer struct {
+	client, server host.Host
+	handlers       *ConsensusHandlers
+}
+
+// newTestLightClientServer stores the updates of two altair periods and a bootstrap with the given root.
+func newTestLightClientServer(t *testing.T, bootstrapRoot [32]byte, bootstrap *cltypes.LightClientBootstrap, updates []*cltypes.LightClientUpdate) *testLightClientServer {
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for _, update := range updates {
+			if err := rawdb.WriteLightClientUpdate(tx, update); err != nil {
+				return err
+			}
+		}
+		return rawdb.WriteLightClientBootstrap(tx, bootstrapRoot, bootstrap)
+	}))
+
+	genesisConfig, networkConfig, beaconConfig := clparams.GetConfigsByNetwork(clparams.MainnetNetwork)
+	server, client := newTestHost(t), newTestHost(t)
+	handlers := NewConsensusHandlers(ctx, db, server, peers.New(server), beaconConfig, genesisConfig, networkConfig, &cltypes.MetadataV2{})
+	handlers.Start()
+	require.NoError(t, client.Connect(ctx, peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}))
+	return &testLightClientServer{client: client, server: server, handlers: handlers}
+}
+
+// request sends the request and decodes every response chunk with newObject, checking their context bytes.
+func (s *testLightClientServer) request(t *testing.T, topic string, req cltypes.ObjectSSZ, newObject func() cltypes.ObjectSSZ) ([]cltypes.ObjectSSZ, bool) {
+	var buffer bytes.Buffer
+	require.NoError(t, ssz_snappy.EncodeAndWrite(&buffer, req))
+	response, isError, err := communication.SendRequestRawToPeer(context.Background(), s.client, buffer.Bytes(), topic, s.server.ID())
+	require.NoError(t, err)
+	if isError {
+		return nil, true
+	}
+	altairDigest, err := s.handlers.forkDigestForVersion(clparams.AltairVersion)
+	require.NoError(t, err)
+
+	r := bytes.NewReader(response)
+	objects := []cltypes.ObjectSSZ{}
+	for r.Len() > 0 {
+		// The response code of the first chunk is consumed by the request.
+		if len(objects) > 0 {
+			code, err := r.ReadByte()
+			require.NoError(t, err)
+			require.Equal(t, byte(SuccessfulResponsePrefix), code)
+		}
+		forkDigest := [4]byte{}
+		_, err := io.ReadFull(r, forkDigest[:])
+		require.NoError(t, err)
+		require.Equal(t, altairDigest, forkDigest)
+		object := newObject()
+		require.NoError(t, ssz_snappy.DecodeAndReadNoForkDigest(r, object))
+		objects = append(objects, object)
+	}
+	return objects, false
+}
+
+func TestLightClientBootstrapHandler(t *testing.T) {
+	// First altair slot.
+	slot := clparams.MainnetBeaconConfig.AltairForkEpoch * 32
+	bootstrap := &cltypes.LightClientBootstrap{
+		Header:                     &cltypes.BeaconBlockHeader{Slot: slot, Root: [32]byte{1}},
+		CurrentSyncCommittee:       testSyncCommittee(),
+		CurrentSyncCommitteeBranch: testBranch(5),
+	}
+	root := [32]byte{2}
+	s := newTestLightClientServer(t, root, bootstrap, nil)
+	newBootstrap := func() cltypes.ObjectSSZ { return &cltypes.LightClientBootstrap{} }
+
+	objects, isError := s.request(t, communication.LightClientBootstrapV1, &cltypes.SingleRoot{Root: root}, newBootstrap)
+	require.False(t, isError)
+	require.Equal(t, []cltypes.ObjectSSZ{bootstrap}, objects)
+
+	_, isError = s.request(t, communication.LightClientBootstrapV1, &cltypes.SingleRoot{Root: [32]byte{3}}, newBootstrap)
+	require.True(t, isError)
+}
+
+func TestLightClientUpdatesByRangeHandler(t *testing.T) {
+	slot := clparams.MainnetBeaconConfig.AltairForkEpoch * 32
+	period := slot / 8192
+	updates := []*cltypes.LightClientUpdate{
+		testLightClientUpdate(slot),
+		testLightClientUpdate(slot + 8192),
+		// A gap, responses stop before it.
+		testLightClientUpdate(slot + 3*8192),
+	}
+	s := newTestLightClientServer(t, [32]byte{}, &cltypes.LightClientBootstrap{
+		Header:                     &cltypes.BeaconBlockHeader{},
+		CurrentSyncCommittee:       testSyncCommittee(),
+		CurrentSyncCommitteeBranch: testBranch(5),
+	}, updates)
+	newUpdate := func() cltypes.ObjectSSZ { return &cltypes.LightClientUpdate{} }
+
+	objects, isError := s.request(t, communication.LightClientUpdatesByRangeV1, &cltypes.LightClientUpdatesByRangeRequest{Period: period, Count: 10}, newUpdate)
+	require.False(t, isError)
+	require.Equal(t, []cltypes.ObjectSSZ{updates[0], updates[1]}, objects)
+
+	objects, isError = s.request(t, communication.LightClientUpdatesByRangeV1, &cltypes.LightClientUpdatesByRangeRequest{Period: period + 1, Count: 1}, newUpdate)
+	require.False(t, isError)
+	require.Equal(t, []cltypes.ObjectSSZ{updates[1]}, objects)
+
+	_, isError = s.request(t, communication.LightClientUpdatesByRangeV1, &cltypes.LightClientUpdatesByRangeRequest{Period: period, Count: 0}, newUpdate)
+	require.True(t, isError)
+}
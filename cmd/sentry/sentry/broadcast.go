@@ -55,7 +55,7 @@ func (cs *MultiClient) PropagateNewBlockHashes(ctx context.Context, announces []
 
 		switch sentry.Protocol() {
 
-		case eth.ETH66, eth.ETH67:
+		case eth.ETH66, eth.ETH67, eth.ETH68:
 			if req66 == nil {
 				req66 = &proto_sentry.OutboundMessageData{
 					Id:   proto_sentry.MessageId_NEW_BLOCK_HASHES_66,
@@ -104,7 +104,7 @@ func (cs *MultiClient) BroadcastNewBlock(ctx context.Context, header *types.Head
 
 		switch sentry.Protocol() {
 
-		case eth.ETH66, eth.ETH67:
+		case eth.ETH66, eth.ETH67, eth.ETH68:
 			if req66 == nil {
 				req66 = &proto_sentry.SendMessageToRandomPeersRequest{
 					MaxPeers: 1024,
@@ -162,7 +162,7 @@ func (cs *MultiClient) BroadcastLocalPooledTxs(ctx context.Context, txs []common
 			}
 
 			switch sentry.Protocol() {
-			case eth.ETH66, eth.ETH67:
+			case eth.ETH66, eth.ETH67, eth.ETH68:
 				if req66 == nil {
 					req66 = &proto_sentry.OutboundMessageData{
 						Id:   proto_sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66,
@@ -222,7 +222,7 @@ func (cs *MultiClient) BroadcastRemotePooledTxs(ctx context.Context, txs []commo
 
 			switch sentry.Protocol() {
 
-			case eth.ETH66, eth.ETH67:
+			case eth.ETH66, eth.ETH67, eth.ETH68:
 				if req66 == nil {
 					req66 = &proto_sentry.SendMessageToRandomPeersRequest{
 						MaxPeers: 1024,
@@ -273,7 +273,7 @@ func (cs *MultiClient) PropagatePooledTxsToPeersList(ctx context.Context, peers
 			for _, peer := range peers {
 				switch sentry.Protocol() {
 
-				case eth.ETH66, eth.ETH67:
+				case eth.ETH66, eth.ETH67, eth.ETH68:
 					req66 := &proto_sentry.SendMessageByIdRequest{
 						PeerId: peer,
 						Data: &proto_sentry.OutboundMessageData{
@@ -0,0 +1,130 @@
+package sentry
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ledgerwatch/erigon-lib/direct"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	proto_sentry "github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	proto_types "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// erigon-lib predates eth/68, so the ids its clients may subscribe to on eth/68 sentries are registered here.
+// Typed announcements of eth/68 peers are also delivered as plain hashes to the subscribers of eth/66 announcements, like the txpool.
+func init() {
+	ids := map[proto_sentry.MessageId]struct{}{
+		eth.MessageIdNewPooledTransactionHashes68:               {},
+		proto_sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66: {},
+	}
+	for id := range eth.FromProto[eth.ETH68] {
+		ids[id] = struct{}{}
+	}
+	direct.ProtoIds[eth.ETH68] = ids
+}
+
+// handleNewPooledTransactionHashes68 delivers the typed announcement of an eth/68 peer to the subscribers of
+// the eth/68 announcements as is and to the subscribers of the eth/66 ones as a list of hashes
+func handleNewPooledTransactionHashes68(
+	peerID [64]byte,
+	msg p2p.Msg,
+	send func(msgId proto_sentry.MessageId, peerID [64]byte, b []byte),
+	hasSubscribers func(msgId proto_sentry.MessageId) bool,
+) error {
+	typed, hashes := hasSubscribers(eth.MessageIdNewPooledTransactionHashes68), hasSubscribers(proto_sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66)
+	if !typed && !hashes {
+		return nil
+	}
+	b := make([]byte, msg.Size)
+	if _, err := io.ReadFull(msg.Payload, b); err != nil {
+		return fmt.Errorf("reading msg into bytes: %w", err)
+	}
+	var packet eth.NewPooledTransactionHashesPacket68
+	if err := rlp.DecodeBytes(b, &packet); err != nil {
+		return fmt.Errorf("decoding NewPooledTransactionHashes68: %w", err)
+	}
+	if err := packet.Validate(); err != nil {
+		return fmt.Errorf("invalid NewPooledTransactionHashes68: %w", err)
+	}
+	if typed {
+		send(eth.MessageIdNewPooledTransactionHashes68, peerID, b)
+	}
+	if hashes {
+		data, err := rlp.EncodeToBytes(eth.NewPooledTransactionHashesPacket(packet.Hashes))
+		if err != nil {
+			return err
+		}
+		send(proto_sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66, peerID, data)
+	}
+	return nil
+}
+
+// TxPoolSentries adapts the sentries for erigon-lib's txpool, which only talks to eth/66 and eth/67 sentries.
+// eth/68 sentries accept its eth/66 hash announcements and send them to the peers as typed ones.
+func TxPoolSentries(sentries []direct.SentryClient) []direct.SentryClient {
+	adapted := make([]direct.SentryClient, len(sentries))
+	for i, sentry := range sentries {
+		adapted[i] = sentry
+		if sentry.Protocol() >= eth.ETH68 {
+			adapted[i] = txPoolSentry{sentry}
+		}
+	}
+	return adapted
+}
+
+type txPoolSentry struct {
+	direct.SentryClient
+}
+
+func (txPoolSentry) Protocol() uint { return eth.ETH67 }
+
+// outboundData returns the payload of the message for the peers of the sentry, nil when there is nothing to send.
+// On eth/68 the hash announcements of the txpool get the types and the sizes of the transactions, the announced
+// transactions which already left the pool are dropped.
+func (ss *GrpcServer) outboundData(ctx context.Context, msg *proto_sentry.OutboundMessageData) ([]byte, error) {
+	if ss.Protocol.Version < eth.ETH68 || msg.Id != proto_sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66 {
+		return msg.Data, nil
+	}
+	var hashes eth.NewPooledTransactionHashesPacket
+	if err := rlp.DecodeBytes(msg.Data, &hashes); err != nil {
+		return nil, fmt.Errorf("decoding NewPooledTransactionHashes66: %w", err)
+	}
+	if ss.TxPool == nil {
+		return nil, fmt.Errorf("announcing transactions to eth/68 peers needs the txpool")
+	}
+	req := &txpool_proto.TransactionsRequest{Hashes: make([]*proto_types.H256, len(hashes))}
+	for i, hash := range hashes {
+		req.Hashes[i] = gointerfaces.ConvertHashToH256(hash)
+	}
+	reply, err := ss.TxPool.Transactions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(reply.RlpTxs) != len(hashes) {
+		return nil, fmt.Errorf("txpool returned %d transactions for %d hashes", len(reply.RlpTxs), len(hashes))
+	}
+	var announces eth.NewPooledTransactionHashesPacket68
+	for i, txRlp := range reply.RlpTxs {
+		if len(txRlp) == 0 {
+			continue
+		}
+		// typed transactions start with their type, legacy ones with an rlp list prefix
+		txType := byte(types.LegacyTxType)
+		if txRlp[0] < 0x80 {
+			txType = txRlp[0]
+		}
+		announces.Types = append(announces.Types, txType)
+		announces.Sizes = append(announces.Sizes, uint32(len(txRlp)))
+		announces.Hashes = append(announces.Hashes, hashes[i])
+	}
+	if len(announces.Hashes) == 0 {
+		return nil, nil
+	}
+	return rlp.EncodeToBytes(&announces)
+}
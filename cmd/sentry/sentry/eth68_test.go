@@ -0,0 +1,201 @@
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/direct"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	proto_sentry "github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/txpool"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// testPeer connects a remote peer to the sentry over a pipe and completes the handshake,
+// the returned end is the one of the remote peer
+func testPeer(t *testing.T, ss *GrpcServer, id byte) (p2p.MsgReadWriter, [64]byte) {
+	t.Helper()
+	local, remote := p2p.MsgPipe()
+	t.Cleanup(func() { local.Close() })
+	peerID := [64]byte{id}
+	peer := p2p.NewPeer(enode.ID{id}, peerID, "test", []p2p.Cap{{Name: eth.ProtocolName, Version: ss.Protocol.Version}})
+	go ss.Protocol.Run(peer, local) //nolint:errcheck
+
+	require.NoError(t, handShake(context.Background(), ss.GetStatus(), peerID, remote, ss.Protocol.Version, ss.Protocol.Version, nil))
+	// the sentry starts to sync from the peer
+	msg, err := remote.ReadMsg()
+	require.NoError(t, err)
+	require.Equal(t, uint64(eth.GetBlockHeadersMsg), msg.Code)
+	msg.Discard()
+	require.Eventually(t, func() bool { return ss.getPeer(peerID) != nil }, time.Second, 10*time.Millisecond)
+	return remote, peerID
+}
+
+func TestTxAnnouncementsMixedProtocols(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := memdb.NewTestDB(t)
+	gspec := &core.Genesis{Config: &params.ChainConfig{HomesteadBlock: big.NewInt(1), ChainID: big.NewInt(1)}}
+	genesis := gspec.MustCommit(db)
+	var statusData *proto_sentry.StatusData
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		statusData = testSentryServer(tx, gspec, genesis.Hash()).statusData
+		return nil
+	}))
+
+	announces := eth.NewPooledTransactionHashesPacket68{
+		Types:  []byte{types.LegacyTxType, types.DynamicFeeTxType},
+		Sizes:  []uint32{120, 1200},
+		Hashes: []common.Hash{{1}, {2}},
+	}
+	pooledTxs := testTxPool{txs: map[common.Hash][]byte{}}
+	var pooled eth.NewPooledTransactionHashesPacket68
+	for _, tx := range []types.Transaction{
+		types.NewTransaction(0, common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), nil),
+		types.NewEIP1559Transaction(*uint256.NewInt(1), 1, common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), uint256.NewInt(1), uint256.NewInt(1), nil),
+	} {
+		var buf bytes.Buffer
+		require.NoError(t, tx.MarshalBinary(&buf))
+		pooledTxs.txs[tx.Hash()] = buf.Bytes()
+		pooled.Types = append(pooled.Types, tx.Type())
+		pooled.Sizes = append(pooled.Sizes, uint32(buf.Len()))
+		pooled.Hashes = append(pooled.Hashes, tx.Hash())
+	}
+	// the last announced transaction has already left the pool
+	announced := append(append([]common.Hash{}, pooled.Hashes...), common.Hash{3})
+	var announcedHashes types2.Hashes
+	for _, hash := range announced {
+		announcedHashes = append(announcedHashes, hash[:]...)
+	}
+
+	for _, protocol := range []uint{eth.ETH66, eth.ETH67, eth.ETH68} {
+		protocol := protocol
+		t.Run(eth.ProtocolToString[protocol], func(t *testing.T) {
+			ss := NewGrpcServer(ctx, nil, func() *eth.NodeInfo { return nil }, &p2p.Config{}, protocol)
+			ss.statusData = statusData
+
+			// the txpool subscribes to the announcements of eth/66, eth/68 subscribers get the typed ones
+			ids := []proto_sentry.MessageId{proto_sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66, eth.MessageIdNewPooledTransactionHashes68}
+			client := direct.NewSentryClientDirect(protocol, ss)
+			stream, err := client.Messages(ctx, &proto_sentry.MessagesRequest{Ids: ids})
+			require.NoError(t, err)
+			require.Eventually(t, func() bool {
+				return ss.hasSubscribers(proto_sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66)
+			}, time.Second, 10*time.Millisecond)
+			if protocol >= eth.ETH68 {
+				require.True(t, ss.hasSubscribers(eth.MessageIdNewPooledTransactionHashes68))
+			} else {
+				require.False(t, ss.hasSubscribers(eth.MessageIdNewPooledTransactionHashes68))
+			}
+			reply, err := ss.HandShake(ctx, nil)
+			require.NoError(t, err)
+			require.Equal(t, map[uint]proto_sentry.Protocol{
+				eth.ETH66: proto_sentry.Protocol_ETH66,
+				eth.ETH67: proto_sentry.Protocol_ETH67,
+				eth.ETH68: eth.ProtocolETH68,
+			}[protocol], reply.Protocol)
+
+			remote, peerID := testPeer(t, ss, 1)
+
+			// inbound announcements reach the txpool as hashes whatever the protocol of the peer is
+			if protocol >= eth.ETH68 {
+				require.NoError(t, p2p.Send(remote, eth.NewPooledTransactionHashesMsg, &announces))
+			} else {
+				require.NoError(t, p2p.Send(remote, eth.NewPooledTransactionHashesMsg, eth.NewPooledTransactionHashesPacket(announces.Hashes)))
+			}
+			received := map[proto_sentry.MessageId][]byte{}
+			for len(received) < len(ids) {
+				msg, err := stream.Recv()
+				require.NoError(t, err)
+				require.Equal(t, gointerfaces.ConvertHashToH512(peerID), msg.PeerId)
+				received[msg.Id] = msg.Data
+				if protocol < eth.ETH68 {
+					break
+				}
+			}
+			var hashes eth.NewPooledTransactionHashesPacket
+			require.NoError(t, rlp.DecodeBytes(received[proto_sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66], &hashes))
+			require.Equal(t, announces.Hashes, []common.Hash(hashes))
+			if protocol >= eth.ETH68 {
+				var typed eth.NewPooledTransactionHashesPacket68
+				require.NoError(t, rlp.DecodeBytes(received[eth.MessageIdNewPooledTransactionHashes68], &typed))
+				require.Equal(t, announces, typed)
+			}
+
+			// the hash announcements of the txpool get the types and the sizes of the transactions on eth/68
+			ss.TxPool = pooledTxs
+			send := txpool.NewSend(ctx, TxPoolSentries([]direct.SentryClient{client}), nil)
+			send.AnnouncePooledTxs(announcedHashes)
+			readTxAnnouncement(t, remote, protocol, pooled, announced)
+			send.PropagatePooledTxsToPeersList([]types2.PeerID{gointerfaces.ConvertHashToH512(peerID)}, announcedHashes)
+			readTxAnnouncement(t, remote, protocol, pooled, announced)
+
+			// typed announcements are refused by the sentries older than eth/68
+			if protocol < eth.ETH68 {
+				data, err := rlp.EncodeToBytes(&announces)
+				require.NoError(t, err)
+				_, err = ss.SendMessageToAll(ctx, &proto_sentry.OutboundMessageData{Id: eth.MessageIdNewPooledTransactionHashes68, Data: data})
+				require.Error(t, err)
+			}
+
+			// eth/68 peers with inconsistent announcements are dropped
+			if protocol >= eth.ETH68 {
+				invalid := announces
+				invalid.Sizes = invalid.Sizes[:1]
+				require.NoError(t, p2p.Send(remote, eth.NewPooledTransactionHashesMsg, &invalid))
+				require.Eventually(t, func() bool { return ss.getPeer(peerID) == nil }, time.Second, 10*time.Millisecond)
+			}
+		})
+	}
+}
+
+// readTxAnnouncement reads the announcement the remote peer got: the pooled transactions with their types and
+// sizes on eth/68, all the announced hashes on the older protocols
+func readTxAnnouncement(t *testing.T, remote p2p.MsgReadWriter, protocol uint, pooled eth.NewPooledTransactionHashesPacket68, announced []common.Hash) {
+	t.Helper()
+	msg, err := remote.ReadMsg()
+	require.NoError(t, err)
+	require.Equal(t, uint64(eth.NewPooledTransactionHashesMsg), msg.Code)
+	if protocol >= eth.ETH68 {
+		var typed eth.NewPooledTransactionHashesPacket68
+		require.NoError(t, msg.Decode(&typed))
+		require.Equal(t, pooled, typed)
+	} else {
+		var hashes eth.NewPooledTransactionHashesPacket
+		require.NoError(t, msg.Decode(&hashes))
+		require.Equal(t, announced, []common.Hash(hashes))
+	}
+}
+
+// testTxPool serves the pooled transactions like the Transactions method of the txpool
+type testTxPool struct {
+	txpool_proto.TxpoolClient
+	txs map[common.Hash][]byte
+}
+
+func (p testTxPool) Transactions(_ context.Context, req *txpool_proto.TransactionsRequest, _ ...grpc.CallOption) (*txpool_proto.TransactionsReply, error) {
+	reply := &txpool_proto.TransactionsReply{RlpTxs: make([][]byte, len(req.Hashes))}
+	for i, hash := range req.Hashes {
+		reply.RlpTxs[i] = append([]byte{}, p.txs[gointerfaces.ConvertH256ToHash(hash)]...)
+	}
+	return reply, nil
+}
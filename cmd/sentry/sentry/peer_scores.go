@@ -0,0 +1,314 @@
+package sentry
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+const (
+	kickBan             = 5 * time.Minute // Ban of the first kick, each next one doubles it
+	maxKickBan          = 24 * time.Hour
+	scoreExpiration     = 30 * 24 * time.Hour // Scores of the peers not seen for that long are forgotten, unless they are banned for good
+	maxPeerScores       = 50_000              // Above it the scores of the least recently seen peers are forgotten too
+	scoresFlushInterval = time.Minute
+	bestDialCandidates  = 64 // How many of the best known peers are dialed again on start
+)
+
+type scoreKey struct {
+	id enode.ID
+	ip string // 16 byte form of the IP
+}
+
+func newScoreKey(id enode.ID, ip net.IP) scoreKey {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		ip16 = net.IPv6zero
+	}
+	return scoreKey{id: id, ip: string(ip16)}
+}
+
+// peerScores keeps the scores of the peers per enode ID and IP. They are persisted in the node database of the p2p
+// server, so the peers which were useless, slow or malicious are remembered across reconnects and restarts
+type peerScores struct {
+	lock   sync.Mutex
+	db     *enode.DB // nil until the p2p server is started
+	scores map[scoreKey]*p2p.PeerScore
+	dirty  map[scoreKey]struct{}
+	loaded chan struct{} // closed once the scores are loaded from the node database
+}
+
+func newPeerScores() *peerScores {
+	return &peerScores{scores: map[scoreKey]*p2p.PeerScore{}, dirty: map[scoreKey]struct{}{}, loaded: make(chan struct{})}
+}
+
+// open loads the scores from the node database, the ones of the peers not seen for long are dropped
+func (s *peerScores) open(db *enode.DB, now time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var undecodable []scoreKey
+	if err := db.ForEachPeerScore(func(id enode.ID, ip net.IP, enc []byte) error {
+		key := newScoreKey(id, ip)
+		var score p2p.PeerScore
+		if err := rlp.DecodeBytes(enc, &score); err != nil {
+			log.Debug("[p2p] Dropping undecodable peer score", "id", id, "ip", ip, "err", err)
+			undecodable = append(undecodable, key)
+			return nil
+		}
+		if _, ok := s.scores[key]; !ok {
+			s.scores[key] = &score
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	s.db = db
+	for _, key := range undecodable {
+		if err := s.forget(key); err != nil {
+			return err
+		}
+	}
+	if err := s.expire(now); err != nil {
+		return err
+	}
+	close(s.loaded)
+	return nil
+}
+
+// flush forgets the expired scores and writes the changed ones to the node database
+func (s *peerScores) flush(now time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	if err := s.expire(now); err != nil {
+		return err
+	}
+	for key := range s.dirty {
+		enc, err := rlp.EncodeToBytes(s.scores[key])
+		if err != nil {
+			return err
+		}
+		if err = s.db.UpdatePeerScore(key.id, net.IP(key.ip), enc); err != nil {
+			return err
+		}
+		delete(s.dirty, key)
+	}
+	return nil
+}
+
+// expire forgets the scores of the peers not seen for scoreExpiration, and of the least recently seen ones above
+// maxPeerScores. The permanent bans and the scores changed since the last flush, of the connected peers, are kept
+func (s *peerScores) expire(now time.Time) error {
+	var kept []scoreKey
+	for key, score := range s.scores {
+		if _, changed := s.dirty[key]; changed || score.BannedUntil == p2p.BannedForever {
+			continue
+		}
+		if now.Sub(time.Unix(int64(score.LastSeen), 0)) > scoreExpiration {
+			if err := s.forget(key); err != nil {
+				return err
+			}
+			continue
+		}
+		kept = append(kept, key)
+	}
+	if excess := len(s.scores) - maxPeerScores; excess > 0 {
+		sort.Slice(kept, func(i, j int) bool { return s.scores[kept[i]].LastSeen < s.scores[kept[j]].LastSeen })
+		if excess > len(kept) {
+			excess = len(kept)
+		}
+		for _, key := range kept[:excess] {
+			if err := s.forget(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// forget drops the score of the peer from the memory and the node database
+func (s *peerScores) forget(key scoreKey) error {
+	delete(s.scores, key)
+	delete(s.dirty, key)
+	return s.db.DeletePeerScore(key.id, net.IP(key.ip))
+}
+
+// close flushes the scores, the node database is closed along with the p2p server
+func (s *peerScores) close() error {
+	err := s.flush(time.Now())
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.db = nil
+	return err
+}
+
+// update applies f to the score of the peer, the score is created if the peer is unknown
+func (s *peerScores) update(id enode.ID, ip net.IP, f func(score *p2p.PeerScore)) {
+	key := newScoreKey(id, ip)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	score, ok := s.scores[key]
+	if !ok {
+		score = &p2p.PeerScore{}
+		s.scores[key] = score
+	}
+	f(score)
+	s.dirty[key] = struct{}{}
+}
+
+// get returns a copy of the score of the peer, nil if the peer is unknown
+func (s *peerScores) get(id enode.ID, ip net.IP) *p2p.PeerScore {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	score, ok := s.scores[newScoreKey(id, ip)]
+	if !ok {
+		return nil
+	}
+	cpy := *score
+	return &cpy
+}
+
+func (s *peerScores) value(id enode.ID, ip net.IP) float64 {
+	if score := s.get(id, ip); score != nil {
+		return score.Value()
+	}
+	return 0
+}
+
+func (s *peerScores) banned(id enode.ID, ip net.IP, now time.Time) bool {
+	score := s.get(id, ip)
+	return score != nil && score.Banned(now)
+}
+
+// dialable is the dial filter of the p2p server, banned peers aren't dialed
+func (s *peerScores) dialable(n *enode.Node) bool {
+	return !s.banned(n.ID(), n.IP(), time.Now())
+}
+
+// bestNodes returns up to n of the dialable nodes with the best positive scores, to be dialed again
+func (s *peerScores) bestNodes(n int, now time.Time) []*enode.Node {
+	type candidate struct {
+		node  *enode.Node
+		value float64
+	}
+	var candidates []candidate
+	s.lock.Lock()
+	for _, score := range s.scores {
+		if score.Enode == "" || score.Banned(now) || score.Value() <= 0 {
+			continue
+		}
+		node, err := enode.ParseV4(score.Enode)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{node: node, value: score.Value()})
+	}
+	s.lock.Unlock()
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].value > candidates[j].value })
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	nodes := make([]*enode.Node, len(candidates))
+	for i, c := range candidates {
+		nodes[i] = c.node
+	}
+	return nodes
+}
+
+// dialCandidates returns the source of the dial candidates with the best known peers,
+// it yields them once the scores are loaded
+func (s *peerScores) dialCandidates() enode.Iterator {
+	return &bestNodesIter{scores: s, closed: make(chan struct{})}
+}
+
+type bestNodesIter struct {
+	scores    *peerScores
+	nodes     []*enode.Node
+	cur       *enode.Node
+	loaded    bool
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (it *bestNodesIter) Next() bool {
+	if !it.loaded {
+		select {
+		case <-it.scores.loaded:
+		case <-it.closed:
+			return false
+		}
+		it.nodes, it.loaded = it.scores.bestNodes(bestDialCandidates, time.Now()), true
+	}
+	if len(it.nodes) == 0 {
+		it.cur = nil
+		return false
+	}
+	it.cur, it.nodes = it.nodes[0], it.nodes[1:]
+	return true
+}
+
+func (it *bestNodesIter) Node() *enode.Node {
+	return it.cur
+}
+
+func (it *bestNodesIter) Close() {
+	it.closeOnce.Do(func() { close(it.closed) })
+}
+
+// penalize records the invalid data of the peer and bans it, for good or for a while getting longer with each penalty
+func (s *peerScores) penalize(id enode.ID, ip net.IP, forever bool, now time.Time) {
+	s.update(id, ip, func(score *p2p.PeerScore) {
+		score.Invalid++
+		if forever {
+			score.BannedUntil = p2p.BannedForever
+			return
+		}
+		ban := maxKickBan
+		if score.Invalid <= 16 {
+			if ban = kickBan << (score.Invalid - 1); ban > maxKickBan {
+				ban = maxKickBan
+			}
+		}
+		if until := uint64(now.Add(ban).Unix()); until > score.BannedUntil {
+			score.BannedUntil = until
+		}
+	})
+}
+
+// peerIP returns the IP of the connection to the peer
+func peerIP(peer *p2p.Peer) net.IP {
+	if addr, ok := peer.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	return peer.Node().IP()
+}
+
+// deliveredCount returns the number of the headers or the bodies in the eth/66 response
+func deliveredCount(payload []byte) int {
+	_, packet, _, err := rlp.Split(payload)
+	if err != nil {
+		return 0
+	}
+	_, _, rest, err := rlp.Split(packet) // request id
+	if err != nil {
+		return 0
+	}
+	_, items, _, err := rlp.Split(rest)
+	if err != nil {
+		return 0
+	}
+	n, err := rlp.CountValues(items)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package sentry
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+func TestPeerScoresPenalize(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	scores := newPeerScores()
+	id, ip := enode.ID{1}, net.IP{10, 0, 0, 1}
+
+	scores.penalize(id, ip, false, now)
+	require.Equal(t, uint64(now.Add(kickBan).Unix()), scores.get(id, ip).BannedUntil)
+	scores.penalize(id, ip, false, now)
+	require.Equal(t, uint64(now.Add(2*kickBan).Unix()), scores.get(id, ip).BannedUntil)
+	require.True(t, scores.banned(id, ip, now))
+	require.False(t, scores.banned(id, ip, now.Add(maxKickBan)))
+	require.False(t, scores.banned(id, net.IP{10, 0, 0, 2}, now), "the ban is per IP")
+
+	for i := 0; i < 30; i++ {
+		scores.penalize(id, ip, false, now)
+	}
+	require.Equal(t, uint64(now.Add(maxKickBan).Unix()), scores.get(id, ip).BannedUntil)
+
+	scores.penalize(id, ip, true, now)
+	require.Equal(t, uint64(p2p.BannedForever), scores.get(id, ip).BannedUntil)
+	require.Equal(t, uint64(33), scores.get(id, ip).Invalid)
+}
+
+func TestPeerScoresPersistence(t *testing.T) {
+	db, err := enode.OpenDB("")
+	require.NoError(t, err)
+	defer db.Close()
+	now := time.Now()
+
+	key, _ := crypto.GenerateKey()
+	good := enode.NewV4(&key.PublicKey, net.IP{10, 0, 0, 1}, 30303, 30303)
+	old, banned := enode.ID{2}, enode.ID{3}
+
+	scores := newPeerScores()
+	require.NoError(t, scores.open(db, now))
+	scores.update(good.ID(), good.IP(), func(score *p2p.PeerScore) {
+		score.Enode, score.LastSeen, score.Headers = good.URLv4(), uint64(now.Unix()), 10_000
+	})
+	scores.update(old, nil, func(score *p2p.PeerScore) {
+		score.LastSeen = uint64(now.Add(-2 * scoreExpiration).Unix())
+	})
+	scores.penalize(banned, nil, true, now.Add(-2*scoreExpiration))
+	require.NoError(t, scores.close())
+
+	scores = newPeerScores()
+	require.NoError(t, scores.open(db, now))
+	require.Equal(t, 10.0, scores.value(good.ID(), good.IP()))
+	require.Nil(t, scores.get(old, nil), "expired score")
+	require.True(t, scores.banned(banned, nil, now), "permanent bans don't expire")
+	require.True(t, scores.dialable(good))
+
+	it := scores.dialCandidates()
+	defer it.Close()
+	require.True(t, it.Next())
+	require.Equal(t, good.ID(), it.Node().ID())
+	require.False(t, it.Next())
+}
+
+func TestPeerScoresExpireOnFlush(t *testing.T) {
+	db, err := enode.OpenDB("")
+	require.NoError(t, err)
+	defer db.Close()
+	now := time.Now()
+
+	scores := newPeerScores()
+	require.NoError(t, scores.open(db, now))
+	seen := func(id enode.ID, at time.Time) {
+		scores.update(id, nil, func(score *p2p.PeerScore) { score.LastSeen = uint64(at.Unix()) })
+	}
+	seen(enode.ID{1}, now)
+	seen(enode.ID{2}, now.Add(-scoreExpiration/2))
+	require.NoError(t, scores.flush(now))
+	require.NotNil(t, db.PeerScore(enode.ID{2}, nil))
+	scores.penalize(enode.ID{3}, nil, true, now)
+
+	// a month later only the changed scores and the permanent bans are left
+	later := now.Add(scoreExpiration)
+	seen(enode.ID{1}, later)
+	require.NoError(t, scores.flush(later))
+	require.Len(t, scores.scores, 2)
+	require.NotNil(t, scores.get(enode.ID{1}, nil))
+	require.Nil(t, scores.get(enode.ID{2}, nil))
+	require.True(t, scores.banned(enode.ID{3}, nil, later))
+	require.Nil(t, db.PeerScore(enode.ID{2}, nil), "expired score is deleted from the node database")
+}
+
+func TestPeerScoresLimit(t *testing.T) {
+	db, err := enode.OpenDB("")
+	require.NoError(t, err)
+	defer db.Close()
+	now := time.Now()
+
+	scores := newPeerScores()
+	require.NoError(t, scores.open(db, now))
+	for i := 0; i < maxPeerScores+10; i++ {
+		scores.scores[newScoreKey(enode.ID{byte(i), byte(i >> 8), byte(i >> 16)}, nil)] = &p2p.PeerScore{LastSeen: uint64(now.Unix()) + uint64(i)}
+	}
+	require.NoError(t, scores.flush(now))
+	require.Len(t, scores.scores, maxPeerScores)
+	require.Nil(t, scores.get(enode.ID{9}, nil), "least recently seen")
+	require.NotNil(t, scores.get(enode.ID{10}, nil))
+}
+
+func TestDeliveredCount(t *testing.T) {
+	payload, err := rlp.EncodeToBytes(&eth.BlockHeadersPacket66{RequestId: 1, BlockHeadersPacket: make(eth.BlockHeadersPacket, 0)})
+	require.NoError(t, err)
+	require.Equal(t, 0, deliveredCount(payload))
+
+	payload, err = rlp.EncodeToBytes(&eth.BlockBodiesRLPPacket66{RequestId: 1, BlockBodiesRLPPacket: eth.BlockBodiesRLPPacket{{0xc0}, {0xc0}, {0xc0}}})
+	require.NoError(t, err)
+	require.Equal(t, 3, deliveredCount(payload))
+	require.Equal(t, 0, deliveredCount([]byte{0x01}))
+}
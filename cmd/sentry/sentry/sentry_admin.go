@@ -0,0 +1,131 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+)
+
+// The sentry serves the ADMIN service of privateapi, the static and trusted peers it is given are kept in the node
+// database and added again when it restarts.
+
+var _ privateapi.AdminServer = (*GrpcServer)(nil) // compile-time interface check
+
+// adminNode parses the URL of the request, the p2p server must be started as the peers are kept in its node database
+func (ss *GrpcServer) adminNode(in *privateapi.AdminPeerRequest) (*enode.Node, *p2p.Server, error) {
+	ss.lock.RLock()
+	srv := ss.P2pServer
+	ss.lock.RUnlock()
+	if srv == nil {
+		return nil, nil, errors.New("p2p server was not started")
+	}
+	node, err := enode.Parse(enode.ValidSchemes, in.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid enode: %w", err)
+	}
+	return node, srv, nil
+}
+
+func (ss *GrpcServer) AdminAddPeer(_ context.Context, in *privateapi.AdminPeerRequest) (*privateapi.AdminPeerReply, error) {
+	node, srv, err := ss.adminNode(in)
+	if err != nil {
+		return nil, err
+	}
+	if err = srv.NodeDB().AddStaticNode(node); err != nil {
+		return nil, err
+	}
+	srv.AddPeer(node)
+	return &privateapi.AdminPeerReply{}, nil
+}
+
+func (ss *GrpcServer) AdminRemovePeer(_ context.Context, in *privateapi.AdminPeerRequest) (*privateapi.AdminPeerReply, error) {
+	node, srv, err := ss.adminNode(in)
+	if err != nil {
+		return nil, err
+	}
+	if err = srv.NodeDB().RemoveStaticNode(node.ID()); err != nil {
+		return nil, err
+	}
+	srv.RemovePeer(node)
+	return &privateapi.AdminPeerReply{}, nil
+}
+
+func (ss *GrpcServer) AdminAddTrustedPeer(_ context.Context, in *privateapi.AdminPeerRequest) (*privateapi.AdminPeerReply, error) {
+	node, srv, err := ss.adminNode(in)
+	if err != nil {
+		return nil, err
+	}
+	if err = srv.NodeDB().AddTrustedNode(node); err != nil {
+		return nil, err
+	}
+	srv.AddTrustedPeer(node)
+	return &privateapi.AdminPeerReply{}, nil
+}
+
+func (ss *GrpcServer) AdminRemoveTrustedPeer(_ context.Context, in *privateapi.AdminPeerRequest) (*privateapi.AdminPeerReply, error) {
+	node, srv, err := ss.adminNode(in)
+	if err != nil {
+		return nil, err
+	}
+	if err = srv.NodeDB().RemoveTrustedNode(node.ID()); err != nil {
+		return nil, err
+	}
+	srv.RemoveTrustedPeer(node)
+	return &privateapi.AdminPeerReply{}, nil
+}
+
+// AdminBanPeer disconnects the peer and bans it for good, unlike PenalizePeer which bans it for a while
+func (ss *GrpcServer) AdminBanPeer(_ context.Context, in *privateapi.AdminBanPeerRequest) (*privateapi.AdminPeerReply, error) {
+	if len(in.PeerID) != 64 {
+		return nil, fmt.Errorf("invalid peer id length: %d", len(in.PeerID))
+	}
+	var peerID [64]byte
+	copy(peerID[:], in.PeerID)
+	if peerInfo := ss.getPeer(peerID); peerInfo != nil {
+		ss.scores.penalize(peerInfo.peer.ID(), peerInfo.ip, true, time.Now())
+	}
+	ss.removePeer(peerID)
+	return &privateapi.AdminPeerReply{}, nil
+}
+
+// AdminPeerScores returns the scores of the connected peers
+func (ss *GrpcServer) AdminPeerScores(_ context.Context, _ *privateapi.AdminPeerScoresRequest) (*privateapi.AdminPeerScoresReply, error) {
+	reply := &privateapi.AdminPeerScoresReply{Scores: map[string]*p2p.PeerScore{}}
+	ss.rangePeers(func(peerInfo *PeerInfo) bool {
+		if score := ss.scores.get(peerInfo.peer.ID(), peerInfo.ip); score != nil {
+			reply.Scores[peerInfo.peer.ID().String()] = score
+		}
+		return true
+	})
+	return reply, nil
+}
+
+// restoreAdminPeers adds the peers of the previous runs to the started p2p server
+func (ss *GrpcServer) restoreAdminPeers() error {
+	db := ss.P2pServer.NodeDB()
+	static, err := db.StaticNodes()
+	if err != nil {
+		return fmt.Errorf("loading static peers: %w", err)
+	}
+	for _, node := range static {
+		ss.P2pServer.AddPeer(node)
+	}
+	trusted, err := db.TrustedNodes()
+	if err != nil {
+		return fmt.Errorf("loading trusted peers: %w", err)
+	}
+	for _, node := range trusted {
+		ss.P2pServer.AddTrustedPeer(node)
+	}
+	if len(static)+len(trusted) > 0 {
+		log.Info("[p2p] Restored peers added by admin", "static", len(static), "trusted", len(trusted))
+	}
+	return nil
+}
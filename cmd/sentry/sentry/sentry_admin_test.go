@@ -0,0 +1,85 @@
+package sentry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+)
+
+func TestAdminPeersPersist(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+
+	start := func() *GrpcServer {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		ss := NewGrpcServer(ctx, nil, func() *eth.NodeInfo { return nil }, &p2p.Config{}, eth.ETH66)
+		ss.P2pServer = &p2p.Server{Config: p2p.Config{
+			Name:         "test",
+			MaxPeers:     1,
+			ListenAddr:   "127.0.0.1:0",
+			NoDiscovery:  true,
+			PrivateKey:   key,
+			NodeDatabase: dir,
+		}}
+		require.NoError(t, ss.P2pServer.Start(ctx))
+		require.NoError(t, ss.restoreAdminPeers())
+		return ss
+	}
+
+	static := "enode://d860a01f9722d78051619d1e2351aba3f43f943f6f00718d1b9baa4101932a1f5011f16bb2b1bb35db20d6fe28fa0bf09636d26a87d31de9ec6203eeedb1f666@127.0.0.1:30303"
+	trusted := "enode://22a8232c3abc76a16ae9d6c3b164f98775fe226f0917b0ca871128a74a8e9630b458460865bab457221f1d448dd9791d24c4e5d88786180ac185df813a68d4de@127.0.0.1:30304"
+
+	ss := start()
+	admin := privateapi.NewAdminClientDirect(ss)
+	_, err := admin.AdminAddPeer(ctx, &privateapi.AdminPeerRequest{URL: static})
+	require.NoError(t, err)
+	_, err = admin.AdminAddTrustedPeer(ctx, &privateapi.AdminPeerRequest{URL: trusted})
+	require.NoError(t, err)
+	_, err = admin.AdminAddPeer(ctx, &privateapi.AdminPeerRequest{URL: "enode://nonsense"})
+	require.ErrorContains(t, err, "invalid enode")
+	ss.Close()
+
+	ss = start()
+	defer ss.Close()
+	nodes, err := ss.P2pServer.NodeDB().StaticNodes()
+	require.NoError(t, err)
+	require.Equal(t, []string{static}, urls(nodes))
+	nodes, err = ss.P2pServer.NodeDB().TrustedNodes()
+	require.NoError(t, err)
+	require.Equal(t, []string{trusted}, urls(nodes))
+
+	admin = privateapi.NewAdminClientDirect(ss)
+	_, err = admin.AdminRemovePeer(ctx, &privateapi.AdminPeerRequest{URL: static})
+	require.NoError(t, err)
+	_, err = admin.AdminRemoveTrustedPeer(ctx, &privateapi.AdminPeerRequest{URL: trusted})
+	require.NoError(t, err)
+	nodes, err = ss.P2pServer.NodeDB().StaticNodes()
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+	nodes, err = ss.P2pServer.NodeDB().TrustedNodes()
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+}
+
+func TestAdminBeforeStart(t *testing.T) {
+	ss := NewGrpcServer(context.Background(), nil, func() *eth.NodeInfo { return nil }, &p2p.Config{}, eth.ETH66)
+	_, err := privateapi.NewAdminClientDirect(ss).AdminAddPeer(context.Background(), &privateapi.AdminPeerRequest{URL: "enode://x"})
+	require.ErrorContains(t, err, "p2p server was not started")
+}
+
+func urls(nodes []*enode.Node) []string {
+	res := make([]string, len(nodes))
+	for i, n := range nodes {
+		res[i] = n.URLv4()
+	}
+	return res
+}
@@ -9,6 +9,7 @@ import (
 	proto_sentry "github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/stages/bodydownload"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
@@ -45,7 +46,7 @@ func (cs *MultiClient) SendBodyRequest(ctx context.Context, req *bodydownload.Bo
 		}
 
 		switch cs.sentries[i].Protocol() {
-		case eth.ETH66, eth.ETH67:
+		case eth.ETH66, eth.ETH67, eth.ETH68:
 			//log.Info(fmt.Sprintf("Sending body request for %v", req.BlockNums))
 			var bytes []byte
 			var err error
@@ -87,7 +88,7 @@ func (cs *MultiClient) SendHeaderRequest(ctx context.Context, req *headerdownloa
 			continue
 		}
 		switch cs.sentries[i].Protocol() {
-		case eth.ETH66, eth.ETH67:
+		case eth.ETH66, eth.ETH67, eth.ETH68:
 			//log.Info(fmt.Sprintf("Sending header request {hash: %x, height: %d, length: %d}", req.Hash, req.Number, req.Length))
 			reqData := &eth.GetBlockHeadersPacket66{
 				RequestId: rand.Uint64(), // nolint: gosec
@@ -155,11 +156,22 @@ func (cs *MultiClient) Penalize(ctx context.Context, penalties []headerdownload.
 			PeerId:  gointerfaces.ConvertHashToH512(penalties[i].PeerID),
 			Penalty: proto_sentry.PenaltyKind_Kick, // TODO: Extend penalty kinds
 		}
+		var ban *privateapi.AdminBanPeerRequest
+		if penalties[i].Penalty == headerdownload.InvalidSealPenalty {
+			// forged seals can't be honest mistakes, the sentries serving ADMIN ban the peer for good
+			ban = &privateapi.AdminBanPeerRequest{PeerID: penalties[i].PeerID[:]}
+		}
 		for i, ok, next := cs.randSentryIndex(); ok; i, ok = next() {
 			if !cs.sentries[i].Ready() {
 				continue
 			}
 
+			if admin, ok := cs.sentries[i].(privateapi.AdminClient); ok && ban != nil {
+				if _, err1 := admin.AdminBanPeer(ctx, ban, &grpc.EmptyCallOption{}); err1 != nil {
+					log.Error("Could not ban peer", "err", err1)
+				}
+				continue
+			}
 			if _, err1 := cs.sentries[i].PenalizePeer(ctx, &outreq, &grpc.EmptyCallOption{}); err1 != nil {
 				log.Error("Could not send penalty", "err", err1)
 			}
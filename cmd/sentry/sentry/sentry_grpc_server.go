@@ -24,12 +24,14 @@ import (
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/grpcutil"
 	proto_sentry "github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	proto_types "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/core/forkid"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/p2p/dnsdisc"
 	"github.com/ledgerwatch/erigon/p2p/enode"
@@ -55,9 +57,12 @@ type PeerInfo struct {
 	peer          *p2p.Peer
 	lock          sync.RWMutex
 	deadlines     []time.Time // Request deadlines
+	requested     []time.Time // Times the requests with deadlines were sent at, in the order of the deadlines
 	latestDealine time.Time
 	height        uint64
 	rw            p2p.MsgReadWriter
+	scores        *peerScores // Set for the peers run by the sentry
+	ip            net.IP
 
 	removed    chan struct{} // close this channel on remove
 	ctx        context.Context
@@ -72,6 +77,7 @@ type PeerInfo struct {
 type PeerRef struct {
 	pi     *PeerInfo
 	height uint64
+	score  float64
 }
 
 // PeersByMinBlock is the priority queue of peers. Used to select certain number of peers considered to be "best available"
@@ -82,8 +88,11 @@ func (bp PeersByMinBlock) Len() int {
 	return len(bp)
 }
 
-// Less (part of heap.Interface) compares two peers
+// Less (part of heap.Interface) compares two peers, the score decides between the peers of the same height
 func (bp PeersByMinBlock) Less(i, j int) bool {
+	if bp[i].height == bp[j].height {
+		return bp[i].score < bp[j].score
+	}
 	return bp[i].height < bp[j].height
 }
 
@@ -148,6 +157,7 @@ func (pi *PeerInfo) AddDeadline(deadline time.Time) {
 	pi.lock.Lock()
 	defer pi.lock.Unlock()
 	pi.deadlines = append(pi.deadlines, deadline)
+	pi.requested = append(pi.requested, time.Now())
 	pi.latestDealine = deadline
 }
 
@@ -168,20 +178,60 @@ func (pi *PeerInfo) SetIncreasedHeight(newHeight uint64) {
 // ClearDeadlines goes through the deadlines of
 // given peers and removes the ones that have passed
 // Optionally, it also clears one extra deadline - this is used when response is received
+// The passed deadlines count as timeouts in the score of the peer, the cleared extra one gives the latency
 // It returns the number of deadlines left
 func (pi *PeerInfo) ClearDeadlines(now time.Time, givePermit bool) int {
 	pi.lock.Lock()
-	defer pi.lock.Unlock()
 	// Look for the first deadline which is not passed yet
 	firstNotPassed := sort.Search(len(pi.deadlines), func(i int) bool {
 		return pi.deadlines[i].After(now)
 	})
 	cutOff := firstNotPassed
-	if cutOff < len(pi.deadlines) && givePermit {
+	var latency time.Duration
+	answered := cutOff < len(pi.deadlines) && givePermit
+	if answered {
+		latency = now.Sub(pi.requested[cutOff])
 		cutOff++
 	}
 	pi.deadlines = pi.deadlines[cutOff:]
-	return len(pi.deadlines)
+	pi.requested = pi.requested[cutOff:]
+	left := len(pi.deadlines)
+	pi.lock.Unlock()
+
+	if pi.scores != nil && (firstNotPassed > 0 || answered) {
+		pi.scores.update(pi.peer.ID(), pi.ip, func(score *p2p.PeerScore) {
+			score.Timeouts += uint64(firstNotPassed)
+			if answered {
+				// moving average, a new response time weighs an eighth
+				ms := uint64(latency.Milliseconds())
+				if score.Latency == 0 {
+					score.Latency = ms
+				} else {
+					score.Latency = (score.Latency*7 + ms) / 8
+				}
+			}
+		})
+	}
+	return left
+}
+
+// addDelivered records the headers and the bodies delivered by the peer in its score
+func (pi *PeerInfo) addDelivered(headers, bodies int) {
+	if pi.scores == nil || headers+bodies == 0 {
+		return
+	}
+	pi.scores.update(pi.peer.ID(), pi.ip, func(score *p2p.PeerScore) {
+		score.Headers += uint64(headers)
+		score.Bodies += uint64(bodies)
+	})
+}
+
+// Score rates the peer, see p2p.PeerScore
+func (pi *PeerInfo) Score() float64 {
+	if pi.scores == nil {
+		return 0
+	}
+	return pi.scores.value(pi.peer.ID(), pi.ip)
 }
 
 func (pi *PeerInfo) LatestDeadline() time.Time {
@@ -245,7 +295,7 @@ func ConvertH512ToPeerID(h512 *proto_types.H512) [64]byte {
 func makeP2PServer(
 	p2pConfig p2p.Config,
 	genesisHash common.Hash,
-	protocol p2p.Protocol,
+	protocols []p2p.Protocol,
 ) (*p2p.Server, error) {
 	var urls []string
 	chainConfig := params.ChainConfigByGenesisHash(genesisHash)
@@ -260,7 +310,7 @@ func makeP2PServer(
 		p2pConfig.BootstrapNodes = bootstrapNodes
 		p2pConfig.BootstrapNodesV5 = bootstrapNodes
 	}
-	p2pConfig.Protocols = []p2p.Protocol{protocol}
+	p2pConfig.Protocols = protocols
 	return &p2p.Server{Config: p2pConfig}, nil
 }
 
@@ -392,6 +442,7 @@ func runPeer(
 			if _, err := io.ReadFull(msg.Payload, b); err != nil {
 				log.Error(fmt.Sprintf("%s: reading msg into bytes: %v", peerID, err))
 			}
+			peerInfo.addDelivered(deliveredCount(b), 0)
 			send(eth.ToProto[protocol][msg.Code], peerID, b)
 		case eth.GetBlockBodiesMsg:
 			if !hasSubscribers(eth.ToProto[protocol][msg.Code]) {
@@ -411,6 +462,7 @@ func runPeer(
 			if _, err := io.ReadFull(msg.Payload, b); err != nil {
 				log.Error(fmt.Sprintf("%s: reading msg into bytes: %v", peerID, err))
 			}
+			peerInfo.addDelivered(0, deliveredCount(b))
 			send(eth.ToProto[protocol][msg.Code], peerID, b)
 		case eth.GetNodeDataMsg:
 			if protocol >= eth.ETH67 {
@@ -465,6 +517,13 @@ func runPeer(
 			}
 			send(eth.ToProto[protocol][msg.Code], peerID, b)
 		case eth.NewPooledTransactionHashesMsg:
+			if protocol >= eth.ETH68 {
+				if err := handleNewPooledTransactionHashes68(peerID, msg, send, hasSubscribers); err != nil {
+					msg.Discard()
+					return fmt.Errorf("%s: %w", peerID, err)
+				}
+				break
+			}
 			if !hasSubscribers(eth.ToProto[protocol][msg.Code]) {
 				continue
 			}
@@ -530,6 +589,7 @@ func grpcSentryServer(ctx context.Context, sentryAddr string, ss *GrpcServer, he
 	}
 	grpcServer := grpcutil.NewServer(100, nil)
 	proto_sentry.RegisterSentryServer(grpcServer, ss)
+	privateapi.RegisterAdminServer(grpcServer, ss)
 	var healthServer *health.Server
 	if healthCheck {
 		healthServer = health.NewServer()
@@ -552,9 +612,10 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 		ctx:          ctx,
 		p2p:          cfg,
 		peersStreams: NewPeersStreams(),
+		scores:       newPeerScores(),
 	}
 
-	if protocol != eth.ETH66 && protocol != eth.ETH67 {
+	if protocol != eth.ETH66 && protocol != eth.ETH67 && protocol != eth.ETH68 {
 		panic(fmt.Errorf("unexpected p2p protocol: %d", protocol))
 	}
 
@@ -572,7 +633,21 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 			}
 			log.Trace(fmt.Sprintf("[%s] Start with peer", printablePeerID))
 
+			now, ip, network := time.Now(), peerIP(peer), peer.Info().Network
+			if ss.scores.banned(peer.ID(), ip, now) && !network.Trusted && !network.Static {
+				log.Trace(fmt.Sprintf("[%s] Peer is banned", printablePeerID))
+				return p2p.DiscUselessPeer
+			}
+			ss.scores.update(peer.ID(), ip, func(score *p2p.PeerScore) {
+				score.LastSeen = uint64(now.Unix())
+				if !network.Inbound {
+					// the ports of the inbound connections aren't the ones to dial
+					score.Enode = peer.Node().URLv4()
+				}
+			})
+
 			peerInfo := NewPeerInfo(peer, rw)
+			peerInfo.scores, peerInfo.ip = ss.scores, ip
 			defer peerInfo.Close()
 
 			defer ss.GoodPeers.Delete(peerID)
@@ -633,6 +708,8 @@ type GrpcServer struct {
 	proto_sentry.UnimplementedSentryServer
 	ctx                  context.Context
 	Protocol             p2p.Protocol
+	ExtraProtocols       []p2p.Protocol            // served alongside eth by the same p2p server, like snap
+	TxPool               txpool_proto.TxpoolClient // types and sizes of the transactions announced to eth/68 peers
 	discoveryDNS         []string
 	GoodPeers            sync.Map
 	statusData           *proto_sentry.StatusData
@@ -644,6 +721,7 @@ type GrpcServer struct {
 	messageStreamsLock   sync.RWMutex
 	peersStreams         *PeersStreams
 	p2p                  *p2p.Config
+	scores               *peerScores
 }
 
 func (ss *GrpcServer) rangePeers(f func(peerInfo *PeerInfo) bool) {
@@ -695,7 +773,7 @@ func (ss *GrpcServer) writePeer(logPrefix string, peerInfo *PeerInfo, msgcode ui
 
 func (ss *GrpcServer) startSync(ctx context.Context, bestHash common.Hash, peerID [64]byte) error {
 	switch ss.Protocol.Version {
-	case eth.ETH66, eth.ETH67:
+	case eth.ETH66, eth.ETH67, eth.ETH68:
 		b, err := rlp.EncodeToBytes(&eth.GetBlockHeadersPacket66{
 			RequestId: rand.Uint64(), // nolint: gosec
 			GetBlockHeadersPacket: &eth.GetBlockHeadersPacket{
@@ -721,9 +799,13 @@ func (ss *GrpcServer) startSync(ctx context.Context, bestHash common.Hash, peerI
 	return nil
 }
 
+// PenalizePeer disconnects the peer and bans it, for a while or for good depending on the penalty
 func (ss *GrpcServer) PenalizePeer(_ context.Context, req *proto_sentry.PenalizePeerRequest) (*emptypb.Empty, error) {
 	//log.Warn("Received penalty", "kind", req.GetPenalty().Descriptor().FullName, "from", fmt.Sprintf("%s", req.GetPeerId()))
 	peerID := ConvertH512ToPeerID(req.PeerId)
+	if peerInfo := ss.getPeer(peerID); peerInfo != nil {
+		ss.scores.penalize(peerInfo.peer.ID(), peerInfo.ip, false, time.Now())
+	}
 	ss.removePeer(peerID)
 	return &emptypb.Empty{}, nil
 }
@@ -757,7 +839,7 @@ func (ss *GrpcServer) findBestPeersWithPermit(peerCount int) []*PeerInfo {
 		height := peerInfo.Height()
 		//fmt.Printf("%d deadlines for peer %s\n", deadlines, peerID)
 		if deadlines < maxPermitsPerPeer {
-			heap.Push(&byMinBlock, PeerRef{pi: peerInfo, height: height})
+			heap.Push(&byMinBlock, PeerRef{pi: peerInfo, height: height, score: peerInfo.Score()})
 			if byMinBlock.Len() > peerCount {
 				// Remove the worst peer
 				peerRef := heap.Pop(&byMinBlock).(PeerRef)
@@ -830,7 +912,7 @@ func (ss *GrpcServer) SendMessageByMinBlock(_ context.Context, inreq *proto_sent
 	return reply, nil
 }
 
-func (ss *GrpcServer) SendMessageById(_ context.Context, inreq *proto_sentry.SendMessageByIdRequest) (*proto_sentry.SentPeers, error) {
+func (ss *GrpcServer) SendMessageById(ctx context.Context, inreq *proto_sentry.SendMessageByIdRequest) (*proto_sentry.SentPeers, error) {
 	reply := &proto_sentry.SentPeers{}
 	msgcode := eth.FromProto[ss.Protocol.Version][inreq.Data.Id]
 	if msgcode != eth.GetBlockHeadersMsg &&
@@ -852,7 +934,11 @@ func (ss *GrpcServer) SendMessageById(_ context.Context, inreq *proto_sentry.Sen
 		return reply, nil
 	}
 
-	ss.writePeer("sendMessageById", peerInfo, msgcode, inreq.Data.Data, 0)
+	data, err := ss.outboundData(ctx, inreq.Data)
+	if err != nil || data == nil {
+		return reply, err
+	}
+	ss.writePeer("sendMessageById", peerInfo, msgcode, data, 0)
 	reply.Peers = []*proto_types.H512{inreq.PeerId}
 	return reply, nil
 }
@@ -867,6 +953,10 @@ func (ss *GrpcServer) SendMessageToRandomPeers(ctx context.Context, req *proto_s
 		msgcode != eth.TransactionsMsg {
 		return reply, fmt.Errorf("sendMessageToRandomPeers not implemented for message Id: %s", req.Data.Id)
 	}
+	data, err := ss.outboundData(ctx, req.Data)
+	if err != nil || data == nil {
+		return reply, err
+	}
 
 	peerInfos := make([]*PeerInfo, 0, 32) // 32 gives capacity for 1024 peers, well beyond default
 	ss.rangePeers(func(peerInfo *PeerInfo) bool {
@@ -886,7 +976,7 @@ func (ss *GrpcServer) SendMessageToRandomPeers(ctx context.Context, req *proto_s
 	var lastErr error
 	// Send the block to a subset of our peers at random
 	for _, peerInfo := range peerInfos[:peersToSendCount] {
-		ss.writePeer("sendMessageToRandomPeers", peerInfo, msgcode, req.Data.Data, 0)
+		ss.writePeer("sendMessageToRandomPeers", peerInfo, msgcode, data, 0)
 		reply.Peers = append(reply.Peers, gointerfaces.ConvertHashToH512(peerInfo.ID()))
 	}
 	return reply, lastErr
@@ -901,10 +991,14 @@ func (ss *GrpcServer) SendMessageToAll(ctx context.Context, req *proto_sentry.Ou
 		msgcode != eth.NewBlockHashesMsg {
 		return reply, fmt.Errorf("sendMessageToAll not implemented for message Id: %s", req.Id)
 	}
+	data, err := ss.outboundData(ctx, req)
+	if err != nil || data == nil {
+		return reply, err
+	}
 
 	var lastErr error
 	ss.rangePeers(func(peerInfo *PeerInfo) bool {
-		ss.writePeer("SendMessageToAll", peerInfo, msgcode, req.Data, 0)
+		ss.writePeer("SendMessageToAll", peerInfo, msgcode, data, 0)
 		reply.Peers = append(reply.Peers, gointerfaces.ConvertHashToH512(peerInfo.ID()))
 		return true
 	})
@@ -918,6 +1012,9 @@ func (ss *GrpcServer) HandShake(context.Context, *emptypb.Empty) (*proto_sentry.
 		reply.Protocol = proto_sentry.Protocol_ETH66
 	case eth.ETH67:
 		reply.Protocol = proto_sentry.Protocol_ETH67
+	case eth.ETH68:
+		// remote clients of erigon-lib don't know this value yet, eth/68 sentries are only usable in-process
+		reply.Protocol = eth.ProtocolETH68
 	}
 	return reply, nil
 }
@@ -941,12 +1038,21 @@ func (ss *GrpcServer) SetStatus(ctx context.Context, statusData *proto_sentry.St
 			if err != nil {
 				return nil, err
 			}
+			// the best of the known peers are dialed again
+			candidates := enode.NewFairMix(time.Second)
+			candidates.AddSource(ss.scores.dialCandidates())
+			if ss.Protocol.DialCandidates != nil {
+				candidates.AddSource(ss.Protocol.DialCandidates)
+			}
+			ss.Protocol.DialCandidates = candidates
 		}
 
-		srv, err := makeP2PServer(*ss.p2p, genesisHash, ss.Protocol)
+		// eth goes first, the version of the first protocol is the one announced in the dial candidates
+		srv, err := makeP2PServer(*ss.p2p, genesisHash, append([]p2p.Protocol{ss.Protocol}, ss.ExtraProtocols...))
 		if err != nil {
 			return reply, err
 		}
+		srv.DialFilter = ss.scores.dialable
 
 		// Add protocol
 		if err = srv.Start(ss.ctx); err != nil {
@@ -955,6 +1061,12 @@ func (ss *GrpcServer) SetStatus(ctx context.Context, statusData *proto_sentry.St
 		}
 
 		ss.P2pServer = srv
+		if err = ss.startScores(); err != nil {
+			return reply, err
+		}
+		if err = ss.restoreAdminPeers(); err != nil {
+			return reply, err
+		}
 	}
 
 	ss.P2pServer.LocalNode().Set(eth.CurrentENREntryFromForks(statusData.ForkData.HeightForks, statusData.ForkData.TimeForks, genesisHash, statusData.MaxBlockHeight, statusData.MaxBlockTime))
@@ -970,30 +1082,35 @@ func (ss *GrpcServer) Peers(_ context.Context, _ *emptypb.Empty) (*proto_sentry.
 		return nil, errors.New("p2p server was not started")
 	}
 
-	peers := ss.P2pServer.PeersInfo()
+	peers := ss.P2pServer.Peers()
 
 	var reply proto_sentry.PeersReply
 	reply.Peers = make([]*proto_types.PeerInfo, 0, len(peers))
 
 	for _, peer := range peers {
-		rpcPeer := proto_types.PeerInfo{
-			Id:             peer.ID,
-			Name:           peer.Name,
-			Enode:          peer.Enode,
-			Enr:            peer.ENR,
-			Caps:           peer.Caps,
-			ConnLocalAddr:  peer.Network.LocalAddress,
-			ConnRemoteAddr: peer.Network.RemoteAddress,
-			ConnIsInbound:  peer.Network.Inbound,
-			ConnIsTrusted:  peer.Network.Trusted,
-			ConnIsStatic:   peer.Network.Static,
-		}
-		reply.Peers = append(reply.Peers, &rpcPeer)
+		reply.Peers = append(reply.Peers, peerInfo(peer))
 	}
 
 	return &reply, nil
 }
 
+// peerInfo describes the peer for the replies
+func peerInfo(p *p2p.Peer) *proto_types.PeerInfo {
+	peer := p.Info()
+	return &proto_types.PeerInfo{
+		Id:             peer.ID,
+		Name:           peer.Name,
+		Enode:          peer.Enode,
+		Enr:            peer.ENR,
+		Caps:           peer.Caps,
+		ConnLocalAddr:  peer.Network.LocalAddress,
+		ConnRemoteAddr: peer.Network.RemoteAddress,
+		ConnIsInbound:  peer.Network.Inbound,
+		ConnIsTrusted:  peer.Network.Trusted,
+		ConnIsStatic:   peer.Network.Static,
+	}
+}
+
 func (ss *GrpcServer) SimplePeerCount() (pc int) {
 	ss.rangePeers(func(peerInfo *PeerInfo) bool {
 		pc++
@@ -1013,19 +1130,7 @@ func (ss *GrpcServer) PeerById(_ context.Context, req *proto_sentry.PeerByIdRequ
 	sentryPeer := ss.getPeer(peerID)
 
 	if sentryPeer != nil {
-		peer := sentryPeer.peer.Info()
-		rpcPeer = &proto_types.PeerInfo{
-			Id:             peer.ID,
-			Name:           peer.Name,
-			Enode:          peer.Enode,
-			Enr:            peer.ENR,
-			Caps:           peer.Caps,
-			ConnLocalAddr:  peer.Network.LocalAddress,
-			ConnRemoteAddr: peer.Network.RemoteAddress,
-			ConnIsInbound:  peer.Network.Inbound,
-			ConnIsTrusted:  peer.Network.Trusted,
-			ConnIsStatic:   peer.Network.Static,
-		}
+		rpcPeer = peerInfo(sentryPeer.peer)
 	}
 
 	return &proto_sentry.PeerByIdReply{Peer: rpcPeer}, nil
@@ -1128,9 +1233,35 @@ func (ss *GrpcServer) Messages(req *proto_sentry.MessagesRequest, server proto_s
 	}
 }
 
+// startScores loads the scores of the peers from the node database of the started p2p server
+// and persists them periodically
+func (ss *GrpcServer) startScores() error {
+	if err := ss.scores.open(ss.P2pServer.NodeDB(), time.Now()); err != nil {
+		return fmt.Errorf("loading peer scores: %w", err)
+	}
+	go func() {
+		ticker := time.NewTicker(scoresFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ss.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := ss.scores.flush(time.Now()); err != nil {
+					log.Warn("[p2p] Saving peer scores failed", "err", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
 // Close performs cleanup operations for the sentry
 func (ss *GrpcServer) Close() {
 	if ss.P2pServer != nil {
+		if err := ss.scores.close(); err != nil {
+			log.Warn("[p2p] Saving peer scores failed", "err", err)
+		}
 		ss.P2pServer.Stop()
 	}
 }
@@ -34,6 +34,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/engineapi"
@@ -793,7 +794,26 @@ func (cs *MultiClient) makeStatusData() *proto_sentry.StatusData {
 	}
 }
 
-func GrpcClient(ctx context.Context, sentryAddr string) (*direct.SentryClientRemote, error) {
+// SentryClientRemote is the client of a sentry in another process, with its ADMIN service
+type SentryClientRemote struct {
+	*direct.SentryClientRemote
+	privateapi.AdminClient
+}
+
+// SentryClientLocal is the client of a sentry in the same process, with its ADMIN service
+type SentryClientLocal struct {
+	*direct.SentryClientDirect
+	privateapi.AdminClient
+}
+
+func NewSentryClientLocal(protocol uint, server *GrpcServer) *SentryClientLocal {
+	return &SentryClientLocal{
+		SentryClientDirect: direct.NewSentryClientDirect(protocol, server),
+		AdminClient:        privateapi.NewAdminClientDirect(server),
+	}
+}
+
+func GrpcClient(ctx context.Context, sentryAddr string) (*SentryClientRemote, error) {
 	// creating grpc client connection
 	var dialOpts []grpc.DialOption
 
@@ -811,5 +831,8 @@ func GrpcClient(ctx context.Context, sentryAddr string) (*direct.SentryClientRem
 	if err != nil {
 		return nil, fmt.Errorf("creating client connection to sentry P2P: %w", err)
 	}
-	return direct.NewSentryClientRemote(proto_sentry.NewSentryClient(conn)), nil
+	return &SentryClientRemote{
+		SentryClientRemote: direct.NewSentryClientRemote(proto_sentry.NewSentryClient(conn)),
+		AdminClient:        privateapi.NewAdminClient(conn),
+	}, nil
 }
@@ -5,6 +5,7 @@ import (
 	"math/big"
 
 	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
@@ -47,10 +48,17 @@ type TxTask struct {
 	Logs               []*types.Log
 	TraceFroms         map[common.Address]struct{}
 	TraceTos           map[common.Address]struct{}
+	StateSync          StateSync // brought into the block by the engine at its finalisation
 
 	UsedGas uint64
 }
 
+// StateSync is what the consensus engine brought into the block from outside of the chain,
+// it is written in the transaction of the block's state
+type StateSync interface {
+	Write(tx kv.RwTx, blockNum uint64) error
+}
+
 type TxTaskQueue []*TxTask
 
 func (h TxTaskQueue) Len() int {
@@ -160,10 +160,11 @@ func (rw *Worker) RunTxTask(txTask *exec22.TxTask) {
 				return core.SysCallContract(contract, data, *rw.chainConfig, ibs, header, txTask.ExcessDataGas, rw.engine, false /* constCall */)
 			}
 
-			if _, _, err := rw.engine.Finalize(rw.chainConfig, types.CopyHeader(header), ibs, txTask.Txs, txTask.Uncles, nil /* receipts */, txTask.Withdrawals, rw.epoch, rw.chain, syscall); err != nil {
+			if _, _, stateSync, err := consensus.FinalizeWithStateSync(rw.engine, rw.chainConfig, types.CopyHeader(header), ibs, txTask.Txs, txTask.Uncles, nil /* receipts */, txTask.Withdrawals, rw.epoch, rw.chain, syscall); err != nil {
 				//fmt.Printf("error=%v\n", err)
 				txTask.Error = err
 			} else {
+				txTask.StateSync = stateSync
 				txTask.TraceTos = map[common.Address]struct{}{}
 				txTask.TraceTos[txTask.Coinbase] = struct{}{}
 				for _, uncle := range txTask.Uncles {
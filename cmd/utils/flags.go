@@ -51,6 +51,7 @@ import (
 	"github.com/ledgerwatch/erigon/p2p/netutil"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/params/networkname"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -361,12 +362,51 @@ var (
 		Name:  "rpc.accessList",
 		Usage: "Specify granular (method-by-method) API allowlist",
 	}
+	RpcResultCacheFlag = cli.StringFlag{
+		Name:  "rpc.resultcache",
+		Usage: "Cache the results of rpc calls about finalized blocks (blocks, receipts, traces) in \"memory\" or in \"mdbx\" (<datadir>/rpccache). Disabled by default",
+	}
+	RpcResultCacheSizeFlag = cli.StringFlag{
+		Name:  "rpc.resultcache.size",
+		Usage: "Size limit of --rpc.resultcache",
+		Value: "512MB",
+	}
+	RpcResultCacheFinalityFlag = cli.StringFlag{
+		Name:  "rpc.resultcache.finality",
+		Usage: "Only the results of the blocks up to this one are cached: \"finalized\" or \"safe\"",
+		Value: "finalized",
+	}
+	RpcRateLimitsFlag = cli.StringFlag{
+		Name:  "rpc.rateLimits",
+		Usage: "Specify per-client request rate and concurrency limits of the HTTP, WebSocket and TCP endpoints",
+	}
 
 	RpcGasCapFlag = cli.UintFlag{
 		Name:  "rpc.gascap",
 		Usage: "Sets a cap on gas that can be used in eth_call/estimateGas",
 		Value: 50000000,
 	}
+	RpcMaxGetProofRewindBlockCountFlag = cli.IntFlag{
+		Name:  "rpc.maxgetproofrewindblockcount.limit",
+		Usage: "Max number of blocks eth_getProof can rewind the state trie back from the head to build proofs",
+		Value: rpccfg.DefaultMaxGetProofRewindBlockCount,
+	}
+	RpcSignerFlag = cli.StringFlag{
+		Name:  "rpc.signer",
+		Usage: "Signer used by eth_sendTransaction, eth_signTransaction and eth_sign: keystore or external. Disabled by default",
+	}
+	RpcSignerKeystoreFlag = cli.StringFlag{
+		Name:  "rpc.signer.keystore",
+		Usage: "Keystore directory of the keystore signer. Refused when http.addr isn't loopback, unless --allow-insecure-unlock is set",
+	}
+	RpcSignerPasswordFlag = cli.StringFlag{
+		Name:  "rpc.signer.password",
+		Usage: "Password file of the keystore signer, every line is tried on every key",
+	}
+	RpcSignerURLFlag = cli.StringFlag{
+		Name:  "rpc.signer.url",
+		Usage: "Endpoint of the external (clef) signer, an ipc path or an http/ws url",
+	}
 	RpcTraceCompatFlag = cli.BoolFlag{
 		Name:  "trace.compat",
 		Usage: "Bug for bug compatibility with OE for trace_ routines",
@@ -472,9 +512,13 @@ var (
 	}
 	P2pProtocolVersionFlag = cli.UintSliceFlag{
 		Name:  "p2p.protocol",
-		Usage: "Version of eth p2p protocol",
+		Usage: "Versions of eth p2p protocol, one sentry per version (66, 67, 68)",
 		Value: cli.NewUintSlice(nodecfg.DefaultConfig.P2P.ProtocolVersion...),
 	}
+	P2pServeSnapFlag = cli.BoolFlag{
+		Name:  "p2p.snap",
+		Usage: "Serve the state of the head block to the peers over the snap/1 protocol (internal sentries only). Older state roots are not served, so peers which pivot behind the head get empty responses",
+	}
 	P2pProtocolAllowedPorts = cli.UintSliceFlag{
 		Name:  "p2p.allowed-ports",
 		Usage: "Allowed ports to pick for different eth p2p protocol versions as follows <porta>,<portb>,..,<porti>",
@@ -624,6 +668,10 @@ var (
 		Name:  ethconfig.FlagSnapStop,
 		Usage: "Workaround to stop producing new snapshots, if you meet some snapshots-related critical bug. It will stop move historical data from DB to new immutable snapshots. DB will grow and may slightly slow-down - and removing this flag in future will not fix this effect (db size will not greatly reduce).",
 	}
+	SnapVerifyFlag = cli.BoolFlag{
+		Name:  ethconfig.FlagSnapVerify,
+		Usage: "Check snapshot segments against their torrent files, preverified hashes and block ranges on startup. Bad indices are rebuilt, bad segments are removed and downloaded again",
+	}
 	TorrentVerbosityFlag = cli.IntFlag{
 		Name:  "torrent.verbosity",
 		Value: 2,
@@ -691,10 +739,16 @@ var (
 
 	HeimdallURLFlag = cli.StringFlag{
 		Name:  "bor.heimdall",
-		Usage: "URL of Heimdall service",
+		Usage: "URL of Heimdall service, file://<dir> replays the responses recorded with --bor.heimdall.record",
 		Value: "http://localhost:1317",
 	}
 
+	// HeimdallRecordFlag records the heimdall responses, so bor can sync later without heimdall
+	HeimdallRecordFlag = cli.StringFlag{
+		Name:  "bor.heimdall.record",
+		Usage: "Directory to record the spans and state sync events received from Heimdall into",
+	}
+
 	// WithoutHeimdallFlag no heimdall (for testing purpose)
 	WithoutHeimdallFlag = cli.BoolFlag{
 		Name:  "bor.withoutheimdall",
@@ -871,6 +925,8 @@ func NewP2PConfig(
 		enodeDBPath = filepath.Join(dirs.Nodes, "eth66")
 	case eth.ETH67:
 		enodeDBPath = filepath.Join(dirs.Nodes, "eth67")
+	case eth.ETH68:
+		enodeDBPath = filepath.Join(dirs.Nodes, "eth68")
 	default:
 		return nil, fmt.Errorf("unknown protocol: %v", protocol)
 	}
@@ -1053,6 +1109,9 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config, nodeName, datadir string) {
 	if ctx.IsSet(DiscoveryV5Flag.Name) {
 		cfg.DiscoveryV5 = ctx.Bool(DiscoveryV5Flag.Name)
 	}
+	if ctx.IsSet(P2pServeSnapFlag.Name) {
+		cfg.ServeSnap = ctx.Bool(P2pServeSnapFlag.Name)
+	}
 
 	ethPeers := cfg.MaxPeers
 	cfg.Name = nodeName
@@ -1358,6 +1417,7 @@ func setParlia(ctx *cli.Context, cfg *params.ParliaConfig, datadir string) {
 func setBorConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 	cfg.HeimdallURL = ctx.String(HeimdallURLFlag.Name)
 	cfg.WithoutHeimdall = ctx.Bool(WithoutHeimdallFlag.Name)
+	cfg.HeimdallRecordDir = ctx.String(HeimdallRecordFlag.Name)
 }
 
 func setMiner(ctx *cli.Context, cfg *params.MiningConfig) {
@@ -1465,6 +1525,7 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	cfg.Snapshot.Produce = !ctx.Bool(SnapStopFlag.Name)
 	cfg.Snapshot.NoDownloader = ctx.Bool(NoDownloaderFlag.Name)
 	cfg.Snapshot.Verify = ctx.Bool(DownloaderVerifyFlag.Name)
+	cfg.Snapshot.VerifySegments = ctx.Bool(SnapVerifyFlag.Name)
 	cfg.Snapshot.DownloaderAddr = strings.TrimSpace(ctx.String(DownloaderAddrFlag.Name))
 	if cfg.Snapshot.DownloaderAddr == "" {
 		downloadRateStr := ctx.String(TorrentDownloadRateFlag.Name)
@@ -256,7 +256,7 @@ func New(
 	signatures, _ := lru.NewARC(inmemorySignatures)
 	vABI, _ := abi.JSON(strings.NewReader(validatorsetABI))
 	sABI, _ := abi.JSON(strings.NewReader(stateReceiverABI))
-	heimdallClient, _ := NewHeimdallClient(heimdallURL)
+	heimdallClient := NewHeimdallClientFromURL(heimdallURL)
 	genesisContractsClient := NewGenesisContractsClient(chainConfig, borConfig.ValidatorContract, borConfig.StateReceiverContract)
 	c := &Bor{
 		chainConfig:            chainConfig,
@@ -723,39 +723,51 @@ func (c *Bor) Finalize(config *params.ChainConfig, header *types.Header, state *
 	txs types.Transactions, uncles []*types.Header, r types.Receipts, withdrawals []*types.Withdrawal,
 	e consensus.EpochReader, chain consensus.ChainHeaderReader, syscall consensus.SystemCall,
 ) (types.Transactions, types.Receipts, error) {
-	var err error
+	txs, receipts, _, err := c.FinalizeWithStateSync(config, header, state, txs, uncles, r, withdrawals, e, chain, syscall)
+	return txs, receipts, err
+}
+
+// FinalizeWithStateSync is Finalize which also returns the span and the state sync events committed by the block
+func (c *Bor) FinalizeWithStateSync(config *params.ChainConfig, header *types.Header, state *state.IntraBlockState,
+	txs types.Transactions, uncles []*types.Header, r types.Receipts, withdrawals []*types.Withdrawal,
+	e consensus.EpochReader, chain consensus.ChainHeaderReader, syscall consensus.SystemCall,
+) (types.Transactions, types.Receipts, consensus.StateSync, error) {
+	var (
+		err       error
+		stateSync blockStateSync
+	)
 	headerNumber := header.Number.Uint64()
 	if isSprintStart(headerNumber, c.config.CalculateSprint(headerNumber)) {
 		cx := chainContext{Chain: chain, Bor: c}
 		// check and commit span
-		if err := c.checkAndCommitSpan(state, header, cx, syscall); err != nil {
+		if stateSync.span, err = c.checkAndCommitSpan(state, header, cx, syscall); err != nil {
 			log.Error("Error while committing span", "err", err)
-			return nil, types.Receipts{}, err
+			return nil, types.Receipts{}, nil, err
 		}
 
 		if !c.WithoutHeimdall {
 			// commit states
-			_, err = c.CommitStates(state, header, cx, syscall)
+			_, stateSync.events, err = c.commitStates(state, header, cx, syscall)
 			if err != nil {
 				log.Error("Error while committing states", "err", err)
-				return nil, types.Receipts{}, err
+				return nil, types.Receipts{}, nil, err
 			}
 		}
 	}
 
 	if err = c.changeContractCodeIfNeeded(headerNumber, state); err != nil {
 		log.Error("Error changing contract code", "err", err)
-		return nil, types.Receipts{}, err
+		return nil, types.Receipts{}, nil, err
 	}
 
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	// header.Root = state.IntermediateRoot(chain.Config().IsSpuriousDragon(header.Number.Uint64()))
 	header.UncleHash = types.CalcUncleHash(nil)
 
-	// Set state sync data to blockchain
-	// bc := chain.(*core.BlockChain)
-	// bc.SetStateSync(stateSyncData)
-	return nil, types.Receipts{}, nil
+	if stateSync.span == nil && len(stateSync.events) == 0 {
+		return nil, types.Receipts{}, nil, nil
+	}
+	return nil, types.Receipts{}, &stateSync, nil
 }
 
 func decodeGenesisAlloc(i interface{}) (core.GenesisAlloc, error) {
@@ -799,7 +811,7 @@ func (c *Bor) FinalizeAndAssemble(chainConfig *params.ChainConfig, header *types
 		cx := chainContext{Chain: chain, Bor: c}
 
 		// check and commit span
-		err := c.checkAndCommitSpan(state, header, cx, syscall)
+		_, err := c.checkAndCommitSpan(state, header, cx, syscall)
 		if err != nil {
 			log.Error("Error while committing span", "err", err)
 			return nil, nil, types.Receipts{}, err
@@ -1041,17 +1053,16 @@ func (c *Bor) checkAndCommitSpan(
 	header *types.Header,
 	chain chainContext,
 	syscall consensus.SystemCall,
-) error {
+) (*HeimdallSpan, error) {
 	headerNumber := header.Number.Uint64()
 	span, err := c.GetCurrentSpan(header, state, chain, syscall)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if c.needToCommitSpan(span, headerNumber) {
-		err := c.fetchAndCommitSpan(span.ID+1, state, header, chain, syscall)
-		return err
+		return c.fetchAndCommitSpan(span.ID+1, state, header, chain, syscall)
 	}
-	return nil
+	return nil, nil
 }
 
 func (c *Bor) needToCommitSpan(span *Span, headerNumber uint64) bool {
@@ -1129,29 +1140,29 @@ func (c *Bor) fetchAndCommitSpan(
 	header *types.Header,
 	chain chainContext,
 	syscall consensus.SystemCall,
-) error {
+) (*HeimdallSpan, error) {
 	var heimdallSpan HeimdallSpan
 
 	if c.WithoutHeimdall {
 		s, err := c.getNextHeimdallSpanForTest(newSpanID, state, header, chain, syscall)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		heimdallSpan = *s
 	} else {
 		response, err := c.HeimdallClient.FetchWithRetry(c.execCtx, fmt.Sprintf("bor/span/%d", newSpanID), "")
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if err := json.Unmarshal(response.Result, &heimdallSpan); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// check if chain id matches with heimdall span
 	if heimdallSpan.ChainID != c.chainConfig.ChainID.String() {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"chain id proposed span, %s, and bor chain id, %s, doesn't match",
 			heimdallSpan.ChainID,
 			c.chainConfig.ChainID.String(),
@@ -1165,7 +1176,7 @@ func (c *Bor) fetchAndCommitSpan(
 	}
 	validatorBytes, err := rlp.EncodeToBytes(validators)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// get producers bytes
@@ -1175,7 +1186,7 @@ func (c *Bor) fetchAndCommitSpan(
 	}
 	producerBytes, err := rlp.EncodeToBytes(producers)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// method
@@ -1198,12 +1209,13 @@ func (c *Bor) fetchAndCommitSpan(
 	)
 	if err != nil {
 		log.Error("Unable to pack tx for commitSpan", "err", err)
-		return err
+		return nil, err
 	}
 
-	_, err = syscall(common.HexToAddress(c.config.ValidatorContract), data)
-	// apply message
-	return err
+	if _, err = syscall(common.HexToAddress(c.config.ValidatorContract), data); err != nil {
+		return nil, err
+	}
+	return &heimdallSpan, nil
 }
 
 // CommitStates commit states
@@ -1213,11 +1225,23 @@ func (c *Bor) CommitStates(
 	chain chainContext,
 	syscall consensus.SystemCall,
 ) ([]*types.StateSyncData, error) {
+	stateSyncs, _, err := c.commitStates(state, header, chain, syscall)
+	return stateSyncs, err
+}
+
+// commitStates commit states, it returns the committed events as well
+func (c *Bor) commitStates(
+	state *state.IntraBlockState,
+	header *types.Header,
+	chain chainContext,
+	syscall consensus.SystemCall,
+) ([]*types.StateSyncData, []*EventRecordWithTime, error) {
 	stateSyncs := make([]*types.StateSyncData, 0)
+	var committed []*EventRecordWithTime
 	number := header.Number.Uint64()
 	_lastStateID, err := c.GenesisContractsClient.LastStateId(header, state, chain, c, syscall)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	to := time.Unix(int64(chain.Chain.GetHeaderByNumber(number-c.config.CalculateSprint(number)).Time), 0)
@@ -1229,7 +1253,7 @@ func (c *Bor) CommitStates(
 	eventRecords, err := c.HeimdallClient.FetchStateSyncEvents(c.execCtx, lastStateID+1, to.Unix())
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if c.config.OverrideStateSyncRecords != nil {
 		if val, ok := c.config.OverrideStateSyncRecords[strconv.FormatUint(number, 10)]; ok {
@@ -1256,11 +1280,12 @@ func (c *Bor) CommitStates(
 		stateSyncs = append(stateSyncs, &stateData)
 
 		if err := c.GenesisContractsClient.CommitState(eventRecord, state, header, chain, c, syscall); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		committed = append(committed, eventRecord)
 		lastStateID++
 	}
-	return stateSyncs, nil
+	return stateSyncs, committed, nil
 }
 
 func validateEventRecord(eventRecord *EventRecordWithTime, number uint64, to time.Time, lastStateID uint64, chainID string) error {
@@ -0,0 +1,388 @@
+package bor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	spanPathPrefix  = "bor/span/"
+	eventListPath   = "clerk/event-record/list"
+	eventPathPrefix = "clerk/event-record/"
+
+	// HeimdallReplayScheme makes --bor.heimdall replay the store in the directory of the URL instead of talking to heimdall
+	HeimdallReplayScheme = "file://"
+)
+
+// ErrHeimdallNotRecorded is returned when the store has no response for the request
+var ErrHeimdallNotRecorded = errors.New("heimdall response is not recorded")
+
+// HeimdallStore keeps the heimdall responses needed by bor in a directory, so they can be replayed
+// without heimdall:
+//
+//	spans/<span id>.json     - result of bor/span/<span id>
+//	events/<event id>.json   - state sync event
+//	events.json              - ranges of the recorded events: all the events from from_id with a time before to_time
+//
+// The files are plain json, so the stores of the tests can be written by hand.
+type HeimdallStore struct {
+	dir  string
+	lock sync.Mutex
+}
+
+// eventsRange is a range of events.json: all the events from FromID with a time before ToTime are recorded, they are
+// the events up to NextID. A request from an id up to NextID continues the range, the ids before it are all known,
+// so such ranges are merged. Ranges with a gap between them stay apart, the requests across the gap aren't answered.
+type eventsRange struct {
+	FromID uint64 `json:"from_id"`
+	ToTime int64  `json:"to_time"`
+	NextID uint64 `json:"next_id"`
+}
+
+func (r *eventsRange) covers(fromID uint64, to int64) bool {
+	return r.FromID <= fromID && to <= r.ToTime
+}
+
+// addEventsRange adds the range to the ranges sorted by FromID, merging the ranges which continue each other
+func addEventsRange(ranges []eventsRange, r eventsRange) []eventsRange {
+	ranges = append(ranges, r)
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].FromID < ranges[j].FromID })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.FromID > last.NextID {
+			merged = append(merged, r)
+			continue
+		}
+		if r.ToTime > last.ToTime {
+			last.ToTime = r.ToTime
+		}
+		if r.NextID > last.NextID {
+			last.NextID = r.NextID
+		}
+	}
+	return merged
+}
+
+func NewHeimdallStore(dir string) *HeimdallStore {
+	return &HeimdallStore{dir: dir}
+}
+
+func (s *HeimdallStore) Dir() string { return s.dir }
+
+func (s *HeimdallStore) spanFile(id uint64) string {
+	return filepath.Join(s.dir, "spans", fmt.Sprintf("%d.json", id))
+}
+
+func (s *HeimdallStore) eventFile(id uint64) string {
+	return filepath.Join(s.dir, "events", fmt.Sprintf("%d.json", id))
+}
+
+func (s *HeimdallStore) rangeFile() string {
+	return filepath.Join(s.dir, "events.json")
+}
+
+// Span returns the recorded result of bor/span/<id>
+func (s *HeimdallStore) Span(id uint64) (json.RawMessage, error) {
+	data, err := os.ReadFile(s.spanFile(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: span %d", ErrHeimdallNotRecorded, id)
+	}
+	return data, err
+}
+
+func (s *HeimdallStore) PutSpan(id uint64, span json.RawMessage) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return writeFileAtomic(s.spanFile(id), span)
+}
+
+// Event returns the recorded event, nil if there is none
+func (s *HeimdallStore) Event(id uint64) (*EventRecordWithTime, error) {
+	data, err := os.ReadFile(s.eventFile(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var event EventRecordWithTime
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("event %d: %w", id, err)
+	}
+	return &event, nil
+}
+
+// Events returns up to limit (0 is no limit) consecutive events from fromID with a time before to,
+// like the clerk/event-record/list of heimdall. The store has to cover the request.
+func (s *HeimdallStore) Events(fromID uint64, to int64, limit int) ([]*EventRecordWithTime, error) {
+	recorded, err := s.eventsRanges()
+	if err != nil {
+		return nil, err
+	}
+	covered := false
+	for i := range recorded {
+		if recorded[i].covers(fromID, to) {
+			covered = true
+			break
+		}
+	}
+	if !covered {
+		return nil, fmt.Errorf("%w: events from %d before %s", ErrHeimdallNotRecorded, fromID, time.Unix(to, 0).UTC().Format(time.RFC3339))
+	}
+	events := make([]*EventRecordWithTime, 0)
+	for id := fromID; limit <= 0 || len(events) < limit; id++ {
+		event, err := s.Event(id)
+		if err != nil {
+			return nil, err
+		}
+		// ids are consecutive, so a missing one is the end of the recorded events
+		if event == nil || !event.Time.Before(time.Unix(to, 0)) {
+			break
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// PutEvents records the complete answer to the request of the events from fromID with a time before to
+func (s *HeimdallStore) PutEvents(fromID uint64, to int64, events []*EventRecordWithTime) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(s.eventFile(event.ID), data); err != nil {
+			return err
+		}
+	}
+	recorded, err := s.eventsRanges()
+	if err != nil {
+		return err
+	}
+	nextID := fromID
+	if len(events) > 0 {
+		nextID = events[len(events)-1].ID + 1
+	}
+	recorded = addEventsRange(recorded, eventsRange{FromID: fromID, ToTime: to, NextID: nextID})
+	data, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.rangeFile(), data)
+}
+
+func (s *HeimdallStore) eventsRanges() ([]eventsRange, error) {
+	data, err := os.ReadFile(s.rangeFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recorded []eventsRange
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.rangeFile(), err)
+	}
+	return recorded, nil
+}
+
+// response answers the heimdall REST request bor makes from the recorded responses
+func (s *HeimdallStore) response(path string, query string) (*ResponseWithHeight, error) {
+	path = strings.Trim(path, "/")
+	var result interface{}
+	switch {
+	case strings.HasPrefix(path, spanPathPrefix):
+		id, err := strconv.ParseUint(strings.TrimPrefix(path, spanPathPrefix), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid span id: %w", err)
+		}
+		span, err := s.Span(id)
+		if err != nil {
+			return nil, err
+		}
+		return &ResponseWithHeight{Height: "0", Result: span}, nil
+	case path == eventListPath:
+		params, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		fromID, err := strconv.ParseUint(params.Get("from-id"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from-id: %w", err)
+		}
+		to, err := strconv.ParseInt(params.Get("to-time"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to-time: %w", err)
+		}
+		limit := stateFetchLimit
+		if params.Has("limit") {
+			if limit, err = strconv.Atoi(params.Get("limit")); err != nil {
+				return nil, fmt.Errorf("invalid limit: %w", err)
+			}
+		}
+		if result, err = s.Events(fromID, to, limit); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(path, eventPathPrefix):
+		id, err := strconv.ParseUint(strings.TrimPrefix(path, eventPathPrefix), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event id: %w", err)
+		}
+		event, err := s.Event(id)
+		if err != nil {
+			return nil, err
+		}
+		if event == nil {
+			return nil, fmt.Errorf("%w: event %d", ErrHeimdallNotRecorded, id)
+		}
+		result = event
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrHeimdallNotRecorded, path)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseWithHeight{Height: "0", Result: data}, nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReplayHeimdallClient answers bor from a HeimdallStore, so bor can sync and be tested without heimdall.
+// Requests which weren't recorded fail with ErrHeimdallNotRecorded instead of being retried.
+type ReplayHeimdallClient struct {
+	store *HeimdallStore
+}
+
+var _ IHeimdallClient = (*ReplayHeimdallClient)(nil)
+
+func NewReplayHeimdallClient(store *HeimdallStore) *ReplayHeimdallClient {
+	return &ReplayHeimdallClient{store: store}
+}
+
+func (h *ReplayHeimdallClient) Fetch(ctx context.Context, path string, query string) (*ResponseWithHeight, error) {
+	return h.store.response(path, query)
+}
+
+// FetchWithRetry doesn't retry, the store won't change
+func (h *ReplayHeimdallClient) FetchWithRetry(ctx context.Context, path string, query string) (*ResponseWithHeight, error) {
+	return h.store.response(path, query)
+}
+
+func (h *ReplayHeimdallClient) FetchStateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*EventRecordWithTime, error) {
+	return h.store.Events(fromID, to, 0)
+}
+
+// RecordingHeimdallClient passes the requests to heimdall and records the spans and the state sync events
+// into a HeimdallStore for the ReplayHeimdallClient.
+type RecordingHeimdallClient struct {
+	client IHeimdallClient
+	store  *HeimdallStore
+}
+
+var _ IHeimdallClient = (*RecordingHeimdallClient)(nil)
+
+func NewRecordingHeimdallClient(client IHeimdallClient, store *HeimdallStore) *RecordingHeimdallClient {
+	return &RecordingHeimdallClient{client: client, store: store}
+}
+
+func (h *RecordingHeimdallClient) Fetch(ctx context.Context, path string, query string) (*ResponseWithHeight, error) {
+	response, err := h.client.Fetch(ctx, path, query)
+	if err == nil {
+		h.record(path, response)
+	}
+	return response, err
+}
+
+func (h *RecordingHeimdallClient) FetchWithRetry(ctx context.Context, path string, query string) (*ResponseWithHeight, error) {
+	response, err := h.client.FetchWithRetry(ctx, path, query)
+	if err == nil {
+		h.record(path, response)
+	}
+	return response, err
+}
+
+func (h *RecordingHeimdallClient) FetchStateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*EventRecordWithTime, error) {
+	events, err := h.client.FetchStateSyncEvents(ctx, fromID, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.store.PutEvents(fromID, to, events); err != nil {
+		log.Warn("Failed to record heimdall state sync events", "fromID", fromID, "to", to, "err", err)
+	}
+	return events, nil
+}
+
+// record keeps the spans, the event lists are recorded by FetchStateSyncEvents which knows they are complete
+func (h *RecordingHeimdallClient) record(path string, response *ResponseWithHeight) {
+	path = strings.Trim(path, "/")
+	if response == nil || response.Result == nil || !strings.HasPrefix(path, spanPathPrefix) {
+		return
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(path, spanPathPrefix), 10, 64)
+	if err != nil {
+		return
+	}
+	if err := h.store.PutSpan(id, response.Result); err != nil {
+		log.Warn("Failed to record heimdall span", "id", id, "err", err)
+	}
+}
+
+// NewHeimdallServer serves the recorded responses over the REST API of heimdall, as a local stand-in for it.
+// Requests which weren't recorded get 404, which the HeimdallClient retries.
+func NewHeimdallServer(store *HeimdallStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		response, err := store.response(r.URL.Path, r.URL.RawQuery)
+		switch {
+		case errors.Is(err, ErrHeimdallNotRecorded):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Debug("Failed to write heimdall response", "err", err)
+		}
+	})
+}
+
+// NewHeimdallClientFromURL returns the client of heimdall at the url, or the ReplayHeimdallClient of the store
+// in the directory of a file:// url
+func NewHeimdallClientFromURL(urlString string) IHeimdallClient {
+	if strings.HasPrefix(urlString, HeimdallReplayScheme) {
+		return NewReplayHeimdallClient(NewHeimdallStore(strings.TrimPrefix(urlString, HeimdallReplayScheme)))
+	}
+	heimdallClient, _ := NewHeimdallClient(urlString)
+	return heimdallClient
+}
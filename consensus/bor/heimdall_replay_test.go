@@ -0,0 +1,149 @@
+package bor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func eventIDs(events []*EventRecordWithTime) []uint64 {
+	ids := make([]uint64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func idRange(from, to uint64) []uint64 {
+	ids := make([]uint64, 0, to-from+1)
+	for id := from; id <= to; id++ {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestHeimdallRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1600000000, 0).UTC()
+
+	// heimdall is played by the stand-in server of a store with 2 spans and 60 events, one per second
+	heimdallStore := NewHeimdallStore(t.TempDir())
+	for id := uint64(0); id < 2; id++ {
+		span, err := json.Marshal(&HeimdallSpan{Span: Span{ID: id, StartBlock: id * 6400, EndBlock: id*6400 + 6399}, ChainID: "15001"})
+		require.NoError(t, err)
+		require.NoError(t, heimdallStore.PutSpan(id, span))
+	}
+	var events []*EventRecordWithTime
+	for id := uint64(1); id <= 60; id++ {
+		events = append(events, &EventRecordWithTime{
+			EventRecord: EventRecord{ID: id, Contract: common.Address{1}, Data: []byte{byte(id)}, ChainID: "15001"},
+			Time:        start.Add(time.Duration(id) * time.Second),
+		})
+	}
+	require.NoError(t, heimdallStore.PutEvents(1, start.Add(time.Hour).Unix(), events))
+	server := httptest.NewServer(NewHeimdallServer(heimdallStore))
+	defer server.Close()
+
+	heimdall, err := NewHeimdallClient(server.URL)
+	require.NoError(t, err)
+	_, err = heimdall.Fetch(ctx, "bor/span/2", "")
+	require.Error(t, err)
+
+	// record the responses bor asks for, more events than fit into a page of heimdall
+	dir := t.TempDir()
+	recording := NewRecordingHeimdallClient(heimdall, NewHeimdallStore(dir))
+	span1, err := recording.FetchWithRetry(ctx, "bor/span/1", "")
+	require.NoError(t, err)
+	recorded, err := recording.FetchStateSyncEvents(ctx, 1, start.Add(55*time.Second).Unix())
+	require.NoError(t, err)
+	require.Equal(t, idRange(1, 54), eventIDs(recorded))
+	recorded, err = recording.FetchStateSyncEvents(ctx, 55, start.Add(70*time.Second).Unix())
+	require.NoError(t, err)
+	require.Equal(t, idRange(55, 60), eventIDs(recorded))
+
+	// and replay them
+	replay := NewHeimdallClientFromURL(HeimdallReplayScheme + dir)
+	require.IsType(t, &ReplayHeimdallClient{}, replay)
+	span, err := replay.FetchWithRetry(ctx, "bor/span/1", "")
+	require.NoError(t, err)
+	require.JSONEq(t, string(span1.Result), string(span.Result))
+	_, err = replay.FetchWithRetry(ctx, "bor/span/0", "")
+	require.ErrorIs(t, err, ErrHeimdallNotRecorded)
+
+	replayed, err := replay.FetchStateSyncEvents(ctx, 10, start.Add(30*time.Second).Unix())
+	require.NoError(t, err)
+	require.Equal(t, idRange(10, 29), eventIDs(replayed))
+	require.True(t, start.Add(10*time.Second).Equal(replayed[0].Time))
+	require.Equal(t, events[9].Data, replayed[0].Data)
+	replayed, err = replay.FetchStateSyncEvents(ctx, 61, start.Add(70*time.Second).Unix())
+	require.NoError(t, err)
+	require.Empty(t, replayed)
+	// events after the recorded time may still come
+	_, err = replay.FetchStateSyncEvents(ctx, 55, start.Add(71*time.Second).Unix())
+	require.ErrorIs(t, err, ErrHeimdallNotRecorded)
+
+	// the recording can stand in for heimdall as well
+	server2 := httptest.NewServer(NewHeimdallServer(NewHeimdallStore(dir)))
+	defer server2.Close()
+	heimdall2, err := NewHeimdallClient(server2.URL)
+	require.NoError(t, err)
+	replayed, err = heimdall2.FetchStateSyncEvents(ctx, 1, start.Add(70*time.Second).Unix())
+	require.NoError(t, err)
+	require.Equal(t, idRange(1, 60), eventIDs(replayed))
+}
+
+func TestHeimdallStoreEventsGap(t *testing.T) {
+	start := time.Unix(1600000000, 0).UTC()
+	event := func(id uint64) *EventRecordWithTime {
+		return &EventRecordWithTime{
+			EventRecord: EventRecord{ID: id, Contract: common.Address{1}, Data: []byte{byte(id)}, ChainID: "15001"},
+			Time:        start.Add(time.Duration(id) * time.Second),
+		}
+	}
+	events := func(from, to uint64) []*EventRecordWithTime {
+		var events []*EventRecordWithTime
+		for id := from; id <= to; id++ {
+			events = append(events, event(id))
+		}
+		return events
+	}
+	at := func(seconds int) int64 { return start.Add(time.Duration(seconds) * time.Second).Unix() }
+
+	// two recordings which aren't adjacent, events 10-29 aren't recorded
+	store := NewHeimdallStore(t.TempDir())
+	require.NoError(t, store.PutEvents(1, at(10), events(1, 9)))
+	require.NoError(t, store.PutEvents(30, at(40), events(30, 39)))
+
+	replayed, err := store.Events(1, at(10), 0)
+	require.NoError(t, err)
+	require.Equal(t, idRange(1, 9), eventIDs(replayed))
+	replayed, err = store.Events(32, at(40), 0)
+	require.NoError(t, err)
+	require.Equal(t, idRange(32, 39), eventIDs(replayed))
+
+	// the requests across the gap fail instead of stopping at it
+	_, err = store.Events(1, at(40), 0)
+	require.ErrorIs(t, err, ErrHeimdallNotRecorded)
+	_, err = store.Events(10, at(20), 0)
+	require.ErrorIs(t, err, ErrHeimdallNotRecorded)
+
+	// once the gap is recorded, the ranges are one
+	require.NoError(t, store.PutEvents(10, at(30), events(10, 29)))
+	replayed, err = store.Events(1, at(40), 0)
+	require.NoError(t, err)
+	require.Equal(t, idRange(1, 39), eventIDs(replayed))
+	recorded, err := store.eventsRanges()
+	require.NoError(t, err)
+	require.Equal(t, []eventsRange{{FromID: 1, ToTime: at(40), NextID: 40}}, recorded)
+
+	// a request without new events extends the time of the range it continues
+	require.NoError(t, store.PutEvents(40, at(45), nil))
+	replayed, err = store.Events(35, at(45), 0)
+	require.NoError(t, err)
+	require.Equal(t, idRange(35, 39), eventIDs(replayed))
+}
@@ -0,0 +1,73 @@
+package bor
+
+import (
+	"encoding/json"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+)
+
+var _ consensus.StateSyncEngine = (*Bor)(nil)
+
+// blockStateSync is what Finalize brought into a block from heimdall
+type blockStateSync struct {
+	span   *HeimdallSpan
+	events []*EventRecordWithTime
+}
+
+// Write writes the span and the state sync events committed by the block, so they can be queried by the block
+// and by their ids. Execution writes them with the state of the block and truncates them on unwind.
+func (stateSync *blockStateSync) Write(tx kv.RwTx, blockNum uint64) error {
+	if stateSync.span != nil {
+		data, err := json.Marshal(stateSync.span)
+		if err != nil {
+			return err
+		}
+		if err := rawdb.WriteBorSpan(tx, stateSync.span.ID, blockNum, data); err != nil {
+			return err
+		}
+	}
+	if len(stateSync.events) == 0 {
+		return nil
+	}
+	events := make([][]byte, 0, len(stateSync.events))
+	for _, event := range stateSync.events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		events = append(events, data)
+	}
+	return rawdb.WriteBorEvents(tx, blockNum, stateSync.events[0].ID, events)
+}
+
+// ReadStateSyncEvents returns the state sync events committed by the block
+func ReadStateSyncEvents(tx kv.Tx, blockNum uint64) ([]*EventRecordWithTime, error) {
+	data, err := rawdb.ReadBorEventsOfBlock(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*EventRecordWithTime, 0, len(data))
+	for _, v := range data {
+		var event EventRecordWithTime
+		if err := json.Unmarshal(v, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// ReadSpan returns the span and the number of the block which committed it, nil if no block committed it
+func ReadSpan(tx kv.Getter, spanID uint64) (*HeimdallSpan, uint64, error) {
+	blockNum, data, err := rawdb.ReadBorSpan(tx, spanID)
+	if err != nil || data == nil {
+		return nil, 0, err
+	}
+	var span HeimdallSpan
+	if err := json.Unmarshal(data, &span); err != nil {
+		return nil, 0, err
+	}
+	return &span, blockNum, nil
+}
@@ -0,0 +1,80 @@
+package bor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+)
+
+func TestWriteStateSync(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	// blocks 16 and 32 commit events, 16 commits span 1 as well
+	event := func(id uint64) *EventRecordWithTime {
+		return &EventRecordWithTime{
+			EventRecord: EventRecord{ID: id, Contract: common.Address{1}, Data: []byte{byte(id)}, ChainID: "137"},
+			Time:        time.Unix(int64(1600000000+id), 0).UTC(),
+		}
+	}
+	var stateSync16, stateSync32 consensus.StateSync = &blockStateSync{
+		span:   &HeimdallSpan{Span: Span{ID: 1, StartBlock: 256, EndBlock: 6655}, ChainID: "137"},
+		events: []*EventRecordWithTime{event(1), event(2)},
+	}, &blockStateSync{events: []*EventRecordWithTime{event(3)}}
+	require.NoError(t, stateSync16.Write(tx, 16))
+	require.NoError(t, stateSync32.Write(tx, 32))
+
+	requireEvents := func(t *testing.T, tx kv.Tx, blockNum uint64, ids ...uint64) {
+		t.Helper()
+		events, err := ReadStateSyncEvents(tx, blockNum)
+		require.NoError(t, err)
+		require.Len(t, events, len(ids))
+		for i, id := range ids {
+			require.Equal(t, id, events[i].ID)
+			require.True(t, event(id).Time.Equal(events[i].Time))
+			require.Equal(t, event(id).Data, events[i].Data)
+		}
+	}
+	requireEvents(t, tx, 16, 1, 2)
+	requireEvents(t, tx, 17)
+	requireEvents(t, tx, 32, 3)
+	blockNum, _, err := rawdb.ReadBorEvent(tx, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(16), blockNum)
+	span, blockNum, err := ReadSpan(tx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(16), blockNum)
+	require.Equal(t, uint64(6655), span.EndBlock)
+
+	// unwinding to block 20 drops what block 32 committed
+	require.NoError(t, rawdb.TruncateBorEvents(tx, 21))
+	requireEvents(t, tx, 16, 1, 2)
+	requireEvents(t, tx, 32)
+	_, event3, err := rawdb.ReadBorEvent(tx, 3)
+	require.NoError(t, err)
+	require.Nil(t, event3)
+	span, _, err = ReadSpan(tx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, span)
+
+	// and unwinding to block 10 drops the rest
+	require.NoError(t, rawdb.TruncateBorEvents(tx, 11))
+	requireEvents(t, tx, 16)
+	span, _, err = ReadSpan(tx, 1)
+	require.NoError(t, err)
+	require.Nil(t, span)
+	for _, table := range []string{rawdb.BorEvents, rawdb.BorEventNums, rawdb.BorSpans} {
+		var left int
+		require.NoError(t, tx.ForEach(table, nil, func(k, v []byte) error {
+			left++
+			return nil
+		}))
+		require.Zero(t, left, table)
+	}
+}
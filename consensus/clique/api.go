@@ -16,124 +16,50 @@
 
 package clique
 
-/*
 import (
+	"context"
 	"fmt"
 
+	"github.com/ledgerwatch/erigon-lib/kv"
+
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus"
-	"github.com/ledgerwatch/erigon/core/types"
-	"github.com/ledgerwatch/erigon/rpc"
 )
-// API is a user facing RPC API to allow controlling the signer and voting
-// mechanisms of the proof-of-authority scheme.
-type API struct {
-	chain  consensus.ChainHeaderReader
-	clique *Clique
-}
-
-// GetSnapshot retrieves the state snapshot at a given block.
-func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
-	// Retrieve the requested block number (or current if none requested)
-	var header *types.Header
-	if number == nil || *number == rpc.LatestBlockNumber {
-		header = api.chain.CurrentHeader()
-	} else {
-		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
-	}
-	// Ensure we have an actually valid block and return its snapshot
-	if header == nil {
-		return nil, errUnknownBlock
-	}
-
-	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return snap, err
-}
-
-// GetSnapshotAtHash retrieves the state snapshot at a given block.
-func (api *API) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
-	header := api.chain.GetHeaderByHash(hash)
-	if header == nil {
-		return nil, errUnknownBlock
-	}
-
-	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
-	if err != nil {
-		return nil, err
-	}
 
-	return snap, err
-}
+// statusBlocks is the number of the recent blocks Status reports on
+const statusBlocks = 64
 
-// GetSigners retrieves the list of authorized signers at the specified block.
-func (api *API) GetSigners(number *rpc.BlockNumber) ([]common.Address, error) {
-	// Retrieve the requested block number (or current if none requested)
-	var header *types.Header
-	if number == nil || *number == rpc.LatestBlockNumber {
-		header = api.chain.CurrentHeader()
-	} else {
-		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
-	}
-	// Ensure we have an actually valid block and return the signers from its snapshot
-	if header == nil {
-		return nil, errUnknownBlock
-	}
-
-	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
-	if err != nil {
-		return nil, err
-	}
-	return snap.signers(), nil
+// API allows controlling the signer and voting mechanisms of the proof-of-authority scheme.
+// It is served by rpcdaemon, which reaches it over the private api of the node.
+type API struct {
+	clique *Clique
+	db     kv.RoDB
+	chain  func(tx kv.Getter) consensus.ChainHeaderReader
 }
 
-// GetSignersAtHash retrieves the list of authorized signers at the specified block.
-func (api *API) GetSignersAtHash(hash common.Hash) ([]common.Address, error) {
-	header := api.chain.GetHeaderByHash(hash)
-	if header == nil {
-		return nil, errUnknownBlock
-	}
-	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
-	if err != nil {
-		return nil, err
-	}
-	return snap.signers(), nil
+// NewAPI returns the API of the engine, chain gives access to the headers of the chain in db.
+func NewAPI(clique *Clique, db kv.RoDB, chain func(tx kv.Getter) consensus.ChainHeaderReader) *API {
+	return &API{clique: clique, db: db, chain: chain}
 }
 
 // Proposals returns the current proposals the node tries to uphold and vote on.
 func (api *API) Proposals() map[common.Address]bool {
-	api.clique.lock.RLock()
-	defer api.clique.lock.RUnlock()
-
-	proposals := make(map[common.Address]bool)
-	for address, auth := range api.clique.proposals {
-		proposals[address] = auth
-	}
-	return proposals
+	return api.clique.Proposals()
 }
 
 // Propose injects a new authorization proposal that the signer will attempt to
 // push through.
-func (api *API) Propose(address common.Address, auth bool) {
-	api.clique.lock.Lock()
-	defer api.clique.lock.Unlock()
-
-	api.clique.proposals[address] = auth
+func (api *API) Propose(address common.Address, auth bool) error {
+	return api.clique.Propose(address, auth)
 }
 
 // Discard drops a currently running proposal, stopping the signer from casting
 // further votes (either for or against).
-func (api *API) Discard(address common.Address) {
-	api.clique.lock.Lock()
-	defer api.clique.lock.Unlock()
-
-	delete(api.clique.proposals, address)
+func (api *API) Discard(address common.Address) error {
+	return api.clique.Discard(address)
 }
 
-type status struct {
+type Status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
 	NumBlocks     uint64                 `json:"numBlocks"`
@@ -143,49 +69,56 @@ type status struct {
 // - the number of active signers,
 // - the number of signers,
 // - the percentage of in-turn blocks
-func (api *API) Status() (*status, error) {
+func (api *API) Status(ctx context.Context) (*Status, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	chain := api.chain(tx)
+
 	var (
-		numBlocks = uint64(64)
-		header    = api.chain.CurrentHeader()
-		diff      = uint64(0)
+		numBlocks = uint64(statusBlocks)
+		header    = chain.CurrentHeader()
 		optimals  = 0
 	)
-	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.clique.Snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
 	if err != nil {
 		return nil, err
 	}
-	var (
-		signers = snap.signers()
-		end     = header.Number.Uint64()
-		start   = end - numBlocks
-	)
-	if numBlocks > end {
-		start = 1
-		numBlocks = end - start
+	end := header.Number.Uint64()
+	if end <= numBlocks {
+		// the genesis block has no sealer
+		numBlocks = 0
+		if end > 0 {
+			numBlocks = end - 1
+		}
 	}
+	start := end - numBlocks
 	signStatus := make(map[common.Address]int)
-	for _, s := range signers {
+	for _, s := range snap.GetSigners() {
 		signStatus[s] = 0
 	}
 	for n := start; n < end; n++ {
-		h := api.chain.GetHeaderByNumber(n)
+		h := chain.GetHeaderByNumber(n)
 		if h == nil {
 			return nil, fmt.Errorf("missing block %d", n)
 		}
-		if h.Difficulty.Cmp(diffInTurn) == 0 {
+		if h.Difficulty.Cmp(DiffInTurn) == 0 {
 			optimals++
 		}
-		diff += h.Difficulty.Uint64()
 		sealer, err := api.clique.Author(h)
 		if err != nil {
 			return nil, err
 		}
 		signStatus[sealer]++
 	}
-	return &status{
-		InturnPercent: float64(100*optimals) / float64(numBlocks),
-		SigningStatus: signStatus,
-		NumBlocks:     numBlocks,
-	}, nil
+	status := &Status{SigningStatus: signStatus, NumBlocks: numBlocks}
+	if numBlocks > 0 {
+		status.InturnPercent = float64(100*optimals) / float64(numBlocks)
+	}
+	return status, nil
 }
-*/
@@ -182,7 +182,7 @@ type Clique struct {
 	signatures *lru.ARCCache // Signatures of recent blocks to speed up mining
 	recents    *lru.ARCCache // Snapshots for recent block to speed up reorgs
 
-	proposals map[common.Address]bool // Current list of proposals we are pushing
+	proposals map[common.Address]bool // Current list of proposals we are pushing, mirror of the CliqueVotes table
 
 	signer common.Address // Ethereum address of the signing key
 	signFn SignerFn       // Signer function to authorize hashes with
@@ -221,6 +221,12 @@ func New(cfg *params.ChainConfig, snapshotConfig *params.ConsensusSnapshotConfig
 		exitCh:         exitCh,
 	}
 
+	if proposals, err := loadProposals(cliqueDB); err != nil {
+		log.Error("on Clique init while loading proposals", "err", err)
+	} else {
+		c.proposals = proposals
+	}
+
 	// warm the cache
 	snapNum, err := lastSnapshot(cliqueDB)
 	if err != nil {
@@ -17,6 +17,7 @@
 package clique_test
 
 import (
+	"bytes"
 	"context"
 	"math/big"
 	"testing"
@@ -26,11 +27,13 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
 	"github.com/ledgerwatch/erigon/consensus/clique"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/stages"
 )
@@ -137,3 +140,86 @@ func TestReimportMirroredState(t *testing.T) {
 	}
 
 }
+
+func TestPersistedVotes(t *testing.T) {
+	var (
+		cliqueDB = memdb.NewTestDB(t)
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		engine   = clique.New(params.AllCliqueProtocolChanges, params.CliqueSnapshot, cliqueDB)
+		voted    = common.Address{0x01}
+	)
+	genspec := &core.Genesis{
+		ExtraData: make([]byte, clique.ExtraVanity+common.AddressLength+clique.ExtraSeal),
+		Config:    params.AllCliqueProtocolChanges,
+	}
+	copy(genspec.ExtraData[clique.ExtraVanity:], addr[:])
+	m := stages.MockWithGenesisEngine(t, genspec, engine, false)
+
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 2, func(i int, block *core.BlockGen) {
+		block.SetDifficulty(clique.DiffInTurn)
+	}, false /* intermediateHashes */)
+	if err != nil {
+		t.Fatalf("generate blocks: %v", err)
+	}
+	for i, block := range chain.Blocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = chain.Blocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, clique.ExtraVanity+clique.ExtraSeal)
+		header.Difficulty = clique.DiffInTurn
+
+		sig, _ := crypto.Sign(clique.SealHash(header).Bytes(), key)
+		copy(header.Extra[len(header.Extra)-clique.ExtraSeal:], sig)
+		chain.Headers[i] = header
+		chain.Blocks[i] = block.WithSeal(header)
+	}
+	if err := m.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+
+	if err := engine.Propose(voted, true); err != nil {
+		t.Fatal(err)
+	}
+	// the vote survives a restart of the engine
+	engine = clique.New(params.AllCliqueProtocolChanges, params.CliqueSnapshot, cliqueDB)
+	if proposals := engine.Proposals(); len(proposals) != 1 || !proposals[voted] {
+		t.Fatalf("proposals mismatch: have %v", proposals)
+	}
+
+	chainReader := func(tx kv.Getter) consensus.ChainHeaderReader {
+		return stagedsync.ChainReader{Cfg: *m.ChainConfig, Db: tx}
+	}
+	// and it is cast by the next block the signer seals
+	if err := m.DB.View(context.Background(), func(tx kv.Tx) error {
+		parent := chain.TopBlock.Header()
+		header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(int64(parent.Number.Uint64() + 1)), Coinbase: addr}
+		engine.Authorize(addr, nil)
+		if err := engine.Prepare(chainReader(tx), header, nil); err != nil {
+			return err
+		}
+		if header.Coinbase != voted || !bytes.Equal(header.Nonce[:], clique.NonceAuthVote) {
+			t.Errorf("vote mismatch: have %x %x", header.Coinbase, header.Nonce)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := clique.NewAPI(engine, m.DB, chainReader).Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.NumBlocks != 1 || status.InturnPercent != 100 || status.SigningStatus[addr] != 1 {
+		t.Errorf("status mismatch: have %+v", status)
+	}
+
+	if err := engine.Discard(voted); err != nil {
+		t.Fatal(err)
+	}
+	engine = clique.New(params.AllCliqueProtocolChanges, params.CliqueSnapshot, cliqueDB)
+	if proposals := engine.Proposals(); len(proposals) != 0 {
+		t.Fatalf("proposals mismatch: have %v", proposals)
+	}
+}
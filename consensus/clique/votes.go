@@ -0,0 +1,83 @@
+package clique
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// CliqueVotes keeps the proposals of the signer across restarts: address -> 1 to authorize, 0 to drop.
+// erigon-lib doesn't know about the table, so it is added to the chaindata tables the consensus db is opened with.
+const CliqueVotes = "CliqueVotes"
+
+func init() {
+	if _, ok := kv.ChaindataTablesCfg[CliqueVotes]; !ok {
+		kv.ChaindataTables = append(kv.ChaindataTables, CliqueVotes)
+		kv.ChaindataTablesCfg[CliqueVotes] = kv.TableCfgItem{}
+		sort.Strings(kv.ChaindataTables)
+	}
+}
+
+func loadProposals(db kv.RoDB) (map[common.Address]bool, error) {
+	proposals := make(map[common.Address]bool)
+	if err := db.View(context.Background(), func(tx kv.Tx) error {
+		return tx.ForEach(CliqueVotes, nil, func(k, v []byte) error {
+			if len(k) == common.AddressLength && len(v) == 1 {
+				proposals[common.BytesToAddress(k)] = v[0] == 1
+			}
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+// Propose injects a new authorization proposal that the signer will attempt to
+// push through. The proposal is persisted, so the signer keeps voting on it after a restart.
+func (c *Clique) Propose(address common.Address, auth bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	v := []byte{0}
+	if auth {
+		v[0] = 1
+	}
+	if err := c.db.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(CliqueVotes, address.Bytes(), v)
+	}); err != nil {
+		return err
+	}
+	c.proposals[address] = auth
+	return nil
+}
+
+// Discard drops a currently running proposal, stopping the signer from casting
+// further votes (either for or against).
+func (c *Clique) Discard(address common.Address) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.db.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Delete(CliqueVotes, address.Bytes())
+	}); err != nil {
+		return err
+	}
+	delete(c.proposals, address)
+	return nil
+}
+
+// Proposals returns the current proposals the node tries to uphold and vote on.
+func (c *Clique) Proposals() map[common.Address]bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	proposals := make(map[common.Address]bool, len(c.proposals))
+	for address, auth := range c.proposals {
+		proposals[address] = auth
+	}
+	return proposals
+}
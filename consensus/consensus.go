@@ -21,6 +21,7 @@ import (
 	"context"
 	"math/big"
 
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
@@ -177,6 +178,36 @@ type PoSA interface {
 	AllowLightProcess(chain ChainReader, currentHeader *types.Header) bool
 }
 
+// StateSync is what an engine brought into a block from outside of the chain (the state sync events and spans of bor).
+type StateSync interface {
+	// Write writes it next to the executed block, in the transaction of the block's state
+	Write(tx kv.RwTx, blockNum uint64) error
+}
+
+// StateSyncEngine is implemented by the engines which bring data from outside of the chain into the blocks,
+// execution finalizes their blocks with FinalizeWithStateSync to keep that data.
+type StateSyncEngine interface {
+	Engine
+
+	// FinalizeWithStateSync is Finalize which also returns what the block brought in, nil if nothing
+	FinalizeWithStateSync(config *params.ChainConfig, header *types.Header, state *state.IntraBlockState,
+		txs types.Transactions, uncles []*types.Header, receipts types.Receipts, withdrawals []*types.Withdrawal,
+		e EpochReader, chain ChainHeaderReader, syscall SystemCall,
+	) (types.Transactions, types.Receipts, StateSync, error)
+}
+
+// FinalizeWithStateSync finalizes the block, returning what the engine brought into it when it is a StateSyncEngine
+func FinalizeWithStateSync(engine Engine, config *params.ChainConfig, header *types.Header, state *state.IntraBlockState,
+	txs types.Transactions, uncles []*types.Header, receipts types.Receipts, withdrawals []*types.Withdrawal,
+	e EpochReader, chain ChainHeaderReader, syscall SystemCall,
+) (types.Transactions, types.Receipts, StateSync, error) {
+	if stateSyncEngine, ok := engine.(StateSyncEngine); ok {
+		return stateSyncEngine.FinalizeWithStateSync(config, header, state, txs, uncles, receipts, withdrawals, e, chain, syscall)
+	}
+	outTxs, outReceipts, err := engine.Finalize(config, header, state, txs, uncles, receipts, withdrawals, e, chain, syscall)
+	return outTxs, outReceipts, nil, err
+}
+
 type AsyncEngine interface {
 	Engine
 
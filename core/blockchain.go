@@ -68,6 +68,7 @@ type EphemeralExecResult struct {
 	Difficulty       *math.HexOrDecimal256 `json:"currentDifficulty" gencodec:"required"`
 	GasUsed          math.HexOrDecimal64   `json:"gasUsed"`
 	StateSyncReceipt *types.Receipt        `json:"-"`
+	StateSync        consensus.StateSync   `json:"-"` // brought into the block by the engine, to be written with it
 }
 
 func ExecuteBlockEphemerallyForBSC(
@@ -249,6 +250,7 @@ func ExecuteBlockEphemerally(
 		rejectedTxs []*RejectedTx
 		includedTxs types.Transactions
 		receipts    types.Receipts
+		stateSync   consensus.StateSync
 	)
 
 	var excessDataGas *big.Int
@@ -321,7 +323,8 @@ func ExecuteBlockEphemerally(
 	}
 	if !vmConfig.ReadOnly {
 		txs := block.Transactions()
-		if _, _, _, err := FinalizeBlockExecution(engine, stateReader, block.Header(), excessDataGas, txs, block.Uncles(), stateWriter, chainConfig, ibs, receipts, block.Withdrawals(), epochReader, chainReader, false); err != nil {
+		var err error
+		if _, _, _, stateSync, err = FinalizeBlockExecution(engine, stateReader, block.Header(), excessDataGas, txs, block.Uncles(), stateWriter, chainConfig, ibs, receipts, block.Withdrawals(), epochReader, chainReader, false); err != nil {
 			return nil, err
 		}
 	}
@@ -335,6 +338,7 @@ func ExecuteBlockEphemerally(
 		Difficulty:  (*math.HexOrDecimal256)(header.Difficulty),
 		GasUsed:     math.HexOrDecimal64(*usedGas),
 		Rejected:    rejectedTxs,
+		StateSync:   stateSync,
 	}
 
 	return execRs, nil
@@ -368,6 +372,7 @@ func ExecuteBlockEphemerallyBor(
 		rejectedTxs []*RejectedTx
 		includedTxs types.Transactions
 		receipts    types.Receipts
+		stateSync   consensus.StateSync
 	)
 
 	var excessDataGas *big.Int
@@ -437,7 +442,8 @@ func ExecuteBlockEphemerallyBor(
 	}
 	if !vmConfig.ReadOnly {
 		txs := block.Transactions()
-		if _, _, _, err := FinalizeBlockExecution(engine, stateReader, block.Header(), excessDataGas, txs, block.Uncles(), stateWriter, chainConfig, ibs, receipts, block.Withdrawals(), epochReader, chainReader, false); err != nil {
+		var err error
+		if _, _, _, stateSync, err = FinalizeBlockExecution(engine, stateReader, block.Header(), excessDataGas, txs, block.Uncles(), stateWriter, chainConfig, ibs, receipts, block.Withdrawals(), epochReader, chainReader, false); err != nil {
 			return nil, err
 		}
 	}
@@ -471,6 +477,7 @@ func ExecuteBlockEphemerallyBor(
 		GasUsed:          math.HexOrDecimal64(*usedGas),
 		Rejected:         rejectedTxs,
 		StateSyncReceipt: stateSyncReceipt,
+		StateSync:        stateSync,
 	}
 
 	return execRs, nil
@@ -590,27 +597,27 @@ func CallContractTx(contract common.Address, data []byte, ibs *state.IntraBlockS
 func FinalizeBlockExecution(engine consensus.Engine, stateReader state.StateReader, header *types.Header, excessDataGas *big.Int,
 	txs types.Transactions, uncles []*types.Header, stateWriter state.WriterWithChangeSets, cc *params.ChainConfig, ibs *state.IntraBlockState,
 	receipts types.Receipts, withdrawals []*types.Withdrawal, e consensus.EpochReader, headerReader consensus.ChainHeaderReader, isMining bool,
-) (newBlock *types.Block, newTxs types.Transactions, newReceipt types.Receipts, err error) {
+) (newBlock *types.Block, newTxs types.Transactions, newReceipt types.Receipts, stateSync consensus.StateSync, err error) {
 	syscall := func(contract common.Address, data []byte) ([]byte, error) {
 		return SysCallContract(contract, data, *cc, ibs, header, excessDataGas, engine, false /* constCall */)
 	}
 	if isMining {
 		newBlock, newTxs, newReceipt, err = engine.FinalizeAndAssemble(cc, header, ibs, txs, uncles, receipts, withdrawals, e, headerReader, syscall, nil)
 	} else {
-		_, _, err = engine.Finalize(cc, header, ibs, txs, uncles, receipts, withdrawals, e, headerReader, syscall)
+		_, _, stateSync, err = consensus.FinalizeWithStateSync(engine, cc, header, ibs, txs, uncles, receipts, withdrawals, e, headerReader, syscall)
 	}
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
 	if err := ibs.CommitBlock(cc.Rules(header.Number.Uint64(), header.Time), stateWriter); err != nil {
-		return nil, nil, nil, fmt.Errorf("committing block %d failed: %w", header.Number.Uint64(), err)
+		return nil, nil, nil, nil, fmt.Errorf("committing block %d failed: %w", header.Number.Uint64(), err)
 	}
 
 	if err := stateWriter.WriteChangeSets(); err != nil {
-		return nil, nil, nil, fmt.Errorf("writing changesets for block %d failed: %w", header.Number.Uint64(), err)
+		return nil, nil, nil, nil, fmt.Errorf("writing changesets for block %d failed: %w", header.Number.Uint64(), err)
 	}
-	return newBlock, newTxs, newReceipt, nil
+	return newBlock, newTxs, newReceipt, stateSync, nil
 }
 
 func InitializeBlockExecution(engine consensus.Engine, chain consensus.ChainHeaderReader, epochReader consensus.EpochReader, header *types.Header, excessDataGas *big.Int, txs types.Transactions, uncles []*types.Header, cc *params.ChainConfig, ibs *state.IntraBlockState) error {
@@ -0,0 +1,147 @@
+package rawdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// Tables of the bor state sync events and spans applied by the blocks, written by the execution stage.
+// erigon-lib doesn't know about them, so they are added to the chaindata tables here.
+const (
+	BorEvents    = "BorEvents"    // event_id -> block_num_u64 + event payload
+	BorEventNums = "BorEventNums" // block_num_u64 -> event_id of the first event of the block
+	BorSpans     = "BorSpans"     // span_id -> block_num_u64 + span payload
+)
+
+func init() {
+	for _, name := range []string{BorEvents, BorEventNums, BorSpans} {
+		if _, ok := kv.ChaindataTablesCfg[name]; !ok {
+			kv.ChaindataTables = append(kv.ChaindataTables, name)
+			kv.ChaindataTablesCfg[name] = kv.TableCfgItem{}
+		}
+	}
+	sort.Strings(kv.ChaindataTables)
+}
+
+// WriteBorEvents stores the payloads of the consecutive events from firstID applied by the block
+func WriteBorEvents(tx kv.RwTx, blockNum uint64, firstID uint64, events [][]byte) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := tx.Put(BorEventNums, common2.EncodeTs(blockNum), common2.EncodeTs(firstID)); err != nil {
+		return err
+	}
+	for i, event := range events {
+		v := make([]byte, 8+len(event))
+		binary.BigEndian.PutUint64(v, blockNum)
+		copy(v[8:], event)
+		if err := tx.Put(BorEvents, common2.EncodeTs(firstID+uint64(i)), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadBorEvent returns the payload of the event and the number of the block which applied it, nil if there is no such event
+func ReadBorEvent(tx kv.Getter, id uint64) (blockNum uint64, event []byte, err error) {
+	v, err := tx.GetOne(BorEvents, common2.EncodeTs(id))
+	if err != nil || v == nil {
+		return 0, nil, err
+	}
+	if len(v) < 8 {
+		return 0, nil, fmt.Errorf("invalid bor event %d: %x", id, v)
+	}
+	return binary.BigEndian.Uint64(v), common2.Copy(v[8:]), nil
+}
+
+// ReadBorEventsOfBlock returns the payloads of the events applied by the block, in the order of their ids
+func ReadBorEventsOfBlock(tx kv.Tx, blockNum uint64) ([][]byte, error) {
+	firstID, err := tx.GetOne(BorEventNums, common2.EncodeTs(blockNum))
+	if err != nil || len(firstID) != 8 {
+		return nil, err
+	}
+	c, err := tx.Cursor(BorEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	var events [][]byte
+	for k, v, err := c.Seek(firstID); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		if len(v) < 8 || binary.BigEndian.Uint64(v) != blockNum {
+			break
+		}
+		events = append(events, common2.Copy(v[8:]))
+	}
+	return events, nil
+}
+
+// WriteBorSpan stores the payload of the span committed by the block
+func WriteBorSpan(tx kv.RwTx, spanID uint64, blockNum uint64, span []byte) error {
+	v := make([]byte, 8+len(span))
+	binary.BigEndian.PutUint64(v, blockNum)
+	copy(v[8:], span)
+	return tx.Put(BorSpans, common2.EncodeTs(spanID), v)
+}
+
+// ReadBorSpan returns the payload of the span and the number of the block which committed it, nil if there is no such span
+func ReadBorSpan(tx kv.Getter, spanID uint64) (blockNum uint64, span []byte, err error) {
+	v, err := tx.GetOne(BorSpans, common2.EncodeTs(spanID))
+	if err != nil || v == nil {
+		return 0, nil, err
+	}
+	if len(v) < 8 {
+		return 0, nil, fmt.Errorf("invalid bor span %d: %x", spanID, v)
+	}
+	return binary.BigEndian.Uint64(v), common2.Copy(v[8:]), nil
+}
+
+// TruncateBorEvents removes the events and the spans applied by the given block number or newer
+func TruncateBorEvents(tx kv.RwTx, blockNum uint64) error {
+	var firstID []byte
+	if err := tx.ForEach(BorEventNums, common2.EncodeTs(blockNum), func(k, v []byte) error {
+		if firstID == nil {
+			firstID = common2.Copy(v)
+		}
+		return tx.Delete(BorEventNums, k)
+	}); err != nil {
+		return err
+	}
+	if firstID != nil {
+		// events are applied in the order of their ids, so all the later ones go as well
+		if err := tx.ForEach(BorEvents, firstID, func(k, _ []byte) error {
+			return tx.Delete(BorEvents, k)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// spans are committed in the order of their ids as well
+	var spans [][]byte
+	c, err := tx.Cursor(BorSpans)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, v, err := c.Last(); k != nil; k, v, err = c.Prev() {
+		if err != nil {
+			return err
+		}
+		if len(v) >= 8 && binary.BigEndian.Uint64(v) < blockNum {
+			break
+		}
+		spans = append(spans, common2.Copy(k))
+	}
+	for _, k := range spans {
+		if err := tx.Delete(BorSpans, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
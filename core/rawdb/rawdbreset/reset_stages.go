@@ -193,7 +193,7 @@ var Tables = map[stages.SyncStage][]string{
 }
 var stateBuckets = []string{
 	kv.PlainState, kv.HashedAccounts, kv.HashedStorage, kv.TrieOfAccounts, kv.TrieOfStorage,
-	kv.Epoch, kv.PendingEpoch, kv.BorReceipts,
+	kv.Epoch, kv.PendingEpoch, kv.BorReceipts, rawdb.BorEvents, rawdb.BorEventNums, rawdb.BorSpans,
 	kv.Code, kv.PlainContractCode, kv.ContractCode, kv.IncarnationMap,
 }
 var stateHistoryBuckets = []string{
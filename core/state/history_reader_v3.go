@@ -1,8 +1,13 @@
 package state
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 
+	"github.com/google/btree"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	libstate "github.com/ledgerwatch/erigon-lib/state"
 	"github.com/ledgerwatch/erigon/common"
@@ -18,6 +23,9 @@ type HistoryReaderV3 struct {
 	trace bool
 	tx    kv.Tx
 	ttx   kv.TemporalTx
+
+	storageHistory       *libstate.HistoryContext
+	storageFilesEndTxNum uint64
 }
 
 func NewHistoryReaderV3() *HistoryReaderV3 {
@@ -36,6 +44,13 @@ func (hr *HistoryReaderV3) SetTx(tx kv.Tx) {
 func (hr *HistoryReaderV3) SetTxNum(txNum uint64) { hr.txNum = txNum }
 func (hr *HistoryReaderV3) SetTrace(trace bool)   { hr.trace = trace }
 
+// SetStorageHistory - storage history of the aggregator and the txNum up to which it is kept in files,
+// used only by ForEachStorage
+func (hr *HistoryReaderV3) SetStorageHistory(hc *libstate.HistoryContext, filesEndTxNum uint64) {
+	hr.storageHistory = hc
+	hr.storageFilesEndTxNum = filesEndTxNum
+}
+
 func (hr *HistoryReaderV3) ReadAccountData(address common.Address) (*accounts.Account, error) {
 	var enc []byte
 	var ok bool
@@ -184,164 +199,161 @@ func (hr *HistoryReaderV3) ReadAccountIncarnation(address common.Address) (uint6
 	return 0, nil
 }
 
-/*
-func (s *HistoryReaderV3) ForEachStorage(addr common.Address, startLocation common.Hash, cb func(key, seckey common.Hash, value uint256.Int) bool, maxResults int) error {
-	acc, err := s.ReadAccountData(addr)
-	if err != nil {
-		return err
+// ForEachStorage iterates over the storage of the account at txNum, ordered by storage location and starting from
+// startLocation. Locations changed since txNum are restored from the storage history (see SetStorageHistory),
+// the rest are read from PlainState, which holds the latest state
+func (hr *HistoryReaderV3) ForEachStorage(addr common.Address, startLocation common.Hash, cb func(key, seckey common.Hash, value uint256.Int) bool, maxResults int) error {
+	if hr.storageHistory == nil {
+		return fmt.Errorf("ForEachStorage: storage history is not set")
 	}
-
-	var k [length.Addr + length.Incarnation + length.Hash]byte
-	copy(k[:], addr[:])
-	binary.BigEndian.PutUint64(k[length.Addr:], acc.Incarnation)
-	copy(k[length.Addr+length.Incarnation:], startLocation[:])
-
-	//toKey := make([]byte, 4)
-	//bigCurrent.FillBytes(toKey)
-	//
-	//bigStep := big.NewInt(0x100000000)
-	//bigStep.Div(bigStep, bigCount)
-	//bigCurrent.Add(bigCurrent, bigStep)
-	//toKey = make([]byte, 4)
-	//bigCurrent.FillBytes(toKey)
-
 	st := btree.New(16)
-	min := &storageItem{key: startLocation}
-	overrideCounter := 0
-	if t, ok := s.storage[addr]; ok {
-		t.AscendGreaterOrEqual(min, func(i btree.Item) bool {
-			item := i.(*storageItem)
-			st.ReplaceOrInsert(item)
-			if !item.value.IsZero() {
-				copy(lastKey[:], item.key[:])
-				// Only count non-zero items
-				overrideCounter++
-			}
-			return overrideCounter < maxResults
-		})
-	}
-	numDeletes := st.Len() - overrideCounter
+	changed := map[common.Hash]struct{}{}
 
-	var lastKey common.Hash
-	iterator := s.ac.IterateStorageHistory(startLocation.Bytes(), nil, s.txNum)
-	for iterator.HasNext() {
-		k, vs, p := iterator.Next()
-		if len(vs) == 0 {
-			// Skip deleted entries
-			continue
+	from := append(common.CopyBytes(addr[:]), startLocation[:]...)
+	// st keeps at most maxResults changed locations which still existed at txNum: locations after the last of them
+	// can't be among the results, so the history walks stop there
+	if err := hr.forEachChangedStorage(addr, from, func(k []byte) (bool, error) {
+		si := storageItem{}
+		copy(si.key[:], k[length.Addr:])
+		if maxResults > 0 && st.Len() >= maxResults && bytes.Compare(si.key[:], st.Max().(*storageItem).key[:]) > 0 {
+			return false, nil
 		}
-		kLoc := k[20:]
-		keyHash, err1 := common.HashData(kLoc)
-		if err1 != nil {
-			return err1
+		if _, ok := changed[si.key]; ok {
+			return true, nil
 		}
-		//fmt.Printf("seckey: %x\n", seckey)
-		si := storageItem{}
-		copy(si.key[:], kLoc)
-		copy(si.seckey[:], keyHash[:])
-		if st.Has(&si) {
-			continue
+		v, ok, err := hr.storageHistory.GetNoStateWithRecent(k, hr.txNum, hr.tx)
+		if err != nil {
+			return false, err
 		}
-		si.value.SetBytes(vs)
+		if !ok {
+			// Not changed since txNum, the latest state holds it
+			return true, nil
+		}
+		changed[si.key] = struct{}{}
+		si.value.SetBytes(v)
+		if si.value.IsZero() {
+			// Did not exist at txNum
+			return true, nil
+		}
+		keyHash, err := common.HashData(si.key[:])
+		if err != nil {
+			return false, err
+		}
+		copy(si.seckey[:], keyHash[:])
 		st.ReplaceOrInsert(&si)
-		if bytes.Compare(kLoc, lastKey[:]) > 0 {
-			// Beyond overrides
-			if st.Len() < maxResults+numDeletes {
+		if st.Len() > maxResults {
+			st.DeleteMax()
+		}
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	enc, err := hr.tx.GetOne(kv.PlainState, addr[:])
+	if err != nil {
+		return err
+	}
+	if len(enc) > 0 {
+		incarnation, err := accounts.DecodeIncarnationFromStorage(enc)
+		if err != nil {
+			return err
+		}
+		prefix := dbutils.PlainGenerateStoragePrefix(addr[:], incarnation)
+		fromKey := append(common.CopyBytes(prefix), startLocation[:]...)
+		c, err := hr.tx.Cursor(kv.PlainState)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		// More than maxResults unchanged locations are never needed
+		unchanged := 0
+		for k, v, err := c.Seek(fromKey); k != nil && unchanged < maxResults; k, v, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			if !bytes.HasPrefix(k, prefix) {
+				break
+			}
+			si := storageItem{}
+			copy(si.key[:], k[len(prefix):])
+			if _, ok := changed[si.key]; ok {
 				continue
 			}
-			break
+			keyHash, err := common.HashData(si.key[:])
+			if err != nil {
+				return err
+			}
+			copy(si.seckey[:], keyHash[:])
+			si.value.SetBytes(v)
+			st.ReplaceOrInsert(&si)
+			unchanged++
 		}
-
 	}
 
 	results := 0
-	var innerErr error
-	st.AscendGreaterOrEqual(min, func(i btree.Item) bool {
+	st.Ascend(func(i btree.Item) bool {
 		item := i.(*storageItem)
-		if !item.value.IsZero() {
-			// Skip if value == 0
-			cb(item.key, item.seckey, item.value)
-			results++
+		if item.value.IsZero() {
+			return true
 		}
-		return results < maxResults
+		results++
+		return cb(item.key, item.seckey, item.value) && results < maxResults
 	})
-	return innerErr
+	return nil
 }
-*/
 
-/*
-func (s *PlainState) ForEachStorage(addr common.Address, startLocation common.Hash, cb func(key, seckey common.Hash, value uint256.Int) bool, maxResults int) error {
-	st := btree.New(16)
-	var k [length.Addr + length.Incarnation + length.Hash]byte
-	copy(k[:], addr[:])
-	accData, err := GetAsOf(s.tx, s.accHistoryC, s.accChangesC, false , addr[:], s.blockNr)
-	if err != nil {
-		return err
+// forEachChangedStorage calls f in key order for the storage keys of the account, starting from the given key, which
+// were changed at or after txNum, until f returns false. History kept in files and recent history are walked one after
+// the other, f may stop each of them. Recent history is looked up in the storage history index, which is walked only
+// within the account's keys. History kept in files can be walked only sequentially, so it is scanned just when txNum
+// is older than its end
+func (hr *HistoryReaderV3) forEachChangedStorage(addr common.Address, from []byte, f func(k []byte) (bool, error)) error {
+	if hr.txNum < hr.storageFilesEndTxNum {
+		it := hr.storageHistory.IterateChanged(hr.txNum, hr.storageFilesEndTxNum, hr.tx)
+		defer it.Close()
+		var k, v []byte
+		for it.HasNext() {
+			k, v = it.Next(k[:0], v[:0])
+			if bytes.Compare(k, from) < 0 {
+				continue
+			}
+			if !bytes.HasPrefix(k, addr[:]) {
+				break
+			}
+			more, err := f(k)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+		}
 	}
-	var acc accounts.Account
-	if err := acc.DecodeForStorage(accData); err != nil {
-		log.Error("Error decoding account", "err", err)
+
+	idx, err := hr.tx.CursorDupSort(kv.StorageIdx)
+	if err != nil {
 		return err
 	}
-	binary.BigEndian.PutUint64(k[length.Addr:], acc.Incarnation)
-	copy(k[length.Addr+length.Incarnation:], startLocation[:])
-	var lastKey common.Hash
-	overrideCounter := 0
-	min := &storageItem{key: startLocation}
-	if t, ok := s.storage[addr]; ok {
-		t.AscendGreaterOrEqual(min, func(i btree.Item) bool {
-			item := i.(*storageItem)
-			st.ReplaceOrInsert(item)
-			if !item.value.IsZero() {
-				copy(lastKey[:], item.key[:])
-				// Only count non-zero items
-				overrideCounter++
-			}
-			return overrideCounter < maxResults
-		})
-	}
-	numDeletes := st.Len() - overrideCounter
-	if err := WalkAsOfStorage(s.tx, addr, acc.Incarnation, startLocation, s.blockNr, func(kAddr, kLoc, vs []byte) (bool, error) {
-		if !bytes.Equal(kAddr, addr[:]) {
-			return false, nil
+	defer idx.Close()
+	fromTxNum := make([]byte, 8)
+	binary.BigEndian.PutUint64(fromTxNum, hr.txNum)
+	for k, _, err := idx.Seek(from); k != nil; k, _, err = idx.NextNoDup() {
+		if err != nil {
+			return err
 		}
-		if len(vs) == 0 {
-			// Skip deleted entries
-			return true, nil
+		if !bytes.HasPrefix(k, addr[:]) {
+			break
 		}
-		keyHash, err1 := common.HashData(kLoc)
-		if err1 != nil {
-			return false, err1
+		txNum, err := idx.SeekBothRange(k, fromTxNum)
+		if err != nil {
+			return err
 		}
-		//fmt.Printf("seckey: %x\n", seckey)
-		si := storageItem{}
-		copy(si.key[:], kLoc)
-		copy(si.seckey[:], keyHash[:])
-		if st.Has(&si) {
-			return true, nil
+		if txNum == nil {
+			continue
 		}
-		si.value.SetBytes(vs)
-		st.ReplaceOrInsert(&si)
-		if bytes.Compare(kLoc, lastKey[:]) > 0 {
-			// Beyond overrides
-			return st.Len() < maxResults+numDeletes, nil
+		if more, err := f(k); err != nil || !more {
+			return err
 		}
-		return st.Len() < maxResults+overrideCounter+numDeletes, nil
-	}); err != nil {
-		log.Error("ForEachStorage walk error", "err", err)
-		return err
 	}
-	results := 0
-	var innerErr error
-	st.AscendGreaterOrEqual(min, func(i btree.Item) bool {
-		item := i.(*storageItem)
-		if !item.value.IsZero() {
-			// Skip if value == 0
-			cb(item.key, item.seckey, item.value)
-			results++
-		}
-		return results < maxResults
-	})
-	return innerErr
+	return nil
 }
-*/
@@ -37,6 +37,7 @@ type StateV3 struct {
 	queue        exec22.TxTaskQueue
 	queueLock    sync.Mutex
 	changes      map[string]*btree2.Map[string, []byte]
+	stateSyncs   map[uint64]exec22.StateSync // block number -> what the engine brought into the block
 	sizeEstimate uint64
 	txsDone      *atomic2.Uint64
 	finished     bool
@@ -47,6 +48,7 @@ func NewStateV3() *StateV3 {
 		triggers:     map[uint64]*exec22.TxTask{},
 		senderTxNums: map[common.Address]uint64{},
 		changes:      map[string]*btree2.Map[string, []byte]{},
+		stateSyncs:   map[uint64]exec22.StateSync{},
 		txsDone:      atomic2.NewUint64(0),
 	}
 	rs.receiveWork = sync.NewCond(&rs.queueLock)
@@ -124,6 +126,12 @@ func (rs *StateV3) Flush(ctx context.Context, rwTx kv.RwTx, logPrefix string, lo
 		}
 		t.Clear()
 	}
+	for blockNum, stateSync := range rs.stateSyncs {
+		if err := stateSync.Write(rwTx, blockNum); err != nil {
+			return err
+		}
+		delete(rs.stateSyncs, blockNum)
+	}
 	rs.sizeEstimate = 0
 	return nil
 }
@@ -369,6 +377,9 @@ func (rs *StateV3) appplyState(roTx kv.Tx, txTask *exec22.TxTask, agg *libstate.
 			}
 		}
 	}
+	if txTask.StateSync != nil {
+		rs.stateSyncs[txTask.BlockNum] = txTask.StateSync
+	}
 	return nil
 }
 
@@ -133,6 +133,7 @@ type Snapshot struct {
 	Produce        bool // produce new snapshots
 	NoDownloader   bool // possible to use snapshots without calling Downloader
 	Verify         bool // verify snapshots on startup
+	VerifySegments bool // check segments and indices on startup, rebuild bad indices and download bad segments again
 	DownloaderAddr string
 }
 
@@ -147,12 +148,16 @@ func (s Snapshot) String() string {
 	if !s.Produce {
 		out = append(out, "--"+FlagSnapStop+"=true")
 	}
+	if s.VerifySegments {
+		out = append(out, "--"+FlagSnapVerify+"=true")
+	}
 	return strings.Join(out, " ")
 }
 
 var (
 	FlagSnapKeepBlocks = "snap.keepblocks"
 	FlagSnapStop       = "snap.stop"
+	FlagSnapVerify     = "snap.verify"
 )
 
 func NewSnapCfg(enabled, keepBlocks, produce bool) Snapshot {
@@ -233,6 +238,9 @@ type Config struct {
 
 	// No heimdall service
 	WithoutHeimdall bool
+
+	// Directory to record the heimdall responses into, for the replay with --bor.heimdall=file://<dir>
+	HeimdallRecordDir string
 	// Ethstats service
 	Ethstats string
 	// Consensus layer
@@ -255,6 +263,8 @@ type Sync struct {
 
 	BlockDownloaderWindow      int
 	BodyDownloadTimeoutSeconds int // TODO: change to duration
+	// ParallelStages runs stages which don't depend on each other (the history and lookup indices) concurrently
+	ParallelStages bool
 }
 
 // Chains where snapshots are enabled by default
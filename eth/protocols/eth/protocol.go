@@ -35,13 +35,22 @@ import (
 const (
 	ETH66 = 66
 	ETH67 = 67
+	ETH68 = 68
 )
 
 var ProtocolToString = map[uint]string{
 	ETH66: "eth66",
 	ETH67: "eth67",
+	ETH68: "eth68",
 }
 
+// The sentry interface of erigon-lib predates eth/68, these are the values upstream gives
+// to the typed transaction announcements and to the protocol.
+const (
+	MessageIdNewPooledTransactionHashes68 = proto_sentry.MessageId(32)
+	ProtocolETH68                         = proto_sentry.Protocol(3)
+)
+
 // ProtocolName is the official short name of the `eth` protocol used during
 // devp2p capability negotiation.
 const ProtocolName = "eth"
@@ -102,6 +111,20 @@ var ToProto = map[uint]map[uint64]proto_sentry.MessageId{
 		GetPooledTransactionsMsg:      proto_sentry.MessageId_GET_POOLED_TRANSACTIONS_66,
 		PooledTransactionsMsg:         proto_sentry.MessageId_POOLED_TRANSACTIONS_66,
 	},
+	ETH68: {
+		GetBlockHeadersMsg:            proto_sentry.MessageId_GET_BLOCK_HEADERS_66,
+		BlockHeadersMsg:               proto_sentry.MessageId_BLOCK_HEADERS_66,
+		GetBlockBodiesMsg:             proto_sentry.MessageId_GET_BLOCK_BODIES_66,
+		BlockBodiesMsg:                proto_sentry.MessageId_BLOCK_BODIES_66,
+		GetReceiptsMsg:                proto_sentry.MessageId_GET_RECEIPTS_66,
+		ReceiptsMsg:                   proto_sentry.MessageId_RECEIPTS_66,
+		NewBlockHashesMsg:             proto_sentry.MessageId_NEW_BLOCK_HASHES_66,
+		NewBlockMsg:                   proto_sentry.MessageId_NEW_BLOCK_66,
+		TransactionsMsg:               proto_sentry.MessageId_TRANSACTIONS_66,
+		NewPooledTransactionHashesMsg: MessageIdNewPooledTransactionHashes68,
+		GetPooledTransactionsMsg:      proto_sentry.MessageId_GET_POOLED_TRANSACTIONS_66,
+		PooledTransactionsMsg:         proto_sentry.MessageId_POOLED_TRANSACTIONS_66,
+	},
 }
 
 var FromProto = map[uint]map[proto_sentry.MessageId]uint64{
@@ -135,6 +158,22 @@ var FromProto = map[uint]map[proto_sentry.MessageId]uint64{
 		proto_sentry.MessageId_GET_POOLED_TRANSACTIONS_66:       GetPooledTransactionsMsg,
 		proto_sentry.MessageId_POOLED_TRANSACTIONS_66:           PooledTransactionsMsg,
 	},
+	ETH68: {
+		proto_sentry.MessageId_GET_BLOCK_HEADERS_66:       GetBlockHeadersMsg,
+		proto_sentry.MessageId_BLOCK_HEADERS_66:           BlockHeadersMsg,
+		proto_sentry.MessageId_GET_BLOCK_BODIES_66:        GetBlockBodiesMsg,
+		proto_sentry.MessageId_BLOCK_BODIES_66:            BlockBodiesMsg,
+		proto_sentry.MessageId_GET_RECEIPTS_66:            GetReceiptsMsg,
+		proto_sentry.MessageId_RECEIPTS_66:                ReceiptsMsg,
+		proto_sentry.MessageId_NEW_BLOCK_HASHES_66:        NewBlockHashesMsg,
+		proto_sentry.MessageId_NEW_BLOCK_66:               NewBlockMsg,
+		proto_sentry.MessageId_TRANSACTIONS_66:            TransactionsMsg,
+		MessageIdNewPooledTransactionHashes68:             NewPooledTransactionHashesMsg,
+		proto_sentry.MessageId_GET_POOLED_TRANSACTIONS_66: GetPooledTransactionsMsg,
+		proto_sentry.MessageId_POOLED_TRANSACTIONS_66:     PooledTransactionsMsg,
+		// plain hashes of erigon-lib's txpool, the sentry adds the types and the sizes before sending them
+		proto_sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66: NewPooledTransactionHashesMsg,
+	},
 }
 
 // Packet represents a p2p message in the `eth` protocol.
@@ -731,6 +770,22 @@ type ReceiptsRLPPacket66 struct {
 // NewPooledTransactionHashesPacket represents a transaction announcement packet.
 type NewPooledTransactionHashesPacket []common.Hash
 
+// NewPooledTransactionHashesPacket68 represents a transaction announcement packet on eth/68 and newer,
+// the type and the size of each announced transaction go along with its hash.
+type NewPooledTransactionHashesPacket68 struct {
+	Types  []byte
+	Sizes  []uint32
+	Hashes []common.Hash
+}
+
+// Validate checks that the lists of the announcement are of the same length
+func (p *NewPooledTransactionHashesPacket68) Validate() error {
+	if len(p.Types) != len(p.Hashes) || len(p.Sizes) != len(p.Hashes) {
+		return fmt.Errorf("invalid len of fields: hashes %d, types %d, sizes %d", len(p.Hashes), len(p.Types), len(p.Sizes))
+	}
+	return nil
+}
+
 // GetPooledTransactionsPacket represents a transaction query.
 type GetPooledTransactionsPacket []common.Hash
 
@@ -917,6 +972,9 @@ func (*ReceiptsPacket) Kind() byte   { return ReceiptsMsg }
 func (*NewPooledTransactionHashesPacket) Name() string { return "NewPooledTransactionHashes" }
 func (*NewPooledTransactionHashesPacket) Kind() byte   { return NewPooledTransactionHashesMsg }
 
+func (*NewPooledTransactionHashesPacket68) Name() string { return "NewPooledTransactionHashes" }
+func (*NewPooledTransactionHashesPacket68) Kind() byte   { return NewPooledTransactionHashesMsg }
+
 func (*GetPooledTransactionsPacket) Name() string { return "GetPooledTransactions" }
 func (*GetPooledTransactionsPacket) Kind() byte   { return GetPooledTransactionsMsg }
 
@@ -281,3 +281,23 @@ func TestDecodePooledTx(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestNewPooledTransactionHashes68(t *testing.T) {
+	packet := &NewPooledTransactionHashesPacket68{
+		Types:  []byte{types.LegacyTxType, types.DynamicFeeTxType},
+		Sizes:  []uint32{100, 1024},
+		Hashes: []common.Hash{common.HexToHash("deadc0de"), common.HexToHash("feedbeef")},
+	}
+	want := common.FromHex("f84c820002c464820400f842a000000000000000000000000000000000000000000000000000000000deadc0dea000000000000000000000000000000000000000000000000000000000feedbeef")
+	have, err := rlp.EncodeToBytes(packet)
+	assert.NoError(t, err)
+	assert.Equal(t, want, have)
+
+	decoded := new(NewPooledTransactionHashesPacket68)
+	assert.NoError(t, rlp.DecodeBytes(have, decoded))
+	assert.Equal(t, packet, decoded)
+	assert.NoError(t, decoded.Validate())
+
+	decoded.Sizes = decoded.Sizes[:1]
+	assert.Error(t, decoded.Validate())
+}
@@ -0,0 +1,554 @@
+package snap
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+const (
+	// softResponseLimit is the target maximum size of replies to data retrievals.
+	softResponseLimit = 2 * 1024 * 1024
+
+	// maxCodeLookups is the maximum number of bytecodes to serve. This number is
+	// there to limit the number of disk lookups.
+	maxCodeLookups = 1024
+
+	// stateLookupSlack defines the ratio by how much a state response can exceed
+	// the requested limit in order to try and avoid breaking up contracts into
+	// multiple packages and proving them.
+	stateLookupSlack = 0.1
+
+	// maxTrieNodeLookups is the maximum number of state trie nodes to serve. This
+	// number is there to limit the number of disk lookups.
+	maxTrieNodeLookups = 1024
+
+	// maxTrieNodeTimeSpent is the maximum time we should spend on looking up trie nodes.
+	// If we spend too much time, then it's a fairly high chance of timing out
+	// at the remote side, which means all the work is in vain.
+	maxTrieNodeTimeSpent = 5 * time.Second
+)
+
+var maxHash = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+// Handler serves the snap/1 requests of the peers. Erigon keeps the state of the latest block only, so only requests
+// for the state root of the block up to which the IntermediateHashes stage has progressed are answered, the requests
+// for the roots of older blocks (snap syncing peers pivot some blocks behind the head) get empty responses. Accounts and
+// storage come from the hashed state, trie nodes and range proofs are built with turbo/trie on top of the
+// intermediate hashes, like the proofs of eth_getProof.
+type Handler struct {
+	ctx          context.Context
+	db           kv.RoDB
+	headerReader services.HeaderReader
+}
+
+func NewHandler(ctx context.Context, db kv.RoDB, headerReader services.HeaderReader) *Handler {
+	return &Handler{ctx: ctx, db: db, headerReader: headerReader}
+}
+
+// Protocol returns the snap/1 capability, to be run by the p2p server alongside eth
+func (h *Handler) Protocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    ProtocolName,
+		Version: SNAP1,
+		Length:  protocolLength,
+		Run: func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+			for {
+				if err := h.handleMessage(rw); err != nil {
+					log.Trace("[snap] Message handling failed", "peer", peer.ID(), "err", err)
+					return err
+				}
+			}
+		},
+		NodeInfo: func() interface{} { return nil },
+		PeerInfo: func(peerID [64]byte) interface{} { return nil },
+	}
+}
+
+// handleMessage is invoked whenever an inbound message is received from a remote peer.
+// The remote connection is torn down upon returning any error.
+func (h *Handler) handleMessage(rw p2p.MsgReadWriter) error {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
+	}
+
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var req GetAccountRangePacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		var accounts []*AccountData
+		var proofs [][]byte
+		if err := h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			accounts, proofs, err = h.ServiceGetAccountRangeQuery(tx, &req)
+			return err
+		}); err != nil {
+			log.Warn("[snap] Serving account range failed", "err", err)
+		}
+		return p2p.Send(rw, AccountRangeMsg, &AccountRangePacket{ID: req.ID, Accounts: accounts, Proof: proofs})
+
+	case GetStorageRangesMsg:
+		var req GetStorageRangesPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		var slots [][]*StorageData
+		var proofs [][]byte
+		if err := h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			slots, proofs, err = h.ServiceGetStorageRangesQuery(tx, &req)
+			return err
+		}); err != nil {
+			log.Warn("[snap] Serving storage ranges failed", "err", err)
+		}
+		return p2p.Send(rw, StorageRangesMsg, &StorageRangesPacket{ID: req.ID, Slots: slots, Proof: proofs})
+
+	case GetByteCodesMsg:
+		var req GetByteCodesPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		var codes [][]byte
+		if err := h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			codes, err = h.ServiceGetByteCodesQuery(tx, &req)
+			return err
+		}); err != nil {
+			log.Warn("[snap] Serving byte codes failed", "err", err)
+		}
+		return p2p.Send(rw, ByteCodesMsg, &ByteCodesPacket{ID: req.ID, Codes: codes})
+
+	case GetTrieNodesMsg:
+		var req GetTrieNodesPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		var nodes [][]byte
+		if err := h.db.View(h.ctx, func(tx kv.Tx) (err error) {
+			nodes, err = h.ServiceGetTrieNodesQuery(tx, &req, time.Now())
+			return err
+		}); err != nil {
+			if errors.Is(err, errBadRequest) {
+				return err
+			}
+			log.Warn("[snap] Serving trie nodes failed", "err", err)
+		}
+		return p2p.Send(rw, TrieNodesMsg, &TrieNodesPacket{ID: req.ID, Nodes: nodes})
+
+	case AccountRangeMsg, StorageRangesMsg, ByteCodesMsg, TrieNodesMsg:
+		// erigon doesn't snap sync, so it never asks for these
+		return nil
+
+	default:
+		return fmt.Errorf("%w: %v", errInvalidMsgCode, msg.Code)
+	}
+}
+
+// hasState tells whether the hashed state and the intermediate hashes are of the block with the state root
+func (h *Handler) hasState(tx kv.Tx, root common.Hash) (bool, error) {
+	trieProgress, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return false, err
+	}
+	hashStateProgress, err := stages.GetStageProgress(tx, stages.HashState)
+	if err != nil {
+		return false, err
+	}
+	if hashStateProgress != trieProgress {
+		return false, nil
+	}
+	header, err := h.headerReader.HeaderByNumber(h.ctx, tx, trieProgress)
+	if err != nil || header == nil {
+		return false, err
+	}
+	return header.Root == root, nil
+}
+
+// loadTrie builds the part of the state trie along the keys, the rest of it is hashed.
+// Keys are in HEX encoding, storage keys are prefixed by the account key and the incarnation
+func (h *Handler) loadTrie(tx kv.Tx, root common.Hash, keys [][]byte) (*trie.Trie, error) {
+	rl, proofRl := trie.NewRetainList(0), trie.NewRetainList(0)
+	for _, key := range keys {
+		rl.AddHex(key)
+		proofRl.AddHex(key)
+	}
+	loader := trie.NewFlatDBTrieLoader("snap")
+	if err := loader.Reset(rl, nil, nil, false); err != nil {
+		return nil, err
+	}
+	t, err := loader.CalcSubTrie(tx, proofRl, h.ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	if hash := t.Hash(); hash != root {
+		return nil, fmt.Errorf("state root mismatch: computed %x, expected %x", hash, root)
+	}
+	return t, nil
+}
+
+// ServiceGetAccountRangeQuery assembles the response to an account range query.
+// It is exposed to allow external packages to test protocol behavior.
+func (h *Handler) ServiceGetAccountRangeQuery(tx kv.Tx, req *GetAccountRangePacket) ([]*AccountData, [][]byte, error) {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	if ok, err := h.hasState(tx, req.Root); err != nil || !ok {
+		return nil, nil, err
+	}
+
+	c, err := tx.Cursor(kv.HashedAccounts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.Close()
+	keys := [][]byte{keyToNibbles(req.Origin[:])}
+	var hashes []common.Hash
+	var size uint64
+	for k, v, err := c.Seek(req.Origin[:]); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, nil, err
+		}
+		hashes = append(hashes, common.BytesToHash(k))
+		keys = append(keys, keyToNibbles(k))
+		// the storage root isn't known before the trie is loaded, it's accounted as if it was there
+		size += uint64(length.Hash + len(v) + length.Hash)
+		if bytes.Compare(k, req.Limit[:]) >= 0 || size > req.Bytes {
+			break
+		}
+	}
+
+	t, err := h.loadTrie(tx, req.Root, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	accs := make([]*AccountData, 0, len(hashes))
+	for _, hash := range hashes {
+		acc, ok := t.GetAccount(hash[:])
+		if !ok || acc == nil {
+			return nil, nil, fmt.Errorf("account %x is not loaded into the trie", hash)
+		}
+		body, err := slimAccountRLP(acc)
+		if err != nil {
+			return nil, nil, err
+		}
+		accs = append(accs, &AccountData{Hash: hash, Body: body})
+	}
+
+	var proofs proofSet
+	proof, err := t.Prove(req.Origin[:], 0, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	proofs.add(proof)
+	if len(hashes) > 0 {
+		if proof, err = t.Prove(hashes[len(hashes)-1][:], 0, false); err != nil {
+			return nil, nil, err
+		}
+		proofs.add(proof)
+	}
+	return accs, proofs.nodes, nil
+}
+
+// ServiceGetStorageRangesQuery assembles the response to a storage ranges query.
+// It is exposed to allow external packages to test protocol behavior.
+func (h *Handler) ServiceGetStorageRangesQuery(tx kv.Tx, req *GetStorageRangesPacket) ([][]*StorageData, [][]byte, error) {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	if ok, err := h.hasState(tx, req.Root); err != nil || !ok {
+		return nil, nil, err
+	}
+	// Calculate the hard limit at which to abort, even if mid storage trie
+	hardLimit := uint64(float64(req.Bytes) * (1 + stateLookupSlack))
+
+	c, err := tx.CursorDupSort(kv.HashedStorage)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.Close()
+	var (
+		slots  [][]*StorageData
+		proofs proofSet
+		size   uint64
+	)
+	for i, account := range req.Accounts {
+		// If we've exceeded the requested data limit, abort without opening
+		// a new storage range (that we'd need to prove due to exceeded size)
+		if size >= req.Bytes {
+			break
+		}
+		// The first account might start from a different origin and the last
+		// might end before the storage trie ends
+		var origin common.Hash
+		if len(req.Origin) > 0 {
+			origin, req.Origin = common.BytesToHash(req.Origin), nil
+		}
+		limit := maxHash
+		if i == len(req.Accounts)-1 && len(req.Limit) > 0 {
+			limit, req.Limit = common.BytesToHash(req.Limit), nil
+		}
+
+		enc, err := tx.GetOne(kv.HashedAccounts, account[:])
+		if err != nil {
+			return nil, nil, err
+		}
+		var acc accounts.Account
+		if len(enc) > 0 {
+			if err = acc.DecodeForStorage(enc); err != nil {
+				return nil, nil, err
+			}
+		}
+		// Iterate over the requested range and pile slots up
+		var (
+			storage []*StorageData
+			last    common.Hash
+			abort   bool
+		)
+		if acc.Incarnation > 0 {
+			prefix := dbutils.GenerateStoragePrefix(account[:], acc.Incarnation)
+			for v, err := c.SeekBothRange(prefix, origin[:]); v != nil; _, v, err = c.NextDup() {
+				if err != nil {
+					return nil, nil, err
+				}
+				if size >= hardLimit {
+					abort = true
+					break
+				}
+				last = common.BytesToHash(v[:length.Hash])
+				body, err := rlp.EncodeToBytes(v[length.Hash:])
+				if err != nil {
+					return nil, nil, err
+				}
+				size += uint64(length.Hash + len(body))
+				storage = append(storage, &StorageData{Hash: last, Body: body})
+				if bytes.Compare(last[:], limit[:]) >= 0 {
+					break
+				}
+			}
+		}
+		if len(storage) > 0 {
+			slots = append(slots, storage)
+		}
+
+		// Generate the Merkle proofs for the first and last storage slot, but
+		// only if the response was capped. If the entire storage trie included
+		// in the response, no need for any proofs.
+		if origin != (common.Hash{}) || (abort && len(storage) > 0) {
+			if len(enc) == 0 {
+				return nil, nil, nil
+			}
+			keys := [][]byte{storageKeyToNibbles(account, acc.Incarnation, origin)}
+			if last != (common.Hash{}) {
+				keys = append(keys, storageKeyToNibbles(account, acc.Incarnation, last))
+			}
+			t, err := h.loadTrie(tx, req.Root, keys)
+			if err != nil {
+				return nil, nil, err
+			}
+			proof, err := t.Prove(append(common.CopyBytes(account[:]), origin[:]...), 2*length.Hash, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			proofs.add(proof)
+			if last != (common.Hash{}) {
+				if proof, err = t.Prove(append(common.CopyBytes(account[:]), last[:]...), 2*length.Hash, true); err != nil {
+					return nil, nil, err
+				}
+				proofs.add(proof)
+			}
+			// Proof terminates the reply as proofs are only added if a node
+			// refuses to serve more data
+			break
+		}
+	}
+	return slots, proofs.nodes, nil
+}
+
+// ServiceGetByteCodesQuery assembles the response to a byte codes query.
+// It is exposed to allow external packages to test protocol behavior.
+func (h *Handler) ServiceGetByteCodesQuery(tx kv.Tx, req *GetByteCodesPacket) ([][]byte, error) {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	if len(req.Hashes) > maxCodeLookups {
+		req.Hashes = req.Hashes[:maxCodeLookups]
+	}
+	var (
+		codes [][]byte
+		size  uint64
+	)
+	for _, hash := range req.Hashes {
+		if hash == trie.EmptyCodeHash {
+			// Peers should not request the empty code, but if they do, at
+			// least sent them back a correct response without db lookups
+			codes = append(codes, []byte{})
+		} else {
+			code, err := tx.GetOne(kv.Code, hash[:])
+			if err != nil {
+				return nil, err
+			}
+			if len(code) > 0 {
+				codes = append(codes, common.CopyBytes(code))
+				size += uint64(len(code))
+			}
+		}
+		if size > req.Bytes {
+			break
+		}
+	}
+	return codes, nil
+}
+
+// ServiceGetTrieNodesQuery assembles the response to a trie nodes query.
+// It is exposed to allow external packages to test protocol behavior.
+func (h *Handler) ServiceGetTrieNodesQuery(tx kv.Tx, req *GetTrieNodesPacket, start time.Time) ([][]byte, error) {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	if ok, err := h.hasState(tx, req.Root); err != nil || !ok {
+		return nil, err
+	}
+
+	// All the requested nodes are loaded at once, in the trie their paths are stripped of the incarnations
+	type nodePath struct {
+		path    []byte
+		storage bool
+	}
+	var (
+		keys  [][]byte
+		paths [][]nodePath
+	)
+	for _, pathset := range req.Paths {
+		if len(keys) >= maxTrieNodeLookups {
+			break
+		}
+		switch len(pathset) {
+		case 0:
+			// Ensure we penalize invalid requests
+			return nil, fmt.Errorf("%w: zero-item pathset requested", errBadRequest)
+		case 1:
+			path := trie.CompactToHex(pathset[0])
+			keys = append(keys, path)
+			paths = append(paths, []nodePath{{path: path}})
+		default:
+			// Storage slots requested, the account is addressed by its hash
+			account := common.BytesToHash(pathset[0])
+			enc, err := tx.GetOne(kv.HashedAccounts, account[:])
+			if err != nil {
+				return nil, err
+			}
+			var acc accounts.Account
+			if len(enc) > 0 {
+				if err = acc.DecodeForStorage(enc); err != nil {
+					return nil, err
+				}
+			}
+			var storagePaths []nodePath
+			if acc.Incarnation > 0 {
+				accountPath, prefix := keyToNibbles(account[:]), keyToNibbles(dbutils.GenerateStoragePrefix(account[:], acc.Incarnation))
+				for _, compact := range pathset[1:] {
+					path := trie.CompactToHex(compact)
+					keys = append(keys, append(common.CopyBytes(prefix), path...))
+					storagePaths = append(storagePaths, nodePath{path: append(common.CopyBytes(accountPath), path...), storage: true})
+				}
+			}
+			paths = append(paths, storagePaths)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	t, err := h.loadTrie(tx, req.Root, keys)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		nodes [][]byte
+		size  uint64
+	)
+	for _, pathset := range paths {
+		for _, p := range pathset {
+			blob, _, err := t.NodeByPath(p.path, p.storage)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, blob)
+			size += uint64(len(blob))
+			if size >= req.Bytes {
+				break
+			}
+		}
+		if size >= req.Bytes || time.Since(start) > maxTrieNodeTimeSpent {
+			break
+		}
+	}
+	return nodes, nil
+}
+
+func slimAccountRLP(acc *accounts.Account) ([]byte, error) {
+	slim := slimAccount{Nonce: acc.Nonce, Balance: acc.Balance.ToBig()}
+	if acc.Root != trie.EmptyRoot {
+		slim.Root = acc.Root[:]
+	}
+	if acc.CodeHash != trie.EmptyCodeHash {
+		slim.CodeHash = acc.CodeHash[:]
+	}
+	return rlp.EncodeToBytes(&slim)
+}
+
+// proofSet collects the nodes of the proofs, each node once
+type proofSet struct {
+	seen  map[string]struct{}
+	nodes [][]byte
+}
+
+func (s *proofSet) add(proof [][]byte) {
+	if s.seen == nil {
+		s.seen = map[string]struct{}{}
+	}
+	for _, node := range proof {
+		if _, ok := s.seen[string(node)]; ok {
+			continue
+		}
+		s.seen[string(node)] = struct{}{}
+		s.nodes = append(s.nodes, node)
+	}
+}
+
+func keyToNibbles(key []byte) []byte {
+	nibbles := make([]byte, 2*len(key))
+	for i, b := range key {
+		nibbles[i*2] = b / 16
+		nibbles[i*2+1] = b % 16
+	}
+	return nibbles
+}
+
+func storageKeyToNibbles(account common.Hash, incarnation uint64, key common.Hash) []byte {
+	k := make([]byte, length.Hash+length.Incarnation+length.Hash)
+	copy(k, account[:])
+	binary.BigEndian.PutUint64(k[length.Hash:], incarnation)
+	copy(k[length.Hash+length.Incarnation:], key[:])
+	return keyToNibbles(k)
+}
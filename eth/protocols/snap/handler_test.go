@@ -0,0 +1,332 @@
+package snap
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+type headerReader struct{}
+
+func (headerReader) Header(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (*types.Header, error) {
+	return rawdb.ReadHeader(tx, hash, blockHeight), nil
+}
+func (headerReader) HeaderByNumber(ctx context.Context, tx kv.Getter, blockHeight uint64) (*types.Header, error) {
+	return rawdb.ReadHeaderByNumber(tx, blockHeight), nil
+}
+func (headerReader) HeaderByHash(ctx context.Context, tx kv.Getter, hash common.Hash) (*types.Header, error) {
+	return rawdb.ReadHeaderByHash(tx, hash)
+}
+
+type testAccount struct {
+	hash    common.Hash
+	account accounts.Account
+	storage []*StorageData // sorted by hash, bodies are RLP encoded
+	code    []byte
+}
+
+// testState writes the hashed state of a few hundred accounts, some of them contracts with storage,
+// and the header of the block with its state root, as if the state was executed up to block 1
+func testState(t *testing.T) (kv.RwDB, common.Hash, []*testAccount) {
+	t.Helper()
+	db := memdb.NewTestDB(t)
+	var accs []*testAccount
+	for i := 0; i < 300; i++ {
+		acc := &testAccount{hash: crypto.Keccak256Hash(big.NewInt(int64(i)).Bytes()), account: accounts.NewAccount()}
+		acc.account.Nonce = uint64(i)
+		acc.account.Balance = *uint256.NewInt(uint64(i) * 1000)
+		if i%10 == 0 {
+			acc.account.Incarnation = 1
+			acc.code = []byte{0x60, byte(i), 0x60, 0x00, 0x55}
+			acc.account.CodeHash = crypto.Keccak256Hash(acc.code)
+			storageTrie := trie.New(common.Hash{})
+			for j := 0; j < 3*i+1; j++ {
+				value := big.NewInt(int64(j*1000 + i + 1)).Bytes()
+				key := crypto.Keccak256Hash(big.NewInt(int64(j)).Bytes())
+				storageTrie.Update(key[:], value)
+				body, err := rlp.EncodeToBytes(value)
+				require.NoError(t, err)
+				acc.storage = append(acc.storage, &StorageData{Hash: key, Body: body})
+			}
+			sort.Slice(acc.storage, func(i, j int) bool { return bytes.Compare(acc.storage[i].Hash[:], acc.storage[j].Hash[:]) < 0 })
+			acc.account.Root = storageTrie.Hash()
+		}
+		accs = append(accs, acc)
+	}
+	sort.Slice(accs, func(i, j int) bool { return bytes.Compare(accs[i].hash[:], accs[j].hash[:]) < 0 })
+
+	var root common.Hash
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for _, acc := range accs {
+			enc := make([]byte, acc.account.EncodingLengthForStorage())
+			acc.account.EncodeForStorage(enc)
+			require.NoError(t, tx.Put(kv.HashedAccounts, acc.hash[:], enc))
+			for _, slot := range acc.storage {
+				var value []byte
+				require.NoError(t, rlp.DecodeBytes(slot.Body, &value))
+				require.NoError(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(acc.hash, acc.account.Incarnation, slot.Hash), value))
+			}
+			if acc.code != nil {
+				require.NoError(t, tx.Put(kv.Code, acc.account.CodeHash[:], acc.code))
+			}
+		}
+		var err error
+		if root, err = trie.CalcRoot("test", tx); err != nil {
+			return err
+		}
+		header := &types.Header{Number: big.NewInt(1), Root: root, Difficulty: big.NewInt(1)}
+		rawdb.WriteHeader(tx, header)
+		require.NoError(t, rawdb.WriteCanonicalHash(tx, header.Hash(), 1))
+		require.NoError(t, stages.SaveStageProgress(tx, stages.HashState, 1))
+		return stages.SaveStageProgress(tx, stages.IntermediateHashes, 1)
+	}))
+	return db, root, accs
+}
+
+func TestServeAccountRange(t *testing.T) {
+	db, root, accs := testState(t)
+	h := NewHandler(context.Background(), db, headerReader{})
+	tx, err := db.BeginRo(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	// the whole state fits into the response
+	got, proof, err := h.ServiceGetAccountRangeQuery(tx, &GetAccountRangePacket{Root: root, Limit: maxHash, Bytes: softResponseLimit})
+	require.NoError(t, err)
+	require.Len(t, got, len(accs))
+	for i, acc := range accs {
+		require.Equal(t, acc.hash, got[i].Hash)
+		expected, err := slimAccountRLP(&acc.account)
+		require.NoError(t, err)
+		require.Equal(t, expected, []byte(got[i].Body))
+	}
+	require.NotEmpty(t, proof)
+	require.Equal(t, root, crypto.Keccak256Hash(proof[0]))
+
+	// the range starts at the first account after the origin and stops once the size is exceeded
+	origin := accs[100].hash
+	origin[31]++
+	got, proof, err = h.ServiceGetAccountRangeQuery(tx, &GetAccountRangePacket{Root: root, Origin: origin, Limit: maxHash, Bytes: 1})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, accs[101].hash, got[0].Hash)
+	require.Equal(t, root, crypto.Keccak256Hash(proof[0]))
+
+	// the account at the limit is the last one
+	got, _, err = h.ServiceGetAccountRangeQuery(tx, &GetAccountRangePacket{Root: root, Origin: accs[10].hash, Limit: accs[20].hash, Bytes: softResponseLimit})
+	require.NoError(t, err)
+	require.Len(t, got, 11)
+	require.Equal(t, accs[20].hash, got[10].Hash)
+
+	// there is no state of other roots
+	got, proof, err = h.ServiceGetAccountRangeQuery(tx, &GetAccountRangePacket{Root: common.Hash{1}, Limit: maxHash, Bytes: softResponseLimit})
+	require.NoError(t, err)
+	require.Empty(t, got)
+	require.Empty(t, proof)
+}
+
+func TestServeStorageRanges(t *testing.T) {
+	db, root, accs := testState(t)
+	h := NewHandler(context.Background(), db, headerReader{})
+	tx, err := db.BeginRo(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	var contracts []*testAccount
+	var hashes []common.Hash
+	for _, acc := range accs {
+		if len(acc.storage) > 0 {
+			contracts = append(contracts, acc)
+			hashes = append(hashes, acc.hash)
+		}
+	}
+	// complete storages need no proofs
+	slots, proof, err := h.ServiceGetStorageRangesQuery(tx, &GetStorageRangesPacket{Root: root, Accounts: hashes, Bytes: softResponseLimit})
+	require.NoError(t, err)
+	require.Empty(t, proof)
+	require.Len(t, slots, len(contracts))
+	for i, acc := range contracts {
+		require.Equal(t, acc.storage, slots[i])
+	}
+
+	// the storage from the origin is proven
+	acc := contracts[len(contracts)-1]
+	slots, proof, err = h.ServiceGetStorageRangesQuery(tx, &GetStorageRangesPacket{Root: root, Accounts: []common.Hash{acc.hash}, Origin: acc.storage[10].Hash[:], Bytes: softResponseLimit})
+	require.NoError(t, err)
+	require.Len(t, slots, 1)
+	require.Equal(t, acc.storage[10:], slots[0])
+	require.NotEmpty(t, proof)
+	require.Equal(t, acc.account.Root, crypto.Keccak256Hash(proof[0]))
+
+	// so is a storage cut by the size limit
+	slots, proof, err = h.ServiceGetStorageRangesQuery(tx, &GetStorageRangesPacket{Root: root, Accounts: []common.Hash{acc.hash}, Bytes: 100})
+	require.NoError(t, err)
+	require.Len(t, slots, 1)
+	require.Less(t, len(slots[0]), len(acc.storage))
+	require.Equal(t, acc.storage[:len(slots[0])], slots[0])
+	require.Equal(t, acc.account.Root, crypto.Keccak256Hash(proof[0]))
+
+	// there is no state of other roots
+	slots, _, err = h.ServiceGetStorageRangesQuery(tx, &GetStorageRangesPacket{Root: common.Hash{1}, Accounts: hashes, Bytes: softResponseLimit})
+	require.NoError(t, err)
+	require.Empty(t, slots)
+}
+
+func TestServeByteCodes(t *testing.T) {
+	db, _, accs := testState(t)
+	h := NewHandler(context.Background(), db, headerReader{})
+	tx, err := db.BeginRo(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	var contract *testAccount
+	for _, acc := range accs {
+		if acc.code != nil {
+			contract = acc
+			break
+		}
+	}
+	codes, err := h.ServiceGetByteCodesQuery(tx, &GetByteCodesPacket{Hashes: []common.Hash{contract.account.CodeHash, {1}, trie.EmptyCodeHash}, Bytes: softResponseLimit})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{contract.code, {}}, codes)
+}
+
+func TestServeTrieNodes(t *testing.T) {
+	db, root, accs := testState(t)
+	h := NewHandler(context.Background(), db, headerReader{})
+	tx, err := db.BeginRo(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	var contract *testAccount
+	for _, acc := range accs {
+		if len(acc.storage) > 10 {
+			contract = acc
+			break
+		}
+	}
+	nodes, err := h.ServiceGetTrieNodesQuery(tx, &GetTrieNodesPacket{
+		Root: root,
+		Paths: []TrieNodePathSet{
+			{{0x00}},                           // the root
+			{{0x10 | accs[0].hash[0]>>4}},      // a child of the root
+			{contract.hash[:], {0x00}, {0x00}}, // the storage root, twice
+			{common.Hash{1}.Bytes(), {0x00}},   // a missing account has no storage
+			{{0x20, accs[0].hash[0]}, {0x00}},  // account paths of storage nodes are hashes
+		},
+		Bytes: softResponseLimit,
+	}, time.Now())
+	require.NoError(t, err)
+	require.Len(t, nodes, 4)
+	require.Equal(t, root, crypto.Keccak256Hash(nodes[0]))
+	require.True(t, bytes.Contains(nodes[0], crypto.Keccak256(nodes[1])))
+	require.Equal(t, contract.account.Root, crypto.Keccak256Hash(nodes[2]))
+	require.Equal(t, nodes[2], nodes[3])
+
+	// there is no state of other roots
+	nodes, err = h.ServiceGetTrieNodesQuery(tx, &GetTrieNodesPacket{Root: common.Hash{1}, Paths: []TrieNodePathSet{{{0x00}}}, Bytes: softResponseLimit}, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+
+	_, err = h.ServiceGetTrieNodesQuery(tx, &GetTrieNodesPacket{Root: root, Paths: []TrieNodePathSet{{}}, Bytes: softResponseLimit}, time.Now())
+	require.ErrorIs(t, err, errBadRequest)
+}
+
+func TestServeOlderRoot(t *testing.T) {
+	db, root, accs := testState(t)
+	// the genesis block had another state, which isn't kept
+	olderRoot := crypto.Keccak256Hash([]byte("genesis"))
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		header := &types.Header{Number: big.NewInt(0), Root: olderRoot, Difficulty: big.NewInt(1)}
+		rawdb.WriteHeader(tx, header)
+		return rawdb.WriteCanonicalHash(tx, header.Hash(), 0)
+	}))
+	h := NewHandler(context.Background(), db, headerReader{})
+
+	local, remote := p2p.MsgPipe()
+	defer remote.Close()
+	go h.Protocol().Run(p2p.NewPeer(enode.ID{1}, [64]byte{1}, "test", nil), local) //nolint:errcheck
+
+	require.NoError(t, p2p.Send(remote, GetAccountRangeMsg, &GetAccountRangePacket{ID: 1, Root: olderRoot, Limit: maxHash, Bytes: softResponseLimit}))
+	msg, err := remote.ReadMsg()
+	require.NoError(t, err)
+	require.Equal(t, uint64(AccountRangeMsg), msg.Code)
+	var accountRange AccountRangePacket
+	require.NoError(t, msg.Decode(&accountRange))
+	require.Equal(t, uint64(1), accountRange.ID)
+	require.Empty(t, accountRange.Accounts)
+	require.Empty(t, accountRange.Proof)
+
+	require.NoError(t, p2p.Send(remote, GetStorageRangesMsg, &GetStorageRangesPacket{ID: 2, Root: olderRoot, Accounts: []common.Hash{accs[0].hash}, Bytes: softResponseLimit}))
+	msg, err = remote.ReadMsg()
+	require.NoError(t, err)
+	require.Equal(t, uint64(StorageRangesMsg), msg.Code)
+	var storageRanges StorageRangesPacket
+	require.NoError(t, msg.Decode(&storageRanges))
+	require.Equal(t, uint64(2), storageRanges.ID)
+	require.Empty(t, storageRanges.Slots)
+	require.Empty(t, storageRanges.Proof)
+
+	require.NoError(t, p2p.Send(remote, GetTrieNodesMsg, &GetTrieNodesPacket{ID: 3, Root: olderRoot, Paths: []TrieNodePathSet{{{0x00}}}, Bytes: softResponseLimit}))
+	msg, err = remote.ReadMsg()
+	require.NoError(t, err)
+	require.Equal(t, uint64(TrieNodesMsg), msg.Code)
+	var trieNodes TrieNodesPacket
+	require.NoError(t, msg.Decode(&trieNodes))
+	require.Equal(t, uint64(3), trieNodes.ID)
+	require.Empty(t, trieNodes.Nodes)
+
+	// the head root is still served
+	require.NoError(t, p2p.Send(remote, GetAccountRangeMsg, &GetAccountRangePacket{ID: 4, Root: root, Limit: maxHash, Bytes: 1}))
+	msg, err = remote.ReadMsg()
+	require.NoError(t, err)
+	require.NoError(t, msg.Decode(&accountRange))
+	require.Len(t, accountRange.Accounts, 1)
+}
+
+func TestProtocol(t *testing.T) {
+	db, root, accs := testState(t)
+	h := NewHandler(context.Background(), db, headerReader{})
+
+	local, remote := p2p.MsgPipe()
+	defer remote.Close()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- h.Protocol().Run(p2p.NewPeer(enode.ID{1}, [64]byte{1}, "test", nil), local)
+	}()
+
+	require.NoError(t, p2p.Send(remote, GetAccountRangeMsg, &GetAccountRangePacket{ID: 7, Root: root, Limit: maxHash, Bytes: 1}))
+	msg, err := remote.ReadMsg()
+	require.NoError(t, err)
+	require.Equal(t, uint64(AccountRangeMsg), msg.Code)
+	var res AccountRangePacket
+	require.NoError(t, msg.Decode(&res))
+	require.Equal(t, uint64(7), res.ID)
+	require.Len(t, res.Accounts, 1)
+	require.Equal(t, accs[0].hash, res.Accounts[0].Hash)
+	require.Equal(t, root, crypto.Keccak256Hash(res.Proof[0]))
+
+	// peers sending unknown messages are disconnected
+	require.NoError(t, p2p.Send(remote, protocolLength, []byte{}))
+	require.ErrorIs(t, <-errc, errInvalidMsgCode)
+}
@@ -638,6 +638,7 @@ Loop:
 			case <-logEvery.C:
 				stepsInDB := rawdbhelpers.IdxStepsCountV3(applyTx)
 				progress.Log(rs, rws.Len(), uint64(queueSize), count, inputBlockNum.Load(), outputBlockNum.Load(), outputTxNum.Load(), repeatCount.Load(), uint64(resultsSize.Load()), resultCh, stepsInDB)
+				execStage.ReportProgress(outputBlockNum.Load(), maxBlockNum)
 				if rs.SizeEstimate() < commitThreshold {
 					break
 				}
@@ -35,6 +35,10 @@ type Stage struct {
 	ID stages.SyncStage
 	// Disabled defines if the stage is disabled. It sets up when the stage is build by its `StageBuilder`.
 	Disabled bool
+	// DependsOn lists the stages whose output this stage reads. A stage which declares its dependencies may be run
+	// concurrently with the neighbouring stages it doesn't depend on, see `Sync.EnableParallelStages`. Stages with nil
+	// DependsOn are always run alone, after all the stages before them.
+	DependsOn []stages.SyncStage
 }
 
 // StageState is the state of the stage.
@@ -42,24 +46,39 @@ type StageState struct {
 	state       *Sync
 	ID          stages.SyncStage
 	BlockNumber uint64 // BlockNumber is the current block number of the stage at the beginning of the state execution.
+
+	executionLimit uint64 // not 0 when the stage runs in a bounded round of a parallel group, caps ExecutionAt
 }
 
-func (s *StageState) LogPrefix() string { return s.state.LogPrefix() }
+func (s *StageState) LogPrefix() string { return s.state.stageLogPrefix(s.ID) }
 
 // Update updates the stage state (current block number) in the database. Can be called multiple times during stage execution.
 func (s *StageState) Update(db kv.Putter, newBlockNum uint64) error {
 	if m, ok := syncMetrics[s.ID]; ok {
 		m.Set(newBlockNum)
 	}
+	s.ReportProgress(newBlockNum, 0)
 	return stages.SaveStageProgress(db, s.ID, newBlockNum)
 }
+
+// ReportProgress feeds the sync status APIs with the block the stage got to, and the block it is moving to if it is
+// known better than from the previous stages (0 otherwise). Long stages call it from their log tickers.
+func (s *StageState) ReportProgress(current, target uint64) {
+	if s.state != nil {
+		s.state.progress.progressed(s.ID, current, target)
+	}
+}
 func (s *StageState) UpdatePrune(db kv.Putter, blockNum uint64) error {
 	return stages.SaveStagePruneProgress(db, s.ID, blockNum)
 }
 
-// ExecutionAt gets the current state of the "Execution" stage, which block is currently executed.
+// ExecutionAt gets the current state of the "Execution" stage, which block is currently executed. Stages running in a
+// parallel group get at most the end of their current round, see `Sync.EnableParallelStages`.
 func (s *StageState) ExecutionAt(db kv.Getter) (uint64, error) {
 	execution, err := stages.GetStageProgress(db, stages.Execution)
+	if err == nil && s.executionLimit != 0 && execution > s.executionLimit {
+		execution = s.executionLimit
+	}
 	return execution, err
 }
 
@@ -321,6 +321,7 @@ func BodiesForward(
 				noProgressCount = 0 // Reset, there was progress
 			}
 			logDownloadingBodies(logPrefix, bodyProgress, headerProgress-requestedLow, totalDelivered, prevDeliveredCount, deliveredCount, prevWastedCount, wastedCount)
+			s.ReportProgress(bodyProgress, headerProgress)
 			prevProgress = bodyProgress
 			prevDeliveredCount = deliveredCount
 			prevWastedCount = wastedCount
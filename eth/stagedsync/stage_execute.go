@@ -191,6 +191,11 @@ func executeBlock(
 			}
 		}
 	}
+	if execRs.StateSync != nil {
+		if err = execRs.StateSync.Write(tx, blockNum); err != nil {
+			return err
+		}
+	}
 
 	if cfg.changeSetHook != nil {
 		if hasChangeSet, ok := stateWriter.(HasChangeSetWriter); ok {
@@ -326,6 +331,9 @@ func unwindExec3(u *UnwindState, s *StageState, tx kv.RwTx, ctx context.Context,
 	if err := rawdb.TruncateBorReceipts(tx, u.UnwindPoint+1); err != nil {
 		return fmt.Errorf("truncate bor receipts: %w", err)
 	}
+	if err := rawdb.TruncateBorEvents(tx, u.UnwindPoint+1); err != nil {
+		return fmt.Errorf("truncate bor events: %w", err)
+	}
 	if err := rawdb.DeleteNewerEpochs(tx, u.UnwindPoint+1); err != nil {
 		return fmt.Errorf("delete newer epochs: %w", err)
 	}
@@ -491,6 +499,7 @@ Loop:
 			gas = 0
 			tx.CollectMetrics()
 			syncMetrics[stages.Execution].Set(blockNum)
+			s.ReportProgress(blockNum, to)
 		}
 	}
 
@@ -687,6 +696,9 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 	if err := rawdb.TruncateBorReceipts(tx, u.UnwindPoint+1); err != nil {
 		return fmt.Errorf("truncate bor receipts: %w", err)
 	}
+	if err := rawdb.TruncateBorEvents(tx, u.UnwindPoint+1); err != nil {
+		return fmt.Errorf("truncate bor events: %w", err)
+	}
 	if err := rawdb.DeleteNewerEpochs(tx, u.UnwindPoint+1); err != nil {
 		return fmt.Errorf("delete newer epochs: %w", err)
 	}
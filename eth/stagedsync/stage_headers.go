@@ -879,6 +879,7 @@ Loop:
 		case <-logEvery.C:
 			progress := cfg.hd.Progress()
 			logProgressHeaders(logPrefix, prevProgress, progress)
+			s.ReportProgress(progress, cfg.hd.TopSeenHeight())
 			stats := cfg.hd.ExtractStats()
 			if prevProgress == progress {
 				noProgressCounter++
@@ -190,6 +190,10 @@ func SpawnMiningCreateBlockStage(s *StageState, tx kv.RwTx, cfg MiningCreateBloc
 			"callers", debug.Callers(10))
 		return err
 	}
+	if cfg.chainConfig.Clique != nil && header.Coinbase != (common.Address{}) {
+		// clique casts the proposals of the signer, persisted by clique_propose, through the coinbase and the nonce
+		log.Info(fmt.Sprintf("[%s] Casting clique vote", logPrefix), "address", header.Coinbase, "authorize", header.Nonce != (types.BlockNonce{}))
+	}
 
 	if cfg.blockBuilderParameters != nil {
 		header.MixDigest = cfg.blockBuilderParameters.PrevRandao
@@ -390,6 +390,12 @@ func WaitForDownloader(s *StageState, ctx context.Context, cfg SnapshotsCfg, tx
 	for i := range missingSnapshots {
 		downloadRequest = append(downloadRequest, snapshotsync.NewDownloadRequest(&missingSnapshots[i], "", ""))
 	}
+	// the downloader doesn't find the segments of this repo's types by itself, seeds them on request
+	seedable, err := snapshotsync.SeedableSegments(cfg.snapshots.Dir())
+	if err != nil {
+		return err
+	}
+	downloadRequest = append(downloadRequest, seedable...)
 
 	log.Info(fmt.Sprintf("[%s] Fetching torrent files metadata", s.LogPrefix()))
 	for {
@@ -2,7 +2,12 @@ package stagedsync
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"sync"
 
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/huandu/xstrings"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/core/types"
@@ -77,3 +82,173 @@ func MiningStages(
 		},
 	}
 }
+
+// CustomStage is a stage an embedder adds to the default ones, see RegisterCustomStage.
+type CustomStage struct {
+	// Stage is copied into every sync built with WithCustomStages. Forward and Unwind MUST NOT be nil, Prune may be.
+	// Unlike the default stages, its functions always get a transaction: when the sync runs without one, the stage
+	// gets its own, committed once the function returned without error.
+	Stage *Stage
+	// After is the stage the custom stage runs after: Execution, any stage following it, or another custom stage.
+	// The custom stage is unwound and pruned right before it. Defaults to Execution.
+	After stages.SyncStage
+	// Tables are owned by the stage and created in the chain database next to the default ones.
+	Tables kv.TableCfg
+}
+
+var (
+	customStagesLock sync.Mutex
+	customStages     []CustomStage
+)
+
+// RegisterCustomStage adds a stage to the syncs built by erigon. It must be called before the node opens its database,
+// usually from an init function, so that the tables of the stage get created.
+func RegisterCustomStage(custom CustomStage) error {
+	if custom.Stage == nil || custom.Stage.ID == "" {
+		return fmt.Errorf("custom stage without id")
+	}
+	if custom.Stage.Forward == nil || custom.Stage.Unwind == nil {
+		return fmt.Errorf("custom stage %s: Forward and Unwind are required", custom.Stage.ID)
+	}
+	if custom.After == "" {
+		custom.After = stages.Execution
+	}
+	customStagesLock.Lock()
+	defer customStagesLock.Unlock()
+	for _, id := range stages.AllStages {
+		if id == custom.Stage.ID {
+			return fmt.Errorf("custom stage %s: id is taken by a default stage", custom.Stage.ID)
+		}
+	}
+	for _, registered := range customStages {
+		if registered.Stage.ID == custom.Stage.ID {
+			return fmt.Errorf("custom stage %s: already registered", custom.Stage.ID)
+		}
+	}
+	for name := range custom.Tables {
+		if _, ok := kv.ChaindataTablesCfg[name]; ok && !isCustomTable(name) {
+			return fmt.Errorf("custom stage %s: table %s is taken", custom.Stage.ID, name)
+		}
+	}
+	for name, cfg := range custom.Tables {
+		if _, ok := kv.ChaindataTablesCfg[name]; !ok {
+			kv.ChaindataTables = append(kv.ChaindataTables, name)
+		}
+		kv.ChaindataTablesCfg[name] = cfg
+	}
+	sort.Strings(kv.ChaindataTables)
+	if _, ok := syncMetrics[custom.Stage.ID]; !ok {
+		syncMetrics[custom.Stage.ID] = metrics.GetOrCreateCounter(fmt.Sprintf(`sync{stage="%s"}`, xstrings.ToSnakeCase(string(custom.Stage.ID))))
+	}
+	customStages = append(customStages, custom)
+	return nil
+}
+
+// UnregisterCustomStage removes the stage from the syncs built from now on, its tables are kept.
+func UnregisterCustomStage(id stages.SyncStage) {
+	customStagesLock.Lock()
+	defer customStagesLock.Unlock()
+	for i, registered := range customStages {
+		if registered.Stage.ID == id {
+			customStages = append(customStages[:i], customStages[i+1:]...)
+			return
+		}
+	}
+}
+
+// isCustomTable must be called under customStagesLock
+func isCustomTable(name string) bool {
+	for _, registered := range customStages {
+		if _, ok := registered.Tables[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WithCustomStages inserts the registered custom stages into the stages of a sync and its unwind and prune orders.
+// Custom stages registered after the same stage run in the order of registration and unwind in the reverse order.
+func WithCustomStages(db kv.RwDB, stagesList []*Stage, unwindOrder UnwindOrder, pruneOrder PruneOrder) ([]*Stage, UnwindOrder, PruneOrder, error) {
+	customStagesLock.Lock()
+	defer customStagesLock.Unlock()
+	if len(customStages) == 0 {
+		return stagesList, unwindOrder, pruneOrder, nil
+	}
+	stagesList = append([]*Stage(nil), stagesList...)
+	unwindOrder = append(UnwindOrder(nil), unwindOrder...)
+	pruneOrder = append(PruneOrder(nil), pruneOrder...)
+
+	execution := indexOfStage(stagesList, stages.Execution)
+	after := map[stages.SyncStage]stages.SyncStage{}
+	for _, custom := range customStages {
+		stage := withOwnTx(db, *custom.Stage)
+		i := indexOfStage(stagesList, custom.After)
+		if i < 0 || execution < 0 || i < execution {
+			return nil, nil, nil, fmt.Errorf("custom stage %s: stage %s doesn't run after Execution", stage.ID, custom.After)
+		}
+		for i+1 < len(stagesList) && after[stagesList[i+1].ID] == custom.After {
+			i++
+		}
+		stagesList = append(stagesList[:i+1], append([]*Stage{&stage}, stagesList[i+1:]...)...)
+		after[stage.ID] = custom.After
+		unwindOrder = insertBefore(unwindOrder, stage.ID, custom.After, after)
+		pruneOrder = insertBefore(pruneOrder, stage.ID, custom.After, after)
+	}
+	return stagesList, unwindOrder, pruneOrder, nil
+}
+
+// withOwnTx opens a transaction for the functions of the stage when the sync runs without one
+func withOwnTx(db kv.RwDB, stage Stage) Stage {
+	forward, unwind, prune := stage.Forward, stage.Unwind, stage.Prune
+	stage.Forward = func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+		if tx != nil {
+			return forward(firstCycle, badBlockUnwind, s, u, tx, quiet)
+		}
+		return db.Update(context.Background(), func(tx kv.RwTx) error {
+			return forward(firstCycle, badBlockUnwind, s, u, tx, quiet)
+		})
+	}
+	stage.Unwind = func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+		if tx != nil {
+			return unwind(firstCycle, u, s, tx)
+		}
+		return db.Update(context.Background(), func(tx kv.RwTx) error { return unwind(firstCycle, u, s, tx) })
+	}
+	if prune != nil {
+		stage.Prune = func(firstCycle bool, p *PruneState, tx kv.RwTx) error {
+			if tx != nil {
+				return prune(firstCycle, p, tx)
+			}
+			return db.Update(context.Background(), func(tx kv.RwTx) error { return prune(firstCycle, p, tx) })
+		}
+	}
+	return stage
+}
+
+func indexOfStage(stagesList []*Stage, id stages.SyncStage) int {
+	for i, stage := range stagesList {
+		if stage.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertBefore puts id in front of the stage it runs after, and in front of the custom stages which run after the same
+// stage and were inserted before it. Orders without that stage are left as they are.
+func insertBefore[T ~[]stages.SyncStage](order T, id, before stages.SyncStage, after map[stages.SyncStage]stages.SyncStage) T {
+	i := -1
+	for j, stage := range order {
+		if stage == before {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		return order
+	}
+	for i > 0 && after[order[i-1]] == before {
+		i--
+	}
+	return append(order[:i], append(T{id}, order[i:]...)...)
+}
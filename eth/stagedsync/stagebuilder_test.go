@@ -0,0 +1,74 @@
+package stagedsync
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+func TestWithCustomStages(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	noop := &Stage{
+		Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+			return nil
+		},
+		Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error { return nil },
+	}
+	custom := func(id stages.SyncStage, after stages.SyncStage) CustomStage {
+		stage := *noop
+		stage.ID = id
+		return CustomStage{Stage: &stage, After: after}
+	}
+	t.Cleanup(func() {
+		for _, id := range []stages.SyncStage{"A", "B", "C", "D"} {
+			UnregisterCustomStage(id)
+		}
+	})
+
+	require.Error(t, RegisterCustomStage(custom(stages.Execution, "")))
+	require.NoError(t, RegisterCustomStage(custom("A", "")))
+	require.Error(t, RegisterCustomStage(custom("A", "")))
+	require.NoError(t, RegisterCustomStage(custom("B", "")))
+	require.NoError(t, RegisterCustomStage(custom("C", stages.TxLookup)))
+	require.NoError(t, RegisterCustomStage(custom("D", "C")))
+
+	stagesList := []*Stage{{ID: stages.Headers}, {ID: stages.Execution}, {ID: stages.HashState}, {ID: stages.TxLookup}, {ID: stages.Finish}}
+	unwindOrder := UnwindOrder{stages.Finish, stages.TxLookup, stages.HashState, stages.Execution, stages.Headers}
+	pruneOrder := PruneOrder{stages.Finish, stages.TxLookup, stages.Execution, stages.HashState, stages.Headers}
+	stagesList, unwindOrder, pruneOrder, err := WithCustomStages(db, stagesList, unwindOrder, pruneOrder)
+	require.NoError(t, err)
+
+	var ids []stages.SyncStage
+	for _, stage := range stagesList {
+		ids = append(ids, stage.ID)
+	}
+	require.Equal(t, []stages.SyncStage{stages.Headers, stages.Execution, "A", "B", stages.HashState, stages.TxLookup, "C", "D", stages.Finish}, ids)
+	require.Equal(t, UnwindOrder{stages.Finish, "D", "C", stages.TxLookup, stages.HashState, "B", "A", stages.Execution, stages.Headers}, unwindOrder)
+	require.Equal(t, PruneOrder{stages.Finish, "D", "C", stages.TxLookup, "B", "A", stages.Execution, stages.HashState, stages.Headers}, pruneOrder)
+
+	// the custom stages get a transaction even when the sync has none
+	for _, id := range []stages.SyncStage{"A", "B", "C", "D"} {
+		UnregisterCustomStage(id)
+	}
+	var gotTx bool
+	forward := *noop
+	forward.ID = "A"
+	forward.Forward = func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+		gotTx = tx != nil
+		return nil
+	}
+	require.NoError(t, RegisterCustomStage(CustomStage{Stage: &forward}))
+	stagesList, _, _, err = WithCustomStages(db, []*Stage{{ID: stages.Execution}}, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, stagesList[1].Forward(false, false, nil, nil, nil, false))
+	require.True(t, gotTx)
+
+	UnregisterCustomStage("A")
+	require.NoError(t, RegisterCustomStage(custom("A", stages.Headers)))
+	_, _, _, err = WithCustomStages(db, []*Stage{{ID: stages.Headers}, {ID: stages.Execution}}, nil, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package stages
+
+import (
+	"time"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// Progress is a snapshot of what a stage is doing, as reported by the sync status APIs.
+type Progress struct {
+	Stage        SyncStage `json:"stage"`
+	Running      bool      `json:"running"`
+	CurrentBlock uint64    `json:"currentBlock"`
+	// TargetBlock is the block the stage is moving to: the progress of the stages it depends on, or a better
+	// estimate reported by the stage itself (the highest seen header for Headers).
+	TargetBlock     uint64  `json:"targetBlock"`
+	BlocksPerSecond float64 `json:"blocksPerSecond"`
+	// EtaSeconds is only set while the stage is running and moving.
+	EtaSeconds     float64    `json:"etaSeconds,omitempty"`
+	LastRunSeconds float64    `json:"lastRunSeconds"`
+	LastError      string     `json:"lastError,omitempty"`
+	LastErrorTime  *time.Time `json:"lastErrorTime,omitempty"`
+	// Unwinds are the latest unwinds of the stage, the oldest first.
+	Unwinds   []Unwind  `json:"unwinds,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Unwind is a finished unwind of a stage.
+type Unwind struct {
+	From     uint64       `json:"from"`
+	To       uint64       `json:"to"`
+	BadBlock *common.Hash `json:"badBlock,omitempty"`
+	Time     time.Time    `json:"time"`
+}
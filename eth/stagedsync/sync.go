@@ -24,6 +24,10 @@ type Sync struct {
 	currentStage uint
 	timings      []Timing
 	logPrefixes  []string
+
+	parallelTmpDir string // not empty when stages which don't depend on each other run concurrently
+	parallelBatch  uint64 // blocks a stage of a parallel group moves forward in one round
+	progress       *progressTracker
 }
 
 type Timing struct {
@@ -122,6 +126,20 @@ func (s *Sync) LogPrefix() string {
 	return s.logPrefixes[s.currentStage]
 }
 
+// stageLogPrefix is LogPrefix of the given stage, it doesn't rely on the current stage because several stages may run
+// at the same time.
+func (s *Sync) stageLogPrefix(id stages.SyncStage) string {
+	if s == nil {
+		return ""
+	}
+	for i, stage := range s.stages {
+		if stage.ID == id {
+			return s.logPrefixes[i]
+		}
+	}
+	return s.LogPrefix()
+}
+
 func (s *Sync) SetCurrentStage(id stages.SyncStage) error {
 	for i, stage := range s.stages {
 		if stage.ID == id {
@@ -155,6 +173,15 @@ func New(stagesList []*Stage, unwindOrder UnwindOrder, pruneOrder PruneOrder) *S
 	for i := range stagesList {
 		logPrefixes[i] = fmt.Sprintf("%d/%d %s", i+1, len(stagesList), stagesList[i].ID)
 	}
+	for i, stage := range stagesList {
+		for _, dep := range stage.DependsOn {
+			for _, later := range stagesList[i:] {
+				if later.ID == dep {
+					panic(fmt.Sprintf("stage %s depends on %s which doesn't run before it", stage.ID, dep))
+				}
+			}
+		}
+	}
 
 	return &Sync{
 		stages:       stagesList,
@@ -162,6 +189,7 @@ func New(stagesList []*Stage, unwindOrder UnwindOrder, pruneOrder PruneOrder) *S
 		unwindOrder:  unwindStages,
 		pruningOrder: pruneStages,
 		logPrefixes:  logPrefixes,
+		progress:     newProgressTracker(stagesList),
 	}
 }
 
@@ -186,7 +214,7 @@ func (s *Sync) StageState(stage stages.SyncStage, tx kv.Tx, db kv.RoDB) (*StageS
 		}
 	}
 
-	return &StageState{s, stage, blockNum}, nil
+	return &StageState{state: s, ID: stage, BlockNumber: blockNum}, nil
 }
 
 func (s *Sync) RunUnwind(db kv.RwDB, tx kv.RwTx) error {
@@ -252,6 +280,17 @@ func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool, quiet bool) error {
 			continue
 		}
 
+		// Stages which open their own transactions may run together, with an external tx everything is sequential
+		if tx == nil {
+			if group, next := s.parallelGroup(); len(group) > 1 {
+				if err := s.runStageGroup(group, db, firstCycle, badBlockUnwind, quiet); err != nil {
+					return err
+				}
+				s.currentStage = next
+				continue
+			}
+		}
+
 		if err := s.runStage(stage, db, tx, firstCycle, badBlockUnwind, quiet); err != nil {
 			return err
 		}
@@ -349,15 +388,22 @@ func (s *Sync) runStage(stage *Stage, db kv.RwDB, tx kv.RwTx, firstCycle bool, b
 	if err != nil {
 		return err
 	}
+	target, err := s.stageTarget(stage, tx, db)
+	if err != nil {
+		return err
+	}
+	s.progress.started(stage.ID, stageState.BlockNumber, target)
 
 	if err = stage.Forward(firstCycle, badBlockUnwind, stageState, s, tx, quiet); err != nil {
-		wrappedError := fmt.Errorf("[%s] %w", s.LogPrefix(), err)
+		wrappedError := fmt.Errorf("[%s] %w", stageState.LogPrefix(), err)
 		log.Debug("Error while executing stage", "err", wrappedError)
+		s.progress.finished(stage.ID, time.Since(start), wrappedError)
 		return wrappedError
 	}
 
 	took := time.Since(start)
-	logPrefix := s.LogPrefix()
+	s.progress.finished(stage.ID, took, nil)
+	logPrefix := stageState.LogPrefix()
 	if took > 60*time.Second {
 		log.Info(fmt.Sprintf("[%s] DONE", logPrefix), "in", took)
 	} else {
@@ -388,8 +434,11 @@ func (s *Sync) unwindStage(firstCycle bool, stage *Stage, db kv.RwDB, tx kv.RwTx
 
 	err = stage.Unwind(firstCycle, unwind, stageState, tx)
 	if err != nil {
-		return fmt.Errorf("[%s] %w", s.LogPrefix(), err)
+		err = fmt.Errorf("[%s] %w", s.LogPrefix(), err)
+		s.progress.finished(stage.ID, time.Since(start), err)
+		return err
 	}
+	s.progress.unwound(stage.ID, stageState.BlockNumber, unwind.UnwindPoint, unwind.BadBlock)
 
 	took := time.Since(start)
 	if took > 60*time.Second {
@@ -419,7 +468,9 @@ func (s *Sync) pruneStage(firstCycle bool, stage *Stage, db kv.RwDB, tx kv.RwTx)
 
 	err = stage.Prune(firstCycle, prune, tx)
 	if err != nil {
-		return fmt.Errorf("[%s] %w", s.LogPrefix(), err)
+		err = fmt.Errorf("[%s] %w", s.LogPrefix(), err)
+		s.progress.finished(stage.ID, time.Since(start), err)
+		return err
 	}
 
 	took := time.Since(start)
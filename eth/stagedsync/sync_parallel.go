@@ -0,0 +1,227 @@
+package stagedsync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// DefaultParallelStagesBatch is how many blocks a stage of a parallel group moves forward in one round.
+const DefaultParallelStagesBatch = 100_000
+
+// EnableParallelStages lets Run execute neighbouring stages which declared their dependencies and don't depend on each
+// other at the same time. It only applies to cycles without an external transaction: every stage of such a group reads
+// from its own read transaction and writes into an in-memory batch under tmpDir. To bound the batches, the group runs
+// in rounds in which every stage moves at most batchBlocks blocks past its progress, as ExecutionAt tells it. Once all
+// stages of a round succeeded, their batches are committed one by one in the order of the stages, each in its own
+// read/write transaction, the same way stages opening their own transactions do, and the next round starts. If any
+// stage of a round fails, nothing of that round is committed. Unwind and prune always run sequentially in UnwindOrder
+// and PruneOrder.
+func (s *Sync) EnableParallelStages(tmpDir string, batchBlocks uint64) {
+	s.parallelTmpDir = tmpDir
+	s.parallelBatch = batchBlocks
+}
+
+// parallelGroup returns the stages starting at the current one which can run concurrently, and the index of the
+// stage to continue with after them.
+func (s *Sync) parallelGroup() ([]*Stage, uint) {
+	if s.parallelTmpDir == "" {
+		return nil, s.currentStage
+	}
+	var group []*Stage
+	next := s.currentStage
+	for i := s.currentStage; i < uint(len(s.stages)); i++ {
+		stage := s.stages[i]
+		if stage.Disabled || stage.Forward == nil {
+			continue
+		}
+		if stage.DependsOn == nil || dependsOnAny(stage, group) {
+			break
+		}
+		// the debugging env flags are only honoured by the sequential loop
+		if string(stage.ID) == dbg.StopBeforeStage() || string(stage.ID) == dbg.StopAfterStage() {
+			break
+		}
+		group = append(group, stage)
+		next = i + 1
+	}
+	return group, next
+}
+
+func dependsOnAny(stage *Stage, group []*Stage) bool {
+	for _, dep := range stage.DependsOn {
+		for _, other := range group {
+			if other.ID == dep {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runStageGroup runs the rounds of the group until all its stages caught up with their targets, stopped moving or
+// one of them asked for an unwind.
+func (s *Sync) runStageGroup(group []*Stage, db kv.RwDB, firstCycle bool, badBlockUnwind bool, quiet bool) error {
+	unwinder := &groupUnwinder{s: s}
+	took := make([]time.Duration, len(group))
+	running := make([]int, len(group))
+	for i := range group {
+		running[i] = i
+	}
+	for len(running) > 0 && s.unwindPoint == nil {
+		round := make([]*Stage, len(running))
+		for j, i := range running {
+			round[j] = group[i]
+		}
+		roundTook, moving, err := s.runGroupRound(round, db, unwinder, firstCycle, badBlockUnwind, quiet)
+		for j, i := range running {
+			took[i] += roundTook[j]
+		}
+		if err != nil {
+			return err
+		}
+		next := running[:0]
+		for j, i := range running {
+			if moving[j] {
+				next = append(next, i)
+			}
+		}
+		running = next
+	}
+
+	for i, stage := range group {
+		s.progress.finished(stage.ID, took[i], nil)
+		logPrefix := s.stageLogPrefix(stage.ID)
+		if took[i] > 60*time.Second {
+			log.Info(fmt.Sprintf("[%s] DONE", logPrefix), "in", took[i])
+		} else {
+			log.Debug(fmt.Sprintf("[%s] DONE", logPrefix), "in", took[i])
+		}
+		s.timings = append(s.timings, Timing{stage: stage.ID, took: took[i]})
+	}
+	return nil
+}
+
+// runGroupRound runs one round of the stages concurrently and commits their batches. moving tells, for each stage,
+// that it moved forward in the round and is still short of its target.
+func (s *Sync) runGroupRound(group []*Stage, db kv.RwDB, u Unwinder, firstCycle bool, badBlockUnwind bool, quiet bool) (took []time.Duration, moving []bool, err error) {
+	errs := make([]error, len(group))
+	took = make([]time.Duration, len(group))
+	moving = make([]bool, len(group))
+	commit := make([]chan bool, len(group))
+	committed := make(chan error)
+	var forwarded, done sync.WaitGroup
+	forwarded.Add(len(group))
+	done.Add(len(group))
+	for i := range group {
+		commit[i] = make(chan bool, 1)
+		go func(i int) {
+			defer done.Done()
+			start := time.Now()
+			batch, more, err := s.forwardInBatch(group[i], db, u, firstCycle, badBlockUnwind, quiet)
+			errs[i], took[i], moving[i] = err, time.Since(start), more
+			forwarded.Done()
+			if err != nil {
+				return
+			}
+			defer batch.Rollback()
+			// MDBX write transactions are bound to the thread which opened them, so the batch is committed here
+			if !<-commit[i] {
+				return
+			}
+			committed <- db.Update(context.Background(), func(tx kv.RwTx) error { return batch.Flush(tx) })
+		}(i)
+	}
+	forwarded.Wait()
+
+	for i := range group {
+		if errs[i] != nil {
+			err = errs[i]
+			log.Debug("Error while executing stage", "err", err)
+			break
+		}
+	}
+	for i := range group {
+		if errs[i] != nil {
+			continue
+		}
+		if err != nil {
+			commit[i] <- false
+			continue
+		}
+		commit[i] <- true
+		if err = <-committed; err != nil {
+			err = fmt.Errorf("[%s] %w", s.stageLogPrefix(group[i].ID), err)
+		}
+	}
+	done.Wait()
+	if err != nil {
+		for i, stage := range group {
+			if errs[i] == nil {
+				s.progress.finished(stage.ID, took[i], fmt.Errorf("not committed: %w", err))
+			}
+		}
+		return took, nil, err
+	}
+	return took, moving, nil
+}
+
+// forwardInBatch runs one round of the stage on top of a fresh read transaction, the returned batch holds everything
+// it wrote. more tells that the stage moved forward and has not reached its target yet.
+func (s *Sync) forwardInBatch(stage *Stage, db kv.RwDB, u Unwinder, firstCycle bool, badBlockUnwind bool, quiet bool) (batch *memdb.MemoryMutation, more bool, err error) {
+	roTx, err := db.BeginRo(context.Background())
+	if err != nil {
+		return nil, false, err
+	}
+	defer roTx.Rollback()
+	batch = memdb.NewMemoryBatch(roTx, s.parallelTmpDir)
+	if batch == nil {
+		return nil, false, fmt.Errorf("[%s] can't create batch", s.stageLogPrefix(stage.ID))
+	}
+	start := time.Now()
+	stageState, err := s.StageState(stage.ID, batch, db)
+	var target, progress uint64
+	if err == nil {
+		target, err = s.stageTarget(stage, batch, db)
+	}
+	if err == nil {
+		if s.parallelBatch > 0 && stageState.BlockNumber+s.parallelBatch < target {
+			stageState.executionLimit = stageState.BlockNumber + s.parallelBatch
+		}
+		s.progress.started(stage.ID, stageState.BlockNumber, target)
+		err = stage.Forward(firstCycle, badBlockUnwind, stageState, u, batch, quiet)
+	}
+	if err == nil {
+		progress, err = stages.GetStageProgress(batch, stage.ID)
+	}
+	if err != nil {
+		batch.Rollback()
+		err = fmt.Errorf("[%s] %w", s.stageLogPrefix(stage.ID), err)
+		s.progress.finished(stage.ID, time.Since(start), err)
+		return nil, false, err
+	}
+	return batch, progress > stageState.BlockNumber && progress < target, nil
+}
+
+// groupUnwinder serialises unwind requests of concurrently running stages, the lowest unwind point wins.
+type groupUnwinder struct {
+	lock sync.Mutex
+	s    *Sync
+}
+
+func (u *groupUnwinder) UnwindTo(unwindPoint uint64, badBlock common.Hash) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.s.unwindPoint != nil && *u.s.unwindPoint <= unwindPoint {
+		return
+	}
+	u.s.UnwindTo(unwindPoint, badBlock)
+}
@@ -0,0 +1,173 @@
+package stagedsync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+// maxUnwindHistory is how many of the latest unwinds are kept per stage
+const maxUnwindHistory = 16
+
+// progressTracker keeps what the stages report about themselves for the sync status APIs. It is written by the
+// running stages, possibly concurrently, and read by the API servers.
+type progressTracker struct {
+	lock   sync.Mutex
+	stages map[stages.SyncStage]*stageProgress
+}
+
+type stageProgress struct {
+	stages.Progress
+	startBlock uint64 // CurrentBlock when the stage started, the speed is measured from there
+	startTime  time.Time
+}
+
+func newProgressTracker(stagesList []*Stage) *progressTracker {
+	t := &progressTracker{stages: make(map[stages.SyncStage]*stageProgress, len(stagesList))}
+	for _, stage := range stagesList {
+		t.stages[stage.ID] = &stageProgress{Progress: stages.Progress{Stage: stage.ID}}
+	}
+	return t
+}
+
+// get must be called under the lock, stages which aren't part of the sync are tracked as well
+func (t *progressTracker) get(id stages.SyncStage) *stageProgress {
+	p, ok := t.stages[id]
+	if !ok {
+		p = &stageProgress{Progress: stages.Progress{Stage: id}}
+		t.stages[id] = p
+	}
+	return p
+}
+
+func (t *progressTracker) started(id stages.SyncStage, current, target uint64) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.get(id)
+	now := time.Now()
+	p.Running, p.CurrentBlock, p.TargetBlock, p.BlocksPerSecond, p.EtaSeconds = true, current, target, 0, 0
+	p.startBlock, p.startTime, p.UpdatedAt = current, now, now
+}
+
+// progressed records the block the stage got to, target is only updated when it is known
+func (t *progressTracker) progressed(id stages.SyncStage, current, target uint64) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.get(id)
+	now := time.Now()
+	p.CurrentBlock, p.UpdatedAt = current, now
+	if target > 0 {
+		p.TargetBlock = target
+	}
+	p.BlocksPerSecond, p.EtaSeconds = 0, 0
+	if !p.Running || current <= p.startBlock {
+		return
+	}
+	if elapsed := now.Sub(p.startTime).Seconds(); elapsed > 0 {
+		p.BlocksPerSecond = float64(current-p.startBlock) / elapsed
+	}
+	if p.BlocksPerSecond > 0 && p.TargetBlock > current {
+		p.EtaSeconds = float64(p.TargetBlock-current) / p.BlocksPerSecond
+	}
+}
+
+func (t *progressTracker) finished(id stages.SyncStage, took time.Duration, err error) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.get(id)
+	now := time.Now()
+	p.Running, p.EtaSeconds, p.UpdatedAt = false, 0, now
+	if err != nil {
+		errTime := now
+		p.LastError, p.LastErrorTime = err.Error(), &errTime
+		return
+	}
+	p.LastRunSeconds = took.Seconds()
+}
+
+func (t *progressTracker) unwound(id stages.SyncStage, from, to uint64, badBlock common.Hash) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.get(id)
+	unwind := stages.Unwind{From: from, To: to, Time: time.Now()}
+	if badBlock != (common.Hash{}) {
+		unwind.BadBlock = &badBlock
+	}
+	if len(p.Unwinds) == maxUnwindHistory {
+		p.Unwinds = append(p.Unwinds[:0], p.Unwinds[1:]...)
+	}
+	p.Unwinds = append(p.Unwinds, unwind)
+	p.CurrentBlock, p.UpdatedAt = to, unwind.Time
+}
+
+func (t *progressTracker) snapshot(ids []stages.SyncStage) []stages.Progress {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	res := make([]stages.Progress, 0, len(ids))
+	for _, id := range ids {
+		p := t.get(id).Progress
+		p.Unwinds = append([]stages.Unwind(nil), p.Unwinds...)
+		res = append(res, p)
+	}
+	return res
+}
+
+// Progress reports the progress of the enabled stages in the order they run.
+func (s *Sync) Progress() []stages.Progress {
+	ids := make([]stages.SyncStage, 0, len(s.stages))
+	for _, stage := range s.stages {
+		if !stage.Disabled {
+			ids = append(ids, stage.ID)
+		}
+	}
+	return s.progress.snapshot(ids)
+}
+
+// stageTarget is where the stage is expected to get in this cycle: the progress of the stages it depends on, or of
+// the stage before it.
+func (s *Sync) stageTarget(stage *Stage, tx kv.Tx, db kv.RoDB) (uint64, error) {
+	deps := stage.DependsOn
+	if deps == nil {
+		for i := range s.stages {
+			if s.stages[i].ID != stage.ID {
+				continue
+			}
+			for j := i - 1; j >= 0; j-- {
+				if !s.stages[j].Disabled {
+					deps = []stages.SyncStage{s.stages[j].ID}
+					break
+				}
+			}
+			break
+		}
+	}
+	if len(deps) == 0 {
+		return 0, nil
+	}
+	var target uint64
+	for _, dep := range deps {
+		depState, err := s.StageState(dep, tx, db)
+		if err != nil {
+			return 0, err
+		}
+		if depState.BlockNumber > target {
+			target = depState.BlockNumber
+		}
+	}
+	return target, nil
+}
@@ -1,9 +1,13 @@
 package stagedsync
 
 import (
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
@@ -616,3 +620,294 @@ func TestSyncInterruptLongUnwind(t *testing.T) {
 func unwindOf(s stages.SyncStage) stages.SyncStage {
 	return stages.SyncStage(append([]byte(s), 0xF0))
 }
+
+func TestParallelStages(t *testing.T) {
+	var lock sync.Mutex
+	flow := make([]stages.SyncStage, 0)
+	appendFlow := func(id stages.SyncStage) {
+		lock.Lock()
+		defer lock.Unlock()
+		flow = append(flow, id)
+	}
+	// both indices have to be running at the same time to get past the barrier
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	waitForOthers := func() error {
+		barrier.Done()
+		passed := make(chan struct{})
+		go func() {
+			barrier.Wait()
+			close(passed)
+		}()
+		select {
+		case <-passed:
+			return nil
+		case <-time.After(10 * time.Second):
+			return errors.New("stages don't run concurrently")
+		}
+	}
+	failTxLookup := false
+	db := memdb.NewTestDB(t)
+	// stages running alone get no transaction and have to open their own
+	updateInOwnTx := func(s *StageState, progress uint64) error {
+		return db.Update(context.Background(), func(tx kv.RwTx) error { return s.Update(tx, progress) })
+	}
+	s := []*Stage{
+		{
+			ID:          stages.Execution,
+			Description: "Executing blocks",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				appendFlow(stages.Execution)
+				return updateInOwnTx(s, 100)
+			},
+		},
+		{
+			ID:          stages.LogIndex,
+			Description: "Generate receipt logs index",
+			DependsOn:   []stages.SyncStage{stages.Execution},
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				if err := waitForOthers(); err != nil {
+					return err
+				}
+				appendFlow(stages.LogIndex)
+				assert.Equal(t, "2/4 LogIndex", s.LogPrefix())
+				executed, err := s.ExecutionAt(tx)
+				if err != nil {
+					return err
+				}
+				return s.Update(tx, executed)
+			},
+		},
+		{
+			ID:          stages.TxLookup,
+			Description: "Generate tx lookup index",
+			DependsOn:   []stages.SyncStage{stages.Execution},
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				if err := waitForOthers(); err != nil {
+					return err
+				}
+				appendFlow(stages.TxLookup)
+				assert.Equal(t, "3/4 TxLookup", s.LogPrefix())
+				if err := s.Update(tx, 100); err != nil {
+					return err
+				}
+				if failTxLookup {
+					return errors.New("test error")
+				}
+				return nil
+			},
+		},
+		{
+			ID:          stages.Finish,
+			Description: "Final",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				appendFlow(stages.Finish)
+				return nil
+			},
+		},
+	}
+	state := New(s, nil, nil)
+	state.EnableParallelStages(t.TempDir(), DefaultParallelStagesBatch)
+	err := state.Run(db, nil, true /* initialCycle */, false /* quiet */)
+	assert.NoError(t, err)
+
+	assert.Equal(t, stages.Execution, flow[0])
+	assert.ElementsMatch(t, []stages.SyncStage{stages.LogIndex, stages.TxLookup}, flow[1:3])
+	assert.Equal(t, stages.Finish, flow[3])
+	for _, id := range []stages.SyncStage{stages.Execution, stages.LogIndex, stages.TxLookup} {
+		stageState, err := state.StageState(id, nil, db)
+		assert.NoError(t, err)
+		assert.Equal(t, 100, int(stageState.BlockNumber))
+	}
+
+	// A failing stage leaves nothing of its group committed
+	s[0].Forward = func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+		return updateInOwnTx(s, 200)
+	}
+	failTxLookup = true
+	barrier.Add(2)
+	err = state.Run(db, nil, false /* initialCycle */, false /* quiet */)
+	assert.Equal(t, fmt.Errorf("[3/4 TxLookup] %w", errors.New("test error")), err)
+	for id, progress := range map[stages.SyncStage]int{stages.Execution: 200, stages.LogIndex: 100, stages.TxLookup: 100} {
+		stageState, err := state.StageState(id, nil, db)
+		assert.NoError(t, err)
+		assert.Equal(t, progress, int(stageState.BlockNumber))
+	}
+}
+
+func TestDependencyOnLaterStage(t *testing.T) {
+	s := []*Stage{
+		{ID: stages.LogIndex, DependsOn: []stages.SyncStage{stages.Execution}},
+		{ID: stages.Execution},
+	}
+	assert.Panics(t, func() { New(s, nil, nil) })
+}
+
+func TestSyncProgress(t *testing.T) {
+	expectedErr := errors.New("test error")
+	failSenders := true
+	s := []*Stage{
+		{
+			ID:          stages.Headers,
+			Description: "Downloading headers",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				s.ReportProgress(1000, 3000)
+				return s.Update(tx, 2000)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return u.Done(tx)
+			},
+		},
+		{
+			ID:          stages.Bodies,
+			Description: "Downloading block bodiess",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				if s.BlockNumber == 0 {
+					u.UnwindTo(1500, common.HexToHash("0x01"))
+				}
+				return s.Update(tx, 2000)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return u.Done(tx)
+			},
+		},
+		{
+			ID:          stages.Senders,
+			Description: "Recovering senders from tx signatures",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				if failSenders {
+					return expectedErr
+				}
+				return nil
+			},
+		},
+	}
+	state := New(s, []stages.SyncStage{s[2].ID, s[1].ID, s[0].ID}, nil)
+	db, tx := memdb.NewTestTx(t)
+	err := state.Run(db, tx, true /* initialCycle */, false /* quiet */)
+	assert.Equal(t, fmt.Errorf("[3/3 Senders] %w", expectedErr), err)
+
+	progress := state.Progress()
+	assert.Equal(t, 3, len(progress))
+	headers, bodies, senders := progress[0], progress[1], progress[2]
+	assert.Equal(t, stages.Headers, headers.Stage)
+	assert.Equal(t, 2000, int(headers.CurrentBlock))
+	assert.Equal(t, 3000, int(headers.TargetBlock)) // reported by the stage, not derived from other stages
+	assert.False(t, headers.Running)
+	badBlock := common.HexToHash("0x01")
+	assert.Equal(t, []stages.Unwind{{From: 2000, To: 1500, BadBlock: &badBlock, Time: headers.Unwinds[0].Time}}, headers.Unwinds)
+
+	assert.Equal(t, 2000, int(bodies.CurrentBlock))
+	assert.Equal(t, 2000, int(bodies.TargetBlock)) // headers progress
+	assert.Equal(t, 1, len(bodies.Unwinds))
+	assert.Empty(t, bodies.LastError)
+
+	assert.Equal(t, "[3/3 Senders] test error", senders.LastError)
+	assert.NotNil(t, senders.LastErrorTime)
+	assert.False(t, senders.Running)
+
+	failSenders = false
+	state.DisableStages(stages.Bodies)
+	err = state.Run(db, tx, false /* initialCycle */, false /* quiet */)
+	assert.NoError(t, err)
+	progress = state.Progress()
+	assert.Equal(t, []stages.SyncStage{stages.Headers, stages.Senders}, []stages.SyncStage{progress[0].Stage, progress[1].Stage})
+	// the last error stays reported until there is a new one
+	assert.Equal(t, "[3/3 Senders] test error", progress[1].LastError)
+}
+
+func TestParallelStagesBatch(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	var lock sync.Mutex
+	rounds := map[stages.SyncStage][][2]uint64{}
+	// records the progress the stage starts the round from and the block it may go to
+	forward := func(id stages.SyncStage, followLimit bool) ExecFunc {
+		return func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+			executed, err := s.ExecutionAt(tx)
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			rounds[id] = append(rounds[id], [2]uint64{s.BlockNumber, executed})
+			lock.Unlock()
+			if !followLimit {
+				executed, err = stages.GetStageProgress(tx, stages.Execution)
+				if err != nil {
+					return err
+				}
+			}
+			return s.Update(tx, executed)
+		}
+	}
+	s := []*Stage{
+		{
+			ID:          stages.Execution,
+			Description: "Executing blocks",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				return db.Update(context.Background(), func(tx kv.RwTx) error { return s.Update(tx, 250) })
+			},
+		},
+		{ID: stages.LogIndex, DependsOn: []stages.SyncStage{stages.Execution}, Forward: forward(stages.LogIndex, true)},
+		{ID: stages.TxLookup, DependsOn: []stages.SyncStage{stages.Execution}, Forward: forward(stages.TxLookup, false)},
+	}
+	state := New(s, nil, nil)
+	state.EnableParallelStages(t.TempDir(), 100)
+	assert.NoError(t, state.Run(db, nil, true /* initialCycle */, false /* quiet */))
+
+	// every round starts from what the previous one committed
+	assert.Equal(t, [][2]uint64{{0, 100}, {100, 200}, {200, 250}}, rounds[stages.LogIndex])
+	// a stage which doesn't stop at the end of the round catches up in the first one
+	assert.Equal(t, [][2]uint64{{0, 100}}, rounds[stages.TxLookup])
+	for _, id := range []stages.SyncStage{stages.LogIndex, stages.TxLookup} {
+		stageState, err := state.StageState(id, nil, db)
+		assert.NoError(t, err)
+		assert.Equal(t, 250, int(stageState.BlockNumber))
+	}
+}
+
+// BenchmarkParallelStagesBatch times a group of two index stages catching up with Execution over 200k blocks, with the
+// whole output in one batch and in bounded rounds.
+func BenchmarkParallelStagesBatch(b *testing.B) {
+	const blocks, keysPerBlock = 200_000, 4
+	forward := func(table string) ExecFunc {
+		return func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+			executed, err := s.ExecutionAt(tx)
+			if err != nil {
+				return err
+			}
+			k := make([]byte, 32)
+			for blockNum := s.BlockNumber + 1; blockNum <= executed; blockNum++ {
+				for i := 0; i < keysPerBlock; i++ {
+					binary.BigEndian.PutUint64(k, blockNum)
+					binary.BigEndian.PutUint64(k[8:], uint64(i))
+					if err := tx.Put(table, k, k[:8]); err != nil {
+						return err
+					}
+				}
+			}
+			return s.Update(tx, executed)
+		}
+	}
+	for _, batch := range []uint64{0, 10_000, DefaultParallelStagesBatch} {
+		b.Run(fmt.Sprintf("batch=%d", batch), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				db := memdb.NewTestDB(b)
+				if err := db.Update(context.Background(), func(tx kv.RwTx) error {
+					return stages.SaveStageProgress(tx, stages.Execution, blocks)
+				}); err != nil {
+					b.Fatal(err)
+				}
+				state := New([]*Stage{
+					{ID: stages.LogIndex, DependsOn: []stages.SyncStage{stages.Execution}, Forward: forward(kv.LogTopicIndex)},
+					{ID: stages.TxLookup, DependsOn: []stages.SyncStage{stages.Execution}, Forward: forward(kv.TxLookup)},
+				}, nil, nil)
+				state.EnableParallelStages(b.TempDir(), batch)
+				b.StartTimer()
+				if err := state.Run(db, nil, true /* initialCycle */, true /* quiet */); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
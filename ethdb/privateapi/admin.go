@@ -0,0 +1,304 @@
+package privateapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/log/v3"
+	"google.golang.org/grpc"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/p2p"
+)
+
+// The ADMIN service lets rpcdaemon manage the static and trusted peers of the sentries and read their scores, and the
+// backend ban peers for good. Like SYNCPROGRESS its messages are JSON, and both the backend and the sentries serve it.
+
+type AdminPeerRequest struct {
+	URL string `json:"url"`
+}
+
+type AdminPeerReply struct{}
+
+type AdminBanPeerRequest struct {
+	PeerID hexutil.Bytes `json:"peerId"` // 64 byte public key of the peer
+}
+
+type AdminPeerScoresRequest struct{}
+
+type AdminPeerScoresReply struct {
+	Scores map[string]*p2p.PeerScore `json:"scores"` // by the ID of the connected peers, as in the PeerInfo replies
+}
+
+type AdminServer interface {
+	AdminAddPeer(context.Context, *AdminPeerRequest) (*AdminPeerReply, error)
+	AdminRemovePeer(context.Context, *AdminPeerRequest) (*AdminPeerReply, error)
+	AdminAddTrustedPeer(context.Context, *AdminPeerRequest) (*AdminPeerReply, error)
+	AdminRemoveTrustedPeer(context.Context, *AdminPeerRequest) (*AdminPeerReply, error)
+	AdminBanPeer(context.Context, *AdminBanPeerRequest) (*AdminPeerReply, error)
+	AdminPeerScores(context.Context, *AdminPeerScoresRequest) (*AdminPeerScoresReply, error)
+}
+
+type AdminClient interface {
+	AdminAddPeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error)
+	AdminRemovePeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error)
+	AdminAddTrustedPeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error)
+	AdminRemoveTrustedPeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error)
+	AdminBanPeer(ctx context.Context, in *AdminBanPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error)
+	AdminPeerScores(ctx context.Context, in *AdminPeerScoresRequest, opts ...grpc.CallOption) (*AdminPeerScoresReply, error)
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&adminServiceDesc, srv)
+}
+
+const (
+	adminAddPeerMethod           = "/remote.ADMIN/AddPeer"
+	adminRemovePeerMethod        = "/remote.ADMIN/RemovePeer"
+	adminAddTrustedPeerMethod    = "/remote.ADMIN/AddTrustedPeer"
+	adminRemoveTrustedPeerMethod = "/remote.ADMIN/RemoveTrustedPeer"
+	adminBanPeerMethod           = "/remote.ADMIN/BanPeer"
+	adminPeerScoresMethod        = "/remote.ADMIN/PeerScores"
+)
+
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: "remote.ADMIN",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "AddPeer", Handler: adminHandler(adminAddPeerMethod, AdminServer.AdminAddPeer)},
+		{MethodName: "RemovePeer", Handler: adminHandler(adminRemovePeerMethod, AdminServer.AdminRemovePeer)},
+		{MethodName: "AddTrustedPeer", Handler: adminHandler(adminAddTrustedPeerMethod, AdminServer.AdminAddTrustedPeer)},
+		{MethodName: "RemoveTrustedPeer", Handler: adminHandler(adminRemoveTrustedPeerMethod, AdminServer.AdminRemoveTrustedPeer)},
+		{MethodName: "BanPeer", Handler: jsonHandler(adminBanPeerMethod, func() interface{} { return new(AdminBanPeerRequest) },
+			func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+				return srv.(AdminServer).AdminBanPeer(ctx, in.(*AdminBanPeerRequest))
+			})},
+		{MethodName: "PeerScores", Handler: jsonHandler(adminPeerScoresMethod, func() interface{} { return new(AdminPeerScoresRequest) },
+			func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+				return srv.(AdminServer).AdminPeerScores(ctx, in.(*AdminPeerScoresRequest))
+			})},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func adminHandler(method string, call func(AdminServer, context.Context, *AdminPeerRequest) (*AdminPeerReply, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return jsonHandler(method, func() interface{} { return new(AdminPeerRequest) },
+		func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+			return call(srv.(AdminServer), ctx, in.(*AdminPeerRequest))
+		})
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) invoke(ctx context.Context, method string, in *AdminPeerRequest, opts []grpc.CallOption) (*AdminPeerReply, error) {
+	out := new(AdminPeerReply)
+	if err := invokeJSON(ctx, c.cc, method, in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) AdminAddPeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	return c.invoke(ctx, adminAddPeerMethod, in, opts)
+}
+
+func (c *adminClient) AdminRemovePeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	return c.invoke(ctx, adminRemovePeerMethod, in, opts)
+}
+
+func (c *adminClient) AdminAddTrustedPeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	return c.invoke(ctx, adminAddTrustedPeerMethod, in, opts)
+}
+
+func (c *adminClient) AdminRemoveTrustedPeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	return c.invoke(ctx, adminRemoveTrustedPeerMethod, in, opts)
+}
+
+func (c *adminClient) AdminBanPeer(ctx context.Context, in *AdminBanPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	out := new(AdminPeerReply)
+	if err := invokeJSON(ctx, c.cc, adminBanPeerMethod, in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) AdminPeerScores(ctx context.Context, in *AdminPeerScoresRequest, opts ...grpc.CallOption) (*AdminPeerScoresReply, error) {
+	out := new(AdminPeerScoresReply)
+	if err := invokeJSON(ctx, c.cc, adminPeerScoresMethod, in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NewAdminClientDirect calls the server in the same process, without grpc.
+func NewAdminClientDirect(server AdminServer) AdminClient {
+	return &adminClientDirect{server: server}
+}
+
+type adminClientDirect struct {
+	server AdminServer
+}
+
+func (c *adminClientDirect) AdminAddPeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	return c.server.AdminAddPeer(ctx, in)
+}
+
+func (c *adminClientDirect) AdminRemovePeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	return c.server.AdminRemovePeer(ctx, in)
+}
+
+func (c *adminClientDirect) AdminAddTrustedPeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	return c.server.AdminAddTrustedPeer(ctx, in)
+}
+
+func (c *adminClientDirect) AdminRemoveTrustedPeer(ctx context.Context, in *AdminPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	return c.server.AdminRemoveTrustedPeer(ctx, in)
+}
+
+func (c *adminClientDirect) AdminBanPeer(ctx context.Context, in *AdminBanPeerRequest, opts ...grpc.CallOption) (*AdminPeerReply, error) {
+	return c.server.AdminBanPeer(ctx, in)
+}
+
+func (c *adminClientDirect) AdminPeerScores(ctx context.Context, in *AdminPeerScoresRequest, opts ...grpc.CallOption) (*AdminPeerScoresReply, error) {
+	return c.server.AdminPeerScores(ctx, in)
+}
+
+// SentryAdmins manages the peers of all the sentries of the node. The sentries share the node key, so a static peer is
+// added to one of them only: the connections of the others would be refused by the peer as duplicates.
+type SentryAdmins []AdminClient
+
+// AddPeer adds the static peer to the first sentry which accepts it
+func (admins SentryAdmins) AddPeer(ctx context.Context, url string) error {
+	if len(admins) == 0 {
+		return errors.New("no sentries")
+	}
+	var err error
+	for _, admin := range admins {
+		if _, err = admin.AdminAddPeer(ctx, &AdminPeerRequest{URL: url}); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("none of %d sentries added the peer: %w", len(admins), err)
+}
+
+// RemovePeer removes the static peer from every sentry, as any of them may have it
+func (admins SentryAdmins) RemovePeer(ctx context.Context, url string) error {
+	return admins.all("RemovePeer", func(admin AdminClient) error {
+		_, err := admin.AdminRemovePeer(ctx, &AdminPeerRequest{URL: url})
+		return err
+	})
+}
+
+// AddTrustedPeer trusts the peer on every sentry, it is removed again from all of them when one fails
+func (admins SentryAdmins) AddTrustedPeer(ctx context.Context, url string) error {
+	for i, admin := range admins {
+		if _, err := admin.AdminAddTrustedPeer(ctx, &AdminPeerRequest{URL: url}); err != nil {
+			for _, added := range admins[:i] {
+				if _, rollbackErr := added.AdminRemoveTrustedPeer(ctx, &AdminPeerRequest{URL: url}); rollbackErr != nil {
+					log.Warn("[admin] Could not roll back trusted peer", "url", url, "err", rollbackErr)
+				}
+			}
+			return fmt.Errorf("sentry %d of %d AddTrustedPeer: %w", i+1, len(admins), err)
+		}
+	}
+	return nil
+}
+
+// RemoveTrustedPeer removes the trusted peer from every sentry
+func (admins SentryAdmins) RemoveTrustedPeer(ctx context.Context, url string) error {
+	return admins.all("RemoveTrustedPeer", func(admin AdminClient) error {
+		_, err := admin.AdminRemoveTrustedPeer(ctx, &AdminPeerRequest{URL: url})
+		return err
+	})
+}
+
+// BanPeer bans the peer for good on every sentry, the one connected to it disconnects it
+func (admins SentryAdmins) BanPeer(ctx context.Context, peerID []byte) error {
+	return admins.all("BanPeer", func(admin AdminClient) error {
+		_, err := admin.AdminBanPeer(ctx, &AdminBanPeerRequest{PeerID: peerID})
+		return err
+	})
+}
+
+// PeerScores collects the scores of the peers connected to the sentries
+func (admins SentryAdmins) PeerScores(ctx context.Context) (map[string]*p2p.PeerScore, error) {
+	scores := map[string]*p2p.PeerScore{}
+	for _, admin := range admins {
+		reply, err := admin.AdminPeerScores(ctx, &AdminPeerScoresRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("sentry PeerScores: %w", err)
+		}
+		for id, score := range reply.Scores {
+			scores[id] = score
+		}
+	}
+	return scores, nil
+}
+
+// all calls every sentry, even after a failure, and reports how many of them failed
+func (admins SentryAdmins) all(method string, call func(AdminClient) error) error {
+	var failed int
+	var firstErr error
+	for _, admin := range admins {
+		if err := call(admin); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+		}
+	}
+	if firstErr != nil {
+		return fmt.Errorf("%s failed on %d of %d sentries: %w", method, failed, len(admins), firstErr)
+	}
+	return nil
+}
+
+func (s *EthBackendServer) AdminAddPeer(ctx context.Context, in *AdminPeerRequest) (*AdminPeerReply, error) {
+	if err := s.eth.AddPeer(ctx, in.URL); err != nil {
+		return nil, err
+	}
+	return &AdminPeerReply{}, nil
+}
+
+func (s *EthBackendServer) AdminRemovePeer(ctx context.Context, in *AdminPeerRequest) (*AdminPeerReply, error) {
+	if err := s.eth.RemovePeer(ctx, in.URL); err != nil {
+		return nil, err
+	}
+	return &AdminPeerReply{}, nil
+}
+
+func (s *EthBackendServer) AdminAddTrustedPeer(ctx context.Context, in *AdminPeerRequest) (*AdminPeerReply, error) {
+	if err := s.eth.AddTrustedPeer(ctx, in.URL); err != nil {
+		return nil, err
+	}
+	return &AdminPeerReply{}, nil
+}
+
+func (s *EthBackendServer) AdminRemoveTrustedPeer(ctx context.Context, in *AdminPeerRequest) (*AdminPeerReply, error) {
+	if err := s.eth.RemoveTrustedPeer(ctx, in.URL); err != nil {
+		return nil, err
+	}
+	return &AdminPeerReply{}, nil
+}
+
+func (s *EthBackendServer) AdminBanPeer(ctx context.Context, in *AdminBanPeerRequest) (*AdminPeerReply, error) {
+	if err := s.eth.BanPeer(ctx, in.PeerID); err != nil {
+		return nil, err
+	}
+	return &AdminPeerReply{}, nil
+}
+
+func (s *EthBackendServer) AdminPeerScores(ctx context.Context, _ *AdminPeerScoresRequest) (*AdminPeerScoresReply, error) {
+	scores, err := s.eth.PeerScores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminPeerScoresReply{Scores: scores}, nil
+}
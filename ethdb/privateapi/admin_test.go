@@ -0,0 +1,160 @@
+package privateapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/ledgerwatch/erigon/p2p"
+)
+
+type testAdminServer struct {
+	static, trusted map[string]struct{}
+	banned          map[string]struct{}
+	scores          map[string]*p2p.PeerScore
+	down            bool
+}
+
+func newTestAdminServer() *testAdminServer {
+	return &testAdminServer{static: map[string]struct{}{}, trusted: map[string]struct{}{}, banned: map[string]struct{}{}, scores: map[string]*p2p.PeerScore{}}
+}
+
+func (s *testAdminServer) AdminAddPeer(_ context.Context, in *AdminPeerRequest) (*AdminPeerReply, error) {
+	if in.URL == "" {
+		return nil, errors.New("invalid enode")
+	}
+	if s.down {
+		return nil, errors.New("sentry down")
+	}
+	s.static[in.URL] = struct{}{}
+	return &AdminPeerReply{}, nil
+}
+
+func (s *testAdminServer) AdminRemovePeer(_ context.Context, in *AdminPeerRequest) (*AdminPeerReply, error) {
+	if s.down {
+		return nil, errors.New("sentry down")
+	}
+	delete(s.static, in.URL)
+	return &AdminPeerReply{}, nil
+}
+
+func (s *testAdminServer) AdminAddTrustedPeer(_ context.Context, in *AdminPeerRequest) (*AdminPeerReply, error) {
+	if s.down {
+		return nil, errors.New("sentry down")
+	}
+	s.trusted[in.URL] = struct{}{}
+	return &AdminPeerReply{}, nil
+}
+
+func (s *testAdminServer) AdminRemoveTrustedPeer(_ context.Context, in *AdminPeerRequest) (*AdminPeerReply, error) {
+	if s.down {
+		return nil, errors.New("sentry down")
+	}
+	delete(s.trusted, in.URL)
+	return &AdminPeerReply{}, nil
+}
+
+func (s *testAdminServer) AdminBanPeer(_ context.Context, in *AdminBanPeerRequest) (*AdminPeerReply, error) {
+	if s.down {
+		return nil, errors.New("sentry down")
+	}
+	s.banned[in.PeerID.String()] = struct{}{}
+	return &AdminPeerReply{}, nil
+}
+
+func (s *testAdminServer) AdminPeerScores(context.Context, *AdminPeerScoresRequest) (*AdminPeerScoresReply, error) {
+	if s.down {
+		return nil, errors.New("sentry down")
+	}
+	return &AdminPeerScoresReply{Scores: s.scores}, nil
+}
+
+func TestAdminOverGrpc(t *testing.T) {
+	ctx := context.Background()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	admin := newTestAdminServer()
+	RegisterAdminServer(server, admin)
+	go server.Serve(lis) //nolint:errcheck
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := NewAdminClient(conn)
+
+	_, err = client.AdminAddPeer(ctx, &AdminPeerRequest{URL: "enode://a@127.0.0.1:30303"})
+	require.NoError(t, err)
+	_, err = client.AdminAddPeer(ctx, &AdminPeerRequest{URL: "enode://b@127.0.0.1:30303"})
+	require.NoError(t, err)
+	_, err = client.AdminRemovePeer(ctx, &AdminPeerRequest{URL: "enode://a@127.0.0.1:30303"})
+	require.NoError(t, err)
+	_, err = client.AdminAddTrustedPeer(ctx, &AdminPeerRequest{URL: "enode://c@127.0.0.1:30303"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]struct{}{"enode://b@127.0.0.1:30303": {}}, admin.static)
+	require.Equal(t, map[string]struct{}{"enode://c@127.0.0.1:30303": {}}, admin.trusted)
+	_, err = client.AdminRemoveTrustedPeer(ctx, &AdminPeerRequest{URL: "enode://c@127.0.0.1:30303"})
+	require.NoError(t, err)
+	require.Empty(t, admin.trusted)
+
+	_, err = client.AdminAddPeer(ctx, &AdminPeerRequest{})
+	require.ErrorContains(t, err, "invalid enode")
+
+	_, err = client.AdminBanPeer(ctx, &AdminBanPeerRequest{PeerID: []byte{1, 2}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]struct{}{"0x0102": {}}, admin.banned)
+
+	admin.scores["aa"] = &p2p.PeerScore{Enode: "enode://a@127.0.0.1:30303", Headers: 2000, BannedUntil: p2p.BannedForever}
+	scores, err := client.AdminPeerScores(ctx, &AdminPeerScoresRequest{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]*p2p.PeerScore{"aa": {Headers: 2000, BannedUntil: p2p.BannedForever}}, scores.Scores)
+}
+
+func TestSentryAdmins(t *testing.T) {
+	ctx := context.Background()
+	first, second := newTestAdminServer(), newTestAdminServer()
+	admins := SentryAdmins{NewAdminClientDirect(first), NewAdminClientDirect(second)}
+	a, b := "enode://a@127.0.0.1:30303", "enode://b@127.0.0.1:30303"
+
+	// the static peer is dialed by one sentry only, the next one takes it when the first fails
+	require.NoError(t, admins.AddPeer(ctx, a))
+	first.down = true
+	require.NoError(t, admins.AddPeer(ctx, b))
+	require.Equal(t, map[string]struct{}{a: {}}, first.static)
+	require.Equal(t, map[string]struct{}{b: {}}, second.static)
+	require.ErrorContains(t, admins.AddPeer(ctx, ""), "none of 2 sentries added the peer: invalid enode")
+
+	// the removal still reaches the sentries after the failed one
+	require.ErrorContains(t, admins.RemovePeer(ctx, b), "RemovePeer failed on 1 of 2 sentries: sentry down")
+	require.Empty(t, second.static)
+
+	// a failed trusted peer is rolled back on the sentries which took it
+	first.down = false
+	second.down = true
+	require.ErrorContains(t, admins.AddTrustedPeer(ctx, a), "sentry 2 of 2 AddTrustedPeer: sentry down")
+	require.Empty(t, first.trusted)
+	second.down = false
+	require.NoError(t, admins.AddTrustedPeer(ctx, a))
+	require.Equal(t, map[string]struct{}{a: {}}, first.trusted)
+	require.Equal(t, map[string]struct{}{a: {}}, second.trusted)
+	require.NoError(t, admins.RemoveTrustedPeer(ctx, a))
+	require.Empty(t, first.trusted)
+	require.Empty(t, second.trusted)
+
+	require.ErrorContains(t, SentryAdmins{}.AddPeer(ctx, a), "no sentries")
+
+	first.scores["1"], second.scores["2"] = &p2p.PeerScore{Headers: 1}, &p2p.PeerScore{Bodies: 2}
+	scores, err := admins.PeerScores(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]*p2p.PeerScore{"1": {Headers: 1}, "2": {Bodies: 2}}, scores)
+
+	first.down = true
+	require.ErrorContains(t, admins.BanPeer(ctx, []byte{1}), "BanPeer failed on 1 of 2 sentries: sentry down")
+	require.Equal(t, map[string]struct{}{"0x01": {}}, second.banned)
+}
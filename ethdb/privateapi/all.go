@@ -26,6 +26,9 @@ func StartGrpc(kv *remotedbserver.KvServer, ethBackendSrv *EthBackendServer, txP
 
 	grpcServer := grpcutil.NewServer(rateLimit, creds)
 	remote.RegisterETHBACKENDServer(grpcServer, ethBackendSrv)
+	RegisterSyncProgressServer(grpcServer, ethBackendSrv)
+	RegisterCliqueServer(grpcServer, ethBackendSrv)
+	RegisterAdminServer(grpcServer, ethBackendSrv)
 	if txPoolServer != nil {
 		txpool_proto.RegisterTxpoolServer(grpcServer, txPoolServer)
 	}
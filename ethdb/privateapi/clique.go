@@ -0,0 +1,219 @@
+package privateapi
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/clique"
+)
+
+// The CLIQUE service lets rpcdaemon operate the clique sealer of the node, like SYNCPROGRESS its messages are JSON.
+
+var ErrNotClique = errors.New("the consensus engine isn't clique")
+
+type CliqueProposeRequest struct {
+	Address common.Address `json:"address"`
+	Auth    bool           `json:"auth"`
+}
+
+type CliqueDiscardRequest struct {
+	Address common.Address `json:"address"`
+}
+
+type CliqueProposalsRequest struct{}
+
+type CliqueStatusRequest struct{}
+
+type CliqueReply struct{}
+
+type CliqueProposalsReply struct {
+	Proposals map[common.Address]bool `json:"proposals"`
+}
+
+type CliqueServer interface {
+	CliquePropose(context.Context, *CliqueProposeRequest) (*CliqueReply, error)
+	CliqueDiscard(context.Context, *CliqueDiscardRequest) (*CliqueReply, error)
+	CliqueProposals(context.Context, *CliqueProposalsRequest) (*CliqueProposalsReply, error)
+	CliqueStatus(context.Context, *CliqueStatusRequest) (*clique.Status, error)
+}
+
+type CliqueClient interface {
+	CliquePropose(ctx context.Context, in *CliqueProposeRequest, opts ...grpc.CallOption) (*CliqueReply, error)
+	CliqueDiscard(ctx context.Context, in *CliqueDiscardRequest, opts ...grpc.CallOption) (*CliqueReply, error)
+	CliqueProposals(ctx context.Context, in *CliqueProposalsRequest, opts ...grpc.CallOption) (*CliqueProposalsReply, error)
+	CliqueStatus(ctx context.Context, in *CliqueStatusRequest, opts ...grpc.CallOption) (*clique.Status, error)
+}
+
+func RegisterCliqueServer(s grpc.ServiceRegistrar, srv CliqueServer) {
+	s.RegisterService(&cliqueServiceDesc, srv)
+}
+
+const (
+	cliqueProposeMethod   = "/remote.CLIQUE/Propose"
+	cliqueDiscardMethod   = "/remote.CLIQUE/Discard"
+	cliqueProposalsMethod = "/remote.CLIQUE/Proposals"
+	cliqueStatusMethod    = "/remote.CLIQUE/Status"
+)
+
+var cliqueServiceDesc = grpc.ServiceDesc{
+	ServiceName: "remote.CLIQUE",
+	HandlerType: (*CliqueServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Propose",
+			Handler: cliqueHandler(cliqueProposeMethod, func() interface{} { return new(CliqueProposeRequest) },
+				func(srv CliqueServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return srv.CliquePropose(ctx, in.(*CliqueProposeRequest))
+				}),
+		},
+		{
+			MethodName: "Discard",
+			Handler: cliqueHandler(cliqueDiscardMethod, func() interface{} { return new(CliqueDiscardRequest) },
+				func(srv CliqueServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return srv.CliqueDiscard(ctx, in.(*CliqueDiscardRequest))
+				}),
+		},
+		{
+			MethodName: "Proposals",
+			Handler: cliqueHandler(cliqueProposalsMethod, func() interface{} { return new(CliqueProposalsRequest) },
+				func(srv CliqueServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return srv.CliqueProposals(ctx, in.(*CliqueProposalsRequest))
+				}),
+		},
+		{
+			MethodName: "Status",
+			Handler: cliqueHandler(cliqueStatusMethod, func() interface{} { return new(CliqueStatusRequest) },
+				func(srv CliqueServer, ctx context.Context, in interface{}) (interface{}, error) {
+					return srv.CliqueStatus(ctx, in.(*CliqueStatusRequest))
+				}),
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func cliqueHandler(method string, newIn func() interface{}, call func(CliqueServer, context.Context, interface{}) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return jsonHandler(method, newIn, func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+		return call(srv.(CliqueServer), ctx, in)
+	})
+}
+
+type cliqueClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCliqueClient(cc grpc.ClientConnInterface) CliqueClient {
+	return &cliqueClient{cc}
+}
+
+func (c *cliqueClient) invoke(ctx context.Context, method string, in, out interface{}, opts []grpc.CallOption) error {
+	return invokeJSON(ctx, c.cc, method, in, out, opts)
+}
+
+func (c *cliqueClient) CliquePropose(ctx context.Context, in *CliqueProposeRequest, opts ...grpc.CallOption) (*CliqueReply, error) {
+	out := new(CliqueReply)
+	if err := c.invoke(ctx, cliqueProposeMethod, in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cliqueClient) CliqueDiscard(ctx context.Context, in *CliqueDiscardRequest, opts ...grpc.CallOption) (*CliqueReply, error) {
+	out := new(CliqueReply)
+	if err := c.invoke(ctx, cliqueDiscardMethod, in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cliqueClient) CliqueProposals(ctx context.Context, in *CliqueProposalsRequest, opts ...grpc.CallOption) (*CliqueProposalsReply, error) {
+	out := new(CliqueProposalsReply)
+	if err := c.invoke(ctx, cliqueProposalsMethod, in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cliqueClient) CliqueStatus(ctx context.Context, in *CliqueStatusRequest, opts ...grpc.CallOption) (*clique.Status, error) {
+	out := new(clique.Status)
+	if err := c.invoke(ctx, cliqueStatusMethod, in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NewCliqueClientDirect calls the server in the same process, without grpc.
+func NewCliqueClientDirect(server CliqueServer) CliqueClient {
+	return &cliqueClientDirect{server: server}
+}
+
+type cliqueClientDirect struct {
+	server CliqueServer
+}
+
+func (c *cliqueClientDirect) CliquePropose(ctx context.Context, in *CliqueProposeRequest, opts ...grpc.CallOption) (*CliqueReply, error) {
+	return c.server.CliquePropose(ctx, in)
+}
+
+func (c *cliqueClientDirect) CliqueDiscard(ctx context.Context, in *CliqueDiscardRequest, opts ...grpc.CallOption) (*CliqueReply, error) {
+	return c.server.CliqueDiscard(ctx, in)
+}
+
+func (c *cliqueClientDirect) CliqueProposals(ctx context.Context, in *CliqueProposalsRequest, opts ...grpc.CallOption) (*CliqueProposalsReply, error) {
+	return c.server.CliqueProposals(ctx, in)
+}
+
+func (c *cliqueClientDirect) CliqueStatus(ctx context.Context, in *CliqueStatusRequest, opts ...grpc.CallOption) (*clique.Status, error) {
+	return c.server.CliqueStatus(ctx, in)
+}
+
+func (s *EthBackendServer) cliqueAPI() (*clique.API, error) {
+	if s.eth == nil {
+		return nil, ErrNotClique
+	}
+	api := s.eth.CliqueAPI()
+	if api == nil {
+		return nil, ErrNotClique
+	}
+	return api, nil
+}
+
+func (s *EthBackendServer) CliquePropose(_ context.Context, in *CliqueProposeRequest) (*CliqueReply, error) {
+	api, err := s.cliqueAPI()
+	if err != nil {
+		return nil, err
+	}
+	if err := api.Propose(in.Address, in.Auth); err != nil {
+		return nil, err
+	}
+	return &CliqueReply{}, nil
+}
+
+func (s *EthBackendServer) CliqueDiscard(_ context.Context, in *CliqueDiscardRequest) (*CliqueReply, error) {
+	api, err := s.cliqueAPI()
+	if err != nil {
+		return nil, err
+	}
+	if err := api.Discard(in.Address); err != nil {
+		return nil, err
+	}
+	return &CliqueReply{}, nil
+}
+
+func (s *EthBackendServer) CliqueProposals(_ context.Context, _ *CliqueProposalsRequest) (*CliqueProposalsReply, error) {
+	api, err := s.cliqueAPI()
+	if err != nil {
+		return nil, err
+	}
+	return &CliqueProposalsReply{Proposals: api.Proposals()}, nil
+}
+
+func (s *EthBackendServer) CliqueStatus(ctx context.Context, _ *CliqueStatusRequest) (*clique.Status, error) {
+	api, err := s.cliqueAPI()
+	if err != nil {
+		return nil, err
+	}
+	return api.Status(ctx)
+}
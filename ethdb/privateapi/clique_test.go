@@ -0,0 +1,69 @@
+package privateapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/clique"
+)
+
+type testCliqueServer struct {
+	proposals map[common.Address]bool
+}
+
+func (s *testCliqueServer) CliquePropose(_ context.Context, in *CliqueProposeRequest) (*CliqueReply, error) {
+	s.proposals[in.Address] = in.Auth
+	return &CliqueReply{}, nil
+}
+
+func (s *testCliqueServer) CliqueDiscard(_ context.Context, in *CliqueDiscardRequest) (*CliqueReply, error) {
+	delete(s.proposals, in.Address)
+	return &CliqueReply{}, nil
+}
+
+func (s *testCliqueServer) CliqueProposals(context.Context, *CliqueProposalsRequest) (*CliqueProposalsReply, error) {
+	return &CliqueProposalsReply{Proposals: s.proposals}, nil
+}
+
+func (s *testCliqueServer) CliqueStatus(context.Context, *CliqueStatusRequest) (*clique.Status, error) {
+	return &clique.Status{InturnPercent: 50, SigningStatus: map[common.Address]int{{1}: 2, {2}: 2}, NumBlocks: 4}, nil
+}
+
+func TestCliqueOverGrpc(t *testing.T) {
+	ctx := context.Background()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	RegisterCliqueServer(server, &testCliqueServer{proposals: map[common.Address]bool{}})
+	go server.Serve(lis) //nolint:errcheck
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := NewCliqueClient(conn)
+
+	_, err = client.CliquePropose(ctx, &CliqueProposeRequest{Address: common.Address{3}, Auth: true})
+	require.NoError(t, err)
+	_, err = client.CliquePropose(ctx, &CliqueProposeRequest{Address: common.Address{4}})
+	require.NoError(t, err)
+	_, err = client.CliqueDiscard(ctx, &CliqueDiscardRequest{Address: common.Address{3}})
+	require.NoError(t, err)
+	proposals, err := client.CliqueProposals(ctx, &CliqueProposalsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, map[common.Address]bool{{4}: false}, proposals.Proposals)
+
+	status, err := client.CliqueStatus(ctx, &CliqueStatusRequest{})
+	require.NoError(t, err)
+	require.Equal(t, &clique.Status{InturnPercent: 50, SigningStatus: map[common.Address]int{{1}: 2, {2}: 2}, NumBlocks: 4}, status)
+
+	// Without a clique node behind it the backend server refuses
+	_, err = (&EthBackendServer{}).CliquePropose(ctx, &CliqueProposeRequest{Address: common.Address{3}, Auth: true})
+	require.ErrorIs(t, err, ErrNotClique)
+}
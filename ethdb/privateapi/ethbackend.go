@@ -17,10 +17,13 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/clique"
 	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc"
@@ -37,7 +40,10 @@ import (
 // 2.2.0 - add NodesInfo function
 // 3.0.0 - adding PoS interfaces
 // 3.1.0 - add Subscribe to logs
-var EthBackendAPIVersion = &types2.VersionReply{Major: 3, Minor: 1, Patch: 0}
+// 3.2.0 - add SYNCPROGRESS service
+// 3.3.0 - add CLIQUE service
+// 3.4.0 - add ADMIN service
+var EthBackendAPIVersion = &types2.VersionReply{Major: 3, Minor: 4, Patch: 0}
 
 const MaxBuilders = 128
 
@@ -72,6 +78,14 @@ type EthBackend interface {
 	NetPeerCount() (uint64, error)
 	NodesInfo(limit int) (*remote.NodesInfoReply, error)
 	Peers(ctx context.Context) (*remote.PeersReply, error)
+	SyncProgress() []stages.Progress
+	CliqueAPI() *clique.API
+	AddPeer(ctx context.Context, url string) error
+	RemovePeer(ctx context.Context, url string) error
+	AddTrustedPeer(ctx context.Context, url string) error
+	RemoveTrustedPeer(ctx context.Context, url string) error
+	BanPeer(ctx context.Context, peerID []byte) error
+	PeerScores(ctx context.Context) (map[string]*p2p.PeerScore, error)
 }
 
 func NewEthBackendServer(ctx context.Context, eth EthBackend, db kv.RwDB, events *shards.Events, blockReader services.BlockAndTxnReader,
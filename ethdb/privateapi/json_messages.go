@@ -0,0 +1,62 @@
+package privateapi
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// The services without a protobuf definition in the interfaces repository (SYNCPROGRESS, CLIQUE, ADMIN) exchange JSON
+// messages. They are carried in a BytesValue, so they go over the default proto codec of grpc like the messages of
+// the other services, and no codec has to be registered in the process.
+
+func encodeJSONMessage(v interface{}) (*wrapperspb.BytesValue, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return wrapperspb.Bytes(data), nil
+}
+
+// invokeJSON calls the method of a JSON service, out is filled with the reply
+func invokeJSON(ctx context.Context, cc grpc.ClientConnInterface, method string, in, out interface{}, opts []grpc.CallOption) error {
+	req, err := encodeJSONMessage(in)
+	if err != nil {
+		return err
+	}
+	reply := new(wrapperspb.BytesValue)
+	if err := cc.Invoke(ctx, method, req, reply, opts...); err != nil {
+		return err
+	}
+	return json.Unmarshal(reply.Value, out)
+}
+
+// jsonHandler is the grpc handler of the method of a JSON service, call gets the request made by newIn
+func jsonHandler(method string, newIn func() interface{}, call func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(wrapperspb.BytesValue)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		in := newIn()
+		if err := json.Unmarshal(req.Value, in); err != nil {
+			return nil, err
+		}
+		var out interface{}
+		var err error
+		if interceptor == nil {
+			out, err = call(srv, ctx, in)
+		} else {
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: method}
+			out, err = interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv, ctx, req)
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		return encodeJSONMessage(out)
+	}
+}
@@ -0,0 +1,82 @@
+package privateapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+// The SYNCPROGRESS service has no protobuf definition in the interfaces repository, its messages are JSON (see invokeJSON).
+
+type SyncProgressRequest struct{}
+
+type SyncProgressReply struct {
+	Stages []stages.Progress `json:"stages"`
+}
+
+type SyncProgressServer interface {
+	SyncProgress(context.Context, *SyncProgressRequest) (*SyncProgressReply, error)
+}
+
+type SyncProgressClient interface {
+	SyncProgress(ctx context.Context, in *SyncProgressRequest, opts ...grpc.CallOption) (*SyncProgressReply, error)
+}
+
+func RegisterSyncProgressServer(s grpc.ServiceRegistrar, srv SyncProgressServer) {
+	s.RegisterService(&syncProgressServiceDesc, srv)
+}
+
+var syncProgressServiceDesc = grpc.ServiceDesc{
+	ServiceName: "remote.SYNCPROGRESS",
+	HandlerType: (*SyncProgressServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SyncProgress",
+			Handler: jsonHandler(syncProgressMethod, func() interface{} { return new(SyncProgressRequest) },
+				func(srv interface{}, ctx context.Context, in interface{}) (interface{}, error) {
+					return srv.(SyncProgressServer).SyncProgress(ctx, in.(*SyncProgressRequest))
+				}),
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+const syncProgressMethod = "/remote.SYNCPROGRESS/SyncProgress"
+
+type syncProgressClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSyncProgressClient(cc grpc.ClientConnInterface) SyncProgressClient {
+	return &syncProgressClient{cc}
+}
+
+func (c *syncProgressClient) SyncProgress(ctx context.Context, in *SyncProgressRequest, opts ...grpc.CallOption) (*SyncProgressReply, error) {
+	out := new(SyncProgressReply)
+	if err := invokeJSON(ctx, c.cc, syncProgressMethod, in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NewSyncProgressClientDirect calls the server in the same process, without grpc.
+func NewSyncProgressClientDirect(server SyncProgressServer) SyncProgressClient {
+	return &syncProgressClientDirect{server: server}
+}
+
+type syncProgressClientDirect struct {
+	server SyncProgressServer
+}
+
+func (c *syncProgressClientDirect) SyncProgress(ctx context.Context, in *SyncProgressRequest, opts ...grpc.CallOption) (*SyncProgressReply, error) {
+	return c.server.SyncProgress(ctx, in)
+}
+
+func (s *EthBackendServer) SyncProgress(_ context.Context, _ *SyncProgressRequest) (*SyncProgressReply, error) {
+	if s.eth == nil {
+		return &SyncProgressReply{}, nil
+	}
+	return &SyncProgressReply{Stages: s.eth.SyncProgress()}, nil
+}
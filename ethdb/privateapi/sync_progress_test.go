@@ -0,0 +1,59 @@
+package privateapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+type testSyncProgressServer struct {
+	progress []stages.Progress
+}
+
+func (s *testSyncProgressServer) SyncProgress(context.Context, *SyncProgressRequest) (*SyncProgressReply, error) {
+	return &SyncProgressReply{Stages: s.progress}, nil
+}
+
+func TestSyncProgressOverGrpc(t *testing.T) {
+	errTime := time.Unix(1_700_000_000, 0).UTC()
+	badBlock := common.HexToHash("0x01")
+	progress := []stages.Progress{
+		{Stage: stages.Headers, CurrentBlock: 100, TargetBlock: 200, Running: true, BlocksPerSecond: 10, EtaSeconds: 10},
+		{Stage: stages.Execution, CurrentBlock: 50, TargetBlock: 100, LastError: "[7/16 Execution] bad block", LastErrorTime: &errTime,
+			Unwinds: []stages.Unwind{{From: 60, To: 50, BadBlock: &badBlock, Time: errTime}}},
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	RegisterSyncProgressServer(server, &testSyncProgressServer{progress: progress})
+	go server.Serve(lis) //nolint:errcheck
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	reply, err := NewSyncProgressClient(conn).SyncProgress(context.Background(), &SyncProgressRequest{})
+	require.NoError(t, err)
+	require.Equal(t, progress, reply.Stages)
+	// The JSON messages go over the proto codec, there is no JSON codec registered in the process
+	require.Nil(t, encoding.GetCodec("json"))
+
+	reply, err = NewSyncProgressClientDirect(&testSyncProgressServer{progress: progress}).SyncProgress(context.Background(), &SyncProgressRequest{})
+	require.NoError(t, err)
+	require.Equal(t, progress, reply.Stages)
+
+	// Without a node behind it the backend server reports nothing rather than failing
+	reply, err = (&EthBackendServer{}).SyncProgress(context.Background(), &SyncProgressRequest{})
+	require.NoError(t, err)
+	require.Empty(t, reply.Stages)
+}
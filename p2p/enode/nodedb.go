@@ -46,6 +46,9 @@ const (
 	dbVersionKey   = "version" // Version of the database to flush if changes
 	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
 	dbLocalPrefix  = "local:"
+	dbScorePrefix  = "score:"   // Peer scores of the sentry, they aren't expired with the discovery data
+	dbStaticPrefix = "static:"  // Static nodes added at runtime, "static:<ID>" holds the URL of the node
+	dbTrustPrefix  = "trusted:" // Trusted nodes added at runtime, "trusted:<ID>" holds the URL of the node
 	dbDiscoverRoot = "v4"
 	dbDiscv5Root   = "v5"
 
@@ -225,6 +228,16 @@ func localItemKey(id ID, field string) []byte {
 	return key
 }
 
+// scoreKey returns the database key of the score of a peer at the IP, "score:<ID><IP>".
+func scoreKey(id ID, ip net.IP) []byte {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		ip16 = zeroIP
+	}
+	key := append([]byte(dbScorePrefix), id[:]...)
+	return append(key, ip16...)
+}
+
 // fetchInt64 retrieves an integer associated with a particular key.
 func (db *DB) fetchInt64(key []byte) int64 {
 	var val int64
@@ -602,6 +615,117 @@ func (db *DB) QuerySeeds(n int, maxAge time.Duration) []*Node {
 	return nodes
 }
 
+// PeerScore retrieves the encoded score of a peer at the IP, nil if there is none.
+func (db *DB) PeerScore(id ID, ip net.IP) []byte {
+	var score []byte
+	if err := db.kv.View(context.Background(), func(tx kv.Tx) error {
+		v, errGet := tx.GetOne(kv.Inodes, scoreKey(id, ip))
+		if errGet != nil {
+			return errGet
+		}
+		score = common.CopyBytes(v)
+		return nil
+	}); err != nil {
+		return nil
+	}
+	return score
+}
+
+// UpdatePeerScore stores the encoded score of a peer at the IP.
+func (db *DB) UpdatePeerScore(id ID, ip net.IP, score []byte) error {
+	return db.kv.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(kv.Inodes, scoreKey(id, ip), common.CopyBytes(score))
+	})
+}
+
+// DeletePeerScore removes the score of a peer at the IP.
+func (db *DB) DeletePeerScore(id ID, ip net.IP) error {
+	return db.kv.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Delete(kv.Inodes, scoreKey(id, ip))
+	})
+}
+
+// ForEachPeerScore calls f with the encoded scores of all the peers, ordered by ID.
+func (db *DB) ForEachPeerScore(f func(id ID, ip net.IP, score []byte) error) error {
+	return db.kv.View(context.Background(), func(tx kv.Tx) error {
+		p := []byte(dbScorePrefix)
+		return tx.ForPrefix(kv.Inodes, p, func(k, v []byte) error {
+			if len(k) != len(p)+len(ID{})+len(zeroIP) {
+				return nil
+			}
+			var id ID
+			copy(id[:], k[len(p):])
+			ip := net.IP(common.CopyBytes(k[len(p)+len(id):]))
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			return f(id, ip, common.CopyBytes(v))
+		})
+	})
+}
+
+// StaticNodes returns the nodes added with AddStaticNode.
+func (db *DB) StaticNodes() ([]*Node, error) {
+	return db.listedNodes(dbStaticPrefix)
+}
+
+// AddStaticNode stores a node the server keeps connected to.
+func (db *DB) AddStaticNode(n *Node) error {
+	return db.listNode(dbStaticPrefix, n)
+}
+
+// RemoveStaticNode removes a node added with AddStaticNode.
+func (db *DB) RemoveStaticNode(id ID) error {
+	return db.unlistNode(dbStaticPrefix, id)
+}
+
+// TrustedNodes returns the nodes added with AddTrustedNode.
+func (db *DB) TrustedNodes() ([]*Node, error) {
+	return db.listedNodes(dbTrustPrefix)
+}
+
+// AddTrustedNode stores a node allowed to connect above the peer limit.
+func (db *DB) AddTrustedNode(n *Node) error {
+	return db.listNode(dbTrustPrefix, n)
+}
+
+// RemoveTrustedNode removes a node added with AddTrustedNode.
+func (db *DB) RemoveTrustedNode(id ID) error {
+	return db.unlistNode(dbTrustPrefix, id)
+}
+
+// listNode stores the text form of the node under the prefix. The record of a node given by its
+// enode:// URL isn't signed, so it can't be stored the way the discovered nodes are.
+func (db *DB) listNode(prefix string, n *Node) error {
+	id := n.ID()
+	return db.kv.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(kv.Inodes, append([]byte(prefix), id[:]...), []byte(n.String()))
+	})
+}
+
+func (db *DB) unlistNode(prefix string, id ID) error {
+	return db.kv.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Delete(kv.Inodes, append([]byte(prefix), id[:]...))
+	})
+}
+
+func (db *DB) listedNodes(prefix string) ([]*Node, error) {
+	var nodes []*Node
+	if err := db.kv.View(context.Background(), func(tx kv.Tx) error {
+		return tx.ForPrefix(kv.Inodes, []byte(prefix), func(k, v []byte) error {
+			n, err := Parse(ValidSchemes, string(v))
+			if err != nil {
+				return fmt.Errorf("p2p/enode: can't parse node %x in DB: %w", k[len(prefix):], err)
+			}
+			nodes = append(nodes, n)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
 // close flushes and closes the database files.
 func (db *DB) Close() {
 	select {
@@ -481,3 +481,95 @@ func TestDBExpireV5(t *testing.T) {
 	db.UpdateFindFailsV5(ID{}, ip, 4)
 	db.expireNodes()
 }
+
+// This test checks that the peer scores are kept apart from the discovery data.
+func TestDBPeerScores(t *testing.T) {
+	db, err := OpenDB("")
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	ip4, ip6 := net.IP{127, 0, 0, 1}, net.ParseIP("::1")
+	if err := db.UpdateLastPongReceived(keytestID, ip4, time.Now().Add(-2*dbNodeExpiration)); err != nil {
+		t.Fatalf("failed to update pong: %v", err)
+	}
+	if err := db.UpdatePeerScore(keytestID, ip4, []byte{1}); err != nil {
+		t.Fatalf("failed to update score: %v", err)
+	}
+	if err := db.UpdatePeerScore(keytestID, ip6, []byte{2}); err != nil {
+		t.Fatalf("failed to update score: %v", err)
+	}
+	db.expireNodes()
+	if score := db.PeerScore(keytestID, ip4); !bytes.Equal(score, []byte{1}) {
+		t.Errorf("score mismatch: have %x, want 01", score)
+	}
+
+	type item struct {
+		ip    string
+		score []byte
+	}
+	var items []item
+	if err := db.ForEachPeerScore(func(id ID, ip net.IP, score []byte) error {
+		if id != keytestID {
+			t.Errorf("id mismatch: have %v, want %v", id, keytestID)
+		}
+		items = append(items, item{ip.String(), score})
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to iterate scores: %v", err)
+	}
+	if want := []item{{"::1", []byte{2}}, {"127.0.0.1", []byte{1}}}; !reflect.DeepEqual(items, want) {
+		t.Errorf("scores mismatch: have %v, want %v", items, want)
+	}
+
+	if err := db.DeletePeerScore(keytestID, ip4); err != nil {
+		t.Fatalf("failed to delete score: %v", err)
+	}
+	if score := db.PeerScore(keytestID, ip4); score != nil {
+		t.Errorf("deleted score is present: %x", score)
+	}
+}
+
+func TestDBListedNodes(t *testing.T) {
+	db, err := OpenDB("")
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	static := MustParse("enode://1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439@10.3.58.6:30303?discport=30301")
+	trusted := MustParse("enode://d860a01f9722d78051619d1e2351aba3f43f943f6f00718d1b9baa4101932a1f5011f16bb2b1bb35db20d6fe28fa0bf09636d26a87d31de9ec6203eeedb1f666@18.138.108.67:30303")
+	if err := db.AddStaticNode(static); err != nil {
+		t.Fatalf("failed to add static node: %v", err)
+	}
+	if err := db.AddTrustedNode(trusted); err != nil {
+		t.Fatalf("failed to add trusted node: %v", err)
+	}
+	db.expireNodes()
+
+	nodes, err := db.StaticNodes()
+	if err != nil {
+		t.Fatalf("failed to read static nodes: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].ID() != static.ID() || nodes[0].URLv4() != static.URLv4() {
+		t.Errorf("static nodes mismatch: have %v, want %v", nodes, static)
+	}
+	nodes, err = db.TrustedNodes()
+	if err != nil {
+		t.Fatalf("failed to read trusted nodes: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].ID() != trusted.ID() {
+		t.Errorf("trusted nodes mismatch: have %v, want %v", nodes, trusted)
+	}
+
+	if err := db.RemoveStaticNode(static.ID()); err != nil {
+		t.Fatalf("failed to remove static node: %v", err)
+	}
+	if nodes, _ = db.StaticNodes(); len(nodes) != 0 {
+		t.Errorf("removed static node is present: %v", nodes)
+	}
+	if nodes, _ = db.TrustedNodes(); len(nodes) != 1 {
+		t.Errorf("trusted node is gone with the static one: %v", nodes)
+	}
+}
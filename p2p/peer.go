@@ -484,7 +484,8 @@ type PeerInfo struct {
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Protocols map[string]interface{} `json:"protocols"`       // Sub-protocol specific metadata fields
+	Score     *PeerScore             `json:"score,omitempty"` // Reputation kept by the sentry
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -0,0 +1,41 @@
+package p2p
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// BannedForever is the BannedUntil of the permanently banned peers
+const BannedForever = math.MaxUint64
+
+// PeerScore is the reputation of a remote node at an IP, the sentry keeps it in the node database across connections
+type PeerScore struct {
+	Enode       string `json:"-"`                     // URL to dial the node again
+	LastSeen    uint64 `json:"lastSeen"`              // Unix time of the last connection
+	Latency     uint64 `json:"latency"`               // Moving average of the response times, in milliseconds
+	Headers     uint64 `json:"headers"`               // Block headers delivered
+	Bodies      uint64 `json:"bodies"`                // Block bodies delivered
+	Invalid     uint64 `json:"invalid"`               // Penalties for invalid data
+	Timeouts    uint64 `json:"timeouts"`              // Requests left unanswered
+	BannedUntil uint64 `json:"bannedUntil,omitempty"` // Unix time the ban ends, BannedForever if it doesn't
+}
+
+// Value rates the peer: a point for each thousand headers or hundred bodies delivered, minus fifty points for each
+// invalid delivery, five for each timeout and one for each hundred milliseconds of latency
+func (s *PeerScore) Value() float64 {
+	return float64(s.Headers)/1000 + float64(s.Bodies)/100 - 50*float64(s.Invalid) - 5*float64(s.Timeouts) - float64(s.Latency)/100
+}
+
+// Banned tells whether the peer is banned at the time
+func (s *PeerScore) Banned(now time.Time) bool {
+	return s.BannedUntil > uint64(now.Unix())
+}
+
+func (s *PeerScore) MarshalJSON() ([]byte, error) {
+	type score PeerScore
+	return json.Marshal(struct {
+		*score
+		Value float64 `json:"value"`
+	}{(*score)(s), s.Value()})
+}
@@ -0,0 +1,55 @@
+package p2p
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeerScoreJSON(t *testing.T) {
+	score := &PeerScore{Enode: "enode://1", LastSeen: 1, Latency: 200, Headers: 3000, Bodies: 100, Invalid: 1, Timeouts: 2}
+	if v := score.Value(); v != -58 {
+		t.Fatalf("wrong value: %v", v)
+	}
+
+	js, err := json.Marshal(&PeerInfo{Score: score})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"score":{"lastSeen":1,"latency":200,"headers":3000,"bodies":100,"invalid":1,"timeouts":2,"value":-58}`
+	if !strings.Contains(string(js), want) {
+		t.Fatalf("wrong json: %s", js)
+	}
+
+	// the sentries send the scores to rpcdaemon as JSON, without the enode
+	js, err = json.Marshal(score)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded PeerScore
+	if err = json.Unmarshal(js, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if want := (PeerScore{LastSeen: 1, Latency: 200, Headers: 3000, Bodies: 100, Invalid: 1, Timeouts: 2}); decoded != want {
+		t.Fatalf("wrong decoded score: got %+v, want %+v", decoded, want)
+	}
+}
+
+func TestPeerScoreBanned(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		until  uint64
+		banned bool
+	}{
+		{0, false},
+		{1000, false},
+		{1001, true},
+		{BannedForever, true},
+	}
+	for _, test := range tests {
+		if banned := (&PeerScore{BannedUntil: test.until}).Banned(now); banned != test.banned {
+			t.Errorf("banned until %d: got %v, want %v", test.until, banned, test.banned)
+		}
+	}
+}
@@ -122,6 +122,10 @@ type Config struct {
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// If DialFilter is set, only the discovered nodes it accepts are dialed,
+	// static nodes are dialed regardless.
+	DialFilter func(n *enode.Node) bool `toml:"-"`
+
 	// NodeDatabase is the path to the database containing the previously seen
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
@@ -146,6 +150,9 @@ type Config struct {
 	// eth/66, eth/67, etc
 	ProtocolVersion []uint
 
+	// ServeSnap enables serving snap/1 alongside eth, only the sentries running in-process can do it
+	ServeSnap bool
+
 	SentryAddr []string
 
 	// If set to a non-nil value, the given NAT port mapper
@@ -307,6 +314,11 @@ func (c *conn) set(f connFlag, val bool) {
 	}
 }
 
+// NodeDB returns the node database, nil until the server is started.
+func (srv *Server) NodeDB() *enode.DB {
+	return srv.nodedb
+}
+
 // LocalNode returns the local node record.
 func (srv *Server) LocalNode() *enode.LocalNode {
 	return srv.localnode
@@ -671,7 +683,11 @@ func (srv *Server) setupDialScheduler() {
 	if len(srv.Protocols) > 0 {
 		subProtocolVersion = srv.Protocols[0].Version
 	}
-	srv.dialsched = newDialScheduler(config, srv.discmix, srv.SetupConn, subProtocolVersion)
+	var it enode.Iterator = srv.discmix
+	if srv.DialFilter != nil {
+		it = enode.Filter(it, srv.DialFilter)
+	}
+	srv.dialsched = newDialScheduler(config, it, srv.SetupConn, subProtocolVersion)
 	for _, n := range srv.StaticNodes {
 		srv.dialsched.addStatic(n)
 	}
@@ -78,6 +78,8 @@ type Client struct {
 	isHTTP          bool
 	services        *serviceRegistry
 	methodAllowList AllowList
+	connCtx         context.Context // parent of the handler context of the connection
+	resultCache     ResultCache
 
 	idCounter uint32
 
@@ -111,8 +113,9 @@ type clientConn struct {
 }
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
-	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
+	ctx := context.WithValue(c.connCtx, clientContextKey{}, c)
 	handler := newHandler(ctx, conn, c.idgen, c.services, c.methodAllowList, 50, false /* traceRequests */)
+	handler.resultCache = c.resultCache
 	return &clientConn{conn, handler}
 }
 
@@ -196,14 +199,16 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(context.Background(), conn, randomIDGenerator(), new(serviceRegistry), nil)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(connCtx context.Context, conn ServerCodec, idgen func() ID, services *serviceRegistry, resultCache ResultCache) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
+		connCtx:     connCtx,
+		resultCache: resultCache,
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
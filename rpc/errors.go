@@ -74,6 +74,10 @@ func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
 
+// NewInvalidParamsError creates an error which is reported to the client with the invalid params code, it is meant
+// for handlers which validate their arguments beyond what the JSON decoding does.
+func NewInvalidParamsError(message string) Error { return &invalidParamsError{message} }
+
 type CustomError struct {
 	Code    int
 	Message string
@@ -66,6 +66,7 @@ type handler struct {
 
 	allowList     AllowList // a list of explicitly allowed methods, if empty -- everything is allowed
 	forbiddenList ForbiddenList
+	resultCache   ResultCache // optional
 
 	subLock             sync.Mutex
 	serverSubs          map[ID]*Subscription
@@ -424,6 +425,13 @@ func (h *handler) isMethodAllowedByGranularControl(method string) bool {
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter.Stream) *jsonrpcMessage {
+	if !msg.isUnsubscribe() {
+		release, err := acquireRateLimit(cp.ctx, msg.Method)
+		if err != nil {
+			return msg.errorResponse(err)
+		}
+		defer release()
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg, stream)
 	}
@@ -490,12 +498,26 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage, stream *jso
 
 // runMethod runs the Go callback for an RPC method.
 func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value, stream *jsoniter.Stream) *jsonrpcMessage {
+	cacheable := h.resultCache != nil && h.resultCache.Cacheable(msg.Method)
+	var cached json.RawMessage
+	var isCached bool
+	if cacheable {
+		cached, isCached = h.resultCache.Get(ctx, msg.Method, msg.Params)
+	}
+
 	if !callb.streamable {
+		if isCached {
+			return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: cached}
+		}
 		result, err := callb.call(ctx, msg.Method, args, stream)
 		if err != nil {
 			return msg.errorResponse(err)
 		}
-		return msg.response(result)
+		answer := msg.response(result)
+		if cacheable && answer.Error == nil {
+			h.resultCache.Put(ctx, msg.Method, msg.Params, answer.Result)
+		}
+		return answer
 	}
 
 	stream.WriteObjectStart()
@@ -508,7 +530,26 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 		stream.WriteMore()
 	}
 	stream.WriteObjectField("result")
-	_, err := callb.call(ctx, msg.Method, args, stream)
+	var err error
+	var maxSize uint64
+	if cacheable && !isCached {
+		maxSize, cacheable = h.resultCache.Admit(ctx, msg.Method, msg.Params)
+	}
+	switch {
+	case isCached:
+		stream.Write(cached)
+	case cacheable:
+		// the result is sent as it is produced, a copy of it is collected for the cache
+		collector := &resultCollector{out: stream, maxSize: maxSize}
+		resultStream := jsoniter.NewStream(jsoniter.ConfigDefault, collector, 4096)
+		_, err = callb.call(ctx, msg.Method, args, resultStream)
+		_ = resultStream.Flush()
+		if err == nil && resultStream.Error == nil && !collector.overflow {
+			h.resultCache.Put(ctx, msg.Method, msg.Params, collector.buf.Bytes())
+		}
+	default:
+		_, err = callb.call(ctx, msg.Method, args, stream)
+	}
 	if err != nil {
 		stream.WriteNil()
 		stream.WriteMore()
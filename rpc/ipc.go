@@ -17,6 +17,7 @@
 package rpc
 
 import (
+	"context"
 	"net"
 
 	"github.com/ledgerwatch/erigon/p2p/netutil"
@@ -34,6 +35,10 @@ func (s *Server) ServeListener(l net.Listener) error {
 			return err
 		}
 		log.Trace("Accepted RPC connection", "conn", conn.RemoteAddr())
-		go s.ServeCodec(NewCodec(conn), 0)
+		connCtx := context.Background()
+		if _, isTCP := conn.RemoteAddr().(*net.TCPAddr); isTCP && s.rateLimiter != nil {
+			connCtx = s.rateLimiter.withClient(connCtx, remoteClient(conn.RemoteAddr().String()))
+		}
+		go s.serveCodec(connCtx, NewCodec(conn))
 	}
 }
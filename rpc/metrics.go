@@ -25,6 +25,7 @@ import (
 var (
 	rpcRequestGauge    = metrics.GetOrCreateCounter("rpc_total")
 	failedReqeustGauge = metrics.GetOrCreateCounter("rpc_failure")
+	rateLimitedCounter = metrics.GetOrCreateCounter("rpc_rate_limited")
 )
 
 func newRPCServingTimerMS(method string, valid bool) *metrics.Summary {
@@ -0,0 +1,323 @@
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultMethodClass is the class of the methods which don't belong to any configured class
+	DefaultMethodClass = "default"
+
+	defaultAPIKeyHeader = "X-API-Key"
+	defaultJwtClaim     = "sub"
+
+	// idle anonymous clients are forgotten after this time, so scanning nodes don't grow the client set forever
+	rateLimitClientIdle = 10 * time.Minute
+)
+
+// DefaultMethodClasses splits the methods into cheap reads (DefaultMethodClass), traces and log queries,
+// it is used when RateLimits.Classes is empty.
+var DefaultMethodClasses = map[string][]string{
+	"trace": {"trace_*", "debug_trace*", "ots_traceTransaction"},
+	"logs":  {"eth_getLogs", "eth_getFilterLogs", "erigon_getLogs*", "erigon_getLatestLogs"},
+}
+
+// RateLimits configures the request budgets of the clients of a server. Clients are identified by an
+// API key sent in a header or by a claim of a JWT signed with JwtSecret, requests without either are
+// accounted per remote IP address with the Default limits.
+type RateLimits struct {
+	APIKeyHeader string                  `json:"apiKeyHeader"` // header carrying the API key, X-API-Key by default
+	JwtClaim     string                  `json:"jwtClaim"`     // claim of the bearer token naming the client, sub by default
+	JwtSecret    string                  `json:"jwtSecret"`    // hex encoded HS256 secret, tokens are ignored without it
+	Classes      map[string][]string     `json:"classes"`      // method class -> methods, a trailing * matches any suffix
+	Default      ClientLimits            `json:"default"`      // limits of anonymous clients and of token clients not listed in Clients
+	Clients      map[string]ClientLimits `json:"clients"`      // limits by client name
+}
+
+// ClientLimits are the limits applied to a single client.
+type ClientLimits struct {
+	APIKeys       []string               `json:"apiKeys"`       // keys identifying the client
+	MaxConcurrent int                    `json:"maxConcurrent"` // requests served at the same time, 0 is unlimited
+	Limits        map[string]TokenBucket `json:"limits"`        // method class -> bucket, classes without a bucket are unlimited
+}
+
+// TokenBucket allows Burst requests at once, refilled by Rate requests per second.
+type TokenBucket struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// limitExceededError is returned when a client is out of its budget. -32005 is the "limit exceeded"
+// code of EIP-1474, the data tells the client when it can retry.
+type limitExceededError struct {
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+func (e *limitExceededError) ErrorData() interface{} {
+	if e.retryAfter <= 0 {
+		return nil
+	}
+	return map[string]interface{}{"retryAfter": e.retryAfter.Seconds()}
+}
+
+type methodClassPrefix struct {
+	prefix, class string
+}
+
+type rateLimitedClient struct {
+	limits   *ClientLimits
+	buckets  map[string]*rate.Limiter
+	inflight int
+	lastSeen time.Time
+}
+
+// rateLimiter keeps the state of all the clients of a server.
+type rateLimiter struct {
+	apiKeyHeader string
+	jwtClaim     string
+	jwtSecret    []byte
+
+	exactClasses  map[string]string
+	prefixClasses []methodClassPrefix // longest prefix first
+	apiKeys       map[string]string   // key -> client name
+	defaults      ClientLimits
+	named         map[string]*ClientLimits
+
+	lock      sync.Mutex
+	clients   map[string]*rateLimitedClient
+	lastPrune time.Time
+}
+
+func newRateLimiter(cfg *RateLimits) (*rateLimiter, error) {
+	l := &rateLimiter{
+		apiKeyHeader: cfg.APIKeyHeader,
+		jwtClaim:     cfg.JwtClaim,
+		exactClasses: map[string]string{},
+		apiKeys:      map[string]string{},
+		defaults:     cfg.Default,
+		named:        map[string]*ClientLimits{},
+		clients:      map[string]*rateLimitedClient{},
+		lastPrune:    time.Now(),
+	}
+	if l.apiKeyHeader == "" {
+		l.apiKeyHeader = defaultAPIKeyHeader
+	}
+	if l.jwtClaim == "" {
+		l.jwtClaim = defaultJwtClaim
+	}
+	if cfg.JwtSecret != "" {
+		secret, err := hex.DecodeString(strings.TrimPrefix(cfg.JwtSecret, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwtSecret: %w", err)
+		}
+		l.jwtSecret = secret
+	}
+	classes := cfg.Classes
+	if len(classes) == 0 {
+		classes = DefaultMethodClasses
+	}
+	for class, methods := range classes {
+		for _, method := range methods {
+			if strings.HasSuffix(method, "*") {
+				l.prefixClasses = append(l.prefixClasses, methodClassPrefix{prefix: strings.TrimSuffix(method, "*"), class: class})
+				continue
+			}
+			if other, ok := l.exactClasses[method]; ok && other != class {
+				return nil, fmt.Errorf("method %s is in classes %s and %s", method, other, class)
+			}
+			l.exactClasses[method] = class
+		}
+	}
+	sort.Slice(l.prefixClasses, func(i, j int) bool {
+		if len(l.prefixClasses[i].prefix) != len(l.prefixClasses[j].prefix) {
+			return len(l.prefixClasses[i].prefix) > len(l.prefixClasses[j].prefix)
+		}
+		return l.prefixClasses[i].prefix < l.prefixClasses[j].prefix
+	})
+	if err := validateClientLimits("default", &l.defaults); err != nil {
+		return nil, err
+	}
+	for name, limits := range cfg.Clients {
+		limits := limits
+		if err := validateClientLimits(name, &limits); err != nil {
+			return nil, err
+		}
+		for _, key := range limits.APIKeys {
+			if other, ok := l.apiKeys[key]; ok {
+				return nil, fmt.Errorf("api key of client %s is also used by client %s", name, other)
+			}
+			l.apiKeys[key] = name
+		}
+		l.named[name] = &limits
+	}
+	return l, nil
+}
+
+func validateClientLimits(name string, limits *ClientLimits) error {
+	if limits.MaxConcurrent < 0 {
+		return fmt.Errorf("client %s: negative maxConcurrent", name)
+	}
+	for class, bucket := range limits.Limits {
+		if bucket.Rate < 0 || bucket.Burst <= 0 {
+			return fmt.Errorf("client %s: class %s needs a non-negative rate and a positive burst", name, class)
+		}
+	}
+	return nil
+}
+
+// methodClass returns the class the rate limits of the method are looked up by
+func (l *rateLimiter) methodClass(method string) string {
+	if class, ok := l.exactClasses[method]; ok {
+		return class
+	}
+	for _, p := range l.prefixClasses {
+		if strings.HasPrefix(method, p.prefix) {
+			return p.class
+		}
+	}
+	return DefaultMethodClass
+}
+
+// identify names the client of the request: the owner of a known API key, the claim of a valid
+// bearer token, or its remote IP address.
+func (l *rateLimiter) identify(r *http.Request) string {
+	if key := r.Header.Get(l.apiKeyHeader); key != "" {
+		if name, ok := l.apiKeys[key]; ok {
+			return "client:" + name
+		}
+	}
+	if l.jwtSecret != nil {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			if name := l.jwtClient(strings.TrimPrefix(auth, "Bearer ")); name != "" {
+				return "client:" + name
+			}
+		}
+	}
+	return remoteClient(r.RemoteAddr)
+}
+
+func (l *rateLimiter) jwtClient(tokenStr string) string {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return l.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{"HS256"}))
+	if err != nil || !token.Valid {
+		return ""
+	}
+	name, _ := claims[l.jwtClaim].(string)
+	return name
+}
+
+func remoteClient(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return "ip:" + host
+	}
+	return "ip:" + remoteAddr
+}
+
+// acquire takes a token of the method class and a concurrency slot of the client, release must be
+// called when the request is served.
+func (l *rateLimiter) acquire(client, method string) (release func(), err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	l.pruneIdle(now)
+	c, ok := l.clients[client]
+	if !ok {
+		limits := &l.defaults
+		if named, ok := l.named[strings.TrimPrefix(client, "client:")]; ok && strings.HasPrefix(client, "client:") {
+			limits = named
+		}
+		c = &rateLimitedClient{limits: limits, buckets: map[string]*rate.Limiter{}}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+
+	if c.limits.MaxConcurrent > 0 && c.inflight >= c.limits.MaxConcurrent {
+		rateLimitedCounter.Inc()
+		return nil, &limitExceededError{message: fmt.Sprintf("too many concurrent requests, the limit is %d", c.limits.MaxConcurrent)}
+	}
+	class := l.methodClass(method)
+	if bucket, ok := c.limits.Limits[class]; ok {
+		limiter, ok := c.buckets[class]
+		if !ok {
+			limiter = rate.NewLimiter(rate.Limit(bucket.Rate), bucket.Burst)
+			c.buckets[class] = limiter
+		}
+		if reservation := limiter.ReserveN(now, 1); !reservation.OK() || reservation.DelayFrom(now) > 0 {
+			retryAfter := time.Duration(0)
+			if reservation.OK() {
+				retryAfter = reservation.DelayFrom(now)
+				reservation.CancelAt(now)
+			}
+			rateLimitedCounter.Inc()
+			return nil, &limitExceededError{message: fmt.Sprintf("request rate limit of %s methods exceeded", class), retryAfter: retryAfter}
+		}
+	}
+
+	c.inflight++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.lock.Lock()
+			defer l.lock.Unlock()
+			c.inflight--
+			c.lastSeen = time.Now()
+		})
+	}, nil
+}
+
+// pruneIdle forgets the clients which haven't sent anything for a while, their buckets are full again anyway.
+func (l *rateLimiter) pruneIdle(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+	for client, c := range l.clients {
+		if c.inflight == 0 && now.Sub(c.lastSeen) > rateLimitClientIdle {
+			delete(l.clients, client)
+		}
+	}
+}
+
+type rateLimitClientKey struct{}
+
+type rateLimitClient struct {
+	limiter *rateLimiter
+	client  string
+}
+
+// withClient marks the requests served with ctx as requests of the client.
+func (l *rateLimiter) withClient(ctx context.Context, client string) context.Context {
+	if l == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimitClientKey{}, rateLimitClient{limiter: l, client: client})
+}
+
+// acquireRateLimit checks the budget of the client of ctx, requests of unidentified connections
+// (in-process and IPC) aren't limited.
+func acquireRateLimit(ctx context.Context, method string) (release func(), err error) {
+	c, ok := ctx.Value(rateLimitClientKey{}).(rateLimitClient)
+	if !ok {
+		return func() {}, nil
+	}
+	return c.limiter.acquire(c.client, method)
+}
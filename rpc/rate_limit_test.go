@@ -0,0 +1,135 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitsMethodClass(t *testing.T) {
+	l, err := newRateLimiter(&RateLimits{})
+	require.NoError(t, err)
+	require.Equal(t, "trace", l.methodClass("trace_filter"))
+	require.Equal(t, "trace", l.methodClass("debug_traceTransaction"))
+	require.Equal(t, "logs", l.methodClass("eth_getLogs"))
+	require.Equal(t, "logs", l.methodClass("erigon_getLogsByHash"))
+	require.Equal(t, DefaultMethodClass, l.methodClass("eth_blockNumber"))
+
+	// the longest prefix wins
+	l, err = newRateLimiter(&RateLimits{Classes: map[string][]string{"a": {"eth_*"}, "b": {"eth_get*"}, "c": {"eth_getLogs"}}})
+	require.NoError(t, err)
+	require.Equal(t, "a", l.methodClass("eth_call"))
+	require.Equal(t, "b", l.methodClass("eth_getBalance"))
+	require.Equal(t, "c", l.methodClass("eth_getLogs"))
+
+	_, err = newRateLimiter(&RateLimits{Classes: map[string][]string{"a": {"eth_call"}, "b": {"eth_call"}}})
+	require.Error(t, err)
+	_, err = newRateLimiter(&RateLimits{Default: ClientLimits{Limits: map[string]TokenBucket{"trace": {Rate: 1}}}})
+	require.Error(t, err)
+}
+
+func TestRateLimitsConcurrency(t *testing.T) {
+	l, err := newRateLimiter(&RateLimits{Default: ClientLimits{MaxConcurrent: 2}})
+	require.NoError(t, err)
+	release1, err := l.acquire("ip:1.1.1.1", "trace_filter")
+	require.NoError(t, err)
+	release2, err := l.acquire("ip:1.1.1.1", "eth_call")
+	require.NoError(t, err)
+	_, err = l.acquire("ip:1.1.1.1", "eth_call")
+	require.Error(t, err)
+	require.Equal(t, -32005, err.(Error).ErrorCode())
+
+	// other clients have their own slots
+	release3, err := l.acquire("ip:2.2.2.2", "eth_call")
+	require.NoError(t, err)
+	release3()
+
+	release1()
+	release1() // releasing twice doesn't free a slot of another request
+	release4, err := l.acquire("ip:1.1.1.1", "eth_call")
+	require.NoError(t, err)
+	_, err = l.acquire("ip:1.1.1.1", "eth_call")
+	require.Error(t, err)
+	release2()
+	release4()
+}
+
+func TestRateLimitsHTTP(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	server := newTestServer()
+	defer server.Stop()
+	require.NoError(t, server.SetRateLimits(&RateLimits{
+		JwtSecret: hex.EncodeToString(secret),
+		Classes:   map[string][]string{"echo": {"test_echo"}},
+		Default: ClientLimits{Limits: map[string]TokenBucket{
+			"echo":             {Rate: 0, Burst: 2},
+			DefaultMethodClass: {Rate: 0, Burst: 1},
+		}},
+		Clients: map[string]ClientLimits{
+			"alice": {APIKeys: []string{"alice-key"}},
+			"bob":   {Limits: map[string]TokenBucket{"echo": {Rate: 0.001, Burst: 1}}},
+		},
+	}))
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	call := func(t *testing.T, header, value string) error {
+		t.Helper()
+		client, err := DialHTTP(httpsrv.URL)
+		require.NoError(t, err)
+		defer client.Close()
+		if header != "" {
+			client.SetHeader(header, value)
+		}
+		var result echoResult
+		return client.Call(&result, "test_echo", "hello", 1, &echoArgs{"world"})
+	}
+	requireLimited := func(t *testing.T, err error) {
+		t.Helper()
+		require.Error(t, err)
+		rpcErr, ok := err.(Error)
+		require.True(t, ok, "%T", err)
+		require.Equal(t, -32005, rpcErr.ErrorCode())
+	}
+	token := func(t *testing.T, secret []byte, subject string) string {
+		t.Helper()
+		tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: subject}).SignedString(secret)
+		require.NoError(t, err)
+		return "Bearer " + tok
+	}
+
+	// anonymous clients use the default budget of their IP address
+	require.NoError(t, call(t, "", ""))
+	require.NoError(t, call(t, "", ""))
+	requireLimited(t, call(t, "", ""))
+	// unknown keys and forged tokens don't get a budget of their own
+	requireLimited(t, call(t, "X-API-Key", "mallory-key"))
+	requireLimited(t, call(t, "Authorization", token(t, []byte("wrong secret"), "bob")))
+	// other method classes have their own buckets
+	client, err := DialHTTP(httpsrv.URL)
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.Call(nil, "test_noArgsRets"))
+	requireLimited(t, client.Call(nil, "test_noArgsRets"))
+
+	// alice isn't limited at all
+	for i := 0; i < 10; i++ {
+		require.NoError(t, call(t, "X-API-Key", "alice-key"))
+	}
+
+	// bob is identified by the token and told when to retry
+	require.NoError(t, call(t, "Authorization", token(t, secret, "bob")))
+	err = call(t, "Authorization", token(t, secret, "bob"))
+	requireLimited(t, err)
+	retryAfter := err.(DataError).ErrorData().(map[string]interface{})["retryAfter"].(float64)
+	require.InDelta(t, (1000 * time.Second).Seconds(), retryAfter, 1)
+
+	// clients with a valid token but no entry of their own get the default limits
+	require.NoError(t, call(t, "Authorization", token(t, secret, "carol")))
+	require.NoError(t, call(t, "Authorization", token(t, secret, "carol")))
+	requireLimited(t, call(t, "Authorization", token(t, secret, "carol")))
+}
@@ -0,0 +1,48 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// ResultCache keeps the results of calls which never change, e.g. the blocks and traces of finalized blocks.
+// The server asks the cache before calling a Cacheable method and offers it the results of successful calls,
+// it is up to the cache to decide whether the result can be stored.
+type ResultCache interface {
+	// Cacheable tells if the results of the method are ever cached, other methods skip the cache completely
+	Cacheable(method string) bool
+	// Get returns the result of the call, if it is cached
+	Get(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, bool)
+	// Admit tells before the call if its result will be kept and up to which size, streamed results are only
+	// collected for the cache when they are admitted
+	Admit(ctx context.Context, method string, params json.RawMessage) (maxSize uint64, ok bool)
+	// Put offers the result of a successful call to the cache
+	Put(ctx context.Context, method string, params json.RawMessage, result json.RawMessage)
+}
+
+// resultCollector passes a streamed result through to the response and keeps a copy of it for the cache,
+// until it grows over the size the cache keeps
+type resultCollector struct {
+	out      io.Writer
+	buf      bytes.Buffer
+	maxSize  uint64
+	overflow bool
+}
+
+func (c *resultCollector) Write(p []byte) (int, error) {
+	if _, err := c.out.Write(p); err != nil {
+		return 0, err
+	}
+	if c.overflow {
+		return len(p), nil
+	}
+	if uint64(c.buf.Len()+len(p)) > c.maxSize {
+		c.overflow = true
+		c.buf = bytes.Buffer{}
+		return len(p), nil
+	}
+	c.buf.Write(p)
+	return len(p), nil
+}
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+type streamService struct{}
+
+func (s *streamService) Echo(ctx context.Context, str string, stream *jsoniter.Stream) error {
+	stream.WriteString(str)
+	return nil
+}
+
+type mapResultCache struct {
+	lock    sync.Mutex
+	results map[string]string
+	puts    int
+	refused map[string]bool // calls which aren't admitted, as if their block wasn't final
+	maxSize uint64
+}
+
+func (c *mapResultCache) Cacheable(method string) bool {
+	return method == "test_echo" || method == "stream_echo"
+}
+
+func (c *mapResultCache) Get(ctx context.Context, method string, params json.RawMessage) (json.RawMessage, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	result, ok := c.results[method+string(params)]
+	return json.RawMessage(result), ok
+}
+
+func (c *mapResultCache) Admit(ctx context.Context, method string, params json.RawMessage) (uint64, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.maxSize, !c.refused[method+string(params)]
+}
+
+func (c *mapResultCache) Put(ctx context.Context, method string, params json.RawMessage, result json.RawMessage) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.results[method+string(params)] = string(result)
+	c.puts++
+}
+
+func TestResultCache(t *testing.T) {
+	cache := &mapResultCache{results: map[string]string{}, refused: map[string]bool{}, maxSize: 1 << 10}
+	server := newTestServer()
+	defer server.Stop()
+	require.NoError(t, server.RegisterName("stream", new(streamService)))
+	server.SetResultCache(cache)
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+	client, err := DialHTTP(httpsrv.URL)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// results are offered to the cache, the cached ones are served without calling the method
+	var echo echoResult
+	require.NoError(t, client.Call(&echo, "test_echo", "x", 1, &echoArgs{"y"}))
+	require.Equal(t, echoResult{"x", 1, &echoArgs{"y"}}, echo)
+	require.Equal(t, `{"String":"x","Int":1,"Args":{"S":"y"}}`, cache.results[`test_echo["x",1,{"S":"y"}]`])
+	cache.results[`test_echo["x",1,{"S":"y"}]`] = `{"String":"cached"}`
+	require.NoError(t, client.Call(&echo, "test_echo", "x", 1, &echoArgs{"y"}))
+	require.Equal(t, "cached", echo.String)
+
+	// streamed results too
+	var streamed string
+	require.NoError(t, client.Call(&streamed, "stream_echo", "x"))
+	require.Equal(t, "x", streamed)
+	require.Equal(t, `"x"`, cache.results[`stream_echo["x"]`])
+	cache.results[`stream_echo["x"]`] = `"cached"`
+	require.NoError(t, client.Call(&streamed, "stream_echo", "x"))
+	require.Equal(t, "cached", streamed)
+
+	// streamed results which aren't admitted or are too big for the cache are sent, but not collected
+	cache.refused[`stream_echo["y"]`] = true
+	require.NoError(t, client.Call(&streamed, "stream_echo", "y"))
+	require.Equal(t, "y", streamed)
+	big := strings.Repeat("z", 2<<10)
+	require.NoError(t, client.Call(&streamed, "stream_echo", big))
+	require.Equal(t, big, streamed)
+	require.Len(t, cache.results, 2)
+
+	// errors and other methods are not cached
+	require.Error(t, client.Call(nil, "test_returnError"))
+	require.NoError(t, client.Call(nil, "test_noArgsRets"))
+	require.Equal(t, 2, cache.puts)
+}
@@ -37,3 +37,6 @@ var DefaultHTTPTimeouts = HTTPTimeouts{
 }
 
 const DefaultEvmCallTimeout = 5 * time.Minute
+
+// DefaultMaxGetProofRewindBlockCount - how many blocks back eth_getProof can rewind the state trie to build proofs
+const DefaultMaxGetProofRewindBlockCount = 100_000
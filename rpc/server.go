@@ -45,6 +45,8 @@ const (
 type Server struct {
 	services        serviceRegistry
 	methodAllowList AllowList
+	rateLimiter     *rateLimiter
+	resultCache     ResultCache
 	idgen           func() ID
 	run             int32
 	codecs          mapset.Set
@@ -69,6 +71,26 @@ func (s *Server) SetAllowList(allowList AllowList) {
 	s.methodAllowList = allowList
 }
 
+// SetRateLimits sets the per-client request budgets of the HTTP, WebSocket and TCP clients of this
+// server, nil removes the limits
+func (s *Server) SetRateLimits(limits *RateLimits) error {
+	if limits == nil {
+		s.rateLimiter = nil
+		return nil
+	}
+	rateLimiter, err := newRateLimiter(limits)
+	if err != nil {
+		return err
+	}
+	s.rateLimiter = rateLimiter
+	return nil
+}
+
+// SetResultCache sets the cache of the results of calls which never change, nil disables caching
+func (s *Server) SetResultCache(cache ResultCache) {
+	s.resultCache = cache
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -83,6 +105,11 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(context.Background(), codec)
+}
+
+// serveCodec is ServeCodec with a context of the connection, which carries the client of the rate limits.
+func (s *Server) serveCodec(connCtx context.Context, codec ServerCodec) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -94,7 +121,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(connCtx, codec, s.idgen, &s.services, s.resultCache)
 	<-codec.closed()
 	c.Close()
 }
@@ -110,6 +137,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stre
 
 	h := newHandler(ctx, codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.traceRequests)
 	h.allowSubscribe = false
+	h.resultCache = s.resultCache
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
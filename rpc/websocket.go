@@ -64,7 +64,11 @@ func (s *Server) WebsocketHandler(allowedOrigins []string, jwtSecret []byte, com
 			return
 		}
 		codec := newWebsocketCodec(conn)
-		s.ServeCodec(codec, 0)
+		connCtx := context.Background()
+		if s.rateLimiter != nil {
+			connCtx = s.rateLimiter.withClient(connCtx, s.rateLimiter.identify(r))
+		}
+		s.serveCodec(connCtx, codec)
 	})
 }
 
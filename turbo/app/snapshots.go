@@ -17,8 +17,10 @@ import (
 	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/common/dir"
 	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/downloader/downloadergrpc"
 	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
 	"github.com/ledgerwatch/erigon-lib/etl"
+	proto_downloader "github.com/ledgerwatch/erigon-lib/gointerfaces/downloader"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcfg"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
@@ -60,6 +62,17 @@ var snapshotCommand = cli.Command{
 				&SnapshotRebuildFlag,
 			}, debug.Flags, logging.Flags),
 		},
+		{
+			Name:   "verify",
+			Action: doVerifyCommand,
+			Usage:  "Check segments against their torrent files, preverified hashes and block ranges, and indices against segments",
+			Before: func(ctx *cli.Context) error { return debug.Setup(ctx) },
+			Flags: joinFlags([]cli.Flag{
+				&utils.DataDirFlag,
+				&SnapshotHealFlag,
+				&utils.DownloaderAddrFlag,
+			}, debug.Flags, logging.Flags),
+		},
 		{
 			Name:   "retire",
 			Action: doRetireCommand,
@@ -125,6 +138,10 @@ var (
 		Name:  "rebuild",
 		Usage: "Force rebuild",
 	}
+	SnapshotHealFlag = cli.BoolFlag{
+		Name:  "heal",
+		Usage: "Rebuild bad indices, remove bad segments and request them from the downloader (--downloader.api.addr) or on the next start of erigon",
+	}
 )
 
 func preloadFileAsync(name string) {
@@ -235,6 +252,56 @@ func doIndicesCommand(cliCtx *cli.Context) error {
 	return nil
 }
 
+func doVerifyCommand(cliCtx *cli.Context) error {
+	ctx := cliCtx.Context
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	heal := cliCtx.Bool(SnapshotHealFlag.Name)
+
+	chainDB := mdbx.NewMDBX(log.New()).Path(dirs.Chaindata).Readonly().MustOpen()
+	defer chainDB.Close()
+	chainConfig := fromdb.ChainConfig(chainDB)
+	chainID, _ := uint256.FromBig(chainConfig.ChainID)
+
+	preverified, err := snapshotsync.PreverifiedSegments(ctx, chainDB, chainConfig.ChainName)
+	if err != nil {
+		return err
+	}
+	workers := estimate.IndexSnapshot.Workers()
+	report, err := snapshotsync.VerifySnapshots(ctx, dirs.Snap, preverified, workers)
+	if err != nil {
+		return err
+	}
+	report.LogStat("snapshots")
+	if report.OK() {
+		return nil
+	}
+	if !heal {
+		return fmt.Errorf("snapshots are broken, run with --%s to fix them", SnapshotHealFlag.Name)
+	}
+
+	if err := snapshotsync.HealSnapshots(ctx, "snapshots", report, dirs, *chainID, semaphore.NewWeighted(int64(workers))); err != nil {
+		return err
+	}
+	downloaderAddr := cliCtx.String(utils.DownloaderAddrFlag.Name)
+	if downloaderAddr == "" {
+		log.Info("[snapshots] Bad segments are removed, erigon downloads them again on the next start")
+		return nil
+	}
+	downloader, err := downloadergrpc.NewClient(ctx, downloaderAddr)
+	if err != nil {
+		return err
+	}
+	if err := snapshotsync.RedownloadSnapshots(ctx, "snapshots", report, downloader); err != nil {
+		return err
+	}
+	// running downloader may still consider pieces of the removed files complete
+	if _, err := downloader.Verify(ctx, &proto_downloader.VerifyRequest{}); err != nil {
+		return err
+	}
+	return nil
+}
+
 func doUncompress(cliCtx *cli.Context) error {
 	ctx := cliCtx.Context
 
@@ -46,6 +46,7 @@ var DefaultFlags = []cli.Flag{
 	&TLSCACertFlag,
 	&StateStreamDisableFlag,
 	&SyncLoopThrottleFlag,
+	&SyncParallelStagesFlag,
 	&BadBlockFlag,
 
 	&utils.HTTPEnabledFlag,
@@ -67,8 +68,17 @@ var DefaultFlags = []cli.Flag{
 	&utils.RpcStreamingDisableFlag,
 	&utils.DBReadConcurrencyFlag,
 	&utils.RpcAccessListFlag,
+	&utils.RpcRateLimitsFlag,
+	&utils.RpcResultCacheFlag,
+	&utils.RpcResultCacheSizeFlag,
+	&utils.RpcResultCacheFinalityFlag,
 	&utils.RpcTraceCompatFlag,
 	&utils.RpcGasCapFlag,
+	&utils.RpcMaxGetProofRewindBlockCountFlag,
+	&utils.RpcSignerFlag,
+	&utils.RpcSignerKeystoreFlag,
+	&utils.RpcSignerPasswordFlag,
+	&utils.RpcSignerURLFlag,
 	&utils.TxpoolApiAddrFlag,
 	&utils.TraceMaxtracesFlag,
 	&HTTPReadTimeoutFlag,
@@ -81,6 +91,7 @@ var DefaultFlags = []cli.Flag{
 
 	&utils.SnapKeepBlocksFlag,
 	&utils.SnapStopFlag,
+	&utils.SnapVerifyFlag,
 	&utils.DbPageSizeFlag,
 	&utils.TorrentPortFlag,
 	&utils.TorrentMaxPeersFlag,
@@ -92,6 +103,7 @@ var DefaultFlags = []cli.Flag{
 	&utils.ListenPortFlag,
 	&utils.P2pProtocolVersionFlag,
 	&utils.P2pProtocolAllowedPorts,
+	&utils.P2pServeSnapFlag,
 	&utils.NATFlag,
 	&utils.NoDiscoverFlag,
 	&utils.DiscoveryV5Flag,
@@ -139,6 +151,7 @@ var DefaultFlags = []cli.Flag{
 	&HealthCheckFlag,
 	&utils.HeimdallURLFlag,
 	&utils.WithoutHeimdallFlag,
+	&utils.HeimdallRecordFlag,
 	&utils.EthStatsURLFlag,
 	&utils.OverrideShanghaiTime,
 
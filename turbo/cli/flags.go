@@ -145,6 +145,11 @@ var (
 		Value: "",
 	}
 
+	SyncParallelStagesFlag = cli.BoolFlag{
+		Name:  "sync.parallel.stages",
+		Usage: "Run stages which don't depend on each other (history, log, call traces and tx lookup indices) concurrently, each in its own transaction",
+	}
+
 	BadBlockFlag = cli.StringFlag{
 		Name:  "bad.block",
 		Usage: "Marks block with given hex string as bad and forces initial reorg before normal staged sync",
@@ -240,6 +245,7 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		}
 		cfg.Sync.LoopThrottle = syncLoopThrottle
 	}
+	cfg.Sync.ParallelStages = ctx.Bool(SyncParallelStagesFlag.Name)
 
 	if ctx.String(BadBlockFlag.Name) != "" {
 		bytes, err := hexutil.Decode(ctx.String(BadBlockFlag.Name))
@@ -372,14 +378,26 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 		},
 		EvmCallTimeout: ctx.Duration(EvmCallTimeoutFlag.Name),
 
-		WebsocketEnabled:     ctx.IsSet(utils.WSEnabledFlag.Name),
-		RpcBatchConcurrency:  ctx.Uint(utils.RpcBatchConcurrencyFlag.Name),
-		RpcStreamingDisable:  ctx.Bool(utils.RpcStreamingDisableFlag.Name),
-		DBReadConcurrency:    ctx.Int(utils.DBReadConcurrencyFlag.Name),
-		RpcAllowListFilePath: ctx.String(utils.RpcAccessListFlag.Name),
-		Gascap:               ctx.Uint64(utils.RpcGasCapFlag.Name),
-		MaxTraces:            ctx.Uint64(utils.TraceMaxtracesFlag.Name),
-		TraceCompatibility:   ctx.Bool(utils.RpcTraceCompatFlag.Name),
+		MaxGetProofRewindBlockCount: ctx.Int(utils.RpcMaxGetProofRewindBlockCountFlag.Name),
+
+		Signer:             ctx.String(utils.RpcSignerFlag.Name),
+		SignerKeystoreDir:  ctx.String(utils.RpcSignerKeystoreFlag.Name),
+		SignerPasswordFile: ctx.String(utils.RpcSignerPasswordFlag.Name),
+		SignerURL:          ctx.String(utils.RpcSignerURLFlag.Name),
+
+		InsecureUnlockAllowed: ctx.Bool(utils.InsecureUnlockAllowedFlag.Name),
+
+		WebsocketEnabled:       ctx.IsSet(utils.WSEnabledFlag.Name),
+		RpcBatchConcurrency:    ctx.Uint(utils.RpcBatchConcurrencyFlag.Name),
+		RpcStreamingDisable:    ctx.Bool(utils.RpcStreamingDisableFlag.Name),
+		DBReadConcurrency:      ctx.Int(utils.DBReadConcurrencyFlag.Name),
+		RpcAllowListFilePath:   ctx.String(utils.RpcAccessListFlag.Name),
+		RpcRateLimitsFilePath:  ctx.String(utils.RpcRateLimitsFlag.Name),
+		RpcResultCache:         ctx.String(utils.RpcResultCacheFlag.Name),
+		RpcResultCacheFinality: ctx.String(utils.RpcResultCacheFinalityFlag.Name),
+		Gascap:                 ctx.Uint64(utils.RpcGasCapFlag.Name),
+		MaxTraces:              ctx.Uint64(utils.TraceMaxtracesFlag.Name),
+		TraceCompatibility:     ctx.Bool(utils.RpcTraceCompatFlag.Name),
 
 		TxPoolApiAddr: ctx.String(utils.TxpoolApiAddrFlag.Name),
 
@@ -406,6 +424,10 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 		utils.Fatalf("Invalid state.cache value provided")
 	}
 
+	if err = c.RpcResultCacheSize.UnmarshalText([]byte(ctx.String(utils.RpcResultCacheSizeFlag.Name))); err != nil {
+		utils.Fatalf("Invalid %s value provided", utils.RpcResultCacheSizeFlag.Name)
+	}
+
 	/*
 		rootCmd.PersistentFlags().BoolVar(&cfg.GRPCServerEnabled, "grpc", false, "Enable GRPC server")
 		rootCmd.PersistentFlags().StringVar(&cfg.GRPCListenAddress, "grpc.addr", node.DefaultGRPCHost, "GRPC server listening interface")
@@ -8,7 +8,9 @@ import (
 	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/clique"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/p2p"
 )
 
@@ -35,5 +37,14 @@ type ApiBackend interface {
 	EngineGetBlobsBundleV1(ctx context.Context, payloadId uint64) (*types2.BlobsBundleV1, error)
 	NodeInfo(ctx context.Context, limit uint32) ([]p2p.NodeInfo, error)
 	Peers(ctx context.Context) ([]*p2p.PeerInfo, error)
+	SyncProgress(ctx context.Context) ([]stages.Progress, error)
+	CliquePropose(ctx context.Context, address common.Address, auth bool) error
+	CliqueDiscard(ctx context.Context, address common.Address) error
+	CliqueProposals(ctx context.Context) (map[common.Address]bool, error)
+	CliqueStatus(ctx context.Context) (*clique.Status, error)
+	AddPeer(ctx context.Context, url string) error
+	RemovePeer(ctx context.Context, url string) error
+	AddTrustedPeer(ctx context.Context, url string) error
+	RemoveTrustedPeer(ctx context.Context, url string) error
 	PendingBlock(ctx context.Context) (*types.Block, error)
 }
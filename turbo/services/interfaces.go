@@ -37,6 +37,17 @@ type TxnReader interface {
 	TxnLookup(ctx context.Context, tx kv.Getter, txnHash common.Hash) (uint64, bool, error)
 	TxnByIdxInBlock(ctx context.Context, tx kv.Getter, blockNum uint64, i int) (txn types.Transaction, err error)
 }
+
+// ReceiptsReader - receipts of executed blocks, from db or from receipts snapshots. Receipts have derived fields set.
+// Returns nil if receipts are not available: block is not executed or its receipts were pruned and not frozen.
+type ReceiptsReader interface {
+	Receipts(ctx context.Context, tx kv.Tx, block *types.Block, senders []common.Address) (types.Receipts, error)
+	// FrozenReceipts - receipts of the block are in snapshots
+	FrozenReceipts(blockHeight uint64) bool
+	// FrozenReceiptsRanges - [from, to) block ranges covered by receipts snapshots, ascending
+	FrozenReceiptsRanges() [][2]uint64
+}
+
 type HeaderAndCanonicalReader interface {
 	HeaderReader
 	CanonicalReader
@@ -54,4 +65,5 @@ type FullBlockReader interface {
 	HeaderReader
 	TxnReader
 	CanonicalReader
+	ReceiptsReader
 }
@@ -115,6 +115,12 @@ func (back *BlockReader) TxnByIdxInBlock(ctx context.Context, tx kv.Getter, bloc
 	return txn, nil
 }
 
+func (back *BlockReader) Receipts(ctx context.Context, tx kv.Tx, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	return rawdb.ReadReceipts(tx, block, senders), nil
+}
+func (back *BlockReader) FrozenReceipts(blockHeight uint64) bool { return false }
+func (back *BlockReader) FrozenReceiptsRanges() [][2]uint64      { return nil }
+
 type RemoteBlockReader struct {
 	client remote.ETHBACKENDClient
 }
@@ -249,6 +255,12 @@ func (back *RemoteBlockReader) BodyRlp(ctx context.Context, tx kv.Getter, hash c
 	return bodyRlp, nil
 }
 
+func (back *RemoteBlockReader) Receipts(ctx context.Context, tx kv.Tx, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	return rawdb.ReadReceipts(tx, block, senders), nil
+}
+func (back *RemoteBlockReader) FrozenReceipts(blockHeight uint64) bool { return false }
+func (back *RemoteBlockReader) FrozenReceiptsRanges() [][2]uint64      { return nil }
+
 // BlockReaderWithSnapshots can read blocks from db and snapshots
 type BlockReaderWithSnapshots struct {
 	sn *RoSnapshots
@@ -794,3 +806,82 @@ func (back *BlockReaderWithSnapshots) TxnLookup(ctx context.Context, tx kv.Gette
 	}
 	return blockNum, true, nil
 }
+
+func (back *BlockReaderWithSnapshots) Receipts(ctx context.Context, tx kv.Tx, block *types.Block, senders []common.Address) (types.Receipts, error) {
+	if receipts := rawdb.ReadReceipts(tx, block, senders); receipts != nil {
+		return receipts, nil
+	}
+
+	var receipts types.Receipts
+	blockHeight := block.NumberU64()
+	ok, err := back.sn.ViewReceipts(blockHeight, func(segment *ReceiptSegment) error {
+		var err error
+		receipts, _, err = back.receiptsFromSnapshot(blockHeight, segment, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok || receipts == nil {
+		return nil, nil
+	}
+	if len(senders) > 0 {
+		block.SendersToTxs(senders)
+	}
+	if err := receipts.DeriveFields(block.Hash(), blockHeight, block.Transactions(), senders); err != nil {
+		return nil, fmt.Errorf("receipts of block %d from snapshots: %w", blockHeight, err)
+	}
+	for _, r := range receipts {
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+	}
+	return receipts, nil
+}
+
+func (back *BlockReaderWithSnapshots) FrozenReceipts(blockHeight uint64) bool {
+	ok, _ := back.sn.ViewReceipts(blockHeight, func(*ReceiptSegment) error { return nil })
+	return ok
+}
+
+func (back *BlockReaderWithSnapshots) FrozenReceiptsRanges() [][2]uint64 {
+	if !back.sn.indicesReady.Load() {
+		return nil
+	}
+	ranges := back.sn.ReceiptsRanges()
+	res := make([][2]uint64, len(ranges))
+	for i, r := range ranges {
+		res[i] = [2]uint64{r.from, r.to}
+	}
+	return res
+}
+
+func (back *BlockReaderWithSnapshots) receiptsFromSnapshot(blockHeight uint64, sn *ReceiptSegment, buf []byte) (types.Receipts, []byte, error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			panic(fmt.Errorf("%+v, snapshot: %d-%d, trace: %s", rec, sn.ranges.from, sn.ranges.to, dbg.Stack()))
+		}
+	}() // avoid crash because Erigon's core does many things
+
+	if sn.idxReceiptsNumber == nil {
+		return nil, buf, nil
+	}
+	receiptsOffset := sn.idxReceiptsNumber.OrdinalLookup(blockHeight - sn.idxReceiptsNumber.BaseDataID())
+
+	gg := sn.seg.MakeGetter()
+	gg.Reset(receiptsOffset)
+	if !gg.HasNext() {
+		return nil, buf, nil
+	}
+	buf, _ = gg.Next(buf[:0])
+	if len(buf) == 0 {
+		return nil, buf, nil
+	}
+	var stored types.ReceiptsForStorage
+	if err := rlp.DecodeBytes(buf, &stored); err != nil {
+		return nil, buf, err
+	}
+	receipts := make(types.Receipts, len(stored))
+	for i, r := range stored {
+		receipts[i] = (*types.Receipt)(r)
+	}
+	return receipts, buf, nil
+}
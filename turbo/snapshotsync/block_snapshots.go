@@ -121,7 +121,7 @@ func (sn *HeaderSegment) reopenIdx(dir string) (err error) {
 		return nil
 	}
 	fileName := snaptype.IdxFileName(sn.ranges.from, sn.ranges.to, snaptype.Headers.String())
-	sn.idxHeaderHash, err = recsplit.OpenIndex(path.Join(dir, fileName))
+	sn.idxHeaderHash, err = openIdx(path.Join(dir, fileName))
 	if err != nil {
 		return fmt.Errorf("%w, fileName: %s", err, fileName)
 	}
@@ -182,7 +182,7 @@ func (sn *BodySegment) reopenIdx(dir string) (err error) {
 		return nil
 	}
 	fileName := snaptype.IdxFileName(sn.ranges.from, sn.ranges.to, snaptype.Bodies.String())
-	sn.idxBodyNumber, err = recsplit.OpenIndex(path.Join(dir, fileName))
+	sn.idxBodyNumber, err = openIdx(path.Join(dir, fileName))
 	if err != nil {
 		return fmt.Errorf("%w, fileName: %s", err, fileName)
 	}
@@ -249,7 +249,7 @@ func (sn *TxnSegment) reopenIdx(dir string) (err error) {
 		return nil
 	}
 	fileName := snaptype.IdxFileName(sn.ranges.from, sn.ranges.to, snaptype.Transactions.String())
-	sn.IdxTxnHash, err = recsplit.OpenIndex(path.Join(dir, fileName))
+	sn.IdxTxnHash, err = openIdx(path.Join(dir, fileName))
 	if err != nil {
 		return fmt.Errorf("%w, fileName: %s", err, fileName)
 	}
@@ -260,7 +260,7 @@ func (sn *TxnSegment) reopenIdx(dir string) (err error) {
 	}
 
 	fileName = snaptype.IdxFileName(sn.ranges.from, sn.ranges.to, snaptype.Transactions2Block.String())
-	sn.IdxTxnHash2BlockNum, err = recsplit.OpenIndex(path.Join(dir, fileName))
+	sn.IdxTxnHash2BlockNum, err = openIdx(path.Join(dir, fileName))
 	if err != nil {
 		return fmt.Errorf("%w, fileName: %s", err, fileName)
 	}
@@ -359,9 +359,10 @@ type RoSnapshots struct {
 	indicesReady  atomic.Bool
 	segmentsReady atomic.Bool
 
-	Headers *headerSegments
-	Bodies  *bodySegments
-	Txs     *txnSegments
+	Headers  *headerSegments
+	Bodies   *bodySegments
+	Txs      *txnSegments
+	Receipts *receiptSegments // optional, don't affect segmentsMax and idxMax
 
 	dir         string
 	segmentsMax atomic.Uint64 // all types of .seg files are available - up to this number
@@ -374,8 +375,9 @@ type RoSnapshots struct {
 //   - all snapshots of given blocks range must exist - to make this blocks range available
 //   - gaps are not allowed
 //   - segment have [from:to) semantic
+//   - receipts segments are optional and may have gaps
 func NewRoSnapshots(cfg ethconfig.Snapshot, snapDir string) *RoSnapshots {
-	return &RoSnapshots{dir: snapDir, cfg: cfg, Headers: &headerSegments{}, Bodies: &bodySegments{}, Txs: &txnSegments{}}
+	return &RoSnapshots{dir: snapDir, cfg: cfg, Headers: &headerSegments{}, Bodies: &bodySegments{}, Txs: &txnSegments{}, Receipts: &receiptSegments{}}
 }
 
 func (s *RoSnapshots) Cfg() ethconfig.Snapshot { return s.cfg }
@@ -409,6 +411,8 @@ func (s *RoSnapshots) DisableReadAhead() {
 	defer s.Bodies.lock.RUnlock()
 	s.Txs.lock.RLock()
 	defer s.Txs.lock.RUnlock()
+	s.Receipts.lock.RLock()
+	defer s.Receipts.lock.RUnlock()
 	for _, sn := range s.Headers.segments {
 		sn.seg.DisableReadAhead()
 	}
@@ -418,6 +422,9 @@ func (s *RoSnapshots) DisableReadAhead() {
 	for _, sn := range s.Txs.segments {
 		sn.Seg.DisableReadAhead()
 	}
+	for _, sn := range s.Receipts.segments {
+		sn.seg.DisableReadAhead()
+	}
 }
 func (s *RoSnapshots) EnableReadAhead() *RoSnapshots {
 	s.Headers.lock.RLock()
@@ -426,6 +433,8 @@ func (s *RoSnapshots) EnableReadAhead() *RoSnapshots {
 	defer s.Bodies.lock.RUnlock()
 	s.Txs.lock.RLock()
 	defer s.Txs.lock.RUnlock()
+	s.Receipts.lock.RLock()
+	defer s.Receipts.lock.RUnlock()
 	for _, sn := range s.Headers.segments {
 		sn.seg.EnableReadAhead()
 	}
@@ -435,6 +444,9 @@ func (s *RoSnapshots) EnableReadAhead() *RoSnapshots {
 	for _, sn := range s.Txs.segments {
 		sn.Seg.EnableReadAhead()
 	}
+	for _, sn := range s.Receipts.segments {
+		sn.seg.EnableReadAhead()
+	}
 	return s
 }
 func (s *RoSnapshots) EnableMadvWillNeed() *RoSnapshots {
@@ -444,6 +456,8 @@ func (s *RoSnapshots) EnableMadvWillNeed() *RoSnapshots {
 	defer s.Bodies.lock.RUnlock()
 	s.Txs.lock.RLock()
 	defer s.Txs.lock.RUnlock()
+	s.Receipts.lock.RLock()
+	defer s.Receipts.lock.RUnlock()
 	for _, sn := range s.Headers.segments {
 		sn.seg.EnableWillNeed()
 	}
@@ -453,6 +467,9 @@ func (s *RoSnapshots) EnableMadvWillNeed() *RoSnapshots {
 	for _, sn := range s.Txs.segments {
 		sn.Seg.EnableWillNeed()
 	}
+	for _, sn := range s.Receipts.segments {
+		sn.seg.EnableWillNeed()
+	}
 	return s
 }
 func (s *RoSnapshots) EnableMadvNormal() *RoSnapshots {
@@ -462,6 +479,8 @@ func (s *RoSnapshots) EnableMadvNormal() *RoSnapshots {
 	defer s.Bodies.lock.RUnlock()
 	s.Txs.lock.RLock()
 	defer s.Txs.lock.RUnlock()
+	s.Receipts.lock.RLock()
+	defer s.Receipts.lock.RUnlock()
 	for _, sn := range s.Headers.segments {
 		sn.seg.EnableMadvNormal()
 	}
@@ -471,6 +490,9 @@ func (s *RoSnapshots) EnableMadvNormal() *RoSnapshots {
 	for _, sn := range s.Txs.segments {
 		sn.Seg.EnableMadvNormal()
 	}
+	for _, sn := range s.Receipts.segments {
+		sn.seg.EnableMadvNormal()
+	}
 	return s
 }
 
@@ -517,6 +539,8 @@ func (s *RoSnapshots) Files() (list []string) {
 	defer s.Bodies.lock.RUnlock()
 	s.Txs.lock.RLock()
 	defer s.Txs.lock.RUnlock()
+	s.Receipts.lock.RLock()
+	defer s.Receipts.lock.RUnlock()
 	max := s.BlocksAvailable()
 	for _, seg := range s.Bodies.segments {
 		if seg.seg == nil {
@@ -548,6 +572,16 @@ func (s *RoSnapshots) Files() (list []string) {
 		_, fName := filepath.Split(seg.Seg.FilePath())
 		list = append(list, fName)
 	}
+	for _, seg := range s.Receipts.segments {
+		if seg.seg == nil {
+			continue
+		}
+		if seg.ranges.from > max {
+			continue
+		}
+		_, fName := filepath.Split(seg.seg.FilePath())
+		list = append(list, fName)
+	}
 	slices.Sort(list)
 	return list
 }
@@ -560,12 +594,20 @@ func (s *RoSnapshots) ReopenList(fileNames []string, optimistic bool) error {
 	defer s.Bodies.lock.Unlock()
 	s.Txs.lock.Lock()
 	defer s.Txs.lock.Unlock()
+	s.Receipts.lock.Lock()
+	defer s.Receipts.lock.Unlock()
 
 	s.closeWhatNotInList(fileNames)
 	var segmentsMax uint64
 	var segmentsMaxSet bool
 Loop:
 	for _, fName := range fileNames {
+		if from, to, ok := Receipts.ParseFileName(fName); ok {
+			if err := s.reopenReceipts(fName, from, to, optimistic); err != nil {
+				return err
+			}
+			continue
+		}
 		f, err := snaptype.ParseFileName(s.dir, fName)
 		if err != nil {
 			log.Warn("invalid segment name", "err", err, "name", fName)
@@ -714,11 +756,18 @@ func (s *RoSnapshots) ReopenFolder() error {
 	if err != nil {
 		return err
 	}
-	list := make([]string, 0, len(files))
+	receipts, err := ReceiptsSegments(s.dir)
+	if err != nil {
+		return err
+	}
+	list := make([]string, 0, len(files)+len(receipts))
 	for _, f := range files {
 		_, fName := filepath.Split(f.Path)
 		list = append(list, fName)
 	}
+	for _, r := range receipts {
+		list = append(list, Receipts.SegmentFileName(r.from, r.to))
+	}
 	return s.ReopenList(list, false)
 }
 func (s *RoSnapshots) ReopenWithDB(db kv.RoDB) error {
@@ -741,6 +790,8 @@ func (s *RoSnapshots) Close() {
 	defer s.Bodies.lock.Unlock()
 	s.Txs.lock.Lock()
 	defer s.Txs.lock.Unlock()
+	s.Receipts.lock.Lock()
+	defer s.Receipts.lock.Unlock()
 	s.closeWhatNotInList(nil)
 }
 
@@ -820,6 +871,8 @@ Loop3:
 			tailC[i] = nil
 		}
 	}
+
+	s.Receipts.closeWhatNotInList(l)
 }
 
 func (s *RoSnapshots) PrintDebug() {
@@ -829,6 +882,8 @@ func (s *RoSnapshots) PrintDebug() {
 	defer s.Bodies.lock.RUnlock()
 	s.Txs.lock.RLock()
 	defer s.Txs.lock.RUnlock()
+	s.Receipts.lock.RLock()
+	defer s.Receipts.lock.RUnlock()
 	fmt.Println("    == Snapshots, Header")
 	for _, sn := range s.Headers.segments {
 		fmt.Printf("%d,  %t\n", sn.ranges.from, sn.idxHeaderHash == nil)
@@ -841,6 +896,10 @@ func (s *RoSnapshots) PrintDebug() {
 	for _, sn := range s.Txs.segments {
 		fmt.Printf("%d,  %t, %t\n", sn.ranges.from, sn.IdxTxnHash == nil, sn.IdxTxnHash2BlockNum == nil)
 	}
+	fmt.Println("    == Snapshots, Receipts")
+	for _, sn := range s.Receipts.segments {
+		fmt.Printf("%d,  %t\n", sn.ranges.from, sn.idxReceiptsNumber == nil)
+	}
 }
 func (s *RoSnapshots) ViewHeaders(blockNum uint64, f func(sn *HeaderSegment) error) (found bool, err error) {
 	if !s.indicesReady.Load() || blockNum > s.BlocksAvailable() {
@@ -917,6 +976,26 @@ func BuildMissedIndices(logPrefix string, ctx context.Context, dirs datadir.Dirs
 			})
 		}
 	}
+	receipts, err := ReceiptsSegments(dir)
+	if err != nil {
+		return err
+	}
+	for _, r := range receipts {
+		if hasReceiptsIdxFile(dir, r) {
+			continue
+		}
+		if err := sem.Acquire(gCtx, 1); err != nil {
+			return err
+		}
+		r := r
+		g.Go(func() error {
+			defer sem.Release(1)
+			p := &background.Progress{}
+			ps.Add(p)
+			defer ps.Delete(p)
+			return ReceiptsIdx(gCtx, filepath.Join(dir, Receipts.SegmentFileName(r.from, r.to)), r.from, tmpDir, p, log.LvlInfo)
+		})
+	}
 	finish := make(chan struct{})
 	go func() {
 		g.Wait()
@@ -1175,32 +1254,51 @@ func retireBlocks(ctx context.Context, blockFrom, blockTo uint64, chainID uint25
 	if err := snapshots.ReopenFolder(); err != nil {
 		return fmt.Errorf("reopen: %w", err)
 	}
-	snapshots.LogStat()
-	if notifier != nil && !reflect.ValueOf(notifier).IsNil() { // notify about new snapshots of any size
-		notifier.OnNewSnapshot()
-	}
-	merger := NewMerger(tmpDir, workers, lvl, chainID, notifier)
-	rangesToMerge := merger.FindMergeRanges(snapshots.Ranges())
-	if len(rangesToMerge) == 0 {
-		return nil
-	}
-	err := merger.Merge(ctx, snapshots, rangesToMerge, snapshots.Dir(), true /* doIndex */)
+	receiptsRanges, err := retireReceipts(ctx, tmpDir, snapshots, db, workers, lvl)
 	if err != nil {
-		return err
+		return fmt.Errorf("retireReceipts: %w", err)
 	}
-	if err := snapshots.ReopenFolder(); err != nil {
-		return fmt.Errorf("reopen: %w", err)
+	if len(receiptsRanges) > 0 {
+		if err := snapshots.ReopenFolder(); err != nil {
+			return fmt.Errorf("reopen: %w", err)
+		}
+		if err := removeCoveredReceipts(snapshots.Dir(), receiptsRanges); err != nil {
+			return err
+		}
 	}
 	snapshots.LogStat()
 	if notifier != nil && !reflect.ValueOf(notifier).IsNil() { // notify about new snapshots of any size
 		notifier.OnNewSnapshot()
 	}
+	merger := NewMerger(tmpDir, workers, lvl, chainID, notifier)
+	rangesToMerge := merger.FindMergeRanges(snapshots.Ranges())
+	if len(rangesToMerge) > 0 {
+		err := merger.Merge(ctx, snapshots, rangesToMerge, snapshots.Dir(), true /* doIndex */)
+		if err != nil {
+			return err
+		}
+		if err := snapshots.ReopenFolder(); err != nil {
+			return fmt.Errorf("reopen: %w", err)
+		}
+		snapshots.LogStat()
+		if notifier != nil && !reflect.ValueOf(notifier).IsNil() { // notify about new snapshots of any size
+			notifier.OnNewSnapshot()
+		}
+	}
 
 	if downloader != nil && !reflect.ValueOf(downloader).IsNil() {
 		downloadRequest := make([]DownloadRequest, 0, len(rangesToMerge))
 		for i := range rangesToMerge {
 			downloadRequest = append(downloadRequest, NewDownloadRequest(&rangesToMerge[i], "", ""))
 		}
+		seedable, err := SeedableSegments(snapshots.Dir())
+		if err != nil {
+			return err
+		}
+		downloadRequest = append(downloadRequest, seedable...)
+		if len(downloadRequest) == 0 {
+			return nil
+		}
 
 		if err := RequestSnapshotsDownload(ctx, downloadRequest, downloader); err != nil {
 			return err
@@ -1258,6 +1356,16 @@ func dumpBlocksRange(ctx context.Context, blockFrom, blockTo uint64, tmpDir, sna
 	return nil
 }
 
+// openIdx doesn't trust the index file: recsplit reads its header without bounds checks and panics on truncated files
+func openIdx(fPath string) (idx *recsplit.Index, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			idx, err = nil, fmt.Errorf("broken index file: %s, %v", fPath, rec)
+		}
+	}()
+	return recsplit.OpenIndex(fPath)
+}
+
 func hasIdxFile(sn *snaptype.FileInfo) bool {
 	stat, err := os.Stat(sn.Path)
 	if err != nil {
@@ -1268,7 +1376,7 @@ func hasIdxFile(sn *snaptype.FileInfo) bool {
 	var result = true
 	switch sn.T {
 	case snaptype.Headers:
-		idx, err := recsplit.OpenIndex(path.Join(dir, fName))
+		idx, err := openIdx(path.Join(dir, fName))
 		if err != nil {
 			return false
 		}
@@ -1279,7 +1387,7 @@ func hasIdxFile(sn *snaptype.FileInfo) bool {
 		}
 		_ = idx.Close()
 	case snaptype.Bodies:
-		idx, err := recsplit.OpenIndex(path.Join(dir, fName))
+		idx, err := openIdx(path.Join(dir, fName))
 		if err != nil {
 			return false
 		}
@@ -1290,7 +1398,7 @@ func hasIdxFile(sn *snaptype.FileInfo) bool {
 		}
 		_ = idx.Close()
 	case snaptype.Transactions:
-		idx, err := recsplit.OpenIndex(path.Join(dir, fName))
+		idx, err := openIdx(path.Join(dir, fName))
 		if err != nil {
 			return false
 		}
@@ -1302,7 +1410,7 @@ func hasIdxFile(sn *snaptype.FileInfo) bool {
 		_ = idx.Close()
 
 		fName = snaptype.IdxFileName(sn.From, sn.To, snaptype.Transactions2Block.String())
-		idx, err = recsplit.OpenIndex(path.Join(dir, fName))
+		idx, err = openIdx(path.Join(dir, fName))
 		if err != nil {
 			return false
 		}
@@ -2045,6 +2153,12 @@ func (m *Merger) Merge(ctx context.Context, snapshots *RoSnapshots, mergeRanges
 		if err != nil {
 			return err
 		}
+		// receipts sub-segments which don't cover whole range are kept: next retirement will dump receipts of merged
+		// range and remove them
+		receiptsToMerge, receiptsComplete := receiptsFilesByRange(snapshots, r.from, r.to)
+		if !receiptsComplete {
+			receiptsToMerge = nil
+		}
 		for _, t := range snaptype.AllSnapshotTypes {
 			segName := snaptype.SegmentFileName(r.from, r.to, t)
 			f, _ := snaptype.ParseFileName(snapDir, segName)
@@ -2058,6 +2172,18 @@ func (m *Merger) Merge(ctx context.Context, snapshots *RoSnapshots, mergeRanges
 				}
 			}
 		}
+		if len(receiptsToMerge) > 0 {
+			segPath := filepath.Join(snapDir, Receipts.SegmentFileName(r.from, r.to))
+			if err := m.merge(ctx, receiptsToMerge, segPath, logEvery); err != nil {
+				return fmt.Errorf("mergeByAppendSegments: %w", err)
+			}
+			if doIndex {
+				p := &background.Progress{}
+				if err := ReceiptsIdx(ctx, segPath, r.from, m.tmpDir, p, m.lvl); err != nil {
+					return err
+				}
+			}
+		}
 		if err := snapshots.ReopenFolder(); err != nil {
 			return fmt.Errorf("ReopenSegments: %w", err)
 		}
@@ -2069,6 +2195,7 @@ func (m *Merger) Merge(ctx context.Context, snapshots *RoSnapshots, mergeRanges
 		for _, t := range snaptype.AllSnapshotTypes {
 			m.removeOldFiles(toMerge[t], snapDir)
 		}
+		m.removeOldFiles(receiptsToMerge, snapDir)
 	}
 	log.Log(m.lvl, "[snapshots] Merge done", "from", mergeRanges[0].from)
 	return nil
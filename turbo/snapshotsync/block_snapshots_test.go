@@ -2,16 +2,23 @@ package snapshotsync
 
 import (
 	"context"
+	"math/big"
+	"os"
 	"path/filepath"
 	"testing"
 	"testing/fstest"
 
 	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/background"
 	"github.com/ledgerwatch/erigon-lib/compress"
 	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon-lib/recsplit"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/math"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/params/networkname"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
@@ -105,6 +112,78 @@ func TestMergeSnapshots(t *testing.T) {
 	require.Equal(1, a)
 }
 
+func TestReceiptsSnapshots(t *testing.T) {
+	dir, require := t.TempDir(), require.New(t)
+	ctx := context.Background()
+	receiptOf := func(n uint64) *types.Receipt {
+		return &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 21_000 + n,
+			Logs:              []*types.Log{{Address: common.Address{1}, Topics: []common.Hash{{byte(n)}}, Data: []byte{byte(n >> 8)}}},
+		}
+	}
+	db := memdb.NewTestDB(t)
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		for n := uint64(0); n < 10_000; n++ {
+			if err := rawdb.WriteReceipts(tx, n, types.Receipts{receiptOf(n)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	for from := uint64(0); from < 10_000; from += 1_000 {
+		for _, snT := range snaptype.AllSnapshotTypes {
+			createTestSegmentFile(t, from, from+1_000, snT, dir)
+		}
+		segPath := filepath.Join(dir, Receipts.SegmentFileName(from, from+1_000))
+		require.NoError(DumpReceipts(ctx, db, segPath, dir, from, from+1_000, 1, log.LvlDebug))
+		require.NoError(ReceiptsIdx(ctx, segPath, from, dir, &background.Progress{}, log.LvlDebug))
+	}
+	err := DumpReceipts(ctx, db, filepath.Join(dir, Receipts.SegmentFileName(10_000, 11_000)), dir, 10_000, 11_000, 1, log.LvlDebug)
+	require.ErrorIs(err, ErrReceiptsMissing)
+
+	s := NewRoSnapshots(ethconfig.Snapshot{Enabled: true}, dir)
+	defer s.Close()
+	require.NoError(s.ReopenFolder())
+	require.Equal(10, len(s.ReceiptsRanges()))
+	require.Contains(s.Files(), Receipts.SegmentFileName(0, 1_000))
+
+	merger := NewMerger(dir, 1, log.LvlInfo, uint256.Int{}, nil)
+	ranges := merger.FindMergeRanges(s.Ranges())
+	require.Equal([]Range{{0, 10_000}}, ranges)
+	require.NoError(merger.Merge(ctx, s, ranges, s.Dir(), false))
+	_, err = os.Stat(filepath.Join(dir, Receipts.SegmentFileName(0, 1_000)))
+	require.ErrorIs(err, os.ErrNotExist)
+	require.NoError(ReceiptsIdx(ctx, filepath.Join(dir, Receipts.SegmentFileName(0, 10_000)), 0, dir, &background.Progress{}, log.LvlDebug))
+	require.NoError(s.ReopenFolder())
+	require.Equal([]Range{{0, 10_000}}, s.ReceiptsRanges())
+
+	reader := NewBlockReaderWithSnapshots(s)
+	require.True(reader.FrozenReceipts(9_999))
+	require.False(reader.FrozenReceipts(10_000))
+	require.Equal([][2]uint64{{0, 10_000}}, reader.FrozenReceiptsRanges())
+
+	_, tx := memdb.NewTestTx(t) // empty db: receipts must come from snapshots
+	txn := types.NewTransaction(0, common.Address{2}, uint256.NewInt(1), 21_000, uint256.NewInt(1), nil)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(5_500)}, []types.Transaction{txn}, nil, nil, nil)
+	receipts, err := reader.Receipts(ctx, tx, block, []common.Address{{3}})
+	require.NoError(err)
+	require.Equal(1, len(receipts))
+	expected := receiptOf(5_500)
+	require.Equal(expected.CumulativeGasUsed, receipts[0].CumulativeGasUsed)
+	require.Equal(expected.CumulativeGasUsed, receipts[0].GasUsed)
+	require.Equal(expected.Logs[0].Topics, receipts[0].Logs[0].Topics)
+	require.Equal(expected.Logs[0].Data, receipts[0].Logs[0].Data)
+	require.Equal(block.Hash(), receipts[0].Logs[0].BlockHash)
+	require.Equal(txn.Hash(), receipts[0].TxHash)
+
+	block = types.NewBlock(&types.Header{Number: big.NewInt(10_500)}, []types.Transaction{txn}, nil, nil, nil)
+	receipts, err = reader.Receipts(ctx, tx, block, []common.Address{{3}})
+	require.NoError(err)
+	require.Nil(receipts)
+}
+
 func TestCanRetire(t *testing.T) {
 	require := require.New(t)
 	cases := []struct {
@@ -239,6 +318,31 @@ func TestParseCompressedFileName(t *testing.T) {
 	require.Equal(2_000, int(f.To))
 }
 
+func TestSeedableSegments(t *testing.T) {
+	dir, require := t.TempDir(), require.New(t)
+	for _, fName := range []string{
+		Receipts.SegmentFileName(0, 500_000),
+		Receipts.SegmentFileName(500_000, 501_000),
+		snaptype.SegmentFileName(0, 500_000, snaptype.Bodies),
+	} {
+		require.NoError(os.WriteFile(filepath.Join(dir, fName), []byte{1}, 0644))
+	}
+	require.NoError(os.WriteFile(filepath.Join(dir, Receipts.SegmentFileName(1_000_000, 1_500_000)), nil, 0644))
+
+	// the seedable scan of the downloader skips the segments of this repo's types
+	require.False(snaptype.IsCorrectFileName(Receipts.SegmentFileName(0, 500_000)))
+	segType, from, to, ok := ParseSegmentFileName(Receipts.SegmentFileName(0, 500_000))
+	require.True(ok)
+	require.Equal(Receipts, segType)
+	require.Equal([2]uint64{0, 500_000}, [2]uint64{from, to})
+	_, _, _, ok = ParseSegmentFileName(snaptype.SegmentFileName(0, 500_000, snaptype.Bodies))
+	require.False(ok)
+
+	downloadRequest, err := SeedableSegments(dir)
+	require.NoError(err)
+	require.Equal([]DownloadRequest{NewDownloadRequest(nil, Receipts.SegmentFileName(0, 500_000), "")}, downloadRequest)
+}
+
 func BenchmarkName(b *testing.B) {
 	a := common.Address{}
 	c := a[:]
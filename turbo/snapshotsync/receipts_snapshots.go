@@ -0,0 +1,474 @@
+package snapshotsync
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/background"
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/recsplit"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// ErrReceiptsMissing - receipts of a block are not in db: block is not executed yet or its receipts were pruned
+var ErrReceiptsMissing = errors.New("receipts missing")
+
+type ReceiptSegment struct {
+	seg               *compress.Decompressor // value: rlp(types.ReceiptsForStorage)
+	idxReceiptsNumber *recsplit.Index        // block_num_u64     -> receipts_segment_offset
+	ranges            Range
+}
+
+func (sn *ReceiptSegment) closeSeg() {
+	if sn.seg != nil {
+		sn.seg.Close()
+		sn.seg = nil
+	}
+}
+func (sn *ReceiptSegment) closeIdx() {
+	if sn.idxReceiptsNumber != nil {
+		sn.idxReceiptsNumber.Close()
+		sn.idxReceiptsNumber = nil
+	}
+}
+func (sn *ReceiptSegment) close() {
+	sn.closeSeg()
+	sn.closeIdx()
+}
+
+func (sn *ReceiptSegment) reopenSeg(dir string) (err error) {
+	sn.closeSeg()
+	fileName := Receipts.SegmentFileName(sn.ranges.from, sn.ranges.to)
+	sn.seg, err = compress.NewDecompressor(path.Join(dir, fileName))
+	if err != nil {
+		return fmt.Errorf("%w, fileName: %s", err, fileName)
+	}
+	return nil
+}
+func (sn *ReceiptSegment) reopenIdxIfNeed(dir string, optimistic bool) (err error) {
+	if sn.idxReceiptsNumber != nil {
+		return nil
+	}
+	err = sn.reopenIdx(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			if optimistic {
+				log.Warn("[snapshots] open index", "err", err)
+			} else {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sn *ReceiptSegment) reopenIdx(dir string) (err error) {
+	sn.closeIdx()
+	if sn.seg == nil {
+		return nil
+	}
+	fileName := Receipts.IdxFileName(sn.ranges.from, sn.ranges.to)
+	sn.idxReceiptsNumber, err = openIdx(path.Join(dir, fileName))
+	if err != nil {
+		return fmt.Errorf("%w, fileName: %s", err, fileName)
+	}
+	if sn.idxReceiptsNumber.ModTime().Before(sn.seg.ModTime()) {
+		// Index has been created before the segment file, needs to be ignored (and rebuilt) as inconsistent
+		sn.idxReceiptsNumber.Close()
+		sn.idxReceiptsNumber = nil
+	}
+	return nil
+}
+
+type receiptSegments struct {
+	lock     sync.RWMutex
+	segments []*ReceiptSegment
+}
+
+func (s *receiptSegments) View(f func([]*ReceiptSegment) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return f(s.segments)
+}
+func (s *receiptSegments) ViewSegment(blockNum uint64, f func(*ReceiptSegment) error) (found bool, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, seg := range s.segments {
+		if !(blockNum >= seg.ranges.from && blockNum < seg.ranges.to) {
+			continue
+		}
+		return true, f(seg)
+	}
+	return false, nil
+}
+
+func (s *receiptSegments) closeWhatNotInList(l []string) {
+Loop:
+	for i, sn := range s.segments {
+		if sn.seg == nil {
+			continue Loop
+		}
+		_, name := filepath.Split(sn.seg.FilePath())
+		for _, fName := range l {
+			if fName == name {
+				continue Loop
+			}
+		}
+		sn.close()
+		s.segments[i] = nil
+	}
+	// unlike blocks, receipts segments may have gaps - so keep every segment which is still open
+	var i int
+	for _, sn := range s.segments {
+		if sn == nil || sn.seg == nil {
+			continue
+		}
+		s.segments[i] = sn
+		i++
+	}
+	for j := i; j < len(s.segments); j++ {
+		s.segments[j] = nil
+	}
+	s.segments = s.segments[:i]
+}
+
+// reopenReceipts - must be called under Receipts.lock
+func (s *RoSnapshots) reopenReceipts(fName string, from, to uint64, optimistic bool) error {
+	for _, sn := range s.Receipts.segments {
+		if sn.seg == nil {
+			continue
+		}
+		_, name := filepath.Split(sn.seg.FilePath())
+		if fName == name {
+			return sn.reopenIdxIfNeed(s.dir, optimistic)
+		}
+	}
+
+	sn := &ReceiptSegment{ranges: Range{from, to}}
+	if err := sn.reopenSeg(s.dir); err != nil {
+		// receipts are optional: missing file only makes receipts of this range unavailable
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if optimistic {
+			log.Warn("[snapshots] open segment", "err", err)
+			return nil
+		}
+		return err
+	}
+	s.Receipts.segments = append(s.Receipts.segments, sn)
+	sort.Slice(s.Receipts.segments, func(i, j int) bool {
+		return s.Receipts.segments[i].ranges.from < s.Receipts.segments[j].ranges.from
+	})
+	return sn.reopenIdxIfNeed(s.dir, optimistic)
+}
+
+// ReceiptsRanges - ranges of open receipts segments which have index
+func (s *RoSnapshots) ReceiptsRanges() (ranges []Range) {
+	_ = s.Receipts.View(func(segments []*ReceiptSegment) error {
+		for _, sn := range segments {
+			if sn.idxReceiptsNumber == nil {
+				continue
+			}
+			ranges = append(ranges, sn.ranges)
+		}
+		return nil
+	})
+	return ranges
+}
+
+// ViewReceipts - receipts are not bound by BlocksAvailable: they are produced only after the Execution stage,
+// so receipts segments may lag behind block segments or have gaps where receipts were pruned before retirement.
+func (s *RoSnapshots) ViewReceipts(blockNum uint64, f func(sn *ReceiptSegment) error) (found bool, err error) {
+	if !s.indicesReady.Load() {
+		return false, nil
+	}
+	_, err = s.Receipts.ViewSegment(blockNum, func(sn *ReceiptSegment) error {
+		if sn.idxReceiptsNumber == nil {
+			return nil
+		}
+		found = true
+		return f(sn)
+	})
+	return found, err
+}
+
+// ReceiptsSegments - lists receipts segments of dir. Gaps are allowed, of overlapping segments the largest one is kept.
+func ReceiptsSegments(dir string) (res []Range, err error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []Range
+	for _, f := range files {
+		if f.IsDir() || !f.Type().IsRegular() {
+			continue
+		}
+		from, to, ok := Receipts.ParseFileName(f.Name())
+		if !ok || from >= to {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() == 0 {
+			continue
+		}
+		list = append(list, Range{from, to})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].from != list[j].from {
+			return list[i].from < list[j].from
+		}
+		return list[i].to > list[j].to
+	})
+	var prevTo uint64
+	for _, r := range list {
+		if r.from < prevTo {
+			continue
+		}
+		res = append(res, r)
+		prevTo = r.to
+	}
+	return res, nil
+}
+
+func hasReceiptsIdxFile(dir string, r Range) bool {
+	segPath := filepath.Join(dir, Receipts.SegmentFileName(r.from, r.to))
+	stat, err := os.Stat(segPath)
+	if err != nil {
+		return false
+	}
+	fName := Receipts.IdxFileName(r.from, r.to)
+	idx, err := openIdx(filepath.Join(dir, fName))
+	if err != nil {
+		return false
+	}
+	defer idx.Close()
+	// If index was created before the segment file, it needs to be ignored (and rebuilt)
+	if idx.ModTime().Before(stat.ModTime()) {
+		log.Warn("Index file has timestamp before segment file, will be recreated", "segfile", segPath, "segtime", stat.ModTime(), "idxfile", fName, "idxtime", idx.ModTime())
+		return false
+	}
+	return true
+}
+
+// DumpReceipts - [from, to)
+// Format: rlp(types.ReceiptsForStorage) of each canonical block, derived fields are restored from the block on read
+func DumpReceipts(ctx context.Context, db kv.RoDB, segmentFilePath, tmpDir string, blockFrom, blockTo uint64, workers int, lvl log.Lvl) error {
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	f, err := compress.NewCompressor(ctx, "Snapshot Receipts", segmentFilePath, tmpDir, compress.MinPatternScore, workers, lvl)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	expectedBlockNum := blockFrom
+	from := common2.EncodeTs(blockFrom)
+	// kv.Receipts has a record for every executed block, even without transactions, and it's kept when ancient blocks
+	// are pruned from db
+	if err := kv.BigChunks(db, kv.Receipts, from, func(tx kv.Tx, k, v []byte) (bool, error) {
+		blockNum := binary.BigEndian.Uint64(k)
+		if blockNum >= blockTo {
+			return false, nil
+		}
+		if blockNum != expectedBlockNum {
+			return false, fmt.Errorf("%w: block %d", ErrReceiptsMissing, expectedBlockNum)
+		}
+		expectedBlockNum++
+		receipts := rawdb.ReadRawReceipts(tx, blockNum)
+		stored := make(types.ReceiptsForStorage, len(receipts))
+		for i, r := range receipts {
+			stored[i] = (*types.ReceiptForStorage)(r)
+		}
+		dataRLP, err := rlp.EncodeToBytes(stored)
+		if err != nil {
+			return false, err
+		}
+		if err := f.AddWord(dataRLP); err != nil {
+			return false, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-logEvery.C:
+			var m runtime.MemStats
+			if lvl >= log.LvlInfo {
+				dbg.ReadMemStats(&m)
+			}
+			log.Log(lvl, "[snapshots] Wrote into file", "block num", blockNum,
+				"alloc", common2.ByteCount(m.Alloc), "sys", common2.ByteCount(m.Sys),
+			)
+		default:
+		}
+		return true, nil
+	}); err != nil {
+		return err
+	}
+	if expectedBlockNum != blockTo {
+		return fmt.Errorf("%w: block %d", ErrReceiptsMissing, expectedBlockNum)
+	}
+	if err := f.Compress(); err != nil {
+		return fmt.Errorf("compress: %w", err)
+	}
+
+	return nil
+}
+
+func ReceiptsIdx(ctx context.Context, segmentFilePath string, firstBlockNumInSegment uint64, tmpDir string, p *background.Progress, lvl log.Lvl) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			_, fName := filepath.Split(segmentFilePath)
+			err = fmt.Errorf("ReceiptsIdx: at=%s, %v, %s", fName, rec, dbg.Stack())
+		}
+	}()
+
+	num := make([]byte, 8)
+
+	d, err := compress.NewDecompressor(segmentFilePath)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	_, fname := filepath.Split(segmentFilePath)
+	p.Name.Store(fname)
+	p.Total.Store(uint64(d.Count()))
+
+	if err := Idx(ctx, d, firstBlockNumInSegment, tmpDir, log.LvlDebug, func(idx *recsplit.RecSplit, i, offset uint64, word []byte) error {
+		p.Processed.Inc()
+		n := binary.PutUvarint(num, i)
+		if err := idx.AddKey(num[:n], offset); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("ReceiptsNumberIdx: %w", err)
+	}
+	return nil
+}
+
+// retireReceipts - dumps receipts of block segments which have no receipts segment yet. Receipts exist only for executed
+// blocks which were not pruned yet: segments beyond Execution stage progress are left for the next retirement,
+// segments of pruned receipts are skipped (their receipts segments can still be downloaded).
+func retireReceipts(ctx context.Context, tmpDir string, snapshots *RoSnapshots, db kv.RoDB, workers int, lvl log.Lvl) (dumped []Range, err error) {
+	var executed uint64
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		executed, err = stages.GetStageProgress(tx, stages.Execution)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	var existing []Range
+	_ = snapshots.Receipts.View(func(segments []*ReceiptSegment) error {
+		for _, sn := range segments {
+			existing = append(existing, sn.ranges)
+		}
+		return nil
+	})
+	covered := func(r Range) bool {
+		for _, e := range existing {
+			if e.from <= r.from && r.to <= e.to {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, r := range snapshots.Ranges() {
+		if covered(r) {
+			continue
+		}
+		if r.to-1 > executed {
+			break
+		}
+		segPath := filepath.Join(snapshots.Dir(), Receipts.SegmentFileName(r.from, r.to))
+		if err := DumpReceipts(ctx, db, segPath, tmpDir, r.from, r.to, workers, lvl); err != nil {
+			if errors.Is(err, ErrReceiptsMissing) {
+				log.Debug("[snapshots] Skip receipts segment", "range", r.String(), "err", err)
+				continue
+			}
+			return dumped, fmt.Errorf("DumpReceipts: %w", err)
+		}
+		p := &background.Progress{}
+		if err := ReceiptsIdx(ctx, segPath, r.from, tmpDir, p, lvl); err != nil {
+			return dumped, err
+		}
+		dumped = append(dumped, r)
+	}
+	return dumped, nil
+}
+
+// removeCoveredReceipts - removes receipts segments which became part of larger ones. Call after reopen, when
+// snapshots don't use them anymore.
+func removeCoveredReceipts(snapDir string, ranges []Range) error {
+	files, err := os.ReadDir(snapDir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		from, to, ok := Receipts.ParseFileName(f.Name())
+		if !ok {
+			continue
+		}
+		for _, r := range ranges {
+			if r.from <= from && to <= r.to && r != (Range{from, to}) {
+				_ = os.Remove(filepath.Join(snapDir, f.Name()))
+				_ = os.Remove(filepath.Join(snapDir, Receipts.IdxFileName(from, to)))
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// receiptsFilesByRange - receipts segments inside [from, to), ok=false if they don't cover the whole range
+func receiptsFilesByRange(snapshots *RoSnapshots, from, to uint64) (toMerge []string, ok bool) {
+	_ = snapshots.Receipts.View(func(segments []*ReceiptSegment) error {
+		next := from
+		ok = true
+		for _, sn := range segments {
+			if sn.ranges.from < from {
+				continue
+			}
+			if sn.ranges.to > to {
+				break
+			}
+			if sn.ranges == (Range{from, to}) { // already merged
+				toMerge, ok = nil, false
+				return nil
+			}
+			if sn.ranges.from != next {
+				ok = false
+			}
+			next = sn.ranges.to
+			toMerge = append(toMerge, sn.seg.FilePath())
+		}
+		ok = ok && next == to
+		return nil
+	})
+	return toMerge, ok
+}
@@ -0,0 +1,92 @@
+package snapshotsync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+)
+
+// SegmentType - segment type of this repo, next to the block types of erigon-lib's snaptype (Headers, Bodies,
+// Transactions). snaptype.Type can't be extended from here: snaptype.ParseFileName fails on the names of the types it
+// doesn't know, and so does the seedable files scan of the downloader, which only takes 4-parts names. Names of these
+// types have a dash - the scan skips their segments, SeedableSegments announces them to the downloader instead.
+type SegmentType string
+
+const (
+	Receipts SegmentType = "receipts-logs" // v1-000000-000500-receipts-logs.seg, value: rlp(types.ReceiptsForStorage)
+)
+
+var AllSegmentTypes = []SegmentType{Receipts}
+
+func (t SegmentType) String() string { return string(t) }
+
+func (t SegmentType) SegmentFileName(from, to uint64) string {
+	return snaptype.FileName(from, to, t.String()) + ".seg"
+}
+func (t SegmentType) IdxFileName(from, to uint64) string {
+	return snaptype.IdxFileName(from, to, t.String())
+}
+
+// ParseFileName - block range of the segment file of type t, ok=false for other files
+func (t SegmentType) ParseFileName(fileName string) (from, to uint64, ok bool) {
+	if filepath.Ext(fileName) != ".seg" {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(fileName, ".seg"), "-", 4)
+	if len(parts) != 4 || parts[0] != "v1" || parts[3] != t.String() {
+		return 0, 0, false
+	}
+	from, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	to, err = strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return from * 1_000, to * 1_000, true
+}
+
+// ParseSegmentFileName - type and block range of the segment file of any of AllSegmentTypes
+func ParseSegmentFileName(fileName string) (t SegmentType, from, to uint64, ok bool) {
+	for _, t := range AllSegmentTypes {
+		if from, to, ok = t.ParseFileName(fileName); ok {
+			return t, from, to, true
+		}
+	}
+	return "", 0, 0, false
+}
+
+// SeedableSegments - download requests which make the downloader seed the complete segments of AllSegmentTypes in
+// dir (it creates their .torrent files if needed). The downloader finds only the segments of snaptype by itself.
+func SeedableSegments(dir string) (downloadRequest []DownloadRequest, err error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() || !f.Type().IsRegular() {
+			continue
+		}
+		_, from, to, ok := ParseSegmentFileName(f.Name())
+		if !ok || to-from != snaptype.Erigon2SegmentSize {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() == 0 {
+			continue
+		}
+		downloadRequest = append(downloadRequest, NewDownloadRequest(nil, f.Name(), ""))
+	}
+	return downloadRequest, nil
+}
@@ -0,0 +1,451 @@
+package snapshotsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1" //nolint:gosec
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	dir2 "github.com/ledgerwatch/erigon-lib/common/dir"
+	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/downloader/downloadercfg"
+	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+	proto_downloader "github.com/ledgerwatch/erigon-lib/gointerfaces/downloader"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
+)
+
+// SnapshotIssue - a file of the snapshots dir which can't be served as it is
+type SnapshotIssue struct {
+	FileName string
+	Reason   string
+	// TorrentHash - info hash of the preverified segment, empty for segments produced locally
+	TorrentHash string
+}
+
+// VerifyReport - result of VerifySnapshots
+type VerifyReport struct {
+	Checked int
+	// BadSegments - segments which don't match their torrent, preverified hash or block range. Need download again
+	// together with their indices.
+	BadSegments []SnapshotIssue
+	// BadTorrents - .torrent files whose info hash differs from the preverified one
+	BadTorrents []SnapshotIssue
+	// BadIndices - indices of good segments which are broken or don't match the segment. Can be rebuilt.
+	BadIndices []SnapshotIssue
+	// Missing - preverified segments which are not in the snapshots dir
+	Missing []SnapshotIssue
+	// Unexpected - segments inside the preverified range which are not preverified, only reported
+	Unexpected []SnapshotIssue
+}
+
+func (r *VerifyReport) OK() bool {
+	return len(r.BadSegments) == 0 && len(r.BadTorrents) == 0 && len(r.BadIndices) == 0 && len(r.Missing) == 0
+}
+
+func (r *VerifyReport) LogStat(logPrefix string) {
+	for _, it := range r.BadSegments {
+		log.Warn(fmt.Sprintf("[%s] Bad segment", logPrefix), "file", it.FileName, "reason", it.Reason)
+	}
+	for _, it := range r.BadTorrents {
+		log.Warn(fmt.Sprintf("[%s] Bad torrent file", logPrefix), "file", it.FileName, "reason", it.Reason)
+	}
+	for _, it := range r.BadIndices {
+		log.Warn(fmt.Sprintf("[%s] Bad index", logPrefix), "file", it.FileName, "reason", it.Reason)
+	}
+	for _, it := range r.Missing {
+		log.Warn(fmt.Sprintf("[%s] Missing segment", logPrefix), "file", it.FileName)
+	}
+	for _, it := range r.Unexpected {
+		log.Info(fmt.Sprintf("[%s] Segment is not preverified", logPrefix), "file", it.FileName)
+	}
+	log.Info(fmt.Sprintf("[%s] Verified", logPrefix), "segments", r.Checked, "bad_segments", len(r.BadSegments),
+		"bad_torrents", len(r.BadTorrents), "bad_indices", len(r.BadIndices), "missing", len(r.Missing))
+}
+
+// segmentToVerify - block segments are parsed by snaptype, the segments of AllSegmentTypes by their SegmentType
+type segmentToVerify struct {
+	fileName string
+	from, to uint64
+	t        string // snaptype.Type.String() or SegmentType.String()
+}
+
+// VerifySnapshots - checks every segment of the snapshots dir:
+//   - its pieces against the hashes of its .torrent file, and the info hash against preverified (from snapcfg);
+//   - its words against the block range of its name;
+//   - its indices against the segment.
+//
+// Preverified segments without a file are reported as missing. Doesn't change anything in the dir, see HealSnapshots.
+func VerifySnapshots(ctx context.Context, snapDir string, preverified snapcfg.Preverified, workers int) (*VerifyReport, error) {
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	hashes := make(map[string]string, len(preverified))
+	var expectBlocks uint64
+	for _, p := range preverified {
+		hashes[p.Name] = p.Hash
+		if f, err := snaptype.ParseFileName("", p.Name); err == nil && f.Ext == ".seg" && f.To > expectBlocks {
+			expectBlocks = f.To
+		}
+	}
+
+	var list []segmentToVerify
+	segments, err := snaptype.Segments(snapDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range segments {
+		_, fName := filepath.Split(f.Path)
+		list = append(list, segmentToVerify{fileName: fName, from: f.From, to: f.To, t: f.T.String()})
+	}
+	receipts, err := ReceiptsSegments(snapDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range receipts {
+		list = append(list, segmentToVerify{fileName: Receipts.SegmentFileName(r.from, r.to), from: r.from, to: r.to, t: Receipts.String()})
+	}
+
+	report := &VerifyReport{Checked: len(list)}
+	var lock sync.Mutex
+	var done int
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(workers)
+	for _, sn := range list {
+		sn := sn
+		g.Go(func() error {
+			hash := hashes[sn.fileName]
+			badSeg, badTorrent, badIdx, err := verifySegment(gCtx, snapDir, sn, hash)
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			done++
+			if badSeg != "" {
+				report.BadSegments = append(report.BadSegments, SnapshotIssue{FileName: sn.fileName, Reason: badSeg, TorrentHash: hash})
+			}
+			if badTorrent != "" {
+				report.BadTorrents = append(report.BadTorrents, SnapshotIssue{FileName: sn.fileName + ".torrent", Reason: badTorrent, TorrentHash: hash})
+			}
+			report.BadIndices = append(report.BadIndices, badIdx...)
+			if hash == "" && sn.t != Receipts.String() && sn.from < expectBlocks {
+				report.Unexpected = append(report.Unexpected, SnapshotIssue{FileName: sn.fileName, Reason: "not preverified"})
+			}
+			select {
+			case <-logEvery.C:
+				log.Info("[snapshots] Verify", "progress", fmt.Sprintf("%d/%d", done, len(list)))
+			default:
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range preverified {
+		if filepath.Ext(p.Name) != ".seg" || dir2.FileExist(filepath.Join(snapDir, p.Name)) {
+			continue
+		}
+		report.Missing = append(report.Missing, SnapshotIssue{FileName: p.Name, Reason: "missing", TorrentHash: p.Hash})
+	}
+	for _, issues := range [][]SnapshotIssue{report.BadSegments, report.BadTorrents, report.BadIndices, report.Missing, report.Unexpected} {
+		sort.Slice(issues, func(i, j int) bool { return issues[i].FileName < issues[j].FileName })
+	}
+	return report, nil
+}
+
+// verifySegment - returns why the segment or its .torrent is bad, and the bad indices of a good segment.
+// err is returned only if verification can't continue (ctx cancelled).
+func verifySegment(ctx context.Context, snapDir string, sn segmentToVerify, hash string) (badSeg, badTorrent string, badIdx []SnapshotIssue, err error) {
+	segPath := filepath.Join(snapDir, sn.fileName)
+	if sn.from >= sn.to {
+		return "wrong block range in file name", "", nil, nil
+	}
+	badSeg, badTorrent, err = verifyTorrent(ctx, segPath, hash)
+	if err != nil || badSeg != "" {
+		return badSeg, badTorrent, nil, err
+	}
+	seg, err := compress.NewDecompressor(segPath)
+	if err != nil {
+		return err.Error(), badTorrent, nil, nil
+	}
+	defer seg.Close()
+	if badSeg = verifyWords(seg, sn); badSeg != "" {
+		return badSeg, badTorrent, nil, nil
+	}
+
+	idxTypes := []string{sn.t}
+	if sn.t == snaptype.Transactions.String() {
+		idxTypes = append(idxTypes, snaptype.Transactions2Block.String())
+	}
+	for _, t := range idxTypes {
+		fName := snaptype.IdxFileName(sn.from, sn.to, t)
+		if reason := verifyIdx(filepath.Join(snapDir, fName), seg, sn); reason != "" {
+			badIdx = append(badIdx, SnapshotIssue{FileName: fName, Reason: reason})
+		}
+	}
+	return "", badTorrent, badIdx, nil
+}
+
+// verifyTorrent - checks the pieces of the segment against its .torrent file. If there is no .torrent file, or it
+// doesn't match the preverified hash, the info hash is computed from the segment itself.
+func verifyTorrent(ctx context.Context, segPath string, hash string) (badSeg, badTorrent string, err error) {
+	var info *metainfo.Info
+	if dir2.FileExist(segPath + ".torrent") {
+		mi, err := metainfo.LoadFromFile(segPath + ".torrent")
+		if err == nil {
+			var torrentInfo metainfo.Info
+			if torrentInfo, err = mi.UnmarshalInfo(); err == nil {
+				info = &torrentInfo
+				if hash != "" && mi.HashInfoBytes().HexString() != hash {
+					info, badTorrent = nil, fmt.Sprintf("info hash %s, preverified %s", mi.HashInfoBytes().HexString(), hash)
+				}
+			}
+		}
+		if err != nil {
+			badTorrent = err.Error()
+		}
+	}
+	if info == nil {
+		if hash == "" {
+			return "", badTorrent, nil // produced locally, nothing to compare with
+		}
+		_, fName := filepath.Split(segPath)
+		info = &metainfo.Info{PieceLength: downloadercfg.DefaultPieceSize, Name: fName}
+		if err := info.BuildFromFilePath(segPath); err != nil {
+			return fmt.Sprintf("build torrent info: %s", err), badTorrent, nil
+		}
+		infoBytes, err := bencode.Marshal(info)
+		if err != nil {
+			return "", badTorrent, err
+		}
+		if got := metainfo.HashBytes(infoBytes).HexString(); got != hash {
+			return fmt.Sprintf("info hash %s, preverified %s", got, hash), badTorrent, nil
+		}
+		return "", badTorrent, nil
+	}
+	badSeg = verifyPieces(ctx, info, segPath)
+	return badSeg, badTorrent, ctx.Err()
+}
+
+func verifyPieces(ctx context.Context, info *metainfo.Info, segPath string) (badSeg string) {
+	f, err := os.Open(segPath)
+	if err != nil {
+		return err.Error()
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err.Error()
+	}
+	if stat.Size() != info.TotalLength() {
+		return fmt.Sprintf("size %d, torrent %d", stat.Size(), info.TotalLength())
+	}
+	for i, numPieces := 0, info.NumPieces(); i < numPieces; i++ {
+		if ctx.Err() != nil {
+			return ""
+		}
+		p := info.Piece(i)
+		h := sha1.New() //nolint:gosec
+		if _, err := io.Copy(h, io.NewSectionReader(f, p.Offset(), p.Length())); err != nil {
+			return err.Error()
+		}
+		if !bytes.Equal(h.Sum(nil), p.Hash().Bytes()) {
+			return fmt.Sprintf("hash mismatch at piece %d", i)
+		}
+	}
+	return ""
+}
+
+// verifyWords - headers, bodies and receipts segments have a word per block of their range
+func verifyWords(seg *compress.Decompressor, sn segmentToVerify) (badSeg string) {
+	blocks := sn.to - sn.from
+	switch sn.t {
+	case snaptype.Headers.String():
+		if uint64(seg.Count()) != blocks {
+			return fmt.Sprintf("%d headers for %d blocks", seg.Count(), blocks)
+		}
+		// first and last headers must have the numbers of the range
+		var word []byte
+		var first, last types.Header
+		g := seg.MakeGetter()
+		for i := 0; g.HasNext(); i++ {
+			word, _ = g.Next(word[:0])
+			if i != 0 && i != seg.Count()-1 {
+				continue
+			}
+			h := &first
+			if i != 0 {
+				h = &last
+			}
+			if len(word) < 2 {
+				return fmt.Sprintf("empty header word %d", i)
+			}
+			if err := rlp.DecodeBytes(word[1:], h); err != nil {
+				return fmt.Sprintf("header word %d: %s", i, err)
+			}
+		}
+		if seg.Count() == 1 {
+			last = first
+		}
+		if first.Number.Uint64() != sn.from || last.Number.Uint64() != sn.to-1 {
+			return fmt.Sprintf("headers %d-%d, expected %d-%d", first.Number.Uint64(), last.Number.Uint64(), sn.from, sn.to-1)
+		}
+	case snaptype.Bodies.String(), Receipts.String():
+		if uint64(seg.Count()) != blocks {
+			return fmt.Sprintf("%d words for %d blocks", seg.Count(), blocks)
+		}
+	}
+	return ""
+}
+
+// verifyIdx - the index must be newer than the segment and have a key per word of it. Block indices start at the
+// first block of the segment.
+func verifyIdx(idxPath string, seg *compress.Decompressor, sn segmentToVerify) (reason string) {
+	idx, err := openIdx(idxPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "" // missing indices are built anyway
+		}
+		return err.Error()
+	}
+	defer idx.Close()
+	if idx.ModTime().Before(seg.ModTime()) {
+		return "created before the segment"
+	}
+	if idx.KeyCount() != uint64(seg.Count()) {
+		return fmt.Sprintf("%d keys for %d words", idx.KeyCount(), seg.Count())
+	}
+	if sn.t != snaptype.Transactions.String() && idx.BaseDataID() != sn.from {
+		return fmt.Sprintf("starts at %d, segment at %d", idx.BaseDataID(), sn.from)
+	}
+	return ""
+}
+
+// PreverifiedSegments - preverified segments of the chain, limited to the ones the node started with (see
+// rawdb.WriteSnapshots) if it knows them
+func PreverifiedSegments(ctx context.Context, db kv.RoDB, chainName string) (snapcfg.Preverified, error) {
+	var snInDB, snHistInDB []string
+	if err := db.View(ctx, func(tx kv.Tx) (err error) {
+		snInDB, snHistInDB, err = rawdb.ReadSnapshots(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return snapcfg.KnownCfg(chainName, snInDB, snHistInDB).Preverified, nil
+}
+
+// VerifyAndHealSnapshots - startup check: VerifySnapshots, then HealSnapshots if anything is wrong. The report is
+// for RedownloadSnapshots once the downloader is up.
+func VerifyAndHealSnapshots(ctx context.Context, logPrefix string, db kv.RoDB, dirs datadir.Dirs, chainName string, chainID uint256.Int, workers int) (*VerifyReport, error) {
+	preverified, err := PreverifiedSegments(ctx, db, chainName)
+	if err != nil {
+		return nil, err
+	}
+	report, err := VerifySnapshots(ctx, dirs.Snap, preverified, workers)
+	if err != nil {
+		return nil, err
+	}
+	report.LogStat(logPrefix)
+	if report.OK() {
+		return report, nil
+	}
+	return report, HealSnapshots(ctx, logPrefix, report, dirs, chainID, semaphore.NewWeighted(int64(workers)))
+}
+
+// HealSnapshots - removes the files VerifySnapshots found bad, and rebuilds the indices of good segments. Bad segments
+// are removed with their indices, so that the downloader doesn't seed them anymore - RedownloadSnapshots requests
+// them again.
+func HealSnapshots(ctx context.Context, logPrefix string, report *VerifyReport, dirs datadir.Dirs, chainID uint256.Int, sem *semaphore.Weighted) error {
+	for _, it := range report.BadTorrents {
+		if err := removeIfExists(filepath.Join(dirs.Snap, it.FileName)); err != nil {
+			return err
+		}
+	}
+	for _, it := range report.BadSegments {
+		from, to, t, ok := segmentFileRange(it.FileName)
+		if !ok {
+			continue
+		}
+		toRemove := []string{it.FileName, snaptype.IdxFileName(from, to, t)}
+		if t == snaptype.Transactions.String() {
+			toRemove = append(toRemove, snaptype.IdxFileName(from, to, snaptype.Transactions2Block.String()))
+		}
+		if it.TorrentHash == "" {
+			// locally produced segment: its .torrent describes the bad file, it's produced again with the segment
+			toRemove = append(toRemove, it.FileName+".torrent")
+		}
+		for _, fName := range toRemove {
+			if err := removeIfExists(filepath.Join(dirs.Snap, fName)); err != nil {
+				return err
+			}
+		}
+		log.Info(fmt.Sprintf("[%s] Removed bad segment", logPrefix), "file", it.FileName)
+	}
+	for _, it := range report.BadIndices {
+		if err := removeIfExists(filepath.Join(dirs.Snap, it.FileName)); err != nil {
+			return err
+		}
+	}
+	if len(report.BadIndices) == 0 {
+		return nil
+	}
+	log.Info(fmt.Sprintf("[%s] Rebuilding indices", logPrefix), "amount", len(report.BadIndices))
+	return BuildMissedIndices(logPrefix, ctx, dirs, chainID, sem)
+}
+
+// RedownloadSnapshots - asks the downloader for the preverified segments which were bad or missing. Locally produced
+// segments are not known to other peers, they can only be produced again.
+func RedownloadSnapshots(ctx context.Context, logPrefix string, report *VerifyReport, downloader proto_downloader.DownloaderClient) error {
+	var downloadRequest []DownloadRequest
+	for _, it := range append(append([]SnapshotIssue{}, report.BadSegments...), report.Missing...) {
+		if it.TorrentHash == "" {
+			log.Warn(fmt.Sprintf("[%s] Segment is not preverified, can't download it", logPrefix), "file", it.FileName)
+			continue
+		}
+		downloadRequest = append(downloadRequest, NewDownloadRequest(nil, it.FileName, it.TorrentHash))
+	}
+	if len(downloadRequest) == 0 {
+		return nil
+	}
+	log.Info(fmt.Sprintf("[%s] Requesting segments download", logPrefix), "amount", len(downloadRequest))
+	return RequestSnapshotsDownload(ctx, downloadRequest, downloader)
+}
+
+func segmentFileRange(fName string) (from, to uint64, t string, ok bool) {
+	if segType, from, to, ok := ParseSegmentFileName(fName); ok {
+		return from, to, segType.String(), true
+	}
+	f, err := snaptype.ParseFileName("", fName)
+	if err != nil {
+		return 0, 0, "", false
+	}
+	return f.From, f.To, f.T.String(), true
+}
+
+func removeIfExists(fPath string) error {
+	if err := os.Remove(fPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
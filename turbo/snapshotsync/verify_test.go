@@ -0,0 +1,112 @@
+package snapshotsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+	proto_downloader "github.com/ledgerwatch/erigon-lib/gointerfaces/downloader"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
+)
+
+func createTestTorrentFile(t *testing.T, segPath string) string {
+	_, fName := filepath.Split(segPath)
+	info := &metainfo.Info{PieceLength: 16 * 1024, Name: fName}
+	require.NoError(t, info.BuildFromFilePath(segPath))
+	infoBytes, err := bencode.Marshal(info)
+	require.NoError(t, err)
+	mi := &metainfo.MetaInfo{InfoBytes: infoBytes}
+	f, err := os.Create(segPath + ".torrent")
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, mi.Write(f))
+	return mi.HashInfoBytes().HexString()
+}
+
+type downloadRecorder struct {
+	proto_downloader.DownloaderClient
+	requests []*proto_downloader.DownloadRequest
+}
+
+func (d *downloadRecorder) Download(ctx context.Context, in *proto_downloader.DownloadRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	d.requests = append(d.requests, in)
+	return &emptypb.Empty{}, nil
+}
+
+func TestVerifySnapshots(t *testing.T) {
+	ctx, require := context.Background(), require.New(t)
+	dirs := datadir.New(t.TempDir())
+	txsSeg := snaptype.SegmentFileName(0, 500_000, snaptype.Transactions)
+	createTestSegmentFile(t, 0, 500_000, snaptype.Transactions, dirs.Snap)
+	hash := createTestTorrentFile(t, filepath.Join(dirs.Snap, txsSeg))
+	preverified := snapcfg.Preverified{{Name: txsSeg, Hash: hash}}
+
+	report, err := VerifySnapshots(ctx, dirs.Snap, preverified, 2)
+	require.NoError(err)
+	require.True(report.OK(), "%+v", report)
+	require.Equal(1, report.Checked)
+
+	// bodies segment must have a word per block
+	createTestSegmentFile(t, 500_000, 501_000, snaptype.Bodies, dirs.Snap)
+	// truncated index
+	txsIdx := snaptype.IdxFileName(0, 500_000, snaptype.Transactions2Block.String())
+	require.NoError(os.Truncate(filepath.Join(dirs.Snap, txsIdx), 10))
+	report, err = VerifySnapshots(ctx, dirs.Snap, append(preverified, snapcfg.PreverifiedItem{Name: "v1-000000-000500-headers.seg", Hash: hash}), 2)
+	require.NoError(err)
+	require.Equal(2, report.Checked)
+	require.Len(report.BadSegments, 1)
+	require.Equal(snaptype.SegmentFileName(500_000, 501_000, snaptype.Bodies), report.BadSegments[0].FileName)
+	require.Len(report.BadIndices, 1)
+	require.Equal(txsIdx, report.BadIndices[0].FileName)
+	require.Len(report.Missing, 1)
+	require.Equal("v1-000000-000500-headers.seg", report.Missing[0].FileName)
+	require.Empty(report.Unexpected)
+
+	// bit-rot is caught by the pieces of the .torrent file
+	segPath := filepath.Join(dirs.Snap, txsSeg)
+	data, err := os.ReadFile(segPath)
+	require.NoError(err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(os.WriteFile(segPath, data, 0644))
+	report, err = VerifySnapshots(ctx, dirs.Snap, preverified, 2)
+	require.NoError(err)
+	require.Len(report.BadSegments, 2)
+	require.Equal(txsSeg, report.BadSegments[0].FileName)
+	require.Equal(hash, report.BadSegments[0].TorrentHash)
+
+	// .torrent which doesn't match preverified, the segment is checked against the preverified hash itself
+	report, err = VerifySnapshots(ctx, dirs.Snap, snapcfg.Preverified{{Name: txsSeg, Hash: "0000000000000000000000000000000000000000"}}, 2)
+	require.NoError(err)
+	require.Len(report.BadTorrents, 1)
+	require.Equal(txsSeg+".torrent", report.BadTorrents[0].FileName)
+	require.Len(report.BadSegments, 2)
+
+	// healing removes the bad segments, the preverified one is downloaded again with its .torrent kept
+	report, err = VerifySnapshots(ctx, dirs.Snap, preverified, 2)
+	require.NoError(err)
+	report.BadIndices = nil // indices of the test segments can't be rebuilt
+	require.NoError(HealSnapshots(ctx, "test", report, dirs, *uint256.NewInt(1), nil))
+	for _, fName := range []string{txsSeg, txsIdx, snaptype.SegmentFileName(500_000, 501_000, snaptype.Bodies)} {
+		_, err := os.Stat(filepath.Join(dirs.Snap, fName))
+		require.ErrorIs(err, os.ErrNotExist, fName)
+	}
+	_, err = os.Stat(segPath + ".torrent")
+	require.NoError(err)
+
+	downloader := &downloadRecorder{}
+	require.NoError(RedownloadSnapshots(ctx, "test", report, downloader))
+	require.Len(downloader.requests, 1)
+	require.Len(downloader.requests[0].Items, 1)
+	require.Equal(txsSeg, downloader.requests[0].Items[0].Path)
+	require.NotNil(downloader.requests[0].Items[0].TorrentHash)
+}
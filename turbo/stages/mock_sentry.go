@@ -200,7 +200,16 @@ func MockWithGenesisPruneMode(t *testing.T, gspec *core.Genesis, key *ecdsa.Priv
 	return MockWithEverything(t, gspec, key, prune, ethash.NewFaker(), false, withPosDownloader)
 }
 
+// MockWithGenesisHistoryV3 - like MockWithGenesis, but history v3 is enabled or disabled regardless of the erigon3 build tag
+func MockWithGenesisHistoryV3(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, historyV3 bool) *MockSentry {
+	return mockWithEverything(t, gspec, key, prune.DefaultMode, ethash.NewFaker(), false, false, historyV3)
+}
+
 func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, prune prune.Mode, engine consensus.Engine, withTxPool bool, withPosDownloader bool) *MockSentry {
+	return mockWithEverything(t, gspec, key, prune, engine, withTxPool, withPosDownloader, ethconfig.EnableHistoryV3InTest)
+}
+
+func mockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, prune prune.Mode, engine consensus.Engine, withTxPool bool, withPosDownloader bool, historyV3 bool) *MockSentry {
 	var tmpdir string
 	if t != nil {
 		tmpdir = t.TempDir()
@@ -211,7 +220,7 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 	var err error
 
 	cfg := ethconfig.Defaults
-	cfg.HistoryV3 = ethconfig.EnableHistoryV3InTest
+	cfg.HistoryV3 = historyV3
 	cfg.StateStream = true
 	cfg.BatchSize = 1 * datasize.MB
 	cfg.Sync.BodyDownloadTimeoutSeconds = 10
@@ -346,7 +355,7 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 	var snapshotsDownloader proto_downloader.DownloaderClient
 
 	blockRetire := snapshotsync.NewBlockRetire(1, dirs.Tmp, mock.BlockSnapshots, mock.DB, snapshotsDownloader, mock.Notifications.Events)
-	mock.Sync = stagedsync.New(
+	stagesList, unwindOrder, pruneOrder, err := stagedsync.WithCustomStages(mock.DB,
 		stagedsync.DefaultStages(mock.Ctx, prune,
 			stagedsync.StageSnapshotsCfg(mock.DB, *mock.ChainConfig, dirs, mock.BlockSnapshots, blockRetire, snapshotsDownloader, blockReader, mock.Notifications.Events, mock.Engine, mock.HistoryV3, mock.agg),
 			stagedsync.StageHeadersCfg(
@@ -399,6 +408,10 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 		stagedsync.DefaultUnwindOrder,
 		stagedsync.DefaultPruneOrder,
 	)
+	if err != nil {
+		panic(err)
+	}
+	mock.Sync = stagedsync.New(stagesList, unwindOrder, pruneOrder)
 
 	mock.sentriesClient.Hd.StartPoSDownloader(mock.Ctx, sendHeaderRequest, penalize)
 
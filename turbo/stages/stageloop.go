@@ -370,7 +370,7 @@ func NewStagedSync(ctx context.Context,
 	// Hence we run it in the test mode.
 	runInTestMode := cfg.ImportMode
 
-	return stagedsync.New(
+	stagesList, unwindOrder, pruneOrder, err := stagedsync.WithCustomStages(db,
 		stagedsync.DefaultStages(ctx, cfg.Prune,
 			stagedsync.StageSnapshotsCfg(db, *controlServer.ChainConfig, dirs, snapshots, blockRetire, snapDownloader, blockReader, notifications.Events, engine, cfg.HistoryV3, agg),
 			stagedsync.StageHeadersCfg(
@@ -421,7 +421,15 @@ func NewStagedSync(ctx context.Context,
 			stagedsync.StageFinishCfg(db, dirs.Tmp, forkValidator), runInTestMode),
 		stagedsync.DefaultUnwindOrder,
 		stagedsync.DefaultPruneOrder,
-	), nil
+	)
+	if err != nil {
+		return nil, err
+	}
+	sync := stagedsync.New(stagesList, unwindOrder, pruneOrder)
+	if cfg.Sync.ParallelStages {
+		sync.EnableParallelStages(dirs.Tmp, stagedsync.DefaultParallelStagesBatch)
+	}
+	return sync, nil
 }
 
 func NewInMemoryExecution(ctx context.Context, db kv.RwDB, cfg *ethconfig.Config, controlServer *sentry.MultiClient, dirs datadir.Dirs, notifications *shards.Notifications, snapshots *snapshotsync.RoSnapshots, agg *state.AggregatorV3) (*stagedsync.Sync, error) {
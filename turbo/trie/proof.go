@@ -95,3 +95,77 @@ func (t *Trie) Prove(key []byte, fromLevel int, storage bool) ([][]byte, error)
 	}
 	return proof, nil
 }
+
+// NodeByPath returns the encoding of the node at the path (in HEX encoding, without terminator) as served by the
+// snap protocol. Paths of storage nodes start with the 64 nibbles of the account key. found is false if there is
+// no node at the path
+func (t *Trie) NodeByPath(path []byte, storage bool) (enc []byte, found bool, err error) {
+	hasher := newHasher(false)
+	defer returnHasherToPool(hasher)
+	tn := t.root
+	for tn != nil {
+		if n, ok := tn.(*accountNode); ok {
+			if !storage {
+				return nil, false, nil
+			}
+			tn, storage = n.storage, false
+			continue
+		}
+		if len(path) == 0 {
+			switch tn.(type) {
+			case *shortNode, *duoNode, *fullNode:
+				rlp, err := hasher.hashChildren(tn, 0)
+				if err != nil {
+					return nil, false, err
+				}
+				return common.CopyBytes(rlp), true, nil
+			case hashNode:
+				return nil, false, fmt.Errorf("encountered hashNode unexpectedly at the end of path")
+			default:
+				return nil, false, nil
+			}
+		}
+		switch n := tn.(type) {
+		case *shortNode:
+			nKey := n.Key
+			if nKey[len(nKey)-1] == 16 {
+				nKey = nKey[:len(nKey)-1]
+			}
+			if len(path) < len(nKey) || !bytes.Equal(nKey, path[:len(nKey)]) {
+				return nil, false, nil
+			}
+			tn = n.Val
+			path = path[len(nKey):]
+		case *duoNode:
+			i1, i2 := n.childrenIdx()
+			switch path[0] {
+			case i1:
+				tn = n.child1
+			case i2:
+				tn = n.child2
+			default:
+				tn = nil
+			}
+			path = path[1:]
+		case *fullNode:
+			tn = n.Children[path[0]]
+			path = path[1:]
+		case valueNode:
+			return nil, false, nil
+		case hashNode:
+			return nil, false, fmt.Errorf("encountered hashNode unexpectedly, path %x", path)
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", tn, tn))
+		}
+	}
+	return nil, false, nil
+}
+
+// CompactToHex converts the compact (hex-prefix) encoding of a path into HEX encoding, without terminator
+func CompactToHex(compact []byte) []byte {
+	hex := compactToHex(compact)
+	if hasTerm(hex) {
+		hex = hex[:len(hex)-1]
+	}
+	return hex
+}
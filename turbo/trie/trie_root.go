@@ -124,6 +124,10 @@ type RootHashAggregator struct {
 	a              accounts.Account
 	leafData       GenStructStepLeafData
 	accData        GenStructStepAccountData
+
+	rd       RetainDecider // decides which nodes are constructed instead of being hashed, nil means "retain nothing"
+	rdBuf    []byte        // nibbles of the current account with incarnation, followed by the storage prefix
+	rootNode node          // root of the constructed trie, only set when rd is not nil
 }
 
 type StreamReceiver interface {
@@ -315,6 +319,27 @@ func (l *FlatDBTrieLoader) CalcTrieRoot(tx kv.Tx, prefix []byte, quit <-chan str
 	return l.receiver.Root(), nil
 }
 
+// CalcSubTrie - works like CalcTrieRoot, but also constructs all trie nodes on the path to the keys of `rd`
+// (instead of only hashing them) and returns them as a Trie. Such trie can be used to build Merkle proofs, see Trie.Prove
+// To build proofs of storage keys, add them to `rd` in format: {addrHash}{incarnation}{storageKeyHash}
+// Keys of `rd` must also be retained by RetainDecider passed to Reset - otherwise their paths are covered by Intermediate Hashes
+func (l *FlatDBTrieLoader) CalcSubTrie(tx kv.Tx, rd RetainDecider, quit <-chan struct{}) (*Trie, error) {
+	if l.receiver != l.defaultReceiver {
+		return nil, fmt.Errorf("CalcSubTrie doesn't support custom stream receivers")
+	}
+	l.defaultReceiver.rd = rd
+	defer func() { l.defaultReceiver.rd = nil }()
+	root, err := l.CalcTrieRoot(tx, []byte{}, quit)
+	if err != nil {
+		return nil, err
+	}
+	t := New(root)
+	if l.defaultReceiver.rootNode != nil {
+		t.root = l.defaultReceiver.rootNode
+	}
+	return t, nil
+}
+
 func (l *FlatDBTrieLoader) logProgress(accountKey, ihK []byte) {
 	var k string
 	if accountKey != nil {
@@ -329,6 +354,24 @@ func (r *RootHashAggregator) RetainNothing(_ []byte) bool {
 	return false
 }
 
+func (r *RootHashAggregator) retainAccount(prefix []byte) bool {
+	if r.rd == nil {
+		return false
+	}
+	return r.rd.Retain(prefix)
+}
+
+// retainStorage - storage prefixes are relative to the storage trie of the current account,
+// but RetainDecider expects them to be prepended by the account key with incarnation
+func (r *RootHashAggregator) retainStorage(prefix []byte) bool {
+	if r.rd == nil {
+		return false
+	}
+	hexutil.DecompressNibbles(r.currAccK, &r.rdBuf)
+	r.rdBuf = append(r.rdBuf, prefix...)
+	return r.rd.Retain(r.rdBuf)
+}
+
 func (r *RootHashAggregator) Reset(hc HashCollector2, shc StorageHashCollector2, trace bool) {
 	r.hc = hc
 	r.shc = shc
@@ -346,6 +389,8 @@ func (r *RootHashAggregator) Reset(hc HashCollector2, shc StorageHashCollector2,
 	r.valueStorage = nil
 	r.wasIHStorage = false
 	r.root = common.Hash{}
+	r.rootNode = nil
+	r.rd = nil
 	r.trace = trace
 	r.hb.trace = trace
 }
@@ -495,6 +540,9 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 		}
 		if r.hb.hasRoot() {
 			r.root = r.hb.rootHash()
+			if r.rd != nil {
+				r.rootNode = r.hb.root()
+			}
 		} else {
 			r.root = EmptyRoot
 		}
@@ -565,7 +613,7 @@ func (r *RootHashAggregator) genStructStorage() error {
 		r.leafData.Value = rlphacks.RlpSerializableBytes(r.valueStorage)
 		data = &r.leafData
 	}
-	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.RetainNothing, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.retainStorage, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.shc == nil {
 			return nil
 		}
@@ -633,7 +681,7 @@ func (r *RootHashAggregator) genStructAccount() error {
 	r.currStorage.Reset()
 	r.succStorage.Reset()
 	var err error
-	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.RetainNothing, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.retainAccount, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.hc == nil {
 			return nil
 		}